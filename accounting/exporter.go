@@ -0,0 +1,65 @@
+// Package accounting exports generated journal entries in formats accounting software can import.
+package accounting
+
+import (
+	"fmt"
+	"inventory-go/models"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Exporter writes journal entries in one accounting software's import format.
+// Register an implementation to make a new format available to the export endpoint.
+type Exporter interface {
+	// Name is the format identifier used in the export request
+	Name() string
+	// ContentType is the MIME type of the exported file
+	ContentType() string
+	// FileExtension is the extension of the exported file, without the dot
+	FileExtension() string
+	// Export writes the entries and their lines to w
+	Export(w io.Writer, entries []models.JournalEntry) error
+}
+
+var (
+	mu        sync.RWMutex
+	exporters = map[string]Exporter{}
+)
+
+// DefaultFormat is the export format used when none is requested
+const DefaultFormat = "generic_csv"
+
+func init() {
+	Register(GenericCSVExporter{})
+}
+
+// Register makes an exporter available by its name, replacing any exporter with the same name
+func Register(e Exporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	exporters[e.Name()] = e
+}
+
+// Get returns the exporter registered for a format
+func Get(format string) (Exporter, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := exporters[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	return e, nil
+}
+
+// Formats returns the names of all registered formats, sorted
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,50 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"inventory-go/models"
+	"io"
+	"strconv"
+)
+
+// GenericCSVExporter writes one row per journal line with the entry's details repeated,
+// a layout most accounting packages can map on import
+type GenericCSVExporter struct{}
+
+// Name implements Exporter
+func (GenericCSVExporter) Name() string { return DefaultFormat }
+
+// ContentType implements Exporter
+func (GenericCSVExporter) ContentType() string { return "text/csv" }
+
+// FileExtension implements Exporter
+func (GenericCSVExporter) FileExtension() string { return "csv" }
+
+// Export implements Exporter
+func (GenericCSVExporter) Export(w io.Writer, entries []models.JournalEntry) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"entry_no", "date", "source_type", "source_id", "reference", "description",
+		"line_no", "account_code", "account_name", "debit", "credit", "memo", "currency",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		for _, l := range e.Lines {
+			record := []string{
+				e.EntryNo, e.EntryDate.Format("2006-01-02"), e.SourceType, e.SourceID, e.Reference, e.Description,
+				strconv.Itoa(l.LineNo), l.AccountCode, l.AccountName,
+				l.Debit.StringFixed(), l.Credit.StringFixed(), l.Memo, e.Currency,
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
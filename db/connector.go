@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Connector opens database connections for work done in the background. A *pgx.Conn is not
+// safe for concurrent use and the server's connection is shared by every request handler, so
+// background work connects on its own with the same settings.
+type Connector struct {
+	config *pgx.ConnConfig
+}
+
+// NewConnector creates a Connector with the same settings as conn
+func NewConnector(conn *pgx.Conn) *Connector {
+	return &Connector{config: conn.Config().Copy()}
+}
+
+// Connect opens a new connection, which the caller must close
+func (c *Connector) Connect(ctx context.Context) (*pgx.Conn, error) {
+	return pgx.ConnectConfig(ctx, c.config)
+}
@@ -1,8 +1,26 @@
 -- Database schema for inventory management system
+-- Monetary columns use NUMERIC(19, 4) to match the fixed-point money.Money type
 
 -- Create extension for UUID support (if not already created)
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Trigram matching for product search that tolerates typos
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- VAT (PPN) tax codes assigned to products and categories; rate is a percentage
+CREATE TABLE IF NOT EXISTS tax_codes (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    type VARCHAR(20) NOT NULL DEFAULT 'standard',
+    rate NUMERIC(7, 4) NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
 -- Categories table
 CREATE TABLE IF NOT EXISTS categories (
     id VARCHAR(36) PRIMARY KEY,
@@ -10,7 +28,12 @@ CREATE TABLE IF NOT EXISTS categories (
     slug VARCHAR(255) NOT NULL UNIQUE,
     description TEXT,
     parent_id VARCHAR(36) REFERENCES categories(id),
+    image_url TEXT,
+    status INTEGER NOT NULL DEFAULT 1,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    -- Path from the root down to the category, kept up to date by the category repository
     breadcrumbs JSONB,
+    tax_code_id VARCHAR(36) REFERENCES tax_codes(id),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -26,21 +49,183 @@ CREATE TABLE IF NOT EXISTS products (
     price JSONB NOT NULL,
     weight JSONB NOT NULL,
     inventory_activity JSONB,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    -- Product search: SKU and name weigh most, then attributes, then the description
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(basic->>'sku', '')), 'A') ||
+        setweight(to_tsvector('indonesian', COALESCE(basic->>'name', '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(basic->>'name', '')), 'A') ||
+        setweight(jsonb_to_tsvector('simple', attributes, '["string"]'), 'B') ||
+        setweight(to_tsvector('indonesian', COALESCE(basic->>'description', '')), 'C') ||
+        setweight(to_tsvector('english', COALESCE(basic->>'description', '')), 'C')
+    ) STORED,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Option types a parent product's variants differ by, such as Color or Size
+CREATE TABLE IF NOT EXISTS product_options (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Values of an option; code stands for the value in generated variant SKUs
+CREATE TABLE IF NOT EXISTS product_option_values (
+    id VARCHAR(36) PRIMARY KEY,
+    option_id VARCHAR(36) NOT NULL REFERENCES product_options(id) ON DELETE CASCADE,
+    value VARCHAR(100) NOT NULL,
+    code VARCHAR(50) NOT NULL DEFAULT '',
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+-- Variants bound to a combination of their parent's option values; option_key lists the value ids in option order
+CREATE TABLE IF NOT EXISTS product_variants (
+    variant_id VARCHAR(36) PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    parent_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    option_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (parent_id, option_key)
+);
+
+-- The value a variant has for each of its parent's options
+CREATE TABLE IF NOT EXISTS product_variant_values (
+    variant_id VARCHAR(36) NOT NULL REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    option_id VARCHAR(36) NOT NULL REFERENCES product_options(id),
+    value_id VARCHAR(36) NOT NULL REFERENCES product_option_values(id),
+    PRIMARY KEY (variant_id, option_id)
+);
+
+-- Barcodes a product or variant is scanned by: manufacturer codes and generated internal EAN-13 codes
+CREATE TABLE IF NOT EXISTS product_barcodes (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    code VARCHAR(48) NOT NULL,
+    symbology VARCHAR(10) NOT NULL CHECK (symbology IN ('ean13', 'upca', 'code128')),
+    source VARCHAR(20) NOT NULL DEFAULT 'manufacturer' CHECK (source IN ('manufacturer', 'internal')),
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Numbers of the internal EAN-13 codes (prefix 20, ten digits, check digit)
+CREATE SEQUENCE IF NOT EXISTS internal_barcode_seq START WITH 1;
+
+-- Sticker layouts for label sheets; sizes in millimetres
+CREATE TABLE IF NOT EXISTS label_templates (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(30) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    page_width NUMERIC(6, 2) NOT NULL CHECK (page_width > 0),
+    page_height NUMERIC(6, 2) NOT NULL CHECK (page_height > 0),
+    columns INTEGER NOT NULL CHECK (columns > 0),
+    rows INTEGER NOT NULL CHECK (rows > 0),
+    label_width NUMERIC(6, 2) NOT NULL CHECK (label_width > 0),
+    label_height NUMERIC(6, 2) NOT NULL CHECK (label_height > 0),
+    margin_top NUMERIC(6, 2) NOT NULL DEFAULT 0,
+    margin_left NUMERIC(6, 2) NOT NULL DEFAULT 0,
+    gap_x NUMERIC(6, 2) NOT NULL DEFAULT 0,
+    gap_y NUMERIC(6, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Units of measure documents can be entered in
+CREATE TABLE IF NOT EXISTS units (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
 );
 
+-- Units a product is kept in: factor is how many base units one unit holds; the base unit has factor 1
+CREATE TABLE IF NOT EXISTS product_units (
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    unit VARCHAR(20) NOT NULL,
+    factor INTEGER NOT NULL CHECK (factor > 0),
+    PRIMARY KEY (product_id, unit)
+);
+
 -- Product images table
 CREATE TABLE IF NOT EXISTS images (
     id VARCHAR(36) PRIMARY KEY,
     product_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     url TEXT NOT NULL,
+    thumbnail_url TEXT,
+    medium_url TEXT,
+    content_type VARCHAR(50),
+    size BIGINT,
+    width INTEGER,
+    height INTEGER,
+    -- Keys of the uploaded original and its resized copies in file storage
+    storage_keys TEXT[] NOT NULL DEFAULT '{}',
     is_primary BOOLEAN DEFAULT FALSE,
     sort_order INTEGER DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Scheduled price changes, such as flash sales; the previous price comes back at ends_at
+CREATE TABLE IF NOT EXISTS price_schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price NUMERIC(19, 4) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    note TEXT,
+    previous_price NUMERIC(19, 4),
+    previous_currency VARCHAR(3),
+    applied_at TIMESTAMP WITH TIME ZONE,
+    reverted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Every change of a product's price
+CREATE TABLE IF NOT EXISTS price_history (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_price NUMERIC(19, 4),
+    new_price NUMERIC(19, 4) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    source VARCHAR(20) NOT NULL,
+    schedule_id VARCHAR(36) REFERENCES price_schedules(id),
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Price lists for customer tiers such as retail, reseller and wholesale
+CREATE TABLE IF NOT EXISTS price_lists (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Price of a product or variant on a price list from a minimum quantity (quantity breaks)
+CREATE TABLE IF NOT EXISTS price_list_items (
+    id VARCHAR(36) PRIMARY KEY,
+    price_list_id VARCHAR(36) NOT NULL REFERENCES price_lists(id) ON DELETE CASCADE,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    min_quantity INTEGER NOT NULL DEFAULT 1,
+    price NUMERIC(19, 4) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (price_list_id, product_id, min_quantity)
+);
+
 -- Customers table
 CREATE TABLE IF NOT EXISTS customers (
     id VARCHAR(36) PRIMARY KEY,
@@ -53,6 +238,8 @@ CREATE TABLE IF NOT EXISTS customers (
     country VARCHAR(100),
     postal_code VARCHAR(20),
     notes TEXT,
+    payment_terms VARCHAR(20) NOT NULL DEFAULT 'cash',
+    price_list_id VARCHAR(36) REFERENCES price_lists(id),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -71,6 +258,7 @@ CREATE TABLE IF NOT EXISTS suppliers (
     country VARCHAR(100),
     postal_code VARCHAR(20),
     notes TEXT,
+    payment_terms VARCHAR(20) NOT NULL DEFAULT 'cash',
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -82,8 +270,17 @@ CREATE TABLE IF NOT EXISTS stock_ins (
     reference_no VARCHAR(100) NOT NULL UNIQUE,
     status VARCHAR(20) NOT NULL DEFAULT 'pending',
     order_date TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    due_date TIMESTAMP WITH TIME ZONE,
+    tax_mode VARCHAR(20) NOT NULL DEFAULT 'exclusive',
+    total NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    paid NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    credited NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    balance NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    exchange_rate NUMERIC(19, 8) NOT NULL DEFAULT 1,
+    base_total NUMERIC(19, 4) NOT NULL DEFAULT 0,
     supplier_id VARCHAR(36) REFERENCES suppliers(id),
+    opening BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -96,13 +293,64 @@ CREATE TABLE IF NOT EXISTS stock_in_items (
     product_id VARCHAR(36) NOT NULL REFERENCES products(id),
     product_name VARCHAR(255) NOT NULL,
     quantity INTEGER NOT NULL DEFAULT 1,
-    unit_cost DECIMAL(10, 2) NOT NULL DEFAULT 0,
-    subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    tax NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    base_unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    base_subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    landed_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    tax_code_id VARCHAR(36) REFERENCES tax_codes(id),
+    tax_code VARCHAR(20),
+    tax_rate NUMERIC(7, 4) NOT NULL DEFAULT 0,
+    unit VARCHAR(20),
+    unit_quantity INTEGER,
+    unit_factor INTEGER NOT NULL DEFAULT 1,
+    base_unit VARCHAR(20),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
 );
 
+-- Cost-plus pricing rules: selling prices set from the latest purchase cost of a product or category
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(20) NOT NULL,
+    product_id VARCHAR(36) REFERENCES products(id),
+    category_id VARCHAR(36) REFERENCES categories(id),
+    markup_type VARCHAR(20) NOT NULL,
+    markup_percent NUMERIC(9, 4) NOT NULL DEFAULT 0,
+    markup_amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    round_to NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    rounding VARCHAR(10) NOT NULL DEFAULT 'nearest',
+    threshold_percent NUMERIC(7, 4) NOT NULL DEFAULT 0,
+    auto_apply BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Price suggestions: new prices proposed by pricing rules when a completed stock-in changes a unit cost
+CREATE TABLE IF NOT EXISTS price_suggestions (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    rule_id VARCHAR(36) REFERENCES pricing_rules(id),
+    stock_in_id VARCHAR(36) NOT NULL REFERENCES stock_ins(id),
+    previous_cost NUMERIC(19, 4),
+    unit_cost NUMERIC(19, 4) NOT NULL,
+    current_price NUMERIC(19, 4) NOT NULL,
+    suggested_price NUMERIC(19, 4) NOT NULL,
+    applied_price NUMERIC(19, 4),
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    auto_applied BOOLEAN NOT NULL DEFAULT FALSE,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- Sales table
 CREATE TABLE IF NOT EXISTS sales (
     id VARCHAR(36) PRIMARY KEY,
@@ -110,11 +358,18 @@ CREATE TABLE IF NOT EXISTS sales (
     customer_id VARCHAR(36) REFERENCES customers(id),
     status VARCHAR(20) NOT NULL DEFAULT 'pending',
     sale_date TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    total DECIMAL(10, 2) NOT NULL DEFAULT 0,
-    discount DECIMAL(10, 2) DEFAULT 0,
-    tax DECIMAL(10, 2) DEFAULT 0,
-    shipping_fee DECIMAL(10, 2) DEFAULT 0,
-    grand_total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    due_date TIMESTAMP WITH TIME ZONE,
+    tax_mode VARCHAR(20) NOT NULL DEFAULT 'exclusive',
+    total NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    paid NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    balance NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    discount NUMERIC(19, 4) DEFAULT 0,
+    tax NUMERIC(19, 4) DEFAULT 0,
+    shipping_fee NUMERIC(19, 4) DEFAULT 0,
+    grand_total NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    exchange_rate NUMERIC(19, 8) NOT NULL DEFAULT 1,
+    base_total NUMERIC(19, 4) NOT NULL DEFAULT 0,
     payment_status VARCHAR(20) DEFAULT 'unpaid',
     payment_method VARCHAR(50),
     platform VARCHAR(50) DEFAULT 'pos',
@@ -131,13 +386,76 @@ CREATE TABLE IF NOT EXISTS sale_items (
     product_id VARCHAR(36) NOT NULL REFERENCES products(id),
     product_name VARCHAR(255) NOT NULL,
     quantity INTEGER NOT NULL DEFAULT 1,
-    unit_price DECIMAL(10, 2) NOT NULL DEFAULT 0,
-    subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    unit_price NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    tax NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    base_subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    base_tax NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    base_discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    cogs NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    tax_code_id VARCHAR(36) REFERENCES tax_codes(id),
+    tax_code VARCHAR(20),
+    tax_rate NUMERIC(7, 4) NOT NULL DEFAULT 0,
+    price_source VARCHAR(20),
+    price_list_id VARCHAR(36) REFERENCES price_lists(id),
+    price_list_code VARCHAR(50),
+    price_min_quantity INTEGER NOT NULL DEFAULT 0,
+    promotion_discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    unit VARCHAR(20),
+    unit_quantity INTEGER,
+    unit_factor INTEGER NOT NULL DEFAULT 1,
+    base_unit VARCHAR(20),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
 );
 
+-- Promotions table (discount rules applied when sales are priced)
+CREATE TABLE IF NOT EXISTS promotions (
+    id VARCHAR(36) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type VARCHAR(20) NOT NULL,
+    scope VARCHAR(20) NOT NULL DEFAULT 'order',
+    product_ids TEXT[],
+    category_ids TEXT[],
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    percent NUMERIC(7, 4) NOT NULL DEFAULT 0,
+    amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    buy_quantity INTEGER NOT NULL DEFAULT 0,
+    get_quantity INTEGER NOT NULL DEFAULT 0,
+    bundle_quantity INTEGER NOT NULL DEFAULT 0,
+    bundle_price NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    min_spend NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    platforms TEXT[],
+    usage_limit INTEGER NOT NULL DEFAULT 0,
+    usage_limit_per_customer INTEGER NOT NULL DEFAULT 0,
+    priority INTEGER NOT NULL DEFAULT 0,
+    exclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Sale promotions table (promotions applied to each sale and the discount they gave)
+CREATE TABLE IF NOT EXISTS sale_promotions (
+    id VARCHAR(36) PRIMARY KEY,
+    sale_id VARCHAR(36) NOT NULL REFERENCES sales(id) ON DELETE CASCADE,
+    promotion_id VARCHAR(36) NOT NULL REFERENCES promotions(id),
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    base_discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- Rejects table (for stock decreases/inventory write-offs)
 CREATE TABLE IF NOT EXISTS rejects (
     id VARCHAR(36) PRIMARY KEY,
@@ -145,7 +463,7 @@ CREATE TABLE IF NOT EXISTS rejects (
     status VARCHAR(20) NOT NULL DEFAULT 'pending',
     reject_date TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     reason TEXT,
-    total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    total NUMERIC(19, 4) NOT NULL DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
@@ -158,13 +476,483 @@ CREATE TABLE IF NOT EXISTS reject_items (
     product_id VARCHAR(36) NOT NULL REFERENCES products(id),
     product_name VARCHAR(255) NOT NULL,
     quantity INTEGER NOT NULL DEFAULT 1,
-    unit_cost DECIMAL(10, 2) NOT NULL DEFAULT 0,
-    subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    cogs NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    unit VARCHAR(20),
+    unit_quantity INTEGER,
+    unit_factor INTEGER NOT NULL DEFAULT 1,
+    base_unit VARCHAR(20),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Kits: products sold as a set of other products. Kits that are not assembled are built
+-- from their components when sold; assembled kits are built ahead by assemblies.
+CREATE TABLE IF NOT EXISTS product_kits (
+    product_id VARCHAR(36) PRIMARY KEY REFERENCES products(id),
+    assembled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Kit components: how many of each product one kit takes
+CREATE TABLE IF NOT EXISTS kit_components (
+    id VARCHAR(36) PRIMARY KEY,
+    kit_id VARCHAR(36) NOT NULL REFERENCES product_kits(product_id) ON DELETE CASCADE,
+    component_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (kit_id, component_id)
+);
+
+-- Bills of materials: what one batch of a finished product is made from
+CREATE TABLE IF NOT EXISTS boms (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    output_quantity INTEGER NOT NULL DEFAULT 1 CHECK (output_quantity > 0),
+    yield_percent NUMERIC(19, 8) NOT NULL DEFAULT 0,
+    note TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     deleted_at TIMESTAMP WITH TIME ZONE
 );
 
+-- Bill of materials components: quantity per batch and the share wasted on top of it
+CREATE TABLE IF NOT EXISTS bom_components (
+    id VARCHAR(36) PRIMARY KEY,
+    bom_id VARCHAR(36) NOT NULL REFERENCES boms(id) ON DELETE CASCADE,
+    component_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    waste_percent NUMERIC(19, 8) NOT NULL DEFAULT 0,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (bom_id, component_id)
+);
+
+-- Assemblies: assembled kits and products with a bill of materials built from their components
+CREATE TABLE IF NOT EXISTS assemblies (
+    id VARCHAR(36) PRIMARY KEY,
+    reference_no VARCHAR(100) NOT NULL,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    product_name VARCHAR(255) NOT NULL,
+    bom_id VARCHAR(36) REFERENCES boms(id),
+    batches INTEGER NOT NULL DEFAULT 0,
+    expected_quantity INTEGER NOT NULL DEFAULT 0,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    assembly_date TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    note TEXT,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    total_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Assembly items: components consumed by an assembly, at their base-currency cost
+CREATE TABLE IF NOT EXISTS assembly_items (
+    id VARCHAR(36) PRIMARY KEY,
+    assembly_id VARCHAR(36) NOT NULL REFERENCES assemblies(id) ON DELETE CASCADE,
+    component_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    component_name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    total_cost NUMERIC(19, 4) NOT NULL DEFAULT 0
+);
+
+-- Exchange rates table (base-currency units per one unit of the foreign currency)
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id VARCHAR(36) PRIMARY KEY,
+    currency VARCHAR(3) NOT NULL,
+    rate NUMERIC(19, 8) NOT NULL,
+    rate_date DATE NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (currency, rate_date)
+);
+
+-- Company settings (key/value)
+CREATE TABLE IF NOT EXISTS settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Cost layers: quantity received by each stock-in item at its base-currency unit cost
+CREATE TABLE IF NOT EXISTS cost_layers (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    source_type VARCHAR(20) NOT NULL,
+    source_id VARCHAR(36) NOT NULL,
+    source_item_id VARCHAR(36) NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    quantity INTEGER NOT NULL,
+    remaining_quantity INTEGER NOT NULL,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Running weighted average cost per product
+CREATE TABLE IF NOT EXISTS product_costs (
+    product_id VARCHAR(36) PRIMARY KEY REFERENCES products(id),
+    quantity INTEGER NOT NULL DEFAULT 0,
+    total_value NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    average_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Cost ledger: every receipt, issue and reversal of inventory value
+CREATE TABLE IF NOT EXISTS cost_movements (
+    id VARCHAR(36) PRIMARY KEY,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    movement_type VARCHAR(20) NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    source_id VARCHAR(36) NOT NULL,
+    source_item_id VARCHAR(36) NOT NULL,
+    layer_id VARCHAR(36) REFERENCES cost_layers(id),
+    quantity INTEGER NOT NULL,
+    unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    total_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    average_total NUMERIC(19, 4),
+    method VARCHAR(20) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reversed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Landed costs: freight, duty and other charges added to the cost of received stock
+CREATE TABLE IF NOT EXISTS landed_costs (
+    id VARCHAR(36) PRIMARY KEY,
+    reference_no VARCHAR(100) NOT NULL,
+    charge_type VARCHAR(20) NOT NULL DEFAULT 'other',
+    description TEXT,
+    charge_date TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    allocation_method VARCHAR(20) NOT NULL DEFAULT 'value',
+    amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    exchange_rate NUMERIC(19, 8) NOT NULL DEFAULT 1,
+    base_amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    supplier_id VARCHAR(36) REFERENCES suppliers(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Stock-ins a landed cost is spread over
+CREATE TABLE IF NOT EXISTS landed_cost_stock_ins (
+    landed_cost_id VARCHAR(36) NOT NULL REFERENCES landed_costs(id) ON DELETE CASCADE,
+    stock_in_id VARCHAR(36) NOT NULL REFERENCES stock_ins(id),
+    PRIMARY KEY (landed_cost_id, stock_in_id)
+);
+
+-- Share of a landed cost assigned to each stock-in item, in the base currency
+CREATE TABLE IF NOT EXISTS landed_cost_allocations (
+    id VARCHAR(36) PRIMARY KEY,
+    landed_cost_id VARCHAR(36) NOT NULL REFERENCES landed_costs(id) ON DELETE CASCADE,
+    stock_in_id VARCHAR(36) NOT NULL REFERENCES stock_ins(id),
+    stock_in_item_id VARCHAR(36) NOT NULL REFERENCES stock_in_items(id),
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    basis NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Chart of accounts: the ledger account used for each posting role
+CREATE TABLE IF NOT EXISTS account_mappings (
+    role VARCHAR(30) PRIMARY KEY,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Journal entries generated from completed documents, in the base currency
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id VARCHAR(36) PRIMARY KEY,
+    entry_no VARCHAR(100) NOT NULL UNIQUE,
+    entry_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    source_type VARCHAR(30) NOT NULL,
+    source_id VARCHAR(36) NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    reversal_of VARCHAR(36) REFERENCES journal_entries(id),
+    reversed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Debit and credit lines of a journal entry
+CREATE TABLE IF NOT EXISTS journal_lines (
+    id VARCHAR(36) PRIMARY KEY,
+    entry_id VARCHAR(36) NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    line_no INTEGER NOT NULL,
+    account_role VARCHAR(30) NOT NULL,
+    account_code VARCHAR(50) NOT NULL,
+    account_name VARCHAR(255) NOT NULL,
+    debit NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    credit NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    memo TEXT NOT NULL DEFAULT ''
+);
+
+-- Accounting periods; documents dated inside a closed period are locked
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    note TEXT,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    closed_by VARCHAR(100),
+    snapshot_method VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+-- Stock on hand and its value at the end of a closed period
+CREATE TABLE IF NOT EXISTS period_snapshots (
+    period_id VARCHAR(36) NOT NULL REFERENCES accounting_periods(id) ON DELETE CASCADE,
+    product_id VARCHAR(36) NOT NULL REFERENCES products(id),
+    product_name VARCHAR(255) NOT NULL DEFAULT '',
+    sku VARCHAR(100) NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL DEFAULT 0,
+    value NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    PRIMARY KEY (period_id, product_id)
+);
+
+-- Audit log of changes an administrator allowed inside a closed period
+CREATE TABLE IF NOT EXISTS period_overrides (
+    id VARCHAR(36) PRIMARY KEY,
+    period_id VARCHAR(36) NOT NULL REFERENCES accounting_periods(id),
+    action VARCHAR(20) NOT NULL,
+    source_type VARCHAR(30) NOT NULL DEFAULT '',
+    source_id VARCHAR(36) NOT NULL DEFAULT '',
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    document_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Payments received from customers
+CREATE TABLE IF NOT EXISTS customer_payments (
+    id VARCHAR(36) PRIMARY KEY,
+    payment_no VARCHAR(100) NOT NULL UNIQUE,
+    customer_id VARCHAR(36) NOT NULL REFERENCES customers(id),
+    payment_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    amount NUMERIC(19, 4) NOT NULL,
+    base_amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    payment_method VARCHAR(50),
+    reference VARCHAR(100),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- The part of a customer payment applied to each sale
+CREATE TABLE IF NOT EXISTS payment_allocations (
+    id VARCHAR(36) PRIMARY KEY,
+    payment_id VARCHAR(36) NOT NULL REFERENCES customer_payments(id) ON DELETE CASCADE,
+    sale_id VARCHAR(36) NOT NULL REFERENCES sales(id),
+    amount NUMERIC(19, 4) NOT NULL,
+    base_amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Payments made to suppliers against stock-ins
+CREATE TABLE IF NOT EXISTS stock_in_payments (
+    id VARCHAR(36) PRIMARY KEY,
+    payment_no VARCHAR(100) NOT NULL UNIQUE,
+    stock_in_id VARCHAR(36) NOT NULL REFERENCES stock_ins(id),
+    supplier_id VARCHAR(36) REFERENCES suppliers(id),
+    payment_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    amount NUMERIC(19, 4) NOT NULL,
+    base_amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    payment_method VARCHAR(50),
+    reference VARCHAR(100),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Credit notes from suppliers for returned goods or allowances
+CREATE TABLE IF NOT EXISTS supplier_credits (
+    id VARCHAR(36) PRIMARY KEY,
+    credit_no VARCHAR(100) NOT NULL UNIQUE,
+    stock_in_id VARCHAR(36) NOT NULL REFERENCES stock_ins(id),
+    supplier_id VARCHAR(36) REFERENCES suppliers(id),
+    credit_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    amount NUMERIC(19, 4) NOT NULL,
+    base_amount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Bulk product imports from CSV or XLSX files; errors holds the rows that were not imported
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    file_name VARCHAR(255) NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'xlsx')),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    upsert BOOLEAN NOT NULL DEFAULT FALSE,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    columns TEXT[] NOT NULL DEFAULT '{}',
+    ignored_columns TEXT[] NOT NULL DEFAULT '{}',
+    errors JSONB NOT NULL DEFAULT '[]',
+    stock_in_id VARCHAR(36) REFERENCES stock_ins(id),
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Bring databases created from an earlier version of this schema up to date. CREATE TABLE IF
+-- NOT EXISTS leaves existing tables alone, so columns added since then are added here.
+
+-- Monetary columns were DECIMAL(10, 2)
+ALTER TABLE stock_ins ALTER COLUMN total TYPE NUMERIC(19, 4);
+ALTER TABLE stock_in_items ALTER COLUMN unit_cost TYPE NUMERIC(19, 4),
+    ALTER COLUMN subtotal TYPE NUMERIC(19, 4);
+ALTER TABLE sales ALTER COLUMN total TYPE NUMERIC(19, 4),
+    ALTER COLUMN discount TYPE NUMERIC(19, 4),
+    ALTER COLUMN tax TYPE NUMERIC(19, 4),
+    ALTER COLUMN shipping_fee TYPE NUMERIC(19, 4),
+    ALTER COLUMN grand_total TYPE NUMERIC(19, 4);
+ALTER TABLE sale_items ALTER COLUMN unit_price TYPE NUMERIC(19, 4),
+    ALTER COLUMN subtotal TYPE NUMERIC(19, 4);
+ALTER TABLE rejects ALTER COLUMN total TYPE NUMERIC(19, 4);
+ALTER TABLE reject_items ALTER COLUMN unit_cost TYPE NUMERIC(19, 4),
+    ALTER COLUMN subtotal TYPE NUMERIC(19, 4);
+
+-- Document currencies; documents from before then are in the base currency
+ALTER TABLE stock_ins ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(19, 8) NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS base_total NUMERIC(19, 4) NOT NULL DEFAULT 0;
+ALTER TABLE stock_in_items ADD COLUMN IF NOT EXISTS base_unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS base_subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(19, 8) NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS base_total NUMERIC(19, 4) NOT NULL DEFAULT 0;
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS base_subtotal NUMERIC(19, 4) NOT NULL DEFAULT 0;
+UPDATE stock_ins SET base_total = total WHERE base_total = 0 AND total <> 0;
+UPDATE stock_in_items SET base_unit_cost = unit_cost, base_subtotal = subtotal
+    WHERE base_subtotal = 0 AND subtotal <> 0;
+UPDATE sales SET base_total = total WHERE base_total = 0 AND total <> 0;
+UPDATE sale_items SET base_subtotal = subtotal WHERE base_subtotal = 0 AND subtotal <> 0;
+
+-- Costs of goods sold; movements without an average_total moved the average by total_cost
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS unit_cost NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cogs NUMERIC(19, 4) NOT NULL DEFAULT 0;
+ALTER TABLE reject_items ADD COLUMN IF NOT EXISTS cogs NUMERIC(19, 4) NOT NULL DEFAULT 0;
+ALTER TABLE cost_movements ADD COLUMN IF NOT EXISTS average_total NUMERIC(19, 4);
+
+-- Line tax and discounts; base amounts are rounded to the base currency (IDR, no decimals)
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS tax NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS base_tax NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS base_discount NUMERIC(19, 4) NOT NULL DEFAULT 0;
+UPDATE sale_items si SET base_tax = ROUND(si.tax * s.exchange_rate), base_discount = ROUND(si.discount * s.exchange_rate)
+    FROM sales s
+    WHERE s.id = si.sale_id AND si.base_tax = 0 AND si.base_discount = 0 AND (si.tax <> 0 OR si.discount <> 0);
+
+-- Landed costs added to received items
+ALTER TABLE stock_in_items ADD COLUMN IF NOT EXISTS landed_cost NUMERIC(19, 4) NOT NULL DEFAULT 0;
+
+-- Receivables; existing sales owe their total unless marked paid
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS payment_terms VARCHAR(20) NOT NULL DEFAULT 'cash';
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS due_date TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS paid NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS balance NUMERIC(19, 4) NOT NULL DEFAULT 0;
+UPDATE sales SET paid = CASE WHEN payment_status = 'paid' THEN total ELSE 0 END,
+    balance = CASE WHEN payment_status = 'paid' THEN 0 ELSE total END
+    WHERE paid = 0 AND balance = 0 AND total <> 0;
+
+-- Payables; existing stock-ins owe their total
+ALTER TABLE suppliers ADD COLUMN IF NOT EXISTS payment_terms VARCHAR(20) NOT NULL DEFAULT 'cash';
+ALTER TABLE stock_ins ADD COLUMN IF NOT EXISTS due_date TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS paid NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS credited NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS balance NUMERIC(19, 4) NOT NULL DEFAULT 0;
+UPDATE stock_ins SET balance = total WHERE paid = 0 AND credited = 0 AND balance = 0 AND total <> 0;
+
+-- Tax codes on categories and document lines, and whether document prices include tax
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS tax_code_id VARCHAR(36) REFERENCES tax_codes(id);
+ALTER TABLE stock_ins ADD COLUMN IF NOT EXISTS tax_mode VARCHAR(20) NOT NULL DEFAULT 'exclusive';
+ALTER TABLE stock_in_items ADD COLUMN IF NOT EXISTS tax NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS discount NUMERIC(19, 4) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_code_id VARCHAR(36) REFERENCES tax_codes(id),
+    ADD COLUMN IF NOT EXISTS tax_code VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(7, 4) NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS tax_mode VARCHAR(20) NOT NULL DEFAULT 'exclusive';
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS tax_code_id VARCHAR(36) REFERENCES tax_codes(id),
+    ADD COLUMN IF NOT EXISTS tax_code VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(7, 4) NOT NULL DEFAULT 0;
+
+-- Customer price lists and where each sale line's price came from
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS price_list_id VARCHAR(36) REFERENCES price_lists(id);
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS price_source VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS price_list_id VARCHAR(36) REFERENCES price_lists(id),
+    ADD COLUMN IF NOT EXISTS price_list_code VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS price_min_quantity INTEGER NOT NULL DEFAULT 0;
+
+-- Promotion discounts on sale lines
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS promotion_discount NUMERIC(19, 4) NOT NULL DEFAULT 0;
+
+-- Units of measure on document lines; existing lines are in the base unit
+ALTER TABLE stock_in_items ADD COLUMN IF NOT EXISTS unit VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS unit_quantity INTEGER,
+    ADD COLUMN IF NOT EXISTS unit_factor INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS base_unit VARCHAR(20);
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS unit VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS unit_quantity INTEGER,
+    ADD COLUMN IF NOT EXISTS unit_factor INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS base_unit VARCHAR(20);
+ALTER TABLE reject_items ADD COLUMN IF NOT EXISTS unit VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS unit_quantity INTEGER,
+    ADD COLUMN IF NOT EXISTS unit_factor INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS base_unit VARCHAR(20);
+
+-- Product attributes
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- Product search; adding the generated column fills it for every existing product
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', COALESCE(basic->>'sku', '')), 'A') ||
+    setweight(to_tsvector('indonesian', COALESCE(basic->>'name', '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(basic->>'name', '')), 'A') ||
+    setweight(jsonb_to_tsvector('simple', attributes, '["string"]'), 'B') ||
+    setweight(to_tsvector('indonesian', COALESCE(basic->>'description', '')), 'C') ||
+    setweight(to_tsvector('english', COALESCE(basic->>'description', '')), 'C')
+) STORED;
+
+-- Category images, status and ordering; existing categories are active
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS image_url TEXT,
+    ADD COLUMN IF NOT EXISTS status INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;
+
+-- Uploaded image details; images saved by URL before uploads have none
+ALTER TABLE images ADD COLUMN IF NOT EXISTS thumbnail_url TEXT,
+    ADD COLUMN IF NOT EXISTS medium_url TEXT,
+    ADD COLUMN IF NOT EXISTS content_type VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS size BIGINT,
+    ADD COLUMN IF NOT EXISTS width INTEGER,
+    ADD COLUMN IF NOT EXISTS height INTEGER,
+    ADD COLUMN IF NOT EXISTS storage_keys TEXT[] NOT NULL DEFAULT '{}';
+
+-- Opening stock from product imports, owed to no supplier
+ALTER TABLE stock_ins ADD COLUMN IF NOT EXISTS opening BOOLEAN NOT NULL DEFAULT FALSE;
+
 -- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(child_category_id);
 CREATE INDEX IF NOT EXISTS idx_products_status ON products(((basic->>'status')::integer));
@@ -175,11 +963,103 @@ CREATE INDEX IF NOT EXISTS idx_stock_in_items_stock_in_id ON stock_in_items(stoc
 CREATE INDEX IF NOT EXISTS idx_stock_in_items_product_id ON stock_in_items(product_id);
 CREATE INDEX IF NOT EXISTS idx_sales_customer_id ON sales(customer_id);
 CREATE INDEX IF NOT EXISTS idx_sales_status ON sales(status);
+CREATE INDEX IF NOT EXISTS idx_sales_sale_date ON sales(sale_date);
 CREATE INDEX IF NOT EXISTS idx_sale_items_sale_id ON sale_items(sale_id);
 CREATE INDEX IF NOT EXISTS idx_sale_items_product_id ON sale_items(product_id);
 CREATE INDEX IF NOT EXISTS idx_rejects_status ON rejects(status);
 CREATE INDEX IF NOT EXISTS idx_reject_items_reject_id ON reject_items(reject_id);
 CREATE INDEX IF NOT EXISTS idx_reject_items_product_id ON reject_items(product_id);
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currency_date ON exchange_rates(currency, rate_date DESC);
+CREATE INDEX IF NOT EXISTS idx_cost_layers_open ON cost_layers(product_id, received_at) WHERE remaining_quantity > 0;
+CREATE INDEX IF NOT EXISTS idx_cost_movements_source ON cost_movements(source_type, source_id);
+CREATE INDEX IF NOT EXISTS idx_cost_movements_product_date ON cost_movements(product_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_landed_cost_stock_ins_stock_in_id ON landed_cost_stock_ins(stock_in_id);
+CREATE INDEX IF NOT EXISTS idx_landed_cost_allocations_landed_cost_id ON landed_cost_allocations(landed_cost_id);
+CREATE INDEX IF NOT EXISTS idx_landed_cost_allocations_stock_in_id ON landed_cost_allocations(stock_in_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_entry_date ON journal_entries(entry_date);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_source ON journal_entries(source_type, source_id);
+CREATE INDEX IF NOT EXISTS idx_journal_lines_entry_id ON journal_lines(entry_id);
+CREATE INDEX IF NOT EXISTS idx_accounting_periods_dates ON accounting_periods(start_date, end_date);
+CREATE INDEX IF NOT EXISTS idx_period_overrides_period_id ON period_overrides(period_id);
+CREATE INDEX IF NOT EXISTS idx_sales_due_date ON sales(due_date);
+CREATE INDEX IF NOT EXISTS idx_customer_payments_customer_id ON customer_payments(customer_id);
+CREATE INDEX IF NOT EXISTS idx_payment_allocations_payment_id ON payment_allocations(payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_allocations_sale_id ON payment_allocations(sale_id);
+CREATE INDEX IF NOT EXISTS idx_stock_ins_due_date ON stock_ins(due_date);
+CREATE INDEX IF NOT EXISTS idx_stock_in_payments_stock_in_id ON stock_in_payments(stock_in_id);
+CREATE INDEX IF NOT EXISTS idx_stock_in_payments_supplier_id ON stock_in_payments(supplier_id);
+CREATE INDEX IF NOT EXISTS idx_supplier_credits_stock_in_id ON supplier_credits(stock_in_id);
+CREATE INDEX IF NOT EXISTS idx_supplier_credits_supplier_id ON supplier_credits(supplier_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tax_codes_code ON tax_codes(code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_categories_tax_code_id ON categories(tax_code_id);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id, sort_order);
+CREATE INDEX IF NOT EXISTS idx_sale_items_tax_code_id ON sale_items(tax_code_id);
+CREATE INDEX IF NOT EXISTS idx_stock_in_items_tax_code_id ON stock_in_items(tax_code_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_lists_code ON price_lists(code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_price_list_items_product_id ON price_list_items(product_id);
+CREATE INDEX IF NOT EXISTS idx_customers_price_list_id ON customers(price_list_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_sale_promotions_sale_id ON sale_promotions(sale_id);
+CREATE INDEX IF NOT EXISTS idx_sale_promotions_promotion_id ON sale_promotions(promotion_id);
+CREATE INDEX IF NOT EXISTS idx_price_history_product_id ON price_history(product_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_price_schedules_product_id ON price_schedules(product_id);
+CREATE INDEX IF NOT EXISTS idx_price_schedules_status ON price_schedules(status, starts_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pricing_rules_product ON pricing_rules(product_id) WHERE deleted_at IS NULL AND product_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pricing_rules_category ON pricing_rules(category_id) WHERE deleted_at IS NULL AND category_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_price_suggestions_status ON price_suggestions(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_price_suggestions_product_id ON price_suggestions(product_id);
+CREATE INDEX IF NOT EXISTS idx_price_suggestions_stock_in_id ON price_suggestions(stock_in_id);
+CREATE INDEX IF NOT EXISTS idx_kit_components_component_id ON kit_components(component_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_boms_product_id ON boms(product_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_bom_components_component_id ON bom_components(component_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assemblies_reference_no ON assemblies(reference_no) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_assemblies_product_date ON assemblies(product_id, assembly_date);
+CREATE INDEX IF NOT EXISTS idx_assembly_items_assembly_id ON assembly_items(assembly_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_units_code ON units(code) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_units_base ON product_units(product_id) WHERE factor = 1;
+CREATE INDEX IF NOT EXISTS idx_product_units_unit ON product_units(unit);
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes);
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN ((basic->>'name') gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN ((basic->>'sku') gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_product_options_product_id ON product_options(product_id, sort_order);
+CREATE INDEX IF NOT EXISTS idx_product_option_values_option_id ON product_option_values(option_id, sort_order);
+CREATE INDEX IF NOT EXISTS idx_product_variant_values_value_id ON product_variant_values(value_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_barcodes_code ON product_barcodes(code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_barcodes_primary ON product_barcodes(product_id) WHERE is_primary;
+CREATE INDEX IF NOT EXISTS idx_product_barcodes_product_id ON product_barcodes(product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_label_templates_code ON label_templates(code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_import_jobs_created_at ON import_jobs(created_at DESC);
+
+-- Default tax codes: PPN at 11% and 12%, zero-rated and exempt
+INSERT INTO tax_codes (id, code, name, type, rate) VALUES
+    (gen_random_uuid()::text, 'PPN11', 'PPN 11%', 'standard', 11),
+    (gen_random_uuid()::text, 'PPN12', 'PPN 12%', 'standard', 12),
+    (gen_random_uuid()::text, 'ZERO', 'PPN 0% (zero-rated)', 'zero_rated', 0),
+    (gen_random_uuid()::text, 'EXEMPT', 'Exempt from PPN', 'exempt', 0)
+ON CONFLICT DO NOTHING;
+
+-- Default price lists; RETAIL is used for walk-in sales (see the default_price_list setting)
+INSERT INTO price_lists (id, code, name) VALUES
+    (gen_random_uuid()::text, 'RETAIL', 'Retail'),
+    (gen_random_uuid()::text, 'RESELLER', 'Reseller'),
+    (gen_random_uuid()::text, 'WHOLESALE', 'Wholesale')
+ON CONFLICT DO NOTHING;
+
+-- Default units of measure; products without units are counted in pcs
+INSERT INTO units (id, code, name) VALUES
+    (gen_random_uuid()::text, 'pcs', 'Pieces'),
+    (gen_random_uuid()::text, 'box', 'Box'),
+    (gen_random_uuid()::text, 'carton', 'Carton'),
+    (gen_random_uuid()::text, 'kg', 'Kilogram')
+ON CONFLICT DO NOTHING;
+
+-- Common sticker layouts: A4 sheets of 33 and 65 labels, and 50 x 30 mm thermal rolls
+INSERT INTO label_templates (id, code, name, page_width, page_height, columns, rows, label_width, label_height, margin_top, margin_left, gap_x, gap_y) VALUES
+    (gen_random_uuid()::text, 'a4-33', 'A4 33 labels (70 x 25.4 mm)', 210, 297, 3, 11, 70, 25.4, 8.8, 0, 0, 0),
+    (gen_random_uuid()::text, 'a4-65', 'A4 65 labels (38.1 x 21.2 mm)', 210, 297, 5, 13, 38.1, 21.2, 10.7, 4.75, 2.5, 0),
+    (gen_random_uuid()::text, 'thermal-50x30', 'Thermal 50 x 30 mm', 50, 30, 1, 1, 50, 30, 0, 0, 0, 0)
+ON CONFLICT DO NOTHING;
 
 -- Create triggers for updating the timestamps
 CREATE OR REPLACE FUNCTION update_timestamp()
@@ -233,6 +1113,74 @@ CREATE TRIGGER trigger_update_reject_items_timestamp
 BEFORE UPDATE ON reject_items
 FOR EACH ROW EXECUTE FUNCTION update_timestamp();
 
+CREATE TRIGGER trigger_update_exchange_rates_timestamp
+BEFORE UPDATE ON exchange_rates
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_landed_costs_timestamp
+BEFORE UPDATE ON landed_costs
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_accounting_periods_timestamp
+BEFORE UPDATE ON accounting_periods
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_tax_codes_timestamp
+BEFORE UPDATE ON tax_codes
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_price_lists_timestamp
+BEFORE UPDATE ON price_lists
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_promotions_timestamp
+BEFORE UPDATE ON promotions
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_price_schedules_timestamp
+BEFORE UPDATE ON price_schedules
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_pricing_rules_timestamp
+BEFORE UPDATE ON pricing_rules
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_price_suggestions_timestamp
+BEFORE UPDATE ON price_suggestions
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_product_kits_timestamp
+BEFORE UPDATE ON product_kits
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_assemblies_timestamp
+BEFORE UPDATE ON assemblies
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_boms_timestamp
+BEFORE UPDATE ON boms
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_units_timestamp
+BEFORE UPDATE ON units
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_product_options_timestamp
+BEFORE UPDATE ON product_options
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_product_barcodes_timestamp
+BEFORE UPDATE ON product_barcodes
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_label_templates_timestamp
+BEFORE UPDATE ON label_templates
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+CREATE TRIGGER trigger_update_import_jobs_timestamp
+BEFORE UPDATE ON import_jobs
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
 -- Create a function to generate UUIDs for VARCHAR(36) ID columns
 CREATE OR REPLACE FUNCTION generate_uuid_for_id()
 RETURNS TRIGGER AS $$
@@ -289,6 +1237,98 @@ CREATE TRIGGER trigger_reject_items_generate_uuid
 BEFORE INSERT ON reject_items
 FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
 
+CREATE TRIGGER trigger_exchange_rates_generate_uuid
+BEFORE INSERT ON exchange_rates
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_landed_costs_generate_uuid
+BEFORE INSERT ON landed_costs
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_accounting_periods_generate_uuid
+BEFORE INSERT ON accounting_periods
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_tax_codes_generate_uuid
+BEFORE INSERT ON tax_codes
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_price_lists_generate_uuid
+BEFORE INSERT ON price_lists
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_price_list_items_generate_uuid
+BEFORE INSERT ON price_list_items
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_promotions_generate_uuid
+BEFORE INSERT ON promotions
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_sale_promotions_generate_uuid
+BEFORE INSERT ON sale_promotions
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_price_schedules_generate_uuid
+BEFORE INSERT ON price_schedules
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_price_history_generate_uuid
+BEFORE INSERT ON price_history
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_pricing_rules_generate_uuid
+BEFORE INSERT ON pricing_rules
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_price_suggestions_generate_uuid
+BEFORE INSERT ON price_suggestions
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_kit_components_generate_uuid
+BEFORE INSERT ON kit_components
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_assemblies_generate_uuid
+BEFORE INSERT ON assemblies
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_assembly_items_generate_uuid
+BEFORE INSERT ON assembly_items
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_boms_generate_uuid
+BEFORE INSERT ON boms
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_bom_components_generate_uuid
+BEFORE INSERT ON bom_components
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_units_generate_uuid
+BEFORE INSERT ON units
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_product_options_generate_uuid
+BEFORE INSERT ON product_options
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_product_option_values_generate_uuid
+BEFORE INSERT ON product_option_values
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_product_barcodes_generate_uuid
+BEFORE INSERT ON product_barcodes
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_label_templates_generate_uuid
+BEFORE INSERT ON label_templates
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
+CREATE TRIGGER trigger_import_jobs_generate_uuid
+BEFORE INSERT ON import_jobs
+FOR EACH ROW EXECUTE FUNCTION generate_uuid_for_id();
+
 -- Add trigger for updating product quantity after stock-in
 CREATE OR REPLACE FUNCTION update_product_quantity_after_stock_in()
 RETURNS TRIGGER AS $$
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"inventory-go/accounting"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// AccountingHandler handles the chart of accounts and journal entries
+type AccountingHandler struct {
+	*BaseHandler
+	repo repositories.JournalRepository
+}
+
+// NewAccountingHandler creates a new AccountingHandler
+func NewAccountingHandler(db *pgx.Conn) *AccountingHandler {
+	return &AccountingHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewJournalRepository(db),
+	}
+}
+
+// GetAccounts handles GET /accounting/accounts
+func (h *AccountingHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.repo.GetAccounts()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get accounts: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, accounts)
+}
+
+// UpdateAccount handles PUT /accounting/accounts/{role}
+func (h *AccountingHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var payload struct {
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	account := models.Account{Role: models.AccountRole(vars["role"]), Code: payload.Code, Name: payload.Name}
+	if err := account.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.SetAccount(&account); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update account: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, account)
+}
+
+// GetJournals handles GET /accounting/journals
+func (h *AccountingHandler) GetJournals(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	filter, ok := parseJournalFilter(w, r)
+	if !ok {
+		return
+	}
+
+	entries, total, err := h.repo.List(filter, offset, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get journal entries: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"data": entries,
+		"pagination": map[string]interface{}{
+			"total":  total,
+			"page":   page,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// ExportJournals handles GET /accounting/journals/export?format=generic_csv
+func (h *AccountingHandler) ExportJournals(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = accounting.DefaultFormat
+	}
+	exporter, err := accounting.Get(format)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("%s (available: %v)", err.Error(), accounting.Formats()))
+		return
+	}
+
+	filter, ok := parseJournalFilter(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.repo.Export(filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get journal entries: "+err.Error())
+		return
+	}
+
+	// Render the whole file first so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, entries); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export journal entries: "+err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("journals-%s.%s", time.Now().Format("20060102"), exporter.FileExtension())
+	w.Header().Set("Content-Type", exporter.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// parseJournalFilter reads the date range and source filters of a journal request,
+// writing a 400 response and returning false when a date is invalid
+func parseJournalFilter(w http.ResponseWriter, r *http.Request) (models.JournalFilter, bool) {
+	filter := models.JournalFilter{
+		SourceType: r.URL.Query().Get("source_type"),
+		SourceID:   r.URL.Query().Get("source_id"),
+	}
+
+	if sd := r.URL.Query().Get("start_date"); sd != "" {
+		t, err := time.Parse("2006-01-02", sd)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start date format (use YYYY-MM-DD)")
+			return filter, false
+		}
+		filter.StartDate = &t
+	}
+
+	if ed := r.URL.Query().Get("end_date"); ed != "" {
+		t, err := time.Parse("2006-01-02", ed)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end date format (use YYYY-MM-DD)")
+			return filter, false
+		}
+		// Set to end of day
+		t = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		filter.EndDate = &t
+	}
+
+	return filter, true
+}
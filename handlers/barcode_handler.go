@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/printing"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// BarcodeHandler handles the barcodes of products and variants, scanner lookups and barcode images
+type BarcodeHandler struct {
+	*BaseHandler
+	repo        repositories.BarcodeRepository
+	productRepo repositories.ProductRepository
+}
+
+// NewBarcodeHandler creates a new BarcodeHandler
+func NewBarcodeHandler(db *pgx.Conn) *BarcodeHandler {
+	return &BarcodeHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewBarcodeRepository(db),
+		productRepo: repositories.NewProductRepository(db),
+	}
+}
+
+// barcodeLookup is the product a scanned code belongs to and the barcode it matched
+type barcodeLookup struct {
+	Product   *models.Product `json:"product"`
+	Barcode   *models.Barcode `json:"barcode,omitempty"`
+	MatchedBy string          `json:"matched_by"`
+}
+
+// GetProductByBarcode handles GET /products/barcode/{code} for scanners. The code is matched
+// against barcodes, as either EAN-13 or UPC-A, and then against SKUs.
+func (h *BarcodeHandler) GetProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimSpace(mux.Vars(r)["code"])
+
+	match, err := h.repo.FindByCode(code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to find barcode: "+err.Error())
+		return
+	}
+	if match == nil {
+		respondWithError(w, http.StatusNotFound, "No product has barcode "+code)
+		return
+	}
+
+	product, err := h.productRepo.GetByID(match.ProductID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return
+	}
+
+	lookup := barcodeLookup{Product: product, Barcode: match.Barcode, MatchedBy: "barcode"}
+	if match.Barcode == nil {
+		lookup.MatchedBy = "sku"
+	}
+	respondWithJSON(w, http.StatusOK, lookup)
+}
+
+// GetProductBarcodes handles GET /products/{id}/barcodes
+func (h *BarcodeHandler) GetProductBarcodes(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.productExists(w, productID) {
+		return
+	}
+
+	barcodes, err := h.repo.GetByProduct(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get barcodes: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, barcodes)
+}
+
+// AddProductBarcode handles POST /products/{id}/barcodes. Without a code, the next internal
+// EAN-13 code is assigned.
+func (h *BarcodeHandler) AddProductBarcode(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.productExists(w, productID) {
+		return
+	}
+
+	var barcode models.Barcode
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&barcode); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	if strings.TrimSpace(barcode.Code) == "" {
+		generated, err := h.repo.GenerateInternal(productID, barcode.IsPrimary)
+		if err != nil {
+			respondWithBarcodeError(w, "Failed to generate barcode: ", err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, generated)
+		return
+	}
+
+	barcode.ID = ""
+	barcode.ProductID = productID
+	if err := barcode.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.Add(&barcode); err != nil {
+		respondWithBarcodeError(w, "Failed to add barcode: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, barcode)
+}
+
+// DeleteProductBarcode handles DELETE /products/{id}/barcodes/{barcodeId}
+func (h *BarcodeHandler) DeleteProductBarcode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.repo.Delete(vars["id"], vars["barcodeId"]); err != nil {
+		respondWithBarcodeError(w, "Failed to delete barcode: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Barcode deleted successfully"})
+}
+
+// GenerateMissingBarcodes handles POST /barcodes/generate, assigning an internal EAN-13 code
+// to every product and variant without a barcode
+func (h *BarcodeHandler) GenerateMissingBarcodes(w http.ResponseWriter, r *http.Request) {
+	created, err := h.repo.GenerateMissing()
+	if err != nil {
+		respondWithBarcodeError(w, "Failed to generate barcodes: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{"created": created})
+}
+
+// RenderBarcode handles GET /barcodes/{code}/image?format=png|svg&symbology=...&module_width=2&height=80
+func (h *BarcodeHandler) RenderBarcode(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimSpace(mux.Vars(r)["code"])
+	q := r.URL.Query()
+
+	symbology := strings.ToLower(q.Get("symbology"))
+	if symbology == "" {
+		symbology = models.DetectSymbology(code)
+	}
+	format := strings.ToLower(q.Get("format"))
+	if format == "" {
+		format = printing.FormatPNG
+	}
+	if format != printing.FormatPNG && format != printing.FormatSVG {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("format must be %s or %s", printing.FormatPNG, printing.FormatSVG))
+		return
+	}
+
+	moduleWidth, ok := parseSizeParam(w, r, "module_width", 2, 1, 10)
+	if !ok {
+		return
+	}
+	height, ok := parseSizeParam(w, r, "height", 80, 10, 1000)
+	if !ok {
+		return
+	}
+
+	symbol, err := printing.Encode(code, symbology)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Render the whole image first so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == printing.FormatSVG {
+		contentType = "image/svg+xml"
+		err = symbol.WriteSVG(&buf, moduleWidth, height)
+	} else {
+		err = symbol.WritePNG(&buf, moduleWidth, height)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render barcode: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// productExists writes a 404 when the product does not exist. When it returns false the
+// error response has been written.
+func (h *BarcodeHandler) productExists(w http.ResponseWriter, productID string) bool {
+	product, err := h.productRepo.GetByID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return false
+	}
+	if product == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return false
+	}
+	return true
+}
+
+// parseSizeParam reads an optional whole number between lo and hi from the query string.
+// When it returns false the error response has been written.
+func parseSizeParam(w http.ResponseWriter, r *http.Request, name string, def, lo, hi int) (int, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("%s must be a whole number from %d to %d", name, lo, hi))
+		return 0, false
+	}
+	return n, true
+}
+
+// respondWithBarcodeError maps a missing barcode to 404 and a code already assigned to 409
+func respondWithBarcodeError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrBarcodeNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repositories.ErrDuplicateBarcode):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
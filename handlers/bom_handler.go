@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// BOMHandler handles bills of materials for products made from other products
+type BOMHandler struct {
+	*BaseHandler
+	repo        repositories.BOMRepository
+	kitRepo     repositories.KitRepository
+	productRepo repositories.ProductRepository
+}
+
+// NewBOMHandler creates a new BOMHandler
+func NewBOMHandler(db *pgx.Conn) *BOMHandler {
+	return &BOMHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewBOMRepository(db),
+		kitRepo:     repositories.NewKitRepository(db),
+		productRepo: repositories.NewProductRepository(db),
+	}
+}
+
+// GetBOMs handles GET /boms
+func (h *BOMHandler) GetBOMs(w http.ResponseWriter, r *http.Request) {
+	boms, err := h.repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get bills of materials: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, boms)
+}
+
+// GetProductBOM handles GET /products/{id}/bom
+func (h *BOMHandler) GetProductBOM(w http.ResponseWriter, r *http.Request) {
+	bom, err := h.repo.GetByProductID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get bill of materials: "+err.Error())
+		return
+	}
+	if bom == nil {
+		respondWithError(w, http.StatusNotFound, "Product has no bill of materials")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, bom)
+}
+
+// SaveProductBOM handles PUT /products/{id}/bom, creating or replacing the product's bill of materials
+func (h *BOMHandler) SaveProductBOM(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+
+	product, err := h.productRepo.GetByID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return
+	}
+	if product == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	kit, err := h.kitRepo.GetByProductID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get kit: "+err.Error())
+		return
+	}
+	if kit != nil {
+		respondWithError(w, http.StatusConflict, "Product is a kit; delete the kit before giving it a bill of materials")
+		return
+	}
+
+	var bom models.BillOfMaterials
+	if err := json.NewDecoder(r.Body).Decode(&bom); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	bom.ProductID = productID
+	if err := bom.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.Save(&bom); err != nil {
+		if errors.Is(err, repositories.ErrInvalidBOM) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to save bill of materials: "+err.Error())
+		return
+	}
+
+	saved, err := h.repo.GetByProductID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get bill of materials: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// DeleteProductBOM handles DELETE /products/{id}/bom. Assemblies already made are kept.
+func (h *BOMHandler) DeleteProductBOM(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+
+	bom, err := h.repo.GetByProductID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get bill of materials: "+err.Error())
+		return
+	}
+	if bom == nil {
+		respondWithError(w, http.StatusNotFound, "Product has no bill of materials")
+		return
+	}
+
+	if err := h.repo.Delete(productID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete bill of materials: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Bill of materials deleted successfully"})
+}
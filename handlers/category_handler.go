@@ -3,10 +3,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"inventory-go/models"
 	"inventory-go/repositories"
 
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -95,7 +97,7 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 	defer r.Body.Close()
 
 	if err := h.repo.Create(&category); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create category: "+err.Error())
+		respondWithCategoryError(w, "Failed to create category: ", err)
 		return
 	}
 
@@ -140,7 +142,7 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	updatedCategory.ID = id
 
 	if err := h.repo.Update(&updatedCategory); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update category: "+err.Error())
+		respondWithCategoryError(w, "Failed to update category: ", err)
 		return
 	}
 
@@ -155,7 +157,8 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	respondWithJSON(w, http.StatusOK, updatedCategory)
 }
 
-// Delete deletes a category
+// Delete deletes a category. With ?reassign_to=<id> its subcategories and products move to
+// that category; without it a category that has any is not deleted.
 func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -172,8 +175,8 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete category: "+err.Error())
+	if err := h.repo.Delete(id, r.URL.Query().Get("reassign_to")); err != nil {
+		respondWithCategoryError(w, "Failed to delete category: ", err)
 		return
 	}
 
@@ -212,3 +215,88 @@ func (h *CategoryHandler) GetBreadcrumbs(w http.ResponseWriter, r *http.Request)
 
 	respondWithJSON(w, http.StatusOK, breadcrumbs)
 }
+
+// GetCategoryTree returns every category as a tree with its product counts
+func (h *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.repo.GetTree()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch category tree: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tree)
+}
+
+// MoveCategory moves a category, with its subcategories, under another parent
+func (h *CategoryHandler) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var move models.CategoryMove
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.repo.Move(id, move); err != nil {
+		respondWithCategoryError(w, "Failed to move category: ", err)
+		return
+	}
+
+	category, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch category: "+err.Error())
+		return
+	}
+	if category != nil {
+		if category.Breadcrumbs, err = h.repo.GetBreadcrumbs(id); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch breadcrumbs: "+err.Error())
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, category)
+}
+
+// ReorderCategories sets the order of the subcategories of a parent
+func (h *CategoryHandler) ReorderCategories(w http.ResponseWriter, r *http.Request) {
+	var reorder models.CategoryReorder
+	if err := json.NewDecoder(r.Body).Decode(&reorder); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if len(reorder.CategoryIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "category_ids is required")
+		return
+	}
+
+	if err := h.repo.Reorder(reorder); err != nil {
+		respondWithCategoryError(w, "Failed to reorder categories: ", err)
+		return
+	}
+
+	categories, err := h.repo.GetByParentID(reorder.ParentID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch categories: "+err.Error())
+		return
+	}
+	sort.SliceStable(categories, func(i, j int) bool { return categories[i].SortOrder < categories[j].SortOrder })
+
+	respondWithJSON(w, http.StatusOK, categories)
+}
+
+// respondWithCategoryError maps a missing category, a cycle or a bad order to 400 and a
+// category still in use to 409
+func respondWithCategoryError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrCategoryNotFound), errors.Is(err, repositories.ErrCategoryCycle),
+		errors.Is(err, repositories.ErrInvalidCategoryOrder):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrCategoryInUse):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
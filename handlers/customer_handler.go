@@ -14,14 +14,16 @@ import (
 // CustomerHandler handles customer-related operations
 type CustomerHandler struct {
 	*BaseHandler
-	repo repositories.CustomerRepository
+	repo          repositories.CustomerRepository
+	priceListRepo repositories.PriceListRepository
 }
 
 // NewCustomerHandler creates a new CustomerHandler
 func NewCustomerHandler(db *pgx.Conn) *CustomerHandler {
 	return &CustomerHandler{
-		BaseHandler: &BaseHandler{DB: db},
-		repo:        repositories.NewCustomerRepository(db),
+		BaseHandler:   &BaseHandler{DB: db},
+		repo:          repositories.NewCustomerRepository(db),
+		priceListRepo: repositories.NewPriceListRepository(db),
 	}
 }
 
@@ -60,6 +62,17 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if customer.PaymentTerms == "" {
+		customer.PaymentTerms = models.PaymentTermsCash
+	}
+	if !customer.PaymentTerms.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment_terms (use cash, net_7, net_14 or net_30)")
+		return
+	}
+	if !h.checkPriceList(w, &customer) {
+		return
+	}
+
 	// Check if email already exists
 	if customer.Email != "" {
 		existing, _ := h.repo.GetByEmail(customer.Email)
@@ -114,6 +127,21 @@ func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request)
 	existing.Phone = customer.Phone
 	existing.Address = customer.Address
 	existing.Notes = customer.Notes
+	if customer.PaymentTerms != "" {
+		if !customer.PaymentTerms.IsValid() {
+			respondWithError(w, http.StatusBadRequest, "Invalid payment_terms (use cash, net_7, net_14 or net_30)")
+			return
+		}
+		existing.PaymentTerms = customer.PaymentTerms
+	}
+
+	// Keep the existing price list unless one is given; an empty ID removes it
+	if customer.PriceListID != nil {
+		if !h.checkPriceList(w, &customer) {
+			return
+		}
+		existing.PriceListID = customer.PriceListID
+	}
 
 	if err := h.repo.Update(existing); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
@@ -178,3 +206,23 @@ func (h *CustomerHandler) GetTopCustomers(w http.ResponseWriter, r *http.Request
 
 	respondWithJSON(w, http.StatusOK, customers)
 }
+
+// checkPriceList clears an empty price list ID and responds with 400 and returns false when
+// the customer's price list does not exist
+func (h *CustomerHandler) checkPriceList(w http.ResponseWriter, customer *models.Customer) bool {
+	if customer.PriceListID == nil || *customer.PriceListID == "" {
+		customer.PriceListID = nil
+		return true
+	}
+
+	list, err := h.priceListRepo.GetByID(*customer.PriceListID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price list: "+err.Error())
+		return false
+	}
+	if list == nil {
+		respondWithError(w, http.StatusBadRequest, "Price list not found")
+		return false
+	}
+	return true
+}
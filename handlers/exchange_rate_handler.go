@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"inventory-go/repositories"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxRateImportSize limits the size of an uploaded exchange rate CSV
+const maxRateImportSize = 5 << 20
+
+// errNoExchangeRate is returned when a foreign-currency document has no rate to use
+var errNoExchangeRate = errors.New("no exchange rate")
+
+// ExchangeRateHandler handles exchange rate operations
+type ExchangeRateHandler struct {
+	*BaseHandler
+	repo repositories.ExchangeRateRepository
+}
+
+// NewExchangeRateHandler creates a new ExchangeRateHandler
+func NewExchangeRateHandler(db *pgx.Conn) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewExchangeRateRepository(db),
+	}
+}
+
+// exchangeRateRequest is the payload for creating an exchange rate.
+// The rate date is given as YYYY-MM-DD.
+type exchangeRateRequest struct {
+	Currency string     `json:"currency"`
+	Rate     money.Rate `json:"rate"`
+	RateDate string     `json:"rate_date"`
+	Note     string     `json:"note"`
+}
+
+// GetExchangeRates handles GET /exchange-rates
+func (h *ExchangeRateHandler) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	currency := r.URL.Query().Get("currency")
+
+	var startDate, endDate *time.Time
+	if sd := r.URL.Query().Get("start_date"); sd != "" {
+		t, err := time.Parse("2006-01-02", sd)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start date format (use YYYY-MM-DD)")
+			return
+		}
+		startDate = &t
+	}
+
+	if ed := r.URL.Query().Get("end_date"); ed != "" {
+		t, err := time.Parse("2006-01-02", ed)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end date format (use YYYY-MM-DD)")
+			return
+		}
+		endDate = &t
+	}
+
+	rates, err := h.repo.List(currency, startDate, endDate)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get exchange rates: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rates)
+}
+
+// GetExchangeRate handles GET /exchange-rates/{id}
+func (h *ExchangeRateHandler) GetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	rate, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get exchange rate: "+err.Error())
+		return
+	}
+
+	if rate == nil {
+		respondWithError(w, http.StatusNotFound, "Exchange rate not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rate)
+}
+
+// GetLatestExchangeRate handles GET /exchange-rates/latest?currency=USD&date=2025-01-31
+// and returns the rate in effect for the currency on the given date (default today)
+func (h *ExchangeRateHandler) GetLatestExchangeRate(w http.ResponseWriter, r *http.Request) {
+	currency := money.NormalizeCurrency(r.URL.Query().Get("currency"))
+
+	date := time.Now()
+	if d := r.URL.Query().Get("date"); d != "" {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)")
+			return
+		}
+		date = t
+	}
+
+	if currency == money.BaseCurrency {
+		rate := models.NewExchangeRate()
+		rate.ID = ""
+		rate.Currency = currency
+		rate.Rate = money.OneRate()
+		rate.RateDate = date
+		respondWithJSON(w, http.StatusOK, rate)
+		return
+	}
+
+	rate, err := h.repo.GetRateOn(currency, date)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get exchange rate: "+err.Error())
+		return
+	}
+
+	if rate == nil {
+		respondWithError(w, http.StatusNotFound, "No exchange rate found for "+currency+" on or before "+date.Format("2006-01-02"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rate)
+}
+
+// CreateExchangeRate handles POST /exchange-rates. Posting a rate for a
+// currency and date that already has one replaces it.
+func (h *ExchangeRateHandler) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var req exchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	rate := models.NewExchangeRate()
+	rate.Currency = req.Currency
+	rate.Rate = req.Rate
+	rate.Note = req.Note
+
+	if req.RateDate != "" {
+		t, err := time.Parse("2006-01-02", req.RateDate)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid rate_date format (use YYYY-MM-DD)")
+			return
+		}
+		rate.RateDate = t
+	}
+
+	if err := rate.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.Create(rate); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create exchange rate: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, rate)
+}
+
+// ImportExchangeRates handles POST /exchange-rates/import. The CSV can be sent
+// as a multipart "file" field or as the raw request body.
+func (h *ExchangeRateHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRateImportSize)
+	defer r.Body.Close()
+
+	var src io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "CSV file is required in the \"file\" field")
+			return
+		}
+		defer file.Close()
+		src = file
+	}
+
+	rates, rowErrors, err := models.ParseExchangeRatesCSV(src)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	imported := 0
+	if len(rates) > 0 {
+		imported, err = h.repo.Import(rates)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to import exchange rates: "+err.Error())
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, models.ExchangeRateImportResult{
+		Imported: imported,
+		Errors:   rowErrors,
+	})
+}
+
+// DeleteExchangeRate handles DELETE /exchange-rates/{id}
+func (h *ExchangeRateHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	rate, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get exchange rate: "+err.Error())
+		return
+	}
+	if rate == nil {
+		respondWithError(w, http.StatusNotFound, "Exchange rate not found")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete exchange rate: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Exchange rate deleted successfully"})
+}
+
+// resolveExchangeRate returns the rate to store on a document. A rate given in
+// the request wins; otherwise the rate in effect on the document date is used.
+func resolveExchangeRate(repo repositories.ExchangeRateRepository, currency string, date time.Time, given money.Rate) (money.Rate, error) {
+	currency = money.NormalizeCurrency(currency)
+	if currency == money.BaseCurrency {
+		return money.OneRate(), nil
+	}
+	if given.IsPositive() {
+		return given, nil
+	}
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	rate, err := repo.GetRateOn(currency, date)
+	if err != nil {
+		return money.Rate{}, err
+	}
+	if rate == nil {
+		return money.Rate{}, fmt.Errorf("%w for %s on or before %s", errNoExchangeRate, currency, date.Format("2006-01-02"))
+	}
+	return rate.Rate, nil
+}
+
+// respondWithRateError writes the response for a failed exchange rate lookup
+func respondWithRateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNoExchangeRate) {
+		respondWithError(w, http.StatusBadRequest, err.Error()+"; provide exchange_rate or add a rate first")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "Failed to get exchange rate: "+err.Error())
+}
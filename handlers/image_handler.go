@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/media"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"inventory-go/storage"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// ImageHandler handles uploading, ordering and removing product images
+type ImageHandler struct {
+	*BaseHandler
+	productRepo repositories.ProductRepository
+	store       storage.BlobStore
+}
+
+// NewImageHandler creates a new ImageHandler keeping image files in store
+func NewImageHandler(db *pgx.Conn, store storage.BlobStore) *ImageHandler {
+	return &ImageHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		productRepo: repositories.NewProductRepository(db),
+		store:       store,
+	}
+}
+
+// GetProductImages handles GET /products/{id}/images
+func (h *ImageHandler) GetProductImages(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.productExists(w, productID) {
+		return
+	}
+
+	images, err := h.productRepo.GetImages(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get images: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, images)
+}
+
+// UploadProductImage handles POST /products/{id}/images, a multipart form with the file in
+// "image" and optionally is_primary=true. The original is stored with thumbnail and medium
+// copies.
+func (h *ImageHandler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.productExists(w, productID) {
+		return
+	}
+
+	// Leave room for the other form fields and the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, models.MaxImageSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("image cannot be larger than %d MB", models.MaxImageSize>>20))
+		} else {
+			respondWithError(w, http.StatusBadRequest, "Invalid multipart form: "+err.Error())
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "image file is required")
+		return
+	}
+	defer file.Close()
+	if header.Size > models.MaxImageSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("image cannot be larger than %d MB", models.MaxImageSize>>20))
+		return
+	}
+
+	isPrimary := false
+	if s := r.FormValue("is_primary"); s != "" {
+		if isPrimary, err = strconv.ParseBool(s); err != nil {
+			respondWithError(w, http.StatusBadRequest, "is_primary must be true or false")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read image: "+err.Error())
+		return
+	}
+	processed, err := media.Process(data)
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupportedImage) {
+			respondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+		} else {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	image := models.Images{
+		ID:          uuid.NewString(),
+		ContentType: processed.Original.ContentType,
+		Size:        int64(len(data)),
+		Width:       processed.Width,
+		Height:      processed.Height,
+		IsPrimary:   isPrimary,
+	}
+	if !h.storeImage(r.Context(), w, productID, &image, processed) {
+		return
+	}
+
+	if err := h.productRepo.AddImage(productID, &image); err != nil {
+		removeImageFiles(h.store, image.StorageKeys)
+		respondWithImageError(w, "Failed to add image: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, image)
+}
+
+// DeleteProductImage handles DELETE /products/{id}/images/{imageId}, removing its files too
+func (h *ImageHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	image, err := h.productRepo.GetImage(vars["id"], vars["imageId"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get image: "+err.Error())
+		return
+	}
+	if image == nil {
+		respondWithError(w, http.StatusNotFound, "Image not found")
+		return
+	}
+
+	if err := h.productRepo.RemoveImage(vars["id"], vars["imageId"]); err != nil {
+		respondWithImageError(w, "Failed to delete image: ", err)
+		return
+	}
+	removeImageFiles(h.store, image.StorageKeys)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Image deleted successfully"})
+}
+
+// SetPrimaryProductImage handles PUT /products/{id}/images/{imageId}/primary
+func (h *ImageHandler) SetPrimaryProductImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.productRepo.SetPrimaryImage(vars["id"], vars["imageId"]); err != nil {
+		respondWithImageError(w, "Failed to set primary image: ", err)
+		return
+	}
+
+	images, err := h.productRepo.GetImages(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get images: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, images)
+}
+
+// ReorderProductImages handles PUT /products/{id}/images/reorder
+func (h *ImageHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.productExists(w, productID) {
+		return
+	}
+
+	var reorder models.ImageReorder
+	if err := json.NewDecoder(r.Body).Decode(&reorder); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := reorder.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.productRepo.ReorderImages(productID, reorder.ImageIDs); err != nil {
+		respondWithImageError(w, "Failed to reorder images: ", err)
+		return
+	}
+
+	images, err := h.productRepo.GetImages(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get images: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, images)
+}
+
+// storeImage saves the original and its copies under products/{productId}/{imageId}/ and
+// records their URLs and keys on the image. If one fails, the files already saved are removed
+// again. When it returns false the error response has been written.
+func (h *ImageHandler) storeImage(ctx context.Context, w http.ResponseWriter, productID string, image *models.Images, processed *media.Image) bool {
+	renditions := []struct {
+		name      string
+		rendition media.Rendition
+		url       *string
+	}{
+		{"original", processed.Original, &image.URL},
+		{"thumbnail", processed.Thumbnail, &image.ThumbnailURL},
+		{"medium", processed.Medium, &image.MediumURL},
+	}
+
+	for _, r := range renditions {
+		key := fmt.Sprintf("products/%s/%s/%s.%s", productID, image.ID, r.name, r.rendition.Extension)
+		url, err := h.store.Put(ctx, key, bytes.NewReader(r.rendition.Data), int64(len(r.rendition.Data)), r.rendition.ContentType)
+		if err != nil {
+			removeImageFiles(h.store, image.StorageKeys)
+			respondWithError(w, http.StatusInternalServerError, "Failed to store image: "+err.Error())
+			return false
+		}
+		*r.url = url
+		image.StorageKeys = append(image.StorageKeys, key)
+	}
+	return true
+}
+
+// productExists writes a 404 when the product does not exist. When it returns false the
+// error response has been written.
+func (h *ImageHandler) productExists(w http.ResponseWriter, productID string) bool {
+	product, err := h.productRepo.GetByID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return false
+	}
+	if product == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return false
+	}
+	return true
+}
+
+// removeImageFiles deletes stored image files. It runs once the images are gone from the
+// database, so files that cannot be deleted are only logged.
+func removeImageFiles(store storage.BlobStore, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete image file %s: %v", key, err)
+		}
+	}
+}
+
+// respondWithImageError maps a missing image to 404, a bad order to 400 and a full gallery to 409
+func respondWithImageError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrImageNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, repositories.ErrInvalidImageOrder):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrTooManyImages):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
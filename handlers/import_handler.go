@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"inventory-go/importer"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportHandler handles bulk product imports from CSV and XLSX files
+type ImportHandler struct {
+	*BaseHandler
+	repo   repositories.ImportRepository
+	runner *importer.Runner
+}
+
+// NewImportHandler creates a new ImportHandler
+func NewImportHandler(db *pgx.Conn) *ImportHandler {
+	return &ImportHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewImportRepository(db),
+		runner:      importer.NewRunner(db),
+	}
+}
+
+// CreateImport handles POST /imports, a multipart form with the CSV or XLSX file in "file"
+// and optionally dry_run=true and upsert=true. The file is checked for a usable header and
+// the job is queued; rows are validated and imported in the background.
+func (h *ImportHandler) CreateImport(w http.ResponseWriter, r *http.Request) {
+	// Leave room for the other form fields and the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, models.MaxImportSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file cannot be larger than %d MB", models.MaxImportSize>>20))
+		} else {
+			respondWithError(w, http.StatusBadRequest, "Invalid multipart form: "+err.Error())
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+	if header.Size > models.MaxImportSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file cannot be larger than %d MB", models.MaxImportSize>>20))
+		return
+	}
+
+	format, err := importer.Format(header.Filename)
+	if err != nil {
+		respondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	job := models.ImportJob{FileName: filepath.Base(header.Filename), Format: format}
+	if s := r.FormValue("dry_run"); s != "" {
+		if job.DryRun, err = strconv.ParseBool(s); err != nil {
+			respondWithError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+	if s := r.FormValue("upsert"); s != "" {
+		if job.Upsert, err = strconv.ParseBool(s); err != nil {
+			respondWithError(w, http.StatusBadRequest, "upsert must be true or false")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read file: "+err.Error())
+		return
+	}
+	sheet, err := importer.Read(data, format)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	columns, err := models.ParseImportHeader(sheet.Header)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(sheet.Records) == 0 {
+		respondWithError(w, http.StatusBadRequest, "file has no rows to import")
+		return
+	}
+	if len(sheet.Records) > models.MaxImportRows {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("file has %d rows; at most %d can be imported at once", len(sheet.Records), models.MaxImportRows))
+		return
+	}
+
+	job.Columns = columns.Columns
+	job.IgnoredColumns = columns.Ignored
+	job.TotalRows = len(sheet.Records)
+	if err := h.repo.Create(&job); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create import job: "+err.Error())
+		return
+	}
+	h.runner.Start(&job, columns, sheet.Records)
+
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// ListImports handles GET /imports, newest first
+func (h *ImportHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	jobs, total, err := h.repo.List(offset, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list import jobs: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"data": jobs,
+		"pagination": map[string]interface{}{
+			"total":  total,
+			"page":   page,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// GetImport handles GET /imports/{id}, the job's progress and row errors
+func (h *ImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// GetImportErrors handles GET /imports/{id}/errors, the rows that were not imported as a CSV
+// file. Each line gives the row number and its errors followed by the row as uploaded, so the
+// file can be corrected and imported again.
+func (h *ImportHandler) GetImportErrors(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(append([]string{"row", "error"}, job.Columns...))
+	for _, e := range job.Errors {
+		writer.Write(append([]string{strconv.Itoa(e.Row), e.Message()}, e.Values...))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to write error report: "+err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("%s-errors.csv", strings.TrimSuffix(job.FileName, filepath.Ext(job.FileName)))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// job loads an import job, writing a 404 when there is none. When it returns false the
+// error response has been written.
+func (h *ImportHandler) job(w http.ResponseWriter, id string) (*models.ImportJob, bool) {
+	job, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get import job: "+err.Error())
+		return nil, false
+	}
+	if job == nil {
+		respondWithError(w, http.StatusNotFound, "Import job not found")
+		return nil, false
+	}
+	return job, true
+}
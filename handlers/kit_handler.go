@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// KitHandler handles kit definitions and the assemblies that build assembled kits and
+// products with a bill of materials
+type KitHandler struct {
+	*BaseHandler
+	repo        repositories.KitRepository
+	bomRepo     repositories.BOMRepository
+	productRepo repositories.ProductRepository
+}
+
+// NewKitHandler creates a new KitHandler
+func NewKitHandler(db *pgx.Conn) *KitHandler {
+	return &KitHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewKitRepository(db),
+		bomRepo:     repositories.NewBOMRepository(db),
+		productRepo: repositories.NewProductRepository(db),
+	}
+}
+
+// GetKits handles GET /kits
+func (h *KitHandler) GetKits(w http.ResponseWriter, r *http.Request) {
+	kits, err := h.repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get kits: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, kits)
+}
+
+// GetProductKit handles GET /products/{id}/kit
+func (h *KitHandler) GetProductKit(w http.ResponseWriter, r *http.Request) {
+	kit, err := h.repo.GetByProductID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get kit: "+err.Error())
+		return
+	}
+	if kit == nil {
+		respondWithError(w, http.StatusNotFound, "Product is not a kit")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, kit)
+}
+
+// SaveProductKit handles PUT /products/{id}/kit, making the product a kit or replacing its components
+func (h *KitHandler) SaveProductKit(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+
+	product, err := h.productRepo.GetByID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return
+	}
+	if product == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	bom, err := h.bomRepo.GetByProductID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get bill of materials: "+err.Error())
+		return
+	}
+	if bom != nil {
+		respondWithError(w, http.StatusConflict, "Product has a bill of materials; delete it before making the product a kit")
+		return
+	}
+
+	var kit models.Kit
+	if err := json.NewDecoder(r.Body).Decode(&kit); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	kit.ProductID = productID
+	if err := kit.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.Save(&kit); err != nil {
+		respondWithKitError(w, "Failed to save kit: ", err)
+		return
+	}
+
+	saved, err := h.repo.GetByProductID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get kit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// DeleteProductKit handles DELETE /products/{id}/kit. The product stays and is sold as a
+// plain product again.
+func (h *KitHandler) DeleteProductKit(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+
+	kit, err := h.repo.GetByProductID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get kit: "+err.Error())
+		return
+	}
+	if kit == nil {
+		respondWithError(w, http.StatusNotFound, "Product is not a kit")
+		return
+	}
+
+	if err := h.repo.Delete(productID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete kit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Kit deleted successfully"})
+}
+
+// GetAssemblies handles GET /assemblies?product_id=...&start_date=...&end_date=...
+func (h *KitHandler) GetAssemblies(w http.ResponseWriter, r *http.Request) {
+	var startDate, endDate *time.Time
+	if sd := r.URL.Query().Get("start_date"); sd != "" {
+		t, err := time.Parse("2006-01-02", sd)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start date format (use YYYY-MM-DD)")
+			return
+		}
+		startDate = &t
+	}
+
+	if ed := r.URL.Query().Get("end_date"); ed != "" {
+		t, err := time.Parse("2006-01-02", ed)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end date format (use YYYY-MM-DD)")
+			return
+		}
+		// Set to end of day
+		t = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		endDate = &t
+	}
+
+	assemblies, err := h.repo.ListAssemblies(r.URL.Query().Get("product_id"), startDate, endDate)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get assemblies: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, assemblies)
+}
+
+// GetAssembly handles GET /assemblies/{id}
+func (h *KitHandler) GetAssembly(w http.ResponseWriter, r *http.Request) {
+	assembly, err := h.repo.GetAssembly(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get assembly: "+err.Error())
+		return
+	}
+	if assembly == nil {
+		respondWithError(w, http.StatusNotFound, "Assembly not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, assembly)
+}
+
+// CreateAssembly handles POST /assemblies. The product must have a bill of materials, made in
+// "batches", or be an assembled kit, built by "quantity". The components are consumed and the
+// product added to stock as soon as the assembly is saved.
+func (h *KitHandler) CreateAssembly(w http.ResponseWriter, r *http.Request) {
+	assembly := models.NewAssembly()
+	if err := json.NewDecoder(r.Body).Decode(assembly); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := assembly.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, err := h.repo.GetAssemblyByReference(assembly.ReferenceNo)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check reference number: "+err.Error())
+		return
+	}
+	if existing != nil {
+		respondWithError(w, http.StatusConflict, "Assembly with this reference number already exists")
+		return
+	}
+
+	if !h.useComponents(w, assembly) {
+		return
+	}
+
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.CostSourceAssembly, assembly.ID, assembly.ReferenceNo, assembly.AssemblyDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.CreateAssembly(assembly, lock); err != nil {
+		respondWithKitError(w, "Failed to create assembly: ", err)
+		return
+	}
+
+	created, err := h.repo.GetAssembly(assembly.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get assembly: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// DeleteAssembly handles DELETE /assemblies/{id}, returning the components to stock. It is
+// refused once any of the units built have been sold.
+func (h *KitHandler) DeleteAssembly(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	assembly, err := h.repo.GetAssembly(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get assembly: "+err.Error())
+		return
+	}
+	if assembly == nil {
+		respondWithError(w, http.StatusNotFound, "Assembly not found")
+		return
+	}
+
+	lock, ok := periodLock(w, r, models.PeriodActionDelete, models.CostSourceAssembly, id, assembly.ReferenceNo, assembly.AssemblyDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteAssembly(id, lock); err != nil {
+		respondWithKitError(w, "Failed to delete assembly: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Assembly deleted successfully"})
+}
+
+// useComponents lists what the assembly consumes from the product's bill of materials or
+// kit. When it returns false the error response has been written.
+func (h *KitHandler) useComponents(w http.ResponseWriter, assembly *models.Assembly) bool {
+	bom, err := h.bomRepo.GetByProductID(assembly.ProductID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get bill of materials: "+err.Error())
+		return false
+	}
+	if bom != nil {
+		if err := assembly.UseBOM(bom); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return false
+		}
+		return true
+	}
+
+	kit, err := h.repo.GetByProductID(assembly.ProductID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get kit: "+err.Error())
+		return false
+	}
+	if kit == nil {
+		respondWithError(w, http.StatusBadRequest, "Product has no bill of materials and is not a kit: "+assembly.ProductID)
+		return false
+	}
+	if !kit.Assembled {
+		respondWithError(w, http.StatusBadRequest, "Kit "+kit.Name+" is not assembled ahead; it is built from its components when sold")
+		return false
+	}
+	if err := assembly.UseKit(kit); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// respondWithKitError maps invalid kits and missing stock to client errors
+func respondWithKitError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrInvalidKit), errors.Is(err, repositories.ErrInsufficientStock):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), prefix+err.Error())
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/printing"
+	"inventory-go/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// LabelHandler handles label templates and printing label sheets
+type LabelHandler struct {
+	*BaseHandler
+	repo repositories.LabelRepository
+}
+
+// NewLabelHandler creates a new LabelHandler
+func NewLabelHandler(db *pgx.Conn) *LabelHandler {
+	return &LabelHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewLabelRepository(db),
+	}
+}
+
+// PrintLabels handles POST /labels, returning a PDF of label sheets with each product's name,
+// price, SKU and barcode
+func (h *LabelHandler) PrintLabels(w http.ResponseWriter, r *http.Request) {
+	var req models.LabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := req.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	template, ok := h.template(w, req.TemplateID)
+	if !ok {
+		return
+	}
+	if req.Skip >= template.LabelsPerPage() {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("skip must be less than the %d labels on a sheet", template.LabelsPerPage()))
+		return
+	}
+
+	// One label per unit received, counted in the base unit
+	items := req.Items
+	if req.StockInID != "" {
+		var err error
+		items, err = h.repo.GetStockInItems(req.StockInID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get stock in items: "+err.Error())
+			return
+		}
+		if items == nil {
+			respondWithError(w, http.StatusNotFound, "Stock in not found")
+			return
+		}
+		if len(items) == 0 {
+			respondWithError(w, http.StatusBadRequest, "Stock in has no items to label")
+			return
+		}
+		total := 0
+		for _, item := range items {
+			total += item.Quantity
+		}
+		if total > models.MaxLabelsPerRequest {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("stock in received %d units; at most %d labels can be printed at once", total, models.MaxLabelsPerRequest))
+			return
+		}
+	}
+
+	labels, err := h.repo.GetLabels(items)
+	if err != nil {
+		if errors.Is(err, repositories.ErrLabelProductNotFound) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get products: "+err.Error())
+		}
+		return
+	}
+
+	// Render the whole file first so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	if err := printing.WriteLabels(&buf, *template, labels, req.Skip); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to render labels: "+err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("labels-%s.pdf", time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// GetLabelTemplates handles GET /label-templates
+func (h *LabelHandler) GetLabelTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.repo.GetTemplates()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get label templates: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, templates)
+}
+
+// GetLabelTemplate handles GET /label-templates/{id}
+func (h *LabelHandler) GetLabelTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.repo.GetTemplateByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get label template: "+err.Error())
+		return
+	}
+	if template == nil {
+		respondWithError(w, http.StatusNotFound, "Label template not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, template)
+}
+
+// CreateLabelTemplate handles POST /label-templates
+func (h *LabelHandler) CreateLabelTemplate(w http.ResponseWriter, r *http.Request) {
+	var template models.LabelTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	template.ID = ""
+	if err := template.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &template) {
+		return
+	}
+
+	if err := h.repo.CreateTemplate(&template); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create label template: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, template)
+}
+
+// UpdateLabelTemplate handles PUT /label-templates/{id}
+func (h *LabelHandler) UpdateLabelTemplate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.repo.GetTemplateByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get label template: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Label template not found")
+		return
+	}
+
+	template := *existing
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	template.ID = id
+	if err := template.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &template) {
+		return
+	}
+
+	if err := h.repo.UpdateTemplate(&template); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update label template: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, template)
+}
+
+// DeleteLabelTemplate handles DELETE /label-templates/{id}
+func (h *LabelHandler) DeleteLabelTemplate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	template, err := h.repo.GetTemplateByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get label template: "+err.Error())
+		return
+	}
+	if template == nil {
+		respondWithError(w, http.StatusNotFound, "Label template not found")
+		return
+	}
+
+	if err := h.repo.DeleteTemplate(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete label template: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Label template deleted successfully"})
+}
+
+// template looks up the template to print with, by ID or code, or the default one. When it
+// returns false the error response has been written.
+func (h *LabelHandler) template(w http.ResponseWriter, idOrCode string) (*models.LabelTemplate, bool) {
+	var (
+		template *models.LabelTemplate
+		err      error
+	)
+	if idOrCode == "" {
+		template, err = h.repo.GetTemplateByCode(models.DefaultLabelTemplate)
+	} else if template, err = h.repo.GetTemplateByID(idOrCode); err == nil && template == nil {
+		template, err = h.repo.GetTemplateByCode(idOrCode)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get label template: "+err.Error())
+		return nil, false
+	}
+	if template == nil {
+		respondWithError(w, http.StatusBadRequest, "Label template not found: "+idOrCode)
+		return nil, false
+	}
+	return template, true
+}
+
+// checkUniqueCode writes a 409 when another template has the code. When it returns false the
+// error response has been written.
+func (h *LabelHandler) checkUniqueCode(w http.ResponseWriter, template *models.LabelTemplate) bool {
+	existing, err := h.repo.GetTemplateByCode(template.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check label template code: "+err.Error())
+		return false
+	}
+	if existing != nil && existing.ID != template.ID {
+		respondWithError(w, http.StatusConflict, "Label template code already exists: "+template.Code)
+		return false
+	}
+	return true
+}
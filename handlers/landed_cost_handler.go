@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/money"
+	"inventory-go/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// LandedCostHandler handles landed cost operations
+type LandedCostHandler struct {
+	*BaseHandler
+	repo        repositories.LandedCostRepository
+	rateRepo    repositories.ExchangeRateRepository
+	stockInRepo repositories.StockInRepository
+}
+
+// NewLandedCostHandler creates a new LandedCostHandler
+func NewLandedCostHandler(db *pgx.Conn) *LandedCostHandler {
+	return &LandedCostHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewLandedCostRepository(db),
+		rateRepo:    repositories.NewExchangeRateRepository(db),
+		stockInRepo: repositories.NewStockInRepository(db),
+	}
+}
+
+// GetLandedCosts handles GET /landed-costs?stock_in_id=...
+func (h *LandedCostHandler) GetLandedCosts(w http.ResponseWriter, r *http.Request) {
+	stockInID := r.URL.Query().Get("stock_in_id")
+
+	var startDate, endDate *time.Time
+	if sd := r.URL.Query().Get("start_date"); sd != "" {
+		t, err := time.Parse("2006-01-02", sd)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start date format (use YYYY-MM-DD)")
+			return
+		}
+		startDate = &t
+	}
+
+	if ed := r.URL.Query().Get("end_date"); ed != "" {
+		t, err := time.Parse("2006-01-02", ed)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end date format (use YYYY-MM-DD)")
+			return
+		}
+		// Set to end of day
+		t = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		endDate = &t
+	}
+
+	landedCosts, err := h.repo.List(stockInID, startDate, endDate)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get landed costs: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, landedCosts)
+}
+
+// GetLandedCost handles GET /landed-costs/{id}
+func (h *LandedCostHandler) GetLandedCost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	landedCost, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get landed cost: "+err.Error())
+		return
+	}
+
+	if landedCost == nil {
+		respondWithError(w, http.StatusNotFound, "Landed cost not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, landedCost)
+}
+
+// CreateLandedCost handles POST /landed-costs
+func (h *LandedCostHandler) CreateLandedCost(w http.ResponseWriter, r *http.Request) {
+	landedCost := models.NewLandedCost()
+	if err := json.NewDecoder(r.Body).Decode(landedCost); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if !h.prepare(w, landedCost) {
+		return
+	}
+	lock, ok := h.landedCostLock(w, r, models.PeriodActionCreate, landedCost)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Create(landedCost, lock); err != nil {
+		respondWithLandedCostError(w, "Failed to create landed cost: ", err)
+		return
+	}
+
+	created, err := h.repo.GetByID(landedCost.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get landed cost: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// UpdateLandedCost handles PUT /landed-costs/{id}
+func (h *LandedCostHandler) UpdateLandedCost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get landed cost: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Landed cost not found")
+		return
+	}
+
+	// Fields left out of the request keep their current values
+	landedCost := *existing
+	landedCost.Allocations = nil
+	if err := json.NewDecoder(r.Body).Decode(&landedCost); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+	landedCost.ID = id
+
+	// Look the rate up again when the currency or date changes without a new rate
+	if landedCost.ExchangeRate == existing.ExchangeRate &&
+		(landedCost.Currency != existing.Currency || !landedCost.ChargeDate.Equal(existing.ChargeDate)) {
+		landedCost.ExchangeRate = money.Rate{}
+	}
+
+	if !h.prepare(w, &landedCost) {
+		return
+	}
+	lock, ok := h.landedCostLock(w, r, models.PeriodActionUpdate, existing, &landedCost)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Update(&landedCost, lock); err != nil {
+		respondWithLandedCostError(w, "Failed to update landed cost: ", err)
+		return
+	}
+
+	updated, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get landed cost: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeleteLandedCost handles DELETE /landed-costs/{id}
+func (h *LandedCostHandler) DeleteLandedCost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get landed cost: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Landed cost not found")
+		return
+	}
+
+	lock, ok := h.landedCostLock(w, r, models.PeriodActionDelete, existing)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(id, lock); err != nil {
+		respondWithLandedCostError(w, "Failed to delete landed cost: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Landed cost deleted successfully"})
+}
+
+// prepare validates a landed cost and converts it to the base currency,
+// writing the error response and returning false when it cannot be saved
+func (h *LandedCostHandler) prepare(w http.ResponseWriter, landedCost *models.LandedCost) bool {
+	if err := landedCost.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	rate, err := resolveExchangeRate(h.rateRepo, landedCost.Currency, landedCost.ChargeDate, landedCost.ExchangeRate)
+	if err != nil {
+		respondWithRateError(w, err)
+		return false
+	}
+	landedCost.ExchangeRate = rate
+	landedCost.ConvertToBase()
+	return true
+}
+
+// landedCostLock builds the closed-period lock of a landed cost change, covering the charge
+// date and the dates of the stock-ins it re-costs. It writes the error response and returns
+// false when a stock-in cannot be read or the override is not authorized.
+func (h *LandedCostHandler) landedCostLock(w http.ResponseWriter, r *http.Request, action string, landedCosts ...*models.LandedCost) (*models.PeriodLock, bool) {
+	var dates []time.Time
+	for _, lc := range landedCosts {
+		dates = append(dates, lc.ChargeDate)
+		for _, stockInID := range lc.StockInIDs {
+			stockIn, err := h.stockInRepo.GetByID(stockInID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to get stock-in: "+err.Error())
+				return nil, false
+			}
+			if stockIn != nil {
+				dates = append(dates, stockIn.OrderDate)
+			}
+		}
+	}
+
+	lc := landedCosts[len(landedCosts)-1]
+	return periodLock(w, r, action, models.JournalSourceLandedCost, lc.ID, lc.ReferenceNo, dates...)
+}
+
+// respondWithLandedCostError maps allocation and costing failures to client errors
+func respondWithLandedCostError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrLandedCostStockIn), errors.Is(err, models.ErrNoAllocationBasis):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), prefix+err.Error())
+	}
+}
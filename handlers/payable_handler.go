@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// PayableHandler handles supplier payments, supplier credits and statements
+type PayableHandler struct {
+	*BaseHandler
+	repo         repositories.PayableRepository
+	stockInRepo  repositories.StockInRepository
+	supplierRepo repositories.SupplierRepository
+}
+
+// NewPayableHandler creates a new PayableHandler
+func NewPayableHandler(db *pgx.Conn) *PayableHandler {
+	return &PayableHandler{
+		BaseHandler:  &BaseHandler{DB: db},
+		repo:         repositories.NewPayableRepository(db),
+		stockInRepo:  repositories.NewStockInRepository(db),
+		supplierRepo: repositories.NewSupplierRepository(db),
+	}
+}
+
+// GetStockInPayments handles GET /stockins/{id}/payments
+func (h *PayableHandler) GetStockInPayments(w http.ResponseWriter, r *http.Request) {
+	stockIn, ok := h.findStockIn(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	payments, err := h.repo.GetStockInPayments(stockIn.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier payments: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, payments)
+}
+
+// CreateStockInPayment handles POST /stockins/{id}/payments
+func (h *PayableHandler) CreateStockInPayment(w http.ResponseWriter, r *http.Request) {
+	stockIn, ok := h.findStockIn(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var payment models.StockInPayment
+	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	payment.StockInID = stockIn.ID
+	payment.GenerateID()
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.JournalSourceSupplierPayment, payment.ID, payment.PaymentNo, payment.PaymentDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.CreatePayment(&payment, lock); err != nil {
+		if errors.Is(err, models.ErrStockInNotPayable) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to create supplier payment: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, payment)
+}
+
+// DeleteStockInPayment handles DELETE /stockins/{id}/payments/{paymentId}
+func (h *PayableHandler) DeleteStockInPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	payment, err := h.repo.GetPayment(vars["paymentId"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier payment: "+err.Error())
+		return
+	}
+	if payment == nil || payment.StockInID != vars["id"] {
+		respondWithError(w, http.StatusNotFound, "Supplier payment not found")
+		return
+	}
+	lock, ok := periodLock(w, r, models.PeriodActionDelete, models.JournalSourceSupplierPayment, payment.ID, payment.PaymentNo, payment.PaymentDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeletePayment(payment.ID, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to delete supplier payment: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Supplier payment deleted successfully"})
+}
+
+// GetSupplierCredits handles GET /supplier-credits?supplier_id=...&stock_in_id=...
+func (h *PayableHandler) GetSupplierCredits(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	credits, err := h.repo.ListCredits(query.Get("supplier_id"), query.Get("stock_in_id"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier credits: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, credits)
+}
+
+// GetSupplierCredit handles GET /supplier-credits/{id}
+func (h *PayableHandler) GetSupplierCredit(w http.ResponseWriter, r *http.Request) {
+	credit, err := h.repo.GetCredit(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier credit: "+err.Error())
+		return
+	}
+	if credit == nil {
+		respondWithError(w, http.StatusNotFound, "Supplier credit not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, credit)
+}
+
+// CreateSupplierCredit handles POST /supplier-credits
+func (h *PayableHandler) CreateSupplierCredit(w http.ResponseWriter, r *http.Request) {
+	var credit models.SupplierCredit
+	if err := json.NewDecoder(r.Body).Decode(&credit); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if credit.StockInID == "" {
+		respondWithError(w, http.StatusBadRequest, "Stock-in ID is required")
+		return
+	}
+	if _, ok := h.findStockIn(w, credit.StockInID); !ok {
+		return
+	}
+
+	credit.GenerateID()
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.JournalSourceSupplierCredit, credit.ID, credit.CreditNo, credit.CreditDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.CreateCredit(&credit, lock); err != nil {
+		if errors.Is(err, models.ErrStockInNotPayable) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to create supplier credit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, credit)
+}
+
+// DeleteSupplierCredit handles DELETE /supplier-credits/{id}
+func (h *PayableHandler) DeleteSupplierCredit(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	credit, err := h.repo.GetCredit(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier credit: "+err.Error())
+		return
+	}
+	if credit == nil {
+		respondWithError(w, http.StatusNotFound, "Supplier credit not found")
+		return
+	}
+	lock, ok := periodLock(w, r, models.PeriodActionDelete, models.JournalSourceSupplierCredit, id, credit.CreditNo, credit.CreditDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteCredit(id, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to delete supplier credit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Supplier credit deleted successfully"})
+}
+
+// GetSupplierStatement handles GET /suppliers/{id}/statement?start_date=2025-01-01&end_date=2025-01-31
+func (h *PayableHandler) GetSupplierStatement(w http.ResponseWriter, r *http.Request) {
+	supplier, err := h.supplierRepo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier: "+err.Error())
+		return
+	}
+	if supplier == nil {
+		respondWithError(w, http.StatusNotFound, "Supplier not found")
+		return
+	}
+
+	startDate, endDate, ok := parseStatementPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	statement, err := h.repo.GetStatement(supplier, startDate, endDate)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get supplier statement: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, statement)
+}
+
+// findStockIn loads a stock-in, writing the error response and returning false when it cannot
+func (h *PayableHandler) findStockIn(w http.ResponseWriter, id string) (*models.StockIn, bool) {
+	stockIn, err := h.stockInRepo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get stock-in: "+err.Error())
+		return nil, false
+	}
+	if stockIn == nil {
+		respondWithError(w, http.StatusNotFound, "Stock-in not found")
+		return nil, false
+	}
+	return stockIn, true
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// Headers an administrator sends to change documents inside a closed period.
+// The token must match the PERIOD_OVERRIDE_TOKEN environment variable.
+const (
+	headerOverrideToken  = "X-Override-Token"
+	headerOverrideReason = "X-Override-Reason"
+	headerUser           = "X-User"
+)
+
+// PeriodHandler handles accounting periods
+type PeriodHandler struct {
+	*BaseHandler
+	repo repositories.PeriodRepository
+}
+
+// NewPeriodHandler creates a new PeriodHandler
+func NewPeriodHandler(db *pgx.Conn) *PeriodHandler {
+	return &PeriodHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewPeriodRepository(db),
+	}
+}
+
+// GetPeriods handles GET /accounting/periods
+func (h *PeriodHandler) GetPeriods(w http.ResponseWriter, r *http.Request) {
+	periods, err := h.repo.List()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get accounting periods: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, periods)
+}
+
+// GetPeriod handles GET /accounting/periods/{id}
+func (h *PeriodHandler) GetPeriod(w http.ResponseWriter, r *http.Request) {
+	period, ok := h.findPeriod(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, period)
+}
+
+// CreatePeriod handles POST /accounting/periods
+func (h *PeriodHandler) CreatePeriod(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name      string `json:"name"`
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	period := models.NewAccountingPeriod()
+	period.Name = payload.Name
+	period.Note = payload.Note
+
+	var err error
+	if period.StartDate, err = time.Parse("2006-01-02", payload.StartDate); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid start date format (use YYYY-MM-DD)")
+		return
+	}
+	if period.EndDate, err = time.Parse("2006-01-02", payload.EndDate); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid end date format (use YYYY-MM-DD)")
+		return
+	}
+	if err := period.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.Create(period); err != nil {
+		if errors.Is(err, models.ErrPeriodOverlap) {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to create accounting period: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, period)
+}
+
+// DeletePeriod handles DELETE /accounting/periods/{id}. Only open periods can be deleted.
+func (h *PeriodHandler) DeletePeriod(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.findPeriod(w, id); !ok {
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, periodConflictStatus(err), "Failed to delete accounting period: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Accounting period deleted successfully"})
+}
+
+// ClosePeriod handles POST /accounting/periods/{id}/close
+func (h *PeriodHandler) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.findPeriod(w, id); !ok {
+		return
+	}
+
+	if err := h.repo.Close(id, r.Header.Get(headerUser)); err != nil {
+		respondWithError(w, periodConflictStatus(err), "Failed to close accounting period: "+err.Error())
+		return
+	}
+
+	period, ok := h.findPeriod(w, id)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, period)
+}
+
+// ReopenPeriod handles POST /accounting/periods/{id}/reopen. It requires the admin override headers.
+func (h *PeriodHandler) ReopenPeriod(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.findPeriod(w, id); !ok {
+		return
+	}
+
+	override, ok := authorizeOverride(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Reopen(id, override); err != nil {
+		respondWithError(w, periodConflictStatus(err), "Failed to reopen accounting period: "+err.Error())
+		return
+	}
+
+	period, ok := h.findPeriod(w, id)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, period)
+}
+
+// GetPeriodOverrides handles GET /accounting/period-overrides?period_id=...
+func (h *PeriodHandler) GetPeriodOverrides(w http.ResponseWriter, r *http.Request) {
+	overrides, err := h.repo.ListOverrides(r.URL.Query().Get("period_id"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get period overrides: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, overrides)
+}
+
+// findPeriod loads a period, writing the error response and returning false when it cannot
+func (h *PeriodHandler) findPeriod(w http.ResponseWriter, id string) (*models.AccountingPeriod, bool) {
+	period, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get accounting period: "+err.Error())
+		return nil, false
+	}
+	if period == nil {
+		respondWithError(w, http.StatusNotFound, "Accounting period not found")
+		return nil, false
+	}
+	return period, true
+}
+
+// periodConflictStatus returns 409 for period state conflicts and 500 otherwise
+func periodConflictStatus(err error) int {
+	if errors.Is(err, repositories.ErrPeriodAlreadyClosed) || errors.Is(err, repositories.ErrPeriodNotClosed) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// authorizeOverride checks the admin override headers of a request and returns the
+// override to audit, writing the error response and returning false when they are missing or wrong
+func authorizeOverride(w http.ResponseWriter, r *http.Request) (*models.PeriodOverride, bool) {
+	token := r.Header.Get(headerOverrideToken)
+	expected := os.Getenv("PERIOD_OVERRIDE_TOKEN")
+	if token == "" || expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		respondWithError(w, http.StatusForbidden, "Invalid override token")
+		return nil, false
+	}
+
+	override := &models.PeriodOverride{
+		Actor:  r.Header.Get(headerUser),
+		Reason: r.Header.Get(headerOverrideReason),
+	}
+	if err := override.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return override, true
+}
+
+// periodLock builds the closed-period lock a change to a document with the given dates is
+// saved under. Both the current and the new date of an update must be given. The repository
+// refuses the change with a *models.PeriodClosedError when a date falls in a closed period,
+// unless the request carries an administrator override, which it then records with the change.
+// When it returns false the error response has been written.
+func periodLock(w http.ResponseWriter, r *http.Request, action, sourceType, sourceID, reference string, dates ...time.Time) (*models.PeriodLock, bool) {
+	lock := models.NewPeriodLock(action, sourceType, sourceID, reference, dates...)
+	if r.Header.Get(headerOverrideToken) == "" {
+		return lock, true
+	}
+
+	override, ok := authorizeOverride(w, r)
+	if !ok {
+		return nil, false
+	}
+	lock.Override = override
+	return lock, true
+}
+
+// lockedStatus returns 423 Locked for a change refused because it falls in a closed
+// accounting period, and status for any other error
+func lockedStatus(err error, status int) int {
+	var closed *models.PeriodClosedError
+	if errors.As(err, &closed) {
+		return http.StatusLocked
+	}
+	return status
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// PriceListHandler handles price lists and price quotes
+type PriceListHandler struct {
+	*BaseHandler
+	repo   repositories.PriceListRepository
+	prices priceResolver
+}
+
+// NewPriceListHandler creates a new PriceListHandler
+func NewPriceListHandler(db *pgx.Conn) *PriceListHandler {
+	return &PriceListHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewPriceListRepository(db),
+		prices:      newPriceResolver(db),
+	}
+}
+
+// GetPriceLists handles GET /price-lists?active=true
+func (h *PriceListHandler) GetPriceLists(w http.ResponseWriter, r *http.Request) {
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+
+	lists, err := h.repo.GetAll(activeOnly)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price lists: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, lists)
+}
+
+// GetPriceList handles GET /price-lists/{id}
+func (h *PriceListHandler) GetPriceList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price list: "+err.Error())
+		return
+	}
+	if list == nil {
+		respondWithError(w, http.StatusNotFound, "Price list not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// CreatePriceList handles POST /price-lists
+func (h *PriceListHandler) CreatePriceList(w http.ResponseWriter, r *http.Request) {
+	list := models.PriceList{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := list.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &list) {
+		return
+	}
+
+	if err := h.repo.Create(&list); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create price list: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, list)
+}
+
+// UpdatePriceList handles PUT /price-lists/{id}. Sending items replaces all of them.
+func (h *PriceListHandler) UpdatePriceList(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price list: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Price list not found")
+		return
+	}
+
+	list := *existing
+	list.Items = nil
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	replaceItems := list.Items != nil
+	if !replaceItems {
+		list.Items = existing.Items
+	}
+
+	list.ID = id
+	if err := list.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &list) {
+		return
+	}
+
+	if err := h.repo.Update(&list, replaceItems); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update price list: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// DeletePriceList handles DELETE /price-lists/{id}
+func (h *PriceListHandler) DeletePriceList(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	list, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price list: "+err.Error())
+		return
+	}
+	if list == nil {
+		respondWithError(w, http.StatusNotFound, "Price list not found")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete price list: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Price list deleted successfully"})
+}
+
+// GetPriceQuote handles GET /price-lists/quote?product_id=...&quantity=10&customer_id=...&currency=IDR
+func (h *PriceListHandler) GetPriceQuote(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	productID := query.Get("product_id")
+	if productID == "" {
+		respondWithError(w, http.StatusBadRequest, "product_id is required")
+		return
+	}
+
+	quantity := 1
+	if q := query.Get("quantity"); q != "" {
+		v, err := strconv.Atoi(q)
+		if err != nil || v < 1 {
+			respondWithError(w, http.StatusBadRequest, "Invalid quantity")
+			return
+		}
+		quantity = v
+	}
+
+	var customerID *string
+	if c := query.Get("customer_id"); c != "" {
+		customerID = &c
+	}
+
+	currency := money.NormalizeCurrency(query.Get("currency"))
+	rate, err := money.ParseRate(query.Get("exchange_rate"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid exchange_rate")
+		return
+	}
+	if currency == money.BaseCurrency {
+		rate = money.OneRate()
+	}
+
+	quotes, ok := h.prices.quote(w, customerID, currency, rate, []priceRequest{{productID: productID, quantity: quantity}})
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, quotes[0])
+}
+
+// checkUniqueCode responds with 409 and returns false when another price list has the same code
+func (h *PriceListHandler) checkUniqueCode(w http.ResponseWriter, list *models.PriceList) bool {
+	existing, err := h.repo.GetByCode(list.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check price list: "+err.Error())
+		return false
+	}
+	if existing != nil && existing.ID != list.ID {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Price list %s already exists", list.Code))
+		return false
+	}
+	return true
+}
+
+// priceRequest is a product and quantity to price
+type priceRequest struct {
+	productID string
+	quantity  int
+}
+
+// priceResolver prices products for a customer from their price list or the product price
+type priceResolver struct {
+	repo        repositories.PriceListRepository
+	productRepo repositories.ProductRepository
+}
+
+// newPriceResolver creates a priceResolver
+func newPriceResolver(db *pgx.Conn) priceResolver {
+	return priceResolver{
+		repo:        repositories.NewPriceListRepository(db),
+		productRepo: repositories.NewProductRepository(db),
+	}
+}
+
+// quote prices each request in the currency for the customer. When it returns false the
+// error response has been written.
+func (p priceResolver) quote(w http.ResponseWriter, customerID *string, currency string, rate money.Rate, requests []priceRequest) ([]models.PriceQuote, bool) {
+	list, err := p.repo.GetForCustomer(customerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price list: "+err.Error())
+		return nil, false
+	}
+
+	products := make([]*models.Product, len(requests))
+	var ids []string
+	for i, req := range requests {
+		product, err := p.productRepo.GetByID(req.productID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+			return nil, false
+		}
+		if product == nil {
+			respondWithError(w, http.StatusBadRequest, "Product not found: "+req.productID)
+			return nil, false
+		}
+		products[i] = product
+		ids = append(ids, product.ID)
+		if product.ParentID != nil {
+			ids = append(ids, *product.ParentID)
+		}
+	}
+
+	var breaks []models.PriceListItem
+	if list != nil {
+		if breaks, err = p.repo.GetBreaks(list.ID, ids); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get price breaks: "+err.Error())
+			return nil, false
+		}
+	}
+
+	quotes := make([]models.PriceQuote, len(requests))
+	for i, req := range requests {
+		quote := models.QuotePrice(list, breaks, products[i], req.quantity, currency, rate)
+		if quote == nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("No %s price for product %s", currency, req.productID))
+			return nil, false
+		}
+		quotes[i] = *quote
+	}
+	return quotes, true
+}
+
+// applySale sets the unit price of sale items sent without one from the customer's price list
+// or the product price, recording the rule applied. Items with a price are marked manual.
+// When it returns false the error response has been written.
+func (p priceResolver) applySale(w http.ResponseWriter, sale *models.Sale) bool {
+	var requests []priceRequest
+	var indexes []int
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		if !item.UnitPrice.IsZero() {
+			if item.PriceSource == "" {
+				item.AppliedPrice = models.AppliedPrice{PriceSource: models.PriceSourceManual}
+			}
+			continue
+		}
+		requests = append(requests, priceRequest{productID: item.ProductID, quantity: item.Quantity})
+		indexes = append(indexes, i)
+	}
+	if len(requests) == 0 {
+		return true
+	}
+
+	sale.BindCurrency()
+	quotes, ok := p.quote(w, sale.CustomerID, sale.Currency, sale.ExchangeRate, requests)
+	if !ok {
+		return false
+	}
+	for n, i := range indexes {
+		sale.Items[i].UnitPrice = quotes[n].UnitPrice
+		sale.Items[i].AppliedPrice = quotes[n].AppliedPrice
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// PriceScheduleHandler handles scheduled prices and product price history
+type PriceScheduleHandler struct {
+	*BaseHandler
+	repo repositories.PriceScheduleRepository
+}
+
+// NewPriceScheduleHandler creates a new PriceScheduleHandler
+func NewPriceScheduleHandler(db *pgx.Conn) *PriceScheduleHandler {
+	return &PriceScheduleHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewPriceScheduleRepository(db),
+	}
+}
+
+// GetPriceHistory handles GET /products/{id}/price-history?limit=50
+func (h *PriceScheduleHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.checkProduct(w, productID) {
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 || v > 500 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit (use 1-500)")
+			return
+		}
+		limit = v
+	}
+
+	history, err := h.repo.GetHistory(productID, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price history: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, history)
+}
+
+// GetProductPriceSchedules handles GET /products/{id}/price-schedules?status=pending
+func (h *PriceScheduleHandler) GetProductPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.checkProduct(w, productID) {
+		return
+	}
+	h.listSchedules(w, r, productID)
+}
+
+// GetPriceSchedules handles GET /price-schedules?status=pending&product_id=...
+func (h *PriceScheduleHandler) GetPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	h.listSchedules(w, r, r.URL.Query().Get("product_id"))
+}
+
+func (h *PriceScheduleHandler) listSchedules(w http.ResponseWriter, r *http.Request, productID string) {
+	status := models.PriceScheduleStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	schedules, err := h.repo.List(productID, status)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price schedules: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, schedules)
+}
+
+// GetPriceSchedule handles GET /price-schedules/{id}
+func (h *PriceScheduleHandler) GetPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price schedule: "+err.Error())
+		return
+	}
+	if schedule == nil {
+		respondWithError(w, http.StatusNotFound, "Price schedule not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, schedule)
+}
+
+// CreatePriceSchedule handles POST /products/{id}/price-schedules. The currency defaults to
+// the product's.
+func (h *PriceScheduleHandler) CreatePriceSchedule(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	price, err := h.repo.GetProductPrice(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return
+	}
+	if price == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	var schedule models.PriceSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	schedule.ID = ""
+	schedule.ProductID = productID
+	if schedule.Currency == "" {
+		schedule.Currency = price.Currency
+	}
+	if err := schedule.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if schedule.EndsAt != nil && !schedule.EndsAt.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "ends_at must be in the future")
+		return
+	}
+
+	overlap, err := h.repo.FindOverlap(&schedule)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check price schedules: "+err.Error())
+		return
+	}
+	if overlap != nil {
+		respondWithError(w, http.StatusConflict,
+			fmt.Sprintf("Overlaps %s price schedule %s", overlap.Status, overlap.ID))
+		return
+	}
+
+	if err := h.repo.Create(&schedule); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create price schedule: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, schedule)
+}
+
+// CancelPriceSchedule handles DELETE /price-schedules/{id}. Cancelling an active schedule
+// restores the product's previous price straight away.
+func (h *PriceScheduleHandler) CancelPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price schedule: "+err.Error())
+		return
+	}
+	if schedule == nil {
+		respondWithError(w, http.StatusNotFound, "Price schedule not found")
+		return
+	}
+	if schedule.Status != models.PriceSchedulePending && schedule.Status != models.PriceScheduleActive {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Price schedule is already %s", schedule.Status))
+		return
+	}
+
+	if err := h.repo.Cancel(schedule); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel price schedule: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, schedule)
+}
+
+// RunPriceSchedules handles POST /price-schedules/run, applying and reverting due schedules
+// without waiting for the scheduler
+func (h *PriceScheduleHandler) RunPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	run, err := h.repo.RunDue(time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to run price schedules: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, run)
+}
+
+// checkProduct responds with 404 and returns false when the product does not exist
+func (h *PriceScheduleHandler) checkProduct(w http.ResponseWriter, productID string) bool {
+	price, err := h.repo.GetProductPrice(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return false
+	}
+	if price == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/money"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// PricingRuleHandler handles cost-plus pricing rules and the review queue of price suggestions
+type PricingRuleHandler struct {
+	*BaseHandler
+	repo         repositories.PricingRuleRepository
+	productRepo  repositories.ProductRepository
+	categoryRepo repositories.CategoryRepository
+}
+
+// NewPricingRuleHandler creates a new PricingRuleHandler
+func NewPricingRuleHandler(db *pgx.Conn) *PricingRuleHandler {
+	return &PricingRuleHandler{
+		BaseHandler:  &BaseHandler{DB: db},
+		repo:         repositories.NewPricingRuleRepository(db),
+		productRepo:  repositories.NewProductRepository(db),
+		categoryRepo: repositories.NewCategoryRepository(db),
+	}
+}
+
+// GetPricingRules handles GET /pricing-rules?active=true
+func (h *PricingRuleHandler) GetPricingRules(w http.ResponseWriter, r *http.Request) {
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+
+	rules, err := h.repo.GetAll(activeOnly)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get pricing rules: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rules)
+}
+
+// GetPricingRule handles GET /pricing-rules/{id}
+func (h *PricingRuleHandler) GetPricingRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get pricing rule: "+err.Error())
+		return
+	}
+	if rule == nil {
+		respondWithError(w, http.StatusNotFound, "Pricing rule not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rule)
+}
+
+// CreatePricingRule handles POST /pricing-rules
+func (h *PricingRuleHandler) CreatePricingRule(w http.ResponseWriter, r *http.Request) {
+	rule := models.PricingRule{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := rule.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkTarget(w, &rule) {
+		return
+	}
+
+	if err := h.repo.Create(&rule); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create pricing rule: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, rule)
+}
+
+// UpdatePricingRule handles PUT /pricing-rules/{id}
+func (h *PricingRuleHandler) UpdatePricingRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get pricing rule: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Pricing rule not found")
+		return
+	}
+
+	rule := *existing
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	rule.ID = id
+	if err := rule.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkTarget(w, &rule) {
+		return
+	}
+
+	if err := h.repo.Update(&rule); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update pricing rule: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, rule)
+}
+
+// DeletePricingRule handles DELETE /pricing-rules/{id}
+func (h *PricingRuleHandler) DeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	rule, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get pricing rule: "+err.Error())
+		return
+	}
+	if rule == nil {
+		respondWithError(w, http.StatusNotFound, "Pricing rule not found")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete pricing rule: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Pricing rule deleted successfully"})
+}
+
+// GetPriceSuggestions handles GET /price-suggestions?status=pending&product_id=...
+func (h *PricingRuleHandler) GetPriceSuggestions(w http.ResponseWriter, r *http.Request) {
+	status := models.PriceSuggestionStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	suggestions, err := h.repo.ListSuggestions(status, r.URL.Query().Get("product_id"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price suggestions: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, suggestions)
+}
+
+// GetPriceSuggestion handles GET /price-suggestions/{id}
+func (h *PricingRuleHandler) GetPriceSuggestion(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := h.loadSuggestion(w, r, false)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, suggestion)
+}
+
+// ApplyPriceSuggestion handles POST /price-suggestions/{id}/apply. The body may give a
+// "price" to use instead of the suggested one.
+func (h *PricingRuleHandler) ApplyPriceSuggestion(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := h.loadSuggestion(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Price *money.Money `json:"price"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	price := suggestion.SuggestedPrice
+	if req.Price != nil {
+		price = req.Price.In(suggestion.Currency)
+		if price.IsNegative() {
+			respondWithError(w, http.StatusBadRequest, "price cannot be negative")
+			return
+		}
+	}
+
+	if err := h.repo.ApplySuggestion(suggestion, price); err != nil {
+		respondWithError(w, reviewConflictStatus(err), "Failed to apply price suggestion: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, suggestion)
+}
+
+// RejectPriceSuggestion handles POST /price-suggestions/{id}/reject
+func (h *PricingRuleHandler) RejectPriceSuggestion(w http.ResponseWriter, r *http.Request) {
+	suggestion, ok := h.loadSuggestion(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.RejectSuggestion(suggestion); err != nil {
+		respondWithError(w, reviewConflictStatus(err), "Failed to reject price suggestion: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, suggestion)
+}
+
+// loadSuggestion loads the suggestion named in the path. When pending is set it must still
+// be waiting for review (409 otherwise). When it returns false the error response has been written.
+func (h *PricingRuleHandler) loadSuggestion(w http.ResponseWriter, r *http.Request, pending bool) (*models.PriceSuggestion, bool) {
+	suggestion, err := h.repo.GetSuggestion(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get price suggestion: "+err.Error())
+		return nil, false
+	}
+	if suggestion == nil {
+		respondWithError(w, http.StatusNotFound, "Price suggestion not found")
+		return nil, false
+	}
+	if pending && suggestion.Status != models.PriceSuggestionPending {
+		respondWithError(w, http.StatusConflict, "Price suggestion is already "+string(suggestion.Status))
+		return nil, false
+	}
+	return suggestion, true
+}
+
+// checkTarget responds and returns false when the rule's product or category does not exist
+// or already has a rule
+func (h *PricingRuleHandler) checkTarget(w http.ResponseWriter, rule *models.PricingRule) bool {
+	if rule.Scope == models.PricingRuleScopeProduct {
+		product, err := h.productRepo.GetByID(*rule.ProductID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+			return false
+		}
+		if product == nil {
+			respondWithError(w, http.StatusBadRequest, "Product not found: "+*rule.ProductID)
+			return false
+		}
+	} else {
+		category, err := h.categoryRepo.GetByID(*rule.CategoryID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get category: "+err.Error())
+			return false
+		}
+		if category == nil {
+			respondWithError(w, http.StatusBadRequest, "Category not found: "+*rule.CategoryID)
+			return false
+		}
+	}
+
+	existing, err := h.repo.GetByTarget(rule)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check pricing rule: "+err.Error())
+		return false
+	}
+	if existing != nil && existing.ID != rule.ID {
+		respondWithError(w, http.StatusConflict, "Pricing rule "+existing.Name+" already covers this "+string(rule.Scope))
+		return false
+	}
+	return true
+}
+
+// reviewConflictStatus returns 409 when a suggestion was reviewed in the meantime
+func reviewConflictStatus(err error) int {
+	if errors.Is(err, repositories.ErrSuggestionReviewed) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
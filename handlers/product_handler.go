@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"inventory-go/models"
+	"inventory-go/money"
 	"inventory-go/repositories"
+	"inventory-go/storage"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -17,14 +20,19 @@ import (
 // ProductHandler handles product-related operations
 type ProductHandler struct {
 	*BaseHandler
-	prodRepo repositories.ProductRepository
+	prodRepo   repositories.ProductRepository
+	searchRepo repositories.ProductSearchRepository
+	store      storage.BlobStore
 }
 
-// NewProductHandler creates a new ProductHandler
-func NewProductHandler(db *pgx.Conn) *ProductHandler {
+// NewProductHandler creates a new ProductHandler; store holds the product images removed
+// along with a product
+func NewProductHandler(db *pgx.Conn, store storage.BlobStore) *ProductHandler {
 	return &ProductHandler{
 		BaseHandler: &BaseHandler{DB: db},
 		prodRepo:    repositories.NewProductRepository(db),
+		searchRepo:  repositories.NewProductSearchRepository(db),
+		store:       store,
 	}
 }
 
@@ -155,9 +163,63 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The product is only marked deleted, but its images go for good
+	images, err := h.prodRepo.DeleteImages(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, image := range images {
+		removeImageFiles(h.store, image.StorageKeys)
+	}
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
+// AddProductAttribute handles POST /products/{id}/attributes, setting one attribute
+func (h *ProductHandler) AddProductAttribute(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var attribute repositories.Attribute
+	if err := json.NewDecoder(r.Body).Decode(&attribute); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	attribute.Key = strings.TrimSpace(attribute.Key)
+	attribute.Value = strings.TrimSpace(attribute.Value)
+	if attribute.Key == "" || attribute.Value == "" {
+		respondWithError(w, http.StatusBadRequest, "Attribute key and value are required")
+		return
+	}
+
+	if err := h.prodRepo.AddAttribute(id, attribute); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	product, err := h.prodRepo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving updated product")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, product)
+}
+
+// DeleteProductAttribute handles DELETE /products/{id}/attributes/{key}
+func (h *ProductHandler) DeleteProductAttribute(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.prodRepo.RemoveAttribute(vars["id"], vars["key"]); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Attribute removed successfully"})
+}
+
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
@@ -170,31 +232,14 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 	}
 	offset := (page - 1) * limit
 
-	var status *int
-	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
-		if s, err := strconv.Atoi(statusStr); err == nil {
-			status = &s
-		}
-	}
-
-	var (
-		productPtrs []*models.Product
-		total       int64
-		err         error
-	)
-
-	// Convert status from *int to string if not nil
-	statusStr := ""
-	if status != nil {
-		statusStr = fmt.Sprintf("%d", *status)
+	// Filters combine; category_id includes its subcategories
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// Check if category ID is provided
-	if categoryID := r.URL.Query().Get("category_id"); categoryID != "" {
-		productPtrs, total, err = h.prodRepo.List(offset, limit, statusStr)
-	} else {
-		productPtrs, total, err = h.prodRepo.List(offset, limit, statusStr)
-	}
+	productPtrs, total, err := h.prodRepo.List(offset, limit, filter)
 
 	// Convert []*models.Product to []models.Product for compatibility
 	products := make([]models.Product, 0, len(productPtrs))
@@ -219,3 +264,124 @@ func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request)
 		},
 	})
 }
+
+// SearchProducts handles GET /products/search?q=...&category_id=...&status=...&condition=...
+// &min_price=...&max_price=...&in_stock=true&sort=..., returning the best matches first unless
+// sorted otherwise, and facet counts over everything found
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.searchRepo.Search(models.ProductSearch{
+		Query:         r.URL.Query().Get("q"),
+		ProductFilter: filter,
+		Offset:        offset,
+		Limit:         limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"data":   result.Products,
+		"facets": result.Facets,
+		"pagination": map[string]interface{}{
+			"total":  result.Total,
+			"page":   page,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// parseProductFilter reads the product filters from the query string
+func parseProductFilter(r *http.Request) (models.ProductFilter, error) {
+	q := r.URL.Query()
+	filter := models.ProductFilter{
+		CategoryID: q.Get("category_id"),
+		Attributes: parseAttributeFilter(r),
+	}
+
+	if status := q.Get("status"); status != "" {
+		if _, err := strconv.Atoi(status); err != nil {
+			return filter, fmt.Errorf("invalid status: %s", status)
+		}
+		filter.Status = status
+	}
+	if condition := q.Get("condition"); condition != "" {
+		if _, err := strconv.Atoi(condition); err != nil {
+			return filter, fmt.Errorf("invalid condition: %s", condition)
+		}
+		filter.Condition = condition
+	}
+
+	var err error
+	if filter.MinPrice, err = parsePriceParam(r, "min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parsePriceParam(r, "max_price"); err != nil {
+		return filter, err
+	}
+
+	if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.Cmp(*filter.MaxPrice) > 0 {
+		return filter, fmt.Errorf("min_price is above max_price")
+	}
+
+	if inStock := q.Get("in_stock"); inStock != "" {
+		b, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return filter, fmt.Errorf("invalid in_stock: %s", inStock)
+		}
+		filter.InStock = b
+	}
+
+	if sort := q.Get("sort"); sort != "" {
+		if !models.IsValidProductSort(sort) {
+			return filter, fmt.Errorf("invalid sort: %s", sort)
+		}
+		filter.Sort = sort
+	}
+
+	return filter, nil
+}
+
+// parsePriceParam reads an optional price from the query string
+func parsePriceParam(r *http.Request, name string) (*money.Money, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	price, err := money.Parse(s, money.BaseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, s)
+	}
+	return &price, nil
+}
+
+// parseAttributeFilter reads attribute filters given as attr.<name>=<value>, e.g. attr.color=red
+func parseAttributeFilter(r *http.Request) map[string]string {
+	var attributes map[string]string
+	for key, values := range r.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "attr."); ok && name != "" && len(values) > 0 {
+			if attributes == nil {
+				attributes = map[string]string{}
+			}
+			attributes[name] = values[0]
+		}
+	}
+	return attributes
+}
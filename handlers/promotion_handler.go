@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// PromotionHandler handles promotions and the promotions report
+type PromotionHandler struct {
+	*BaseHandler
+	repo repositories.PromotionRepository
+}
+
+// NewPromotionHandler creates a new PromotionHandler
+func NewPromotionHandler(db *pgx.Conn) *PromotionHandler {
+	return &PromotionHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewPromotionRepository(db),
+	}
+}
+
+// GetPromotions handles GET /promotions?active=true
+func (h *PromotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+
+	promotions, err := h.repo.GetAll(activeOnly)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get promotions: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promotions)
+}
+
+// GetPromotion handles GET /promotions/{id}
+func (h *PromotionHandler) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	promotion, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get promotion: "+err.Error())
+		return
+	}
+	if promotion == nil {
+		respondWithError(w, http.StatusNotFound, "Promotion not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promotion)
+}
+
+// CreatePromotion handles POST /promotions
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	promotion := models.Promotion{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := promotion.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &promotion) {
+		return
+	}
+
+	if err := h.repo.Create(&promotion); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create promotion: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, promotion)
+}
+
+// UpdatePromotion handles PUT /promotions/{id}
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get promotion: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Promotion not found")
+		return
+	}
+
+	promotion := *existing
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	promotion.ID = id
+	if err := promotion.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &promotion) {
+		return
+	}
+
+	if err := h.repo.Update(&promotion); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update promotion: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, promotion)
+}
+
+// DeletePromotion handles DELETE /promotions/{id}
+func (h *PromotionHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	promotion, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get promotion: "+err.Error())
+		return
+	}
+	if promotion == nil {
+		respondWithError(w, http.StatusNotFound, "Promotion not found")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete promotion: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Promotion deleted successfully"})
+}
+
+// GetPromotionReport handles GET /reports/promotions?start_date=2025-01-01&end_date=2025-01-31.
+// It defaults to the last month.
+func (h *PromotionHandler) GetPromotionReport(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseStatementPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.repo.GetReport(startDate, endDate)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get promotions report: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// checkUniqueCode responds with 409 and returns false when another promotion has the same code
+func (h *PromotionHandler) checkUniqueCode(w http.ResponseWriter, promotion *models.Promotion) bool {
+	existing, err := h.repo.GetByCode(promotion.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check promotion: "+err.Error())
+		return false
+	}
+	if existing != nil && existing.ID != promotion.ID {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Promotion %s already exists", promotion.Code))
+		return false
+	}
+	return true
+}
+
+// promotionResolver applies the running promotions to sales as they are priced
+type promotionResolver struct {
+	repo repositories.PromotionRepository
+}
+
+// newPromotionResolver creates a promotionResolver
+func newPromotionResolver(db *pgx.Conn) promotionResolver {
+	return promotionResolver{repo: repositories.NewPromotionRepository(db)}
+}
+
+// applySale discounts the sale's items with the promotions running on its date and platform,
+// within their usage limits, and records them on the sale. Cancelled sales get none. When it
+// returns false the error response has been written.
+func (p promotionResolver) applySale(w http.ResponseWriter, sale *models.Sale) bool {
+	var promotions []models.Promotion
+	if sale.Status != models.SaleStatusCancelled {
+		date := sale.SaleDate
+		if date.IsZero() {
+			date = time.Now()
+		}
+		var err error
+		if promotions, err = p.repo.GetRunning(date); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get promotions: "+err.Error())
+			return false
+		}
+	}
+	if len(promotions) == 0 {
+		models.ApplyPromotions(sale, nil, nil, nil)
+		return true
+	}
+
+	ids := make([]string, len(promotions))
+	for i, promotion := range promotions {
+		ids[i] = promotion.ID
+	}
+	usage, err := p.repo.GetUsage(ids, sale.CustomerID, sale.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get promotion usage: "+err.Error())
+		return false
+	}
+
+	productIDs := make([]string, len(sale.Items))
+	for i, item := range sale.Items {
+		productIDs[i] = item.ProductID
+	}
+	scopes, err := p.repo.GetProductScopes(productIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product categories: "+err.Error())
+		return false
+	}
+
+	models.ApplyPromotions(sale, promotions, scopes, usage)
+	return true
+}
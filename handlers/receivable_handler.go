@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReceivableHandler handles customer payments, open invoices and statements
+type ReceivableHandler struct {
+	*BaseHandler
+	repo         repositories.ReceivableRepository
+	customerRepo repositories.CustomerRepository
+}
+
+// NewReceivableHandler creates a new ReceivableHandler
+func NewReceivableHandler(db *pgx.Conn) *ReceivableHandler {
+	return &ReceivableHandler{
+		BaseHandler:  &BaseHandler{DB: db},
+		repo:         repositories.NewReceivableRepository(db),
+		customerRepo: repositories.NewCustomerRepository(db),
+	}
+}
+
+// GetOpenInvoices handles GET /customers/{id}/open-invoices
+func (h *ReceivableHandler) GetOpenInvoices(w http.ResponseWriter, r *http.Request) {
+	customer, ok := h.findCustomer(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	invoices, err := h.repo.GetOpenInvoices(customer.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get open invoices: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, invoices)
+}
+
+// GetCustomerStatement handles GET /customers/{id}/statement?start_date=2025-01-01&end_date=2025-01-31
+func (h *ReceivableHandler) GetCustomerStatement(w http.ResponseWriter, r *http.Request) {
+	customer, ok := h.findCustomer(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	startDate, endDate, ok := parseStatementPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	statement, err := h.repo.GetStatement(customer, startDate, endDate)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get customer statement: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, statement)
+}
+
+// GetCustomerPayments handles GET /customer-payments?customer_id=...
+func (h *ReceivableHandler) GetCustomerPayments(w http.ResponseWriter, r *http.Request) {
+	payments, err := h.repo.ListPayments(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get customer payments: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, payments)
+}
+
+// GetCustomerPayment handles GET /customer-payments/{id}
+func (h *ReceivableHandler) GetCustomerPayment(w http.ResponseWriter, r *http.Request) {
+	payment, err := h.repo.GetPayment(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get customer payment: "+err.Error())
+		return
+	}
+	if payment == nil {
+		respondWithError(w, http.StatusNotFound, "Customer payment not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, payment)
+}
+
+// CreateCustomerPayment handles POST /customer-payments
+func (h *ReceivableHandler) CreateCustomerPayment(w http.ResponseWriter, r *http.Request) {
+	var payment models.CustomerPayment
+	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := payment.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := h.findCustomer(w, payment.CustomerID); !ok {
+		return
+	}
+
+	payment.GenerateID()
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.JournalSourceCustomerPayment, payment.ID, payment.PaymentNo, payment.PaymentDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.CreatePayment(&payment, lock); err != nil {
+		if errors.Is(err, models.ErrPaymentExceedsBalance) || errors.Is(err, models.ErrInvalidAllocation) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to create customer payment: "+err.Error())
+		return
+	}
+
+	created, err := h.repo.GetPayment(payment.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving created payment")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// DeleteCustomerPayment handles DELETE /customer-payments/{id}
+func (h *ReceivableHandler) DeleteCustomerPayment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	payment, err := h.repo.GetPayment(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get customer payment: "+err.Error())
+		return
+	}
+	if payment == nil {
+		respondWithError(w, http.StatusNotFound, "Customer payment not found")
+		return
+	}
+	lock, ok := periodLock(w, r, models.PeriodActionDelete, models.JournalSourceCustomerPayment, id, payment.PaymentNo, payment.PaymentDate)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeletePayment(id, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to delete customer payment: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Customer payment deleted successfully"})
+}
+
+// findCustomer loads a customer, writing the error response and returning false when it cannot
+func (h *ReceivableHandler) findCustomer(w http.ResponseWriter, id string) (*models.Customer, bool) {
+	customer, err := h.customerRepo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get customer: "+err.Error())
+		return nil, false
+	}
+	if customer == nil {
+		respondWithError(w, http.StatusNotFound, "Customer not found")
+		return nil, false
+	}
+	return customer, true
+}
+
+// parseStatementPeriod reads the start_date and end_date of a statement, defaulting to the
+// last month. It writes a 400 response and returns false when a date is invalid.
+func parseStatementPeriod(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, now.Location())
+	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0)
+
+	if start := r.URL.Query().Get("start_date"); start != "" {
+		t, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start_date format (use YYYY-MM-DD)")
+			return startDate, endDate, false
+		}
+		startDate = t
+	}
+	if end := r.URL.Query().Get("end_date"); end != "" {
+		t, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end_date format (use YYYY-MM-DD)")
+			return startDate, endDate, false
+		}
+		// Set to end of day
+		endDate = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+	if endDate.Before(startDate) {
+		respondWithError(w, http.StatusBadRequest, "end_date must not be before start_date")
+		return startDate, endDate, false
+	}
+
+	return startDate, endDate, true
+}
@@ -17,6 +17,7 @@ type RejectHandler struct {
 	*BaseHandler
 	rejectRepo  repositories.RejectRepository
 	productRepo repositories.ProductRepository
+	units       unitResolver
 }
 
 // NewRejectHandler creates a new RejectHandler
@@ -25,6 +26,7 @@ func NewRejectHandler(db *pgx.Conn) *RejectHandler {
 		BaseHandler: &BaseHandler{DB: db},
 		rejectRepo:  repositories.NewRejectRepository(db),
 		productRepo: repositories.NewProductRepository(db),
+		units:       newUnitResolver(db),
 	}
 }
 
@@ -131,6 +133,15 @@ func (h *RejectHandler) CreateReject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Convert quantities and unit costs entered in other units to the base unit
+	if !h.units.applyReject(w, &reject) {
+		return
+	}
+	if err := reject.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Validate and process items
 	for i, item := range reject.Items {
 		if item.ProductID == "" {
@@ -160,8 +171,8 @@ func (h *RejectHandler) CreateReject(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Calculate subtotal if not provided
-		if item.Subtotal == 0 {
-			reject.Items[i].Subtotal = item.UnitCost * float64(item.Quantity)
+		if item.Subtotal.IsZero() {
+			reject.Items[i].CalculateSubtotal()
 		}
 
 		// Check stock availability if status is completed
@@ -173,9 +184,15 @@ func (h *RejectHandler) CreateReject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Refuse rejects dated inside a closed accounting period
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.CostSourceReject, reject.ID, reject.ReferenceNo, reject.RejectDate)
+	if !ok {
+		return
+	}
+
 	// Create the reject
-	if err := h.rejectRepo.Create(&reject); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create reject: "+err.Error())
+	if err := h.rejectRepo.Create(&reject, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to create reject: "+err.Error())
 		return
 	}
 
@@ -231,13 +248,20 @@ func (h *RejectHandler) UpdateReject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Refuse moving a reject into, out of or within a closed accounting period
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceReject, id, existingReject.ReferenceNo,
+		existingReject.RejectDate, reject.RejectDate)
+	if !ok {
+		return
+	}
+
 	// Update fields
 	reject.ID = id
 	reject.Items = existingReject.Items // Keep existing items
 
 	// Update the reject
-	if err := h.rejectRepo.Update(&reject); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update reject: "+err.Error())
+	if err := h.rejectRepo.Update(&reject, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to update reject: "+err.Error())
 		return
 	}
 
@@ -273,9 +297,14 @@ func (h *RejectHandler) DeleteReject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lock, ok := periodLock(w, r, models.PeriodActionDelete, models.CostSourceReject, id, reject.ReferenceNo, reject.RejectDate)
+	if !ok {
+		return
+	}
+
 	// Delete the reject
-	if err := h.rejectRepo.Delete(id); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete reject: "+err.Error())
+	if err := h.rejectRepo.Delete(id, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to delete reject: "+err.Error())
 		return
 	}
 
@@ -303,6 +332,10 @@ func (h *RejectHandler) AddRejectItem(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Cannot modify a completed or cancelled reject")
 		return
 	}
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceReject, rejectID, reject.ReferenceNo, reject.RejectDate)
+	if !ok {
+		return
+	}
 
 	// Parse item data
 	var item models.RejectItem
@@ -321,10 +354,17 @@ func (h *RejectHandler) AddRejectItem(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Product ID is required")
 		return
 	}
+	if !h.units.applyLine(w, item.ProductID, &item.LineUnit, &item.Quantity, &item.UnitCost) {
+		return
+	}
 	if item.Quantity <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Quantity must be greater than zero")
 		return
 	}
+	if err := item.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Get product details
 	product, err := h.productRepo.GetByID(item.ProductID)
@@ -343,13 +383,13 @@ func (h *RejectHandler) AddRejectItem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Calculate subtotal if not provided
-	if item.Subtotal == 0 {
-		item.Subtotal = item.UnitCost * float64(item.Quantity)
+	if item.Subtotal.IsZero() {
+		item.CalculateSubtotal()
 	}
 
 	// Add the item
-	if err := h.rejectRepo.AddRejectItem(&item); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to add reject item: "+err.Error())
+	if err := h.rejectRepo.AddRejectItem(&item, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to add reject item: "+err.Error())
 		return
 	}
 
@@ -385,6 +425,10 @@ func (h *RejectHandler) UpdateRejectItem(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Cannot modify a completed or cancelled reject")
 		return
 	}
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceReject, rejectID, reject.ReferenceNo, reject.RejectDate)
+	if !ok {
+		return
+	}
 
 	// Parse item data
 	var item models.RejectItem
@@ -404,19 +448,26 @@ func (h *RejectHandler) UpdateRejectItem(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Product ID is required")
 		return
 	}
+	if !h.units.applyLine(w, item.ProductID, &item.LineUnit, &item.Quantity, &item.UnitCost) {
+		return
+	}
 	if item.Quantity <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Quantity must be greater than zero")
 		return
 	}
+	if err := item.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Calculate subtotal if not provided
-	if item.Subtotal == 0 {
-		item.Subtotal = item.UnitCost * float64(item.Quantity)
+	if item.Subtotal.IsZero() {
+		item.CalculateSubtotal()
 	}
 
 	// Update the item
-	if err := h.rejectRepo.UpdateRejectItem(&item); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update reject item: "+err.Error())
+	if err := h.rejectRepo.UpdateRejectItem(&item, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to update reject item: "+err.Error())
 		return
 	}
 
@@ -445,10 +496,14 @@ func (h *RejectHandler) DeleteRejectItem(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Cannot modify a completed or cancelled reject")
 		return
 	}
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceReject, rejectID, reject.ReferenceNo, reject.RejectDate)
+	if !ok {
+		return
+	}
 
 	// Delete the item
-	if err := h.rejectRepo.DeleteRejectItem(itemID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete reject item: "+err.Error())
+	if err := h.rejectRepo.DeleteRejectItem(itemID, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to delete reject item: "+err.Error())
 		return
 	}
 
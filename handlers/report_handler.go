@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReportHandler handles financial reports
+type ReportHandler struct {
+	*BaseHandler
+	costingRepo    repositories.CostingRepository
+	reportRepo     repositories.ReportRepository
+	receivableRepo repositories.ReceivableRepository
+	payableRepo    repositories.PayableRepository
+}
+
+// NewReportHandler creates a new ReportHandler
+func NewReportHandler(db *pgx.Conn) *ReportHandler {
+	return &ReportHandler{
+		BaseHandler:    &BaseHandler{DB: db},
+		costingRepo:    repositories.NewCostingRepository(db),
+		reportRepo:     repositories.NewReportRepository(db),
+		receivableRepo: repositories.NewReceivableRepository(db),
+		payableRepo:    repositories.NewPayableRepository(db),
+	}
+}
+
+// GetInventoryValuation handles GET /reports/inventory-valuation?as_of=2025-01-31
+func (h *ReportHandler) GetInventoryValuation(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now()
+	if a := r.URL.Query().Get("as_of"); a != "" {
+		t, err := time.Parse("2006-01-02", a)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid as_of date format (use YYYY-MM-DD)")
+			return
+		}
+		// Include the whole day
+		asOf = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	valuation, err := h.costingRepo.GetInventoryValuation(asOf)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get inventory valuation: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, valuation)
+}
+
+// GetProductCostMovements handles GET /products/{id}/cost-movements
+func (h *ReportHandler) GetProductCostMovements(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	movements, err := h.costingRepo.GetProductMovements(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get cost movements: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, movements)
+}
+
+// GetProfitReport handles GET /reports/profit?start_date=2025-01-01&end_date=2025-01-31&group_by=category
+func (h *ReportHandler) GetProfitReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Default to last 30 days
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, -1, 0)
+
+	if start := query.Get("start_date"); start != "" {
+		t, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid start_date format (use YYYY-MM-DD)")
+			return
+		}
+		startDate = t
+	}
+	if end := query.Get("end_date"); end != "" {
+		t, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid end_date format (use YYYY-MM-DD)")
+			return
+		}
+		// Set to end of day
+		endDate = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+	if endDate.Before(startDate) {
+		respondWithError(w, http.StatusBadRequest, "end_date must not be before start_date")
+		return
+	}
+
+	filter := models.ProfitReportFilter{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		GroupBy:    models.ProfitGroupByProduct,
+		Interval:   models.ReportIntervalDay,
+		Platform:   query.Get("platform"),
+		CustomerID: query.Get("customer_id"),
+		CategoryID: query.Get("category_id"),
+	}
+	if g := query.Get("group_by"); g != "" {
+		filter.GroupBy = models.ProfitGroupBy(g)
+		if !filter.GroupBy.IsValid() {
+			respondWithError(w, http.StatusBadRequest, "Invalid group_by (use product, variant, category, customer, platform or period)")
+			return
+		}
+	}
+	if i := query.Get("interval"); i != "" {
+		filter.Interval = models.ReportInterval(i)
+		if !filter.Interval.IsValid() {
+			respondWithError(w, http.StatusBadRequest, "Invalid interval (use day, week or month)")
+			return
+		}
+	}
+	if l := query.Get("category_level"); l != "" {
+		level, err := strconv.Atoi(l)
+		if err != nil || level < 1 {
+			respondWithError(w, http.StatusBadRequest, "category_level must be a positive integer")
+			return
+		}
+		filter.CategoryLevel = level
+	}
+
+	report, err := h.reportRepo.GetProfitReport(filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get profit report: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// GetARAging handles GET /reports/ar-aging?as_of=2025-01-31
+func (h *ReportHandler) GetARAging(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now()
+	if a := r.URL.Query().Get("as_of"); a != "" {
+		t, err := time.Parse("2006-01-02", a)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid as_of date format (use YYYY-MM-DD)")
+			return
+		}
+		// Include the whole day
+		asOf = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	report, err := h.receivableRepo.GetAging(asOf)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get receivables aging: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// GetAPAging handles GET /reports/ap-aging?as_of=2025-01-31
+func (h *ReportHandler) GetAPAging(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now()
+	if a := r.URL.Query().Get("as_of"); a != "" {
+		t, err := time.Parse("2006-01-02", a)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid as_of date format (use YYYY-MM-DD)")
+			return
+		}
+		// Include the whole day
+		asOf = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	report, err := h.payableRepo.GetAging(asOf)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get payables aging: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
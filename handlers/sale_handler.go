@@ -19,6 +19,11 @@ type SaleHandler struct {
 	saleRepo     repositories.SaleRepository
 	customerRepo repositories.CustomerRepository
 	prodRepo     repositories.ProductRepository
+	rateRepo     repositories.ExchangeRateRepository
+	prices       priceResolver
+	promotions   promotionResolver
+	taxes        taxResolver
+	units        unitResolver
 }
 
 // NewSaleHandler creates a new SaleHandler
@@ -28,6 +33,11 @@ func NewSaleHandler(db *pgx.Conn) *SaleHandler {
 		saleRepo:     repositories.NewSaleRepository(db),
 		customerRepo: repositories.NewCustomerRepository(db),
 		prodRepo:     repositories.NewProductRepository(db),
+		rateRepo:     repositories.NewExchangeRateRepository(db),
+		prices:       newPriceResolver(db),
+		promotions:   newPromotionResolver(db),
+		taxes:        newTaxResolver(db),
+		units:        newUnitResolver(db),
 	}
 }
 
@@ -140,16 +150,52 @@ func (h *SaleHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Calculate totals if not provided
-	if sale.Total == 0 {
-		sale.CalculateTotals()
+	// Refuse sales dated inside a closed accounting period
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.CostSourceSale, sale.ID, sale.ReferenceNo, sale.SaleDate)
+	if !ok {
+		return
 	}
 
-	if err := h.saleRepo.Create(&sale); err != nil {
+	// Set the due date from the customer's payment terms
+	if err := h.assignDueDate(&sale); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	// Resolve the exchange rate for foreign-currency sales
+	rate, err := resolveExchangeRate(h.rateRepo, sale.Currency, sale.SaleDate, sale.ExchangeRate)
+	if err != nil {
+		respondWithRateError(w, err)
+		return
+	}
+	sale.ExchangeRate = rate
+
+	// Convert quantities and prices entered in other units to the base unit and price items sent
+	// without a unit price, check the sale is not too large to total, then apply the running
+	// promotions, assign tax codes and default the tax mode
+	if !h.units.applySale(w, &sale) || !h.prices.applySale(w, &sale) {
+		return
+	}
+	if err := sale.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.promotions.applySale(w, &sale) || !h.taxes.applySale(w, &sale) {
+		return
+	}
+
+	// Calculate totals if not provided or when prices, discounts or tax were calculated
+	if sale.Total.IsZero() || hasCalculatedItems(sale.Items) {
+		sale.CalculateTotals()
+	} else {
+		sale.ConvertToBase()
+	}
+
+	if err := h.saleRepo.Create(&sale, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
 	// Reload to get relationships
 	createdSale, err := h.saleRepo.GetByID(sale.ID)
 	if err != nil {
@@ -200,6 +246,12 @@ func (h *SaleHandler) UpdateSale(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Refuse moving a sale into, out of or within a closed accounting period
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceSale, id, existing.ReferenceNo, existing.SaleDate, sale.SaleDate)
+	if !ok {
+		return
+	}
+
 	// Update fields
 	existing.ReferenceNo = sale.ReferenceNo
 	existing.Status = sale.Status
@@ -209,12 +261,48 @@ func (h *SaleHandler) UpdateSale(w http.ResponseWriter, r *http.Request) {
 	existing.Items = sale.Items
 	existing.Payments = sale.Payments
 	existing.Platform = sale.Platform
+	existing.DueDate = sale.DueDate
+	if err := h.assignDueDate(existing); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Keep the existing currency and rate unless new ones are given
+	if sale.Currency != "" {
+		existing.Currency = sale.Currency
+		existing.ExchangeRate = sale.ExchangeRate
+	} else if sale.ExchangeRate.IsPositive() {
+		existing.ExchangeRate = sale.ExchangeRate
+	}
+	rate, err := resolveExchangeRate(h.rateRepo, existing.Currency, existing.SaleDate, existing.ExchangeRate)
+	if err != nil {
+		respondWithRateError(w, err)
+		return
+	}
+	existing.ExchangeRate = rate
+
+	// Keep the existing tax mode unless a new one is given, then convert quantities and prices to
+	// the base unit, price the items sent without a unit price, check the sale is not too large
+	// to total, apply the running promotions again and assign tax codes
+	if sale.TaxMode != "" {
+		existing.TaxMode = sale.TaxMode
+	}
+	if !h.units.applySale(w, existing) || !h.prices.applySale(w, existing) {
+		return
+	}
+	if err := existing.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.promotions.applySale(w, existing) || !h.taxes.applySale(w, existing) {
+		return
+	}
 
 	// Recalculate totals
 	existing.CalculateTotals()
 
-	if err := h.saleRepo.Update(existing); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := h.saleRepo.Update(existing, lock); err != nil {
+		respondWithError(w, lockedStatus(err, saleConflictStatus(err)), err.Error())
 		return
 	}
 
@@ -233,7 +321,8 @@ func (h *SaleHandler) DeleteSale(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Check if sale exists
-	if _, err := h.saleRepo.GetByID(id); err != nil {
+	existing, err := h.saleRepo.GetByID(id)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			respondWithError(w, http.StatusNotFound, "Sale not found")
 			return
@@ -241,15 +330,58 @@ func (h *SaleHandler) DeleteSale(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	lock := models.NewPeriodLock(models.PeriodActionDelete, models.CostSourceSale, id, "")
+	if existing != nil {
+		var ok bool
+		if lock, ok = periodLock(w, r, models.PeriodActionDelete, models.CostSourceSale, id, existing.ReferenceNo, existing.SaleDate); !ok {
+			return
+		}
+	}
 
-	if err := h.saleRepo.Delete(id); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if err := h.saleRepo.Delete(id, lock); err != nil {
+		respondWithError(w, lockedStatus(err, saleConflictStatus(err)), err.Error())
 		return
 	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
+// assignDueDate sets the due date of a sale from its customer's payment terms unless one was given.
+// Sales without a customer are due on the sale date.
+func (h *SaleHandler) assignDueDate(sale *models.Sale) error {
+	terms := models.PaymentTermsCash
+	if sale.DueDate == nil && sale.CustomerID != nil && *sale.CustomerID != "" {
+		customer, err := h.customerRepo.GetByID(*sale.CustomerID)
+		if err != nil {
+			return err
+		}
+		if customer != nil && customer.PaymentTerms.IsValid() {
+			terms = customer.PaymentTerms
+		}
+	}
+	sale.SetDueDate(terms)
+	return nil
+}
+
+// hasCalculatedItems reports whether any sale item has its price from a price rule, a
+// discount from a promotion or its tax from a tax code
+func hasCalculatedItems(items []models.SaleItem) bool {
+	for _, item := range items {
+		if item.IsCalculated() || !item.PromotionDiscount.IsZero() || item.HasTaxCode() {
+			return true
+		}
+	}
+	return false
+}
+
+// saleConflictStatus returns 409 when a sale cannot change because payments are allocated to it
+func saleConflictStatus(err error) int {
+	if errors.Is(err, repositories.ErrSaleHasPayments) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *SaleHandler) GetCustomerSales(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	customerID := vars["id"]
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// SettingHandler handles company settings
+type SettingHandler struct {
+	*BaseHandler
+	repo repositories.SettingRepository
+}
+
+// NewSettingHandler creates a new SettingHandler
+func NewSettingHandler(db *pgx.Conn) *SettingHandler {
+	return &SettingHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewSettingRepository(db),
+	}
+}
+
+// GetSettings handles GET /settings
+func (h *SettingHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get settings: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSetting handles PUT /settings/{key}
+func (h *SettingHandler) UpdateSetting(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var payload struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	setting := models.Setting{Key: vars["key"], Value: payload.Value}
+	if err := setting.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.Set(&setting); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update setting: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, setting)
+}
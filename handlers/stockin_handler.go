@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"inventory-go/models"
+	"inventory-go/money"
 	"inventory-go/repositories"
 	"net/http"
 	"strconv"
@@ -18,6 +20,9 @@ type StockInHandler struct {
 	stockInRepo  repositories.StockInRepository
 	productRepo  repositories.ProductRepository
 	supplierRepo repositories.SupplierRepository
+	rateRepo     repositories.ExchangeRateRepository
+	taxes        taxResolver
+	units        unitResolver
 }
 
 // NewStockInHandler creates a new StockInHandler
@@ -27,6 +32,9 @@ func NewStockInHandler(db *pgx.Conn) *StockInHandler {
 		stockInRepo:  repositories.NewStockInRepository(db),
 		productRepo:  repositories.NewProductRepository(db),
 		supplierRepo: repositories.NewSupplierRepository(db),
+		rateRepo:     repositories.NewExchangeRateRepository(db),
+		taxes:        newTaxResolver(db),
+		units:        newUnitResolver(db),
 	}
 }
 
@@ -131,6 +139,7 @@ func (h *StockInHandler) CreateStockIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer r.Body.Close()
+	stockIn.Opening = false // Only product imports record opening stock
 
 	// Validate the stock-in
 	if stockIn.ReferenceNo == "" {
@@ -151,6 +160,16 @@ func (h *StockInHandler) CreateStockIn(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Convert quantities and unit costs entered in other units to the base unit, then assign tax
+	// codes to the items and default the tax mode
+	if !h.units.applyStockIn(w, &stockIn) || !h.taxes.applyStockIn(w, &stockIn) {
+		return
+	}
+	if err := stockIn.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Validate and process items
 	for i, item := range stockIn.Items {
 		if item.ProductID == "" {
@@ -179,15 +198,46 @@ func (h *StockInHandler) CreateStockIn(w http.ResponseWriter, r *http.Request) {
 			stockIn.Items[i].ProductName = product.Basic.Name
 		}
 
-		// Calculate subtotal if not provided
-		if item.Subtotal == 0 {
-			stockIn.Items[i].Subtotal = item.UnitCost * float64(item.Quantity)
+		// Calculate subtotal and tax if not provided or when tax is calculated from a tax code
+		if item.Subtotal.IsZero() || item.HasTaxCode() {
+			stockIn.Items[i].CalculateTax(stockIn.TaxMode)
 		}
 	}
 
+	// Refuse stock-ins dated inside a closed accounting period
+	lock, ok := periodLock(w, r, models.PeriodActionCreate, models.CostSourceStockIn, stockIn.ID, stockIn.ReferenceNo, stockIn.OrderDate)
+	if !ok {
+		return
+	}
+
+	// Resolve the exchange rate for foreign-currency purchases
+	rate, err := resolveExchangeRate(h.rateRepo, stockIn.Currency, stockIn.OrderDate, stockIn.ExchangeRate)
+	if err != nil {
+		respondWithRateError(w, err)
+		return
+	}
+	stockIn.ExchangeRate = rate
+	if err := stockIn.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Set the due date from the supplier's payment terms
+	if err := h.assignDueDate(&stockIn); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Calculate total if not provided or when tax is calculated from tax codes
+	if stockIn.Total.IsZero() || stockInHasTaxCodes(stockIn.Items) {
+		stockIn.CalculateTotals()
+	} else {
+		stockIn.ConvertToBase()
+	}
+
 	// Create the stock-in
-	if err := h.stockInRepo.Create(&stockIn); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create stock-in: "+err.Error())
+	if err := h.stockInRepo.Create(&stockIn, lock); err != nil {
+		respondWithError(w, lockedStatus(err, http.StatusInternalServerError), "Failed to create stock-in: "+err.Error())
 		return
 	}
 
@@ -219,13 +269,74 @@ func (h *StockInHandler) UpdateStockIn(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	// Refuse moving a stock-in into, out of or within a closed accounting period
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceStockIn, id, existingStockIn.ReferenceNo,
+		existingStockIn.OrderDate, stockIn.OrderDate)
+	if !ok {
+		return
+	}
+
 	// Update fields
 	stockIn.ID = id
 	stockIn.Items = existingStockIn.Items // Keep existing items
+	stockIn.Opening = existingStockIn.Opening
+	if stockIn.Opening && !stockIn.Paid.IsZero() {
+		respondWithError(w, http.StatusBadRequest, "Opening stock is not paid for")
+		return
+	}
+
+	// The items were priced in the existing tax mode, so it can only change while there are none
+	if stockIn.TaxMode == "" {
+		stockIn.TaxMode = existingStockIn.TaxMode
+	}
+	if stockIn.TaxMode != existingStockIn.TaxMode && len(stockIn.Items) > 0 {
+		respondWithError(w, http.StatusBadRequest, "Tax mode cannot change once a stock-in has items")
+		return
+	}
+	if !stockIn.TaxMode.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "tax_mode must be \"exclusive\" or \"inclusive\"")
+		return
+	}
+
+	// Keep the existing currency and rate unless new ones are given
+	if stockIn.Currency == "" {
+		stockIn.Currency = existingStockIn.Currency
+		if !stockIn.ExchangeRate.IsPositive() {
+			stockIn.ExchangeRate = existingStockIn.ExchangeRate
+		}
+	}
+	rate, err := resolveExchangeRate(h.rateRepo, stockIn.Currency, stockIn.OrderDate, stockIn.ExchangeRate)
+	if err != nil {
+		respondWithRateError(w, err)
+		return
+	}
+	stockIn.ExchangeRate = rate
+	if err := stockIn.ValidateAmounts(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	stockIn.ConvertToBase()
+
+	// Supplier payments are part of what was paid; credits are kept as recorded
+	recorded := money.Zero(stockIn.Currency)
+	for _, p := range existingStockIn.Payments {
+		recorded = recorded.Add(p.Amount.In(stockIn.Currency))
+	}
+	if stockIn.Paid.Cmp(recorded) < 0 {
+		respondWithError(w, http.StatusBadRequest, "Paid cannot be less than the supplier payments recorded ("+recorded.String()+")")
+		return
+	}
+	stockIn.Credited = existingStockIn.Credited.In(stockIn.Currency)
+	stockIn.CalculateBalance()
+
+	if err := h.assignDueDate(&stockIn); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	// Update the stock-in
-	if err := h.stockInRepo.Update(&stockIn); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update stock-in: "+err.Error())
+	if err := h.stockInRepo.Update(&stockIn, lock); err != nil {
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), "Failed to update stock-in: "+err.Error())
 		return
 	}
 
@@ -254,10 +365,14 @@ func (h *StockInHandler) DeleteStockIn(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusNotFound, "Stock-in not found")
 		return
 	}
+	lock, ok := periodLock(w, r, models.PeriodActionDelete, models.CostSourceStockIn, id, stockIn.ReferenceNo, stockIn.OrderDate)
+	if !ok {
+		return
+	}
 
 	// Delete the stock-in
-	if err := h.stockInRepo.Delete(id); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete stock-in: "+err.Error())
+	if err := h.stockInRepo.Delete(id, lock); err != nil {
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), "Failed to delete stock-in: "+err.Error())
 		return
 	}
 
@@ -279,6 +394,10 @@ func (h *StockInHandler) AddStockInItem(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusNotFound, "Stock-in not found")
 		return
 	}
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceStockIn, stockInID, stockIn.ReferenceNo, stockIn.OrderDate)
+	if !ok {
+		return
+	}
 
 	// Parse item data
 	var item models.StockInItem
@@ -297,6 +416,9 @@ func (h *StockInHandler) AddStockInItem(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Product ID is required")
 		return
 	}
+	if !h.units.applyLine(w, item.ProductID, &item.LineUnit, &item.Quantity, &item.UnitCost) {
+		return
+	}
 	if item.Quantity <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Quantity must be greater than zero")
 		return
@@ -318,14 +440,14 @@ func (h *StockInHandler) AddStockInItem(w http.ResponseWriter, r *http.Request)
 		item.ProductName = product.Basic.Name
 	}
 
-	// Calculate subtotal if not provided
-	if item.Subtotal == 0 {
-		item.Subtotal = item.UnitCost * float64(item.Quantity)
+	// Calculate subtotal and tax in the stock-in's tax mode
+	if !h.priceItem(w, stockIn, &item) {
+		return
 	}
 
 	// Add the item
-	if err := h.stockInRepo.AddStockInItem(&item); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to add stock-in item: "+err.Error())
+	if err := h.stockInRepo.AddStockInItem(&item, lock); err != nil {
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), "Failed to add stock-in item: "+err.Error())
 		return
 	}
 
@@ -363,19 +485,37 @@ func (h *StockInHandler) UpdateStockInItem(w http.ResponseWriter, r *http.Reques
 		respondWithError(w, http.StatusBadRequest, "Product ID is required")
 		return
 	}
+	if !h.units.applyLine(w, item.ProductID, &item.LineUnit, &item.Quantity, &item.UnitCost) {
+		return
+	}
 	if item.Quantity <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Quantity must be greater than zero")
 		return
 	}
 
-	// Calculate subtotal if not provided
-	if item.Subtotal == 0 {
-		item.Subtotal = item.UnitCost * float64(item.Quantity)
+	// Get the stock-in for its currency and rate
+	stockIn, err := h.stockInRepo.GetByID(stockInID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get stock-in: "+err.Error())
+		return
+	}
+	if stockIn == nil {
+		respondWithError(w, http.StatusNotFound, "Stock-in not found")
+		return
+	}
+	lock, ok := periodLock(w, r, models.PeriodActionUpdate, models.CostSourceStockIn, stockInID, stockIn.ReferenceNo, stockIn.OrderDate)
+	if !ok {
+		return
+	}
+
+	// Calculate subtotal and tax in the stock-in's tax mode
+	if !h.priceItem(w, stockIn, &item) {
+		return
 	}
 
 	// Update the item
-	if err := h.stockInRepo.UpdateStockInItem(&item); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to update stock-in item: "+err.Error())
+	if err := h.stockInRepo.UpdateStockInItem(&item, lock); err != nil {
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), "Failed to update stock-in item: "+err.Error())
 		return
 	}
 
@@ -385,11 +525,26 @@ func (h *StockInHandler) UpdateStockInItem(w http.ResponseWriter, r *http.Reques
 // DeleteStockInItem handles DELETE /stockins/{stockInId}/items/{itemId}
 func (h *StockInHandler) DeleteStockInItem(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
+	stockInID := vars["stockInId"]
 	itemID := vars["itemId"]
 
+	// Items of a stock-in in a closed accounting period cannot be removed
+	stockIn, err := h.stockInRepo.GetByID(stockInID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get stock-in: "+err.Error())
+		return
+	}
+	lock := models.NewPeriodLock(models.PeriodActionUpdate, models.CostSourceStockIn, stockInID, "")
+	if stockIn != nil {
+		var ok bool
+		if lock, ok = periodLock(w, r, models.PeriodActionUpdate, models.CostSourceStockIn, stockInID, stockIn.ReferenceNo, stockIn.OrderDate); !ok {
+			return
+		}
+	}
+
 	// Delete the item
-	if err := h.stockInRepo.DeleteStockInItem(itemID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete stock-in item: "+err.Error())
+	if err := h.stockInRepo.DeleteStockInItem(itemID, lock); err != nil {
+		respondWithError(w, lockedStatus(err, costConflictStatus(err)), "Failed to delete stock-in item: "+err.Error())
 		return
 	}
 
@@ -507,3 +662,59 @@ func (h *StockInHandler) GetDailyStockIn(w http.ResponseWriter, r *http.Request)
 
 	respondWithJSON(w, http.StatusOK, dailyData)
 }
+
+// priceItem assigns a tax code to an item added to or changed on a stock-in, calculates
+// its subtotal and tax when needed and converts it to the base currency. When it returns
+// false the error response has been written.
+func (h *StockInHandler) priceItem(w http.ResponseWriter, stockIn *models.StockIn, item *models.StockInItem) bool {
+	if err := item.ValidateAmounts(stockIn.Currency, stockIn.ExchangeRate); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	mode := stockIn.TaxMode
+	line := taxLine{productID: item.ProductID, tax: &item.LineTax, manual: !item.Tax.IsZero()}
+	if !h.taxes.apply(w, &mode, []taxLine{line}) {
+		return false
+	}
+	if item.Subtotal.IsZero() || item.HasTaxCode() {
+		item.CalculateTax(mode)
+	}
+	item.ConvertToBase(stockIn.Currency, stockIn.ExchangeRate)
+	return true
+}
+
+// stockInHasTaxCodes reports whether any stock-in item has its tax calculated from a tax code
+func stockInHasTaxCodes(items []models.StockInItem) bool {
+	for _, item := range items {
+		if item.HasTaxCode() {
+			return true
+		}
+	}
+	return false
+}
+
+// assignDueDate sets the due date of a stock-in from its supplier's payment terms unless one was
+// given. Stock-ins without a supplier are due on the order date.
+func (h *StockInHandler) assignDueDate(stockIn *models.StockIn) error {
+	terms := models.PaymentTermsCash
+	if stockIn.DueDate == nil && stockIn.SupplierID != nil && *stockIn.SupplierID != "" {
+		supplier, err := h.supplierRepo.GetByID(*stockIn.SupplierID)
+		if err != nil {
+			return err
+		}
+		if supplier != nil && supplier.PaymentTerms.IsValid() {
+			terms = supplier.PaymentTerms
+		}
+	}
+	stockIn.SetDueDate(terms)
+	return nil
+}
+
+// costConflictStatus returns 409 when a change would reverse stock that was already issued
+// or un-complete a stock-in that supplier payments were recorded against
+func costConflictStatus(err error) int {
+	if errors.Is(err, repositories.ErrCostLayerConsumed) || errors.Is(err, repositories.ErrStockInHasPayments) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
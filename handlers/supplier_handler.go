@@ -64,6 +64,14 @@ func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request)
 	}
 	defer r.Body.Close()
 
+	if supplier.PaymentTerms == "" {
+		supplier.PaymentTerms = models.PaymentTermsCash
+	}
+	if !supplier.PaymentTerms.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment_terms (use cash, net_7, net_14 or net_30)")
+		return
+	}
+
 	if err := h.repo.Create(&supplier); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create supplier: "+err.Error())
 		return
@@ -88,6 +96,14 @@ func (h *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request)
 	// Ensure ID in path matches body
 	supplier.ID = id
 
+	if supplier.PaymentTerms == "" {
+		supplier.PaymentTerms = models.PaymentTermsCash
+	}
+	if !supplier.PaymentTerms.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "Invalid payment_terms (use cash, net_7, net_14 or net_30)")
+		return
+	}
+
 	if err := h.repo.Update(&supplier); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update supplier: "+err.Error())
 		return
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaxHandler handles tax codes and the VAT report
+type TaxHandler struct {
+	*BaseHandler
+	repo repositories.TaxRepository
+}
+
+// NewTaxHandler creates a new TaxHandler
+func NewTaxHandler(db *pgx.Conn) *TaxHandler {
+	return &TaxHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewTaxRepository(db),
+	}
+}
+
+// GetTaxCodes handles GET /tax-codes?active=true
+func (h *TaxHandler) GetTaxCodes(w http.ResponseWriter, r *http.Request) {
+	activeOnly, _ := strconv.ParseBool(r.URL.Query().Get("active"))
+
+	codes, err := h.repo.GetAll(activeOnly)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tax codes: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, codes)
+}
+
+// GetTaxCode handles GET /tax-codes/{id}
+func (h *TaxHandler) GetTaxCode(w http.ResponseWriter, r *http.Request) {
+	code, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tax code: "+err.Error())
+		return
+	}
+	if code == nil {
+		respondWithError(w, http.StatusNotFound, "Tax code not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, code)
+}
+
+// CreateTaxCode handles POST /tax-codes
+func (h *TaxHandler) CreateTaxCode(w http.ResponseWriter, r *http.Request) {
+	code := models.TaxCode{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&code); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := code.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &code) {
+		return
+	}
+
+	if err := h.repo.Create(&code); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create tax code: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, code)
+}
+
+// UpdateTaxCode handles PUT /tax-codes/{id}
+func (h *TaxHandler) UpdateTaxCode(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tax code: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Tax code not found")
+		return
+	}
+
+	code := *existing
+	if err := json.NewDecoder(r.Body).Decode(&code); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	code.ID = id
+	if err := code.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &code) {
+		return
+	}
+
+	if err := h.repo.Update(&code); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update tax code: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, code)
+}
+
+// DeleteTaxCode handles DELETE /tax-codes/{id}
+func (h *TaxHandler) DeleteTaxCode(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	code, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tax code: "+err.Error())
+		return
+	}
+	if code == nil {
+		respondWithError(w, http.StatusNotFound, "Tax code not found")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete tax code: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Tax code deleted successfully"})
+}
+
+// GetVATReport handles GET /reports/vat?year=2025&month=1. It defaults to the previous
+// month, the one normally being filed.
+func (h *TaxHandler) GetVATReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	previous := time.Now().AddDate(0, -1, 0)
+	year, month := previous.Year(), int(previous.Month())
+
+	if y := query.Get("year"); y != "" {
+		v, err := strconv.Atoi(y)
+		if err != nil || v < 1 {
+			respondWithError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+		year = v
+	}
+	if m := query.Get("month"); m != "" {
+		v, err := strconv.Atoi(m)
+		if err != nil || v < 1 || v > 12 {
+			respondWithError(w, http.StatusBadRequest, "Invalid month (use 1-12)")
+			return
+		}
+		month = v
+	}
+
+	report, err := h.repo.GetVATReport(year, month)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get VAT report: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// checkUniqueCode responds with 409 and returns false when another tax code has the same code
+func (h *TaxHandler) checkUniqueCode(w http.ResponseWriter, code *models.TaxCode) bool {
+	existing, err := h.repo.GetByCode(code.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check tax code: "+err.Error())
+		return false
+	}
+	if existing != nil && existing.ID != code.ID {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Tax code %s already exists", code.Code))
+		return false
+	}
+	return true
+}
+
+// taxLine is a document line whose tax code may need to be resolved
+type taxLine struct {
+	productID string
+	tax       *models.LineTax
+
+	// manual is set when the line was given a tax amount without a tax code; it is left as is
+	manual bool
+}
+
+// taxResolver assigns tax codes to document lines and defaults the tax mode from settings
+type taxResolver struct {
+	repo     repositories.TaxRepository
+	settings repositories.SettingRepository
+}
+
+// newTaxResolver creates a taxResolver
+func newTaxResolver(db *pgx.Conn) taxResolver {
+	return taxResolver{
+		repo:     repositories.NewTaxRepository(db),
+		settings: repositories.NewSettingRepository(db),
+	}
+}
+
+// apply sets the document's tax mode from the tax_mode setting when none was given and
+// gives each line its tax code: the one named on the line, else the product's, its
+// category's or the default. When it returns false the error response has been written.
+func (t taxResolver) apply(w http.ResponseWriter, mode *models.TaxMode, lines []taxLine) bool {
+	if *mode == "" {
+		value, err := t.settings.Get(models.SettingTaxMode)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get tax mode: "+err.Error())
+			return false
+		}
+		*mode = models.TaxMode(value)
+	}
+	if !mode.IsValid() {
+		respondWithError(w, http.StatusBadRequest, "tax_mode must be \"exclusive\" or \"inclusive\"")
+		return false
+	}
+
+	var unresolved []string
+	for _, line := range lines {
+		if line.tax.HasTaxCode() {
+			code, err := t.repo.GetByID(*line.tax.TaxCodeID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to get tax code: "+err.Error())
+				return false
+			}
+			if code == nil || !code.Active {
+				respondWithError(w, http.StatusBadRequest, "Tax code not found: "+*line.tax.TaxCodeID)
+				return false
+			}
+			line.tax.SetTaxCode(code)
+			continue
+		}
+		if !line.manual {
+			unresolved = append(unresolved, line.productID)
+		}
+	}
+	if len(unresolved) == 0 {
+		return true
+	}
+
+	codes, err := t.repo.ResolveProductTaxCodes(unresolved)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to resolve tax codes: "+err.Error())
+		return false
+	}
+	for _, line := range lines {
+		if line.tax.HasTaxCode() || line.manual {
+			continue
+		}
+		if code, ok := codes[line.productID]; ok {
+			line.tax.SetTaxCode(code)
+		}
+	}
+	return true
+}
+
+// applySale resolves the tax mode and tax codes of a sale's items
+func (t taxResolver) applySale(w http.ResponseWriter, sale *models.Sale) bool {
+	lines := make([]taxLine, len(sale.Items))
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		lines[i] = taxLine{productID: item.ProductID, tax: &item.LineTax, manual: !item.Tax.IsZero()}
+	}
+	return t.apply(w, &sale.TaxMode, lines)
+}
+
+// applyStockIn resolves the tax mode and tax codes of a stock-in's items
+func (t taxResolver) applyStockIn(w http.ResponseWriter, stockIn *models.StockIn) bool {
+	lines := make([]taxLine, len(stockIn.Items))
+	for i := range stockIn.Items {
+		item := &stockIn.Items[i]
+		lines[i] = taxLine{productID: item.ProductID, tax: &item.LineTax, manual: !item.Tax.IsZero()}
+	}
+	return t.apply(w, &stockIn.TaxMode, lines)
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"inventory-go/repositories"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// UnitHandler handles units of measure and the units products are kept in
+type UnitHandler struct {
+	*BaseHandler
+	repo        repositories.UnitRepository
+	productRepo repositories.ProductRepository
+}
+
+// NewUnitHandler creates a new UnitHandler
+func NewUnitHandler(db *pgx.Conn) *UnitHandler {
+	return &UnitHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewUnitRepository(db),
+		productRepo: repositories.NewProductRepository(db),
+	}
+}
+
+// GetUnits handles GET /units
+func (h *UnitHandler) GetUnits(w http.ResponseWriter, r *http.Request) {
+	units, err := h.repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get units: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, units)
+}
+
+// GetUnit handles GET /units/{id}
+func (h *UnitHandler) GetUnit(w http.ResponseWriter, r *http.Request) {
+	unit, err := h.repo.GetByID(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get unit: "+err.Error())
+		return
+	}
+	if unit == nil {
+		respondWithError(w, http.StatusNotFound, "Unit not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, unit)
+}
+
+// CreateUnit handles POST /units
+func (h *UnitHandler) CreateUnit(w http.ResponseWriter, r *http.Request) {
+	var unit models.Unit
+	if err := json.NewDecoder(r.Body).Decode(&unit); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := unit.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &unit) {
+		return
+	}
+
+	if err := h.repo.Create(&unit); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create unit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, unit)
+}
+
+// UpdateUnit handles PUT /units/{id}
+func (h *UnitHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	existing, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get unit: "+err.Error())
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Unit not found")
+		return
+	}
+
+	unit := *existing
+	if err := json.NewDecoder(r.Body).Decode(&unit); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	unit.ID = id
+	if err := unit.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkUniqueCode(w, &unit) {
+		return
+	}
+
+	if err := h.repo.Update(&unit); err != nil {
+		respondWithError(w, unitConflictStatus(err), "Failed to update unit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, unit)
+}
+
+// DeleteUnit handles DELETE /units/{id}
+func (h *UnitHandler) DeleteUnit(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	unit, err := h.repo.GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get unit: "+err.Error())
+		return
+	}
+	if unit == nil {
+		respondWithError(w, http.StatusNotFound, "Unit not found")
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		respondWithError(w, unitConflictStatus(err), "Failed to delete unit: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Unit deleted successfully"})
+}
+
+// GetProductUnits handles GET /products/{id}/units
+func (h *UnitHandler) GetProductUnits(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.checkProduct(w, productID) {
+		return
+	}
+
+	units, err := h.repo.GetProductUnits(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product units: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, units)
+}
+
+// SaveProductUnits handles PUT /products/{id}/units, replacing the product's base unit and conversions
+func (h *UnitHandler) SaveProductUnits(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if !h.checkProduct(w, productID) {
+		return
+	}
+
+	var units models.ProductUnits
+	if err := json.NewDecoder(r.Body).Decode(&units); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	units.ProductID = productID
+	if err := units.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.SaveProductUnits(&units); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrUnknownUnit):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, unitConflictStatus(err), "Failed to save product units: "+err.Error())
+		}
+		return
+	}
+
+	saved, err := h.repo.GetProductUnits(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product units: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// checkProduct responds with 404 and returns false when the product does not exist
+func (h *UnitHandler) checkProduct(w http.ResponseWriter, productID string) bool {
+	product, err := h.productRepo.GetByID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return false
+	}
+	if product == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return false
+	}
+	return true
+}
+
+// checkUniqueCode responds with 409 and returns false when another unit has the same code
+func (h *UnitHandler) checkUniqueCode(w http.ResponseWriter, unit *models.Unit) bool {
+	existing, err := h.repo.GetByCode(unit.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check unit: "+err.Error())
+		return false
+	}
+	if existing != nil && existing.ID != unit.ID {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Unit %s already exists", unit.Code))
+		return false
+	}
+	return true
+}
+
+// unitConflictStatus returns 409 when a unit is still in use or a base unit cannot change
+func unitConflictStatus(err error) int {
+	if errors.Is(err, repositories.ErrUnitInUse) || errors.Is(err, repositories.ErrBaseUnitHasStock) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// unitLine is a document line whose quantity and price or cost may have been entered in
+// another unit
+type unitLine struct {
+	productID string
+	unit      *models.LineUnit
+	quantity  *int
+	amount    *money.Money
+}
+
+// unitResolver converts document lines entered in any of a product's units to its base unit
+type unitResolver struct {
+	repo repositories.UnitRepository
+}
+
+// newUnitResolver creates a unitResolver
+func newUnitResolver(db *pgx.Conn) unitResolver {
+	return unitResolver{repo: repositories.NewUnitRepository(db)}
+}
+
+// apply sets each line's base quantity and amount from those entered in its unit. Lines
+// without a product are left for the document's own validation. When it returns false the error
+// response has been written.
+func (u unitResolver) apply(w http.ResponseWriter, lines []unitLine) bool {
+	var ids []string
+	for _, line := range lines {
+		if line.productID != "" {
+			ids = append(ids, line.productID)
+		}
+	}
+	if len(ids) == 0 {
+		return true
+	}
+
+	units, err := u.repo.ResolveProductUnits(ids)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to resolve product units: "+err.Error())
+		return false
+	}
+	for _, line := range lines {
+		if line.productID == "" {
+			continue
+		}
+		if err := line.unit.Convert(units[line.productID], line.quantity, line.amount); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// applyLine converts a single line added to or changed on a document
+func (u unitResolver) applyLine(w http.ResponseWriter, productID string, unit *models.LineUnit, quantity *int, amount *money.Money) bool {
+	return u.apply(w, []unitLine{{productID: productID, unit: unit, quantity: quantity, amount: amount}})
+}
+
+// applySale converts the quantities and unit prices of a sale's items
+func (u unitResolver) applySale(w http.ResponseWriter, sale *models.Sale) bool {
+	lines := make([]unitLine, len(sale.Items))
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		lines[i] = unitLine{productID: item.ProductID, unit: &item.LineUnit, quantity: &item.Quantity, amount: &item.UnitPrice}
+	}
+	return u.apply(w, lines)
+}
+
+// applyStockIn converts the quantities and unit costs of a stock-in's items
+func (u unitResolver) applyStockIn(w http.ResponseWriter, stockIn *models.StockIn) bool {
+	lines := make([]unitLine, len(stockIn.Items))
+	for i := range stockIn.Items {
+		item := &stockIn.Items[i]
+		lines[i] = unitLine{productID: item.ProductID, unit: &item.LineUnit, quantity: &item.Quantity, amount: &item.UnitCost}
+	}
+	return u.apply(w, lines)
+}
+
+// applyReject converts the quantities and unit costs of a reject's items
+func (u unitResolver) applyReject(w http.ResponseWriter, reject *models.Reject) bool {
+	lines := make([]unitLine, len(reject.Items))
+	for i := range reject.Items {
+		item := &reject.Items[i]
+		lines[i] = unitLine{productID: item.ProductID, unit: &item.LineUnit, quantity: &item.Quantity, amount: &item.UnitCost}
+	}
+	return u.apply(w, lines)
+}
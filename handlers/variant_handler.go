@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-go/models"
+	"inventory-go/repositories"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// VariantHandler handles a parent product's option types and the variants made from them
+type VariantHandler struct {
+	*BaseHandler
+	repo        repositories.VariantRepository
+	productRepo repositories.ProductRepository
+}
+
+// NewVariantHandler creates a new VariantHandler
+func NewVariantHandler(db *pgx.Conn) *VariantHandler {
+	return &VariantHandler{
+		BaseHandler: &BaseHandler{DB: db},
+		repo:        repositories.NewVariantRepository(db),
+		productRepo: repositories.NewProductRepository(db),
+	}
+}
+
+// createVariantRequest is a variant to add and the option values it stands for
+type createVariantRequest struct {
+	models.Product
+	Options map[string]string `json:"options"`
+}
+
+// GetProductOptions handles GET /products/{id}/options
+func (h *VariantHandler) GetProductOptions(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if _, ok := h.parent(w, productID); !ok {
+		return
+	}
+
+	options, err := h.repo.GetOptions(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product options: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, options)
+}
+
+// SaveProductOptions handles PUT /products/{id}/options, replacing the option types and values
+func (h *VariantHandler) SaveProductOptions(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if _, ok := h.parent(w, productID); !ok {
+		return
+	}
+
+	var options []models.ProductOption
+	if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	if err := models.ValidateProductOptions(productID, options); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.repo.SaveOptions(productID, options); err != nil {
+		respondWithVariantError(w, "Failed to save product options: ", err)
+		return
+	}
+
+	saved, err := h.repo.GetOptions(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product options: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// GetProductVariants handles GET /products/{id}/variants
+func (h *VariantHandler) GetProductVariants(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	if _, ok := h.parent(w, productID); !ok {
+		return
+	}
+
+	variants, err := h.repo.GetVariants(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get variants: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, variants)
+}
+
+// CreateProductVariant handles POST /products/{id}/variants, adding one variant for a
+// combination of option values
+func (h *VariantHandler) CreateProductVariant(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	parent, ok := h.parent(w, productID)
+	if !ok {
+		return
+	}
+
+	var req createVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	options, err := h.repo.GetOptions(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product options: "+err.Error())
+		return
+	}
+	values, err := models.ResolveVariantOptions(options, req.Options)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	variant := models.Variant{Product: req.Product}
+	variant.ID = ""
+	variant.Basic.SKU = strings.TrimSpace(variant.Basic.SKU)
+	if variant.Basic.SKU == "" {
+		if variant.Basic.SKU, err = models.ExpandVariantPattern(models.DefaultSKUPattern(options), parent.Basic, options, values, true); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if variant.Basic.Name == "" {
+		if variant.Basic.Name, err = models.ExpandVariantPattern(models.DefaultNamePattern(options), parent.Basic, options, values, false); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if variant.Basic.Status == 0 {
+		variant.Basic.Status = parent.Basic.Status
+	}
+
+	if err := h.repo.CreateVariant(parent, &variant, options, values); err != nil {
+		respondWithVariantError(w, "Failed to create variant: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, variant)
+}
+
+// GenerateProductVariants handles POST /products/{id}/variants/generate, creating a variant
+// for every combination of option values that has none
+func (h *VariantHandler) GenerateProductVariants(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	parent, ok := h.parent(w, productID)
+	if !ok {
+		return
+	}
+
+	var generation models.VariantGeneration
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&generation); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	result, err := h.repo.GenerateVariants(parent, generation)
+	if err != nil {
+		respondWithVariantError(w, "Failed to generate variants: ", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, result)
+}
+
+// parent looks up a product that can have variants. When it returns false the error
+// response has been written.
+func (h *VariantHandler) parent(w http.ResponseWriter, productID string) (*models.Product, bool) {
+	product, err := h.productRepo.GetByID(productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get product: "+err.Error())
+		return nil, false
+	}
+	if product == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return nil, false
+	}
+	if product.Basic.IsVariant {
+		respondWithError(w, http.StatusBadRequest, "Product is a variant; options belong to its parent")
+		return nil, false
+	}
+	return product, true
+}
+
+// respondWithVariantError maps invalid patterns to 400 and options in use, duplicate
+// variants and taken SKUs to 409
+func respondWithVariantError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrInvalidVariant):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repositories.ErrOptionInUse), errors.Is(err, repositories.ErrDuplicateVariant),
+		errors.Is(err, repositories.ErrDuplicateSKU):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
@@ -0,0 +1,145 @@
+// Package importer reads product spreadsheets and runs bulk import jobs in the background.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Record is a non-blank row of an import file with its row number, counting the header as row 1
+type Record struct {
+	Row    int
+	Values []string
+}
+
+// Sheet is the header and rows read from an import file
+type Sheet struct {
+	Header  []string
+	Records []Record
+}
+
+// ErrEmptyFile is returned for files with no header row
+var ErrEmptyFile = errors.New("file has no header row")
+
+// Format returns the import format of a file by its name
+func Format(fileName string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv", ".txt":
+		return models.ImportFormatCSV, nil
+	case ".xlsx":
+		return models.ImportFormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %q; upload a .csv or .xlsx file", filepath.Ext(fileName))
+	}
+}
+
+// Read reads an import file in the given format. Blank rows are skipped but still counted,
+// so row numbers match what the user sees in their spreadsheet.
+func Read(data []byte, format string) (*Sheet, error) {
+	var rows []Record
+	var err error
+	switch format {
+	case models.ImportFormatCSV:
+		rows, err = readCSV(data)
+	case models.ImportFormatXLSX:
+		rows, err = readXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &Sheet{}
+	for _, rec := range rows {
+		if isBlank(rec.Values) {
+			continue
+		}
+		if sheet.Header == nil {
+			sheet.Header = rec.Values
+			continue
+		}
+		sheet.Records = append(sheet.Records, rec)
+	}
+	if sheet.Header == nil {
+		return nil, ErrEmptyFile
+	}
+	return sheet, nil
+}
+
+// readCSV reads comma- or semicolon-separated values, going by whichever the header uses
+// more. Spreadsheets saved in locales with a decimal comma use semicolons. Rows are numbered
+// by the line they start on, as the CSV reader skips empty lines.
+func readCSV(data []byte) ([]Record, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows []Record
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, Record{Row: line, Values: values})
+	}
+}
+
+// sniffDelimiter picks ',' or ';' by counting them in the first line
+func sniffDelimiter(data []byte) rune {
+	line, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+	if bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+// readXLSX reads the first sheet of a workbook. Cells are read as stored rather than as
+// formatted, so prices and SKUs are not rounded or reformatted by the cell's number format.
+func readXLSX(data []byte) ([]Record, error) {
+	book, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	defer book.Close()
+
+	sheets := book.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, ErrEmptyFile
+	}
+	values, err := book.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheets[0], err)
+	}
+	rows := make([]Record, len(values))
+	for i := range values {
+		rows[i] = Record{Row: i + 1, Values: values[i]}
+	}
+	return rows, nil
+}
+
+// isBlank reports whether every cell of a row is empty
+func isBlank(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,460 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"inventory-go/db"
+	"inventory-go/models"
+	"inventory-go/money"
+	"inventory-go/repositories"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// progressEvery is how many rows are applied between saves of the job's progress
+const progressEvery = 25
+
+// Runner runs import jobs one at a time in the background. Each job gets its own connection
+// from a db.Connector.
+type Runner struct {
+	connector *db.Connector
+	mu        sync.Mutex
+}
+
+// NewRunner creates a Runner that connects with the same settings as conn
+func NewRunner(conn *pgx.Conn) *Runner {
+	return &Runner{connector: db.NewConnector(conn)}
+}
+
+// RecoverInterrupted fails the jobs left queued or running when the server last stopped, as
+// their files are not kept
+func RecoverInterrupted(conn *pgx.Conn) {
+	count, err := repositories.NewImportRepository(conn).FailInterrupted()
+	if err != nil {
+		log.Printf("Import runner: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Import runner: %d interrupted import jobs marked as failed", count)
+	}
+}
+
+// Start runs a queued job in the background. The job must already be stored; the runner
+// works on a copy of it, so the caller may go on using it.
+func (r *Runner) Start(job *models.ImportJob, header *models.ImportHeader, records []Record) {
+	queued := *job
+	job = &queued
+	go func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		ctx := context.Background()
+		conn, err := r.connector.Connect(ctx)
+		if err != nil {
+			log.Printf("Import runner: failed to connect for job %s: %v", job.ID, err)
+			return
+		}
+		defer conn.Close(ctx)
+
+		newRun(conn, job, header, records).execute()
+	}()
+}
+
+// run is one import job being carried out
+type run struct {
+	job     *models.ImportJob
+	header  *models.ImportHeader
+	records []Record
+
+	jobs       repositories.ImportRepository
+	products   repositories.ProductRepository
+	variants   repositories.VariantRepository
+	categories repositories.CategoryRepository
+	stockIns   repositories.StockInRepository
+
+	// failed holds the row numbers already reported, so a row is reported once
+	failed map[int]bool
+	// opening lists the opening stock of the products created
+	opening []models.StockInItem
+}
+
+// plannedRow is a row that passed validation and what it will do
+type plannedRow struct {
+	*models.ImportRow
+	// existing is the product the SKU belongs to, when updating
+	existing *repositories.ImportedProduct
+	// parentID is the parent of a new variant when it already exists; otherwise the parent
+	// is created from an earlier row of the file
+	parentID   string
+	categoryID *string
+}
+
+func newRun(conn *pgx.Conn, job *models.ImportJob, header *models.ImportHeader, records []Record) *run {
+	return &run{
+		job:        job,
+		header:     header,
+		records:    records,
+		jobs:       repositories.NewImportRepository(conn),
+		products:   repositories.NewProductRepository(conn),
+		variants:   repositories.NewVariantRepository(conn),
+		categories: repositories.NewCategoryRepository(conn),
+		stockIns:   repositories.NewStockInRepository(conn),
+		failed:     map[int]bool{},
+	}
+}
+
+// execute validates and applies the rows, failing the job as a whole only when it cannot
+// go on, e.g. when the database is unreachable
+func (r *run) execute() {
+	defer func() {
+		if p := recover(); p != nil {
+			r.fail(fmt.Errorf("import stopped unexpectedly: %v", p))
+		}
+	}()
+
+	now := time.Now()
+	r.job.Status = models.ImportStatusRunning
+	r.job.StartedAt = &now
+	r.job.TotalRows = len(r.records)
+	r.save()
+
+	planned, err := r.validate()
+	if err != nil {
+		r.fail(err)
+		return
+	}
+
+	if r.job.DryRun {
+		for _, row := range planned {
+			if row.existing != nil {
+				r.job.Updated++
+			} else {
+				r.job.Created++
+			}
+		}
+		r.job.ProcessedRows = r.job.TotalRows
+		r.finish()
+		return
+	}
+
+	r.job.ProcessedRows = len(r.failed)
+	created := r.apply(planned)
+	r.receiveOpeningStock(created)
+	r.finish()
+}
+
+// validate parses every row and checks it against the products and categories, reporting
+// the rows that cannot be imported. It returns the rows that can, products before variants.
+func (r *run) validate() ([]*plannedRow, error) {
+	var rows []*models.ImportRow
+	skuRows := map[string]int{}
+	for _, rec := range r.records {
+		row, errs := r.header.ParseRow(rec.Row, rec.Values)
+		if row.SKU != "" {
+			if first, ok := skuRows[row.SKU]; ok {
+				errs = append(errs, models.ImportFieldError{Column: models.ImportColumnSKU, Message: fmt.Sprintf("SKU is also on row %d", first)})
+			} else {
+				skuRows[row.SKU] = row.Row
+			}
+		}
+		if len(errs) > 0 {
+			r.reject(row, errs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	skus := make([]string, 0, len(rows)*2)
+	needCategories := false
+	for _, row := range rows {
+		skus = append(skus, row.SKU)
+		if row.IsVariant() {
+			skus = append(skus, row.ParentSKU)
+		}
+		needCategories = needCategories || row.Category != ""
+	}
+	found, err := r.jobs.LookupSKUs(skus)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolver *models.CategoryResolver
+	if needCategories {
+		categories, err := r.categories.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		resolver = models.NewCategoryResolver(categories)
+	}
+
+	// Products come first so variants can be checked against the parents that will be created
+	sort.SliceStable(rows, func(i, j int) bool { return !rows[i].IsVariant() && rows[j].IsVariant() })
+
+	var planned []*plannedRow
+	newParents := map[string]bool{}
+	for _, row := range rows {
+		p := &plannedRow{ImportRow: row}
+		var errs []models.ImportFieldError
+		fail := func(column, message string) {
+			errs = append(errs, models.ImportFieldError{Column: column, Message: message})
+		}
+
+		if existing, ok := found[row.SKU]; ok {
+			p.existing = &existing
+			switch {
+			case !r.job.Upsert:
+				fail(models.ImportColumnSKU, "a product with this SKU already exists; import with upsert to update it")
+			case row.IsVariant() && !existing.IsVariant:
+				fail(models.ImportColumnParentSKU, "the product with this SKU is not a variant")
+			case !row.IsVariant() && existing.IsVariant:
+				fail(models.ImportColumnParentSKU, "the product with this SKU is a variant; give its parent_sku")
+			case row.IsVariant() && (existing.ParentID == nil || found[row.ParentSKU].ID != *existing.ParentID):
+				fail(models.ImportColumnParentSKU, "the variant with this SKU belongs to another product")
+			}
+		} else if row.Name == nil && !row.IsVariant() {
+			fail(models.ImportColumnName, "name is required for new products")
+		}
+
+		if row.IsVariant() {
+			if row.Category != "" {
+				fail(models.ImportColumnCategory, "variants take their parent's category")
+			}
+			if p.existing == nil {
+				if parent, ok := found[row.ParentSKU]; ok {
+					if parent.IsVariant {
+						fail(models.ImportColumnParentSKU, fmt.Sprintf("parent %q is itself a variant", row.ParentSKU))
+					}
+					p.parentID = parent.ID
+				} else if !newParents[row.ParentSKU] {
+					fail(models.ImportColumnParentSKU, fmt.Sprintf("parent product %q not found in the file or the inventory", row.ParentSKU))
+				}
+			}
+		} else if row.Category != "" {
+			id, err := resolver.Resolve(row.Category)
+			if err != nil {
+				fail(models.ImportColumnCategory, err.Error())
+			} else {
+				p.categoryID = &id
+			}
+		}
+
+		if len(errs) > 0 {
+			r.reject(row, errs...)
+			continue
+		}
+		if !row.IsVariant() && p.existing == nil {
+			newParents[row.SKU] = true
+		}
+		planned = append(planned, p)
+	}
+	return planned, nil
+}
+
+// apply creates and updates the products, returning the IDs of those created by SKU
+func (r *run) apply(planned []*plannedRow) map[string]string {
+	created := map[string]string{}
+	for i, row := range planned {
+		var err error
+		switch {
+		case row.existing != nil:
+			err = r.update(row)
+		case row.IsVariant():
+			err = r.createVariant(row, created)
+		default:
+			err = r.create(row, created)
+		}
+
+		if err != nil {
+			r.reject(row.ImportRow, models.ImportFieldError{Message: err.Error()})
+		} else if row.existing != nil {
+			r.job.Updated++
+		} else {
+			r.job.Created++
+		}
+
+		r.job.ProcessedRows++
+		if (i+1)%progressEvery == 0 {
+			r.save()
+		}
+	}
+	return created
+}
+
+// create adds a new product with the defaults the product API uses
+func (r *run) create(row *plannedRow, created map[string]string) error {
+	product := models.NewProduct()
+	row.ApplyTo(product)
+	product.Price.LastUpdateUnix = time.Now().Unix()
+	product.ChildCategoryID = row.categoryID
+
+	if err := r.products.Create(product); err != nil {
+		return fmt.Errorf("failed to create product: %w", err)
+	}
+	created[row.SKU] = product.ID
+	r.addOpeningStock(row, product.ID, product.Basic.Name)
+	return nil
+}
+
+// update copies the row's non-blank cells onto the existing product
+func (r *run) update(row *plannedRow) error {
+	product, err := r.products.GetByID(row.existing.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	row.ApplyTo(product)
+	if row.categoryID != nil {
+		product.ChildCategoryID = row.categoryID
+	}
+	if row.Price != nil {
+		product.Price.LastUpdateUnix = time.Now().Unix()
+	}
+
+	if err := r.products.Update(product); err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	return nil
+}
+
+// createVariant adds a variant to its parent, first adding any options and values the row
+// names that the parent does not have yet
+func (r *run) createVariant(row *plannedRow, created map[string]string) error {
+	parentID := row.parentID
+	if parentID == "" {
+		if parentID = created[row.ParentSKU]; parentID == "" {
+			return fmt.Errorf("parent product %q was not imported", row.ParentSKU)
+		}
+	}
+	parent, err := r.products.GetByID(parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get parent product: %w", err)
+	}
+
+	options, err := r.variants.GetOptions(parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get product options: %w", err)
+	}
+	if merged, added := models.MergeImportOptions(options, row.Options); added {
+		if err := models.ValidateProductOptions(parentID, merged); err != nil {
+			return err
+		}
+		if err := r.variants.SaveOptions(parentID, merged); err != nil {
+			return err
+		}
+		if options, err = r.variants.GetOptions(parentID); err != nil {
+			return fmt.Errorf("failed to get product options: %w", err)
+		}
+	}
+	values, err := models.ResolveVariantOptions(options, row.OptionValues())
+	if err != nil {
+		return err
+	}
+
+	// Prices given without a currency are in the parent's currency
+	variant := models.Variant{}
+	variant.Price.Currency = parent.Price.Currency
+	row.ApplyTo(&variant.Product)
+	if variant.Basic.Name == "" {
+		if variant.Basic.Name, err = models.ExpandVariantPattern(models.DefaultNamePattern(options), parent.Basic, options, values, false); err != nil {
+			return err
+		}
+	}
+	if variant.Basic.Status == 0 {
+		variant.Basic.Status = parent.Basic.Status
+	}
+
+	if err := r.variants.CreateVariant(parent, &variant, options, values); err != nil {
+		return err
+	}
+	created[row.SKU] = variant.ID
+	r.addOpeningStock(row, variant.ID, variant.Basic.Name)
+	return nil
+}
+
+// addOpeningStock queues a new product's opening stock for the import's stock-in
+func (r *run) addOpeningStock(row *plannedRow, productID, name string) {
+	if row.OpeningStock <= 0 {
+		return
+	}
+	item := models.NewStockInItem()
+	item.ProductID = productID
+	item.ProductName = name
+	item.Quantity = row.OpeningStock
+	item.UnitCost = row.Cost
+	item.LineUnit = models.LineUnit{UnitQuantity: row.OpeningStock, UnitFactor: 1}
+	r.opening = append(r.opening, *item)
+}
+
+// receiveOpeningStock records the opening stock of the products created as one completed
+// opening stock-in, so it gets cost layers and is posted against opening balance equity. The
+// products stay imported if it fails; the job's message says so.
+func (r *run) receiveOpeningStock(created map[string]string) {
+	if len(r.opening) == 0 {
+		return
+	}
+
+	stockIn := models.NewStockIn()
+	stockIn.ReferenceNo = "IMPORT-" + strings.ToUpper(r.job.ID[:8])
+	stockIn.Status = models.StockInStatusCompleted
+	stockIn.Note = "Opening stock from import of " + r.job.FileName
+	stockIn.Opening = true
+	stockIn.Currency = money.BaseCurrency
+	stockIn.ExchangeRate = money.OneRate()
+	stockIn.TaxMode = models.TaxModeExclusive
+	stockIn.Items = r.opening
+	stockIn.CalculateTotals()
+	stockIn.CalculateBalance()
+
+	// Imports cannot override a closed period, so opening stock dated in one is refused
+	lock := models.NewPeriodLock(models.PeriodActionCreate, models.CostSourceStockIn, stockIn.ID, stockIn.ReferenceNo, stockIn.OrderDate)
+	if err := r.stockIns.Create(stockIn, lock); err != nil {
+		r.job.Message = fmt.Sprintf("%d products were imported but their opening stock was not recorded: %v", len(created), err)
+		return
+	}
+	r.job.StockInID = &stockIn.ID
+}
+
+// reject reports a row that is not imported
+func (r *run) reject(row *models.ImportRow, errs ...models.ImportFieldError) {
+	if r.failed[row.Row] {
+		return
+	}
+	r.failed[row.Row] = true
+	r.job.Errors = append(r.job.Errors, models.ImportRowError{
+		Row:    row.Row,
+		SKU:    row.SKU,
+		Errors: errs,
+		Values: row.Values,
+	})
+	r.job.Failed++
+}
+
+// finish completes the job with its rejected rows in file order
+func (r *run) finish() {
+	sort.SliceStable(r.job.Errors, func(i, j int) bool { return r.job.Errors[i].Row < r.job.Errors[j].Row })
+	now := time.Now()
+	r.job.Status = models.ImportStatusCompleted
+	r.job.FinishedAt = &now
+	r.save()
+}
+
+// fail stops the job, keeping the counts of what was already done
+func (r *run) fail(err error) {
+	log.Printf("Import runner: job %s failed: %v", r.job.ID, err)
+	now := time.Now()
+	r.job.Status = models.ImportStatusFailed
+	r.job.Message = err.Error()
+	r.job.FinishedAt = &now
+	r.save()
+}
+
+// save stores the job's progress; a failed save only loses progress, so it is logged
+func (r *run) save() {
+	if err := r.jobs.Save(r.job); err != nil {
+		log.Printf("Import runner: %v", err)
+	}
+}
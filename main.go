@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"inventory-go/db"
+	"inventory-go/importer"
 	"inventory-go/routes"
+	"inventory-go/scheduler"
+	"inventory-go/storage"
 	"log"
 	"net/http"
 	"os"
@@ -29,9 +32,27 @@ func main() {
 	}
 	defer db.CloseDB()
 
+	// Product images are kept on the local disk or in an S3-compatible bucket
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize file storage: %v", err)
+	}
+
+	// Imports cannot resume after a restart, so fail the ones that were cut short
+	importer.RecoverInterrupted(dbConn)
+
 	// Create router
 	r := mux.NewRouter()
-	routes.SetupRoutes(r, dbConn)
+	routes.SetupRoutes(r, dbConn, store)
+
+	// Apply and revert scheduled prices in the background
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	interval, err := time.ParseDuration(os.Getenv("PRICE_SCHEDULER_INTERVAL"))
+	if err != nil {
+		interval = scheduler.DefaultPriceInterval
+	}
+	scheduler.NewPriceScheduler(dbConn, interval).Start(schedulerCtx)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -58,6 +79,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopScheduler()
 
 	// Create a deadline for server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
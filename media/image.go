@@ -0,0 +1,107 @@
+// Package media checks uploaded images and resizes them for display.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+// Longest side, in pixels, of the resized copies of an image
+const (
+	ThumbnailSize = 200
+	MediumSize    = 800
+)
+
+// maxPixels guards against small files that decode to huge images
+const maxPixels = 50_000_000
+
+// ErrUnsupportedImage is returned for files that are not JPEG, PNG, GIF or WebP images
+var ErrUnsupportedImage = errors.New("image must be a JPEG, PNG, GIF or WebP file")
+
+// extensions are the file extensions of the accepted image types
+var extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// Rendition is one stored copy of an image
+type Rendition struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+}
+
+// Image is an uploaded image with its resized copies
+type Image struct {
+	Original  Rendition
+	Thumbnail Rendition
+	Medium    Rendition
+	// Width and Height are the size of the original, upright
+	Width  int
+	Height int
+}
+
+// Process checks that data is an image of an accepted type, going by its content rather than
+// the name or type the client gave, and makes the thumbnail and medium copies. The original
+// is kept as uploaded; the copies are turned upright and saved as JPEG, or as PNG when the
+// original may have transparency.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image is %d x %d pixels; at most %d megapixels are accepted", config.Width, config.Height, maxPixels/1_000_000)
+	}
+
+	decoded, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+
+	img := &Image{
+		Original: Rendition{Data: data, ContentType: contentType, Extension: ext},
+		Width:    decoded.Bounds().Dx(),
+		Height:   decoded.Bounds().Dy(),
+	}
+	if img.Thumbnail, err = resize(decoded, ThumbnailSize, contentType); err != nil {
+		return nil, err
+	}
+	if img.Medium, err = resize(decoded, MediumSize, contentType); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// resize fits the image within size × size pixels; smaller images keep their size
+func resize(img image.Image, size int, contentType string) (Rendition, error) {
+	resized := imaging.Fit(img, size, size, imaging.Lanczos)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85}); err != nil {
+			return Rendition{}, fmt.Errorf("failed to encode image: %w", err)
+		}
+		return Rendition{Data: buf.Bytes(), ContentType: "image/jpeg", Extension: "jpg"}, nil
+	}
+	if err := png.Encode(&buf, resized); err != nil {
+		return Rendition{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return Rendition{Data: buf.Bytes(), ContentType: "image/png", Extension: "png"}, nil
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Barcode symbologies
+const (
+	SymbologyEAN13   = "ean13"
+	SymbologyUPCA    = "upca"
+	SymbologyCode128 = "code128"
+)
+
+// Barcode sources: printed on the item by its manufacturer, or assigned by us
+const (
+	BarcodeSourceManufacturer = "manufacturer"
+	BarcodeSourceInternal     = "internal"
+)
+
+// InternalBarcodePrefix starts the EAN-13 codes generated for items without a manufacturer
+// barcode. GS1 keeps prefix 20 for use within a company, so it never clashes with retail codes.
+const InternalBarcodePrefix = "20"
+
+// maxCode128Length keeps Code 128 barcodes short enough to scan from a label
+const maxCode128Length = 48
+
+// Barcode is one of the codes a product or variant can be scanned by
+type Barcode struct {
+	ID        string    `json:"id" db:"id"`
+	ProductID string    `json:"product_id" db:"product_id"`
+	Code      string    `json:"code" db:"code"`
+	Symbology string    `json:"symbology" db:"symbology"`
+	Source    string    `json:"source" db:"source"`
+	IsPrimary bool      `json:"is_primary" db:"is_primary"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BarcodeMatch is the product a scanned code belongs to. Barcode is nil when the code
+// matched the product's SKU rather than one of its barcodes.
+type BarcodeMatch struct {
+	ProductID string
+	Barcode   *Barcode
+}
+
+// GTINCheckDigit returns the check digit of an EAN or UPC code given without it: from the
+// right, digits are weighted 3, 1, 3, ... and the check digit tops the sum up to a multiple of 10
+func GTINCheckDigit(digits string) (byte, error) {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if d < '0' || d > '9' {
+			return 0, fmt.Errorf("%q is not a digit", d)
+		}
+		weight := 1
+		if (len(digits)-1-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(d-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10), nil
+}
+
+// validGTIN reports whether code has the given length, only digits and a correct check digit
+func validGTIN(code string, length int) error {
+	if len(code) != length {
+		return fmt.Errorf("must be %d digits", length)
+	}
+	check, err := GTINCheckDigit(code[:length-1])
+	if err != nil {
+		return errors.New("must contain only digits")
+	}
+	if code[length-1] != check {
+		return fmt.Errorf("check digit should be %c", check)
+	}
+	return nil
+}
+
+// DetectSymbology guesses the symbology of a code: 13 digits are EAN-13, 12 digits UPC-A and
+// anything else Code 128
+func DetectSymbology(code string) string {
+	if strings.Trim(code, "0123456789") == "" {
+		switch len(code) {
+		case 13:
+			return SymbologyEAN13
+		case 12:
+			return SymbologyUPCA
+		}
+	}
+	return SymbologyCode128
+}
+
+// ValidateBarcodeCode checks a code against its symbology
+func ValidateBarcodeCode(code string, symbology string) error {
+	switch symbology {
+	case SymbologyEAN13:
+		if err := validGTIN(code, 13); err != nil {
+			return fmt.Errorf("invalid EAN-13 %s: %w", code, err)
+		}
+	case SymbologyUPCA:
+		if err := validGTIN(code, 12); err != nil {
+			return fmt.Errorf("invalid UPC-A %s: %w", code, err)
+		}
+	case SymbologyCode128:
+		if code == "" || len(code) > maxCode128Length {
+			return fmt.Errorf("Code 128 barcodes must be 1 to %d characters", maxCode128Length)
+		}
+		for _, c := range code {
+			if c < ' ' || c > '~' {
+				return fmt.Errorf("invalid Code 128 %s: only printable ASCII characters can be encoded", code)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown symbology %q (use %s, %s or %s)", symbology, SymbologyEAN13, SymbologyUPCA, SymbologyCode128)
+	}
+	return nil
+}
+
+// Validate normalizes the barcode, detects a missing symbology, checks the code and gives
+// it an ID. Internal codes must be EAN-13 with the internal prefix.
+func (b *Barcode) Validate() error {
+	b.Code = strings.TrimSpace(b.Code)
+	b.Symbology = strings.ToLower(strings.TrimSpace(b.Symbology))
+	if b.Code == "" {
+		return errors.New("code is required")
+	}
+	if b.Symbology == "" {
+		b.Symbology = DetectSymbology(b.Code)
+	}
+	if err := ValidateBarcodeCode(b.Code, b.Symbology); err != nil {
+		return err
+	}
+
+	switch b.Source {
+	case "":
+		b.Source = BarcodeSourceManufacturer
+	case BarcodeSourceManufacturer:
+	case BarcodeSourceInternal:
+		if b.Symbology != SymbologyEAN13 || !strings.HasPrefix(b.Code, InternalBarcodePrefix) {
+			return fmt.Errorf("internal barcodes must be EAN-13 codes starting with %s", InternalBarcodePrefix)
+		}
+	default:
+		return fmt.Errorf("unknown barcode source %q", b.Source)
+	}
+
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// InternalEAN13 returns the internal EAN-13 code for a sequence number: the internal prefix,
+// the number in ten digits and the check digit
+func InternalEAN13(sequence int64) (string, error) {
+	if sequence < 0 || sequence > 9999999999 {
+		return "", fmt.Errorf("internal barcode number %d is out of range", sequence)
+	}
+	digits := fmt.Sprintf("%s%010d", InternalBarcodePrefix, sequence)
+	check, err := GTINCheckDigit(digits)
+	if err != nil {
+		return "", err
+	}
+	return digits + string(check), nil
+}
+
+// BarcodeLookupCodes returns the codes a scanned code may be stored as. A UPC-A code is the
+// EAN-13 code with a leading zero, so scanners reporting either find the same product.
+func BarcodeLookupCodes(code string) []string {
+	code = strings.TrimSpace(code)
+	codes := []string{code}
+	if strings.Trim(code, "0123456789") == "" {
+		switch {
+		case len(code) == 12:
+			codes = append(codes, "0"+code)
+		case len(code) == 13 && code[0] == '0':
+			codes = append(codes, code[1:])
+		}
+	}
+	return codes
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGTINCheckDigit(t *testing.T) {
+	tests := []struct {
+		name   string
+		digits string
+		check  byte
+	}{
+		{"GTIN-8", "9638507", '4'},
+		{"GTIN-12", "03600029145", '2'},
+		{"GTIN-13", "400638133393", '1'},
+		{"GTIN-13 check digit 7", "590123412345", '7'},
+		{"GTIN-14", "0001234560001", '2'},
+		{"all zeros", "000000000000", '0'},
+	}
+	for _, tt := range tests {
+		check, err := GTINCheckDigit(tt.digits)
+		if err != nil || check != tt.check {
+			t.Errorf("%s: GTINCheckDigit(%s) = %c, %v, want %c", tt.name, tt.digits, check, err, tt.check)
+		}
+	}
+	if _, err := GTINCheckDigit("40063813339A"); err == nil {
+		t.Error("GTINCheckDigit accepted a letter")
+	}
+}
+
+func TestValidateBarcodeCode(t *testing.T) {
+	tests := []struct {
+		code      string
+		symbology string
+		valid     bool
+	}{
+		{"4006381333931", SymbologyEAN13, true},
+		{"4006381333932", SymbologyEAN13, false},
+		{"400638133393", SymbologyEAN13, false},
+		{"036000291452", SymbologyUPCA, true},
+		{"036000291453", SymbologyUPCA, false},
+		{"SKU-001/red", SymbologyCode128, true},
+		{"café", SymbologyCode128, false},
+		{"4006381333931", "qr", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateBarcodeCode(tt.code, tt.symbology); (err == nil) != tt.valid {
+			t.Errorf("ValidateBarcodeCode(%s, %s) = %v, want valid %v", tt.code, tt.symbology, err, tt.valid)
+		}
+	}
+}
+
+func TestInternalEAN13(t *testing.T) {
+	tests := []struct {
+		sequence int64
+		code     string
+	}{
+		{0, "2000000000008"},
+		{1, "2000000000015"},
+		{1234567890, "2012345678903"},
+		{9999999999, "2099999999998"},
+	}
+	for _, tt := range tests {
+		code, err := InternalEAN13(tt.sequence)
+		if err != nil || code != tt.code {
+			t.Errorf("InternalEAN13(%d) = %s, %v, want %s", tt.sequence, code, err, tt.code)
+			continue
+		}
+		if !strings.HasPrefix(code, InternalBarcodePrefix) || DetectSymbology(code) != SymbologyEAN13 {
+			t.Errorf("InternalEAN13(%d) = %s, want an EAN-13 starting with %s", tt.sequence, code, InternalBarcodePrefix)
+		}
+		b := Barcode{Code: code, Source: BarcodeSourceInternal}
+		if err := b.Validate(); err != nil {
+			t.Errorf("internal barcode %s: Validate() = %v", code, err)
+		}
+	}
+	for _, sequence := range []int64{-1, 10000000000} {
+		if code, err := InternalEAN13(sequence); err == nil {
+			t.Errorf("InternalEAN13(%d) = %s, want an error", sequence, code)
+		}
+	}
+
+	b := Barcode{Code: "4006381333931", Source: BarcodeSourceInternal}
+	if err := b.Validate(); err == nil {
+		t.Error("internal barcode without the internal prefix is valid")
+	}
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BillOfMaterials lists what goes into one batch of a finished product, such as 1 kg of bulk
+// coffee repacked into 4 × 250 g bags. Finished goods are made from it by assemblies, and
+// their unit cost is the cost of the components consumed.
+type BillOfMaterials struct {
+	ID        string `json:"id" db:"id"`
+	ProductID string `json:"product_id" db:"product_id"`
+	Name      string `json:"name,omitempty" db:"-"`
+	SKU       string `json:"sku,omitempty" db:"-"`
+
+	// Units of the finished product one batch makes before losses
+	OutputQuantity int `json:"output_quantity" db:"output_quantity"`
+	// Percentage of the output expected to be good, e.g. 98; zero means 100
+	YieldPercent money.Rate     `json:"yield_percent" db:"yield_percent"`
+	Note         string         `json:"note,omitempty" db:"note"`
+	Components   []BOMComponent `json:"components" db:"-"`
+
+	// Estimated cost from the components' current average cost, in the base currency
+	BatchCost money.Money `json:"batch_cost" db:"-"`
+	UnitCost  money.Money `json:"unit_cost" db:"-"`
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// BOMComponent is a product consumed by one batch, with the share lost while making it
+type BOMComponent struct {
+	ID          string `json:"id" db:"id"`
+	BOMID       string `json:"bom_id" db:"bom_id"`
+	ComponentID string `json:"component_id" db:"component_id"`
+	Quantity    int    `json:"quantity" db:"quantity"`
+	// Percentage of the quantity lost on top of it, e.g. 2 for spillage
+	WastePercent money.Rate `json:"waste_percent" db:"waste_percent"`
+
+	// Component details, for display
+	Name  string `json:"name,omitempty" db:"-"`
+	SKU   string `json:"sku,omitempty" db:"-"`
+	Stock int    `json:"stock" db:"-"`
+	// Current average unit cost and the cost per batch including waste
+	UnitCost  money.Money `json:"unit_cost" db:"-"`
+	BatchCost money.Money `json:"batch_cost" db:"-"`
+}
+
+// Validate checks the output and components and gives them IDs
+func (b *BillOfMaterials) Validate() error {
+	if b.ID == "" {
+		b.ID = uuid.NewString()
+	}
+	if b.OutputQuantity == 0 {
+		b.OutputQuantity = 1
+	}
+	if b.OutputQuantity < 1 {
+		return errors.New("output_quantity must be at least 1")
+	}
+	if b.YieldPercent.Rat().Sign() < 0 || b.YieldPercent.Rat().Cmp(big.NewRat(100, 1)) > 0 {
+		return errors.New("yield_percent must be between 0 and 100")
+	}
+	if len(b.Components) == 0 {
+		return errors.New("a bill of materials needs at least one component")
+	}
+
+	seen := map[string]bool{}
+	for i := range b.Components {
+		c := &b.Components[i]
+		if c.ComponentID == "" {
+			return errors.New("component_id is required for all components")
+		}
+		if c.ComponentID == b.ProductID {
+			return errors.New("a product cannot be its own component")
+		}
+		if seen[c.ComponentID] {
+			return fmt.Errorf("component %s is listed more than once", c.ComponentID)
+		}
+		seen[c.ComponentID] = true
+		if c.Quantity < 1 {
+			return errors.New("quantity must be at least 1")
+		}
+		if c.WastePercent.Rat().Sign() < 0 {
+			return errors.New("waste_percent cannot be negative")
+		}
+		if c.ID == "" {
+			c.ID = uuid.NewString()
+		}
+		c.BOMID = b.ID
+	}
+	return nil
+}
+
+// yield returns the expected good share of the output
+func (b *BillOfMaterials) yield() *big.Rat {
+	if b.YieldPercent.IsZero() {
+		return big.NewRat(1, 1)
+	}
+	return new(big.Rat).Quo(b.YieldPercent.Rat(), big.NewRat(100, 1))
+}
+
+// wasteFactor returns 1 plus the component's waste share
+func (c *BOMComponent) wasteFactor() *big.Rat {
+	return new(big.Rat).Add(big.NewRat(1, 1), new(big.Rat).Quo(c.WastePercent.Rat(), big.NewRat(100, 1)))
+}
+
+// ExpectedOutput returns the good units a number of batches is expected to make, rounded down
+func (b *BillOfMaterials) ExpectedOutput(batches int) int {
+	output := new(big.Rat).Mul(big.NewRat(int64(batches*b.OutputQuantity), 1), b.yield())
+	return int(new(big.Int).Quo(output.Num(), output.Denom()).Int64())
+}
+
+// Required returns how much of a component a number of batches consumes including waste,
+// rounded up to whole units
+func (c *BOMComponent) Required(batches int) int {
+	need := new(big.Rat).Mul(big.NewRat(int64(batches*c.Quantity), 1), c.wasteFactor())
+	units, remainder := new(big.Int).QuoRem(need.Num(), need.Denom(), new(big.Int))
+	if remainder.Sign() > 0 {
+		units.Add(units, big.NewInt(1))
+	}
+	return int(units.Int64())
+}
+
+// RollUp estimates the cost of a batch and of each good unit from the components' unit
+// costs in the base currency. Components without a cost count as zero.
+func (b *BillOfMaterials) RollUp(costs map[string]money.Money) {
+	b.BatchCost = money.Zero(money.BaseCurrency)
+	for i := range b.Components {
+		c := &b.Components[i]
+		c.UnitCost = money.Zero(money.BaseCurrency)
+		if cost, ok := costs[c.ComponentID]; ok {
+			c.UnitCost = cost.In(money.BaseCurrency)
+		}
+		c.BatchCost = c.UnitCost.Mul(int64(c.Quantity)).MulRat(c.wasteFactor())
+		b.BatchCost = b.BatchCost.Add(c.BatchCost)
+	}
+
+	good := new(big.Rat).Mul(big.NewRat(int64(b.OutputQuantity), 1), b.yield())
+	b.UnitCost = money.Zero(money.BaseCurrency)
+	if good.Sign() > 0 {
+		b.UnitCost = b.BatchCost.MulRat(new(big.Rat).Inv(good))
+	}
+}
@@ -16,6 +16,7 @@ type Category struct {
 	Status      int       `json:"status" db:"status"`
 	SortOrder   int       `json:"sort_order" db:"sort_order"`
 	ImageURL    string    `json:"image_url,omitempty" db:"image_url"`
+	TaxCodeID   *string   `json:"tax_code_id,omitempty" db:"tax_code_id"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 	DeletedAt   *time.Time `json:"-" db:"deleted_at"`
@@ -99,3 +100,50 @@ func BuildCategoryBreadcrumbs(categoryID string, categories map[string]Category)
 
 	return breadcrumbs
 }
+
+// CategoryTreeNode is a category in the full tree with how many products it holds. Variants
+// are counted with their parent product, not separately.
+type CategoryTreeNode struct {
+	Category
+	// ProductCount is the products in this category itself
+	ProductCount int64 `json:"product_count"`
+	// TotalProductCount also includes the products in every category below it
+	TotalProductCount int64               `json:"total_product_count"`
+	Children          []*CategoryTreeNode `json:"children"`
+}
+
+// CategoryMove puts a category, with everything below it, under a new parent
+type CategoryMove struct {
+	// ParentID is the new parent; null makes the category a root
+	ParentID *string `json:"parent_id"`
+	// SortOrder places it among its new siblings; it goes last when omitted
+	SortOrder *int `json:"sort_order,omitempty"`
+}
+
+// CategoryReorder orders the subcategories of a parent, or the roots when ParentID is null
+type CategoryReorder struct {
+	ParentID    *string  `json:"parent_id"`
+	CategoryIDs []string `json:"category_ids"`
+}
+
+// BuildCategoryTree links the nodes to their parents and returns the roots. The order of the
+// nodes is kept among siblings; a node whose parent is missing becomes a root.
+func BuildCategoryTree(nodes []*CategoryTreeNode) []*CategoryTreeNode {
+	byID := make(map[string]*CategoryTreeNode, len(nodes))
+	for _, node := range nodes {
+		node.Children = []*CategoryTreeNode{}
+		byID[node.ID] = node
+	}
+
+	roots := []*CategoryTreeNode{}
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			if parent, ok := byID[*node.ParentID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
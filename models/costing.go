@@ -0,0 +1,222 @@
+package models
+
+import (
+	"inventory-go/money"
+	"time"
+)
+
+// CostingMethod determines how the cost of goods sold is measured
+type CostingMethod string
+
+const (
+	// CostingMethodFIFO issues stock from the oldest receipt layers first
+	CostingMethodFIFO CostingMethod = "fifo"
+	// CostingMethodAverage issues stock at the running weighted average cost
+	CostingMethodAverage CostingMethod = "average"
+)
+
+// IsValid reports whether the costing method is supported
+func (m CostingMethod) IsValid() bool {
+	return m == CostingMethodFIFO || m == CostingMethodAverage
+}
+
+// Documents that move inventory cost
+const (
+	CostSourceStockIn = "stock_in"
+	CostSourceSale    = "sale"
+	CostSourceReject  = "reject"
+	// CostSourceAssembly moves cost from the components consumed to the product built
+	CostSourceAssembly = "assembly"
+)
+
+// Cost movement types
+const (
+	CostMovementReceipt  = "receipt"
+	CostMovementIssue    = "issue"
+	CostMovementReversal = "reversal"
+)
+
+// CostLayer is the quantity received by one stock-in item at one unit cost.
+// Remaining quantity is drawn down oldest first.
+type CostLayer struct {
+	ID                string      `json:"id" db:"id"`
+	ProductID         string      `json:"product_id" db:"product_id"`
+	SourceType        string      `json:"source_type" db:"source_type"`
+	SourceID          string      `json:"source_id" db:"source_id"`
+	SourceItemID      string      `json:"source_item_id" db:"source_item_id"`
+	ReceivedAt        time.Time   `json:"received_at" db:"received_at"`
+	Quantity          int         `json:"quantity" db:"quantity"`
+	RemainingQuantity int         `json:"remaining_quantity" db:"remaining_quantity"`
+	UnitCost          money.Money `json:"unit_cost" db:"unit_cost"`
+	CreatedAt         time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// ProductCost is the running weighted average cost state of a product.
+// AverageCost keeps the last known average when the quantity drops to zero or below.
+type ProductCost struct {
+	ProductID   string      `json:"product_id" db:"product_id"`
+	Quantity    int         `json:"quantity" db:"quantity"`
+	TotalValue  money.Money `json:"total_value" db:"total_value"`
+	AverageCost money.Money `json:"average_cost" db:"average_cost"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// CostMovement is a ledger entry for inventory quantity and value in the base currency.
+// Issues and reversals of receipts carry negative quantities and costs. AverageTotal is what
+// the movement added to the running average; under FIFO an issue removes the average cost
+// rather than its layer cost, so reversals put back AverageTotal to leave the average as it was.
+type CostMovement struct {
+	ID           string        `json:"id" db:"id"`
+	ProductID    string        `json:"product_id" db:"product_id"`
+	MovementType string        `json:"movement_type" db:"movement_type"`
+	SourceType   string        `json:"source_type" db:"source_type"`
+	SourceID     string        `json:"source_id" db:"source_id"`
+	SourceItemID string        `json:"source_item_id" db:"source_item_id"`
+	LayerID      *string       `json:"layer_id,omitempty" db:"layer_id"`
+	Quantity     int           `json:"quantity" db:"quantity"`
+	UnitCost     money.Money   `json:"unit_cost" db:"unit_cost"`
+	TotalCost    money.Money   `json:"total_cost" db:"total_cost"`
+	AverageTotal money.Money   `json:"average_total" db:"average_total"`
+	Method       CostingMethod `json:"method" db:"method"`
+	OccurredAt   time.Time     `json:"occurred_at" db:"occurred_at"`
+	ReversedAt   *time.Time    `json:"reversed_at,omitempty" db:"reversed_at"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+}
+
+// CostDraw is the quantity and cost taken from one layer when stock is issued, and its share
+// of the cost taken from the running average. LayerID is nil for quantity issued beyond the
+// available layers.
+type CostDraw struct {
+	LayerID     *string
+	Quantity    int
+	TotalCost   money.Money
+	AverageCost money.Money
+}
+
+// InventoryValuationLine is the value of one product's stock
+type InventoryValuationLine struct {
+	ProductID   string      `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	SKU         string      `json:"sku"`
+	Quantity    int         `json:"quantity"`
+	UnitCost    money.Money `json:"unit_cost"`
+	Value       money.Money `json:"value"`
+}
+
+// InventoryValuation is the value of all stock at a point in time, in the base currency
+type InventoryValuation struct {
+	AsOf          time.Time                `json:"as_of"`
+	Method        CostingMethod            `json:"method"`
+	Currency      string                   `json:"currency"`
+	Products      []InventoryValuationLine `json:"products"`
+	TotalQuantity int                      `json:"total_quantity"`
+	TotalValue    money.Money              `json:"total_value"`
+}
+
+// NewProductCost returns an empty cost state for a product
+func NewProductCost(productID string) *ProductCost {
+	return &ProductCost{
+		ProductID:   productID,
+		TotalValue:  money.Zero(money.BaseCurrency),
+		AverageCost: money.Zero(money.BaseCurrency),
+	}
+}
+
+// Reverse undoes a movement's change to the running average
+func (p *ProductCost) Reverse(m CostMovement) {
+	p.Add(-m.Quantity, m.AverageTotal.Neg())
+}
+
+// Add adds quantity and value to the running average. Negative values remove them.
+func (p *ProductCost) Add(quantity int, value money.Money) {
+	p.Quantity += quantity
+	p.TotalValue = p.TotalValue.Add(value)
+	if p.Quantity > 0 {
+		p.AverageCost = p.TotalValue.Div(int64(p.Quantity))
+	} else if p.Quantity == 0 {
+		p.TotalValue = money.Zero(money.BaseCurrency)
+	}
+}
+
+// Issue removes quantity at the current average cost and returns the cost removed.
+// Issuing more than is on hand costs the excess at the last known average.
+func (p *ProductCost) Issue(quantity int) money.Money {
+	var cost money.Money
+	switch {
+	case p.Quantity <= 0:
+		cost = p.AverageCost.Mul(int64(quantity))
+	case quantity <= p.Quantity:
+		cost = p.TotalValue.MulRatio(int64(quantity), int64(p.Quantity))
+	default:
+		cost = p.TotalValue.Add(p.AverageCost.Mul(int64(quantity - p.Quantity)))
+	}
+	cost = cost.In(money.BaseCurrency)
+	p.Add(-quantity, cost.Neg())
+	return cost
+}
+
+// IssueCost draws quantity from the layers (oldest first, updating their remaining
+// quantity) and from the running average, and returns the draws costed by method.
+// Each draw also carries its share of the average cost, whatever the method.
+// Layers must be ordered oldest first.
+func IssueCost(method CostingMethod, layers []CostLayer, state *ProductCost, quantity int) []CostDraw {
+	fallbackCost := state.AverageCost
+	averageTotal := state.Issue(quantity)
+
+	var draws []CostDraw
+	remaining := quantity
+	for i := range layers {
+		if remaining == 0 {
+			break
+		}
+		layer := &layers[i]
+		if layer.RemainingQuantity <= 0 {
+			continue
+		}
+		take := layer.RemainingQuantity
+		if take > remaining {
+			take = remaining
+		}
+		layer.RemainingQuantity -= take
+		remaining -= take
+
+		layerID := layer.ID
+		draws = append(draws, CostDraw{
+			LayerID:   &layerID,
+			Quantity:  take,
+			TotalCost: layer.UnitCost.Mul(int64(take)).In(money.BaseCurrency),
+		})
+	}
+	if remaining > 0 {
+		draws = append(draws, CostDraw{
+			Quantity:  remaining,
+			TotalCost: fallbackCost.Mul(int64(remaining)).In(money.BaseCurrency),
+		})
+	}
+
+	// Spread the average cost over the layer draws; the last draw takes the remainder
+	allocated := money.Zero(money.BaseCurrency)
+	for i := range draws {
+		if i == len(draws)-1 {
+			draws[i].AverageCost = averageTotal.Sub(allocated)
+		} else {
+			draws[i].AverageCost = averageTotal.MulRatio(int64(draws[i].Quantity), int64(quantity))
+			allocated = allocated.Add(draws[i].AverageCost)
+		}
+		if method == CostingMethodAverage {
+			draws[i].TotalCost = draws[i].AverageCost
+		}
+	}
+
+	return draws
+}
+
+// TotalDrawCost adds up the cost of a set of draws
+func TotalDrawCost(draws []CostDraw) money.Money {
+	total := money.Zero(money.BaseCurrency)
+	for _, d := range draws {
+		total = total.Add(d.TotalCost)
+	}
+	return total
+}
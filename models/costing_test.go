@@ -0,0 +1,111 @@
+package models
+
+import (
+	"inventory-go/money"
+	"testing"
+)
+
+func idr(s string) money.Money {
+	return money.MustParse(s, money.BaseCurrency)
+}
+
+// costLayers returns two receipts, 10 at 100 then 10 at 130, and the running average they make
+func costLayers() ([]CostLayer, *ProductCost) {
+	layers := []CostLayer{
+		{ID: "a", Quantity: 10, RemainingQuantity: 10, UnitCost: idr("100")},
+		{ID: "b", Quantity: 10, RemainingQuantity: 10, UnitCost: idr("130")},
+	}
+	state := NewProductCost("p")
+	for _, l := range layers {
+		state.Add(l.Quantity, l.UnitCost.Mul(int64(l.Quantity)))
+	}
+	return layers, state
+}
+
+func TestProductCostAdd(t *testing.T) {
+	state := NewProductCost("p")
+	state.Add(3, idr("100"))
+	if state.AverageCost.String() != "33.3333" {
+		t.Errorf("average = %s, want 33.3333", state.AverageCost.String())
+	}
+	state.Add(-3, idr("-100"))
+	if state.Quantity != 0 || !state.TotalValue.IsZero() || state.AverageCost.String() != "33.3333" {
+		t.Errorf("emptied state = %d %s %s, want 0 0 33.3333", state.Quantity, state.TotalValue.String(), state.AverageCost.String())
+	}
+}
+
+func TestIssueCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    CostingMethod
+		quantity  int
+		costs     []string
+		averages  []string
+		remaining []int
+		total     string
+	}{
+		{"fifo within first layer", CostingMethodFIFO, 4, []string{"400"}, []string{"460"}, []int{6, 10}, "400"},
+		{"fifo across layers", CostingMethodFIFO, 15, []string{"1000", "650"}, []string{"1150", "575"}, []int{0, 5}, "1650"},
+		{"fifo beyond stock", CostingMethodFIFO, 25, []string{"1000", "1300", "575"}, []string{"1150", "1150", "575"}, []int{0, 0}, "2875"},
+		{"average within first layer", CostingMethodAverage, 4, []string{"460"}, []string{"460"}, []int{6, 10}, "460"},
+		{"average across layers", CostingMethodAverage, 15, []string{"1150", "575"}, []string{"1150", "575"}, []int{0, 5}, "1725"},
+		{"average beyond stock", CostingMethodAverage, 25, []string{"1150", "1150", "575"}, []string{"1150", "1150", "575"}, []int{0, 0}, "2875"},
+	}
+	for _, tt := range tests {
+		layers, state := costLayers()
+		draws := IssueCost(tt.method, layers, state, tt.quantity)
+		if len(draws) != len(tt.costs) {
+			t.Errorf("%s: %d draws, want %d", tt.name, len(draws), len(tt.costs))
+			continue
+		}
+		for i, d := range draws {
+			if d.TotalCost.String() != tt.costs[i] || d.AverageCost.String() != tt.averages[i] {
+				t.Errorf("%s: draw %d = %s (average %s), want %s (average %s)",
+					tt.name, i, d.TotalCost.String(), d.AverageCost.String(), tt.costs[i], tt.averages[i])
+			}
+		}
+		if got := TotalDrawCost(draws).String(); got != tt.total {
+			t.Errorf("%s: total = %s, want %s", tt.name, got, tt.total)
+		}
+		for i, l := range layers {
+			if l.RemainingQuantity != tt.remaining[i] {
+				t.Errorf("%s: layer %s remaining = %d, want %d", tt.name, l.ID, l.RemainingQuantity, tt.remaining[i])
+			}
+		}
+		if state.Quantity != 20-tt.quantity {
+			t.Errorf("%s: quantity = %d, want %d", tt.name, state.Quantity, 20-tt.quantity)
+		}
+	}
+}
+
+// TestIssueReverse issues stock and reverses the movements the way the costing repository
+// records them, which must leave the running average where it started under either method
+func TestIssueReverse(t *testing.T) {
+	for _, method := range []CostingMethod{CostingMethodFIFO, CostingMethodAverage} {
+		for _, quantity := range []int{4, 15, 20, 25} {
+			layers, state := costLayers()
+			// Issue some first so the average differs from the oldest layer cost
+			IssueCost(method, layers, state, 3)
+			start := *state
+
+			var movements []CostMovement
+			for _, d := range IssueCost(method, layers, state, quantity) {
+				movements = append(movements, CostMovement{
+					Quantity:     -d.Quantity,
+					TotalCost:    d.TotalCost.Neg(),
+					AverageTotal: d.AverageCost.Neg(),
+				})
+			}
+			for _, m := range movements {
+				state.Reverse(m)
+			}
+
+			if state.Quantity != start.Quantity || state.TotalValue.Cmp(start.TotalValue) != 0 ||
+				state.AverageCost.Cmp(start.AverageCost) != 0 {
+				t.Errorf("%s issue %d: reversed to %d %s %s, want %d %s %s", method, quantity,
+					state.Quantity, state.TotalValue.String(), state.AverageCost.String(),
+					start.Quantity, start.TotalValue.String(), start.AverageCost.String())
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,15 +9,21 @@ import (
 
 // Customer model
 type Customer struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Email       string    `json:"email,omitempty" db:"email"`
-	Phone       string    `json:"phone,omitempty" db:"phone"`
-	Address     string    `json:"address,omitempty" db:"address"`
-	TotalOrders int       `json:"total_orders" db:"total_orders"`
-	TotalSpent  float64   `json:"total_spent" db:"total_spent"`
-	LastOrderAt time.Time `json:"last_order_at,omitempty" db:"last_order_at"`
-	Notes       string    `json:"notes,omitempty" db:"notes"`
+	ID          string      `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Email       string      `json:"email,omitempty" db:"email"`
+	Phone       string      `json:"phone,omitempty" db:"phone"`
+	Address     string      `json:"address,omitempty" db:"address"`
+	TotalOrders int         `json:"total_orders" db:"total_orders"`
+	TotalSpent  money.Money `json:"total_spent" db:"total_spent"`
+	LastOrderAt time.Time   `json:"last_order_at,omitempty" db:"last_order_at"`
+	Notes       string      `json:"notes,omitempty" db:"notes"`
+
+	// How long the customer has to pay, used to set the due date of their sales
+	PaymentTerms PaymentTerms `json:"payment_terms" db:"payment_terms"`
+
+	// Price list the customer buys from; customers without one use the default price list
+	PriceListID *string `json:"price_list_id,omitempty" db:"price_list_id"`
 
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ExchangeRateSourceManual marks a rate entered through the API
+	ExchangeRateSourceManual = "manual"
+	// ExchangeRateSourceCSV marks a rate loaded from a CSV import
+	ExchangeRateSourceCSV = "csv"
+)
+
+// ExchangeRate is the number of base-currency units per one unit of Currency on RateDate
+type ExchangeRate struct {
+	ID        string     `json:"id" db:"id"`
+	Currency  string     `json:"currency" db:"currency"`
+	Rate      money.Rate `json:"rate" db:"rate"`
+	RateDate  time.Time  `json:"rate_date" db:"rate_date"`
+	Source    string     `json:"source" db:"source"`
+	Note      string     `json:"note,omitempty" db:"note"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// ExchangeRateImportError describes a CSV row that could not be imported
+type ExchangeRateImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ExchangeRateImportResult summarizes a CSV import
+type ExchangeRateImportResult struct {
+	Imported int                       `json:"imported"`
+	Errors   []ExchangeRateImportError `json:"errors,omitempty"`
+}
+
+// NewExchangeRate creates a new exchange rate with default values
+func NewExchangeRate() *ExchangeRate {
+	now := time.Now()
+	return &ExchangeRate{
+		ID:        uuid.NewString(),
+		Source:    ExchangeRateSourceManual,
+		RateDate:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Validate normalizes and checks the exchange rate
+func (e *ExchangeRate) Validate() error {
+	e.Currency = money.NormalizeCurrency(e.Currency)
+	if e.Currency == money.BaseCurrency {
+		return fmt.Errorf("rates are not recorded for the base currency %s", money.BaseCurrency)
+	}
+	if len(e.Currency) != 3 {
+		return fmt.Errorf("invalid currency code %q", e.Currency)
+	}
+	if !e.Rate.IsPositive() {
+		return errors.New("rate must be greater than zero")
+	}
+	if e.RateDate.IsZero() {
+		return errors.New("rate_date is required")
+	}
+	e.RateDate = truncateToDate(e.RateDate)
+	if e.Source == "" {
+		e.Source = ExchangeRateSourceManual
+	}
+	return nil
+}
+
+// ParseExchangeRatesCSV reads rates from CSV with a header row containing
+// currency, rate and rate_date (or date) columns, plus an optional note column.
+// Rows that fail validation are reported with their line number and skipped.
+func ParseExchangeRatesCSV(r io.Reader) ([]ExchangeRate, []ExchangeRateImportError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, errors.New("CSV file is empty")
+		}
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["rate_date"]; !ok {
+		if i, ok := columns["date"]; ok {
+			columns["rate_date"] = i
+		}
+	}
+	for _, required := range []string{"currency", "rate", "rate_date"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("CSV header is missing the %q column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rates []ExchangeRate
+	var rowErrors []ExchangeRateImportError
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			rowErrors = append(rowErrors, ExchangeRateImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		rate := NewExchangeRate()
+		rate.Source = ExchangeRateSourceCSV
+		rate.Currency = field(record, "currency")
+		rate.Note = field(record, "note")
+
+		parsedRate, err := money.ParseRate(field(record, "rate"))
+		if err != nil {
+			rowErrors = append(rowErrors, ExchangeRateImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		rate.Rate = parsedRate
+
+		rateDate, err := time.Parse("2006-01-02", field(record, "rate_date"))
+		if err != nil {
+			rowErrors = append(rowErrors, ExchangeRateImportError{Line: line, Error: "invalid rate_date (use YYYY-MM-DD)"})
+			continue
+		}
+		rate.RateDate = rateDate
+
+		if err := rate.Validate(); err != nil {
+			rowErrors = append(rowErrors, ExchangeRateImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		rates = append(rates, *rate)
+	}
+
+	return rates, rowErrors, nil
+}
+
+// documentRate returns the rate used to convert a document's amounts into the base currency
+func documentRate(currency string, rate money.Rate) money.Rate {
+	if money.NormalizeCurrency(currency) == money.BaseCurrency {
+		return money.OneRate()
+	}
+	return rate
+}
+
+// truncateToDate drops the time of day, keeping the date in its original location
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
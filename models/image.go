@@ -0,0 +1,27 @@
+package models
+
+import "errors"
+
+// MaxImageSize is the largest product image that can be uploaded, in bytes
+const MaxImageSize = 10 << 20
+
+// MaxImagesPerProduct limits the gallery of one product or variant
+const MaxImagesPerProduct = 20
+
+// ImageReorder sets the order product images are shown in
+type ImageReorder struct {
+	ImageIDs []string `json:"image_ids"`
+}
+
+// Validate checks that the reorder lists at least one image
+func (r *ImageReorder) Validate() error {
+	if len(r.ImageIDs) == 0 {
+		return errors.New("image_ids is required")
+	}
+	for _, id := range r.ImageIDs {
+		if id == "" {
+			return errors.New("image_ids cannot contain empty IDs")
+		}
+	}
+	return nil
+}
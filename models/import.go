@@ -0,0 +1,524 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import job statuses
+const (
+	ImportStatusQueued    = "queued"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Import file formats
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+)
+
+// MaxImportSize is the largest import file accepted, in bytes
+const MaxImportSize = 20 << 20
+
+// MaxImportRows limits the products one import can hold
+const MaxImportRows = 10000
+
+// Import columns. Headers are matched ignoring case, spaces and hyphens, and option columns
+// are named "option:<name>", e.g. "option:Color".
+const (
+	ImportColumnSKU          = "sku"
+	ImportColumnName         = "name"
+	ImportColumnDescription  = "description"
+	ImportColumnStatus       = "status"
+	ImportColumnCondition    = "condition"
+	ImportColumnPrice        = "price"
+	ImportColumnCurrency     = "currency"
+	ImportColumnWeight       = "weight"
+	ImportColumnWeightUnit   = "weight_unit"
+	ImportColumnCategory     = "category"
+	ImportColumnParentSKU    = "parent_sku"
+	ImportColumnOpeningStock = "opening_stock"
+	ImportColumnCost         = "cost"
+
+	importOptionPrefix = "option:"
+)
+
+// importColumns are the known columns and the other headers they may go by
+var importColumns = map[string]string{
+	"sku":           ImportColumnSKU,
+	"name":          ImportColumnName,
+	"product_name":  ImportColumnName,
+	"description":   ImportColumnDescription,
+	"status":        ImportColumnStatus,
+	"condition":     ImportColumnCondition,
+	"price":         ImportColumnPrice,
+	"currency":      ImportColumnCurrency,
+	"weight":        ImportColumnWeight,
+	"weight_unit":   ImportColumnWeightUnit,
+	"category":      ImportColumnCategory,
+	"parent_sku":    ImportColumnParentSKU,
+	"opening_stock": ImportColumnOpeningStock,
+	"stock":         ImportColumnOpeningStock,
+	"cost":          ImportColumnCost,
+	"unit_cost":     ImportColumnCost,
+}
+
+// Product status and condition names accepted in place of their codes
+var (
+	importStatuses = map[string]int{
+		"banned": -2, "pending": -1, "active": 1, "featured": 2, "inactive": 3,
+	}
+	importConditions  = map[string]int{"new": 1, "used": 2}
+	importWeightUnits = map[string]int{
+		"1": 1, "g": 1, "gr": 1, "gram": 1, "grams": 1,
+		"2": 2, "kg": 2, "kilogram": 2, "kilograms": 2,
+	}
+)
+
+// ImportJob is a bulk product import running in the background. In a dry run nothing is
+// saved and Created and Updated count the products that would be.
+type ImportJob struct {
+	ID       string `json:"id" db:"id"`
+	FileName string `json:"file_name" db:"file_name"`
+	Format   string `json:"format" db:"format"`
+	Status   string `json:"status" db:"status"`
+	DryRun   bool   `json:"dry_run" db:"dry_run"`
+	// Upsert updates products whose SKU already exists instead of reporting them as errors
+	Upsert bool `json:"upsert" db:"upsert"`
+
+	TotalRows     int `json:"total_rows" db:"total_rows"`
+	ProcessedRows int `json:"processed_rows" db:"processed_rows"`
+	Created       int `json:"created" db:"created"`
+	Updated       int `json:"updated" db:"updated"`
+	Failed        int `json:"failed" db:"failed"`
+
+	Columns        []string `json:"columns" db:"columns"`
+	IgnoredColumns []string `json:"ignored_columns,omitempty" db:"ignored_columns"`
+	// Errors lists the rows that were not imported; left out of job listings
+	Errors []ImportRowError `json:"errors,omitempty" db:"errors"`
+	// StockInID is the stock-in recording the opening stock of the products created
+	StockInID *string `json:"stock_in_id,omitempty" db:"stock_in_id"`
+	// Message explains why a job failed as a whole
+	Message string `json:"message,omitempty" db:"message"`
+
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ImportRowError is a row that was not imported, with its cells as read from the file
+type ImportRowError struct {
+	Row    int                `json:"row"`
+	SKU    string             `json:"sku,omitempty"`
+	Errors []ImportFieldError `json:"errors"`
+	Values []string           `json:"values"`
+}
+
+// ImportFieldError is one problem with a row, naming the column when it is about one cell
+type ImportFieldError struct {
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Message joins the row's problems into one line
+func (e ImportRowError) Message() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Message
+		if fe.Column != "" {
+			messages[i] = fe.Column + ": " + fe.Message
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ImportOption is the value a variant row gives for one option
+type ImportOption struct {
+	Name  string
+	Value string
+}
+
+// ImportRow is one product or variant read from an import file. Fields are nil when their
+// cell is blank, so updates keep the current value.
+type ImportRow struct {
+	Row       int
+	Values    []string
+	SKU       string
+	ParentSKU string
+
+	Name        *string
+	Description *string
+	Status      *int
+	Condition   *int
+	Price       *money.Money
+	Currency    *string
+	Weight      *float64
+	WeightUnit  *int
+	// Category is a category slug or path, e.g. "electronics/phones" or "Electronics > Phones"
+	Category string
+	Options  []ImportOption
+
+	// OpeningStock is received for new products only, at Cost per unit in the base currency
+	OpeningStock int
+	Cost         money.Money
+}
+
+// IsVariant reports whether the row is a variant of another product
+func (r *ImportRow) IsVariant() bool {
+	return r.ParentSKU != ""
+}
+
+// OptionValues returns the row's option values by option name
+func (r *ImportRow) OptionValues() map[string]string {
+	values := make(map[string]string, len(r.Options))
+	for _, o := range r.Options {
+		values[o.Name] = o.Value
+	}
+	return values
+}
+
+// ApplyTo copies the row's non-blank fields onto the product
+func (r *ImportRow) ApplyTo(p *Product) {
+	p.Basic.SKU = r.SKU
+	if r.Name != nil {
+		p.Basic.Name = *r.Name
+	}
+	if r.Description != nil {
+		p.Basic.Description = *r.Description
+	}
+	if r.Status != nil {
+		p.Basic.Status = *r.Status
+	}
+	if r.Condition != nil {
+		p.Basic.Condition = *r.Condition
+	}
+	if r.Currency != nil {
+		p.Price.Currency = *r.Currency
+	}
+	if r.Price != nil {
+		p.Price.Price = *r.Price
+	}
+	p.Price.BindCurrency()
+	if r.Weight != nil {
+		p.Weight.Weight = *r.Weight
+	}
+	if r.WeightUnit != nil {
+		p.Weight.Unit = *r.WeightUnit
+	}
+}
+
+// ImportHeader maps the columns of an import file
+type ImportHeader struct {
+	Columns []string
+	// Ignored lists the headers that are not import columns
+	Ignored []string
+
+	index   map[string]int
+	options []importOptionColumn
+}
+
+// importOptionColumn is the column holding the values of an option
+type importOptionColumn struct {
+	name  string
+	index int
+}
+
+// ParseImportHeader reads the header row. It needs a SKU column and refuses columns given twice.
+func ParseImportHeader(header []string) (*ImportHeader, error) {
+	h := &ImportHeader{Columns: header, index: map[string]int{}}
+	optionNames := map[string]bool{}
+	for i, title := range header {
+		title = strings.TrimSpace(strings.TrimPrefix(title, "\ufeff"))
+		if title == "" {
+			continue
+		}
+		key := normalizeImportHeader(title)
+
+		if strings.HasPrefix(key, importOptionPrefix) {
+			name := strings.TrimSpace(title[strings.Index(title, ":")+1:])
+			if name == "" {
+				return nil, fmt.Errorf("column %d: option columns need a name, e.g. option:Color", i+1)
+			}
+			if optionNames[strings.ToLower(name)] {
+				return nil, fmt.Errorf("option %s has more than one column", name)
+			}
+			optionNames[strings.ToLower(name)] = true
+			h.options = append(h.options, importOptionColumn{name: name, index: i})
+			continue
+		}
+
+		column, ok := importColumns[key]
+		if !ok {
+			h.Ignored = append(h.Ignored, title)
+			continue
+		}
+		if _, dup := h.index[column]; dup {
+			return nil, fmt.Errorf("column %s is given more than once", column)
+		}
+		h.index[column] = i
+	}
+
+	if _, ok := h.index[ImportColumnSKU]; !ok {
+		return nil, errors.New("the file needs a sku column")
+	}
+	return h, nil
+}
+
+// normalizeImportHeader lower-cases a header and joins its words with underscores
+func normalizeImportHeader(title string) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	if i := strings.Index(key, ":"); i >= 0 {
+		return strings.TrimSpace(key[:i]) + ":" + strings.TrimSpace(key[i+1:])
+	}
+	return strings.Join(strings.FieldsFunc(key, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "_")
+}
+
+// ParseRow reads a row's cells into an ImportRow, checking each cell. row is the row's
+// number in the file, counting the header as row 1.
+func (h *ImportHeader) ParseRow(row int, record []string) (*ImportRow, []ImportFieldError) {
+	r := &ImportRow{Row: row, Values: record}
+	var errs []ImportFieldError
+	fail := func(column, format string, args ...any) {
+		errs = append(errs, ImportFieldError{Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+	cell := func(column string) string {
+		i, ok := h.index[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	text := func(column string) *string {
+		if s := cell(column); s != "" {
+			return &s
+		}
+		return nil
+	}
+
+	r.SKU = cell(ImportColumnSKU)
+	if r.SKU == "" {
+		fail(ImportColumnSKU, "is required")
+	}
+	r.ParentSKU = cell(ImportColumnParentSKU)
+	if r.ParentSKU != "" && strings.EqualFold(r.ParentSKU, r.SKU) {
+		fail(ImportColumnParentSKU, "cannot be the row's own SKU")
+	}
+	r.Name = text(ImportColumnName)
+	r.Description = text(ImportColumnDescription)
+	r.Category = cell(ImportColumnCategory)
+
+	if s := cell(ImportColumnStatus); s != "" {
+		if status, ok := parseImportCode(s, importStatuses); ok {
+			r.Status = &status
+		} else {
+			fail(ImportColumnStatus, "%q is not a status; use active, featured, pending, inactive or banned", s)
+		}
+	}
+	if s := cell(ImportColumnCondition); s != "" {
+		if condition, ok := parseImportCode(s, importConditions); ok {
+			r.Condition = &condition
+		} else {
+			fail(ImportColumnCondition, "%q is not a condition; use new or used", s)
+		}
+	}
+
+	if s := cell(ImportColumnCurrency); s != "" {
+		currency := strings.ToUpper(s)
+		if money.IsKnownCurrency(currency) {
+			r.Currency = &currency
+		} else {
+			fail(ImportColumnCurrency, "%q is not a known currency", s)
+		}
+	}
+	if s := cell(ImportColumnPrice); s != "" {
+		currency := money.DefaultCurrency
+		if r.Currency != nil {
+			currency = *r.Currency
+		}
+		price, err := money.Parse(s, currency)
+		switch {
+		case err != nil:
+			fail(ImportColumnPrice, "%q is not a number", s)
+		case price.IsNegative():
+			fail(ImportColumnPrice, "cannot be negative")
+		default:
+			r.Price = &price
+		}
+	}
+
+	if s := cell(ImportColumnWeight); s != "" {
+		weight, err := strconv.ParseFloat(s, 64)
+		if err != nil || weight < 0 {
+			fail(ImportColumnWeight, "%q is not a weight", s)
+		} else {
+			r.Weight = &weight
+		}
+	}
+	if s := cell(ImportColumnWeightUnit); s != "" {
+		if unit, ok := importWeightUnits[strings.ToLower(s)]; ok {
+			r.WeightUnit = &unit
+		} else {
+			fail(ImportColumnWeightUnit, "%q is not a weight unit; use g or kg", s)
+		}
+	}
+
+	if s := cell(ImportColumnOpeningStock); s != "" {
+		stock, err := strconv.Atoi(s)
+		if err != nil {
+			// Spreadsheets may give whole numbers as 12.0
+			if f, ferr := strconv.ParseFloat(s, 64); ferr == nil && f == float64(int(f)) {
+				stock, err = int(f), nil
+			}
+		}
+		if err != nil || stock < 0 {
+			fail(ImportColumnOpeningStock, "%q is not a whole number of units", s)
+		} else {
+			r.OpeningStock = stock
+		}
+	}
+	if s := cell(ImportColumnCost); s != "" {
+		cost, err := money.Parse(s, money.BaseCurrency)
+		if err != nil || cost.IsNegative() {
+			fail(ImportColumnCost, "%q is not a cost", s)
+		} else {
+			r.Cost = cost
+		}
+	}
+
+	for _, o := range h.options {
+		if o.index < len(record) && strings.TrimSpace(record[o.index]) != "" {
+			r.Options = append(r.Options, ImportOption{Name: o.name, Value: strings.TrimSpace(record[o.index])})
+		}
+	}
+	if len(r.Options) > 0 && !r.IsVariant() {
+		fail("", "option columns are only for variants; give the parent_sku")
+	}
+
+	return r, errs
+}
+
+// parseImportCode reads a code given by name or number
+func parseImportCode(s string, names map[string]int) (int, bool) {
+	if code, ok := names[strings.ToLower(s)]; ok {
+		return code, true
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	for _, c := range names {
+		if c == code {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// MergeImportOptions adds the options and values a variant row names to the parent's
+// options, returning whether any were added
+func MergeImportOptions(options []ProductOption, named []ImportOption) ([]ProductOption, bool) {
+	merged := make([]ProductOption, len(options))
+	for i, o := range options {
+		merged[i] = o
+		merged[i].Values = append([]ProductOptionValue(nil), o.Values...)
+	}
+
+	added := false
+	for _, n := range named {
+		found := -1
+		for i := range merged {
+			if strings.EqualFold(merged[i].Name, n.Name) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			merged = append(merged, ProductOption{Name: n.Name})
+			found = len(merged) - 1
+		}
+		hasValue := false
+		for _, v := range merged[found].Values {
+			if strings.EqualFold(v.Value, n.Value) {
+				hasValue = true
+				break
+			}
+		}
+		if !hasValue {
+			merged[found].Values = append(merged[found].Values, ProductOptionValue{Value: n.Value})
+			added = true
+		}
+	}
+	return merged, added
+}
+
+// CategoryResolver finds categories by slug or by path
+type CategoryResolver struct {
+	byKey map[string][]string
+}
+
+// NewCategoryResolver indexes the categories by slug, by name, and by their paths of names
+// and of slugs
+func NewCategoryResolver(categories []Category) *CategoryResolver {
+	byID := make(map[string]Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	r := &CategoryResolver{byKey: map[string][]string{}}
+	add := func(key, id string) {
+		for _, existing := range r.byKey[key] {
+			if existing == id {
+				return
+			}
+		}
+		r.byKey[key] = append(r.byKey[key], id)
+	}
+	for _, c := range categories {
+		// Walk up to the root; the depth limit guards against broken parent links
+		var names, slugs []string
+		for at, depth := c, 0; depth < len(categories); depth++ {
+			names = append([]string{strings.ToLower(at.Name)}, names...)
+			slugs = append([]string{strings.ToLower(at.Slug)}, slugs...)
+			if at.ParentID == nil {
+				break
+			}
+			parent, ok := byID[*at.ParentID]
+			if !ok {
+				break
+			}
+			at = parent
+		}
+		add(strings.ToLower(c.Slug), c.ID)
+		add(strings.ToLower(c.Name), c.ID)
+		add(strings.Join(names, "/"), c.ID)
+		add(strings.Join(slugs, "/"), c.ID)
+	}
+	return r
+}
+
+// Resolve returns the ID of the category named by a slug or a path such as
+// "Electronics > Phones" or "electronics/phones"
+func (r *CategoryResolver) Resolve(s string) (string, error) {
+	parts := strings.FieldsFunc(s, func(c rune) bool { return c == '>' || c == '/' })
+	for i := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(parts[i]))
+	}
+	ids := r.byKey[strings.Join(parts, "/")]
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("category %q not found", s)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("category %q matches %d categories; give its slug or full path", s, len(ids))
+	}
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountRole is the purpose of a general ledger account in generated journals
+type AccountRole string
+
+const (
+	AccountInventory  AccountRole = "inventory"
+	AccountCOGS       AccountRole = "cogs"
+	AccountRevenue    AccountRole = "revenue"
+	AccountTaxPayable AccountRole = "tax_payable"
+	AccountTaxInput   AccountRole = "tax_input"
+	AccountReceivable AccountRole = "accounts_receivable"
+	AccountPayable    AccountRole = "accounts_payable"
+	AccountShrinkage  AccountRole = "shrinkage"
+	AccountCash       AccountRole = "cash"
+
+	AccountOpeningEquity AccountRole = "opening_balance_equity"
+)
+
+// accountDefaults is the chart of accounts used for roles that have never been mapped
+var accountDefaults = map[AccountRole]Account{
+	AccountCash:          {Role: AccountCash, Code: "1100", Name: "Cash and Bank"},
+	AccountReceivable:    {Role: AccountReceivable, Code: "1200", Name: "Accounts Receivable"},
+	AccountInventory:     {Role: AccountInventory, Code: "1300", Name: "Inventory"},
+	AccountTaxInput:      {Role: AccountTaxInput, Code: "1400", Name: "VAT Input (PPN Masukan)"},
+	AccountPayable:       {Role: AccountPayable, Code: "2100", Name: "Accounts Payable"},
+	AccountTaxPayable:    {Role: AccountTaxPayable, Code: "2200", Name: "Tax Payable"},
+	AccountOpeningEquity: {Role: AccountOpeningEquity, Code: "3000", Name: "Opening Balance Equity"},
+	AccountRevenue:       {Role: AccountRevenue, Code: "4000", Name: "Sales Revenue"},
+	AccountCOGS:          {Role: AccountCOGS, Code: "5000", Name: "Cost of Goods Sold"},
+	AccountShrinkage:     {Role: AccountShrinkage, Code: "5100", Name: "Inventory Shrinkage"},
+}
+
+// IsValid reports whether the account role is known
+func (r AccountRole) IsValid() bool {
+	_, ok := accountDefaults[r]
+	return ok
+}
+
+// Account maps an account role to a code and name in the company's chart of accounts
+type Account struct {
+	Role      AccountRole `json:"role" db:"role"`
+	Code      string      `json:"code" db:"code"`
+	Name      string      `json:"name" db:"name"`
+	UpdatedAt *time.Time  `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// DefaultAccounts returns the default mapping of every account role, ordered by code
+func DefaultAccounts() []Account {
+	accounts := make([]Account, 0, len(accountDefaults))
+	for _, a := range accountDefaults {
+		accounts = append(accounts, a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Code < accounts[j].Code })
+	return accounts
+}
+
+// Validate checks the account role and code
+func (a *Account) Validate() error {
+	if !a.Role.IsValid() {
+		return fmt.Errorf("unknown account role %q", a.Role)
+	}
+	if a.Code == "" {
+		return errors.New("code is required")
+	}
+	if a.Name == "" {
+		a.Name = accountDefaults[a.Role].Name
+	}
+	return nil
+}
+
+// Documents that generate journal entries
+const (
+	JournalSourceSale           = "sale"
+	JournalSourceSalePayment    = "sale_payment"
+	JournalSourceStockIn        = "stock_in"
+	JournalSourceStockInPayment = "stock_in_payment"
+	JournalSourceReject         = "reject"
+	JournalSourceLandedCost     = "landed_cost"
+
+	JournalSourceCustomerPayment = "customer_payment"
+	JournalSourceSupplierPayment = "supplier_payment"
+	JournalSourceSupplierCredit  = "supplier_credit"
+)
+
+// JournalEntry is a balanced double-entry journal in the base currency.
+// Reversals carry the ID of the entry they cancel.
+type JournalEntry struct {
+	ID          string        `json:"id" db:"id"`
+	EntryNo     string        `json:"entry_no" db:"entry_no"`
+	EntryDate   time.Time     `json:"entry_date" db:"entry_date"`
+	SourceType  string        `json:"source_type" db:"source_type"`
+	SourceID    string        `json:"source_id" db:"source_id"`
+	Reference   string        `json:"reference" db:"reference"`
+	Description string        `json:"description" db:"description"`
+	Currency    string        `json:"currency" db:"currency"`
+	ReversalOf  *string       `json:"reversal_of,omitempty" db:"reversal_of"`
+	ReversedAt  *time.Time    `json:"reversed_at,omitempty" db:"reversed_at"`
+	Lines       []JournalLine `json:"lines" db:"-"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+}
+
+// JournalLine debits or credits one account
+type JournalLine struct {
+	ID          string      `json:"id" db:"id"`
+	EntryID     string      `json:"entry_id" db:"entry_id"`
+	LineNo      int         `json:"line_no" db:"line_no"`
+	AccountRole AccountRole `json:"account_role" db:"account_role"`
+	AccountCode string      `json:"account_code" db:"account_code"`
+	AccountName string      `json:"account_name" db:"account_name"`
+	Debit       money.Money `json:"debit" db:"debit"`
+	Credit      money.Money `json:"credit" db:"credit"`
+	Memo        string      `json:"memo,omitempty" db:"memo"`
+}
+
+// NewJournalEntry creates an empty journal entry for a document
+func NewJournalEntry(sourceType, sourceID, reference string, date time.Time, description string) *JournalEntry {
+	id := uuid.NewString()
+	return &JournalEntry{
+		ID:          id,
+		EntryNo:     "JE-" + date.Format("20060102") + "-" + id[:6],
+		EntryDate:   date,
+		SourceType:  sourceType,
+		SourceID:    sourceID,
+		Reference:   reference,
+		Description: description,
+		Currency:    money.BaseCurrency,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// Debit adds a debit line. Zero amounts are skipped and negative amounts become credits.
+func (e *JournalEntry) Debit(role AccountRole, amount money.Money, memo string) {
+	e.addLine(role, amount, memo)
+}
+
+// Credit adds a credit line. Zero amounts are skipped and negative amounts become debits.
+func (e *JournalEntry) Credit(role AccountRole, amount money.Money, memo string) {
+	e.addLine(role, amount.Neg(), memo)
+}
+
+// addLine adds a line with a signed amount: positive debits, negative credits
+func (e *JournalEntry) addLine(role AccountRole, amount money.Money, memo string) {
+	amount = amount.In(money.BaseCurrency)
+	if amount.IsZero() {
+		return
+	}
+	line := JournalLine{
+		ID:          uuid.NewString(),
+		EntryID:     e.ID,
+		LineNo:      len(e.Lines) + 1,
+		AccountRole: role,
+		Debit:       money.Zero(money.BaseCurrency),
+		Credit:      money.Zero(money.BaseCurrency),
+		Memo:        memo,
+	}
+	if amount.IsPositive() {
+		line.Debit = amount
+	} else {
+		line.Credit = amount.Neg()
+	}
+	e.Lines = append(e.Lines, line)
+}
+
+// Totals returns the total debits and credits of the entry
+func (e *JournalEntry) Totals() (money.Money, money.Money) {
+	debit := money.Zero(money.BaseCurrency)
+	credit := money.Zero(money.BaseCurrency)
+	for _, l := range e.Lines {
+		debit = debit.Add(l.Debit)
+		credit = credit.Add(l.Credit)
+	}
+	return debit, credit
+}
+
+// Validate checks that the entry has lines and that debits equal credits
+func (e *JournalEntry) Validate() error {
+	if len(e.Lines) == 0 {
+		return errors.New("journal entry has no lines")
+	}
+	debit, credit := e.Totals()
+	if debit.Cmp(credit) != 0 {
+		return fmt.Errorf("journal entry %s is unbalanced: debits %s, credits %s", e.EntryNo, debit, credit)
+	}
+	return nil
+}
+
+// ApplyAccounts fills the account code and name of each line from the chart of accounts
+func (e *JournalEntry) ApplyAccounts(accounts map[AccountRole]Account) {
+	for i := range e.Lines {
+		account, ok := accounts[e.Lines[i].AccountRole]
+		if !ok {
+			account = accountDefaults[e.Lines[i].AccountRole]
+		}
+		e.Lines[i].AccountCode = account.Code
+		e.Lines[i].AccountName = account.Name
+	}
+}
+
+// SameAs reports whether two entries post the same amounts to the same accounts on the same date
+func (e *JournalEntry) SameAs(o *JournalEntry) bool {
+	if e.SourceType != o.SourceType || !e.EntryDate.Equal(o.EntryDate) || len(e.Lines) != len(o.Lines) {
+		return false
+	}
+	for i := range e.Lines {
+		a, b := e.Lines[i], o.Lines[i]
+		if a.AccountRole != b.AccountRole || a.AccountCode != b.AccountCode ||
+			a.Debit.Cmp(b.Debit) != 0 || a.Credit.Cmp(b.Credit) != 0 || a.Memo != b.Memo {
+			return false
+		}
+	}
+	return true
+}
+
+// Reverse returns an entry dated on the given date that cancels this one
+func (e *JournalEntry) Reverse(date time.Time) *JournalEntry {
+	reversal := NewJournalEntry(e.SourceType, e.SourceID, e.Reference, date, "Reversal of "+e.EntryNo)
+	original := e.ID
+	reversal.ReversalOf = &original
+	for _, l := range e.Lines {
+		line := l
+		line.ID = uuid.NewString()
+		line.EntryID = reversal.ID
+		line.Debit, line.Credit = l.Credit, l.Debit
+		reversal.Lines = append(reversal.Lines, line)
+	}
+	return reversal
+}
+
+// SaleJournal records a completed sale: the receivable against revenue and tax,
+// and the cost of goods sold against inventory. Amounts are in the base currency;
+// revenue takes any rounding difference so the entry balances.
+func SaleJournal(id, reference string, date time.Time, total, tax, cogs money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceSale, id, reference, date, "Sale "+reference)
+	e.Debit(AccountReceivable, total, "")
+	e.Credit(AccountRevenue, total.Sub(tax), "")
+	e.Credit(AccountTaxPayable, tax, "")
+	e.Debit(AccountCOGS, cogs, "")
+	e.Credit(AccountInventory, cogs, "")
+	return e
+}
+
+// SalePaymentJournal records money received against a sale's receivable
+func SalePaymentJournal(id, reference string, date time.Time, amount money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceSalePayment, id, reference, date, "Payment received for "+reference)
+	e.Debit(AccountCash, amount, "")
+	e.Credit(AccountReceivable, amount, "")
+	return e
+}
+
+// StockInJournal records stock received and creditable input tax against the amount owed
+// to the supplier
+func StockInJournal(id, reference string, date time.Time, total, tax money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceStockIn, id, reference, date, "Stock-in "+reference)
+	e.Debit(AccountInventory, total.Sub(tax), "")
+	e.Debit(AccountTaxInput, tax, "")
+	e.Credit(AccountPayable, total, "")
+	return e
+}
+
+// OpeningStockJournal records opening stock brought into the books by an import against
+// opening balance equity, as no supplier is owed for it
+func OpeningStockJournal(id, reference string, date time.Time, total money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceStockIn, id, reference, date, "Opening stock "+reference)
+	e.Debit(AccountInventory, total, "")
+	e.Credit(AccountOpeningEquity, total, "")
+	return e
+}
+
+// StockInPaymentJournal records money paid against a stock-in's payable
+func StockInPaymentJournal(id, reference string, date time.Time, amount money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceStockInPayment, id, reference, date, "Payment made for "+reference)
+	e.Debit(AccountPayable, amount, "")
+	e.Credit(AccountCash, amount, "")
+	return e
+}
+
+// RejectJournal writes off the cost of rejected stock
+func RejectJournal(id, reference string, date time.Time, cogs money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceReject, id, reference, date, "Reject "+reference)
+	e.Debit(AccountShrinkage, cogs, "")
+	e.Credit(AccountInventory, cogs, "")
+	return e
+}
+
+// LandedCostJournal adds a landed cost charge to inventory against the amount owed for it
+func LandedCostJournal(id, reference string, date time.Time, amount money.Money, memo string) *JournalEntry {
+	e := NewJournalEntry(JournalSourceLandedCost, id, reference, date, "Landed cost "+reference)
+	e.Debit(AccountInventory, amount, memo)
+	e.Credit(AccountPayable, amount, memo)
+	return e
+}
+
+// CustomerPaymentJournal records money received from a customer against their receivable
+func CustomerPaymentJournal(id, reference string, date time.Time, amount money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceCustomerPayment, id, reference, date, "Payment received "+reference)
+	e.Debit(AccountCash, amount, "")
+	e.Credit(AccountReceivable, amount, "")
+	return e
+}
+
+// SupplierPaymentJournal records money paid to a supplier against a stock-in's payable
+func SupplierPaymentJournal(id, reference string, date time.Time, amount money.Money) *JournalEntry {
+	e := NewJournalEntry(JournalSourceSupplierPayment, id, reference, date, "Payment made "+reference)
+	e.Debit(AccountPayable, amount, "")
+	e.Credit(AccountCash, amount, "")
+	return e
+}
+
+// SupplierCreditJournal takes a supplier credit note off the payable. The credit recovers
+// the cost of returned goods, which are written off to shrinkage with a reject.
+func SupplierCreditJournal(id, reference string, date time.Time, amount money.Money, memo string) *JournalEntry {
+	e := NewJournalEntry(JournalSourceSupplierCredit, id, reference, date, "Supplier credit "+reference)
+	e.Debit(AccountPayable, amount, memo)
+	e.Credit(AccountShrinkage, amount, memo)
+	return e
+}
+
+// JournalFilter selects journal entries by date range and source document
+type JournalFilter struct {
+	StartDate  *time.Time
+	EndDate    *time.Time
+	SourceType string
+	SourceID   string
+}
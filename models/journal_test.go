@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestJournalsBalance(t *testing.T) {
+	day := date("2024-03-05")
+	tests := []struct {
+		name  string
+		entry *JournalEntry
+		lines int
+		debit string
+	}{
+		{"sale with tax and cost", SaleJournal("s", "INV-1", day, idr("111000"), idr("11000"), idr("60000")), 5, "171000"},
+		{"sale without tax", SaleJournal("s", "INV-2", day, idr("50000"), idr("0"), idr("30000")), 4, "80000"},
+		{"sale not yet costed", SaleJournal("s", "INV-3", day, idr("13703"), idr("1358"), idr("0")), 3, "13703"},
+		{"sale payment", SalePaymentJournal("p", "INV-1", day, idr("40000")), 2, "40000"},
+		{"stock-in with input tax", StockInJournal("si", "PO-1", day, idr("111000"), idr("11000")), 3, "111000"},
+		{"stock-in without tax", StockInJournal("si", "PO-2", day, idr("75000"), idr("0")), 2, "75000"},
+		{"opening stock", OpeningStockJournal("si", "IMPORT-1", day, idr("75000")), 2, "75000"},
+		{"stock-in payment", StockInPaymentJournal("si", "PO-1", day, idr("111000")), 2, "111000"},
+		{"landed cost", LandedCostJournal("lc", "LC-1", day, idr("250000"), "freight"), 2, "250000"},
+		{"reject", RejectJournal("r", "REJ-1", day, idr("9000")), 2, "9000"},
+		{"customer payment", CustomerPaymentJournal("cp", "RCPT-1", day, idr("5000")), 2, "5000"},
+		{"supplier payment", SupplierPaymentJournal("sp", "PAY-1", day, idr("5000")), 2, "5000"},
+		{"supplier credit", SupplierCreditJournal("sc", "CN-1", day, idr("1200"), "returned"), 2, "1200"},
+		{"sale refund", SaleJournal("s", "INV-4", day, idr("-111000"), idr("-11000"), idr("-60000")), 5, "171000"},
+	}
+	for _, tt := range tests {
+		if err := tt.entry.Validate(); err != nil {
+			t.Errorf("%s: Validate() = %v", tt.name, err)
+		}
+		debit, credit := tt.entry.Totals()
+		if debit.Cmp(credit) != 0 || debit.String() != tt.debit {
+			t.Errorf("%s: debits %s, credits %s, want both %s", tt.name, debit.String(), credit.String(), tt.debit)
+		}
+		if len(tt.entry.Lines) != tt.lines {
+			t.Errorf("%s: %d lines, want %d", tt.name, len(tt.entry.Lines), tt.lines)
+		}
+		for _, l := range tt.entry.Lines {
+			if l.Debit.IsNegative() || l.Credit.IsNegative() || l.Debit.IsZero() == l.Credit.IsZero() {
+				t.Errorf("%s: line %d debits %s and credits %s, want one positive amount", tt.name, l.LineNo, l.Debit.String(), l.Credit.String())
+			}
+		}
+
+		reversal := tt.entry.Reverse(day)
+		if err := reversal.Validate(); err != nil || reversal.ReversalOf == nil || *reversal.ReversalOf != tt.entry.ID {
+			t.Errorf("%s: reversal = %v, reversal of %v", tt.name, err, reversal.ReversalOf)
+		}
+	}
+}
+
+func TestJournalSaleLines(t *testing.T) {
+	e := SaleJournal("s", "INV-1", date("2024-03-05"), idr("111000"), idr("11000"), idr("60000"))
+	want := []struct {
+		role   AccountRole
+		debit  string
+		credit string
+	}{
+		{AccountReceivable, "111000", "0"},
+		{AccountRevenue, "0", "100000"},
+		{AccountTaxPayable, "0", "11000"},
+		{AccountCOGS, "60000", "0"},
+		{AccountInventory, "0", "60000"},
+	}
+	for i, w := range want {
+		l := e.Lines[i]
+		if l.AccountRole != w.role || l.Debit.String() != w.debit || l.Credit.String() != w.credit {
+			t.Errorf("line %d = %s %s/%s, want %s %s/%s", i, l.AccountRole, l.Debit.String(), l.Credit.String(), w.role, w.debit, w.credit)
+		}
+	}
+}
+
+func TestJournalValidate(t *testing.T) {
+	e := NewJournalEntry(JournalSourceSale, "s", "INV-1", date("2024-03-05"), "")
+	if err := e.Validate(); err == nil {
+		t.Error("entry without lines is valid")
+	}
+	e.Debit(AccountReceivable, idr("100"), "")
+	e.Credit(AccountRevenue, idr("99"), "")
+	if err := e.Validate(); err == nil {
+		t.Error("unbalanced entry is valid")
+	}
+}
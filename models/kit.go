@@ -0,0 +1,236 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kit is a product sold as a set of other products, such as a gift set. A kit that is not
+// assembled has no stock of its own: selling it takes its components out of stock. An
+// assembled kit is built ahead by assemblies and sold from its own stock.
+type Kit struct {
+	ProductID  string         `json:"product_id" db:"product_id"`
+	Name       string         `json:"name,omitempty" db:"-"`
+	SKU        string         `json:"sku,omitempty" db:"-"`
+	Assembled  bool           `json:"assembled" db:"assembled"`
+	Components []KitComponent `json:"components" db:"-"`
+
+	// Stock is the kit's own stock; Available is what can be sold now, which for a kit that is
+	// not assembled is how many kits its components make
+	Stock     int `json:"stock" db:"-"`
+	Available int `json:"available" db:"-"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// KitComponent is one product that goes into a kit and how many of it each kit takes
+type KitComponent struct {
+	ID          string `json:"id" db:"id"`
+	KitID       string `json:"kit_id" db:"kit_id"`
+	ComponentID string `json:"component_id" db:"component_id"`
+	Quantity    int    `json:"quantity" db:"quantity"`
+
+	// Component details, for display
+	Name  string `json:"name,omitempty" db:"-"`
+	SKU   string `json:"sku,omitempty" db:"-"`
+	Stock int    `json:"stock" db:"-"`
+}
+
+// Validate checks the components and gives them IDs
+func (k *Kit) Validate() error {
+	if len(k.Components) == 0 {
+		return errors.New("a kit needs at least one component")
+	}
+
+	seen := map[string]bool{}
+	for i := range k.Components {
+		c := &k.Components[i]
+		if c.ComponentID == "" {
+			return errors.New("component_id is required for all components")
+		}
+		if c.ComponentID == k.ProductID {
+			return errors.New("a kit cannot be its own component")
+		}
+		if seen[c.ComponentID] {
+			return fmt.Errorf("component %s is listed more than once", c.ComponentID)
+		}
+		seen[c.ComponentID] = true
+		if c.Quantity == 0 {
+			c.Quantity = 1
+		}
+		if c.Quantity < 1 {
+			return errors.New("quantity must be at least 1")
+		}
+		if c.ID == "" {
+			c.ID = uuid.NewString()
+		}
+		c.KitID = k.ProductID
+	}
+	return nil
+}
+
+// Buildable returns how many kits the components' stock makes: the smallest of each
+// component's stock divided by the quantity a kit takes
+func (k *Kit) Buildable() int {
+	buildable := -1
+	for _, c := range k.Components {
+		if c.Quantity <= 0 {
+			continue
+		}
+		n := max(c.Stock/c.Quantity, 0)
+		if buildable < 0 || n < buildable {
+			buildable = n
+		}
+	}
+	return max(buildable, 0)
+}
+
+// CalculateAvailable sets what can be sold: the kit's own stock when it is assembled,
+// otherwise what its components make
+func (k *Kit) CalculateAvailable() {
+	if k.Assembled {
+		k.Available = max(k.Stock, 0)
+	} else {
+		k.Available = k.Buildable()
+	}
+}
+
+// Assembly builds a quantity of an assembled kit, or of a product with a bill of materials,
+// taking its components out of stock. What it builds is valued at what the components cost,
+// in the base currency.
+type Assembly struct {
+	ID          string `json:"id" db:"id"`
+	ReferenceNo string `json:"reference_no" db:"reference_no"`
+	ProductID   string `json:"product_id" db:"product_id"`
+	ProductName string `json:"product_name" db:"product_name"`
+	// Bill of materials used, and how many batches of it were made
+	BOMID   *string `json:"bom_id,omitempty" db:"bom_id"`
+	Batches int     `json:"batches,omitempty" db:"batches"`
+	// Good units the batches were expected to make; Quantity is what was actually made
+	ExpectedQuantity int            `json:"expected_quantity,omitempty" db:"expected_quantity"`
+	Quantity         int            `json:"quantity" db:"quantity"`
+	AssemblyDate     time.Time      `json:"assembly_date" db:"assembly_date"`
+	Note             string         `json:"note,omitempty" db:"note"`
+	UnitCost         money.Money    `json:"unit_cost" db:"unit_cost"`
+	TotalCost        money.Money    `json:"total_cost" db:"total_cost"`
+	Items            []AssemblyItem `json:"items" db:"-"`
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// AssemblyItem is a component consumed by an assembly and the cost it was issued at
+type AssemblyItem struct {
+	ID            string      `json:"id" db:"id"`
+	AssemblyID    string      `json:"assembly_id" db:"assembly_id"`
+	ComponentID   string      `json:"component_id" db:"component_id"`
+	ComponentName string      `json:"component_name" db:"component_name"`
+	Quantity      int         `json:"quantity" db:"quantity"`
+	UnitCost      money.Money `json:"unit_cost" db:"unit_cost"`
+	TotalCost     money.Money `json:"total_cost" db:"total_cost"`
+}
+
+// NewAssembly creates an assembly dated now
+func NewAssembly() *Assembly {
+	now := time.Now()
+	return &Assembly{
+		ID:           uuid.NewString(),
+		AssemblyDate: now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// Validate checks the reference and quantities
+func (a *Assembly) Validate() error {
+	if a.ReferenceNo == "" {
+		return errors.New("reference_no is required")
+	}
+	if a.ProductID == "" {
+		return errors.New("product_id is required")
+	}
+	if a.Quantity < 0 || a.Batches < 0 {
+		return errors.New("quantity and batches cannot be negative")
+	}
+	if a.AssemblyDate.IsZero() {
+		a.AssemblyDate = time.Now()
+	}
+	return nil
+}
+
+// UseKit lists the components the assembly consumes: each component's quantity per kit
+// times the number of kits built
+func (a *Assembly) UseKit(kit *Kit) error {
+	if a.Quantity < 1 {
+		return errors.New("quantity must be at least 1")
+	}
+	a.ProductName = kit.Name
+	a.BOMID = nil
+	a.Batches = 0
+	a.ExpectedQuantity = 0
+	a.Items = make([]AssemblyItem, len(kit.Components))
+	for i, c := range kit.Components {
+		a.Items[i] = AssemblyItem{
+			ID:            uuid.NewString(),
+			AssemblyID:    a.ID,
+			ComponentID:   c.ComponentID,
+			ComponentName: c.Name,
+			Quantity:      c.Quantity * a.Quantity,
+			UnitCost:      money.Zero(money.BaseCurrency),
+			TotalCost:     money.Zero(money.BaseCurrency),
+		}
+	}
+	a.UnitCost = money.Zero(money.BaseCurrency)
+	a.TotalCost = money.Zero(money.BaseCurrency)
+	return nil
+}
+
+// UseBOM lists the components a number of batches consumes, waste included. The quantity
+// made defaults to the expected output and may be given when the actual yield differed;
+// either way the components' whole cost goes to the units made.
+func (a *Assembly) UseBOM(bom *BillOfMaterials) error {
+	if a.Batches < 1 {
+		return errors.New("batches must be at least 1")
+	}
+	a.ProductName = bom.Name
+	a.BOMID = &bom.ID
+	a.ExpectedQuantity = bom.ExpectedOutput(a.Batches)
+	if a.Quantity == 0 {
+		a.Quantity = a.ExpectedQuantity
+	}
+	if a.Quantity < 1 {
+		return errors.New("the batches are expected to make nothing; give the quantity made")
+	}
+
+	a.Items = make([]AssemblyItem, len(bom.Components))
+	for i, c := range bom.Components {
+		a.Items[i] = AssemblyItem{
+			ID:            uuid.NewString(),
+			AssemblyID:    a.ID,
+			ComponentID:   c.ComponentID,
+			ComponentName: c.Name,
+			Quantity:      c.Required(a.Batches),
+			UnitCost:      money.Zero(money.BaseCurrency),
+			TotalCost:     money.Zero(money.BaseCurrency),
+		}
+	}
+	a.UnitCost = money.Zero(money.BaseCurrency)
+	a.TotalCost = money.Zero(money.BaseCurrency)
+	return nil
+}
+
+// BindCurrency attaches the base currency to the costs
+func (a *Assembly) BindCurrency() {
+	a.UnitCost = a.UnitCost.In(money.BaseCurrency)
+	a.TotalCost = a.TotalCost.In(money.BaseCurrency)
+	for i := range a.Items {
+		a.Items[i].UnitCost = a.Items[i].UnitCost.In(money.BaseCurrency)
+		a.Items[i].TotalCost = a.Items[i].TotalCost.In(money.BaseCurrency)
+	}
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLabelTemplate is the code of the template used when a label request names none
+const DefaultLabelTemplate = "a4-33"
+
+// MaxLabelsPerRequest limits the size of one label PDF
+const MaxLabelsPerRequest = 5000
+
+// LabelTemplate is a sticker layout: a page holding Columns × Rows labels. Sizes are in
+// millimetres; Margin is the distance from the page edge to the first label and Gap the
+// space between labels.
+type LabelTemplate struct {
+	ID          string     `json:"id" db:"id"`
+	Code        string     `json:"code" db:"code"`
+	Name        string     `json:"name" db:"name"`
+	PageWidth   float64    `json:"page_width" db:"page_width"`
+	PageHeight  float64    `json:"page_height" db:"page_height"`
+	Columns     int        `json:"columns" db:"columns"`
+	Rows        int        `json:"rows" db:"rows"`
+	LabelWidth  float64    `json:"label_width" db:"label_width"`
+	LabelHeight float64    `json:"label_height" db:"label_height"`
+	MarginTop   float64    `json:"margin_top" db:"margin_top"`
+	MarginLeft  float64    `json:"margin_left" db:"margin_left"`
+	GapX        float64    `json:"gap_x" db:"gap_x"`
+	GapY        float64    `json:"gap_y" db:"gap_y"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time `json:"-" db:"deleted_at"`
+}
+
+// LabelsPerPage is how many labels fit on one page
+func (t *LabelTemplate) LabelsPerPage() int {
+	return t.Columns * t.Rows
+}
+
+// Validate normalizes the code, defaults the name, checks that the labels fit on the page and
+// gives the template an ID
+func (t *LabelTemplate) Validate() error {
+	t.Code = strings.ToLower(strings.TrimSpace(t.Code))
+	if t.Code == "" {
+		return errors.New("code is required")
+	}
+	if len(t.Code) > 30 {
+		return errors.New("code cannot be longer than 30 characters")
+	}
+	if t.Name == "" {
+		t.Name = t.Code
+	}
+
+	if t.PageWidth <= 0 || t.PageHeight <= 0 || t.LabelWidth <= 0 || t.LabelHeight <= 0 {
+		return errors.New("page and label sizes must be positive")
+	}
+	if t.Columns < 1 || t.Rows < 1 {
+		return errors.New("columns and rows must be at least 1")
+	}
+	if t.MarginTop < 0 || t.MarginLeft < 0 || t.GapX < 0 || t.GapY < 0 {
+		return errors.New("margins and gaps cannot be negative")
+	}
+
+	// Allow for rounding in the published sizes of sticker sheets
+	const tolerance = 0.5
+	width := t.MarginLeft + float64(t.Columns)*t.LabelWidth + float64(t.Columns-1)*t.GapX
+	if width > t.PageWidth+tolerance {
+		return fmt.Errorf("%d columns of labels are %.1f mm wide, wider than the %.1f mm page", t.Columns, width, t.PageWidth)
+	}
+	height := t.MarginTop + float64(t.Rows)*t.LabelHeight + float64(t.Rows-1)*t.GapY
+	if height > t.PageHeight+tolerance {
+		return fmt.Errorf("%d rows of labels are %.1f mm high, higher than the %.1f mm page", t.Rows, height, t.PageHeight)
+	}
+
+	if t.ID == "" {
+		t.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// LabelItem is a product or variant and how many labels to print for it
+type LabelItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// LabelRequest asks for label sheets for the items, or for one label per unit received by a
+// stock-in. Skip leaves the first positions of the first sheet empty, for a sheet already
+// partly used.
+type LabelRequest struct {
+	TemplateID string      `json:"template_id"`
+	Items      []LabelItem `json:"items"`
+	StockInID  string      `json:"stock_in_id"`
+	Skip       int         `json:"skip"`
+}
+
+// Validate checks that the request names either items or a stock-in
+func (r *LabelRequest) Validate() error {
+	if len(r.Items) == 0 && r.StockInID == "" {
+		return errors.New("items or stock_in_id is required")
+	}
+	if len(r.Items) > 0 && r.StockInID != "" {
+		return errors.New("give either items or stock_in_id, not both")
+	}
+	if r.Skip < 0 {
+		return errors.New("skip cannot be negative")
+	}
+
+	total := 0
+	for _, item := range r.Items {
+		if item.ProductID == "" {
+			return errors.New("product_id is required for all items")
+		}
+		if item.Quantity < 1 {
+			return fmt.Errorf("quantity for product %s must be at least 1", item.ProductID)
+		}
+		total += item.Quantity
+	}
+	if total > MaxLabelsPerRequest {
+		return fmt.Errorf("at most %d labels can be printed at once", MaxLabelsPerRequest)
+	}
+	return nil
+}
+
+// Label is what is printed on one label
+type Label struct {
+	ProductID string
+	Name      string
+	SKU       string
+	Price     money.Money
+	Currency  string
+	// Barcode is the product's primary barcode; labels of products without one show the SKU
+	// as a Code 128 barcode
+	Barcode   string
+	Symbology string
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LandedCostType is the kind of charge added to the cost of received stock
+type LandedCostType string
+
+const (
+	LandedCostFreight   LandedCostType = "freight"
+	LandedCostDuty      LandedCostType = "duty"
+	LandedCostBrokerage LandedCostType = "brokerage"
+	LandedCostInsurance LandedCostType = "insurance"
+	LandedCostOther     LandedCostType = "other"
+)
+
+// IsValid reports whether the charge type is supported
+func (t LandedCostType) IsValid() bool {
+	switch t {
+	case LandedCostFreight, LandedCostDuty, LandedCostBrokerage, LandedCostInsurance, LandedCostOther:
+		return true
+	}
+	return false
+}
+
+// LandedCostAllocationMethod determines how a charge is spread over stock-in items
+type LandedCostAllocationMethod string
+
+const (
+	// AllocateByValue spreads the charge by each item's base-currency subtotal
+	AllocateByValue LandedCostAllocationMethod = "value"
+	// AllocateByQuantity spreads the charge by each item's quantity
+	AllocateByQuantity LandedCostAllocationMethod = "quantity"
+	// AllocateByWeight spreads the charge by each item's quantity times its product weight
+	AllocateByWeight LandedCostAllocationMethod = "weight"
+)
+
+// IsValid reports whether the allocation method is supported
+func (m LandedCostAllocationMethod) IsValid() bool {
+	return m == AllocateByValue || m == AllocateByQuantity || m == AllocateByWeight
+}
+
+// ErrNoAllocationBasis is returned when none of a charge's stock-in items has any value,
+// quantity or weight to allocate by
+var ErrNoAllocationBasis = errors.New("the stock-in items have nothing to allocate the charge by")
+
+// LandedCost is a charge such as freight or import duty that is added to the cost of the
+// items of one or more stock-ins
+type LandedCost struct {
+	ID               string                     `json:"id" db:"id"`
+	ReferenceNo      string                     `json:"reference_no" db:"reference_no"`
+	ChargeType       LandedCostType             `json:"charge_type" db:"charge_type"`
+	Description      string                     `json:"description,omitempty" db:"description"`
+	ChargeDate       time.Time                  `json:"charge_date" db:"charge_date"`
+	AllocationMethod LandedCostAllocationMethod `json:"allocation_method" db:"allocation_method"`
+	Amount           money.Money                `json:"amount" db:"amount"`
+
+	// Currency of the charge and the rate to the base currency at the charge date
+	Currency     string      `json:"currency" db:"currency"`
+	ExchangeRate money.Rate  `json:"exchange_rate" db:"exchange_rate"`
+	BaseAmount   money.Money `json:"base_amount" db:"base_amount"`
+
+	// Relations
+	SupplierID  *string                `json:"supplier_id,omitempty" db:"supplier_id"`
+	StockInIDs  []string               `json:"stock_in_ids" db:"-"`
+	Allocations []LandedCostAllocation `json:"allocations,omitempty" db:"-"`
+
+	// Timestamps
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// LandedCostAllocation is the share of a charge assigned to one stock-in item, in the base currency
+type LandedCostAllocation struct {
+	ID            string      `json:"id" db:"id"`
+	LandedCostID  string      `json:"landed_cost_id" db:"landed_cost_id"`
+	StockInID     string      `json:"stock_in_id" db:"stock_in_id"`
+	StockInItemID string      `json:"stock_in_item_id" db:"stock_in_item_id"`
+	ProductID     string      `json:"product_id" db:"product_id"`
+	ProductName   string      `json:"product_name" db:"product_name"`
+	Basis         float64     `json:"basis" db:"basis"`
+	Amount        money.Money `json:"amount" db:"amount"`
+
+	// Charge details, filled when listing the allocations of a stock-in
+	ReferenceNo      string                     `json:"reference_no,omitempty" db:"-"`
+	ChargeType       LandedCostType             `json:"charge_type,omitempty" db:"-"`
+	AllocationMethod LandedCostAllocationMethod `json:"allocation_method,omitempty" db:"-"`
+}
+
+// LandedCostItem is a stock-in item a charge can be allocated to
+type LandedCostItem struct {
+	ItemID       string
+	StockInID    string
+	ProductID    string
+	ProductName  string
+	Quantity     int
+	BaseSubtotal money.Money
+	// UnitWeight is the product weight in grams
+	UnitWeight float64
+}
+
+// NewLandedCost creates a new landed cost with default values
+func NewLandedCost() *LandedCost {
+	now := time.Now()
+	return &LandedCost{
+		ID:               uuid.NewString(),
+		ChargeType:       LandedCostFreight,
+		AllocationMethod: AllocateByValue,
+		ChargeDate:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+}
+
+// Validate checks the landed cost and removes duplicate stock-ins
+func (l *LandedCost) Validate() error {
+	if l.ReferenceNo == "" {
+		return errors.New("reference_no is required")
+	}
+	if l.ChargeType == "" {
+		l.ChargeType = LandedCostOther
+	}
+	if !l.ChargeType.IsValid() {
+		return fmt.Errorf("invalid charge_type %q (use freight, duty, brokerage, insurance or other)", l.ChargeType)
+	}
+	if l.AllocationMethod == "" {
+		l.AllocationMethod = AllocateByValue
+	}
+	if !l.AllocationMethod.IsValid() {
+		return fmt.Errorf("invalid allocation_method %q (use value, quantity or weight)", l.AllocationMethod)
+	}
+	if !l.Amount.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	if l.ChargeDate.IsZero() {
+		l.ChargeDate = time.Now()
+	}
+
+	seen := map[string]bool{}
+	ids := make([]string, 0, len(l.StockInIDs))
+	for _, id := range l.StockInIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return errors.New("at least one stock-in is required")
+	}
+	l.StockInIDs = ids
+	return nil
+}
+
+// BindCurrency normalizes the charge currency and attaches it to every amount
+func (l *LandedCost) BindCurrency() {
+	l.Currency = money.NormalizeCurrency(l.Currency)
+	l.ExchangeRate = documentRate(l.Currency, l.ExchangeRate)
+	l.Amount = l.Amount.In(l.Currency)
+	l.BaseAmount = l.BaseAmount.In(money.BaseCurrency)
+	for i := range l.Allocations {
+		l.Allocations[i].Amount = l.Allocations[i].Amount.In(money.BaseCurrency)
+	}
+}
+
+// ConvertToBase fills the base-currency amount using the charge's exchange rate
+func (l *LandedCost) ConvertToBase() {
+	l.BindCurrency()
+	l.Amount = l.Amount.Round()
+	l.BaseAmount = l.ExchangeRate.Convert(l.Amount, money.BaseCurrency).Round()
+}
+
+// Basis returns the quantity an item contributes to the allocation under this method
+func (m LandedCostAllocationMethod) Basis(item LandedCostItem) *big.Rat {
+	switch m {
+	case AllocateByQuantity:
+		return big.NewRat(int64(item.Quantity), 1)
+	case AllocateByWeight:
+		weight := new(big.Rat)
+		if item.UnitWeight > 0 {
+			weight.SetFloat64(item.UnitWeight)
+		}
+		return weight.Mul(weight, big.NewRat(int64(item.Quantity), 1))
+	}
+	return item.BaseSubtotal.Rat()
+}
+
+// Allocate spreads the base amount of the charge over the items in proportion to
+// their basis. Shares are rounded to the base currency; the last item with a basis
+// takes the remainder so the shares add up to the charge exactly.
+func (l *LandedCost) Allocate(items []LandedCostItem) ([]LandedCostAllocation, error) {
+	bases := make([]*big.Rat, len(items))
+	total := new(big.Rat)
+	last := -1
+	for i, item := range items {
+		bases[i] = l.AllocationMethod.Basis(item)
+		if bases[i].Sign() < 0 {
+			bases[i].SetInt64(0)
+		}
+		if bases[i].Sign() > 0 {
+			last = i
+		}
+		total.Add(total, bases[i])
+	}
+	if last < 0 {
+		return nil, ErrNoAllocationBasis
+	}
+
+	amount := l.BaseAmount.In(money.BaseCurrency)
+	allocated := money.Zero(money.BaseCurrency)
+	var allocations []LandedCostAllocation
+	for i, item := range items {
+		if bases[i].Sign() == 0 {
+			continue
+		}
+		share := amount.Sub(allocated)
+		if i != last {
+			share = amount.MulRat(new(big.Rat).Quo(bases[i], total)).Round()
+			allocated = allocated.Add(share)
+		}
+		basis, _ := bases[i].Float64()
+		allocations = append(allocations, LandedCostAllocation{
+			ID:            uuid.NewString(),
+			LandedCostID:  l.ID,
+			StockInID:     item.StockInID,
+			StockInItemID: item.ItemID,
+			ProductID:     item.ProductID,
+			ProductName:   item.ProductName,
+			Basis:         basis,
+			Amount:        share,
+		})
+	}
+	return allocations, nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"inventory-go/money"
+	"testing"
+)
+
+func TestLandedCostAllocate(t *testing.T) {
+	items := []LandedCostItem{
+		{ItemID: "1", StockInID: "si", ProductID: "a", Quantity: 3, BaseSubtotal: idr("30000"), UnitWeight: 250},
+		{ItemID: "2", StockInID: "si", ProductID: "b", Quantity: 1, BaseSubtotal: idr("10000")},
+		{ItemID: "3", StockInID: "si", ProductID: "c", Quantity: 2, BaseSubtotal: idr("20000"), UnitWeight: 500},
+	}
+	tests := []struct {
+		name   string
+		method LandedCostAllocationMethod
+		amount string
+		want   map[string]string
+	}{
+		{"value", AllocateByValue, "100000", map[string]string{"1": "50000", "2": "16667", "3": "33333"}},
+		{"value rounded half up", AllocateByValue, "10001", map[string]string{"1": "5001", "2": "1667", "3": "3333"}},
+		{"quantity", AllocateByQuantity, "100000", map[string]string{"1": "50000", "2": "16667", "3": "33333"}},
+		{"weight skips items without one", AllocateByWeight, "100000", map[string]string{"1": "42857", "3": "57143"}},
+		{"weight of a small charge", AllocateByWeight, "1", map[string]string{"1": "0", "3": "1"}},
+	}
+	for _, tt := range tests {
+		l := &LandedCost{ID: "lc", AllocationMethod: tt.method, BaseAmount: idr(tt.amount)}
+		allocations, err := l.Allocate(items)
+		if err != nil {
+			t.Errorf("%s: Allocate() = %v", tt.name, err)
+			continue
+		}
+		if len(allocations) != len(tt.want) {
+			t.Errorf("%s: %d allocations, want %d", tt.name, len(allocations), len(tt.want))
+		}
+		sum := money.Zero(money.BaseCurrency)
+		for _, a := range allocations {
+			if want := tt.want[a.StockInItemID]; a.Amount.String() != want {
+				t.Errorf("%s: item %s gets %s, want %s", tt.name, a.StockInItemID, a.Amount.String(), want)
+			}
+			if a.LandedCostID != "lc" || a.Amount.Currency() != money.BaseCurrency {
+				t.Errorf("%s: allocation of %s to %s in %s", tt.name, a.Amount.String(), a.LandedCostID, a.Amount.Currency())
+			}
+			sum = sum.Add(a.Amount)
+		}
+		if sum.Cmp(l.BaseAmount) != 0 {
+			t.Errorf("%s: allocations add up to %s, want %s", tt.name, sum.String(), l.BaseAmount.String())
+		}
+	}
+}
+
+func TestLandedCostAllocateWithoutBasis(t *testing.T) {
+	unweighed := []LandedCostItem{{ItemID: "1", Quantity: 3, BaseSubtotal: idr("30000")}}
+	tests := []struct {
+		name   string
+		method LandedCostAllocationMethod
+		items  []LandedCostItem
+	}{
+		{"no items", AllocateByValue, nil},
+		{"no weights", AllocateByWeight, unweighed},
+		{"nothing received", AllocateByQuantity, []LandedCostItem{{ItemID: "1", Quantity: 0}}},
+		{"no value", AllocateByValue, []LandedCostItem{{ItemID: "1", Quantity: 3, BaseSubtotal: idr("0")}}},
+	}
+	for _, tt := range tests {
+		l := &LandedCost{AllocationMethod: tt.method, BaseAmount: idr("5000")}
+		if _, err := l.Allocate(tt.items); !errors.Is(err, ErrNoAllocationBasis) {
+			t.Errorf("%s: Allocate() = %v, want %v", tt.name, err, ErrNoAllocationBasis)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"math/big"
+)
+
+// MaxQuantity is the largest quantity accepted on a document line
+const MaxQuantity = 1_000_000
+
+// ErrOutOfRange is returned for documents with quantities or amounts too large to total
+var ErrOutOfRange = errors.New("quantity or amount out of range")
+
+// documentLimits adds up the amounts of a document exactly, so a document too large to
+// total in fixed point is refused before its totals are worked out
+type documentLimits struct {
+	total *big.Rat
+	err   error
+}
+
+func newDocumentLimits() *documentLimits {
+	return &documentLimits{total: new(big.Rat)}
+}
+
+// line adds a line of quantity at a unit amount, and the line's other amounts such as tax
+// and discount
+func (d *documentLimits) line(quantity int, unit money.Money, other ...money.Money) {
+	if d.err == nil && (quantity > MaxQuantity || quantity < -MaxQuantity) {
+		d.err = fmt.Errorf("%w: quantity %d is more than %d", ErrOutOfRange, quantity, MaxQuantity)
+	}
+	value := new(big.Rat).Mul(unit.Rat(), big.NewRat(int64(quantity), 1))
+	d.total.Add(d.total, value.Abs(value))
+	d.amounts(other...)
+}
+
+// amounts adds amounts that are not per unit, such as a document's total
+func (d *documentLimits) amounts(amounts ...money.Money) {
+	for _, m := range amounts {
+		d.total.Add(d.total, new(big.Rat).Abs(m.Rat()))
+	}
+}
+
+// check returns an error when a quantity was out of range or the amounts add up to more
+// than money.MaxAmount, either as they are or converted to the base currency at the rate.
+// A rate that is not known yet is not checked.
+func (d *documentLimits) check(rate money.Rate) error {
+	if d.err != nil {
+		return d.err
+	}
+	if money.ExceedsMax(d.total) {
+		return fmt.Errorf("%w: amounts add up to more than %d", ErrOutOfRange, int64(money.MaxAmount))
+	}
+	if rate.IsPositive() && money.ExceedsMax(new(big.Rat).Mul(d.total, rate.Rat())) {
+		return fmt.Errorf("%w: amounts add up to more than %d %s", ErrOutOfRange, int64(money.MaxAmount), money.BaseCurrency)
+	}
+	return nil
+}
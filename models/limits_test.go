@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"inventory-go/money"
+	"testing"
+)
+
+func TestSaleValidateAmounts(t *testing.T) {
+	item := func(quantity int, price string) SaleItem {
+		return SaleItem{ProductID: "p", Quantity: quantity, UnitPrice: money.MustParse(price, "")}
+	}
+	tests := []struct {
+		name     string
+		currency string
+		rate     string
+		items    []SaleItem
+		err      bool
+	}{
+		{"ordinary sale", "IDR", "", []SaleItem{item(3, "15000"), item(1, "250000")}, false},
+		{"at the limit", "IDR", "", []SaleItem{item(1_000_000, "10000000")}, false},
+		{"quantity too large", "IDR", "", []SaleItem{item(MaxQuantity+1, "1")}, true},
+		{"negative quantity too large", "IDR", "", []SaleItem{item(-MaxQuantity-1, "1")}, true},
+		{"line too large", "IDR", "", []SaleItem{item(1_000_000, "10000000.0001")}, true},
+		{"lines add up too large", "IDR", "", []SaleItem{item(1, "6000000000000"), item(1, "6000000000000")}, true},
+		{"base total too large", "USD", "16000", []SaleItem{item(1000, "1000000000")}, true},
+		{"rate not known yet", "USD", "", []SaleItem{item(1000, "1000000000")}, false},
+	}
+	for _, tt := range tests {
+		s := Sale{Currency: tt.currency, Items: tt.items}
+		if tt.rate != "" {
+			s.ExchangeRate = rate(tt.rate)
+		}
+		err := s.Validate()
+		if tt.err != errors.Is(err, ErrOutOfRange) || (!tt.err && err != nil) {
+			t.Errorf("%s: Validate() = %v, want out of range %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestStockInValidateAmounts(t *testing.T) {
+	s := StockIn{Currency: "IDR", Items: []StockInItem{{Quantity: 10, UnitCost: idr("5000")}}}
+	if err := s.ValidateAmounts(); err != nil {
+		t.Errorf("ValidateAmounts() = %v", err)
+	}
+	s.Paid = idr("20000000000000")
+	if err := s.ValidateAmounts(); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("ValidateAmounts() with a paid amount above the limit = %v, want %v", err, ErrOutOfRange)
+	}
+
+	item := StockInItem{Quantity: 2, UnitCost: money.MustParse("400000000000", "USD")}
+	if err := item.ValidateAmounts("USD", rate("16000")); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("item ValidateAmounts() = %v, want %v", err, ErrOutOfRange)
+	}
+	if err := item.ValidateAmounts("IDR", rate("16000")); err != nil {
+		t.Errorf("item ValidateAmounts() in the base currency = %v", err)
+	}
+}
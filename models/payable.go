@@ -0,0 +1,264 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrStockInNotPayable is returned when a payment or credit is recorded against a stock-in
+// that is not completed or does not owe enough
+var ErrStockInNotPayable = errors.New("stock-in cannot take this payment")
+
+// StockInPayment is money paid to a supplier against a stock-in
+type StockInPayment struct {
+	ID            string      `json:"id" db:"id"`
+	PaymentNo     string      `json:"payment_no" db:"payment_no"`
+	StockInID     string      `json:"stock_in_id" db:"stock_in_id"`
+	SupplierID    *string     `json:"supplier_id,omitempty" db:"supplier_id"`
+	PaymentDate   time.Time   `json:"payment_date" db:"payment_date"`
+	Currency      string      `json:"currency" db:"currency"`
+	Amount        money.Money `json:"amount" db:"amount"`
+	PaymentMethod string      `json:"payment_method,omitempty" db:"payment_method"`
+	Reference     string      `json:"reference,omitempty" db:"reference"`
+	Note          string      `json:"note,omitempty" db:"note"`
+
+	// Amount in the base currency at the stock-in's exchange rate
+	BaseAmount money.Money `json:"base_amount" db:"base_amount"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// GenerateID sets a UUID if ID is empty and ensures the payment number and date exist
+func (p *StockInPayment) GenerateID() {
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+	if p.PaymentNo == "" {
+		p.PaymentNo = "SPAY-" + time.Now().Format("20060102") + "-" + p.ID[:6]
+	}
+	if p.PaymentDate.IsZero() {
+		p.PaymentDate = time.Now()
+	}
+}
+
+// SupplierCredit is a credit note from a supplier, for goods returned or a price allowance,
+// that reduces what is owed on a stock-in
+type SupplierCredit struct {
+	ID         string      `json:"id" db:"id"`
+	CreditNo   string      `json:"credit_no" db:"credit_no"`
+	StockInID  string      `json:"stock_in_id" db:"stock_in_id"`
+	SupplierID *string     `json:"supplier_id,omitempty" db:"supplier_id"`
+	CreditDate time.Time   `json:"credit_date" db:"credit_date"`
+	Currency   string      `json:"currency" db:"currency"`
+	Amount     money.Money `json:"amount" db:"amount"`
+	Reason     string      `json:"reason,omitempty" db:"reason"`
+
+	// Amount in the base currency at the stock-in's exchange rate
+	BaseAmount money.Money `json:"base_amount" db:"base_amount"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// GenerateID sets a UUID if ID is empty and ensures the credit number and date exist
+func (c *SupplierCredit) GenerateID() {
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+	if c.CreditNo == "" {
+		c.CreditNo = "SCR-" + time.Now().Format("20060102") + "-" + c.ID[:6]
+	}
+	if c.CreditDate.IsZero() {
+		c.CreditDate = time.Now()
+	}
+}
+
+// SetDueDate sets the due date from the given payment terms when none was given
+func (s *StockIn) SetDueDate(terms PaymentTerms) {
+	if s.DueDate != nil {
+		return
+	}
+	date := s.OrderDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+	due := terms.DueDate(date)
+	s.DueDate = &due
+}
+
+// CalculateBalance sets the balance to the total less payments and supplier credits. Opening
+// stock owes nothing.
+func (s *StockIn) CalculateBalance() {
+	if s.Opening {
+		s.Balance = money.Zero(s.Total.Currency())
+		return
+	}
+	s.Balance = s.Total.Sub(s.Paid).Sub(s.Credited)
+}
+
+// settle checks that a completed stock-in owes at least the amount, converts it to the
+// base currency and takes it off the balance
+func (s *StockIn) settle(amount money.Money) (money.Money, error) {
+	s.BindCurrency()
+	amount = amount.In(s.Currency)
+	if s.Status != StockInStatusCompleted {
+		return amount, fmt.Errorf("%w: only completed stock-ins are owed to the supplier", ErrStockInNotPayable)
+	}
+	if s.Opening {
+		return amount, fmt.Errorf("%w: opening stock is not owed to a supplier", ErrStockInNotPayable)
+	}
+	if !amount.IsPositive() {
+		return amount, fmt.Errorf("%w: amount must be positive", ErrStockInNotPayable)
+	}
+	if amount.Cmp(s.Balance) > 0 {
+		return amount, fmt.Errorf("%w: %s is more than the balance of %s",
+			ErrStockInNotPayable, amount.String(), s.Balance.String())
+	}
+	return s.ExchangeRate.Convert(amount, money.BaseCurrency).Round(), nil
+}
+
+// ApplyPayment records a payment against the stock-in in its currency
+func (s *StockIn) ApplyPayment(p *StockInPayment) error {
+	base, err := s.settle(p.Amount)
+	if err != nil {
+		return err
+	}
+	p.StockInID = s.ID
+	p.SupplierID = s.SupplierID
+	p.Currency = s.Currency
+	p.Amount = p.Amount.In(s.Currency)
+	p.BaseAmount = base
+	s.Paid = s.Paid.Add(p.Amount)
+	s.CalculateBalance()
+	return nil
+}
+
+// ApplyCredit records a supplier credit against the stock-in in its currency
+func (s *StockIn) ApplyCredit(c *SupplierCredit) error {
+	base, err := s.settle(c.Amount)
+	if err != nil {
+		return err
+	}
+	c.StockInID = s.ID
+	c.SupplierID = s.SupplierID
+	c.Currency = s.Currency
+	c.Amount = c.Amount.In(s.Currency)
+	c.BaseAmount = base
+	s.Credited = s.Credited.Add(c.Amount)
+	s.CalculateBalance()
+	return nil
+}
+
+// OpenBill is a completed stock-in that has not been fully paid or credited
+type OpenBill struct {
+	StockInID    string      `json:"stock_in_id"`
+	ReferenceNo  string      `json:"reference_no"`
+	SupplierID   string      `json:"supplier_id,omitempty"`
+	SupplierName string      `json:"supplier_name,omitempty"`
+	OrderDate    time.Time   `json:"order_date"`
+	DueDate      time.Time   `json:"due_date"`
+	Currency     string      `json:"currency"`
+	ExchangeRate money.Rate  `json:"exchange_rate"`
+	Total        money.Money `json:"total"`
+	Balance      money.Money `json:"balance"`
+
+	// Balance converted to the base currency at the stock-in's exchange rate
+	BaseBalance money.Money `json:"base_balance"`
+	DaysOverdue int         `json:"days_overdue"`
+}
+
+// Age fills the base balance and the number of days the bill is past due on the given date
+func (b *OpenBill) Age(asOf time.Time) {
+	b.Currency = money.NormalizeCurrency(b.Currency)
+	b.Total = b.Total.In(b.Currency)
+	b.Balance = b.Balance.In(b.Currency)
+	b.BaseBalance = documentRate(b.Currency, b.ExchangeRate).Convert(b.Balance, money.BaseCurrency).Round()
+	b.DaysOverdue = daysPastDue(b.DueDate, asOf)
+}
+
+// APAgingLine is what is owed to one supplier by age
+type APAgingLine struct {
+	SupplierID   string `json:"supplier_id,omitempty"`
+	SupplierName string `json:"supplier_name"`
+	BillCount    int    `json:"bill_count"`
+	AgingBuckets
+}
+
+// APAgingReport buckets outstanding payables per supplier, in the base currency
+type APAgingReport struct {
+	AsOf      time.Time     `json:"as_of"`
+	Currency  string        `json:"currency"`
+	Suppliers []APAgingLine `json:"suppliers"`
+	Totals    AgingBuckets  `json:"totals"`
+}
+
+// NewAPAgingReport groups aged open bills by supplier. Stock-ins without a supplier are
+// reported together.
+func NewAPAgingReport(asOf time.Time, bills []OpenBill) *APAgingReport {
+	report := &APAgingReport{
+		AsOf:      asOf,
+		Currency:  money.BaseCurrency,
+		Suppliers: []APAgingLine{},
+		Totals:    NewAgingBuckets(),
+	}
+
+	index := map[string]int{}
+	for _, bill := range bills {
+		i, ok := index[bill.SupplierID]
+		if !ok {
+			name := bill.SupplierName
+			if bill.SupplierID == "" {
+				name = "No supplier"
+			}
+			report.Suppliers = append(report.Suppliers, APAgingLine{
+				SupplierID:   bill.SupplierID,
+				SupplierName: name,
+				AgingBuckets: NewAgingBuckets(),
+			})
+			i = len(report.Suppliers) - 1
+			index[bill.SupplierID] = i
+		}
+		line := &report.Suppliers[i]
+		line.BillCount++
+		line.Add(bill.DaysOverdue, bill.BaseBalance)
+		report.Totals.Add(bill.DaysOverdue, bill.BaseBalance)
+	}
+
+	sort.SliceStable(report.Suppliers, func(a, b int) bool {
+		return report.Suppliers[a].SupplierName < report.Suppliers[b].SupplierName
+	})
+	return report
+}
+
+// SupplierStatement reconciles what is owed to a supplier over a period: stock-ins are
+// credits, payments and supplier credits are debits, in the base currency
+type SupplierStatement struct {
+	SupplierID     string          `json:"supplier_id"`
+	SupplierName   string          `json:"supplier_name"`
+	StartDate      time.Time       `json:"start_date"`
+	EndDate        time.Time       `json:"end_date"`
+	Currency       string          `json:"currency"`
+	OpeningBalance money.Money     `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	TotalDebit     money.Money     `json:"total_debit"`
+	TotalCredit    money.Money     `json:"total_credit"`
+	ClosingBalance money.Money     `json:"closing_balance"`
+}
+
+// NewSupplierStatement builds a statement from every stock-in, payment and credit up to the
+// end date. Documents before the start date make up the opening balance.
+func NewSupplierStatement(supplier *Supplier, start, end time.Time, lines []StatementLine) *SupplierStatement {
+	s := &SupplierStatement{
+		SupplierID:   supplier.ID,
+		SupplierName: supplier.Name,
+		StartDate:    start,
+		EndDate:      end,
+		Currency:     money.BaseCurrency,
+	}
+	s.OpeningBalance, s.Lines, s.TotalDebit, s.TotalCredit, s.ClosingBalance = buildStatement(start, lines, true)
+	return s
+}
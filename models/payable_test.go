@@ -0,0 +1,175 @@
+package models
+
+import (
+	"errors"
+	"inventory-go/money"
+	"testing"
+)
+
+// payableStockIn returns a completed USD stock-in of 100 at 16000 with nothing paid yet
+func payableStockIn() *StockIn {
+	supplierID := "sup"
+	s := &StockIn{
+		ID:           "si",
+		Status:       StockInStatusCompleted,
+		Currency:     "USD",
+		ExchangeRate: rate("16000"),
+		Total:        money.MustParse("100", "USD"),
+		Paid:         money.Zero("USD"),
+		Credited:     money.Zero("USD"),
+		SupplierID:   &supplierID,
+	}
+	s.CalculateBalance()
+	return s
+}
+
+func TestApplyPaymentsAndCredits(t *testing.T) {
+	type step struct {
+		credit bool
+		amount string
+		base   string
+		err    error
+	}
+	tests := []struct {
+		name     string
+		steps    []step
+		paid     string
+		credited string
+		balance  string
+	}{
+		{"payment", []step{{false, "40", "640000", nil}}, "40", "0", "60"},
+		{"credit", []step{{true, "12.5", "200000", nil}}, "0", "12.5", "87.5"},
+		{"settled by payment and credit", []step{{true, "30", "480000", nil}, {false, "70", "1120000", nil}}, "70", "30", "0"},
+		{"payment over balance", []step{{true, "30", "480000", nil}, {false, "70.01", "", ErrStockInNotPayable}}, "0", "30", "70"},
+		{"credit over balance", []step{{false, "100", "1600000", nil}, {true, "1", "", ErrStockInNotPayable}}, "100", "0", "0"},
+		{"zero amount", []step{{false, "0", "", ErrStockInNotPayable}}, "0", "0", "100"},
+	}
+	for _, tt := range tests {
+		s := payableStockIn()
+		for i, st := range tt.steps {
+			var err error
+			var base money.Money
+			if st.credit {
+				c := &SupplierCredit{Amount: money.MustParse(st.amount, "")}
+				err = s.ApplyCredit(c)
+				base = c.BaseAmount
+				if err == nil && (c.Currency != "USD" || c.StockInID != "si" || *c.SupplierID != "sup") {
+					t.Errorf("%s: credit %d = %s %s %v, want USD si sup", tt.name, i, c.Currency, c.StockInID, c.SupplierID)
+				}
+			} else {
+				p := &StockInPayment{Amount: money.MustParse(st.amount, "")}
+				err = s.ApplyPayment(p)
+				base = p.BaseAmount
+				if err == nil && (p.Currency != "USD" || p.StockInID != "si" || *p.SupplierID != "sup") {
+					t.Errorf("%s: payment %d = %s %s %v, want USD si sup", tt.name, i, p.Currency, p.StockInID, p.SupplierID)
+				}
+			}
+			if !errors.Is(err, st.err) {
+				t.Errorf("%s: step %d error = %v, want %v", tt.name, i, err, st.err)
+				continue
+			}
+			if err == nil && base.String() != st.base {
+				t.Errorf("%s: step %d base amount = %s, want %s", tt.name, i, base.String(), st.base)
+			}
+		}
+		if s.Paid.String() != tt.paid || s.Credited.String() != tt.credited || s.Balance.String() != tt.balance {
+			t.Errorf("%s: paid %s, credited %s, balance %s, want %s, %s, %s", tt.name,
+				s.Paid.String(), s.Credited.String(), s.Balance.String(), tt.paid, tt.credited, tt.balance)
+		}
+	}
+}
+
+func TestSettleRequiresCompletedStockIn(t *testing.T) {
+	s := payableStockIn()
+	s.Status = StockInStatusDraft
+	if err := s.ApplyPayment(&StockInPayment{Amount: money.MustParse("10", "USD")}); !errors.Is(err, ErrStockInNotPayable) {
+		t.Errorf("payment on a draft stock-in error = %v, want %v", err, ErrStockInNotPayable)
+	}
+	if !s.Paid.IsZero() {
+		t.Errorf("paid = %s, want 0", s.Paid.String())
+	}
+}
+
+func TestOpeningStockOwesNothing(t *testing.T) {
+	s := payableStockIn()
+	s.Opening = true
+	s.CalculateBalance()
+	if !s.Balance.IsZero() || s.Balance.Currency() != "USD" {
+		t.Errorf("opening stock balance = %s %s, want 0 USD", s.Balance.String(), s.Balance.Currency())
+	}
+	if err := s.ApplyCredit(&SupplierCredit{Amount: money.MustParse("10", "USD")}); !errors.Is(err, ErrStockInNotPayable) {
+		t.Errorf("credit on opening stock error = %v, want %v", err, ErrStockInNotPayable)
+	}
+}
+
+func TestAPAgingReport(t *testing.T) {
+	asOf := date("2024-06-30")
+	bills := []OpenBill{
+		{SupplierID: "2", SupplierName: "Sinar", Currency: "IDR", DueDate: date("2024-07-15"), Balance: idr("700")},
+		{SupplierID: "1", SupplierName: "Maju", Currency: "USD", ExchangeRate: rate("16000"), DueDate: date("2024-04-20"), Balance: money.MustParse("0.5", "USD")},
+		{Currency: "IDR", DueDate: date("2024-03-01"), Balance: idr("300")},
+		{SupplierID: "2", SupplierName: "Sinar", Currency: "IDR", DueDate: date("2024-06-01"), Balance: idr("100")},
+	}
+	for i := range bills {
+		bills[i].Age(asOf)
+	}
+
+	report := NewAPAgingReport(asOf, bills)
+	if len(report.Suppliers) != 3 {
+		t.Fatalf("%d suppliers, want 3", len(report.Suppliers))
+	}
+	tests := []struct {
+		name   string
+		bills  int
+		bucket money.Money
+		want   string
+		total  string
+	}{
+		{"Maju", 1, report.Suppliers[0].Days61To90, "8000", "8000"},
+		{"No supplier", 1, report.Suppliers[1].Over90, "300", "300"},
+		{"Sinar", 2, report.Suppliers[2].Current, "700", "800"},
+	}
+	for i, tt := range tests {
+		line := report.Suppliers[i]
+		if line.SupplierName != tt.name || line.BillCount != tt.bills || tt.bucket.String() != tt.want || line.Total.String() != tt.total {
+			t.Errorf("supplier %d = %s, %d bills, bucket %s, total %s, want %s, %d, %s, %s", i,
+				line.SupplierName, line.BillCount, tt.bucket.String(), line.Total.String(), tt.name, tt.bills, tt.want, tt.total)
+		}
+	}
+	if got := report.Suppliers[2].Days1To30.String(); got != "100" {
+		t.Errorf("Sinar 1-30 days = %s, want 100", got)
+	}
+	if got := report.Totals.Total.String(); got != "9100" {
+		t.Errorf("total = %s, want 9100", got)
+	}
+}
+
+func TestSupplierStatement(t *testing.T) {
+	lines := []StatementLine{
+		{Date: date("2024-01-20"), Type: StatementLineBill, Debit: idr("0"), Credit: idr("2000")},
+		{Date: date("2024-02-05"), Type: StatementLinePayment, Debit: idr("1500"), Credit: idr("0")},
+		{Date: date("2024-02-05"), Type: StatementLineBill, Debit: idr("0"), Credit: idr("800")},
+		{Date: date("2024-02-12"), Type: StatementLineCredit, Debit: idr("300"), Credit: idr("0")},
+	}
+	s := NewSupplierStatement(&Supplier{ID: "s", Name: "Sinar"}, date("2024-02-01"), date("2024-02-29"), lines)
+
+	if s.OpeningBalance.String() != "2000" || s.ClosingBalance.String() != "1000" {
+		t.Errorf("opening %s, closing %s, want 2000, 1000", s.OpeningBalance.String(), s.ClosingBalance.String())
+	}
+	want := []struct {
+		kind    string
+		balance string
+	}{
+		{StatementLineBill, "2800"},
+		{StatementLinePayment, "1300"},
+		{StatementLineCredit, "1000"},
+	}
+	if len(s.Lines) != len(want) {
+		t.Fatalf("%d lines, want %d", len(s.Lines), len(want))
+	}
+	for i, w := range want {
+		if s.Lines[i].Type != w.kind || s.Lines[i].Balance.String() != w.balance {
+			t.Errorf("line %d = %s %s, want %s %s", i, s.Lines[i].Type, s.Lines[i].Balance.String(), w.kind, w.balance)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PeriodStatus represents whether documents may be posted in an accounting period
+type PeriodStatus string
+
+const (
+	PeriodStatusOpen   PeriodStatus = "open"
+	PeriodStatusClosed PeriodStatus = "closed"
+)
+
+// Actions recorded when a closed period is overridden
+const (
+	PeriodActionCreate = "create"
+	PeriodActionUpdate = "update"
+	PeriodActionDelete = "delete"
+	PeriodActionReopen = "reopen"
+)
+
+// ErrPeriodOverlap is returned when a period's dates overlap another period
+var ErrPeriodOverlap = errors.New("period overlaps an existing accounting period")
+
+// AccountingPeriod is a date range whose documents are locked once it is closed.
+// Closing it records a snapshot of stock on hand and its value at the period end.
+type AccountingPeriod struct {
+	ID        string       `json:"id" db:"id"`
+	Name      string       `json:"name" db:"name"`
+	StartDate time.Time    `json:"start_date" db:"start_date"`
+	EndDate   time.Time    `json:"end_date" db:"end_date"`
+	Status    PeriodStatus `json:"status" db:"status"`
+	Note      string       `json:"note,omitempty" db:"note"`
+	ClosedAt  *time.Time   `json:"closed_at,omitempty" db:"closed_at"`
+	ClosedBy  string       `json:"closed_by,omitempty" db:"closed_by"`
+
+	// Stock on hand at the end of the period, recorded when it was closed
+	Snapshot *InventoryValuation `json:"snapshot,omitempty" db:"-"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NewAccountingPeriod creates an open period
+func NewAccountingPeriod() *AccountingPeriod {
+	return &AccountingPeriod{
+		ID:     uuid.NewString(),
+		Status: PeriodStatusOpen,
+	}
+}
+
+// Validate normalizes the period dates and checks the range
+func (p *AccountingPeriod) Validate() error {
+	if p.StartDate.IsZero() || p.EndDate.IsZero() {
+		return errors.New("start_date and end_date are required")
+	}
+	p.StartDate = truncateToDate(p.StartDate)
+	p.EndDate = truncateToDate(p.EndDate)
+	if p.EndDate.Before(p.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	if p.Name == "" {
+		p.Name = p.StartDate.Format("2006-01-02") + " to " + p.EndDate.Format("2006-01-02")
+	}
+	if p.Status == "" {
+		p.Status = PeriodStatusOpen
+	}
+	return nil
+}
+
+// IsClosed reports whether the period is closed
+func (p *AccountingPeriod) IsClosed() bool {
+	return p.Status == PeriodStatusClosed
+}
+
+// EndOfPeriod returns the last instant of the period's end date
+func (p *AccountingPeriod) EndOfPeriod() time.Time {
+	return p.EndDate.Add(24*time.Hour - time.Nanosecond)
+}
+
+// PeriodClosedError is returned when a document dated inside a closed period is changed
+type PeriodClosedError struct {
+	Period AccountingPeriod
+	Date   time.Time
+}
+
+func (e *PeriodClosedError) Error() string {
+	return fmt.Sprintf("%s falls in closed accounting period %s (%s to %s)",
+		e.Date.Format("2006-01-02"), e.Period.Name,
+		e.Period.StartDate.Format("2006-01-02"), e.Period.EndDate.Format("2006-01-02"))
+}
+
+// PeriodOverride is the audit record of a change allowed inside a closed period
+type PeriodOverride struct {
+	ID           string    `json:"id" db:"id"`
+	PeriodID     string    `json:"period_id" db:"period_id"`
+	PeriodName   string    `json:"period_name" db:"-"`
+	Action       string    `json:"action" db:"action"`
+	SourceType   string    `json:"source_type,omitempty" db:"source_type"`
+	SourceID     string    `json:"source_id,omitempty" db:"source_id"`
+	Reference    string    `json:"reference,omitempty" db:"reference"`
+	DocumentDate time.Time `json:"document_date" db:"document_date"`
+	Actor        string    `json:"actor" db:"actor"`
+	Reason       string    `json:"reason" db:"reason"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Validate checks that an override names who made it and why
+func (o *PeriodOverride) Validate() error {
+	if o.Actor == "" {
+		return errors.New("override requires the name of the person making it")
+	}
+	if o.Reason == "" {
+		return errors.New("override requires a reason")
+	}
+	return nil
+}
+
+// PeriodLock is the closed-period check a change to a document is saved under. The
+// repository checks the dates inside the transaction that saves the change, so a period
+// cannot be closed in between, and records the override in that transaction.
+type PeriodLock struct {
+	Action     string
+	SourceType string
+	SourceID   string
+	Reference  string
+	Dates      []time.Time
+
+	// Override is the administrator's override of a closed period, nil when none was given
+	Override *PeriodOverride
+}
+
+// NewPeriodLock creates the lock for a change to a document with the given dates. Both
+// the current and the new date of an update must be given.
+func NewPeriodLock(action, sourceType, sourceID, reference string, dates ...time.Time) *PeriodLock {
+	return &PeriodLock{
+		Action:     action,
+		SourceType: sourceType,
+		SourceID:   sourceID,
+		Reference:  reference,
+		Dates:      dates,
+	}
+}
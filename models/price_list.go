@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceList is a set of selling prices for a customer tier, such as retail, reseller or
+// wholesale. Each product or variant can have several prices by minimum quantity.
+type PriceList struct {
+	ID          string `json:"id" db:"id"`
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description,omitempty" db:"description"`
+	Currency    string `json:"currency" db:"currency"`
+	Active      bool   `json:"active" db:"active"`
+
+	// Relations
+	Items []PriceListItem `json:"items,omitempty" db:"-"`
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// PriceListItem is the price of a product or variant on a price list from a minimum quantity
+type PriceListItem struct {
+	ID          string      `json:"id" db:"id"`
+	PriceListID string      `json:"price_list_id" db:"price_list_id"`
+	ProductID   string      `json:"product_id" db:"product_id"`
+	MinQuantity int         `json:"min_quantity" db:"min_quantity"`
+	Price       money.Money `json:"price" db:"price"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// GenerateID sets a UUID if ID is empty
+func (l *PriceList) GenerateID() {
+	if l.ID == "" {
+		l.ID = uuid.NewString()
+	}
+}
+
+// Validate checks the code and items and binds the item prices to the list currency
+func (l *PriceList) Validate() error {
+	l.Code = strings.ToUpper(strings.TrimSpace(l.Code))
+	if l.Code == "" {
+		return errors.New("code is required")
+	}
+	if l.Name == "" {
+		l.Name = l.Code
+	}
+	l.Currency = money.NormalizeCurrency(l.Currency)
+
+	seen := map[string]bool{}
+	for i := range l.Items {
+		item := &l.Items[i]
+		if item.ProductID == "" {
+			return errors.New("product_id is required for all items")
+		}
+		if item.MinQuantity == 0 {
+			item.MinQuantity = 1
+		}
+		if item.MinQuantity < 1 {
+			return errors.New("min_quantity must be at least 1")
+		}
+		item.Price = item.Price.In(l.Currency)
+		if item.Price.Cmp(money.Zero(l.Currency)) < 0 {
+			return errors.New("price cannot be negative")
+		}
+		key := fmt.Sprintf("%s|%d", item.ProductID, item.MinQuantity)
+		if seen[key] {
+			return fmt.Errorf("product %s has more than one price from quantity %d", item.ProductID, item.MinQuantity)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// PriceSource is where a sale item's unit price came from
+type PriceSource string
+
+const (
+	// PriceSourceManual is a unit price given on the sale
+	PriceSourceManual PriceSource = "manual"
+	// PriceSourcePriceList is a price from the customer's price list
+	PriceSourcePriceList PriceSource = "price_list"
+	// PriceSourceProduct is the product's own price
+	PriceSourceProduct PriceSource = "product"
+)
+
+// AppliedPrice records the rule that set a sale item's unit price
+type AppliedPrice struct {
+	PriceSource      PriceSource `json:"price_source,omitempty" db:"price_source"`
+	PriceListID      *string     `json:"price_list_id,omitempty" db:"price_list_id"`
+	PriceListCode    string      `json:"price_list_code,omitempty" db:"price_list_code"`
+	PriceMinQuantity int         `json:"price_min_quantity,omitempty" db:"price_min_quantity"`
+}
+
+// IsCalculated reports whether the price was resolved rather than given
+func (a AppliedPrice) IsCalculated() bool {
+	return a.PriceSource == PriceSourcePriceList || a.PriceSource == PriceSourceProduct
+}
+
+// PriceQuote is the unit price a customer pays for a quantity of a product and the rule
+// it came from
+type PriceQuote struct {
+	ProductID string      `json:"product_id"`
+	Quantity  int         `json:"quantity"`
+	Currency  string      `json:"currency"`
+	UnitPrice money.Money `json:"unit_price"`
+	AppliedPrice
+}
+
+// QuotePrice finds a product's unit price for a quantity. The price list's highest quantity
+// break at or below the quantity is used, preferring breaks for the variant itself over
+// its parent product's; without one the product's own price applies. Prices in another
+// currency are converted when they are in the base currency and a rate is given. It returns
+// nil when no price can be found in the currency.
+func QuotePrice(list *PriceList, breaks []PriceListItem, product *Product, quantity int, currency string, rate money.Rate) *PriceQuote {
+	currency = money.NormalizeCurrency(currency)
+	quote := &PriceQuote{ProductID: product.ID, Quantity: quantity, Currency: currency}
+
+	if list != nil {
+		best := bestPriceBreak(breaks, product.ID, quantity)
+		if best == nil && product.ParentID != nil {
+			best = bestPriceBreak(breaks, *product.ParentID, quantity)
+		}
+		if best != nil {
+			if price, ok := priceIn(best.Price.In(list.Currency), currency, rate); ok {
+				listID := list.ID
+				quote.UnitPrice = price
+				quote.AppliedPrice = AppliedPrice{
+					PriceSource:      PriceSourcePriceList,
+					PriceListID:      &listID,
+					PriceListCode:    list.Code,
+					PriceMinQuantity: best.MinQuantity,
+				}
+				return quote
+			}
+		}
+	}
+
+	product.Price.BindCurrency()
+	price, ok := priceIn(product.Price.Price, currency, rate)
+	if !ok {
+		return nil
+	}
+	quote.UnitPrice = price
+	quote.AppliedPrice = AppliedPrice{PriceSource: PriceSourceProduct}
+	return quote
+}
+
+// bestPriceBreak returns the product's break with the highest minimum quantity that the
+// quantity reaches
+func bestPriceBreak(breaks []PriceListItem, productID string, quantity int) *PriceListItem {
+	var best *PriceListItem
+	for i := range breaks {
+		b := &breaks[i]
+		if b.ProductID != productID || b.MinQuantity > quantity {
+			continue
+		}
+		if best == nil || b.MinQuantity > best.MinQuantity {
+			best = b
+		}
+	}
+	return best
+}
+
+// priceIn expresses a price in the given currency. Base-currency prices are converted at the
+// rate; other currencies must match.
+func priceIn(price money.Money, currency string, rate money.Rate) (money.Money, bool) {
+	from := money.NormalizeCurrency(price.Currency())
+	if from == currency {
+		return price, true
+	}
+	if from != money.BaseCurrency || !rate.IsPositive() {
+		return price, false
+	}
+	inverse := new(big.Rat).Inv(rate.Rat())
+	return price.MulRat(inverse).In(currency).Round(), true
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"inventory-go/money"
+	"testing"
+)
+
+func TestQuotePrice(t *testing.T) {
+	parentID := "parent"
+	product := func(id string, parent *string, price, currency string) *Product {
+		return &Product{ID: id, ParentID: parent, Price: Price{Price: money.MustParse(price, ""), Currency: currency}}
+	}
+	wholesale := &PriceList{ID: "pl", Code: "WHOLESALE", Currency: "IDR"}
+	dollars := &PriceList{ID: "usd", Code: "EXPORT", Currency: "USD"}
+	breaks := []PriceListItem{
+		{ProductID: "p", MinQuantity: 1, Price: idr("10000")},
+		{ProductID: "p", MinQuantity: 10, Price: idr("9000")},
+		{ProductID: "p", MinQuantity: 50, Price: idr("8000")},
+		{ProductID: "parent", MinQuantity: 1, Price: idr("9500")},
+		{ProductID: "parent", MinQuantity: 20, Price: idr("7000")},
+		{ProductID: "own", MinQuantity: 5, Price: idr("9900")},
+		{ProductID: "q", MinQuantity: 10, Price: idr("11000")},
+	}
+	dollarBreaks := []PriceListItem{{ProductID: "p", MinQuantity: 1, Price: money.MustParse("0.7", "USD")}}
+
+	tests := []struct {
+		name     string
+		list     *PriceList
+		breaks   []PriceListItem
+		product  *Product
+		quantity int
+		currency string
+		rate     string
+		price    string // empty when no price can be found
+		source   PriceSource
+		min      int
+	}{
+		{"first break", wholesale, breaks, product("p", nil, "12000", "IDR"), 1, "IDR", "", "10000", PriceSourcePriceList, 1},
+		{"break reached exactly", wholesale, breaks, product("p", nil, "12000", "IDR"), 10, "IDR", "", "9000", PriceSourcePriceList, 10},
+		{"highest break reached", wholesale, breaks, product("p", nil, "12000", "IDR"), 49, "IDR", "", "9000", PriceSourcePriceList, 10},
+		{"above every break", wholesale, breaks, product("p", nil, "12000", "IDR"), 500, "IDR", "", "8000", PriceSourcePriceList, 50},
+		{"variant uses parent breaks", wholesale, breaks, product("v", &parentID, "12000", "IDR"), 25, "IDR", "", "7000", PriceSourcePriceList, 20},
+		{"variant breaks before parent", wholesale, breaks, product("own", &parentID, "12000", "IDR"), 25, "IDR", "", "9900", PriceSourcePriceList, 5},
+		{"below the only break", wholesale, breaks, product("q", nil, "12000", "IDR"), 9, "IDR", "", "12000", PriceSourceProduct, 0},
+		{"not on the list", wholesale, breaks, product("r", nil, "12000", "IDR"), 100, "IDR", "", "12000", PriceSourceProduct, 0},
+		{"no price list", nil, nil, product("p", nil, "12000", "IDR"), 100, "IDR", "", "12000", PriceSourceProduct, 0},
+		{"list converted", wholesale, breaks, product("p", nil, "12000", "IDR"), 1, "USD", "16000", "0.63", PriceSourcePriceList, 1},
+		{"product price converted", nil, nil, product("p", nil, "12000", "IDR"), 1, "usd", "16000", "0.75", PriceSourceProduct, 0},
+		{"no rate to convert", wholesale, breaks, product("p", nil, "12000", "IDR"), 1, "USD", "", "", "", 0},
+		{"list in the sale currency", dollars, dollarBreaks, product("p", nil, "12000", "IDR"), 1, "USD", "16000", "0.7", PriceSourcePriceList, 1},
+		{"foreign list not converted", dollars, dollarBreaks, product("p", nil, "12000", "IDR"), 1, "IDR", "16000", "12000", PriceSourceProduct, 0},
+		{"foreign product price not converted", nil, nil, product("p", nil, "20", "USD"), 1, "IDR", "16000", "", "", 0},
+	}
+	for _, tt := range tests {
+		var r money.Rate
+		if tt.rate != "" {
+			r = rate(tt.rate)
+		}
+		quote := QuotePrice(tt.list, tt.breaks, tt.product, tt.quantity, tt.currency, r)
+		if tt.price == "" {
+			if quote != nil {
+				t.Errorf("%s: quote = %s %s, want none", tt.name, quote.UnitPrice.String(), quote.PriceSource)
+			}
+			continue
+		}
+		if quote == nil {
+			t.Errorf("%s: no quote, want %s", tt.name, tt.price)
+			continue
+		}
+		if quote.UnitPrice.String() != tt.price || quote.PriceSource != tt.source || quote.PriceMinQuantity != tt.min {
+			t.Errorf("%s: quote = %s %s from %d, want %s %s from %d", tt.name,
+				quote.UnitPrice.String(), quote.PriceSource, quote.PriceMinQuantity, tt.price, tt.source, tt.min)
+		}
+		if quote.Currency != money.NormalizeCurrency(tt.currency) || quote.UnitPrice.Currency() != quote.Currency {
+			t.Errorf("%s: quote in %s, unit price in %s, want %s", tt.name, quote.Currency, quote.UnitPrice.Currency(), tt.currency)
+		}
+		if tt.source == PriceSourcePriceList && (quote.PriceListID == nil || *quote.PriceListID != tt.list.ID || quote.PriceListCode != tt.list.Code) {
+			t.Errorf("%s: price list %v %s, want %s %s", tt.name, quote.PriceListID, quote.PriceListCode, tt.list.ID, tt.list.Code)
+		}
+	}
+}
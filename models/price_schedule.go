@@ -0,0 +1,176 @@
+package models
+
+import (
+	"errors"
+	"inventory-go/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceChangeSource is what changed a product's price
+type PriceChangeSource string
+
+const (
+	// PriceChangeCreated is the price a product was created with
+	PriceChangeCreated PriceChangeSource = "created"
+	// PriceChangeManual is a price changed by updating the product
+	PriceChangeManual PriceChangeSource = "manual"
+	// PriceChangeScheduleStart is a scheduled price taking effect
+	PriceChangeScheduleStart PriceChangeSource = "schedule_start"
+	// PriceChangeScheduleEnd is a scheduled price ending and the previous price coming back
+	PriceChangeScheduleEnd PriceChangeSource = "schedule_end"
+	// PriceChangeCostPlus is a price set by a cost-plus pricing rule after a purchase
+	PriceChangeCostPlus PriceChangeSource = "cost_plus"
+)
+
+// PriceHistory is one change of a product's price
+type PriceHistory struct {
+	ID        string            `json:"id" db:"id"`
+	ProductID string            `json:"product_id" db:"product_id"`
+	OldPrice  *money.Money      `json:"old_price" db:"old_price"`
+	NewPrice  money.Money       `json:"new_price" db:"new_price"`
+	Currency  string            `json:"currency" db:"currency"`
+	Source    PriceChangeSource `json:"source" db:"source"`
+
+	// Schedule that made the change, for scheduled prices
+	ScheduleID *string `json:"schedule_id,omitempty" db:"schedule_id"`
+
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// NewPriceHistory records a change from the old price, if the product had one, to the new one
+func NewPriceHistory(productID string, old *Price, price Price, source PriceChangeSource, scheduleID *string, at time.Time) *PriceHistory {
+	price.BindCurrency()
+	entry := &PriceHistory{
+		ID:         uuid.NewString(),
+		ProductID:  productID,
+		NewPrice:   price.Price,
+		Currency:   price.Currency,
+		Source:     source,
+		ScheduleID: scheduleID,
+		ChangedAt:  at,
+	}
+	if old != nil {
+		old.BindCurrency()
+		oldPrice := old.Price
+		entry.OldPrice = &oldPrice
+	}
+	return entry
+}
+
+// PriceChanged reports whether two prices differ in amount or currency
+func PriceChanged(old, price Price) bool {
+	old.BindCurrency()
+	price.BindCurrency()
+	return old.Currency != price.Currency || old.Price.Cmp(price.Price) != 0
+}
+
+// PriceScheduleStatus is where a scheduled price is in its life
+type PriceScheduleStatus string
+
+const (
+	// PriceSchedulePending has not started yet
+	PriceSchedulePending PriceScheduleStatus = "pending"
+	// PriceScheduleActive is the product's price until the schedule ends
+	PriceScheduleActive PriceScheduleStatus = "active"
+	// PriceScheduleCompleted has been applied and, if it had an end, reverted
+	PriceScheduleCompleted PriceScheduleStatus = "completed"
+	// PriceScheduleExpired ended before the scheduler could apply it
+	PriceScheduleExpired PriceScheduleStatus = "expired"
+	// PriceScheduleCancelled was cancelled
+	PriceScheduleCancelled PriceScheduleStatus = "cancelled"
+)
+
+// IsValid reports whether the status is known
+func (s PriceScheduleStatus) IsValid() bool {
+	switch s {
+	case PriceSchedulePending, PriceScheduleActive, PriceScheduleCompleted, PriceScheduleExpired, PriceScheduleCancelled:
+		return true
+	}
+	return false
+}
+
+// PriceSchedule is a future price for a product, such as a flash sale. It takes effect at
+// StartsAt; when EndsAt is set the price the product had before comes back then, otherwise
+// the change is permanent.
+type PriceSchedule struct {
+	ID        string              `json:"id" db:"id"`
+	ProductID string              `json:"product_id" db:"product_id"`
+	Price     money.Money         `json:"price" db:"price"`
+	Currency  string              `json:"currency" db:"currency"`
+	StartsAt  time.Time           `json:"starts_at" db:"starts_at"`
+	EndsAt    *time.Time          `json:"ends_at,omitempty" db:"ends_at"`
+	Status    PriceScheduleStatus `json:"status" db:"status"`
+	Note      string              `json:"note,omitempty" db:"note"`
+
+	// Price the product had when the schedule took effect, restored when it ends
+	PreviousPrice    *money.Money `json:"previous_price,omitempty" db:"previous_price"`
+	PreviousCurrency string       `json:"previous_currency,omitempty" db:"previous_currency"`
+	AppliedAt        *time.Time   `json:"applied_at,omitempty" db:"applied_at"`
+	RevertedAt       *time.Time   `json:"reverted_at,omitempty" db:"reverted_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// GenerateID sets a UUID if ID is empty
+func (s *PriceSchedule) GenerateID() {
+	if s.ID == "" {
+		s.ID = uuid.NewString()
+	}
+}
+
+// Validate checks the price and window and binds the price to the currency
+func (s *PriceSchedule) Validate() error {
+	if s.ProductID == "" {
+		return errors.New("product_id is required")
+	}
+	s.BindCurrency()
+	if s.Price.IsNegative() {
+		return errors.New("price cannot be negative")
+	}
+	if s.StartsAt.IsZero() {
+		return errors.New("starts_at is required")
+	}
+	if s.EndsAt != nil && !s.EndsAt.After(s.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// BindCurrency normalizes the currency and attaches it to the prices
+func (s *PriceSchedule) BindCurrency() {
+	s.Currency = money.NormalizeCurrency(s.Currency)
+	s.Price = s.Price.In(s.Currency)
+	if s.PreviousPrice != nil {
+		s.PreviousCurrency = money.NormalizeCurrency(s.PreviousCurrency)
+		previous := s.PreviousPrice.In(s.PreviousCurrency)
+		s.PreviousPrice = &previous
+	}
+}
+
+// Overlaps reports whether the schedule's window overlaps another's. A permanent change
+// takes an instant, so it conflicts only with a window it falls inside or a change at the
+// same time.
+func (s *PriceSchedule) Overlaps(other *PriceSchedule) bool {
+	start, end := s.window()
+	otherStart, otherEnd := other.window()
+	return start.Before(otherEnd) && otherStart.Before(end)
+}
+
+// window returns when the schedule starts and ends
+func (s *PriceSchedule) window() (time.Time, time.Time) {
+	if s.EndsAt != nil {
+		return s.StartsAt, *s.EndsAt
+	}
+	return s.StartsAt, s.StartsAt.Add(time.Nanosecond)
+}
+
+// PriceScheduleRun is what one run of the price scheduler did
+type PriceScheduleRun struct {
+	RanAt    time.Time `json:"ran_at"`
+	Applied  int       `json:"applied"`
+	Reverted int       `json:"reverted"`
+	Expired  int       `json:"expired"`
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"errors"
+	"inventory-go/money"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PricingRuleScope is what a cost-plus pricing rule covers
+type PricingRuleScope string
+
+const (
+	// PricingRuleScopeProduct covers one product and its variants
+	PricingRuleScopeProduct PricingRuleScope = "product"
+	// PricingRuleScopeCategory covers the products in a category and the categories below it
+	PricingRuleScopeCategory PricingRuleScope = "category"
+)
+
+// MarkupType is how a pricing rule adds margin to the cost
+type MarkupType string
+
+const (
+	// MarkupPercentage adds a percentage of the cost
+	MarkupPercentage MarkupType = "percentage"
+	// MarkupFixed adds a fixed amount to the cost
+	MarkupFixed MarkupType = "fixed"
+)
+
+// RoundingMode is which way a suggested price is rounded to the rule's step
+type RoundingMode string
+
+const (
+	RoundingNearest RoundingMode = "nearest"
+	RoundingUp      RoundingMode = "up"
+	RoundingDown    RoundingMode = "down"
+)
+
+// PricingRule sets a product's selling price from its latest purchase cost. Amounts are in
+// the base currency, so only products priced in the base currency are repriced.
+type PricingRule struct {
+	ID         string           `json:"id" db:"id"`
+	Name       string           `json:"name" db:"name"`
+	Scope      PricingRuleScope `json:"scope" db:"scope"`
+	ProductID  *string          `json:"product_id,omitempty" db:"product_id"`
+	CategoryID *string          `json:"category_id,omitempty" db:"category_id"`
+
+	MarkupType MarkupType `json:"markup_type" db:"markup_type"`
+	// Percentage of the cost added for percentage markups, e.g. 25 for 25%
+	MarkupPercent money.Rate `json:"markup_percent" db:"markup_percent"`
+	// Amount added to the cost for fixed markups
+	MarkupAmount money.Money `json:"markup_amount" db:"markup_amount"`
+
+	// Step the price is rounded to, e.g. 500 for the nearest Rp 500; zero rounds to the currency
+	RoundTo  money.Money  `json:"round_to" db:"round_to"`
+	Rounding RoundingMode `json:"rounding" db:"rounding"`
+
+	// Smallest change in unit cost, as a percentage of the previous cost, that reprices
+	ThresholdPercent money.Rate `json:"threshold_percent" db:"threshold_percent"`
+	// AutoApply changes the price straight away instead of queueing a suggestion for review
+	AutoApply bool `json:"auto_apply" db:"auto_apply"`
+	Active    bool `json:"active" db:"active"`
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// GenerateID sets a UUID if ID is empty
+func (r *PricingRule) GenerateID() {
+	if r.ID == "" {
+		r.ID = uuid.NewString()
+	}
+}
+
+// Validate checks the scope, markup and rounding and binds the amounts to the base currency
+func (r *PricingRule) Validate() error {
+	r.BindCurrency()
+
+	switch r.Scope {
+	case PricingRuleScopeProduct:
+		if r.ProductID == nil || *r.ProductID == "" {
+			return errors.New("product_id is required for product rules")
+		}
+		r.CategoryID = nil
+	case PricingRuleScopeCategory:
+		if r.CategoryID == nil || *r.CategoryID == "" {
+			return errors.New("category_id is required for category rules")
+		}
+		r.ProductID = nil
+	default:
+		return errors.New("scope must be \"product\" or \"category\"")
+	}
+	if r.Name == "" {
+		r.Name = string(r.Scope) + " markup"
+	}
+
+	switch r.MarkupType {
+	case MarkupPercentage:
+		if !r.MarkupPercent.IsPositive() {
+			return errors.New("markup_percent must be positive")
+		}
+		r.MarkupAmount = money.Zero(money.BaseCurrency)
+	case MarkupFixed:
+		if !r.MarkupAmount.IsPositive() {
+			return errors.New("markup_amount must be positive")
+		}
+		r.MarkupPercent = money.Rate{}
+	default:
+		return errors.New("markup_type must be \"percentage\" or \"fixed\"")
+	}
+
+	if r.Rounding == "" {
+		r.Rounding = RoundingNearest
+	}
+	if r.Rounding != RoundingNearest && r.Rounding != RoundingUp && r.Rounding != RoundingDown {
+		return errors.New("rounding must be \"nearest\", \"up\" or \"down\"")
+	}
+	if r.RoundTo.IsNegative() {
+		return errors.New("round_to cannot be negative")
+	}
+	if r.ThresholdPercent.Rat().Sign() < 0 {
+		return errors.New("threshold_percent cannot be negative")
+	}
+	return nil
+}
+
+// BindCurrency attaches the base currency to the rule's amounts
+func (r *PricingRule) BindCurrency() {
+	r.MarkupAmount = r.MarkupAmount.In(money.BaseCurrency)
+	r.RoundTo = r.RoundTo.In(money.BaseCurrency)
+}
+
+// PriceFor returns the selling price for a base-currency unit cost: the cost plus the markup,
+// rounded to the rule's step
+func (r *PricingRule) PriceFor(cost money.Money) money.Money {
+	cost = cost.In(money.BaseCurrency)
+	var price money.Money
+	if r.MarkupType == MarkupFixed {
+		price = cost.Add(r.MarkupAmount)
+	} else {
+		factor := new(big.Rat).Add(big.NewRat(1, 1), new(big.Rat).Quo(r.MarkupPercent.Rat(), big.NewRat(100, 1)))
+		price = cost.MulRat(factor)
+	}
+	return roundToStep(price.Round(), r.RoundTo, r.Rounding)
+}
+
+// roundToStep rounds a price to a multiple of step; a zero step leaves it alone
+func roundToStep(price, step money.Money, mode RoundingMode) money.Money {
+	unit := step.In(price.Currency()).Minor()
+	if unit <= 0 {
+		return price
+	}
+	amount := price.Minor()
+	remainder := amount % unit
+	if remainder < 0 {
+		remainder += unit
+	}
+	if remainder == 0 {
+		return price
+	}
+
+	down := amount - remainder
+	switch mode {
+	case RoundingUp:
+		amount = down + unit
+	case RoundingDown:
+		amount = down
+	default:
+		if remainder*2 >= unit {
+			amount = down + unit
+		} else {
+			amount = down
+		}
+	}
+	return money.New(amount, price.Currency())
+}
+
+// covers reports whether the rule applies to a product with the given parent and categories
+func (r *PricingRule) covers(productID string, scope ProductScope) bool {
+	if r.Scope == PricingRuleScopeProduct {
+		return r.ProductID != nil && (*r.ProductID == productID || (scope.ParentID != nil && *r.ProductID == *scope.ParentID))
+	}
+	for _, id := range scope.CategoryIDs {
+		if r.CategoryID != nil && *r.CategoryID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectPricingRule picks the active rule for a product: its own rule, then its parent
+// product's, then the rule of the nearest category above it. It returns nil when none covers it.
+func SelectPricingRule(productID string, scope ProductScope, rules []PricingRule) *PricingRule {
+	var best *PricingRule
+	bestRank := -1
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Active || !rule.covers(productID, scope) {
+			continue
+		}
+
+		var rank int
+		switch {
+		case rule.Scope == PricingRuleScopeProduct && *rule.ProductID == productID:
+			rank = len(scope.CategoryIDs) + 2
+		case rule.Scope == PricingRuleScopeProduct:
+			rank = len(scope.CategoryIDs) + 1
+		default:
+			// Categories are ordered from the product's own upwards
+			for depth, id := range scope.CategoryIDs {
+				if id == *rule.CategoryID {
+					rank = len(scope.CategoryIDs) - depth
+					break
+				}
+			}
+		}
+		if rank > bestRank {
+			best, bestRank = rule, rank
+		}
+	}
+	return best
+}
+
+// CostChanged reports whether a new unit cost differs from the previous one by at least the
+// threshold percentage. A first purchase, with no previous cost, always counts.
+func CostChanged(previous *money.Money, cost money.Money, threshold money.Rate) bool {
+	if previous == nil || previous.IsZero() {
+		return true
+	}
+	change := cost.Sub(previous.In(cost.Currency())).Abs()
+	if change.IsZero() {
+		return false
+	}
+	percent := new(big.Rat).Mul(change.Ratio(*previous), big.NewRat(100, 1))
+	return percent.Cmp(threshold.Rat()) >= 0
+}
+
+// PriceSuggestionStatus is where a suggested price change is in review
+type PriceSuggestionStatus string
+
+const (
+	// PriceSuggestionPending is waiting for review
+	PriceSuggestionPending PriceSuggestionStatus = "pending"
+	// PriceSuggestionApplied changed the product's price, on review or automatically
+	PriceSuggestionApplied PriceSuggestionStatus = "applied"
+	// PriceSuggestionRejected was turned down
+	PriceSuggestionRejected PriceSuggestionStatus = "rejected"
+	// PriceSuggestionSuperseded was replaced by a newer suggestion for the product
+	PriceSuggestionSuperseded PriceSuggestionStatus = "superseded"
+)
+
+// IsValid reports whether the status is known
+func (s PriceSuggestionStatus) IsValid() bool {
+	switch s {
+	case PriceSuggestionPending, PriceSuggestionApplied, PriceSuggestionRejected, PriceSuggestionSuperseded:
+		return true
+	}
+	return false
+}
+
+// PriceSuggestion is a new selling price proposed by a pricing rule when a completed
+// stock-in changed a product's unit cost. Costs and prices are in the base currency.
+type PriceSuggestion struct {
+	ID          string  `json:"id" db:"id"`
+	ProductID   string  `json:"product_id" db:"product_id"`
+	ProductName string  `json:"product_name,omitempty" db:"-"`
+	SKU         string  `json:"sku,omitempty" db:"-"`
+	RuleID      *string `json:"rule_id,omitempty" db:"rule_id"`
+	StockInID   string  `json:"stock_in_id" db:"stock_in_id"`
+
+	// Unit cost of the stock-in before this one, nil for a first purchase
+	PreviousCost   *money.Money `json:"previous_cost" db:"previous_cost"`
+	UnitCost       money.Money  `json:"unit_cost" db:"unit_cost"`
+	CurrentPrice   money.Money  `json:"current_price" db:"current_price"`
+	SuggestedPrice money.Money  `json:"suggested_price" db:"suggested_price"`
+	// Price the product was given, which may differ from the suggestion when it was adjusted on review
+	AppliedPrice *money.Money `json:"applied_price,omitempty" db:"applied_price"`
+	Currency     string       `json:"currency" db:"currency"`
+
+	Status      PriceSuggestionStatus `json:"status" db:"status"`
+	AutoApplied bool                  `json:"auto_applied" db:"auto_applied"`
+	ReviewedAt  *time.Time            `json:"reviewed_at,omitempty" db:"reviewed_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BindCurrency attaches the currency to the suggestion's amounts
+func (s *PriceSuggestion) BindCurrency() {
+	s.Currency = money.NormalizeCurrency(s.Currency)
+	s.UnitCost = s.UnitCost.In(s.Currency)
+	s.CurrentPrice = s.CurrentPrice.In(s.Currency)
+	s.SuggestedPrice = s.SuggestedPrice.In(s.Currency)
+	if s.PreviousCost != nil {
+		previous := s.PreviousCost.In(s.Currency)
+		s.PreviousCost = &previous
+	}
+	if s.AppliedPrice != nil {
+		applied := s.AppliedPrice.In(s.Currency)
+		s.AppliedPrice = &applied
+	}
+}
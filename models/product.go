@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +23,9 @@ type Product struct {
 	Weight            Weight            `json:"weight"`
 	InventoryActivity InventoryActivity `json:"inventory_activity,omitempty"`
 
+	// Attributes such as color or material, by name; variants carry their option values
+	Attributes map[string]string `json:"attributes,omitempty" db:"attributes"`
+
 	// Relations
 	Images          []*Images  `json:"pictures,omitempty"`
 	Variants        []*Product `json:"variants,omitempty"`
@@ -54,9 +58,12 @@ type BasicInfo struct {
 }
 
 type Price struct {
-	Price          float64 `json:"price" db:"price"`
-	Currency       string  `json:"currency" db:"currency"`
-	LastUpdateUnix int64   `json:"last_update_unix" db:"last_update_unix"`
+	Price          money.Money `json:"price" db:"price"`
+	Currency       string      `json:"currency" db:"currency"`
+	LastUpdateUnix int64       `json:"last_update_unix" db:"last_update_unix"`
+
+	// TaxCodeID overrides the category's tax code for this product
+	TaxCodeID *string `json:"tax_code_id,omitempty" db:"tax_code_id"`
 }
 
 type Weight struct {
@@ -70,13 +77,62 @@ type InventoryActivity struct {
 	Reject     int `json:"reject" db:"reject"`
 }
 
+// ProductFilter narrows and orders a product listing or search
+type ProductFilter struct {
+	// CategoryID keeps products in the category or any category below it
+	CategoryID string
+	Status     string
+	Condition  string
+	MinPrice   *money.Money
+	MaxPrice   *money.Money
+	InStock    bool
+	// Attributes keeps products that have all of these attribute values, or that have a
+	// variant with all of them; names and values are matched ignoring case
+	Attributes map[string]string
+
+	// Sort is one of the ProductSort orders; listings default to newest and searches to relevance
+	Sort string
+}
+
+// Product sort orders
+const (
+	ProductSortNewest      = "newest"
+	ProductSortRelevance   = "relevance"
+	ProductSortPriceAsc    = "price_asc"
+	ProductSortPriceDesc   = "price_desc"
+	ProductSortStockAsc    = "stock_asc"
+	ProductSortStockDesc   = "stock_desc"
+	ProductSortNameAsc     = "name_asc"
+	ProductSortNameDesc    = "name_desc"
+	ProductSortBestSelling = "best_selling"
+)
+
+// IsValidProductSort reports whether sort is a known product sort order
+func IsValidProductSort(sort string) bool {
+	switch sort {
+	case ProductSortNewest, ProductSortRelevance, ProductSortPriceAsc, ProductSortPriceDesc,
+		ProductSortStockAsc, ProductSortStockDesc, ProductSortNameAsc, ProductSortNameDesc,
+		ProductSortBestSelling:
+		return true
+	}
+	return false
+}
+
 type Images struct {
-	ID        string    `json:"id" db:"id"`
-	ProductID string    `json:"product_id" db:"product_id"`
-	URL       string    `json:"url" db:"url"`
-	IsPrimary bool      `json:"is_primary" db:"is_primary"`
-	SortOrder int       `json:"sort_order" db:"sort_order"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID           string `json:"id" db:"id"`
+	ProductID    string `json:"product_id" db:"product_id"`
+	URL          string `json:"url" db:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
+	MediumURL    string `json:"medium_url,omitempty" db:"medium_url"`
+	ContentType  string `json:"content_type,omitempty" db:"content_type"`
+	Size         int64  `json:"size,omitempty" db:"size"`
+	Width        int    `json:"width,omitempty" db:"width"`
+	Height       int    `json:"height,omitempty" db:"height"`
+	// StorageKeys are the stored files of an uploaded image, removed along with it
+	StorageKeys []string  `json:"-" db:"storage_keys"`
+	IsPrimary   bool      `json:"is_primary" db:"is_primary"`
+	SortOrder   int       `json:"sort_order" db:"sort_order"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
 // NewProduct creates a new product with a generated UUID if not provided
@@ -90,7 +146,8 @@ func NewProduct() *Product {
 			Status: 1, // Default status: Active
 		},
 		Price: Price{
-			Currency: "IDR",
+			Price:    money.Zero(money.DefaultCurrency),
+			Currency: money.DefaultCurrency,
 		},
 		Weight: Weight{
 			Unit: 1, // Default to grams
@@ -98,6 +155,25 @@ func NewProduct() *Product {
 	}
 }
 
+// UnmarshalJSON decodes a price and binds the amount to the price's currency
+func (p *Price) UnmarshalJSON(data []byte) error {
+	type priceAlias Price
+	var decoded priceAlias
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*p = Price(decoded)
+	p.BindCurrency()
+	return nil
+}
+
+// BindCurrency normalizes the currency code and attaches it to the amount.
+// Repositories call this after scanning the price fields individually.
+func (p *Price) BindCurrency() {
+	p.Currency = money.NormalizeCurrency(p.Currency)
+	p.Price = p.Price.In(p.Currency)
+}
+
 // NewImage creates a new image with a generated UUID if not provided
 func NewImage() *Images {
 	return &Images{
@@ -178,6 +254,7 @@ func (p *Product) LoadVariants(db pgx.Tx) error {
 		if err != nil {
 			return err
 		}
+		variant.Price.BindCurrency()
 		variants = append(variants, &variant)
 	}
 
@@ -192,7 +269,7 @@ func (p *Product) LoadVariants(db pgx.Tx) error {
 // BeforeDelete handles cleanup before deleting a product
 func (p *Product) BeforeDelete(db pgx.Tx) error {
 	// Delete all variants
-	_, err := db.Exec(context.Background(), 
+	_, err := db.Exec(context.Background(),
 		"DELETE FROM products WHERE parent_id = $1", p.ID)
 	if err != nil {
 		return fmt.Errorf("error deleting variants: %w", err)
@@ -204,6 +281,6 @@ func (p *Product) BeforeDelete(db pgx.Tx) error {
 	if err != nil {
 		return fmt.Errorf("error deleting images: %w", err)
 	}
-	
+
 	return nil
 }
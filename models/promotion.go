@@ -0,0 +1,519 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromotionType is how a promotion discounts the items it applies to
+type PromotionType string
+
+const (
+	// PromotionPercentage takes a percentage off each qualifying item
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixedAmount takes a fixed amount off the qualifying items, shared between them
+	PromotionFixedAmount PromotionType = "fixed_amount"
+	// PromotionBuyXGetY gives the cheapest get_quantity units free for every buy_quantity
+	// units bought
+	PromotionBuyXGetY PromotionType = "buy_x_get_y"
+	// PromotionBundlePrice sells every bundle_quantity qualifying units for the bundle price
+	PromotionBundlePrice PromotionType = "bundle_price"
+)
+
+// IsValid reports whether the promotion type is known
+func (t PromotionType) IsValid() bool {
+	switch t {
+	case PromotionPercentage, PromotionFixedAmount, PromotionBuyXGetY, PromotionBundlePrice:
+		return true
+	}
+	return false
+}
+
+// PromotionScope is which sale items a promotion applies to
+type PromotionScope string
+
+const (
+	// PromotionScopeOrder applies to every item of the sale
+	PromotionScopeOrder PromotionScope = "order"
+	// PromotionScopeProduct applies to the listed products and their variants
+	PromotionScopeProduct PromotionScope = "product"
+	// PromotionScopeCategory applies to products in the listed categories or below them
+	PromotionScopeCategory PromotionScope = "category"
+)
+
+// IsValid reports whether the promotion scope is known
+func (s PromotionScope) IsValid() bool {
+	return s == PromotionScopeOrder || s == PromotionScopeProduct || s == PromotionScopeCategory
+}
+
+// Promotion is a discount rule applied automatically when a sale is priced
+type Promotion struct {
+	ID          string         `json:"id" db:"id"`
+	Code        string         `json:"code" db:"code"`
+	Name        string         `json:"name" db:"name"`
+	Description string         `json:"description,omitempty" db:"description"`
+	Type        PromotionType  `json:"type" db:"type"`
+	Scope       PromotionScope `json:"scope" db:"scope"`
+
+	// Products or categories the promotion is limited to, depending on the scope
+	ProductIDs  []string `json:"product_ids,omitempty" db:"product_ids"`
+	CategoryIDs []string `json:"category_ids,omitempty" db:"category_ids"`
+
+	// Currency of the amount, bundle price and minimum spend. Base-currency amounts are
+	// converted for sales in other currencies.
+	Currency string `json:"currency" db:"currency"`
+
+	// Percent off for percentage promotions, e.g. 10 for 10%
+	Percent money.Rate `json:"percent" db:"percent"`
+	// Amount off the qualifying items for fixed_amount promotions
+	Amount money.Money `json:"amount" db:"amount"`
+	// Units to buy and units given free for buy_x_get_y promotions
+	BuyQuantity int `json:"buy_quantity,omitempty" db:"buy_quantity"`
+	GetQuantity int `json:"get_quantity,omitempty" db:"get_quantity"`
+	// Units in a bundle and their price together for bundle_price promotions
+	BundleQuantity int         `json:"bundle_quantity,omitempty" db:"bundle_quantity"`
+	BundlePrice    money.Money `json:"bundle_price" db:"bundle_price"`
+
+	// Least the qualifying items must come to, after earlier discounts, for the promotion to apply
+	MinSpend money.Money `json:"min_spend" db:"min_spend"`
+
+	// Sale dates the promotion runs between; either end may be open
+	StartsAt *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+
+	// Platforms the promotion is limited to; empty means every platform
+	Platforms []PlatformType `json:"platforms,omitempty" db:"platforms"`
+
+	// Most sales the promotion can be applied to, in total and per customer; 0 is unlimited.
+	// Cancelled and deleted sales do not count.
+	UsageLimit            int `json:"usage_limit" db:"usage_limit"`
+	UsageLimitPerCustomer int `json:"usage_limit_per_customer" db:"usage_limit_per_customer"`
+	UsageCount            int `json:"usage_count" db:"-"`
+
+	// Promotions are evaluated highest priority first. No later promotion is applied to a
+	// sale once an exclusive one has been.
+	Priority  int  `json:"priority" db:"priority"`
+	Exclusive bool `json:"exclusive" db:"exclusive"`
+	Active    bool `json:"active" db:"active"`
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// GenerateID sets a UUID if ID is empty
+func (p *Promotion) GenerateID() {
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+}
+
+// Validate checks the rule for its type and scope and binds its amounts to its currency
+func (p *Promotion) Validate() error {
+	p.Code = strings.ToUpper(strings.TrimSpace(p.Code))
+	if p.Code == "" {
+		return errors.New("code is required")
+	}
+	if p.Name == "" {
+		p.Name = p.Code
+	}
+	if !p.Type.IsValid() {
+		return fmt.Errorf("type must be %q, %q, %q or %q",
+			PromotionPercentage, PromotionFixedAmount, PromotionBuyXGetY, PromotionBundlePrice)
+	}
+	if p.Scope == "" {
+		p.Scope = PromotionScopeOrder
+	}
+	if !p.Scope.IsValid() {
+		return fmt.Errorf("scope must be %q, %q or %q", PromotionScopeOrder, PromotionScopeProduct, PromotionScopeCategory)
+	}
+
+	p.BindCurrency()
+	zero := money.Zero(p.Currency)
+
+	switch p.Type {
+	case PromotionPercentage:
+		if !p.Percent.IsPositive() || p.Percent.Rat().Cmp(big.NewRat(100, 1)) > 0 {
+			return errors.New("percent must be greater than 0 and at most 100")
+		}
+	case PromotionFixedAmount:
+		if !p.Amount.IsPositive() {
+			return errors.New("amount must be greater than 0")
+		}
+	case PromotionBuyXGetY:
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 {
+			return errors.New("buy_quantity and get_quantity must be at least 1")
+		}
+	case PromotionBundlePrice:
+		if p.BundleQuantity < 2 {
+			return errors.New("bundle_quantity must be at least 2")
+		}
+		if !p.BundlePrice.IsPositive() {
+			return errors.New("bundle_price must be greater than 0")
+		}
+	}
+
+	switch p.Scope {
+	case PromotionScopeOrder:
+		p.ProductIDs, p.CategoryIDs = nil, nil
+	case PromotionScopeProduct:
+		if len(p.ProductIDs) == 0 {
+			return errors.New("product_ids is required for product promotions")
+		}
+		p.CategoryIDs = nil
+	case PromotionScopeCategory:
+		if len(p.CategoryIDs) == 0 {
+			return errors.New("category_ids is required for category promotions")
+		}
+		p.ProductIDs = nil
+	}
+
+	if p.MinSpend.Cmp(zero) < 0 {
+		return errors.New("min_spend cannot be negative")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && p.EndsAt.Before(*p.StartsAt) {
+		return errors.New("ends_at must not be before starts_at")
+	}
+	for _, platform := range p.Platforms {
+		if !platform.IsValid() {
+			return fmt.Errorf("unknown platform %q", platform)
+		}
+	}
+	if p.UsageLimit < 0 || p.UsageLimitPerCustomer < 0 {
+		return errors.New("usage limits cannot be negative")
+	}
+	return nil
+}
+
+// BindCurrency normalizes the currency and attaches it to every amount
+func (p *Promotion) BindCurrency() {
+	p.Currency = money.NormalizeCurrency(p.Currency)
+	p.Amount = p.Amount.In(p.Currency)
+	p.BundlePrice = p.BundlePrice.In(p.Currency)
+	p.MinSpend = p.MinSpend.In(p.Currency)
+}
+
+// RunsOn reports whether a sale dated at the given time on the platform is within the
+// promotion's date window and platforms
+func (p *Promotion) RunsOn(at time.Time, platform PlatformType) bool {
+	if p.StartsAt != nil && at.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && at.After(*p.EndsAt) {
+		return false
+	}
+	if len(p.Platforms) == 0 {
+		return true
+	}
+	for _, allowed := range p.Platforms {
+		if allowed == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// PromotionUsage is how many sales a promotion has been applied to
+type PromotionUsage struct {
+	Total    int
+	Customer int
+}
+
+// withinLimits reports whether the promotion can be applied to one more sale
+func (p *Promotion) withinLimits(usage PromotionUsage) bool {
+	if p.UsageLimit > 0 && usage.Total >= p.UsageLimit {
+		return false
+	}
+	return p.UsageLimitPerCustomer == 0 || usage.Customer < p.UsageLimitPerCustomer
+}
+
+// ProductScope is what a promotion's scope is matched against for a product: its parent
+// product and its category with every category above it
+type ProductScope struct {
+	ParentID    *string
+	CategoryIDs []string
+}
+
+// covers reports whether an item of the product is within the promotion's scope
+func (p *Promotion) covers(productID string, scope ProductScope) bool {
+	switch p.Scope {
+	case PromotionScopeProduct:
+		for _, id := range p.ProductIDs {
+			if id == productID || (scope.ParentID != nil && id == *scope.ParentID) {
+				return true
+			}
+		}
+		return false
+	case PromotionScopeCategory:
+		for _, id := range p.CategoryIDs {
+			for _, category := range scope.CategoryIDs {
+				if id == category {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// AppliedPromotion records a promotion applied to a sale and the discount it gave
+type AppliedPromotion struct {
+	ID           string        `json:"id" db:"id"`
+	SaleID       string        `json:"sale_id" db:"sale_id"`
+	PromotionID  string        `json:"promotion_id" db:"promotion_id"`
+	Code         string        `json:"code" db:"code"`
+	Name         string        `json:"name" db:"name"`
+	Type         PromotionType `json:"type" db:"type"`
+	Discount     money.Money   `json:"discount" db:"discount"`
+	BaseDiscount money.Money   `json:"base_discount" db:"base_discount"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ApplyPromotions discounts the sale's items with the promotions that apply to it and records
+// them on the sale. Discounts given by promotions before are replaced; the rest of each item's
+// discount was entered by hand and is kept. Promotions run highest priority first, each on
+// the items' amounts after the discounts before it, and never take an item below zero.
+// Promotions in another currency apply only when they are in the base currency.
+func ApplyPromotions(sale *Sale, promotions []Promotion, scopes map[string]ProductScope, usage map[string]PromotionUsage) {
+	sale.BindCurrency()
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		item.PromotionDiscount = item.PromotionDiscount.In(sale.Currency)
+		manual := item.Discount.Sub(item.PromotionDiscount)
+		if manual.IsNegative() {
+			manual = money.Zero(sale.Currency)
+		}
+		item.Discount = manual
+		item.PromotionDiscount = money.Zero(sale.Currency)
+	}
+	sale.Promotions = nil
+
+	ordered := make([]Promotion, len(promotions))
+	copy(ordered, promotions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority > ordered[j].Priority
+		}
+		return ordered[i].Code < ordered[j].Code
+	})
+
+	date := sale.SaleDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+	for i := range ordered {
+		promotion := &ordered[i]
+		if !promotion.Active || !promotion.RunsOn(date, sale.Platform) || !promotion.withinLimits(usage[promotion.ID]) {
+			continue
+		}
+		discounts, ok := promotion.discounts(sale, scopes)
+		if !ok {
+			continue
+		}
+
+		total := money.Zero(sale.Currency)
+		for n, discount := range discounts {
+			if !discount.IsPositive() {
+				continue
+			}
+			item := &sale.Items[n]
+			item.Discount = item.Discount.Add(discount)
+			item.PromotionDiscount = item.PromotionDiscount.Add(discount)
+			total = total.Add(discount)
+		}
+		if !total.IsPositive() {
+			continue
+		}
+
+		sale.Promotions = append(sale.Promotions, AppliedPromotion{
+			PromotionID:  promotion.ID,
+			Code:         promotion.Code,
+			Name:         promotion.Name,
+			Type:         promotion.Type,
+			Discount:     total,
+			BaseDiscount: sale.ExchangeRate.Convert(total, money.BaseCurrency).Round(),
+		})
+		if promotion.Exclusive {
+			break
+		}
+	}
+}
+
+// promotionLine is a qualifying sale item and what is left to discount on it
+type promotionLine struct {
+	index     int
+	unitPrice money.Money
+	quantity  int
+	net       money.Money
+}
+
+// discounts works out the promotion's discount on each sale item, indexed like the items.
+// It returns false when the promotion does not apply.
+func (p *Promotion) discounts(sale *Sale, scopes map[string]ProductScope) ([]money.Money, bool) {
+	p.BindCurrency()
+	minSpend, ok := priceIn(p.MinSpend, sale.Currency, sale.ExchangeRate)
+	if !ok {
+		return nil, false
+	}
+
+	var lines []promotionLine
+	spend := money.Zero(sale.Currency)
+	for i, item := range sale.Items {
+		if item.Quantity <= 0 || !p.covers(item.ProductID, scopes[item.ProductID]) {
+			continue
+		}
+		net := item.UnitPrice.Mul(int64(item.Quantity)).Sub(item.Discount)
+		if !net.IsPositive() {
+			continue
+		}
+		lines = append(lines, promotionLine{index: i, unitPrice: item.UnitPrice, quantity: item.Quantity, net: net})
+		spend = spend.Add(net)
+	}
+	if len(lines) == 0 || spend.Cmp(minSpend) < 0 {
+		return nil, false
+	}
+
+	discounts := make([]money.Money, len(sale.Items))
+	for i := range discounts {
+		discounts[i] = money.Zero(sale.Currency)
+	}
+
+	switch p.Type {
+	case PromotionPercentage:
+		factor := new(big.Rat).Quo(p.Percent.Rat(), big.NewRat(100, 1))
+		for _, line := range lines {
+			discounts[line.index] = line.net.MulRat(factor).Round()
+		}
+
+	case PromotionFixedAmount:
+		amount, ok := priceIn(p.Amount, sale.Currency, sale.ExchangeRate)
+		if !ok {
+			return nil, false
+		}
+		if amount.Cmp(spend) > 0 {
+			amount = spend
+		}
+		weights := make([]money.Money, len(lines))
+		for i, line := range lines {
+			weights[i] = line.net
+		}
+		for i, share := range allocateAmount(amount, weights) {
+			discounts[lines[i].index] = share
+		}
+
+	case PromotionBuyXGetY:
+		units := 0
+		for _, line := range lines {
+			units += line.quantity
+		}
+		free := units / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		// The cheapest units are the free ones
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].unitPrice.Cmp(lines[j].unitPrice) < 0 })
+		for _, line := range lines {
+			if free == 0 {
+				break
+			}
+			take := min(free, line.quantity)
+			discounts[line.index] = line.unitPrice.Mul(int64(take))
+			free -= take
+		}
+
+	case PromotionBundlePrice:
+		bundlePrice, ok := priceIn(p.BundlePrice, sale.Currency, sale.ExchangeRate)
+		if !ok {
+			return nil, false
+		}
+		units := 0
+		for _, line := range lines {
+			units += line.quantity
+		}
+		bundles := units / p.BundleQuantity
+		remaining := bundles * p.BundleQuantity
+		// The dearest units go into bundles, which gives the customer the larger saving
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].unitPrice.Cmp(lines[j].unitPrice) > 0 })
+		var bundled []promotionLine
+		value := money.Zero(sale.Currency)
+		for _, line := range lines {
+			if remaining == 0 {
+				break
+			}
+			take := min(remaining, line.quantity)
+			line.net = line.unitPrice.Mul(int64(take))
+			bundled = append(bundled, line)
+			value = value.Add(line.net)
+			remaining -= take
+		}
+		saving := value.Sub(bundlePrice.Mul(int64(bundles)))
+		if !saving.IsPositive() {
+			return nil, false
+		}
+		weights := make([]money.Money, len(bundled))
+		for i, line := range bundled {
+			weights[i] = line.net
+		}
+		for i, share := range allocateAmount(saving, weights) {
+			discounts[bundled[i].index] = share
+		}
+	}
+
+	// Never discount an item below zero
+	for _, line := range lines {
+		net := sale.Items[line.index].UnitPrice.Mul(int64(line.quantity)).Sub(sale.Items[line.index].Discount)
+		if discounts[line.index].Cmp(net) > 0 {
+			discounts[line.index] = net
+		}
+	}
+	return discounts, true
+}
+
+// allocateAmount shares an amount between lines in proportion to their weights. Each share is
+// rounded and the last line takes what is left, so the shares add up to the amount.
+func allocateAmount(amount money.Money, weights []money.Money) []money.Money {
+	shares := make([]money.Money, len(weights))
+	total := money.Zero(amount.Currency())
+	for _, weight := range weights {
+		total = total.Add(weight)
+	}
+	allocated := money.Zero(amount.Currency())
+	for i, weight := range weights {
+		if i == len(weights)-1 {
+			shares[i] = amount.Sub(allocated)
+			break
+		}
+		shares[i] = money.Zero(amount.Currency())
+		if total.IsPositive() {
+			shares[i] = amount.MulRat(weight.Ratio(total)).Round()
+		}
+		allocated = allocated.Add(shares[i])
+	}
+	return shares
+}
+
+// PromotionReportLine is how much a promotion gave away over a period
+type PromotionReportLine struct {
+	PromotionID string        `json:"promotion_id"`
+	Code        string        `json:"code"`
+	Name        string        `json:"name"`
+	Type        PromotionType `json:"type"`
+	SaleCount   int           `json:"sale_count"`
+	// Discount and the totals of the sales it was applied to, in the base currency
+	Discount   money.Money `json:"discount"`
+	SalesTotal money.Money `json:"sales_total"`
+}
+
+// PromotionReport is the use of promotions on completed sales over a period
+type PromotionReport struct {
+	StartDate     time.Time             `json:"start_date"`
+	EndDate       time.Time             `json:"end_date"`
+	Lines         []PromotionReportLine `json:"lines"`
+	TotalDiscount money.Money           `json:"total_discount"`
+}
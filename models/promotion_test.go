@@ -0,0 +1,165 @@
+package models
+
+import (
+	"inventory-go/money"
+	"testing"
+)
+
+// promotionSale returns a sale of two a at 10000, three of variant b-red at 5000 and one c at
+// 30000 with 3000 off by hand and 1000 off from an earlier promotion
+func promotionSale() *Sale {
+	return &Sale{
+		Currency: "IDR",
+		SaleDate: date("2024-03-05"),
+		Items: []SaleItem{
+			{ProductID: "a", Quantity: 2, UnitPrice: idr("10000")},
+			{ProductID: "b-red", Quantity: 3, UnitPrice: idr("5000")},
+			{ProductID: "c", Quantity: 1, UnitPrice: idr("30000"), Discount: idr("4000"), PromotionDiscount: idr("1000")},
+		},
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	parentB := "b"
+	scopes := map[string]ProductScope{
+		"a":     {CategoryIDs: []string{"drinks"}},
+		"b-red": {ParentID: &parentB, CategoryIDs: []string{"snacks", "food"}},
+		"c":     {CategoryIDs: []string{"food"}},
+	}
+	percent := func(code, pct string, priority int) Promotion {
+		return Promotion{ID: code, Code: code, Type: PromotionPercentage, Scope: PromotionScopeOrder,
+			Percent: rate(pct), Priority: priority, Active: true}
+	}
+	onProducts := func(p Promotion, ids ...string) Promotion {
+		p.Scope, p.ProductIDs = PromotionScopeProduct, ids
+		return p
+	}
+	onCategories := func(p Promotion, ids ...string) Promotion {
+		p.Scope, p.CategoryIDs = PromotionScopeCategory, ids
+		return p
+	}
+	fixed := func(code, amount string, priority int) Promotion {
+		return Promotion{ID: code, Code: code, Type: PromotionFixedAmount, Scope: PromotionScopeOrder,
+			Amount: idr(amount), Priority: priority, Active: true}
+	}
+	exclusive := func(p Promotion) Promotion {
+		p.Exclusive = true
+		return p
+	}
+	limited := func(p Promotion, total, perCustomer int) Promotion {
+		p.UsageLimit, p.UsageLimitPerCustomer = total, perCustomer
+		return p
+	}
+	minSpend := func(p Promotion, amount string) Promotion {
+		p.MinSpend = idr(amount)
+		return p
+	}
+	inactive := percent("OFF", "10", 0)
+	inactive.Active = false
+	foreign := percent("USD10", "10", 0)
+	foreign.Currency = "USD"
+
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		usage      map[string]PromotionUsage
+		discounts  [3]string
+		applied    []string
+	}{
+		{"order percentage", []Promotion{percent("P10", "10", 0)}, nil,
+			[3]string{"2000", "1500", "2700"}, []string{"P10"}},
+		{"product scope covers variants", []Promotion{onProducts(percent("B20", "20", 0), "b")}, nil,
+			[3]string{"0", "3000", "0"}, []string{"B20"}},
+		{"category scope", []Promotion{onCategories(percent("FOOD", "10", 0), "food")}, nil,
+			[3]string{"0", "1500", "2700"}, []string{"FOOD"}},
+		{"nothing in scope", []Promotion{onProducts(percent("Z", "10", 0), "z")}, nil,
+			[3]string{"0", "0", "0"}, nil},
+		{"fixed amount shared by value", []Promotion{fixed("F", "6200", 0)}, nil,
+			[3]string{"2000", "1500", "2700"}, []string{"F"}},
+		{"fixed amount capped at the spend", []Promotion{onProducts(fixed("F", "50000", 0), "a")}, nil,
+			[3]string{"20000", "0", "0"}, []string{"F"}},
+		{"stacked by priority on discounted amounts", []Promotion{onProducts(fixed("F", "5000", 1), "a"), percent("P10", "10", 2)}, nil,
+			[3]string{"7000", "1500", "2700"}, []string{"P10", "F"}},
+		{"exclusive stops later promotions", []Promotion{percent("P10", "10", 1), exclusive(onProducts(percent("X", "10", 5), "a"))}, nil,
+			[3]string{"2000", "0", "0"}, []string{"X"}},
+		{"exclusive that does not apply", []Promotion{percent("P10", "10", 1), exclusive(minSpend(onProducts(percent("X", "10", 5), "a"), "20001"))}, nil,
+			[3]string{"2000", "1500", "2700"}, []string{"P10"}},
+		{"min spend reached", []Promotion{minSpend(onProducts(percent("A", "10", 0), "a"), "20000")}, nil,
+			[3]string{"2000", "0", "0"}, []string{"A"}},
+		{"buy 2 get 1 gives the cheapest free", []Promotion{{ID: "B2G1", Code: "B2G1", Type: PromotionBuyXGetY, Scope: PromotionScopeOrder,
+			BuyQuantity: 2, GetQuantity: 1, Active: true}}, nil,
+			[3]string{"0", "10000", "0"}, []string{"B2G1"}},
+		{"bundle takes the dearest units", []Promotion{{ID: "PAIR", Code: "PAIR", Type: PromotionBundlePrice, Scope: PromotionScopeProduct,
+			ProductIDs: []string{"a", "b"}, BundleQuantity: 2, BundlePrice: idr("12000"), Active: true}}, nil,
+			[3]string{"4000", "2000", "0"}, []string{"PAIR"}},
+		{"below the usage limit", []Promotion{limited(percent("P10", "10", 0), 5, 1)}, map[string]PromotionUsage{"P10": {Total: 4}},
+			[3]string{"2000", "1500", "2700"}, []string{"P10"}},
+		{"usage limit reached", []Promotion{limited(percent("P10", "10", 0), 5, 0)}, map[string]PromotionUsage{"P10": {Total: 5}},
+			[3]string{"0", "0", "0"}, nil},
+		{"customer limit reached", []Promotion{limited(percent("P10", "10", 0), 0, 1)}, map[string]PromotionUsage{"P10": {Total: 1, Customer: 1}},
+			[3]string{"0", "0", "0"}, nil},
+		{"inactive", []Promotion{inactive}, nil, [3]string{"0", "0", "0"}, nil},
+		{"other currency", []Promotion{foreign}, nil, [3]string{"0", "0", "0"}, nil},
+	}
+	for _, tt := range tests {
+		sale := promotionSale()
+		ApplyPromotions(sale, tt.promotions, scopes, tt.usage)
+
+		for i, want := range tt.discounts {
+			if got := sale.Items[i].PromotionDiscount.String(); got != want {
+				t.Errorf("%s: item %d promotion discount = %s, want %s", tt.name, i, got, want)
+			}
+		}
+		// The hand-entered discount on c is kept and the earlier promotion's is replaced
+		if got, want := sale.Items[2].Discount, idr("3000").Add(sale.Items[2].PromotionDiscount); got.Cmp(want) != 0 {
+			t.Errorf("%s: c discount = %s, want %s", tt.name, got.String(), want.String())
+		}
+
+		if len(sale.Promotions) != len(tt.applied) {
+			t.Errorf("%s: %d promotions applied, want %v", tt.name, len(sale.Promotions), tt.applied)
+			continue
+		}
+		total := money.Zero("IDR")
+		for _, item := range sale.Items {
+			total = total.Add(item.PromotionDiscount)
+		}
+		recorded := money.Zero("IDR")
+		for i, applied := range sale.Promotions {
+			if applied.Code != tt.applied[i] {
+				t.Errorf("%s: promotion %d = %s, want %s", tt.name, i, applied.Code, tt.applied[i])
+			}
+			if applied.BaseDiscount.Cmp(applied.Discount) != 0 {
+				t.Errorf("%s: %s base discount %s, want %s", tt.name, applied.Code, applied.BaseDiscount.String(), applied.Discount.String())
+			}
+			recorded = recorded.Add(applied.Discount)
+		}
+		if recorded.Cmp(total) != 0 {
+			t.Errorf("%s: promotions record %s, items discounted %s", tt.name, recorded.String(), total.String())
+		}
+	}
+}
+
+func TestApplyPromotionsConvertsBaseCurrency(t *testing.T) {
+	sale := &Sale{
+		Currency:     "USD",
+		ExchangeRate: rate("16000"),
+		SaleDate:     date("2024-03-05"),
+		Items:        []SaleItem{{ProductID: "a", Quantity: 1, UnitPrice: money.MustParse("10", "USD")}},
+	}
+	promotions := []Promotion{{ID: "F", Code: "F", Type: PromotionFixedAmount, Scope: PromotionScopeOrder,
+		Currency: "IDR", Amount: idr("16000"), MinSpend: idr("160000"), Active: true}}
+
+	ApplyPromotions(sale, promotions, nil, nil)
+	if got := sale.Items[0].PromotionDiscount.String(); got != "1" {
+		t.Errorf("promotion discount = %s, want 1", got)
+	}
+	if len(sale.Promotions) != 1 || sale.Promotions[0].BaseDiscount.String() != "16000" {
+		t.Errorf("applied = %+v, want 1 USD off worth 16000", sale.Promotions)
+	}
+
+	sale.Items[0].UnitPrice = money.MustParse("9.99", "USD")
+	ApplyPromotions(sale, promotions, nil, nil)
+	if !sale.Items[0].PromotionDiscount.IsZero() || len(sale.Promotions) != 0 {
+		t.Errorf("below the converted min spend, discount = %s", sale.Items[0].PromotionDiscount.String())
+	}
+}
@@ -0,0 +1,417 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentTerms is how long a customer has to pay a sale
+type PaymentTerms string
+
+const (
+	PaymentTermsCash  PaymentTerms = "cash"
+	PaymentTermsNet7  PaymentTerms = "net_7"
+	PaymentTermsNet14 PaymentTerms = "net_14"
+	PaymentTermsNet30 PaymentTerms = "net_30"
+)
+
+var paymentTermDays = map[PaymentTerms]int{
+	PaymentTermsCash:  0,
+	PaymentTermsNet7:  7,
+	PaymentTermsNet14: 14,
+	PaymentTermsNet30: 30,
+}
+
+// IsValid reports whether the terms are supported
+func (t PaymentTerms) IsValid() bool {
+	_, ok := paymentTermDays[t]
+	return ok
+}
+
+// Days returns the number of days allowed for payment
+func (t PaymentTerms) Days() int {
+	return paymentTermDays[t]
+}
+
+// DueDate returns the date payment is due for a document dated on the given day
+func (t PaymentTerms) DueDate(from time.Time) time.Time {
+	return truncateToDate(from).AddDate(0, 0, t.Days())
+}
+
+// Errors returned when a payment cannot be allocated
+var (
+	ErrPaymentExceedsBalance = errors.New("payment exceeds the open balance")
+	ErrInvalidAllocation     = errors.New("invalid payment allocation")
+)
+
+// OpenInvoice is a completed sale that has not been fully paid
+type OpenInvoice struct {
+	SaleID       string      `json:"sale_id"`
+	ReferenceNo  string      `json:"reference_no"`
+	CustomerID   string      `json:"customer_id,omitempty"`
+	CustomerName string      `json:"customer_name,omitempty"`
+	SaleDate     time.Time   `json:"sale_date"`
+	DueDate      time.Time   `json:"due_date"`
+	Currency     string      `json:"currency"`
+	ExchangeRate money.Rate  `json:"exchange_rate"`
+	Total        money.Money `json:"total"`
+	Paid         money.Money `json:"paid"`
+	Balance      money.Money `json:"balance"`
+
+	// Balance converted to the base currency at the sale's exchange rate
+	BaseBalance money.Money `json:"base_balance"`
+	DaysOverdue int         `json:"days_overdue"`
+}
+
+// Age fills the base balance and the number of days the invoice is past due on the given date
+func (i *OpenInvoice) Age(asOf time.Time) {
+	i.Currency = money.NormalizeCurrency(i.Currency)
+	i.Total = i.Total.In(i.Currency)
+	i.Paid = i.Paid.In(i.Currency)
+	i.Balance = i.Balance.In(i.Currency)
+	i.BaseBalance = documentRate(i.Currency, i.ExchangeRate).Convert(i.Balance, money.BaseCurrency).Round()
+	i.DaysOverdue = daysPastDue(i.DueDate, asOf)
+}
+
+// daysPastDue returns the whole days between the due date and the given date, or 0 when not yet due
+func daysPastDue(due, asOf time.Time) int {
+	days := int(truncateToDate(asOf).Sub(truncateToDate(due)).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// AgingBuckets splits outstanding balances by how many days they are past due
+type AgingBuckets struct {
+	Current    money.Money `json:"current"`
+	Days1To30  money.Money `json:"days_1_30"`
+	Days31To60 money.Money `json:"days_31_60"`
+	Days61To90 money.Money `json:"days_61_90"`
+	Over90     money.Money `json:"over_90"`
+	Total      money.Money `json:"total"`
+}
+
+// NewAgingBuckets returns empty buckets in the base currency
+func NewAgingBuckets() AgingBuckets {
+	zero := money.Zero(money.BaseCurrency)
+	return AgingBuckets{Current: zero, Days1To30: zero, Days31To60: zero, Days61To90: zero, Over90: zero, Total: zero}
+}
+
+// Add puts an amount in the bucket for the given number of days past due
+func (b *AgingBuckets) Add(daysOverdue int, amount money.Money) {
+	switch {
+	case daysOverdue <= 0:
+		b.Current = b.Current.Add(amount)
+	case daysOverdue <= 30:
+		b.Days1To30 = b.Days1To30.Add(amount)
+	case daysOverdue <= 60:
+		b.Days31To60 = b.Days31To60.Add(amount)
+	case daysOverdue <= 90:
+		b.Days61To90 = b.Days61To90.Add(amount)
+	default:
+		b.Over90 = b.Over90.Add(amount)
+	}
+	b.Total = b.Total.Add(amount)
+}
+
+// ARAgingLine is one customer's outstanding balance by age
+type ARAgingLine struct {
+	CustomerID   string `json:"customer_id,omitempty"`
+	CustomerName string `json:"customer_name"`
+	InvoiceCount int    `json:"invoice_count"`
+	AgingBuckets
+}
+
+// ARAgingReport buckets outstanding receivables per customer, in the base currency
+type ARAgingReport struct {
+	AsOf      time.Time     `json:"as_of"`
+	Currency  string        `json:"currency"`
+	Customers []ARAgingLine `json:"customers"`
+	Totals    AgingBuckets  `json:"totals"`
+}
+
+// NewARAgingReport groups aged open invoices by customer. Sales without a customer are
+// reported together as walk-in sales.
+func NewARAgingReport(asOf time.Time, invoices []OpenInvoice) *ARAgingReport {
+	report := &ARAgingReport{
+		AsOf:      asOf,
+		Currency:  money.BaseCurrency,
+		Customers: []ARAgingLine{},
+		Totals:    NewAgingBuckets(),
+	}
+
+	index := map[string]int{}
+	for _, inv := range invoices {
+		i, ok := index[inv.CustomerID]
+		if !ok {
+			name := inv.CustomerName
+			if inv.CustomerID == "" {
+				name = "Walk-in customers"
+			}
+			report.Customers = append(report.Customers, ARAgingLine{
+				CustomerID:   inv.CustomerID,
+				CustomerName: name,
+				AgingBuckets: NewAgingBuckets(),
+			})
+			i = len(report.Customers) - 1
+			index[inv.CustomerID] = i
+		}
+		line := &report.Customers[i]
+		line.InvoiceCount++
+		line.Add(inv.DaysOverdue, inv.BaseBalance)
+		report.Totals.Add(inv.DaysOverdue, inv.BaseBalance)
+	}
+
+	sort.SliceStable(report.Customers, func(a, b int) bool {
+		return report.Customers[a].CustomerName < report.Customers[b].CustomerName
+	})
+	return report
+}
+
+// CustomerPayment is money received from a customer and allocated to their open sales
+type CustomerPayment struct {
+	ID            string      `json:"id" db:"id"`
+	PaymentNo     string      `json:"payment_no" db:"payment_no"`
+	CustomerID    string      `json:"customer_id" db:"customer_id"`
+	PaymentDate   time.Time   `json:"payment_date" db:"payment_date"`
+	Currency      string      `json:"currency" db:"currency"`
+	Amount        money.Money `json:"amount" db:"amount"`
+	PaymentMethod string      `json:"payment_method,omitempty" db:"payment_method"`
+	Reference     string      `json:"reference,omitempty" db:"reference"`
+	Note          string      `json:"note,omitempty" db:"note"`
+
+	// Amount in the base currency, converted at the rate of each sale it settles
+	BaseAmount money.Money `json:"base_amount" db:"base_amount"`
+
+	Allocations []PaymentAllocation `json:"allocations" db:"-"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// PaymentAllocation is the part of a payment applied to one sale
+type PaymentAllocation struct {
+	ID            string      `json:"id" db:"id"`
+	PaymentID     string      `json:"payment_id" db:"payment_id"`
+	SaleID        string      `json:"sale_id" db:"sale_id"`
+	SaleReference string      `json:"sale_reference,omitempty" db:"-"`
+	Amount        money.Money `json:"amount" db:"amount"`
+	BaseAmount    money.Money `json:"base_amount" db:"base_amount"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+}
+
+// GenerateID sets a UUID if ID is empty and ensures the payment number and date exist
+func (p *CustomerPayment) GenerateID() {
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+	if p.PaymentNo == "" {
+		p.PaymentNo = "PAY-" + time.Now().Format("20060102") + "-" + p.ID[:6]
+	}
+	if p.PaymentDate.IsZero() {
+		p.PaymentDate = time.Now()
+	}
+}
+
+// Validate checks the payment before it is allocated
+func (p *CustomerPayment) Validate() error {
+	p.Currency = money.NormalizeCurrency(p.Currency)
+	p.Amount = p.Amount.In(p.Currency)
+	if p.CustomerID == "" {
+		return errors.New("customer_id is required")
+	}
+	if !p.Amount.IsPositive() {
+		return errors.New("amount must be positive")
+	}
+	for _, a := range p.Allocations {
+		if a.SaleID == "" {
+			return errors.New("every allocation needs a sale_id")
+		}
+		if !a.Amount.In(p.Currency).IsPositive() {
+			return errors.New("allocation amounts must be positive")
+		}
+	}
+	return nil
+}
+
+// Allocate applies the payment to the customer's open invoices. Allocations given on the
+// payment are applied first; the rest of the amount settles the remaining invoices in the
+// order given, which should be oldest due first. Only invoices in the payment's currency can
+// be settled, and the payment may not exceed what they owe.
+func (p *CustomerPayment) Allocate(invoices []OpenInvoice) error {
+	open := map[string]*OpenInvoice{}
+	remaining := map[string]money.Money{}
+	for i := range invoices {
+		inv := &invoices[i]
+		if money.NormalizeCurrency(inv.Currency) != p.Currency {
+			continue
+		}
+		open[inv.SaleID] = inv
+		remaining[inv.SaleID] = inv.Balance.In(p.Currency)
+	}
+
+	amounts := map[string]money.Money{}
+	var order []string
+	apply := func(saleID string, amount money.Money) {
+		if _, ok := amounts[saleID]; !ok {
+			order = append(order, saleID)
+			amounts[saleID] = money.Zero(p.Currency)
+		}
+		amounts[saleID] = amounts[saleID].Add(amount)
+		remaining[saleID] = remaining[saleID].Sub(amount)
+	}
+
+	unallocated := p.Amount
+	for _, a := range p.Allocations {
+		amount := a.Amount.In(p.Currency)
+		if _, ok := open[a.SaleID]; !ok {
+			return fmt.Errorf("%w: sale %s is not an open %s invoice of this customer", ErrInvalidAllocation, a.SaleID, p.Currency)
+		}
+		if amount.Cmp(remaining[a.SaleID]) > 0 {
+			return fmt.Errorf("%w: allocation of %s to sale %s is more than its balance of %s",
+				ErrPaymentExceedsBalance, amount.String(), open[a.SaleID].ReferenceNo, remaining[a.SaleID].String())
+		}
+		if amount.Cmp(unallocated) > 0 {
+			return fmt.Errorf("%w: allocations add up to more than the payment amount", ErrInvalidAllocation)
+		}
+		apply(a.SaleID, amount)
+		unallocated = unallocated.Sub(amount)
+	}
+
+	for i := range invoices {
+		if unallocated.IsZero() {
+			break
+		}
+		saleID := invoices[i].SaleID
+		if _, ok := open[saleID]; !ok || !remaining[saleID].IsPositive() {
+			continue
+		}
+		amount := remaining[saleID]
+		if amount.Cmp(unallocated) > 0 {
+			amount = unallocated
+		}
+		apply(saleID, amount)
+		unallocated = unallocated.Sub(amount)
+	}
+
+	if unallocated.IsPositive() {
+		return fmt.Errorf("%w: %s is left after settling every open %s invoice",
+			ErrPaymentExceedsBalance, unallocated.String(), p.Currency)
+	}
+
+	p.Allocations = make([]PaymentAllocation, 0, len(order))
+	p.BaseAmount = money.Zero(money.BaseCurrency)
+	for _, saleID := range order {
+		inv := open[saleID]
+		base := documentRate(p.Currency, inv.ExchangeRate).Convert(amounts[saleID], money.BaseCurrency).Round()
+		p.Allocations = append(p.Allocations, PaymentAllocation{
+			ID:            uuid.NewString(),
+			PaymentID:     p.ID,
+			SaleID:        saleID,
+			SaleReference: inv.ReferenceNo,
+			Amount:        amounts[saleID],
+			BaseAmount:    base,
+		})
+		p.BaseAmount = p.BaseAmount.Add(base)
+	}
+	return nil
+}
+
+// Statement line types
+const (
+	StatementLineInvoice = "invoice"
+	StatementLineBill    = "bill"
+	StatementLinePayment = "payment"
+	StatementLineCredit  = "credit"
+)
+
+// StatementLine is one document on a statement, in the base currency
+type StatementLine struct {
+	Date      time.Time   `json:"date"`
+	Type      string      `json:"type"`
+	SourceID  string      `json:"source_id"`
+	Reference string      `json:"reference"`
+	DueDate   *time.Time  `json:"due_date,omitempty"`
+	Debit     money.Money `json:"debit"`
+	Credit    money.Money `json:"credit"`
+	Balance   money.Money `json:"balance"`
+}
+
+// CustomerStatement lists a customer's invoices and payments over a period with a running
+// balance, in the base currency
+type CustomerStatement struct {
+	CustomerID     string          `json:"customer_id"`
+	CustomerName   string          `json:"customer_name"`
+	StartDate      time.Time       `json:"start_date"`
+	EndDate        time.Time       `json:"end_date"`
+	Currency       string          `json:"currency"`
+	OpeningBalance money.Money     `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	TotalDebit     money.Money     `json:"total_debit"`
+	TotalCredit    money.Money     `json:"total_credit"`
+	ClosingBalance money.Money     `json:"closing_balance"`
+}
+
+// NewCustomerStatement builds a statement from every invoice and payment up to the end date.
+// Documents before the start date make up the opening balance.
+func NewCustomerStatement(customer *Customer, start, end time.Time, lines []StatementLine) *CustomerStatement {
+	s := &CustomerStatement{
+		CustomerID:   customer.ID,
+		CustomerName: customer.Name,
+		StartDate:    start,
+		EndDate:      end,
+		Currency:     money.BaseCurrency,
+	}
+	s.OpeningBalance, s.Lines, s.TotalDebit, s.TotalCredit, s.ClosingBalance = buildStatement(start, lines, false)
+	return s
+}
+
+// buildStatement orders statement lines by date, invoices and bills before payments on the same
+// day, and runs the balance through them. The balance is debits less credits, or credits less
+// debits for accounts with a credit balance such as what is owed to suppliers.
+func buildStatement(start time.Time, lines []StatementLine, creditNormal bool) (opening money.Money, included []StatementLine, debit, credit, closing money.Money) {
+	raises := func(l StatementLine) bool {
+		return l.Type == StatementLineInvoice || l.Type == StatementLineBill
+	}
+	sort.SliceStable(lines, func(a, b int) bool {
+		if !lines[a].Date.Equal(lines[b].Date) {
+			return lines[a].Date.Before(lines[b].Date)
+		}
+		return raises(lines[a]) && !raises(lines[b])
+	})
+
+	movement := func(l StatementLine) money.Money {
+		if creditNormal {
+			return l.Credit.Sub(l.Debit)
+		}
+		return l.Debit.Sub(l.Credit)
+	}
+
+	opening = money.Zero(money.BaseCurrency)
+	debit = money.Zero(money.BaseCurrency)
+	credit = money.Zero(money.BaseCurrency)
+	included = []StatementLine{}
+	for _, l := range lines {
+		if l.Date.Before(start) {
+			opening = opening.Add(movement(l))
+		}
+	}
+
+	closing = opening
+	for _, l := range lines {
+		if l.Date.Before(start) {
+			continue
+		}
+		closing = closing.Add(movement(l))
+		debit = debit.Add(l.Debit)
+		credit = credit.Add(l.Credit)
+		l.Balance = closing
+		included = append(included, l)
+	}
+	return opening, included, debit, credit, closing
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"errors"
+	"inventory-go/money"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func rate(s string) money.Rate {
+	r, err := money.ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// openInvoices returns three IDR invoices oldest due first and one USD invoice
+func openInvoices() []OpenInvoice {
+	invoice := func(id, currency, balance string) OpenInvoice {
+		return OpenInvoice{SaleID: id, ReferenceNo: "INV-" + id, Currency: currency, Balance: money.MustParse(balance, currency)}
+	}
+	usd := invoice("d", "USD", "50")
+	usd.ExchangeRate = rate("16000")
+	return []OpenInvoice{
+		invoice("a", "IDR", "100000"),
+		invoice("b", "IDR", "250000"),
+		usd,
+		invoice("c", "IDR", "80000"),
+	}
+}
+
+func TestPaymentTermsDueDate(t *testing.T) {
+	tests := []struct {
+		terms PaymentTerms
+		want  string
+	}{
+		{PaymentTermsCash, "2024-03-05"},
+		{PaymentTermsNet7, "2024-03-12"},
+		{PaymentTermsNet14, "2024-03-19"},
+		{PaymentTermsNet30, "2024-04-04"},
+	}
+	from := time.Date(2024, 3, 5, 15, 30, 0, 0, time.UTC)
+	for _, tt := range tests {
+		if got := tt.terms.DueDate(from).Format("2006-01-02"); got != tt.want {
+			t.Errorf("%s due date = %s, want %s", tt.terms, got, tt.want)
+		}
+	}
+	if PaymentTerms("net_45").IsValid() {
+		t.Error("net_45 is valid, want invalid")
+	}
+}
+
+func TestAllocatePayment(t *testing.T) {
+	allocate := func(saleID, amount string) PaymentAllocation {
+		return PaymentAllocation{SaleID: saleID, Amount: money.MustParse(amount, "IDR")}
+	}
+	tests := []struct {
+		name        string
+		amount      string
+		allocations []PaymentAllocation
+		want        map[string]string
+		order       []string
+		err         error
+	}{
+		{"oldest first", "150000", nil, map[string]string{"a": "100000", "b": "50000"}, []string{"a", "b"}, nil},
+		{"settles everything", "430000", nil, map[string]string{"a": "100000", "b": "250000", "c": "80000"}, []string{"a", "b", "c"}, nil},
+		{"partial first invoice", "40000", nil, map[string]string{"a": "40000"}, []string{"a"}, nil},
+		{"allocation then oldest", "130000", []PaymentAllocation{allocate("c", "80000")},
+			map[string]string{"c": "80000", "a": "50000"}, []string{"c", "a"}, nil},
+		{"allocation topped up", "150000", []PaymentAllocation{allocate("a", "30000")},
+			map[string]string{"a": "100000", "b": "50000"}, []string{"a", "b"}, nil},
+		{"over-payment", "430001", nil, nil, nil, ErrPaymentExceedsBalance},
+		{"allocation over balance", "200000", []PaymentAllocation{allocate("a", "100001")}, nil, nil, ErrPaymentExceedsBalance},
+		{"allocations over amount", "50000", []PaymentAllocation{allocate("b", "60000")}, nil, nil, ErrInvalidAllocation},
+		{"other currency invoice", "50", []PaymentAllocation{allocate("d", "50")}, nil, nil, ErrInvalidAllocation},
+		{"unknown invoice", "1000", []PaymentAllocation{allocate("x", "1000")}, nil, nil, ErrInvalidAllocation},
+	}
+	for _, tt := range tests {
+		p := CustomerPayment{ID: "p", CustomerID: "cust", Amount: money.MustParse(tt.amount, "IDR"), Allocations: tt.allocations}
+		if err := p.Validate(); err != nil {
+			t.Fatalf("%s: Validate() = %v", tt.name, err)
+		}
+		err := p.Allocate(openInvoices())
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: Allocate() error = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Allocate() error = %v", tt.name, err)
+			continue
+		}
+		if len(p.Allocations) != len(tt.order) {
+			t.Errorf("%s: %d allocations, want %d", tt.name, len(p.Allocations), len(tt.order))
+			continue
+		}
+		for i, a := range p.Allocations {
+			if a.SaleID != tt.order[i] || a.Amount.String() != tt.want[a.SaleID] {
+				t.Errorf("%s: allocation %d = %s %s, want %s %s", tt.name, i, a.SaleID, a.Amount.String(), tt.order[i], tt.want[tt.order[i]])
+			}
+		}
+		if p.BaseAmount.Cmp(p.Amount) != 0 {
+			t.Errorf("%s: base amount = %s, want %s", tt.name, p.BaseAmount.String(), p.Amount.String())
+		}
+	}
+}
+
+func TestAllocateForeignPayment(t *testing.T) {
+	p := CustomerPayment{ID: "p", CustomerID: "cust", Currency: "usd", Amount: money.MustParse("20.5", "USD")}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Allocate(openInvoices()); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Allocations) != 1 || p.Allocations[0].SaleID != "d" {
+		t.Fatalf("allocations = %+v, want one to sale d", p.Allocations)
+	}
+	if got := p.BaseAmount.String(); got != "328000" || p.BaseAmount.Currency() != money.BaseCurrency {
+		t.Errorf("base amount = %s %s, want 328000 IDR", got, p.BaseAmount.Currency())
+	}
+}
+
+func TestAgingBuckets(t *testing.T) {
+	tests := []struct {
+		days   int
+		bucket func(AgingBuckets) money.Money
+	}{
+		{-3, func(b AgingBuckets) money.Money { return b.Current }},
+		{0, func(b AgingBuckets) money.Money { return b.Current }},
+		{1, func(b AgingBuckets) money.Money { return b.Days1To30 }},
+		{30, func(b AgingBuckets) money.Money { return b.Days1To30 }},
+		{31, func(b AgingBuckets) money.Money { return b.Days31To60 }},
+		{60, func(b AgingBuckets) money.Money { return b.Days31To60 }},
+		{61, func(b AgingBuckets) money.Money { return b.Days61To90 }},
+		{90, func(b AgingBuckets) money.Money { return b.Days61To90 }},
+		{91, func(b AgingBuckets) money.Money { return b.Over90 }},
+	}
+	for _, tt := range tests {
+		b := NewAgingBuckets()
+		b.Add(tt.days, idr("1000"))
+		if got := tt.bucket(b).String(); got != "1000" || b.Total.String() != "1000" {
+			t.Errorf("%d days: bucket = %s, total = %s, want 1000", tt.days, got, b.Total.String())
+		}
+	}
+}
+
+func TestARAgingReport(t *testing.T) {
+	asOf := date("2024-06-30")
+	invoices := []OpenInvoice{
+		{CustomerID: "2", CustomerName: "Budi", Currency: "IDR", DueDate: date("2024-06-30"), Balance: idr("500")},
+		{CustomerID: "1", CustomerName: "Ani", Currency: "IDR", DueDate: date("2024-05-31"), Balance: idr("300")},
+		{CustomerID: "2", CustomerName: "Budi", Currency: "IDR", DueDate: date("2024-02-01"), Balance: idr("200")},
+		{Currency: "USD", ExchangeRate: rate("16000"), DueDate: date("2024-06-15"), Balance: money.MustParse("1.5", "USD")},
+	}
+	for i := range invoices {
+		invoices[i].Age(asOf)
+	}
+	if invoices[1].DaysOverdue != 30 || invoices[0].DaysOverdue != 0 {
+		t.Errorf("days overdue = %d, %d, want 30, 0", invoices[1].DaysOverdue, invoices[0].DaysOverdue)
+	}
+
+	report := NewARAgingReport(asOf, invoices)
+	if len(report.Customers) != 3 {
+		t.Fatalf("%d customers, want 3", len(report.Customers))
+	}
+	names := []string{report.Customers[0].CustomerName, report.Customers[1].CustomerName, report.Customers[2].CustomerName}
+	if names[0] != "Ani" || names[1] != "Budi" || names[2] != "Walk-in customers" {
+		t.Errorf("customers = %v, want Ani, Budi, Walk-in customers", names)
+	}
+	budi := report.Customers[1]
+	if budi.InvoiceCount != 2 || budi.Current.String() != "500" || budi.Over90.String() != "200" || budi.Total.String() != "700" {
+		t.Errorf("Budi = %d invoices, current %s, over 90 %s, total %s, want 2, 500, 200, 700",
+			budi.InvoiceCount, budi.Current.String(), budi.Over90.String(), budi.Total.String())
+	}
+	if got := report.Customers[2].Days1To30.String(); got != "24000" {
+		t.Errorf("walk-in 1-30 days = %s, want 24000", got)
+	}
+	if got := report.Totals.Total.String(); got != "25000" {
+		t.Errorf("total = %s, want 25000", got)
+	}
+}
+
+func TestCustomerStatement(t *testing.T) {
+	lines := []StatementLine{
+		{Date: date("2024-02-10"), Type: StatementLinePayment, Debit: idr("0"), Credit: idr("400")},
+		{Date: date("2024-01-15"), Type: StatementLineInvoice, Debit: idr("1000"), Credit: idr("0")},
+		{Date: date("2024-02-10"), Type: StatementLineInvoice, Debit: idr("500"), Credit: idr("0")},
+		{Date: date("2024-02-20"), Type: StatementLinePayment, Debit: idr("0"), Credit: idr("600")},
+	}
+	s := NewCustomerStatement(&Customer{ID: "c", Name: "Ani"}, date("2024-02-01"), date("2024-02-29"), lines)
+
+	if s.OpeningBalance.String() != "1000" || s.ClosingBalance.String() != "500" {
+		t.Errorf("opening %s, closing %s, want 1000, 500", s.OpeningBalance.String(), s.ClosingBalance.String())
+	}
+	if s.TotalDebit.String() != "500" || s.TotalCredit.String() != "1000" {
+		t.Errorf("debits %s, credits %s, want 500, 1000", s.TotalDebit.String(), s.TotalCredit.String())
+	}
+	want := []struct {
+		kind    string
+		balance string
+	}{
+		{StatementLineInvoice, "1500"},
+		{StatementLinePayment, "1100"},
+		{StatementLinePayment, "500"},
+	}
+	if len(s.Lines) != len(want) {
+		t.Fatalf("%d lines, want %d", len(s.Lines), len(want))
+	}
+	for i, w := range want {
+		if s.Lines[i].Type != w.kind || s.Lines[i].Balance.String() != w.balance {
+			t.Errorf("line %d = %s %s, want %s %s", i, s.Lines[i].Type, s.Lines[i].Balance.String(), w.kind, w.balance)
+		}
+	}
+}
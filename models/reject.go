@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,7 +28,7 @@ type Reject struct {
 	Status      RejectStatus `json:"status" db:"status"`
 	RejectDate  time.Time    `json:"reject_date" db:"reject_date"`
 	Reason      string       `json:"reason" db:"reason"`
-	Total       float64      `json:"total" db:"total"`
+	Total       money.Money  `json:"total" db:"total"`
 	Items       []RejectItem `json:"items" db:"-"`
 	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
@@ -36,36 +37,41 @@ type Reject struct {
 
 // RejectItem represents a line item in a stock rejection
 type RejectItem struct {
-	ID          string     `json:"id" db:"id"`
-	RejectID    string     `json:"reject_id" db:"reject_id"`
-	ProductID   string     `json:"product_id" db:"product_id"`
-	ProductName string     `json:"product_name" db:"product_name"`
-	Quantity    int        `json:"quantity" db:"quantity"`
-	UnitCost    float64    `json:"unit_cost" db:"unit_cost"`
-	Subtotal    float64    `json:"subtotal" db:"subtotal"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt   *time.Time `json:"-" db:"deleted_at"`
+	ID          string      `json:"id" db:"id"`
+	RejectID    string      `json:"reject_id" db:"reject_id"`
+	ProductID   string      `json:"product_id" db:"product_id"`
+	ProductName string      `json:"product_name" db:"product_name"`
+	Quantity    int         `json:"quantity" db:"quantity"`
+	UnitCost    money.Money `json:"unit_cost" db:"unit_cost"`
+	Subtotal    money.Money `json:"subtotal" db:"subtotal"`
+	COGS        money.Money `json:"cogs" db:"cogs"` // inventory cost written off, assigned at completion
+
+	// Unit the quantity was entered in; Quantity is in the product's base unit
+	LineUnit
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
 }
 
 // RejectSummary represents summary statistics for stock rejections
 type RejectSummary struct {
-	TotalRejects          int     `json:"total_rejects"`
-	TotalCompletedRejects int     `json:"total_completed_rejects"`
-	TotalPendingRejects   int     `json:"total_pending_rejects"`
-	TotalCancelledRejects int     `json:"total_cancelled_rejects"`
-	TotalValue            float64 `json:"total_value"`
-	TotalRejectedItems    int     `json:"total_rejected_items"`
-	PeriodStart           string  `json:"period_start"`
-	PeriodEnd             string  `json:"period_end"`
+	TotalRejects          int         `json:"total_rejects"`
+	TotalCompletedRejects int         `json:"total_completed_rejects"`
+	TotalPendingRejects   int         `json:"total_pending_rejects"`
+	TotalCancelledRejects int         `json:"total_cancelled_rejects"`
+	TotalValue            money.Money `json:"total_value"`
+	TotalRejectedItems    int         `json:"total_rejected_items"`
+	PeriodStart           string      `json:"period_start"`
+	PeriodEnd             string      `json:"period_end"`
 }
 
 // DailyReject represents daily stock rejection data for reporting
 type DailyReject struct {
-	Date         string  `json:"date"`
-	TotalRejects int     `json:"total_rejects"`
-	TotalValue   float64 `json:"total_value"`
-	ItemCount    int     `json:"item_count"`
+	Date         string      `json:"date"`
+	TotalRejects int         `json:"total_rejects"`
+	TotalValue   money.Money `json:"total_value"`
+	ItemCount    int         `json:"item_count"`
 }
 
 // NewReject creates a new rejection entry with default values
@@ -89,6 +95,28 @@ func NewRejectItem() *RejectItem {
 	}
 }
 
+// ValidateAmounts checks that the quantities and amounts are small enough for the reject to be totalled
+func (r *Reject) ValidateAmounts() error {
+	limits := newDocumentLimits()
+	for _, item := range r.Items {
+		limits.line(item.Quantity, item.UnitCost, item.Subtotal)
+	}
+	limits.amounts(r.Total)
+	return limits.check(money.OneRate())
+}
+
+// ValidateAmounts checks that the item's quantity and amounts are small enough to be totalled
+func (ri *RejectItem) ValidateAmounts() error {
+	limits := newDocumentLimits()
+	limits.line(ri.Quantity, ri.UnitCost, ri.Subtotal)
+	return limits.check(money.OneRate())
+}
+
+// CalculateSubtotal sets the subtotal to unit_cost * quantity, rounded to the currency's minor units
+func (ri *RejectItem) CalculateSubtotal() {
+	ri.Subtotal = ri.UnitCost.Mul(int64(ri.Quantity)).Round()
+}
+
 // Scan implements the sql.Scanner interface for Reject
 func (r *Reject) Scan(value any) error {
 	if value == nil {
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"inventory-go/money"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// ProfitGroupBy selects the dimension a profit report is broken down by
+type ProfitGroupBy string
+
+const (
+	ProfitGroupByProduct  ProfitGroupBy = "product"
+	ProfitGroupByVariant  ProfitGroupBy = "variant"
+	ProfitGroupByCategory ProfitGroupBy = "category"
+	ProfitGroupByCustomer ProfitGroupBy = "customer"
+	ProfitGroupByPlatform ProfitGroupBy = "platform"
+	ProfitGroupByPeriod   ProfitGroupBy = "period"
+)
+
+// IsValid reports whether the grouping is supported
+func (g ProfitGroupBy) IsValid() bool {
+	switch g {
+	case ProfitGroupByProduct, ProfitGroupByVariant, ProfitGroupByCategory,
+		ProfitGroupByCustomer, ProfitGroupByPlatform, ProfitGroupByPeriod:
+		return true
+	}
+	return false
+}
+
+// ReportInterval is the bucket size of a report grouped by period
+type ReportInterval string
+
+const (
+	ReportIntervalDay   ReportInterval = "day"
+	ReportIntervalWeek  ReportInterval = "week"
+	ReportIntervalMonth ReportInterval = "month"
+)
+
+// IsValid reports whether the interval is supported
+func (i ReportInterval) IsValid() bool {
+	return i == ReportIntervalDay || i == ReportIntervalWeek || i == ReportIntervalMonth
+}
+
+// Truncate returns the start of the bucket containing t. Weeks start on Monday.
+func (i ReportInterval) Truncate(t time.Time) time.Time {
+	day := truncateToDate(t)
+	switch i {
+	case ReportIntervalWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case ReportIntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+// Previous returns the start of the bucket before the one starting at t
+func (i ReportInterval) Previous(t time.Time) time.Time {
+	switch i {
+	case ReportIntervalWeek:
+		return t.AddDate(0, 0, -7)
+	case ReportIntervalMonth:
+		return t.AddDate(0, -1, 0)
+	}
+	return t.AddDate(0, 0, -1)
+}
+
+// ProfitReportFilter holds the parameters of a profit report
+type ProfitReportFilter struct {
+	StartDate time.Time
+	EndDate   time.Time
+	GroupBy   ProfitGroupBy
+	// Interval is used when grouping by period
+	Interval ReportInterval
+	// CategoryLevel is the depth of the category tree sales are rolled up to, 1 being the top level
+	CategoryLevel int
+	Platform      string
+	CustomerID    string
+	// CategoryID limits the report to products in this category or any of its descendants
+	CategoryID string
+}
+
+// PreviousPeriod returns the period of the same length immediately before the filter's period
+func (f ProfitReportFilter) PreviousPeriod() (time.Time, time.Time) {
+	length := f.EndDate.Sub(f.StartDate) + time.Second
+	return f.StartDate.Add(-length), f.StartDate.Add(-time.Second)
+}
+
+// ProfitMetrics are the sales and cost figures of a group in the base currency.
+// Revenue is before discounts and excludes tax.
+type ProfitMetrics struct {
+	Orders        int         `json:"orders"`
+	Quantity      int         `json:"quantity"`
+	Revenue       money.Money `json:"revenue"`
+	Discounts     money.Money `json:"discounts"`
+	NetRevenue    money.Money `json:"net_revenue"`
+	COGS          money.Money `json:"cogs"`
+	GrossProfit   money.Money `json:"gross_profit"`
+	MarginPercent float64     `json:"margin_percent"`
+}
+
+// NewProfitMetrics returns empty metrics in the base currency
+func NewProfitMetrics() ProfitMetrics {
+	zero := money.Zero(money.BaseCurrency)
+	return ProfitMetrics{
+		Revenue:     zero,
+		Discounts:   zero,
+		NetRevenue:  zero,
+		COGS:        zero,
+		GrossProfit: zero,
+	}
+}
+
+// Add accumulates another set of metrics and recalculates the derived figures
+func (m *ProfitMetrics) Add(o ProfitMetrics) {
+	m.Orders += o.Orders
+	m.Quantity += o.Quantity
+	m.Revenue = m.Revenue.Add(o.Revenue)
+	m.Discounts = m.Discounts.Add(o.Discounts)
+	m.COGS = m.COGS.Add(o.COGS)
+	m.Calculate()
+}
+
+// Calculate derives net revenue, gross profit and margin from revenue, discounts and COGS
+func (m *ProfitMetrics) Calculate() {
+	m.NetRevenue = m.Revenue.Sub(m.Discounts).Round()
+	m.Revenue = m.Revenue.Round()
+	m.Discounts = m.Discounts.Round()
+	m.COGS = m.COGS.Round()
+	m.GrossProfit = m.NetRevenue.Sub(m.COGS)
+	m.MarginPercent = 0
+	if p := percent(m.GrossProfit.Ratio(m.NetRevenue)); p != nil {
+		m.MarginPercent = *p
+	}
+}
+
+// ProfitComparison is the change from the previous period to the current one.
+// Percentages are nil when the previous figure is zero.
+type ProfitComparison struct {
+	RevenueChange            money.Money `json:"revenue_change"`
+	RevenueChangePercent     *float64    `json:"revenue_change_percent"`
+	GrossProfitChange        money.Money `json:"gross_profit_change"`
+	GrossProfitChangePercent *float64    `json:"gross_profit_change_percent"`
+	// MarginChange is the difference in margin, in percentage points
+	MarginChange float64 `json:"margin_change"`
+}
+
+// ProfitReportLine is one group of a profit report with its previous period figures
+type ProfitReportLine struct {
+	Key      string           `json:"key,omitempty"`
+	Label    string           `json:"label,omitempty"`
+	Current  ProfitMetrics    `json:"current"`
+	Previous ProfitMetrics    `json:"previous"`
+	Change   ProfitComparison `json:"change"`
+}
+
+// NewProfitReportLine returns an empty report line
+func NewProfitReportLine(key, label string) *ProfitReportLine {
+	return &ProfitReportLine{
+		Key:      key,
+		Label:    label,
+		Current:  NewProfitMetrics(),
+		Previous: NewProfitMetrics(),
+	}
+}
+
+// Compare fills the change between the previous and current figures
+func (l *ProfitReportLine) Compare() {
+	l.Current.Calculate()
+	l.Previous.Calculate()
+	l.Change = ProfitComparison{
+		RevenueChange:            l.Current.NetRevenue.Sub(l.Previous.NetRevenue),
+		RevenueChangePercent:     percent(l.Current.NetRevenue.Sub(l.Previous.NetRevenue).Ratio(l.Previous.NetRevenue.Abs())),
+		GrossProfitChange:        l.Current.GrossProfit.Sub(l.Previous.GrossProfit),
+		GrossProfitChangePercent: percent(l.Current.GrossProfit.Sub(l.Previous.GrossProfit).Ratio(l.Previous.GrossProfit.Abs())),
+		MarginChange:             math.Round((l.Current.MarginPercent-l.Previous.MarginPercent)*100) / 100,
+	}
+}
+
+// ProfitReport is the gross margin of completed sales broken down by one dimension,
+// compared with the period of the same length before it. Amounts are in the base currency.
+type ProfitReport struct {
+	GroupBy           ProfitGroupBy      `json:"group_by"`
+	Interval          ReportInterval     `json:"interval,omitempty"`
+	CategoryLevel     int                `json:"category_level,omitempty"`
+	Currency          string             `json:"currency"`
+	StartDate         time.Time          `json:"start_date"`
+	EndDate           time.Time          `json:"end_date"`
+	PreviousStartDate time.Time          `json:"previous_start_date"`
+	PreviousEndDate   time.Time          `json:"previous_end_date"`
+	Lines             []ProfitReportLine `json:"lines"`
+	Total             ProfitReportLine   `json:"total"`
+}
+
+// SortLines orders period lines chronologically and every other grouping
+// by current gross profit, highest first
+func (r *ProfitReport) SortLines() {
+	sort.SliceStable(r.Lines, func(i, j int) bool {
+		a, b := r.Lines[i], r.Lines[j]
+		if r.GroupBy == ProfitGroupByPeriod {
+			return a.Key < b.Key
+		}
+		if c := a.Current.GrossProfit.Cmp(b.Current.GrossProfit); c != 0 {
+			return c > 0
+		}
+		return a.Label < b.Label
+	})
+}
+
+// percent converts a ratio to a percentage rounded to two decimals, or nil when the ratio is undefined
+func percent(r *big.Rat) *float64 {
+	if r == nil {
+		return nil
+	}
+	f, _ := new(big.Rat).Mul(r, big.NewRat(100, 1)).Float64()
+	f = math.Round(f*100) / 100
+	return &f
+}
@@ -3,6 +3,7 @@ package models
 
 import (
 	"errors"
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,18 +12,18 @@ import (
 // SalesSummary represents a summary of sales data
 // for a given time period
 type SalesSummary struct {
-	TotalOrders       int     `json:"total_orders"`
-	TotalSales        float64 `json:"total_sales"`
-	CompletedOrders   int     `json:"completed_orders"`
-	CompletedSales    float64 `json:"completed_sales"`
-	AverageOrderValue float64 `json:"average_order_value"`
+	TotalOrders       int         `json:"total_orders"`
+	TotalSales        money.Money `json:"total_sales"`
+	CompletedOrders   int         `json:"completed_orders"`
+	CompletedSales    money.Money `json:"completed_sales"`
+	AverageOrderValue money.Money `json:"average_order_value"`
 }
 
 // DailySales represents the sales data for a single day
 type DailySales struct {
-	Date       string  `json:"date"`
-	OrderCount int     `json:"order_count"`
-	TotalSales float64 `json:"total_sales"`
+	Date       string      `json:"date"`
+	OrderCount int         `json:"order_count"`
+	TotalSales money.Money `json:"total_sales"`
 }
 
 type SaleStatus string
@@ -46,15 +47,38 @@ const (
 	PlatformOther        PlatformType = "Other"
 )
 
+// IsValid reports whether the platform is known
+func (p PlatformType) IsValid() bool {
+	switch p {
+	case PlatformOfflineStore, PlatformWebsite, PlatformTokopedia, PlatformShopee,
+		PlatformLazada, PlatformBlibli, PlatformBukalapak, PlatformOther:
+		return true
+	}
+	return false
+}
+
 type Sale struct {
-	ID          string     `json:"id" db:"id"`
-	ReferenceNo string     `json:"reference_no" db:"reference_no"`
-	Status      SaleStatus `json:"status" db:"status"`
-	SaleDate    time.Time  `json:"sale_date" db:"sale_date"`
-	Note        string     `json:"note,omitempty" db:"note"`
-	Total       float64    `json:"total" db:"total"`
-	Paid        float64    `json:"paid" db:"paid"`
-	Balance     float64    `json:"balance" db:"balance"`
+	ID          string      `json:"id" db:"id"`
+	ReferenceNo string      `json:"reference_no" db:"reference_no"`
+	Status      SaleStatus  `json:"status" db:"status"`
+	SaleDate    time.Time   `json:"sale_date" db:"sale_date"`
+	Note        string      `json:"note,omitempty" db:"note"`
+	Total       money.Money `json:"total" db:"total"`
+	Paid        money.Money `json:"paid" db:"paid"`
+	Balance     money.Money `json:"balance" db:"balance"`
+
+	// Whether item prices include tax, and the tax of the items by tax code
+	TaxMode    TaxMode          `json:"tax_mode" db:"tax_mode"`
+	TaxTotal   money.Money      `json:"tax_total" db:"-"`
+	TaxSummary []TaxSummaryLine `json:"tax_summary,omitempty" db:"-"`
+
+	// When the balance must be paid, from the customer's payment terms unless given
+	DueDate *time.Time `json:"due_date,omitempty" db:"due_date"`
+
+	// Currency of the document and the rate to the base currency at the sale date
+	Currency     string      `json:"currency" db:"currency"`
+	ExchangeRate money.Rate  `json:"exchange_rate" db:"exchange_rate"`
+	BaseTotal    money.Money `json:"base_total" db:"base_total"`
 
 	// Relations
 	CustomerID *string       `json:"customer_id,omitempty" db:"customer_id"`
@@ -63,6 +87,9 @@ type Sale struct {
 	Payments   []SalePayment `json:"payments,omitempty" db:"-"`
 	Platform   PlatformType  `json:"platform" db:"platform"`
 
+	// Promotions applied when the sale was priced
+	Promotions []AppliedPromotion `json:"promotions,omitempty" db:"-"`
+
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
@@ -70,15 +97,36 @@ type Sale struct {
 }
 
 type SaleItem struct {
-	ID          string  `json:"id" db:"id"`
-	SaleID      string  `json:"sale_id" db:"sale_id"`
-	ProductID   string  `json:"product_id" db:"product_id"`
-	ProductName string  `json:"product_name" db:"product_name"`
-	Quantity    int     `json:"quantity" db:"quantity"`
-	UnitPrice   float64 `json:"unit_price" db:"unit_price"`
-	Tax         float64 `json:"tax" db:"tax"`
-	Discount    float64 `json:"discount" db:"discount"`
-	Subtotal    float64 `json:"subtotal" db:"subtotal"`
+	ID          string      `json:"id" db:"id"`
+	SaleID      string      `json:"sale_id" db:"sale_id"`
+	ProductID   string      `json:"product_id" db:"product_id"`
+	ProductName string      `json:"product_name" db:"product_name"`
+	Quantity    int         `json:"quantity" db:"quantity"`
+	UnitPrice   money.Money `json:"unit_price" db:"unit_price"`
+	Tax         money.Money `json:"tax" db:"tax"`
+	Discount    money.Money `json:"discount" db:"discount"`
+	Subtotal    money.Money `json:"subtotal" db:"subtotal"`
+
+	// Part of the discount given by promotions; the rest was entered by hand
+	PromotionDiscount money.Money `json:"promotion_discount" db:"promotion_discount"`
+
+	// Unit the quantity was entered in; Quantity is in the product's base unit
+	LineUnit
+
+	// Tax code the tax is calculated from, if any
+	LineTax
+
+	// Rule the unit price was set by
+	AppliedPrice
+
+	// Subtotal, tax and discount converted to the base currency
+	BaseSubtotal money.Money `json:"base_subtotal" db:"base_subtotal"`
+	BaseTax      money.Money `json:"base_tax" db:"base_tax"`
+	BaseDiscount money.Money `json:"base_discount" db:"base_discount"`
+
+	// Cost of goods sold in the base currency, assigned when the sale is completed
+	UnitCost money.Money `json:"unit_cost" db:"unit_cost"`
+	COGS     money.Money `json:"cogs" db:"cogs"`
 
 	// Relations
 	Product *Product `json:"product,omitempty" db:"-"`
@@ -90,13 +138,13 @@ type SaleItem struct {
 }
 
 type SalePayment struct {
-	ID            string    `json:"id" db:"id"`
-	SaleID        string    `json:"sale_id" db:"sale_id"`
-	Amount        float64   `json:"amount" db:"amount"`
-	PaymentMethod string    `json:"payment_method" db:"payment_method"` // e.g., "cash", "credit_card", "bank_transfer"
-	Reference     string    `json:"reference,omitempty" db:"reference"`
-	Note          string    `json:"note,omitempty" db:"note"`
-	PaymentDate   time.Time `json:"payment_date" db:"payment_date"`
+	ID            string      `json:"id" db:"id"`
+	SaleID        string      `json:"sale_id" db:"sale_id"`
+	Amount        money.Money `json:"amount" db:"amount"`
+	PaymentMethod string      `json:"payment_method" db:"payment_method"` // e.g., "cash", "credit_card", "bank_transfer"
+	Reference     string      `json:"reference,omitempty" db:"reference"`
+	Note          string      `json:"note,omitempty" db:"note"`
+	PaymentDate   time.Time   `json:"payment_date" db:"payment_date"`
 
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
@@ -117,6 +165,19 @@ func (s *Sale) GenerateID() {
 	}
 }
 
+// SetDueDate sets the due date from the given payment terms when none was given
+func (s *Sale) SetDueDate(terms PaymentTerms) {
+	if s.DueDate != nil {
+		return
+	}
+	date := s.SaleDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+	due := terms.DueDate(date)
+	s.DueDate = &due
+}
+
 // PrepareSave sets the SaleID for all items and payments and calculates totals
 func (s *Sale) PrepareSave() {
 	// Set SaleID for all items
@@ -152,26 +213,93 @@ func (s *SalePayment) GenerateID() {
 
 // Calculate methods
 func (s *Sale) CalculateTotals() {
-	var subtotal float64
+	s.BindCurrency()
+	subtotal := money.Zero(s.Currency)
 
-	// Calculate subtotal from items
-	for _, item := range s.Items {
-		item.CalculateSubtotal()
-		subtotal += item.Subtotal
+	// Calculate subtotal from items, each rounded to the currency's minor units
+	for i := range s.Items {
+		s.Items[i].CalculateTax(s.TaxMode)
+		subtotal = subtotal.Add(s.Items[i].Subtotal)
 	}
 
 	s.Total = subtotal
-	s.Balance = s.Total - s.Paid
+	s.Balance = s.Total.Sub(s.Paid)
+	s.ConvertToBase()
+	s.SummarizeTax()
+}
+
+// SummarizeTax totals the items' taxable amounts and tax by tax code
+func (s *Sale) SummarizeTax() {
+	summary := newTaxSummarizer(s.Currency)
+	for _, item := range s.Items {
+		summary.add(item.LineTax, item.Subtotal.Sub(item.Tax), item.Tax)
+	}
+	s.TaxSummary, s.TaxTotal = summary.result()
+}
+
+// BindCurrency normalizes the sale currency and attaches it to every amount
+func (s *Sale) BindCurrency() {
+	s.Currency = money.NormalizeCurrency(s.Currency)
+	s.ExchangeRate = documentRate(s.Currency, s.ExchangeRate)
+	s.Total = s.Total.In(s.Currency)
+	s.Paid = s.Paid.In(s.Currency)
+	s.Balance = s.Balance.In(s.Currency)
+	s.BaseTotal = s.BaseTotal.In(money.BaseCurrency)
+	for i := range s.Items {
+		item := &s.Items[i]
+		item.UnitPrice = item.UnitPrice.In(s.Currency)
+		item.Tax = item.Tax.In(s.Currency)
+		item.Discount = item.Discount.In(s.Currency)
+		item.PromotionDiscount = item.PromotionDiscount.In(s.Currency)
+		item.Subtotal = item.Subtotal.In(s.Currency)
+		item.BaseSubtotal = item.BaseSubtotal.In(money.BaseCurrency)
+		item.BaseTax = item.BaseTax.In(money.BaseCurrency)
+		item.BaseDiscount = item.BaseDiscount.In(money.BaseCurrency)
+		item.UnitCost = item.UnitCost.In(money.BaseCurrency)
+		item.COGS = item.COGS.In(money.BaseCurrency)
+	}
+	for i := range s.Payments {
+		s.Payments[i].Amount = s.Payments[i].Amount.In(s.Currency)
+	}
+	for i := range s.Promotions {
+		s.Promotions[i].Discount = s.Promotions[i].Discount.In(s.Currency)
+		s.Promotions[i].BaseDiscount = s.Promotions[i].BaseDiscount.In(money.BaseCurrency)
+	}
+}
+
+// ConvertToBase fills the base-currency equivalents using the sale's exchange rate
+func (s *Sale) ConvertToBase() {
+	s.BindCurrency()
+	for i := range s.Items {
+		item := &s.Items[i]
+		item.BaseSubtotal = s.ExchangeRate.Convert(item.Subtotal, money.BaseCurrency).Round()
+		item.BaseTax = s.ExchangeRate.Convert(item.Tax, money.BaseCurrency).Round()
+		item.BaseDiscount = s.ExchangeRate.Convert(item.Discount, money.BaseCurrency).Round()
+	}
+	s.BaseTotal = s.ExchangeRate.Convert(s.Total, money.BaseCurrency).Round()
 }
 
+// CalculateSubtotal sets the subtotal for a price without tax: (unit_price * quantity) + tax - discount
 func (i *SaleItem) CalculateSubtotal() {
-	// Calculate subtotal: (unit_price * quantity) + tax - discount
-	itemTotal := (i.UnitPrice * float64(i.Quantity))
-	i.Subtotal = itemTotal + i.Tax - i.Discount
+	i.CalculateTax(TaxModeExclusive)
+}
+
+// CalculateTax prices the item in the given tax mode. Items with a tax code get their tax
+// from its rate; otherwise the given tax is kept. The subtotal always includes the tax.
+func (i *SaleItem) CalculateTax(mode TaxMode) {
+	amount := i.UnitPrice.Mul(int64(i.Quantity)).Sub(i.Discount)
+	if i.HasTaxCode() {
+		_, i.Tax = splitTax(amount, i.TaxRate, mode)
+	}
+	if mode == TaxModeInclusive {
+		i.Subtotal = amount.Round()
+		return
+	}
+	i.Subtotal = amount.Add(i.Tax).Round()
 }
 
 // Helper methods
-func (s *Sale) AddPayment(amount float64, method string, reference string, note string) {
+func (s *Sale) AddPayment(amount money.Money, method string, reference string, note string) {
 	payment := SalePayment{
 		Amount:        amount,
 		PaymentMethod: method,
@@ -180,14 +308,14 @@ func (s *Sale) AddPayment(amount float64, method string, reference string, note
 		PaymentDate:   time.Now(),
 	}
 	s.Payments = append(s.Payments, payment)
-	s.Paid += amount
-	s.Balance = s.Total - s.Paid
+	s.Paid = s.Paid.Add(amount)
+	s.Balance = s.Total.Sub(s.Paid)
 }
 
 func (s *Sale) UpdateStatus(status SaleStatus) {
 	s.Status = status
 	if status == SaleStatusCompleted {
-		s.Balance = 0 // Ensure balance is zero when completed
+		s.Balance = money.Zero(s.Total.Currency()) // Ensure balance is zero when completed
 		s.Paid = s.Total
 	}
 }
@@ -196,5 +324,23 @@ func (s *Sale) Validate() error {
 	if len(s.Items) == 0 {
 		return errors.New("sale must have at least one item")
 	}
-	return nil
+	if s.TaxMode != "" && !s.TaxMode.IsValid() {
+		return errors.New("tax_mode must be \"exclusive\" or \"inclusive\"")
+	}
+	return s.ValidateAmounts()
+}
+
+// ValidateAmounts checks that the quantities and amounts are small enough for the sale to be
+// totalled. Prices, unit conversions and the exchange rate can be filled in after Validate,
+// so it is checked again once they are known.
+func (s *Sale) ValidateAmounts() error {
+	limits := newDocumentLimits()
+	for _, item := range s.Items {
+		limits.line(item.Quantity, item.UnitPrice, item.Tax, item.Discount, item.PromotionDiscount, item.Subtotal)
+	}
+	for _, payment := range s.Payments {
+		limits.amounts(payment.Amount)
+	}
+	limits.amounts(s.Total, s.Paid)
+	return limits.check(documentRate(s.Currency, s.ExchangeRate))
 }
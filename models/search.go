@@ -0,0 +1,69 @@
+package models
+
+import "inventory-go/money"
+
+// ProductSearch is a full-text product search narrowed by the filters of a product listing
+type ProductSearch struct {
+	Query string
+	ProductFilter
+	Offset int
+	Limit  int
+}
+
+// ProductSearchHit is a product found by a search and how well it matched
+type ProductSearchHit struct {
+	Product
+	Rank float64 `json:"rank"`
+}
+
+// FacetCount is how many products found have a value, e.g. a category or a status
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// PriceRangeFacet is how many products found are priced from Min up to, but not including, Max
+type PriceRangeFacet struct {
+	Min   money.Money  `json:"min"`
+	Max   *money.Money `json:"max,omitempty"`
+	Count int64        `json:"count"`
+}
+
+// ProductFacets summarizes everything a search found, not only the page returned
+type ProductFacets struct {
+	Categories   []FacetCount      `json:"categories"`
+	Status       []FacetCount      `json:"status"`
+	Condition    []FacetCount      `json:"condition"`
+	PriceRanges  []PriceRangeFacet `json:"price_ranges"`
+	Availability []FacetCount      `json:"availability"`
+}
+
+// ProductSearchResult is a page of search hits, the total found and the facets
+type ProductSearchResult struct {
+	Products []ProductSearchHit
+	Total    int64
+	Facets   ProductFacets
+}
+
+// Availability facet values
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// PriceFacetBounds are where the price range facets start, in rupiah; the last range is open
+var PriceFacetBounds = []int64{0, 50000, 100000, 250000, 500000, 1000000}
+
+// PriceRanges returns the empty price range facets
+func PriceRanges() []PriceRangeFacet {
+	ranges := make([]PriceRangeFacet, len(PriceFacetBounds))
+	for i, bound := range PriceFacetBounds {
+		ranges[i].Min = money.FromInt(bound, money.DefaultCurrency)
+		if i+1 < len(PriceFacetBounds) {
+			max := money.FromInt(PriceFacetBounds[i+1], money.DefaultCurrency)
+			ranges[i].Max = &max
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Setting keys
+const (
+	// SettingCostingMethod selects how inventory is costed ("fifo" or "average")
+	SettingCostingMethod = "costing_method"
+
+	// SettingTaxMode is whether prices include tax on documents that do not say ("exclusive" or "inclusive")
+	SettingTaxMode = "tax_mode"
+
+	// SettingDefaultTaxCode is the tax code for products whose product and categories have none
+	SettingDefaultTaxCode = "default_tax_code"
+
+	// SettingDefaultPriceList is the price list code for walk-in sales and customers without a price list
+	SettingDefaultPriceList = "default_price_list"
+)
+
+// settingDefaults holds the value used for a setting that has never been saved
+var settingDefaults = map[string]string{
+	SettingCostingMethod:    string(CostingMethodAverage),
+	SettingTaxMode:          string(TaxModeExclusive),
+	SettingDefaultTaxCode:   "",
+	SettingDefaultPriceList: "RETAIL",
+}
+
+// Setting is a company-wide configuration value
+type Setting struct {
+	Key       string    `json:"key" db:"key"`
+	Value     string    `json:"value" db:"value"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultSettings returns the default value of every known setting
+func DefaultSettings() []Setting {
+	settings := make([]Setting, 0, len(settingDefaults))
+	for key, value := range settingDefaults {
+		settings = append(settings, Setting{Key: key, Value: value})
+	}
+	return settings
+}
+
+// DefaultSettingValue returns the default value for a setting key
+func DefaultSettingValue(key string) string {
+	return settingDefaults[key]
+}
+
+// Validate checks that the setting is known and its value is allowed
+func (s *Setting) Validate() error {
+	if _, ok := settingDefaults[s.Key]; !ok {
+		return fmt.Errorf("unknown setting %q", s.Key)
+	}
+
+	switch s.Key {
+	case SettingCostingMethod:
+		if !CostingMethod(s.Value).IsValid() {
+			return fmt.Errorf("costing_method must be %q or %q", CostingMethodFIFO, CostingMethodAverage)
+		}
+	case SettingTaxMode:
+		if !TaxMode(s.Value).IsValid() {
+			return fmt.Errorf("tax_mode must be %q or %q", TaxModeExclusive, TaxModeInclusive)
+		}
+	case SettingDefaultTaxCode, SettingDefaultPriceList:
+		s.Value = strings.ToUpper(strings.TrimSpace(s.Value))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,36 +19,62 @@ const (
 // StockInSummary represents a summary of stock-in data
 // for a given time period
 type StockInSummary struct {
-	TotalOrders       int     `json:"total_orders"`
-	TotalCost         float64 `json:"total_cost"`
-	CompletedOrders   int     `json:"completed_orders"`
-	CompletedCost     float64 `json:"completed_cost"`
-	AverageOrderValue float64 `json:"average_order_value"`
+	TotalOrders       int         `json:"total_orders"`
+	TotalCost         money.Money `json:"total_cost"`
+	CompletedOrders   int         `json:"completed_orders"`
+	CompletedCost     money.Money `json:"completed_cost"`
+	AverageOrderValue money.Money `json:"average_order_value"`
 }
 
 // DailyStockIn represents the stock-in data for a single day
 type DailyStockIn struct {
-	Date       string  `json:"date"`
-	OrderCount int     `json:"order_count"`
-	TotalCost  float64 `json:"total_cost"`
+	Date       string      `json:"date"`
+	OrderCount int         `json:"order_count"`
+	TotalCost  money.Money `json:"total_cost"`
 }
 
 // StockIn represents a stock-in transaction (purchase order)
 type StockIn struct {
-	ID          string       `json:"id" db:"id"`
-	ReferenceNo string       `json:"reference_no" db:"reference_no"`
+	ID          string        `json:"id" db:"id"`
+	ReferenceNo string        `json:"reference_no" db:"reference_no"`
 	Status      StockInStatus `json:"status" db:"status"`
-	OrderDate   time.Time    `json:"order_date" db:"order_date"`
-	Note        string       `json:"note,omitempty" db:"note"`
-	Total       float64      `json:"total" db:"total"`
-	Paid        float64      `json:"paid" db:"paid"`
-	Balance     float64      `json:"balance" db:"balance"`
+	OrderDate   time.Time     `json:"order_date" db:"order_date"`
+	Note        string        `json:"note,omitempty" db:"note"`
+	Total       money.Money   `json:"total" db:"total"`
+	Paid        money.Money   `json:"paid" db:"paid"`
+	Balance     money.Money   `json:"balance" db:"balance"`
+
+	// Whether item costs include tax, and the input tax of the items by tax code
+	TaxMode    TaxMode          `json:"tax_mode" db:"tax_mode"`
+	TaxTotal   money.Money      `json:"tax_total" db:"-"`
+	TaxSummary []TaxSummaryLine `json:"tax_summary,omitempty" db:"-"`
+
+	// Supplier credits taken off the balance, and when the balance must be paid
+	Credited money.Money `json:"credited" db:"credited"`
+	DueDate  *time.Time  `json:"due_date,omitempty" db:"due_date"`
+
+	// Opening stock recorded by a product import. It is owed to no one, so it has no balance
+	// and is posted against opening balance equity instead of accounts payable.
+	Opening bool `json:"opening" db:"opening"`
+
+	// Currency of the document and the rate to the base currency at the order date
+	Currency     string      `json:"currency" db:"currency"`
+	ExchangeRate money.Rate  `json:"exchange_rate" db:"exchange_rate"`
+	BaseTotal    money.Money `json:"base_total" db:"base_total"`
 
 	// Relations
 	SupplierID *string       `json:"supplier_id,omitempty" db:"supplier_id"`
 	Supplier   *Supplier     `json:"supplier,omitempty" db:"-"`
 	Items      []StockInItem `json:"items" db:"-"`
 
+	// Payments made and credits received against the stock-in
+	Payments []StockInPayment `json:"payments,omitempty" db:"-"`
+	Credits  []SupplierCredit `json:"credits,omitempty" db:"-"`
+
+	// Landed costs allocated to the items, in the base currency
+	LandedCostTotal money.Money            `json:"landed_cost_total" db:"-"`
+	LandedCosts     []LandedCostAllocation `json:"landed_costs,omitempty" db:"-"`
+
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
@@ -56,15 +83,29 @@ type StockIn struct {
 
 // StockInItem represents an item in a stock-in transaction
 type StockInItem struct {
-	ID          string  `json:"id" db:"id"`
-	StockInID   string  `json:"stock_in_id" db:"stock_in_id"`
-	ProductID   string  `json:"product_id" db:"product_id"`
-	ProductName string  `json:"product_name" db:"product_name"`
-	Quantity    int     `json:"quantity" db:"quantity"`
-	UnitCost    float64 `json:"unit_cost" db:"unit_cost"`
-	Tax         float64 `json:"tax,omitempty" db:"tax"`
-	Discount    float64 `json:"discount,omitempty" db:"discount"`
-	Subtotal    float64 `json:"subtotal" db:"subtotal"`
+	ID          string      `json:"id" db:"id"`
+	StockInID   string      `json:"stock_in_id" db:"stock_in_id"`
+	ProductID   string      `json:"product_id" db:"product_id"`
+	ProductName string      `json:"product_name" db:"product_name"`
+	Quantity    int         `json:"quantity" db:"quantity"`
+	UnitCost    money.Money `json:"unit_cost" db:"unit_cost"`
+	Tax         money.Money `json:"tax" db:"tax"`
+	Discount    money.Money `json:"discount" db:"discount"`
+	Subtotal    money.Money `json:"subtotal" db:"subtotal"`
+
+	// Unit the quantity was entered in; Quantity is in the product's base unit
+	LineUnit
+
+	// Tax code the tax is calculated from, if any
+	LineTax
+
+	// Unit cost and subtotal converted to the base currency
+	BaseUnitCost money.Money `json:"base_unit_cost" db:"base_unit_cost"`
+	BaseSubtotal money.Money `json:"base_subtotal" db:"base_subtotal"`
+
+	// Landed costs allocated to the item and the resulting base unit cost used for costing
+	LandedCost     money.Money `json:"landed_cost" db:"landed_cost"`
+	LandedUnitCost money.Money `json:"landed_unit_cost" db:"-"`
 
 	// Relations
 	Product *Product `json:"product,omitempty" db:"-"`
@@ -96,3 +137,126 @@ func NewStockInItem() *StockInItem {
 		UpdatedAt: now,
 	}
 }
+
+// CalculateSubtotal sets the subtotal to unit_cost * quantity, rounded to the currency's minor units
+func (i *StockInItem) CalculateSubtotal() {
+	i.Subtotal = i.UnitCost.Mul(int64(i.Quantity)).Round()
+}
+
+// CalculateTax prices the item in the given tax mode. Items with a tax code get their tax
+// from its rate; otherwise the given tax is kept. The subtotal is the cost without tax, which
+// is what the stock is valued at, so in inclusive mode the unit cost is reduced by the tax.
+func (i *StockInItem) CalculateTax(mode TaxMode) {
+	amount := i.UnitCost.Mul(int64(i.Quantity))
+	if i.HasTaxCode() {
+		_, i.Tax = splitTax(amount, i.TaxRate, mode)
+	}
+	if mode == TaxModeInclusive && i.Quantity > 0 {
+		amount = amount.Sub(i.Tax)
+		i.UnitCost = amount.Div(int64(i.Quantity))
+	}
+	i.Subtotal = amount.Round()
+}
+
+// ValidateAmounts checks that the item's quantity and amounts are small enough to be totalled
+// in a stock-in of the given currency and rate
+func (i *StockInItem) ValidateAmounts(currency string, rate money.Rate) error {
+	limits := newDocumentLimits()
+	i.addLimits(limits)
+	return limits.check(documentRate(currency, rate))
+}
+
+func (i *StockInItem) addLimits(limits *documentLimits) {
+	limits.line(i.Quantity, i.UnitCost, i.Tax, i.Discount, i.Subtotal)
+}
+
+// ConvertToBase binds the item amounts to the document currency and fills the
+// base-currency equivalents. The base unit cost keeps full precision for costing.
+func (i *StockInItem) ConvertToBase(currency string, rate money.Rate) {
+	currency = money.NormalizeCurrency(currency)
+	rate = documentRate(currency, rate)
+	i.UnitCost = i.UnitCost.In(currency)
+	i.Tax = i.Tax.In(currency)
+	i.Discount = i.Discount.In(currency)
+	i.Subtotal = i.Subtotal.In(currency)
+	i.BaseUnitCost = rate.Convert(i.UnitCost, money.BaseCurrency)
+	i.BaseSubtotal = rate.Convert(i.Subtotal, money.BaseCurrency).Round()
+	i.CalculateLandedUnitCost()
+}
+
+// CalculateLandedUnitCost sets the landed unit cost to the base unit cost plus the
+// item's share of landed costs per unit
+func (i *StockInItem) CalculateLandedUnitCost() {
+	i.LandedCost = i.LandedCost.In(money.BaseCurrency)
+	i.LandedUnitCost = i.BaseUnitCost
+	if i.Quantity > 0 {
+		i.LandedUnitCost = i.BaseUnitCost.Add(i.LandedCost.Div(int64(i.Quantity)))
+	}
+}
+
+// BindCurrency normalizes the stock-in currency and attaches it to every amount
+func (s *StockIn) BindCurrency() {
+	s.Currency = money.NormalizeCurrency(s.Currency)
+	s.ExchangeRate = documentRate(s.Currency, s.ExchangeRate)
+	s.Total = s.Total.In(s.Currency)
+	s.Paid = s.Paid.In(s.Currency)
+	s.Balance = s.Balance.In(s.Currency)
+	s.Credited = s.Credited.In(s.Currency)
+	s.BaseTotal = s.BaseTotal.In(money.BaseCurrency)
+	s.LandedCostTotal = s.LandedCostTotal.In(money.BaseCurrency)
+	for i := range s.Items {
+		item := &s.Items[i]
+		item.UnitCost = item.UnitCost.In(s.Currency)
+		item.Tax = item.Tax.In(s.Currency)
+		item.Discount = item.Discount.In(s.Currency)
+		item.Subtotal = item.Subtotal.In(s.Currency)
+		item.BaseUnitCost = item.BaseUnitCost.In(money.BaseCurrency)
+		item.BaseSubtotal = item.BaseSubtotal.In(money.BaseCurrency)
+		item.CalculateLandedUnitCost()
+	}
+}
+
+// ValidateAmounts checks that the quantities and amounts are small enough for the stock-in to
+// be totalled, at its exchange rate once that is known
+func (s *StockIn) ValidateAmounts() error {
+	limits := newDocumentLimits()
+	for i := range s.Items {
+		s.Items[i].addLimits(limits)
+	}
+	limits.amounts(s.Total, s.Paid)
+	return limits.check(documentRate(s.Currency, s.ExchangeRate))
+}
+
+// CalculateTotals sets the total from the item subtotals and tax and converts it to the base currency
+func (s *StockIn) CalculateTotals() {
+	s.BindCurrency()
+	total := money.Zero(s.Currency)
+	for i := range s.Items {
+		if s.Items[i].Subtotal.IsZero() {
+			s.Items[i].CalculateSubtotal()
+		}
+		total = total.Add(s.Items[i].Subtotal).Add(s.Items[i].Tax)
+	}
+	s.Total = total
+	s.CalculateBalance()
+	s.ConvertToBase()
+	s.SummarizeTax()
+}
+
+// SummarizeTax totals the items' costs and input tax by tax code
+func (s *StockIn) SummarizeTax() {
+	summary := newTaxSummarizer(s.Currency)
+	for _, item := range s.Items {
+		summary.add(item.LineTax, item.Subtotal, item.Tax)
+	}
+	s.TaxSummary, s.TaxTotal = summary.result()
+}
+
+// ConvertToBase fills the base-currency equivalents using the stock-in's exchange rate
+func (s *StockIn) ConvertToBase() {
+	s.BindCurrency()
+	for i := range s.Items {
+		s.Items[i].ConvertToBase(s.Currency, s.ExchangeRate)
+	}
+	s.BaseTotal = s.ExchangeRate.Convert(s.Total, money.BaseCurrency).Round()
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,17 +9,20 @@ import (
 
 // Supplier represents a supplier/vendor that provides products
 type Supplier struct {
-	ID             string    `json:"id" db:"id"`
-	Name           string    `json:"name" db:"name"`
-	Email          string    `json:"email,omitempty" db:"email"`
-	Phone          string    `json:"phone,omitempty" db:"phone"`
-	Address        string    `json:"address,omitempty" db:"address"`
-	ContactPerson  string    `json:"contact_person,omitempty" db:"contact_person"`
-	TotalPurchases int       `json:"total_purchases" db:"total_purchases"`
-	TotalSpent     float64   `json:"total_spent" db:"total_spent"`
-	LastOrderAt    time.Time `json:"last_order_at,omitempty" db:"last_order_at"`
-	Notes          string    `json:"notes,omitempty" db:"notes"`
-	
+	ID             string      `json:"id" db:"id"`
+	Name           string      `json:"name" db:"name"`
+	Email          string      `json:"email,omitempty" db:"email"`
+	Phone          string      `json:"phone,omitempty" db:"phone"`
+	Address        string      `json:"address,omitempty" db:"address"`
+	ContactPerson  string      `json:"contact_person,omitempty" db:"contact_person"`
+	TotalPurchases int         `json:"total_purchases" db:"total_purchases"`
+	TotalSpent     money.Money `json:"total_spent" db:"total_spent"`
+	LastOrderAt    time.Time   `json:"last_order_at,omitempty" db:"last_order_at"`
+	Notes          string      `json:"notes,omitempty" db:"notes"`
+
+	// How long the supplier allows for payment, used to set the due date of stock-ins
+	PaymentTerms PaymentTerms `json:"payment_terms" db:"payment_terms"`
+
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaxType is how a tax code treats the goods it is assigned to for VAT (PPN)
+type TaxType string
+
+const (
+	// TaxTypeStandard charges VAT at the code's rate
+	TaxTypeStandard TaxType = "standard"
+	// TaxTypeZeroRated charges VAT at 0%, such as exports; input VAT remains creditable
+	TaxTypeZeroRated TaxType = "zero_rated"
+	// TaxTypeExempt is outside VAT, such as basic necessities
+	TaxTypeExempt TaxType = "exempt"
+)
+
+// IsValid reports whether the tax type is known
+func (t TaxType) IsValid() bool {
+	switch t {
+	case TaxTypeStandard, TaxTypeZeroRated, TaxTypeExempt:
+		return true
+	}
+	return false
+}
+
+// TaxMode is whether document prices already include tax
+type TaxMode string
+
+const (
+	// TaxModeExclusive adds tax on top of the price
+	TaxModeExclusive TaxMode = "exclusive"
+	// TaxModeInclusive treats the price as including tax
+	TaxModeInclusive TaxMode = "inclusive"
+)
+
+// IsValid reports whether the tax mode is known
+func (m TaxMode) IsValid() bool {
+	return m == TaxModeExclusive || m == TaxModeInclusive
+}
+
+// TaxCode is a VAT rate that can be assigned to products and categories
+type TaxCode struct {
+	ID          string  `json:"id" db:"id"`
+	Code        string  `json:"code" db:"code"`
+	Name        string  `json:"name" db:"name"`
+	Description string  `json:"description,omitempty" db:"description"`
+	Type        TaxType `json:"type" db:"type"`
+
+	// Rate is a percentage, e.g. 11 for PPN 11%. Zero-rated and exempt codes have no rate.
+	Rate   money.Rate `json:"rate" db:"rate"`
+	Active bool       `json:"active" db:"active"`
+
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// GenerateID sets a UUID if ID is empty
+func (c *TaxCode) GenerateID() {
+	if c.ID == "" {
+		c.ID = uuid.NewString()
+	}
+}
+
+// Validate checks the code, type and rate
+func (c *TaxCode) Validate() error {
+	c.Code = strings.ToUpper(strings.TrimSpace(c.Code))
+	if c.Code == "" {
+		return errors.New("code is required")
+	}
+	if c.Name == "" {
+		c.Name = c.Code
+	}
+	if c.Type == "" {
+		c.Type = TaxTypeStandard
+	}
+	if !c.Type.IsValid() {
+		return fmt.Errorf("type must be %q, %q or %q", TaxTypeStandard, TaxTypeZeroRated, TaxTypeExempt)
+	}
+
+	if c.Type != TaxTypeStandard {
+		if !c.Rate.IsZero() {
+			return fmt.Errorf("%s tax codes cannot have a rate", c.Type)
+		}
+		return nil
+	}
+	if !c.Rate.IsPositive() || c.Rate.Rat().Cmp(big.NewRat(100, 1)) > 0 {
+		return errors.New("rate must be a percentage greater than 0 and at most 100")
+	}
+	return nil
+}
+
+// Split divides an amount into its taxable base (DPP) and tax. In exclusive mode the amount
+// is the taxable base; in inclusive mode it already contains the tax.
+func (c *TaxCode) Split(amount money.Money, mode TaxMode) (taxable, tax money.Money) {
+	return splitTax(amount, c.Rate, mode)
+}
+
+// splitTax divides an amount at a percentage rate, rounding the tax to the currency's minor units
+func splitTax(amount money.Money, rate money.Rate, mode TaxMode) (taxable, tax money.Money) {
+	if !rate.IsPositive() {
+		return amount, money.Zero(amount.Currency())
+	}
+	hundred := big.NewRat(100, 1)
+	if mode == TaxModeInclusive {
+		// tax = amount * rate / (100 + rate)
+		factor := new(big.Rat).Quo(rate.Rat(), new(big.Rat).Add(hundred, rate.Rat()))
+		tax = amount.MulRat(factor).Round()
+		return amount.Sub(tax), tax
+	}
+	tax = amount.MulRat(new(big.Rat).Quo(rate.Rat(), hundred)).Round()
+	return amount, tax
+}
+
+// LineTax is the tax code applied to a document line, copied from the code when the line is
+// priced so later changes to the code do not alter the document
+type LineTax struct {
+	TaxCodeID *string    `json:"tax_code_id,omitempty" db:"tax_code_id"`
+	TaxCode   string     `json:"tax_code,omitempty" db:"tax_code"`
+	TaxRate   money.Rate `json:"tax_rate" db:"tax_rate"`
+}
+
+// SetTaxCode applies a tax code to the line
+func (l *LineTax) SetTaxCode(code *TaxCode) {
+	id := code.ID
+	l.TaxCodeID = &id
+	l.TaxCode = code.Code
+	l.TaxRate = code.Rate
+}
+
+// HasTaxCode reports whether the line's tax is calculated from a tax code rather than given
+func (l *LineTax) HasTaxCode() bool {
+	return l.TaxCodeID != nil && *l.TaxCodeID != ""
+}
+
+// TaxSummaryLine is the taxable base and tax of a document for one tax code and rate
+type TaxSummaryLine struct {
+	TaxCodeID     *string     `json:"tax_code_id,omitempty"`
+	TaxCode       string      `json:"tax_code"`
+	TaxRate       money.Rate  `json:"tax_rate"`
+	TaxableAmount money.Money `json:"taxable_amount"`
+	Tax           money.Money `json:"tax"`
+}
+
+// taxSummarizer accumulates document lines into a tax summary
+type taxSummarizer struct {
+	currency string
+	lines    []TaxSummaryLine
+	index    map[string]int
+	total    money.Money
+}
+
+func newTaxSummarizer(currency string) *taxSummarizer {
+	return &taxSummarizer{currency: currency, lines: []TaxSummaryLine{}, index: map[string]int{}, total: money.Zero(currency)}
+}
+
+// add records a line's taxable base and tax. Lines without a tax code are grouped together.
+func (s *taxSummarizer) add(lt LineTax, taxable, tax money.Money) {
+	key := lt.TaxCode + "|" + lt.TaxRate.String()
+	i, ok := s.index[key]
+	if !ok {
+		s.lines = append(s.lines, TaxSummaryLine{
+			TaxCodeID:     lt.TaxCodeID,
+			TaxCode:       lt.TaxCode,
+			TaxRate:       lt.TaxRate,
+			TaxableAmount: money.Zero(s.currency),
+			Tax:           money.Zero(s.currency),
+		})
+		i = len(s.lines) - 1
+		s.index[key] = i
+	}
+	s.lines[i].TaxableAmount = s.lines[i].TaxableAmount.Add(taxable.In(s.currency))
+	s.lines[i].Tax = s.lines[i].Tax.Add(tax.In(s.currency))
+	s.total = s.total.Add(tax.In(s.currency))
+}
+
+// result returns the summary ordered by tax code and the total tax
+func (s *taxSummarizer) result() ([]TaxSummaryLine, money.Money) {
+	sort.SliceStable(s.lines, func(a, b int) bool {
+		return s.lines[a].TaxCode < s.lines[b].TaxCode
+	})
+	return s.lines, s.total
+}
+
+// VATReportLine totals the VAT of one tax code over a month, in the base currency
+type VATReportLine struct {
+	TaxCodeID     string      `json:"tax_code_id,omitempty"`
+	TaxCode       string      `json:"tax_code"`
+	TaxType       TaxType     `json:"tax_type,omitempty"`
+	TaxRate       money.Rate  `json:"tax_rate"`
+	DocumentCount int         `json:"document_count"`
+	TaxableAmount money.Money `json:"taxable_amount"`
+	Tax           money.Money `json:"tax"`
+}
+
+// VATReport sets output VAT on completed sales against input VAT on completed stock-ins for
+// a month, in the base currency, for the monthly PPN return
+type VATReport struct {
+	Year        int             `json:"year"`
+	Month       int             `json:"month"`
+	StartDate   time.Time       `json:"start_date"`
+	EndDate     time.Time       `json:"end_date"`
+	Currency    string          `json:"currency"`
+	Output      []VATReportLine `json:"output"`
+	Input       []VATReportLine `json:"input"`
+	TotalOutput money.Money     `json:"total_output"`
+	TotalInput  money.Money     `json:"total_input"`
+
+	// NetPayable is output less input VAT; a negative amount is an overpayment to carry forward
+	NetPayable money.Money `json:"net_payable"`
+}
+
+// NewVATReport totals the output and input lines of a month
+func NewVATReport(year, month int, start, end time.Time, output, input []VATReportLine) *VATReport {
+	report := &VATReport{
+		Year:        year,
+		Month:       month,
+		StartDate:   start,
+		EndDate:     end,
+		Currency:    money.BaseCurrency,
+		Output:      output,
+		Input:       input,
+		TotalOutput: money.Zero(money.BaseCurrency),
+		TotalInput:  money.Zero(money.BaseCurrency),
+	}
+	for _, l := range output {
+		report.TotalOutput = report.TotalOutput.Add(l.Tax)
+	}
+	for _, l := range input {
+		report.TotalInput = report.TotalInput.Add(l.Tax)
+	}
+	report.NetPayable = report.TotalOutput.Sub(report.TotalInput)
+	return report
+}
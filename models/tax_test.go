@@ -0,0 +1,109 @@
+package models
+
+import (
+	"inventory-go/money"
+	"testing"
+)
+
+func TestSplitTax(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   money.Money
+		rate     string
+		mode     TaxMode
+		taxable  string
+		tax      string
+		currency string
+	}{
+		{"exclusive", idr("100000"), "11", TaxModeExclusive, "100000", "11000", "IDR"},
+		{"inclusive", idr("111000"), "11", TaxModeInclusive, "100000", "11000", "IDR"},
+		{"exclusive rounds to whole rupiah", idr("12345"), "11", TaxModeExclusive, "12345", "1358", "IDR"},
+		{"inclusive rounds to whole rupiah", idr("12345"), "11", TaxModeInclusive, "11122", "1223", "IDR"},
+		{"exclusive 12%", idr("99999"), "12", TaxModeExclusive, "99999", "12000", "IDR"},
+		{"zero rate exclusive", idr("50000"), "0", TaxModeExclusive, "50000", "0", "IDR"},
+		{"zero rate inclusive", idr("50000"), "0", TaxModeInclusive, "50000", "0", "IDR"},
+		{"cents", money.MustParse("10.05", "USD"), "11", TaxModeExclusive, "10.05", "1.11", "USD"},
+		{"cents inclusive", money.MustParse("10.05", "USD"), "11", TaxModeInclusive, "9.05", "1", "USD"},
+	}
+	for _, tt := range tests {
+		code := TaxCode{Code: "PPN", Rate: rate(tt.rate)}
+		taxable, tax := code.Split(tt.amount, tt.mode)
+		if taxable.String() != tt.taxable || tax.String() != tt.tax {
+			t.Errorf("%s: Split = %s + %s, want %s + %s", tt.name, taxable.String(), tax.String(), tt.taxable, tt.tax)
+		}
+		if tax.Currency() != tt.currency {
+			t.Errorf("%s: tax currency = %s, want %s", tt.name, tax.Currency(), tt.currency)
+		}
+	}
+}
+
+// taxedSaleItems returns two lines at PPN 11%, one with tax given by hand and one exempt
+func taxedSaleItems() []SaleItem {
+	ppn, exempt := "ppn", "exempt"
+	coded := func(id *string, code, r string) LineTax {
+		return LineTax{TaxCodeID: id, TaxCode: code, TaxRate: rate(r)}
+	}
+	return []SaleItem{
+		{ProductID: "a", Quantity: 2, UnitPrice: idr("50000"), LineTax: coded(&ppn, "PPN11", "11")},
+		{ProductID: "b", Quantity: 1, UnitPrice: idr("12345"), LineTax: coded(&ppn, "PPN11", "11")},
+		{ProductID: "c", Quantity: 1, UnitPrice: idr("10000"), Tax: idr("500")},
+		{ProductID: "d", Quantity: 1, UnitPrice: idr("20000"), LineTax: coded(&exempt, "EXEMPT", "0")},
+	}
+}
+
+func TestSaleSummarizeTax(t *testing.T) {
+	type line struct {
+		code    string
+		taxable string
+		tax     string
+	}
+	tests := []struct {
+		mode     TaxMode
+		summary  []line
+		taxTotal string
+		total    string
+	}{
+		{TaxModeExclusive, []line{{"", "10000", "500"}, {"EXEMPT", "20000", "0"}, {"PPN11", "112345", "12358"}}, "12858", "155203"},
+		{TaxModeInclusive, []line{{"", "9500", "500"}, {"EXEMPT", "20000", "0"}, {"PPN11", "101212", "11133"}}, "11633", "142345"},
+	}
+	for _, tt := range tests {
+		s := Sale{Currency: "IDR", TaxMode: tt.mode, Items: taxedSaleItems()}
+		s.CalculateTotals()
+		if s.TaxTotal.String() != tt.taxTotal || s.Total.String() != tt.total {
+			t.Errorf("%s: tax %s, total %s, want %s, %s", tt.mode, s.TaxTotal.String(), s.Total.String(), tt.taxTotal, tt.total)
+		}
+		if len(s.TaxSummary) != len(tt.summary) {
+			t.Errorf("%s: %d summary lines, want %d", tt.mode, len(s.TaxSummary), len(tt.summary))
+			continue
+		}
+		for i, want := range tt.summary {
+			got := s.TaxSummary[i]
+			if got.TaxCode != want.code || got.TaxableAmount.String() != want.taxable || got.Tax.String() != want.tax {
+				t.Errorf("%s: summary %d = %s %s %s, want %s %s %s", tt.mode, i,
+					got.TaxCode, got.TaxableAmount.String(), got.Tax.String(), want.code, want.taxable, want.tax)
+			}
+		}
+	}
+}
+
+func TestStockInSummarizeTax(t *testing.T) {
+	ppn := "ppn"
+	s := StockIn{Currency: "IDR", TaxMode: TaxModeInclusive, Items: []StockInItem{
+		{ProductID: "a", Quantity: 2, UnitCost: idr("55500"), LineTax: LineTax{TaxCodeID: &ppn, TaxCode: "PPN11", TaxRate: rate("11")}},
+		{ProductID: "b", Quantity: 3, UnitCost: idr("1000")},
+	}}
+	for i := range s.Items {
+		s.Items[i].CalculateTax(s.TaxMode)
+	}
+	s.CalculateTotals()
+
+	if got := s.Items[0].UnitCost.String(); got != "50000" {
+		t.Errorf("inclusive unit cost = %s, want 50000", got)
+	}
+	if s.Total.String() != "114000" || s.TaxTotal.String() != "11000" {
+		t.Errorf("total %s, tax %s, want 114000, 11000", s.Total.String(), s.TaxTotal.String())
+	}
+	if len(s.TaxSummary) != 2 || s.TaxSummary[1].TaxCode != "PPN11" || s.TaxSummary[1].TaxableAmount.String() != "100000" {
+		t.Errorf("summary = %+v, want no code then PPN11 on 100000", s.TaxSummary)
+	}
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBaseUnit is the unit stock is counted in for products without unit conversions
+const DefaultBaseUnit = "pcs"
+
+// Unit is a unit of measure documents can be entered in, such as pcs, box, carton or kg
+type Unit struct {
+	ID        string     `json:"id" db:"id"`
+	Code      string     `json:"code" db:"code"`
+	Name      string     `json:"name" db:"name"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+}
+
+// NormalizeUnit returns a unit code trimmed and in lower case
+func NormalizeUnit(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// Validate normalizes the code, defaults the name and gives the unit an ID
+func (u *Unit) Validate() error {
+	u.Code = NormalizeUnit(u.Code)
+	if u.Code == "" {
+		return errors.New("code is required")
+	}
+	if len(u.Code) > 20 {
+		return errors.New("code cannot be longer than 20 characters")
+	}
+	if u.Name == "" {
+		u.Name = u.Code
+	}
+	if u.ID == "" {
+		u.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// ProductUnits is the unit a product's stock is counted in and the other units it is bought
+// or sold in, e.g. pieces with boxes of 6 and cartons of 24
+type ProductUnits struct {
+	ProductID   string           `json:"product_id"`
+	BaseUnit    string           `json:"base_unit"`
+	Conversions []UnitConversion `json:"conversions"`
+}
+
+// UnitConversion is how many base units one of another unit holds
+type UnitConversion struct {
+	Unit   string `json:"unit" db:"unit"`
+	Name   string `json:"name,omitempty" db:"-"`
+	Factor int    `json:"factor" db:"factor"`
+}
+
+// Validate normalizes the unit codes and checks the factors
+func (p *ProductUnits) Validate() error {
+	p.BaseUnit = NormalizeUnit(p.BaseUnit)
+	if p.BaseUnit == "" {
+		p.BaseUnit = DefaultBaseUnit
+	}
+
+	seen := map[string]bool{p.BaseUnit: true}
+	for i := range p.Conversions {
+		c := &p.Conversions[i]
+		c.Unit = NormalizeUnit(c.Unit)
+		if c.Unit == "" {
+			return errors.New("unit is required for all conversions")
+		}
+		if seen[c.Unit] {
+			return fmt.Errorf("unit %s is listed more than once or is the base unit", c.Unit)
+		}
+		seen[c.Unit] = true
+		if c.Factor < 2 {
+			return fmt.Errorf("factor of %s must be at least 2 base units", c.Unit)
+		}
+	}
+	return nil
+}
+
+// Codes returns every unit code used: the base unit and the converted units
+func (p *ProductUnits) Codes() []string {
+	codes := []string{p.BaseUnit}
+	for _, c := range p.Conversions {
+		codes = append(codes, c.Unit)
+	}
+	return codes
+}
+
+// Factor returns how many base units one of the unit holds
+func (p *ProductUnits) Factor(unit string) (int, bool) {
+	if unit == p.BaseUnit {
+		return 1, true
+	}
+	for _, c := range p.Conversions {
+		if c.Unit == unit {
+			return c.Factor, true
+		}
+	}
+	return 0, false
+}
+
+// LineUnit is the unit a document line was entered in. The line's quantity is always in the
+// product's base unit, which is what stock and costs use; the unit quantity is what was
+// entered, e.g. 2 carton for a quantity of 48 pcs. The line's price or cost is per base unit
+// too, but may be entered per unit as the unit amount.
+type LineUnit struct {
+	Unit         string `json:"unit,omitempty" db:"unit"`
+	UnitQuantity int    `json:"unit_quantity,omitempty" db:"unit_quantity"`
+	UnitFactor   int    `json:"unit_factor,omitempty" db:"unit_factor"`
+	BaseUnit     string `json:"base_unit,omitempty" db:"base_unit"`
+
+	// UnitAmount is the price or cost of one of the unit, e.g. per carton. It is not stored:
+	// the line keeps the amount per base unit worked out from it.
+	UnitAmount *money.Money `json:"unit_amount,omitempty" db:"-"`
+}
+
+// Convert sets the line's base quantity from the quantity entered in its unit, and its amount
+// per base unit (a sale item's unit price, or a stock-in or reject item's unit cost) from the
+// unit amount when one is given. A line without a unit is in the base unit. A line in another
+// unit needs its unit quantity. A line given both quantities, or both amounts, is refused
+// unless they agree.
+func (l *LineUnit) Convert(units *ProductUnits, quantity *int, amount *money.Money) error {
+	l.Unit = NormalizeUnit(l.Unit)
+	if l.Unit == "" {
+		l.Unit = units.BaseUnit
+	}
+	factor, ok := units.Factor(l.Unit)
+	if !ok {
+		return fmt.Errorf("unit %s is not set up for this product", l.Unit)
+	}
+	if l.UnitQuantity < 0 {
+		return errors.New("unit_quantity cannot be negative")
+	}
+
+	switch {
+	case l.UnitQuantity > 0:
+		if *quantity != 0 && *quantity != l.UnitQuantity*factor {
+			return fmt.Errorf("quantity %d does not match %d %s of %d %s", *quantity, l.UnitQuantity, l.Unit, factor, units.BaseUnit)
+		}
+		*quantity = l.UnitQuantity * factor
+	case factor == 1:
+		l.UnitQuantity = *quantity
+	default:
+		return fmt.Errorf("unit_quantity is required for quantities in %s", l.Unit)
+	}
+	l.UnitFactor = factor
+	l.BaseUnit = units.BaseUnit
+
+	if l.UnitAmount == nil {
+		return nil
+	}
+	if l.UnitAmount.IsNegative() {
+		return errors.New("unit_amount cannot be negative")
+	}
+	perBase := l.UnitAmount.Div(int64(factor)).In(amount.Currency())
+	if !amount.IsZero() && amount.Cmp(perBase) != 0 && amount.Mul(int64(factor)).Cmp(*l.UnitAmount) != 0 {
+		return fmt.Errorf("unit_amount %s per %s does not match %s per %s",
+			l.UnitAmount.String(), l.Unit, amount.String(), units.BaseUnit)
+	}
+	*amount = perBase
+	return nil
+}
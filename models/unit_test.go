@@ -0,0 +1,78 @@
+package models
+
+import (
+	"inventory-go/money"
+	"testing"
+)
+
+func TestLineUnitConvert(t *testing.T) {
+	units := &ProductUnits{ProductID: "p", BaseUnit: "pcs", Conversions: []UnitConversion{
+		{Unit: "box", Factor: 6},
+		{Unit: "carton", Factor: 24},
+	}}
+	tests := []struct {
+		name         string
+		unit         string
+		unitQuantity int
+		quantity     int
+		unitAmount   string // empty when not given
+		amount       string
+		err          bool
+		wantQuantity int
+		wantFactor   int
+		wantAmount   string
+	}{
+		{"no unit is the base unit", "", 0, 5, "", "2500", false, 5, 1, "2500"},
+		{"base unit named", " PCS ", 0, 7, "", "2500", false, 7, 1, "2500"},
+		{"base unit by unit quantity", "pcs", 3, 0, "", "2500", false, 3, 1, "2500"},
+		{"carton", "carton", 2, 0, "", "2500", false, 48, 24, "2500"},
+		{"carton with its quantity", "carton", 2, 48, "", "2500", false, 48, 24, "2500"},
+		{"quantity does not match", "carton", 2, 40, "", "2500", true, 0, 0, ""},
+		{"unknown unit", "pallet", 1, 0, "", "2500", true, 0, 0, ""},
+		{"unit quantity required", "carton", 0, 48, "", "2500", true, 0, 0, ""},
+		{"negative unit quantity", "carton", -1, 0, "", "2500", true, 0, 0, ""},
+		{"price per carton", "carton", 2, 0, "60000", "0", false, 48, 24, "2500"},
+		{"price per box", "box", 1, 0, "10000", "0", false, 6, 6, "1666.6667"},
+		{"price per base unit", "pcs", 4, 0, "2500", "0", false, 4, 1, "2500"},
+		{"both prices agree", "carton", 2, 0, "60000", "2500", false, 48, 24, "2500"},
+		{"both prices agree per box", "box", 1, 0, "10000", "1666.6667", false, 6, 6, "1666.6667"},
+		{"prices do not agree", "carton", 2, 0, "60000", "2400", true, 0, 0, ""},
+		{"negative price per unit", "carton", 2, 0, "-60000", "0", true, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		l := LineUnit{Unit: tt.unit, UnitQuantity: tt.unitQuantity}
+		if tt.unitAmount != "" {
+			unitAmount := idr(tt.unitAmount)
+			l.UnitAmount = &unitAmount
+		}
+		quantity, amount := tt.quantity, idr(tt.amount)
+		err := l.Convert(units, &quantity, &amount)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: Convert() = nil, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Convert() = %v", tt.name, err)
+			continue
+		}
+		if quantity != tt.wantQuantity || l.UnitFactor != tt.wantFactor || amount.String() != tt.wantAmount {
+			t.Errorf("%s: quantity %d, factor %d, amount %s, want %d, %d, %s", tt.name,
+				quantity, l.UnitFactor, amount.String(), tt.wantQuantity, tt.wantFactor, tt.wantAmount)
+		}
+		if l.UnitQuantity*l.UnitFactor != quantity || l.BaseUnit != "pcs" || l.Unit == "" {
+			t.Errorf("%s: line = %+v for a quantity of %d", tt.name, l, quantity)
+		}
+	}
+}
+
+func TestLineUnitConvertKeepsCurrency(t *testing.T) {
+	units := &ProductUnits{BaseUnit: "pcs", Conversions: []UnitConversion{{Unit: "box", Factor: 4}}}
+	unitAmount := money.MustParse("10", "")
+	l := LineUnit{Unit: "box", UnitQuantity: 1, UnitAmount: &unitAmount}
+	quantity, amount := 0, money.Zero("USD")
+	if err := l.Convert(units, &quantity, &amount); err != nil || amount.String() != "2.5" || amount.Currency() != "USD" {
+		t.Errorf("Convert() = %v, amount %s %s, want 2.5 USD", err, amount.String(), amount.Currency())
+	}
+}
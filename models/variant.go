@@ -0,0 +1,258 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ProductOption is an option type a parent product's variants differ by, such as Color or Size
+type ProductOption struct {
+	ID        string               `json:"id" db:"id"`
+	ProductID string               `json:"product_id" db:"product_id"`
+	Name      string               `json:"name" db:"name"`
+	SortOrder int                  `json:"sort_order" db:"sort_order"`
+	Values    []ProductOptionValue `json:"values"`
+}
+
+// ProductOptionValue is one value of an option, such as Red or XL
+type ProductOptionValue struct {
+	ID       string `json:"id" db:"id"`
+	OptionID string `json:"option_id" db:"option_id"`
+	Value    string `json:"value" db:"value"`
+	// Code stands for the value in generated SKUs; it defaults to the value in upper case
+	Code      string `json:"code" db:"code"`
+	SortOrder int    `json:"sort_order" db:"sort_order"`
+}
+
+// VariantOption is the value a variant has for one of its parent's options
+type VariantOption struct {
+	OptionID string `json:"option_id" db:"option_id"`
+	Option   string `json:"option" db:"option"`
+	ValueID  string `json:"value_id" db:"value_id"`
+	Value    string `json:"value" db:"value"`
+}
+
+// Variant is a variant product and the option values that distinguish it from its siblings
+type Variant struct {
+	Product
+	Options []VariantOption `json:"options"`
+}
+
+// VariantGeneration asks for a variant for every combination of a parent product's option
+// values. Patterns use {sku} and {name} for the parent and {option name} for a value, e.g.
+// "{sku}-{color}-{size}"; option values stand in the SKU by their code and in the name as is.
+type VariantGeneration struct {
+	SKUPattern  string `json:"sku_pattern"`
+	NamePattern string `json:"name_pattern"`
+}
+
+// VariantGenerationResult lists the variants generated and how many combinations already had one
+type VariantGenerationResult struct {
+	Created []Variant `json:"created"`
+	Skipped int       `json:"skipped"`
+}
+
+var skuCodeCleaner = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// ValidateProductOptions normalizes a parent product's options and their values, numbering
+// them in the order given
+func ValidateProductOptions(productID string, options []ProductOption) error {
+	names := map[string]bool{}
+	for i := range options {
+		o := &options[i]
+		o.Name = strings.TrimSpace(o.Name)
+		if o.Name == "" {
+			return errors.New("name is required for all options")
+		}
+		key := strings.ToLower(o.Name)
+		if names[key] {
+			return fmt.Errorf("option %s is listed more than once", o.Name)
+		}
+		names[key] = true
+		if len(o.Values) == 0 {
+			return fmt.Errorf("option %s must have at least one value", o.Name)
+		}
+		o.ProductID = productID
+		o.SortOrder = i
+
+		values := map[string]bool{}
+		for j := range o.Values {
+			v := &o.Values[j]
+			v.Value = strings.TrimSpace(v.Value)
+			if v.Value == "" {
+				return fmt.Errorf("values of option %s cannot be empty", o.Name)
+			}
+			if values[strings.ToLower(v.Value)] {
+				return fmt.Errorf("value %s of option %s is listed more than once", v.Value, o.Name)
+			}
+			values[strings.ToLower(v.Value)] = true
+			v.Code = strings.Trim(skuCodeCleaner.ReplaceAllString(strings.ToUpper(v.Code), "-"), "-")
+			if v.Code == "" {
+				v.Code = strings.Trim(skuCodeCleaner.ReplaceAllString(strings.ToUpper(v.Value), "-"), "-")
+			}
+			v.SortOrder = j
+		}
+	}
+	return nil
+}
+
+// MatchProductOptions keeps the IDs of the current options and values the new ones name, so
+// variants stay bound to them, and gives the others a new ID
+func MatchProductOptions(current, options []ProductOption) {
+	for i := range options {
+		o := &options[i]
+		var match *ProductOption
+		for j := range current {
+			if current[j].ID == o.ID || strings.EqualFold(current[j].Name, o.Name) {
+				match = &current[j]
+				break
+			}
+		}
+		o.ID = uuid.NewString()
+		if match != nil {
+			o.ID = match.ID
+		}
+
+		for k := range o.Values {
+			v := &o.Values[k]
+			id := uuid.NewString()
+			if match != nil {
+				for _, cv := range match.Values {
+					if cv.ID == v.ID || strings.EqualFold(cv.Value, v.Value) {
+						id = cv.ID
+						break
+					}
+				}
+			}
+			v.ID = id
+			v.OptionID = o.ID
+		}
+	}
+}
+
+// OptionMatrix returns every combination of the options' values, one value per option in
+// option order
+func OptionMatrix(options []ProductOption) [][]ProductOptionValue {
+	if len(options) == 0 {
+		return nil
+	}
+	combos := [][]ProductOptionValue{{}}
+	for _, o := range options {
+		next := make([][]ProductOptionValue, 0, len(combos)*len(o.Values))
+		for _, combo := range combos {
+			for _, v := range o.Values {
+				c := make([]ProductOptionValue, len(combo), len(combo)+1)
+				copy(c, combo)
+				next = append(next, append(c, v))
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// VariantKey identifies a combination of option values; a parent has one variant per key
+func VariantKey(values []ProductOptionValue) string {
+	ids := make([]string, len(values))
+	for i, v := range values {
+		ids[i] = v.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+// ResolveVariantOptions finds the option values named by option, e.g. {"Color": "Red"}. Every
+// option must be given a value, and the values are returned in option order.
+func ResolveVariantOptions(options []ProductOption, named map[string]string) ([]ProductOptionValue, error) {
+	if len(options) == 0 {
+		return nil, errors.New("parent product has no options")
+	}
+	given := make(map[string]string, len(named))
+	for name, value := range named {
+		given[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	if len(given) != len(options) {
+		return nil, fmt.Errorf("a value is required for each of the %d options and no others", len(options))
+	}
+
+	values := make([]ProductOptionValue, 0, len(options))
+	for _, o := range options {
+		value, ok := given[strings.ToLower(o.Name)]
+		if !ok {
+			return nil, fmt.Errorf("a value is required for option %s", o.Name)
+		}
+		found := false
+		for _, v := range o.Values {
+			if strings.EqualFold(v.Value, value) {
+				values = append(values, v)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not a value of option %s", value, o.Name)
+		}
+	}
+	return values, nil
+}
+
+// DefaultSKUPattern is the parent's SKU followed by each option's value code
+func DefaultSKUPattern(options []ProductOption) string {
+	parts := []string{"{sku}"}
+	for _, o := range options {
+		parts = append(parts, "{"+o.Name+"}")
+	}
+	return strings.Join(parts, "-")
+}
+
+// DefaultNamePattern is the parent's name followed by each option's value
+func DefaultNamePattern(options []ProductOption) string {
+	parts := make([]string, len(options))
+	for i, o := range options {
+		parts[i] = "{" + o.Name + "}"
+	}
+	return "{name} " + strings.Join(parts, " / ")
+}
+
+var patternToken = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// ExpandVariantPattern fills in a SKU or name pattern for a combination of option values. In
+// a SKU the values stand by their code.
+func ExpandVariantPattern(pattern string, parent BasicInfo, options []ProductOption, values []ProductOptionValue, sku bool) (string, error) {
+	var unknown string
+	expanded := patternToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		name := strings.ToLower(strings.TrimSpace(token[1 : len(token)-1]))
+		switch name {
+		case "sku":
+			return parent.SKU
+		case "name":
+			return parent.Name
+		}
+		for i, o := range options {
+			if strings.ToLower(o.Name) == name {
+				if sku {
+					return values[i].Code
+				}
+				return values[i].Value
+			}
+		}
+		unknown = token
+		return token
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("pattern %q uses %s, which is not {sku}, {name} or an option", pattern, unknown)
+	}
+	return strings.TrimSpace(expanded), nil
+}
+
+// VariantAttributes returns the variant's option values keyed by option name
+func VariantAttributes(options []ProductOption, values []ProductOptionValue) map[string]string {
+	attributes := make(map[string]string, len(values))
+	for i, v := range values {
+		attributes[options[i].Name] = v.Value
+	}
+	return attributes
+}
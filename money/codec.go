@@ -0,0 +1,128 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MarshalJSON encodes the amount as a JSON number so existing API clients keep working
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number, a quoted decimal string or null.
+// The currency already set on the receiver is kept.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{currency: m.currency}
+		return nil
+	}
+	s, err := unquoteJSON(data)
+	if err != nil {
+		return err
+	}
+	parsed, err := Parse(s, m.currency)
+	if err != nil {
+		return err
+	}
+	m.amount = parsed.amount
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner so NUMERIC columns scan exactly
+func (m *Money) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*m = Money{currency: m.currency}
+		return nil
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("money: cannot scan non-finite numeric")
+	}
+	r := numericToRat(v)
+	amount, err := ratToAmount(r)
+	if err != nil {
+		return err
+	}
+	m.amount = amount
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(m.amount), Exp: -Scale, Valid: true}, nil
+}
+
+// Scan implements sql.Scanner. It is used for text values such as
+// amounts extracted from JSONB columns with ->>.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{currency: m.currency}
+		return nil
+	case string:
+		return m.scanString(v)
+	case []byte:
+		return m.scanString(string(v))
+	case int64:
+		m.amount = v * scaleFactor
+		return nil
+	case float64:
+		return m.scanString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := Parse(s, m.currency)
+	if err != nil {
+		return err
+	}
+	m.amount = parsed.amount
+	return nil
+}
+
+// Value implements driver.Valuer
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// numericToRat converts a finite pgtype.Numeric to an exact rational
+func numericToRat(v pgtype.Numeric) *big.Rat {
+	r := new(big.Rat).SetInt(v.Int)
+	if v.Exp != 0 {
+		p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(v.Exp))), nil)
+		if v.Exp > 0 {
+			r.Mul(r, new(big.Rat).SetInt(p))
+		} else {
+			r.Quo(r, new(big.Rat).SetInt(p))
+		}
+	}
+	return r
+}
+
+// unquoteJSON strips quotes from a JSON string value, leaving numbers untouched
+func unquoteJSON(data []byte) (string, error) {
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("money: invalid JSON string %s", s)
+		}
+		return unquoted, nil
+	}
+	return s, nil
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package money
+
+import "strings"
+
+// DefaultCurrency is the base currency used when an amount has no currency attached
+const DefaultCurrency = "IDR"
+
+// BaseCurrency is the currency that documents are converted to for reporting
+const BaseCurrency = DefaultCurrency
+
+// minorUnits maps ISO 4217 currency codes to the number of decimal places
+// used when rounding amounts in that currency. IDR is deliberately treated as
+// having no minor units since sen are no longer used in practice.
+var minorUnits = map[string]int{
+	"IDR": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"USD": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"SGD": 2,
+	"MYR": 2,
+	"AUD": 2,
+	"HKD": 2,
+	"THB": 2,
+}
+
+// NormalizeCurrency upper-cases a currency code and falls back to DefaultCurrency when empty
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// Decimals returns the number of minor-unit decimal places for a currency.
+// Unknown currencies are rounded to two decimal places.
+func Decimals(code string) int {
+	if d, ok := minorUnits[NormalizeCurrency(code)]; ok {
+		return d
+	}
+	return 2
+}
+
+// IsKnownCurrency reports whether the currency code has explicit rounding rules
+func IsKnownCurrency(code string) bool {
+	_, ok := minorUnits[NormalizeCurrency(code)]
+	return ok
+}
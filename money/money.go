@@ -0,0 +1,357 @@
+// Package money provides an exact decimal amount type used for prices, costs
+// and document totals. Amounts are stored as fixed-point integers so that
+// arithmetic never suffers from binary floating point error.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Scale is the number of decimal places kept internally for every amount.
+// Amounts are rounded to their currency's minor units by Round.
+const Scale = 4
+
+const scaleFactor int64 = 10000
+
+// ErrOverflow is returned when an amount does not fit in the fixed-point range. Arithmetic
+// that overflows panics with it, as a wrapped-around amount would be silently wrong.
+var ErrOverflow = errors.New("money: amount out of range")
+
+// MaxAmount is the largest amount, in major units, accepted as input on documents. It is far
+// inside the fixed-point range, so the sums, tax and conversions worked out from amounts
+// within it cannot overflow.
+const MaxAmount = 10_000_000_000_000
+
+// ErrCurrencyMismatch is the panic value of adding or subtracting amounts in different
+// currencies. Convert one of them first.
+var ErrCurrencyMismatch = errors.New("money: currency mismatch")
+
+// Money is an exact decimal amount in a given currency
+type Money struct {
+	amount   int64 // amount * 10^Scale
+	currency string
+}
+
+// New creates an amount from a count of the currency's minor units
+// (e.g. cents for USD, rupiah for IDR)
+func New(minor int64, currency string) Money {
+	currency = NormalizeCurrency(currency)
+	return Money{amount: minor * pow10(Scale-Decimals(currency)), currency: currency}
+}
+
+// FromInt creates an amount from a whole number of major units
+func FromInt(major int64, currency string) Money {
+	return Money{amount: major * scaleFactor, currency: NormalizeCurrency(currency)}
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency string) Money {
+	return Money{currency: NormalizeCurrency(currency)}
+}
+
+// decimalPattern matches the numbers Parse and ParseRate accept: digits with an optional sign,
+// fraction and exponent. big.Rat alone also accepts fractions such as "1/3" and exponents
+// large enough to exhaust memory.
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d{1,3})?$`)
+
+// parseDecimal parses a decimal string into an exact rational
+func parseDecimal(s string) (*big.Rat, bool) {
+	if !decimalPattern.MatchString(s) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// Parse parses a decimal string such as "15000", "-12.50" or "1.5e3".
+// Digits beyond Scale decimal places are rounded half away from zero.
+func Parse(s string, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Zero(currency), nil
+	}
+	r, ok := parseDecimal(s)
+	if !ok {
+		return Money{}, fmt.Errorf("money: invalid amount %q", s)
+	}
+	amount, err := ratToAmount(r)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{amount: amount, currency: NormalizeCurrency(currency)}, nil
+}
+
+// MustParse is like Parse but panics on invalid input. It is intended for constants.
+func MustParse(s string, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Currency returns the amount's currency code
+func (m Money) Currency() string {
+	return NormalizeCurrency(m.currency)
+}
+
+// In returns the same amount labelled with another currency. It does not convert.
+func (m Money) In(currency string) Money {
+	return Money{amount: m.amount, currency: NormalizeCurrency(currency)}
+}
+
+// Add returns m + o. It panics when both are non-zero amounts in different currencies.
+func (m Money) Add(o Money) Money {
+	return Money{amount: add(m.amount, o.amount), currency: pickCurrency(m, o)}
+}
+
+// Sub returns m - o. It panics when both are non-zero amounts in different currencies.
+func (m Money) Sub(o Money) Money {
+	return Money{amount: add(m.amount, -o.amount), currency: pickCurrency(m, o)}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{amount: -m.amount, currency: m.currency}
+}
+
+// Abs returns the absolute value of m
+func (m Money) Abs() Money {
+	if m.amount < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Mul returns m multiplied by an integer quantity
+func (m Money) Mul(n int64) Money {
+	return Money{amount: checked(new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(n))), currency: m.currency}
+}
+
+// MulRatio returns m * num / den, rounded half away from zero to Scale places.
+// It is used for percentages and proportional allocation.
+func (m Money) MulRatio(num, den int64) Money {
+	if den == 0 {
+		return Money{currency: m.currency}
+	}
+	v := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(num))
+	return Money{amount: checked(roundDiv(v, big.NewInt(den))), currency: m.currency}
+}
+
+// MulRat returns m multiplied by an exact rational factor such as an exchange rate
+func (m Money) MulRat(r *big.Rat) Money {
+	if r == nil {
+		return m
+	}
+	v := new(big.Int).Mul(big.NewInt(m.amount), r.Num())
+	return Money{amount: checked(roundDiv(v, r.Denom())), currency: m.currency}
+}
+
+// Div returns m / n rounded half away from zero to Scale places
+func (m Money) Div(n int64) Money {
+	return m.MulRatio(1, n)
+}
+
+// Round rounds the amount to the currency's minor units, half away from zero
+func (m Money) Round() Money {
+	return m.RoundTo(Decimals(m.Currency()))
+}
+
+// RoundTo rounds the amount to the given number of decimal places, half away from zero
+func (m Money) RoundTo(decimals int) Money {
+	if decimals >= Scale {
+		return m
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	unit := pow10(Scale - decimals)
+	q := roundDiv(big.NewInt(m.amount), big.NewInt(unit)).Int64()
+	return Money{amount: q * unit, currency: m.currency}
+}
+
+// Minor returns the amount in the currency's minor units after rounding
+func (m Money) Minor() int64 {
+	return m.Round().amount / pow10(Scale-Decimals(m.Currency()))
+}
+
+// Cmp compares m and o and returns -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.amount < o.amount:
+		return -1
+	case m.amount > o.amount:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool { return m.amount == 0 }
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool { return m.amount < 0 }
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool { return m.amount > 0 }
+
+// Rat returns the amount as an exact rational number
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.amount), big.NewInt(scaleFactor))
+}
+
+// Ratio returns m / o as an exact rational, or nil when o is zero
+func (m Money) Ratio(o Money) *big.Rat {
+	if o.amount == 0 {
+		return nil
+	}
+	return new(big.Rat).SetFrac(big.NewInt(m.amount), big.NewInt(o.amount))
+}
+
+// String formats the amount as a plain decimal without trailing zeros
+func (m Money) String() string {
+	return formatFixed(m.amount, Scale)
+}
+
+// StringFixed formats the amount with exactly the currency's minor-unit decimals
+func (m Money) StringFixed() string {
+	d := Decimals(m.Currency())
+	r := m.Round()
+	s := r.String()
+	if d == 0 {
+		return s
+	}
+	dot := strings.IndexByte(s, '.')
+	if dot < 0 {
+		return s + "." + strings.Repeat("0", d)
+	}
+	return s + strings.Repeat("0", d-(len(s)-dot-1))
+}
+
+// Sum adds up a list of amounts
+func Sum(amounts ...Money) Money {
+	var total Money
+	for _, a := range amounts {
+		total = total.Add(a)
+	}
+	return total
+}
+
+// ExceedsMax reports whether the absolute value of an exact amount is above MaxAmount
+func ExceedsMax(r *big.Rat) bool {
+	return new(big.Rat).Abs(r).Cmp(big.NewRat(MaxAmount, 1)) > 0
+}
+
+// Min returns the smaller of two amounts
+func Min(a, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of two amounts
+func Max(a, b Money) Money {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// pickCurrency returns the currency of the result of adding a and b. An amount with no
+// currency yet, or a zero amount, takes the other's currency; otherwise they must match.
+func pickCurrency(a, b Money) string {
+	switch {
+	case a.currency == b.currency || b.currency == "":
+		return a.currency
+	case a.currency == "":
+		return b.currency
+	case NormalizeCurrency(a.currency) == NormalizeCurrency(b.currency):
+		return NormalizeCurrency(a.currency)
+	case b.amount == 0:
+		return a.currency
+	case a.amount == 0:
+		return b.currency
+	}
+	panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a.Currency(), b.Currency()))
+}
+
+// add returns a + b, panicking with ErrOverflow when the sum does not fit
+func add(a, b int64) int64 {
+	sum := a + b
+	if (a > 0 && b > 0 && sum < 0) || (a < 0 && b < 0 && sum >= 0) {
+		panic(ErrOverflow)
+	}
+	return sum
+}
+
+// checked returns v as a fixed-point amount, panicking with ErrOverflow when it does not fit
+func checked(v *big.Int) int64 {
+	if !v.IsInt64() || v.Int64() == math.MinInt64 {
+		panic(ErrOverflow)
+	}
+	return v.Int64()
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// roundDiv divides a by b rounding half away from zero
+func roundDiv(a, b *big.Int) *big.Int {
+	if b.Sign() < 0 {
+		a = new(big.Int).Neg(a)
+		b = new(big.Int).Neg(b)
+	}
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(b) >= 0 {
+		if a.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func ratToAmount(r *big.Rat) (int64, error) {
+	return ratToFixed(r, scaleFactor)
+}
+
+// ratToFixed converts r to a fixed-point integer with the given factor
+func ratToFixed(r *big.Rat, factor int64) (int64, error) {
+	v := new(big.Int).Mul(r.Num(), big.NewInt(factor))
+	q := roundDiv(v, r.Denom())
+	if !q.IsInt64() || q.Int64() == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+	return q.Int64(), nil
+}
+
+// formatFixed formats a fixed-point integer with the given scale, trimming trailing zeros
+func formatFixed(v int64, scale int) string {
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign = "-"
+		u = uint64(-(v + 1)) + 1
+	}
+	factor := uint64(pow10(scale))
+	whole := u / factor
+	frac := u % factor
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	fs := strings.TrimRight(fmt.Sprintf("%0*d", scale, frac), "0")
+	return fmt.Sprintf("%s%d.%s", sign, whole, fs)
+}
@@ -0,0 +1,279 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"15000", "15000", false},
+		{"-12.50", "-12.5", false},
+		{"1.5e3", "1500", false},
+		{"  7.25 ", "7.25", false},
+		{"", "0", false},
+		{"0.00005", "0.0001", false},
+		{"-0.00005", "-0.0001", false},
+		{"0.00004", "0", false},
+		{"abc", "", true},
+		{"1e30", "", true},
+		{"1/3", "", true},
+		{"1e99999", "", true},
+		{"0x10", "", true},
+		{".5", "0.5", false},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, "IDR")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got.String(), tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     string
+		fixed    string
+	}{
+		{"1234.5", "IDR", "1235", "1235"},
+		{"1234.4999", "IDR", "1234", "1234"},
+		{"-1234.5", "IDR", "-1235", "-1235"},
+		{"12.345", "USD", "12.35", "12.35"},
+		{"12.344", "USD", "12.34", "12.34"},
+		{"-12.345", "USD", "-12.35", "-12.35"},
+		{"12", "USD", "12", "12.00"},
+		{"12.5", "USD", "12.5", "12.50"},
+		{"99.5", "JPY", "100", "100"},
+		{"1.005", "XYZ", "1.01", "1.01"},
+		{"10", "", "10", "10"},
+	}
+	for _, tt := range tests {
+		m := MustParse(tt.in, tt.currency)
+		if got := m.Round().String(); got != tt.want {
+			t.Errorf("%s %s Round() = %s, want %s", tt.in, tt.currency, got, tt.want)
+		}
+		if got := m.StringFixed(); got != tt.fixed {
+			t.Errorf("%s %s StringFixed() = %s, want %s", tt.in, tt.currency, got, tt.fixed)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{Zero("IDR"), "0"},
+		{New(150, "USD"), "1.5"},
+		{New(-5, "USD"), "-0.05"},
+		{New(15000, "IDR"), "15000"},
+		{FromInt(-3, "IDR"), "-3"},
+		{Money{amount: math.MaxInt64}, "922337203685477.5807"},
+		{Money{amount: -math.MaxInt64}, "-922337203685477.5807"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want string
+	}{
+		{"add", MustParse("10.25", "USD").Add(MustParse("0.75", "USD")), "11"},
+		{"sub", MustParse("10", "USD").Sub(MustParse("10.01", "USD")), "-0.01"},
+		{"mul", MustParse("1.2345", "USD").Mul(3), "3.7035"},
+		{"mul ratio", MustParse("100", "IDR").MulRatio(1, 3), "33.3333"},
+		{"mul ratio halves away from zero", MustParse("-0.0003", "IDR").MulRatio(1, 2), "-0.0002"},
+		{"mul rat", MustParse("2", "USD").MulRat(big.NewRat(31, 2)), "31"},
+		{"div", MustParse("10", "IDR").Div(4), "2.5"},
+		{"sum", Sum(MustParse("1", "IDR"), MustParse("2", "IDR"), MustParse("3.5", "IDR")), "6.5"},
+	}
+	for _, tt := range tests {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got.String(), tt.want)
+		}
+	}
+}
+
+func TestCurrencies(t *testing.T) {
+	usd := MustParse("5", "USD")
+	tests := []struct {
+		name     string
+		a, b     Money
+		currency string
+	}{
+		{"same", usd, MustParse("1", "usd"), "USD"},
+		{"unset adopts", Money{}, usd, "USD"},
+		{"adopts unset", usd, Money{amount: 10000}, "USD"},
+		{"zero adopts", Zero("IDR"), usd, "USD"},
+		{"adopts zero", usd, Zero("IDR"), "USD"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Add(tt.b).Currency(); got != tt.currency {
+			t.Errorf("%s: Add currency = %s, want %s", tt.name, got, tt.currency)
+		}
+		if got := tt.a.Sub(tt.b).Currency(); got != tt.currency {
+			t.Errorf("%s: Sub currency = %s, want %s", tt.name, got, tt.currency)
+		}
+	}
+
+	var total Money
+	for _, m := range []Money{usd, usd, Zero("")} {
+		total = total.Add(m)
+	}
+	if total.String() != "10" || total.Currency() != "USD" {
+		t.Errorf("accumulated total = %s %s, want 10 USD", total.String(), total.Currency())
+	}
+}
+
+func TestPanics(t *testing.T) {
+	huge := Money{amount: math.MaxInt64 / 2, currency: "IDR"}
+	tests := []struct {
+		name string
+		want error
+		fn   func()
+	}{
+		{"add currencies", ErrCurrencyMismatch, func() { MustParse("1", "USD").Add(MustParse("1", "IDR")) }},
+		{"sub currencies", ErrCurrencyMismatch, func() { MustParse("1", "USD").Sub(MustParse("1", "EUR")) }},
+		{"add overflow", ErrOverflow, func() { huge.Add(huge).Add(huge) }},
+		{"sub overflow", ErrOverflow, func() { huge.Neg().Sub(huge).Sub(huge) }},
+		{"mul overflow", ErrOverflow, func() { MustParse("1000000000", "IDR").Mul(1_000_000) }},
+		{"mul ratio overflow", ErrOverflow, func() { huge.MulRatio(3, 1) }},
+		{"mul rat overflow", ErrOverflow, func() { huge.MulRat(big.NewRat(5, 2)) }},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				p := recover()
+				err, ok := p.(error)
+				if !ok || !errors.Is(err, tt.want) {
+					t.Errorf("%s: panic = %v, want %v", tt.name, p, tt.want)
+				}
+			}()
+			tt.fn()
+		}()
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	if r, err := ParseRate("16250.5"); err != nil || r.String() != "16250.5" {
+		t.Errorf("ParseRate(16250.5) = %s, %v", r.String(), err)
+	}
+	if _, err := ParseRate("32501/2"); err == nil {
+		t.Error("ParseRate(32501/2) succeeded, want an error")
+	}
+}
+
+func TestExceedsMax(t *testing.T) {
+	tests := []struct {
+		in   *big.Rat
+		want bool
+	}{
+		{big.NewRat(MaxAmount, 1), false},
+		{big.NewRat(-MaxAmount, 1), false},
+		{new(big.Rat).Add(big.NewRat(MaxAmount, 1), big.NewRat(1, 10000)), true},
+		{big.NewRat(-MaxAmount-1, 1), true},
+	}
+	for _, tt := range tests {
+		if got := ExceedsMax(tt.in); got != tt.want {
+			t.Errorf("ExceedsMax(%s) = %v, want %v", tt.in.FloatString(4), got, tt.want)
+		}
+	}
+
+	// Amounts within the limit leave room for adding up many of them and for tax on top
+	if got := FromInt(MaxAmount, "IDR").Mul(80).MulRatio(112, 100).String(); got != "896000000000000" {
+		t.Errorf("MaxAmount * 80 * 1.12 = %s, want 896000000000000", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     string
+		out      string
+	}{
+		{`12.5`, "USD", "12.5", `12.5`},
+		{`"15000.25"`, "IDR", "15000.25", `15000.25`},
+		{`null`, "USD", "0", `0`},
+		{`-0.0001`, "IDR", "-0.0001", `-0.0001`},
+	}
+	for _, tt := range tests {
+		m := Zero(tt.currency)
+		if err := json.Unmarshal([]byte(tt.in), &m); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if m.String() != tt.want || m.Currency() != tt.currency {
+			t.Errorf("Unmarshal(%s) = %s %s, want %s %s", tt.in, m.String(), m.Currency(), tt.want, tt.currency)
+		}
+		out, err := json.Marshal(m)
+		if err != nil || string(out) != tt.out {
+			t.Errorf("Marshal(%s) = %s, %v, want %s", tt.in, out, err, tt.out)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"twelve"`), &m); err == nil {
+		t.Error("Unmarshal of an invalid amount succeeded")
+	}
+}
+
+func TestScanNumeric(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      pgtype.Numeric
+		want    string
+		wantErr bool
+	}{
+		{"scale 4", pgtype.Numeric{Int: big.NewInt(123456), Exp: -4, Valid: true}, "12.3456", false},
+		{"scale 2", pgtype.Numeric{Int: big.NewInt(-1250), Exp: -2, Valid: true}, "-12.5", false},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(15), Exp: 3, Valid: true}, "15000", false},
+		{"extra digits round", pgtype.Numeric{Int: big.NewInt(123455), Exp: -5, Valid: true}, "1.2346", false},
+		{"null", pgtype.Numeric{}, "0", false},
+		{"nan", pgtype.Numeric{NaN: true, Valid: true}, "", true},
+		{"too large", pgtype.Numeric{Int: big.NewInt(1), Exp: 30, Valid: true}, "", true},
+	}
+	for _, tt := range tests {
+		m := Zero("USD")
+		err := m.ScanNumeric(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ScanNumeric error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (m.String() != tt.want || m.Currency() != "USD") {
+			t.Errorf("%s: ScanNumeric = %s %s, want %s USD", tt.name, m.String(), m.Currency(), tt.want)
+		}
+	}
+
+	// A value written and scanned back is unchanged
+	in := MustParse("-98765.4321", "IDR")
+	v, err := in.NumericValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Money
+	if err := out.ScanNumeric(v); err != nil || out.Cmp(in) != 0 {
+		t.Errorf("NumericValue round trip = %s, %v, want %s", out.String(), err, in.String())
+	}
+}
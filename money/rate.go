@@ -0,0 +1,146 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RateScale is the number of decimal places kept for exchange rates
+const RateScale = 8
+
+const rateFactor int64 = 100000000
+
+// Rate is an exchange rate expressed as base-currency units per one unit of
+// a foreign currency (e.g. 16250.5 IDR per USD)
+type Rate struct {
+	value int64 // rate * 10^RateScale
+}
+
+// OneRate returns the identity rate used for documents in the base currency
+func OneRate() Rate {
+	return Rate{value: rateFactor}
+}
+
+// ParseRate parses a decimal exchange rate such as "16250.5"
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Rate{}, nil
+	}
+	r, ok := parseDecimal(s)
+	if !ok {
+		return Rate{}, fmt.Errorf("money: invalid exchange rate %q", s)
+	}
+	v, err := ratToFixed(r, rateFactor)
+	if err != nil {
+		return Rate{}, err
+	}
+	return Rate{value: v}, nil
+}
+
+// IsZero reports whether the rate is unset
+func (r Rate) IsZero() bool { return r.value == 0 }
+
+// IsPositive reports whether the rate is greater than zero
+func (r Rate) IsPositive() bool { return r.value > 0 }
+
+// Rat returns the rate as an exact rational number
+func (r Rate) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(r.value), big.NewInt(rateFactor))
+}
+
+// Convert converts an amount into the base currency using this rate
+func (r Rate) Convert(m Money, base string) Money {
+	return m.MulRat(r.Rat()).In(base)
+}
+
+// String formats the rate as a plain decimal without trailing zeros
+func (r Rate) String() string {
+	return formatFixed(r.value, RateScale)
+}
+
+// MarshalJSON encodes the rate as a JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number, a quoted decimal string or null
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*r = Rate{}
+		return nil
+	}
+	s, err := unquoteJSON(data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner
+func (r *Rate) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*r = Rate{}
+		return nil
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("money: cannot scan non-finite numeric")
+	}
+	value, err := ratToFixed(numericToRat(v), rateFactor)
+	if err != nil {
+		return err
+	}
+	r.value = value
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(r.value), Exp: -RateScale, Valid: true}, nil
+}
+
+// Scan implements sql.Scanner
+func (r *Rate) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		return r.scanString(v)
+	case []byte:
+		return r.scanString(string(v))
+	case int64:
+		r.value = v * rateFactor
+		return nil
+	case float64:
+		return r.scanString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("money: cannot scan %T into rate", src)
+	}
+}
+
+func (r *Rate) scanString(s string) error {
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value implements driver.Valuer
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
@@ -0,0 +1,164 @@
+package printing
+
+import (
+	"inventory-go/models"
+	"inventory-go/money"
+	"io"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// mmPerPoint converts font sizes, in points, to millimetres
+const mmPerPoint = 25.4 / 72
+
+// maxModuleWidth keeps barcodes on wide labels from growing larger than scanners need
+const maxModuleWidth = 0.5
+
+// minBarHeight is the shortest barcode, in millimetres, worth printing
+const minBarHeight = 3
+
+// WriteLabels writes a PDF of label sheets in the template's layout, one label after another
+// across and then down each sheet. The first skip positions of the first sheet stay empty.
+func WriteLabels(w io.Writer, template models.LabelTemplate, labels []models.Label, skip int) error {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           gofpdf.SizeType{Wd: template.PageWidth, Ht: template.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Labels", true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	perPage := template.LabelsPerPage()
+	skip %= perPage
+	for i, label := range labels {
+		position := (i + skip) % perPage
+		if i == 0 || position == 0 {
+			pdf.AddPage()
+		}
+		col, row := position%template.Columns, position/template.Columns
+		x := template.MarginLeft + float64(col)*(template.LabelWidth+template.GapX)
+		y := template.MarginTop + float64(row)*(template.LabelHeight+template.GapY)
+		drawLabel(pdf, tr, x, y, template.LabelWidth, template.LabelHeight, label)
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	return pdf.Output(w)
+}
+
+// drawLabel draws the name across the top, the barcode with its code in the middle, and the
+// SKU and price along the bottom. Font sizes follow the label height.
+func drawLabel(pdf *gofpdf.Fpdf, tr func(string) string, x, y, width, height float64, label models.Label) {
+	pad := clamp(height*0.06, 0.5, 1.5)
+	innerWidth := width - 2*pad
+	nameSize := clamp(height*0.3, 5, 10)
+	priceSize := clamp(height*0.45, 6, 14)
+	smallSize := clamp(height*0.2, 4, 7)
+	nameHeight := nameSize * mmPerPoint * 1.2
+	priceHeight := priceSize * mmPerPoint * 1.2
+	smallHeight := smallSize * mmPerPoint * 1.2
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", nameSize)
+	pdf.SetXY(x+pad, y+pad)
+	pdf.CellFormat(innerWidth, nameHeight, fitText(pdf, tr(label.Name), innerWidth), "", 0, "L", false, 0, "")
+
+	bottom := y + height - pad - priceHeight
+	price := tr(FormatPrice(label.Price))
+	pdf.SetFont("Helvetica", "B", priceSize)
+	priceWidth := pdf.GetStringWidth(price)
+	pdf.SetXY(x+pad, bottom)
+	pdf.CellFormat(innerWidth, priceHeight, price, "", 0, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", smallSize)
+	pdf.SetXY(x+pad, bottom)
+	pdf.CellFormat(innerWidth-priceWidth-1, priceHeight, fitText(pdf, tr(label.SKU), innerWidth-priceWidth-1), "", 0, "L", false, 0, "")
+
+	top := y + pad + nameHeight + 0.5
+	barHeight := bottom - 0.5 - smallHeight - top
+	symbol := labelSymbol(label)
+	if symbol == nil || barHeight < minBarHeight {
+		return
+	}
+
+	module := innerWidth / float64(symbol.Width())
+	if module > maxModuleWidth {
+		module = maxModuleWidth
+	}
+	left := x + (width-module*float64(symbol.Width()))/2
+	pdf.SetFillColor(0, 0, 0)
+	symbol.Bars(func(start, bars int) {
+		pdf.Rect(left+float64(start)*module, top, float64(bars)*module, barHeight, "F")
+	})
+	pdf.SetXY(x+pad, top+barHeight)
+	pdf.CellFormat(innerWidth, smallHeight, tr(symbol.Code), "", 0, "C", false, 0, "")
+}
+
+// labelSymbol encodes the label's barcode, or its SKU as Code 128 when it has none. It returns
+// nil if neither can be encoded.
+func labelSymbol(label models.Label) *Symbol {
+	code, symbology := label.Barcode, label.Symbology
+	if code == "" {
+		code, symbology = label.SKU, models.SymbologyCode128
+	}
+	if code == "" {
+		return nil
+	}
+	symbol, err := Encode(code, symbology)
+	if err != nil {
+		return nil
+	}
+	return symbol
+}
+
+// fitText shortens s with an ellipsis until it fits the width in the current font
+func fitText(pdf *gofpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSpace(s) + "..."
+}
+
+// FormatPrice formats a price the Indonesian way, e.g. Rp 15.000 or USD 12,50
+func FormatPrice(price money.Money) string {
+	s := price.StringFixed()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(digit)
+	}
+	if fraction != "" {
+		grouped.WriteString("," + fraction)
+	}
+
+	symbol := price.Currency()
+	if symbol == "IDR" {
+		symbol = "Rp"
+	}
+	return sign + symbol + " " + grouped.String()
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrDuplicateBarcode is returned when a code is already assigned to a product
+var ErrDuplicateBarcode = errors.New("barcode is already assigned")
+
+// ErrBarcodeNotFound is returned when a product has no barcode with the given ID
+var ErrBarcodeNotFound = errors.New("barcode not found")
+
+// BarcodeRepository defines methods for the barcodes products and variants are scanned by
+type BarcodeRepository interface {
+	// GetByProduct returns a product's barcodes, the primary one first
+	GetByProduct(productID string) ([]models.Barcode, error)
+	// FindByCode finds the product a scanned code belongs to, by its barcodes and then by SKU.
+	// It returns nil if no product has the code.
+	FindByCode(code string) (*models.BarcodeMatch, error)
+	// Add assigns a barcode to a product. A product's first barcode becomes its primary one.
+	Add(barcode *models.Barcode) error
+	// GenerateInternal assigns the next internal EAN-13 code to a product
+	GenerateInternal(productID string, primary bool) (*models.Barcode, error)
+	// GenerateMissing assigns an internal EAN-13 code to every product without a barcode
+	GenerateMissing() ([]models.Barcode, error)
+	// Delete removes a product's barcode; if it was the primary one, the oldest remaining
+	// barcode becomes primary
+	Delete(productID, id string) error
+}
+
+// BarcodeRepositoryImpl implements the BarcodeRepository interface
+type BarcodeRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewBarcodeRepository creates a new BarcodeRepository
+func NewBarcodeRepository(db *pgx.Conn) BarcodeRepository {
+	return &BarcodeRepositoryImpl{db: db}
+}
+
+const barcodeColumns = `id, product_id, code, symbology, source, is_primary, created_at, updated_at`
+
+func scanBarcode(row pgx.Row) (*models.Barcode, error) {
+	var barcode models.Barcode
+	err := row.Scan(&barcode.ID, &barcode.ProductID, &barcode.Code, &barcode.Symbology, &barcode.Source,
+		&barcode.IsPrimary, &barcode.CreatedAt, &barcode.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &barcode, nil
+}
+
+// GetByProduct retrieves a product's barcodes, the primary one first and then oldest first
+func (r *BarcodeRepositoryImpl) GetByProduct(productID string) ([]models.Barcode, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT `+barcodeColumns+` FROM product_barcodes
+		WHERE product_id = $1
+		ORDER BY is_primary DESC, created_at ASC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query barcodes: %w", err)
+	}
+	defer rows.Close()
+
+	barcodes := []models.Barcode{}
+	for rows.Next() {
+		barcode, err := scanBarcode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan barcode: %w", err)
+		}
+		barcodes = append(barcodes, *barcode)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating barcodes: %w", err)
+	}
+
+	return barcodes, nil
+}
+
+// FindByCode matches the code against barcodes, as either EAN-13 or UPC-A, and then against
+// product SKUs
+func (r *BarcodeRepositoryImpl) FindByCode(code string) (*models.BarcodeMatch, error) {
+	ctx := context.Background()
+
+	barcode, err := scanBarcode(r.db.QueryRow(ctx, `
+		SELECT b.id, b.product_id, b.code, b.symbology, b.source, b.is_primary, b.created_at, b.updated_at
+		FROM product_barcodes b JOIN products p ON p.id = b.product_id AND p.deleted_at IS NULL
+		WHERE b.code = ANY($1)
+		LIMIT 1`, models.BarcodeLookupCodes(code)))
+	if err == nil {
+		return &models.BarcodeMatch{ProductID: barcode.ProductID, Barcode: barcode}, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to find barcode: %w", err)
+	}
+
+	var productID string
+	err = r.db.QueryRow(ctx, `
+		SELECT id FROM products WHERE basic->>'sku' = $1 AND deleted_at IS NULL LIMIT 1`, code).Scan(&productID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find product by SKU: %w", err)
+	}
+	return &models.BarcodeMatch{ProductID: productID}, nil
+}
+
+// Add inserts a validated barcode for a product
+func (r *BarcodeRepositoryImpl) Add(barcode *models.Barcode) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertBarcode(ctx, tx, barcode); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GenerateInternal assigns a product the next internal code
+func (r *BarcodeRepositoryImpl) GenerateInternal(productID string, primary bool) (*models.Barcode, error) {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	barcode, err := generateInternalBarcode(ctx, tx, productID, primary)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return barcode, nil
+}
+
+// GenerateMissing assigns internal codes to the products and variants that have no barcode
+func (r *BarcodeRepositoryImpl) GenerateMissing() ([]models.Barcode, error) {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT p.id FROM products p
+		WHERE p.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM product_barcodes b WHERE b.product_id = p.id)
+		ORDER BY p.created_at ASC, p.id ASC
+		FOR UPDATE`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products without barcodes: %w", err)
+	}
+	var productIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		productIDs = append(productIDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating products: %w", err)
+	}
+
+	created := []models.Barcode{}
+	for _, productID := range productIDs {
+		barcode, err := generateInternalBarcode(ctx, tx, productID, true)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, *barcode)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// Delete removes a barcode and promotes another one if it was primary
+func (r *BarcodeRepositoryImpl) Delete(productID, id string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var wasPrimary bool
+	err = tx.QueryRow(ctx, `
+		DELETE FROM product_barcodes WHERE id = $1 AND product_id = $2
+		RETURNING is_primary`, id, productID).Scan(&wasPrimary)
+	if err == pgx.ErrNoRows {
+		return ErrBarcodeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete barcode: %w", err)
+	}
+
+	if wasPrimary {
+		_, err := tx.Exec(ctx, `
+			UPDATE product_barcodes SET is_primary = TRUE
+			WHERE id = (SELECT id FROM product_barcodes WHERE product_id = $1 ORDER BY created_at ASC LIMIT 1)`,
+			productID)
+		if err != nil {
+			return fmt.Errorf("failed to set primary barcode: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// generateInternalBarcode takes the next number from the internal barcode sequence, skipping
+// codes that were entered by hand
+func generateInternalBarcode(ctx context.Context, tx pgx.Tx, productID string, primary bool) (*models.Barcode, error) {
+	var code string
+	for {
+		var sequence int64
+		if err := tx.QueryRow(ctx, `SELECT nextval('internal_barcode_seq')`).Scan(&sequence); err != nil {
+			return nil, fmt.Errorf("failed to get internal barcode number: %w", err)
+		}
+		var err error
+		if code, err = models.InternalEAN13(sequence); err != nil {
+			return nil, err
+		}
+
+		var taken bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM product_barcodes WHERE code = $1)`, code).Scan(&taken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check barcode: %w", err)
+		}
+		if !taken {
+			break
+		}
+	}
+
+	barcode := &models.Barcode{
+		ProductID: productID,
+		Code:      code,
+		Symbology: models.SymbologyEAN13,
+		Source:    models.BarcodeSourceInternal,
+		IsPrimary: primary,
+	}
+	if err := barcode.Validate(); err != nil {
+		return nil, err
+	}
+	if err := insertBarcode(ctx, tx, barcode); err != nil {
+		return nil, err
+	}
+	return barcode, nil
+}
+
+// insertBarcode refuses a code already assigned, also in its EAN-13 or UPC-A form, keeps a
+// single primary barcode per product and inserts the barcode
+func insertBarcode(ctx context.Context, tx pgx.Tx, barcode *models.Barcode) error {
+	// Serialize barcode changes of the product
+	var locked string
+	err := tx.QueryRow(ctx, `SELECT id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		barcode.ProductID).Scan(&locked)
+	if err == pgx.ErrNoRows {
+		return errors.New("product not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	var taken bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM product_barcodes WHERE code = ANY($1))`,
+		models.BarcodeLookupCodes(barcode.Code)).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check barcode: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: %s", ErrDuplicateBarcode, barcode.Code)
+	}
+
+	var hasPrimary bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM product_barcodes WHERE product_id = $1 AND is_primary)`,
+		barcode.ProductID).Scan(&hasPrimary)
+	if err != nil {
+		return fmt.Errorf("failed to check primary barcode: %w", err)
+	}
+	if !hasPrimary {
+		barcode.IsPrimary = true
+	} else if barcode.IsPrimary {
+		_, err := tx.Exec(ctx, `UPDATE product_barcodes SET is_primary = FALSE WHERE product_id = $1 AND is_primary`,
+			barcode.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to unset primary barcode: %w", err)
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO product_barcodes (id, product_id, code, symbology, source, is_primary)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at`,
+		barcode.ID, barcode.ProductID, barcode.Code, barcode.Symbology, barcode.Source, barcode.IsPrimary,
+	).Scan(&barcode.CreatedAt, &barcode.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert barcode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidBOM is returned when a bill of materials names components that do not exist
+var ErrInvalidBOM = errors.New("invalid bill of materials")
+
+// BOMRepository defines methods for bills of materials
+type BOMRepository interface {
+	GetAll() ([]models.BillOfMaterials, error)
+	// GetByProductID returns a product's bill of materials with its cost rolled up from the
+	// components' current average cost, or nil when it has none
+	GetByProductID(productID string) (*models.BillOfMaterials, error)
+	// Save creates or replaces a product's bill of materials
+	Save(bom *models.BillOfMaterials) error
+	Delete(productID string) error
+}
+
+// BOMRepositoryImpl implements the BOMRepository interface
+type BOMRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewBOMRepository creates a new BOMRepository
+func NewBOMRepository(db *pgx.Conn) BOMRepository {
+	return &BOMRepositoryImpl{db: db}
+}
+
+// GetAll retrieves every bill of materials with its estimated cost
+func (r *BOMRepositoryImpl) GetAll() ([]models.BillOfMaterials, error) {
+	return r.queryBOMs(context.Background(), ``)
+}
+
+// GetByProductID retrieves a product's bill of materials
+func (r *BOMRepositoryImpl) GetByProductID(productID string) (*models.BillOfMaterials, error) {
+	boms, err := r.queryBOMs(context.Background(), `AND b.product_id = $1`, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(boms) == 0 {
+		return nil, nil
+	}
+	return &boms[0], nil
+}
+
+func (r *BOMRepositoryImpl) queryBOMs(ctx context.Context, condition string, args ...any) ([]models.BillOfMaterials, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT b.id, b.product_id, COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''),
+			b.output_quantity, b.yield_percent, COALESCE(b.note, ''), b.created_at, b.updated_at
+		FROM boms b
+		JOIN products p ON p.id = b.product_id AND p.deleted_at IS NULL
+		WHERE b.deleted_at IS NULL `+condition+`
+		ORDER BY p.basic->>'name'`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bills of materials: %w", err)
+	}
+
+	boms := []models.BillOfMaterials{}
+	index := map[string]int{}
+	for rows.Next() {
+		var bom models.BillOfMaterials
+		err := rows.Scan(&bom.ID, &bom.ProductID, &bom.Name, &bom.SKU,
+			&bom.OutputQuantity, &bom.YieldPercent, &bom.Note, &bom.CreatedAt, &bom.UpdatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan bill of materials: %w", err)
+		}
+		index[bom.ID] = len(boms)
+		bom.Components = []models.BOMComponent{}
+		boms = append(boms, bom)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bills of materials: %w", err)
+	}
+	if len(boms) == 0 {
+		return boms, nil
+	}
+
+	ids := make([]string, len(boms))
+	for i, bom := range boms {
+		ids[i] = bom.ID
+	}
+	rows, err = r.db.Query(ctx, `
+		SELECT bc.id, bc.bom_id, bc.component_id, bc.quantity, bc.waste_percent,
+			COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''), COALESCE(p.stock, 0),
+			COALESCE(pc.average_cost, 0)
+		FROM bom_components bc
+		LEFT JOIN products p ON p.id = bc.component_id AND p.deleted_at IS NULL
+		LEFT JOIN product_costs pc ON pc.product_id = bc.component_id
+		WHERE bc.bom_id = ANY($1)
+		ORDER BY bc.sort_order, bc.id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bill of materials components: %w", err)
+	}
+	defer rows.Close()
+
+	costs := map[string]money.Money{}
+	for rows.Next() {
+		var c models.BOMComponent
+		var cost money.Money
+		err := rows.Scan(&c.ID, &c.BOMID, &c.ComponentID, &c.Quantity, &c.WastePercent,
+			&c.Name, &c.SKU, &c.Stock, &cost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bill of materials component: %w", err)
+		}
+		costs[c.ComponentID] = cost
+		bom := &boms[index[c.BOMID]]
+		bom.Components = append(bom.Components, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bill of materials components: %w", err)
+	}
+
+	for i := range boms {
+		boms[i].RollUp(costs)
+	}
+	return boms, nil
+}
+
+// Save stores the bill of materials, replacing the product's current one and its components
+func (r *BOMRepositoryImpl) Save(bom *models.BillOfMaterials) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	componentIDs := make([]string, len(bom.Components))
+	for i, c := range bom.Components {
+		componentIDs[i] = c.ComponentID
+	}
+	var found int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM products WHERE id = ANY($1) AND deleted_at IS NULL`,
+		componentIDs).Scan(&found)
+	if err != nil {
+		return fmt.Errorf("failed to check bill of materials components: %w", err)
+	}
+	if found != len(componentIDs) {
+		return fmt.Errorf("%w: component product not found", ErrInvalidBOM)
+	}
+
+	// Keep the ID of the product's current bill so assemblies made from it still refer to it
+	var existingID string
+	err = tx.QueryRow(ctx, `SELECT id FROM boms WHERE product_id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		bom.ProductID).Scan(&existingID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to get bill of materials: %w", err)
+	}
+
+	now := time.Now()
+	bom.UpdatedAt = now
+	if existingID != "" {
+		bom.ID = existingID
+		err = tx.QueryRow(ctx, `
+			UPDATE boms SET output_quantity = $1, yield_percent = $2, note = $3, updated_at = $4
+			WHERE id = $5
+			RETURNING created_at`,
+			bom.OutputQuantity, bom.YieldPercent, bom.Note, now, bom.ID).Scan(&bom.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update bill of materials: %w", err)
+		}
+		if _, err = tx.Exec(ctx, `DELETE FROM bom_components WHERE bom_id = $1`, bom.ID); err != nil {
+			return fmt.Errorf("failed to replace bill of materials components: %w", err)
+		}
+	} else {
+		bom.CreatedAt = now
+		_, err = tx.Exec(ctx, `
+			INSERT INTO boms (id, product_id, output_quantity, yield_percent, note, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			bom.ID, bom.ProductID, bom.OutputQuantity, bom.YieldPercent, bom.Note, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert bill of materials: %w", err)
+		}
+	}
+
+	for i, c := range bom.Components {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO bom_components (id, bom_id, component_id, quantity, waste_percent, sort_order)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			c.ID, bom.ID, c.ComponentID, c.Quantity, c.WastePercent, i)
+		if err != nil {
+			return fmt.Errorf("failed to insert bill of materials component: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes a product's bill of materials
+func (r *BOMRepositoryImpl) Delete(productID string) error {
+	_, err := r.db.Exec(context.Background(),
+		`UPDATE boms SET deleted_at = $1 WHERE product_id = $2 AND deleted_at IS NULL`, time.Now(), productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete bill of materials: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"inventory-go/models"
 	"time"
@@ -18,11 +20,29 @@ type CategoryRepository interface {
 	GetByParentID(parentID *string) ([]models.Category, error)
 	Create(category *models.Category) error
 	Update(category *models.Category) error
-	Delete(id string) error
+	// Delete removes a category. Its subcategories and products move to reassignTo; without
+	// one, a category that has any is not deleted.
+	Delete(id string, reassignTo string) error
 	GetWithChildren(id string) (*models.Category, error)
 	GetBreadcrumbs(id string) ([]models.Breadcrumb, error)
+
+	// Tree maintenance
+	GetTree() ([]*models.CategoryTreeNode, error)
+	Move(id string, move models.CategoryMove) error
+	Reorder(reorder models.CategoryReorder) error
 }
 
+var (
+	// ErrCategoryNotFound is returned when a category, parent or reassignment target does not exist
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrCategoryCycle is returned when a category would end up below itself
+	ErrCategoryCycle = errors.New("a category cannot be placed under itself or its subcategories")
+	// ErrCategoryInUse is returned when deleting a category that still has subcategories or products
+	ErrCategoryInUse = errors.New("category has subcategories or products; reassign them to another category")
+	// ErrInvalidCategoryOrder is returned when a reorder lists a category that is not a sibling
+	ErrInvalidCategoryOrder = errors.New("invalid category order")
+)
+
 type CategoryRepositoryImpl struct {
 	*BaseRepository
 }
@@ -36,7 +56,7 @@ func NewCategoryRepository(db *pgx.Conn) CategoryRepository {
 // GetAll retrieves all categories
 func (r *CategoryRepositoryImpl) GetAll() ([]models.Category, error) {
 	var categories []models.Category
-	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, 
+	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, 
 	          created_at, updated_at 
 	          FROM categories 
 	          WHERE deleted_at IS NULL
@@ -52,7 +72,7 @@ func (r *CategoryRepositoryImpl) GetAll() ([]models.Category, error) {
 		var category models.Category
 		err := rows.Scan(
 			&category.ID, &category.Name, &category.Slug, &category.Description,
-			&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder,
+			&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder, &category.TaxCodeID,
 			&category.CreatedAt, &category.UpdatedAt,
 		)
 		if err != nil {
@@ -73,14 +93,14 @@ func (r *CategoryRepositoryImpl) GetByID(id string) (*models.Category, error) {
 	var category models.Category
 	var deletedAt *time.Time
 
-	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, 
+	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, 
 	          created_at, updated_at, deleted_at 
 	          FROM categories 
 	          WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.QueryRow(context.Background(), query, id).Scan(
 		&category.ID, &category.Name, &category.Slug, &category.Description,
-		&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder,
+		&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder, &category.TaxCodeID,
 		&category.CreatedAt, &category.UpdatedAt, &deletedAt,
 	)
 
@@ -99,14 +119,14 @@ func (r *CategoryRepositoryImpl) GetBySlug(slug string) (*models.Category, error
 	var category models.Category
 	var deletedAt *time.Time
 
-	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, 
+	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, 
 	          created_at, updated_at, deleted_at 
 	          FROM categories 
 	          WHERE slug = $1 AND deleted_at IS NULL`
 
 	err := r.db.QueryRow(context.Background(), query, slug).Scan(
 		&category.ID, &category.Name, &category.Slug, &category.Description,
-		&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder,
+		&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder, &category.TaxCodeID,
 		&category.CreatedAt, &category.UpdatedAt, &deletedAt,
 	)
 
@@ -127,13 +147,13 @@ func (r *CategoryRepositoryImpl) GetByParentID(parentID *string) ([]models.Categ
 	var err error
 
 	if parentID == nil {
-		query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, 
+		query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, 
 		          created_at, updated_at 
 		          FROM categories 
 		          WHERE parent_id IS NULL AND deleted_at IS NULL`
 		rows, err = r.db.Query(context.Background(), query)
 	} else {
-		query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, 
+		query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, 
 		          created_at, updated_at 
 		          FROM categories 
 		          WHERE parent_id = $1 AND deleted_at IS NULL`
@@ -149,7 +169,7 @@ func (r *CategoryRepositoryImpl) GetByParentID(parentID *string) ([]models.Categ
 		var category models.Category
 		err := rows.Scan(
 			&category.ID, &category.Name, &category.Slug, &category.Description,
-			&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder,
+			&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder, &category.TaxCodeID,
 			&category.CreatedAt, &category.UpdatedAt,
 		)
 		if err != nil {
@@ -189,15 +209,36 @@ func (r *CategoryRepositoryImpl) Create(category *models.Category) error {
 	category.CreatedAt = now
 	category.UpdatedAt = now
 
-	query := `INSERT INTO categories (id, name, slug, description, parent_id, image_url, status, sort_order, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if category.ParentID != nil {
+		if err := lockCategory(ctx, tx, *category.ParentID); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO categories (id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	          RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRow(context.Background(), query,
+	err = tx.QueryRow(ctx, query,
 		category.ID, category.Name, category.Slug, category.Description,
 		category.ParentID, category.ImageURL, category.Status, category.SortOrder,
-		category.CreatedAt, category.UpdatedAt,
+		category.TaxCodeID, category.CreatedAt, category.UpdatedAt,
 	).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	if err := refreshBreadcrumbs(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // Update updates an existing category
@@ -228,38 +269,108 @@ func (r *CategoryRepositoryImpl) Update(category *models.Category) error {
 		}
 	}
 
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A new parent must exist and must not be below the category
+	if category.ParentID != nil && (existing.ParentID == nil || *existing.ParentID != *category.ParentID) {
+		if err := checkCategoryParent(ctx, tx, category.ID, *category.ParentID); err != nil {
+			return err
+		}
+	}
+
 	query := `UPDATE categories 
 	          SET name = $1, slug = $2, description = $3, parent_id = $4, 
-	              image_url = $5, status = $6, sort_order = $7, updated_at = $8
-	          WHERE id = $9
+	              image_url = $5, status = $6, sort_order = $7, tax_code_id = $8, updated_at = $9
+	          WHERE id = $10
 	          RETURNING updated_at`
 
-	return r.db.QueryRow(context.Background(), query,
+	err = tx.QueryRow(ctx, query,
 		category.Name, category.Slug, category.Description,
 		category.ParentID, category.ImageURL, category.Status, category.SortOrder,
-		category.UpdatedAt, category.ID,
+		category.TaxCodeID, category.UpdatedAt, category.ID,
 	).Scan(&category.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	if err := refreshBreadcrumbs(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-// Delete deletes a category by its ID
-func (r *CategoryRepositoryImpl) Delete(id string) error {
-	query := `UPDATE categories SET deleted_at = NOW() WHERE id = $1`
-	_, err := r.db.Exec(context.Background(), query, id)
-	return err
+// Delete deletes a category by its ID, first moving its subcategories and products to
+// reassignTo when given
+func (r *CategoryRepositoryImpl) Delete(id string, reassignTo string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockCategory(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if reassignTo == "" {
+		var inUse bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1 AND deleted_at IS NULL)
+				OR EXISTS (SELECT 1 FROM products WHERE child_category_id = $1 AND deleted_at IS NULL)`,
+			id).Scan(&inUse)
+		if err != nil {
+			return fmt.Errorf("failed to check category usage: %w", err)
+		}
+		if inUse {
+			return ErrCategoryInUse
+		}
+	} else {
+		// The subcategories move under the target, so it must not be below the category
+		if err := checkCategoryParent(ctx, tx, id, reassignTo); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE categories SET parent_id = $2, updated_at = NOW()
+			WHERE parent_id = $1 AND deleted_at IS NULL`, id, reassignTo)
+		if err != nil {
+			return fmt.Errorf("failed to reassign subcategories: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE products SET child_category_id = $2, updated_at = NOW()
+			WHERE child_category_id = $1 AND deleted_at IS NULL`, id, reassignTo)
+		if err != nil {
+			return fmt.Errorf("failed to reassign products: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE categories SET deleted_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	if err := refreshBreadcrumbs(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // GetWithChildren retrieves a category by its ID and its children
 func (r *CategoryRepositoryImpl) GetWithChildren(id string) (*models.Category, error) {
 	// Get the parent category
 	var category models.Category
-	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, 
+	query := `SELECT id, name, slug, description, parent_id, image_url, status, sort_order, tax_code_id, 
 	          created_at, updated_at 
 	          FROM categories 
 	          WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.QueryRow(context.Background(), query, id).Scan(
 		&category.ID, &category.Name, &category.Slug, &category.Description,
-		&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder,
+		&category.ParentID, &category.ImageURL, &category.Status, &category.SortOrder, &category.TaxCodeID,
 		&category.CreatedAt, &category.UpdatedAt,
 	)
 
@@ -284,6 +395,21 @@ func (r *CategoryRepositoryImpl) GetWithChildren(id string) (*models.Category, e
 
 // GetBreadcrumbs retrieves the breadcrumbs for a category by its ID
 func (r *CategoryRepositoryImpl) GetBreadcrumbs(id string) ([]models.Breadcrumb, error) {
+	// The breadcrumbs are kept on the category; walk up the tree only when they are missing
+	var stored []byte
+	err := r.db.QueryRow(context.Background(),
+		`SELECT breadcrumbs FROM categories WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&stored)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("error getting category %s: %w", id, err)
+	}
+	if stored != nil {
+		var breadcrumbs []models.Breadcrumb
+		if err := json.Unmarshal(stored, &breadcrumbs); err != nil {
+			return nil, fmt.Errorf("failed to decode breadcrumbs: %w", err)
+		}
+		return breadcrumbs, nil
+	}
+
 	var breadcrumbs []models.Breadcrumb
 	currentID := id
 	level := 0
@@ -329,3 +455,231 @@ func (r *CategoryRepositoryImpl) GetBreadcrumbs(id string) ([]models.Breadcrumb,
 
 	return breadcrumbs, nil
 }
+
+// GetTree returns every category as a tree, siblings in sort order, with the products in each
+// category and in everything below it
+func (r *CategoryRepositoryImpl) GetTree() ([]*models.CategoryTreeNode, error) {
+	query := `
+		WITH RECURSIVE closure AS (
+			SELECT id AS ancestor_id, id FROM categories WHERE deleted_at IS NULL
+			UNION
+			SELECT cl.ancestor_id, c.id
+			FROM categories c JOIN closure cl ON c.parent_id = cl.id
+			WHERE c.deleted_at IS NULL
+		),
+		direct AS (
+			SELECT child_category_id AS category_id, COUNT(*) AS products
+			FROM products
+			WHERE deleted_at IS NULL AND child_category_id IS NOT NULL
+				AND NOT COALESCE((basic->>'is_variant')::boolean, FALSE)
+			GROUP BY child_category_id
+		),
+		total AS (
+			SELECT cl.ancestor_id AS category_id, SUM(d.products) AS products
+			FROM closure cl JOIN direct d ON d.category_id = cl.id
+			GROUP BY cl.ancestor_id
+		)
+		SELECT c.id, c.name, c.slug, COALESCE(c.description, ''), c.parent_id, COALESCE(c.image_url, ''),
+			c.status, c.sort_order, c.tax_code_id, c.created_at, c.updated_at,
+			COALESCE(d.products, 0), COALESCE(t.products, 0)::bigint
+		FROM categories c
+		LEFT JOIN direct d ON d.category_id = c.id
+		LEFT JOIN total t ON t.category_id = c.id
+		WHERE c.deleted_at IS NULL
+		ORDER BY c.sort_order ASC, c.name ASC`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category tree: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*models.CategoryTreeNode
+	for rows.Next() {
+		node := &models.CategoryTreeNode{}
+		err := rows.Scan(
+			&node.ID, &node.Name, &node.Slug, &node.Description,
+			&node.ParentID, &node.ImageURL, &node.Status, &node.SortOrder, &node.TaxCodeID,
+			&node.CreatedAt, &node.UpdatedAt, &node.ProductCount, &node.TotalProductCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	return models.BuildCategoryTree(nodes), nil
+}
+
+// Move puts a category, with its subcategories, under a new parent or at the root
+func (r *CategoryRepositoryImpl) Move(id string, move models.CategoryMove) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockCategory(ctx, tx, id); err != nil {
+		return err
+	}
+	if move.ParentID != nil {
+		if err := checkCategoryParent(ctx, tx, id, *move.ParentID); err != nil {
+			return err
+		}
+	}
+
+	sortOrder := 0
+	if move.SortOrder != nil {
+		sortOrder = *move.SortOrder
+	} else {
+		err := tx.QueryRow(ctx, `
+			SELECT COALESCE(MAX(sort_order) + 1, 0) FROM categories
+			WHERE parent_id IS NOT DISTINCT FROM $1 AND id <> $2 AND deleted_at IS NULL`,
+			move.ParentID, id).Scan(&sortOrder)
+		if err != nil {
+			return fmt.Errorf("failed to get sort order: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE categories SET parent_id = $2, sort_order = $3, updated_at = NOW()
+		WHERE id = $1`, id, move.ParentID, sortOrder)
+	if err != nil {
+		return fmt.Errorf("failed to move category: %w", err)
+	}
+
+	if err := refreshBreadcrumbs(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Reorder numbers the subcategories of a parent in the order given. Subcategories not listed
+// keep their order after the listed ones.
+func (r *CategoryRepositoryImpl) Reorder(reorder models.CategoryReorder) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM categories
+		WHERE parent_id IS NOT DISTINCT FROM $1 AND deleted_at IS NULL
+		ORDER BY sort_order ASC, name ASC
+		FOR UPDATE`, reorder.ParentID)
+	if err != nil {
+		return fmt.Errorf("failed to query sibling categories: %w", err)
+	}
+	var siblings []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan category: %w", err)
+		}
+		siblings = append(siblings, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	isSibling := make(map[string]bool, len(siblings))
+	for _, id := range siblings {
+		isSibling[id] = true
+	}
+	order := make([]string, 0, len(siblings))
+	listed := make(map[string]bool, len(reorder.CategoryIDs))
+	for _, id := range reorder.CategoryIDs {
+		if !isSibling[id] {
+			return fmt.Errorf("%w: %s is not a subcategory of the parent", ErrInvalidCategoryOrder, id)
+		}
+		if listed[id] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidCategoryOrder, id)
+		}
+		listed[id] = true
+		order = append(order, id)
+	}
+	for _, id := range siblings {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE categories c SET sort_order = o.position - 1, updated_at = NOW()
+		FROM unnest($1::text[]) WITH ORDINALITY AS o(id, position)
+		WHERE c.id = o.id AND c.sort_order <> o.position - 1`, order)
+	if err != nil {
+		return fmt.Errorf("failed to reorder categories: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockCategory locks a category row, returning ErrCategoryNotFound if there is none
+func lockCategory(ctx context.Context, tx pgx.Tx, id string) error {
+	var locked string
+	err := tx.QueryRow(ctx, `SELECT id FROM categories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&locked)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock category: %w", err)
+	}
+	return nil
+}
+
+// checkCategoryParent checks that a category can be placed under parentID: the parent exists
+// and is neither the category nor below it
+func checkCategoryParent(ctx context.Context, tx pgx.Tx, id string, parentID string) error {
+	if err := lockCategory(ctx, tx, parentID); err != nil {
+		return err
+	}
+
+	// Walk up from the parent; reaching the category means it would be its own ancestor
+	var cycle bool
+	err := tx.QueryRow(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = $2
+			UNION
+			SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $1)`, id, parentID).Scan(&cycle)
+	if err != nil {
+		return fmt.Errorf("failed to check category tree: %w", err)
+	}
+	if cycle {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+// refreshBreadcrumbs rebuilds the stored breadcrumbs, from the root down to the category
+// itself, of every category whose path has changed
+func refreshBreadcrumbs(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `
+		WITH RECURSIVE paths AS (
+			SELECT id, jsonb_build_array(jsonb_build_object('id', id, 'name', name, 'slug', slug, 'level', 0)) AS breadcrumbs, 0 AS level
+			FROM categories
+			WHERE parent_id IS NULL AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, p.breadcrumbs || jsonb_build_object('id', c.id, 'name', c.name, 'slug', c.slug, 'level', p.level + 1), p.level + 1
+			FROM categories c JOIN paths p ON c.parent_id = p.id
+			WHERE c.deleted_at IS NULL
+		)
+		UPDATE categories c SET breadcrumbs = p.breadcrumbs
+		FROM paths p
+		WHERE c.id = p.id AND c.breadcrumbs IS DISTINCT FROM p.breadcrumbs`)
+	if err != nil {
+		return fmt.Errorf("failed to update breadcrumbs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,705 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrCostLayerConsumed is returned when a completed stock-in cannot be reversed
+// because stock it received has already been sold or written off
+var ErrCostLayerConsumed = errors.New("stock from this stock-in has already been issued")
+
+// CostingRepository defines methods for inventory costing reports
+type CostingRepository interface {
+	GetCostingMethod() (models.CostingMethod, error)
+	GetInventoryValuation(asOf time.Time) (*models.InventoryValuation, error)
+	GetProductMovements(productID string) ([]models.CostMovement, error)
+}
+
+// CostingRepositoryImpl implements the CostingRepository interface
+type CostingRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewCostingRepository creates a new CostingRepository
+func NewCostingRepository(db *pgx.Conn) CostingRepository {
+	return &CostingRepositoryImpl{db: db}
+}
+
+// GetCostingMethod returns the company's configured costing method
+func (r *CostingRepositoryImpl) GetCostingMethod() (models.CostingMethod, error) {
+	return costingMethod(context.Background(), r.db)
+}
+
+// GetInventoryValuation returns the quantity and value of every product's stock as of the given time.
+// It is built from the cost movement ledger, so past dates report the value at that time.
+func (r *CostingRepositoryImpl) GetInventoryValuation(asOf time.Time) (*models.InventoryValuation, error) {
+	ctx := context.Background()
+
+	method, err := costingMethod(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT m.product_id,
+			COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''),
+			SUM(m.quantity), SUM(m.total_cost)
+		FROM cost_movements m
+		LEFT JOIN products p ON p.id = m.product_id
+		WHERE m.occurred_at <= $1
+		GROUP BY m.product_id, p.basic->>'name', p.basic->>'sku'
+		HAVING SUM(m.quantity) <> 0 OR SUM(m.total_cost) <> 0
+		ORDER BY COALESCE(p.basic->>'name', ''), m.product_id`
+
+	rows, err := r.db.Query(ctx, query, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory valuation: %w", err)
+	}
+	defer rows.Close()
+
+	valuation := &models.InventoryValuation{
+		AsOf:       asOf,
+		Method:     method,
+		Currency:   money.BaseCurrency,
+		Products:   []models.InventoryValuationLine{},
+		TotalValue: money.Zero(money.BaseCurrency),
+	}
+	for rows.Next() {
+		line := models.InventoryValuationLine{
+			UnitCost: money.Zero(money.BaseCurrency),
+			Value:    money.Zero(money.BaseCurrency),
+		}
+		if err := rows.Scan(&line.ProductID, &line.ProductName, &line.SKU, &line.Quantity, &line.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory valuation: %w", err)
+		}
+		if line.Quantity > 0 {
+			line.UnitCost = line.Value.Div(int64(line.Quantity))
+		}
+		valuation.Products = append(valuation.Products, line)
+		valuation.TotalQuantity += line.Quantity
+		valuation.TotalValue = valuation.TotalValue.Add(line.Value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating inventory valuation: %w", err)
+	}
+
+	return valuation, nil
+}
+
+// GetProductMovements returns the cost ledger of a product, oldest first
+func (r *CostingRepositoryImpl) GetProductMovements(productID string) ([]models.CostMovement, error) {
+	query := `SELECT id, product_id, movement_type, source_type, source_id, source_item_id, layer_id,
+			quantity, unit_cost, total_cost, COALESCE(average_total, total_cost), method,
+			occurred_at, reversed_at, created_at
+		FROM cost_movements
+		WHERE product_id = $1
+		ORDER BY occurred_at, created_at`
+
+	rows, err := r.db.Query(context.Background(), query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []models.CostMovement{}
+	for rows.Next() {
+		m := models.CostMovement{
+			UnitCost:     money.Zero(money.BaseCurrency),
+			TotalCost:    money.Zero(money.BaseCurrency),
+			AverageTotal: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(
+			&m.ID, &m.ProductID, &m.MovementType, &m.SourceType, &m.SourceID, &m.SourceItemID, &m.LayerID,
+			&m.Quantity, &m.UnitCost, &m.TotalCost, &m.AverageTotal, &m.Method,
+			&m.OccurredAt, &m.ReversedAt, &m.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cost movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cost movements: %w", err)
+	}
+
+	return movements, nil
+}
+
+// costingMethod reads the configured costing method through a connection or transaction
+func costingMethod(ctx context.Context, q queryRower) (models.CostingMethod, error) {
+	value, err := getSetting(ctx, q, models.SettingCostingMethod)
+	if err != nil {
+		return "", err
+	}
+	method := models.CostingMethod(value)
+	if !method.IsValid() {
+		return models.CostingMethodAverage, nil
+	}
+	return method, nil
+}
+
+// costLine is a document line that moves inventory
+type costLine struct {
+	ItemID    string
+	ProductID string
+	Quantity  int
+	UnitCost  money.Money // base-currency unit cost, used for receipts only
+}
+
+// syncDocumentCosts brings a document's cost movements in line with its status.
+// Movements from an earlier completion are reversed first, then the document is
+// posted again if it is now completed. It must run inside the document's transaction.
+func syncDocumentCosts(ctx context.Context, tx pgx.Tx, sourceType, sourceID string, wasCompleted, isCompleted bool) error {
+	if wasCompleted {
+		if err := reverseDocumentCosts(ctx, tx, sourceType, sourceID); err != nil {
+			return err
+		}
+	}
+	if !isCompleted {
+		return nil
+	}
+
+	switch sourceType {
+	case models.CostSourceStockIn:
+		return postStockInCosts(ctx, tx, sourceID)
+	case models.CostSourceSale:
+		return postSaleCosts(ctx, tx, sourceID)
+	case models.CostSourceReject:
+		return postRejectCosts(ctx, tx, sourceID)
+	case models.CostSourceAssembly:
+		return postAssemblyCosts(ctx, tx, sourceID)
+	}
+	return fmt.Errorf("unknown cost source %q", sourceType)
+}
+
+// postStockInCosts creates a cost layer and a receipt movement for every item of a stock-in
+func postStockInCosts(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	var orderDate time.Time
+	err := tx.QueryRow(ctx, `SELECT order_date FROM stock_ins WHERE id = $1`, stockInID).Scan(&orderDate)
+	if err != nil {
+		return fmt.Errorf("failed to get stock-in for costing: %w", err)
+	}
+
+	// Layers carry the landed unit cost: the base unit cost plus allocated landed costs per unit
+	lines, err := queryCostLines(ctx, tx, `SELECT id, product_id, quantity,
+			base_unit_cost + COALESCE(landed_cost / NULLIF(quantity, 0), 0)
+		FROM stock_in_items WHERE stock_in_id = $1 AND deleted_at IS NULL`, stockInID)
+	if err != nil {
+		return err
+	}
+
+	method, err := costingMethod(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if err := receiveCostLine(ctx, tx, models.CostSourceStockIn, stockInID, orderDate, method, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// receiveCostLine puts a line's quantity into stock at its unit cost: a new cost layer, the
+// running average and a receipt movement
+func receiveCostLine(ctx context.Context, tx pgx.Tx, sourceType, sourceID string, date time.Time, method models.CostingMethod, line costLine) error {
+	if line.Quantity <= 0 {
+		return nil
+	}
+
+	state, err := lockProductCost(ctx, tx, line.ProductID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	layer := models.CostLayer{
+		ID:                uuid.NewString(),
+		ProductID:         line.ProductID,
+		SourceType:        sourceType,
+		SourceID:          sourceID,
+		SourceItemID:      line.ItemID,
+		ReceivedAt:        date,
+		Quantity:          line.Quantity,
+		RemainingQuantity: line.Quantity,
+		UnitCost:          line.UnitCost,
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO cost_layers (
+			id, product_id, source_type, source_id, source_item_id, received_at,
+			quantity, remaining_quantity, unit_cost, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		layer.ID, layer.ProductID, layer.SourceType, layer.SourceID, layer.SourceItemID, layer.ReceivedAt,
+		layer.Quantity, layer.RemainingQuantity, layer.UnitCost, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert cost layer: %w", err)
+	}
+
+	totalCost := line.UnitCost.Mul(int64(line.Quantity))
+	state.Add(line.Quantity, totalCost)
+	if err := saveProductCost(ctx, tx, state); err != nil {
+		return err
+	}
+
+	return insertCostMovement(ctx, tx, &models.CostMovement{
+		ProductID:    line.ProductID,
+		MovementType: models.CostMovementReceipt,
+		SourceType:   sourceType,
+		SourceID:     sourceID,
+		SourceItemID: line.ItemID,
+		LayerID:      &layer.ID,
+		Quantity:     line.Quantity,
+		TotalCost:    totalCost,
+		AverageTotal: totalCost,
+		Method:       method,
+		OccurredAt:   date,
+	})
+}
+
+// postSaleCosts issues stock for every item of a sale and records its cost of goods sold
+func postSaleCosts(ctx context.Context, tx pgx.Tx, saleID string) error {
+	var saleDate time.Time
+	err := tx.QueryRow(ctx, `SELECT sale_date FROM sales WHERE id = $1`, saleID).Scan(&saleDate)
+	if err != nil {
+		return fmt.Errorf("failed to get sale for costing: %w", err)
+	}
+
+	lines, err := queryCostLines(ctx, tx, `SELECT id, product_id, quantity, 0::numeric
+		FROM sale_items WHERE sale_id = $1 AND deleted_at IS NULL`, saleID)
+	if err != nil {
+		return err
+	}
+
+	// Kits that are not assembled are issued as their components
+	kits, err := querySaleKitComponents(ctx, tx, saleID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		cost := money.Zero(money.BaseCurrency)
+		if components, ok := kits[line.ProductID]; ok {
+			for _, component := range components {
+				part, err := issueCostLine(ctx, tx, models.CostSourceSale, saleID, saleDate, costLine{
+					ItemID:    line.ItemID,
+					ProductID: component.ComponentID,
+					Quantity:  line.Quantity * component.Quantity,
+				})
+				if err != nil {
+					return err
+				}
+				cost = cost.Add(part)
+			}
+		} else if cost, err = issueCostLine(ctx, tx, models.CostSourceSale, saleID, saleDate, line); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE sale_items SET unit_cost = $1, cogs = $2 WHERE id = $3`,
+			unitCostOf(cost, line.Quantity), cost, line.ItemID)
+		if err != nil {
+			return fmt.Errorf("failed to update sale item cost: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// postRejectCosts issues stock for every item of a reject and records the cost written off
+func postRejectCosts(ctx context.Context, tx pgx.Tx, rejectID string) error {
+	var rejectDate time.Time
+	err := tx.QueryRow(ctx, `SELECT reject_date FROM rejects WHERE id = $1`, rejectID).Scan(&rejectDate)
+	if err != nil {
+		return fmt.Errorf("failed to get reject for costing: %w", err)
+	}
+
+	lines, err := queryCostLines(ctx, tx, `SELECT id, product_id, quantity, 0::numeric
+		FROM reject_items WHERE reject_id = $1 AND deleted_at IS NULL`, rejectID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		cost, err := issueCostLine(ctx, tx, models.CostSourceReject, rejectID, rejectDate, line)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE reject_items SET cogs = $1 WHERE id = $2`, cost, line.ItemID)
+		if err != nil {
+			return fmt.Errorf("failed to update reject item cost: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// postAssemblyCosts issues the components an assembly consumes and receives the product it
+// builds at their total cost
+func postAssemblyCosts(ctx context.Context, tx pgx.Tx, assemblyID string) error {
+	var date time.Time
+	var productID string
+	var quantity int
+	err := tx.QueryRow(ctx, `SELECT assembly_date, product_id, quantity FROM assemblies WHERE id = $1`,
+		assemblyID).Scan(&date, &productID, &quantity)
+	if err != nil {
+		return fmt.Errorf("failed to get assembly for costing: %w", err)
+	}
+
+	lines, err := queryCostLines(ctx, tx, `SELECT id, component_id, quantity, 0::numeric
+		FROM assembly_items WHERE assembly_id = $1`, assemblyID)
+	if err != nil {
+		return err
+	}
+
+	total := money.Zero(money.BaseCurrency)
+	for _, line := range lines {
+		cost, err := issueCostLine(ctx, tx, models.CostSourceAssembly, assemblyID, date, line)
+		if err != nil {
+			return err
+		}
+		total = total.Add(cost)
+
+		_, err = tx.Exec(ctx, `UPDATE assembly_items SET unit_cost = $1, total_cost = $2 WHERE id = $3`,
+			unitCostOf(cost, line.Quantity), cost, line.ItemID)
+		if err != nil {
+			return fmt.Errorf("failed to update assembly item cost: %w", err)
+		}
+	}
+
+	method, err := costingMethod(ctx, tx)
+	if err != nil {
+		return err
+	}
+	unitCost := unitCostOf(total, quantity)
+	err = receiveCostLine(ctx, tx, models.CostSourceAssembly, assemblyID, date, method, costLine{
+		ItemID:    assemblyID,
+		ProductID: productID,
+		Quantity:  quantity,
+		UnitCost:  unitCost,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE assemblies SET unit_cost = $1, total_cost = $2 WHERE id = $3`,
+		unitCost, total, assemblyID)
+	if err != nil {
+		return fmt.Errorf("failed to update assembly cost: %w", err)
+	}
+	return nil
+}
+
+// querySaleKitComponents returns the components of the kits on a sale that are sold from
+// their components, by kit
+func querySaleKitComponents(ctx context.Context, tx pgx.Tx, saleID string) (map[string][]models.KitComponent, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT kc.kit_id, kc.component_id, kc.quantity
+		FROM kit_components kc
+		JOIN product_kits pk ON pk.product_id = kc.kit_id AND pk.assembled = FALSE
+		WHERE kc.kit_id IN (SELECT product_id FROM sale_items WHERE sale_id = $1 AND deleted_at IS NULL)
+		ORDER BY kc.kit_id, kc.component_id`, saleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kit components: %w", err)
+	}
+	defer rows.Close()
+
+	kits := map[string][]models.KitComponent{}
+	for rows.Next() {
+		var c models.KitComponent
+		if err := rows.Scan(&c.KitID, &c.ComponentID, &c.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan kit component: %w", err)
+		}
+		kits[c.KitID] = append(kits[c.KitID], c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating kit components: %w", err)
+	}
+
+	return kits, nil
+}
+
+// issueCostLine takes a line's quantity out of stock and returns its total cost
+func issueCostLine(ctx context.Context, tx pgx.Tx, sourceType, sourceID string, date time.Time, line costLine) (money.Money, error) {
+	if line.Quantity <= 0 {
+		return money.Zero(money.BaseCurrency), nil
+	}
+
+	method, err := costingMethod(ctx, tx)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	state, err := lockProductCost(ctx, tx, line.ProductID)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	layers, err := lockOpenCostLayers(ctx, tx, line.ProductID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	before := make(map[string]int, len(layers))
+	for _, l := range layers {
+		before[l.ID] = l.RemainingQuantity
+	}
+
+	draws := models.IssueCost(method, layers, state, line.Quantity)
+
+	for _, l := range layers {
+		if l.RemainingQuantity == before[l.ID] {
+			continue
+		}
+		_, err = tx.Exec(ctx, `UPDATE cost_layers SET remaining_quantity = $1, updated_at = $2 WHERE id = $3`,
+			l.RemainingQuantity, time.Now(), l.ID)
+		if err != nil {
+			return money.Money{}, fmt.Errorf("failed to update cost layer: %w", err)
+		}
+	}
+
+	if err := saveProductCost(ctx, tx, state); err != nil {
+		return money.Money{}, err
+	}
+
+	for _, d := range draws {
+		err = insertCostMovement(ctx, tx, &models.CostMovement{
+			ProductID:    line.ProductID,
+			MovementType: models.CostMovementIssue,
+			SourceType:   sourceType,
+			SourceID:     sourceID,
+			SourceItemID: line.ItemID,
+			LayerID:      d.LayerID,
+			Quantity:     -d.Quantity,
+			TotalCost:    d.TotalCost.Neg(),
+			AverageTotal: d.AverageCost.Neg(),
+			Method:       method,
+			OccurredAt:   date,
+		})
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	return models.TotalDrawCost(draws), nil
+}
+
+// reverseDocumentCosts undoes every movement a document has posted. Issued quantities
+// return to their layers; received layers are removed and must not have been drawn from.
+func reverseDocumentCosts(ctx context.Context, tx pgx.Tx, sourceType, sourceID string) error {
+	rows, err := tx.Query(ctx, `SELECT id, product_id, movement_type, source_item_id, layer_id,
+			quantity, total_cost, COALESCE(average_total, total_cost), method
+		FROM cost_movements
+		WHERE source_type = $1 AND source_id = $2 AND movement_type <> $3 AND reversed_at IS NULL
+		ORDER BY created_at`,
+		sourceType, sourceID, models.CostMovementReversal)
+	if err != nil {
+		return fmt.Errorf("failed to get cost movements: %w", err)
+	}
+
+	var movements []models.CostMovement
+	for rows.Next() {
+		m := models.CostMovement{TotalCost: money.Zero(money.BaseCurrency), AverageTotal: money.Zero(money.BaseCurrency)}
+		err := rows.Scan(&m.ID, &m.ProductID, &m.MovementType, &m.SourceItemID, &m.LayerID,
+			&m.Quantity, &m.TotalCost, &m.AverageTotal, &m.Method)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan cost movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating cost movements: %w", err)
+	}
+
+	now := time.Now()
+	for _, m := range movements {
+		if m.LayerID != nil {
+			if m.MovementType == models.CostMovementReceipt {
+				var quantity, remaining int
+				err := tx.QueryRow(ctx, `SELECT quantity, remaining_quantity FROM cost_layers WHERE id = $1 FOR UPDATE`,
+					*m.LayerID).Scan(&quantity, &remaining)
+				if err != nil {
+					return fmt.Errorf("failed to get cost layer: %w", err)
+				}
+				if remaining < quantity {
+					return ErrCostLayerConsumed
+				}
+			}
+			// Receipts empty their layer; issues (negative quantities) refill it
+			_, err := tx.Exec(ctx, `UPDATE cost_layers SET remaining_quantity = remaining_quantity - $1, updated_at = $2 WHERE id = $3`,
+				m.Quantity, now, *m.LayerID)
+			if err != nil {
+				return fmt.Errorf("failed to update cost layer: %w", err)
+			}
+		}
+
+		state, err := lockProductCost(ctx, tx, m.ProductID)
+		if err != nil {
+			return err
+		}
+		state.Reverse(m)
+		if err := saveProductCost(ctx, tx, state); err != nil {
+			return err
+		}
+
+		err = insertCostMovement(ctx, tx, &models.CostMovement{
+			ProductID:    m.ProductID,
+			MovementType: models.CostMovementReversal,
+			SourceType:   sourceType,
+			SourceID:     sourceID,
+			SourceItemID: m.SourceItemID,
+			LayerID:      m.LayerID,
+			Quantity:     -m.Quantity,
+			TotalCost:    m.TotalCost.Neg(),
+			AverageTotal: m.AverageTotal.Neg(),
+			Method:       m.Method,
+			OccurredAt:   now,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE cost_movements SET reversed_at = $1 WHERE id = $2`, now, m.ID)
+		if err != nil {
+			return fmt.Errorf("failed to mark cost movement reversed: %w", err)
+		}
+	}
+
+	// Issued documents no longer carry a cost once reversed
+	switch sourceType {
+	case models.CostSourceSale:
+		_, err = tx.Exec(ctx, `UPDATE sale_items SET unit_cost = 0, cogs = 0 WHERE sale_id = $1`, sourceID)
+	case models.CostSourceReject:
+		_, err = tx.Exec(ctx, `UPDATE reject_items SET cogs = 0 WHERE reject_id = $1`, sourceID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear document costs: %w", err)
+	}
+
+	return nil
+}
+
+// queryCostLines loads document lines selected as (id, product_id, quantity, unit_cost)
+func queryCostLines(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]costLine, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document items for costing: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []costLine
+	for rows.Next() {
+		line := costLine{UnitCost: money.Zero(money.BaseCurrency)}
+		if err := rows.Scan(&line.ItemID, &line.ProductID, &line.Quantity, &line.UnitCost); err != nil {
+			return nil, fmt.Errorf("failed to scan document item for costing: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document items: %w", err)
+	}
+
+	return lines, nil
+}
+
+// lockProductCost loads and locks a product's average cost state, creating an empty one if needed
+func lockProductCost(ctx context.Context, tx pgx.Tx, productID string) (*models.ProductCost, error) {
+	state := models.NewProductCost(productID)
+	err := tx.QueryRow(ctx, `SELECT quantity, total_value, average_cost, updated_at
+		FROM product_costs WHERE product_id = $1 FOR UPDATE`, productID).Scan(
+		&state.Quantity, &state.TotalValue, &state.AverageCost, &state.UpdatedAt,
+	)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get product cost: %w", err)
+	}
+	return state, nil
+}
+
+// saveProductCost stores a product's average cost state
+func saveProductCost(ctx context.Context, tx pgx.Tx, state *models.ProductCost) error {
+	state.UpdatedAt = time.Now()
+	_, err := tx.Exec(ctx, `INSERT INTO product_costs (product_id, quantity, total_value, average_cost, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (product_id) DO UPDATE SET
+			quantity = EXCLUDED.quantity, total_value = EXCLUDED.total_value,
+			average_cost = EXCLUDED.average_cost, updated_at = EXCLUDED.updated_at`,
+		state.ProductID, state.Quantity, state.TotalValue, state.AverageCost, state.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save product cost: %w", err)
+	}
+	return nil
+}
+
+// lockOpenCostLayers loads and locks a product's layers with stock remaining, oldest first
+func lockOpenCostLayers(ctx context.Context, tx pgx.Tx, productID string) ([]models.CostLayer, error) {
+	rows, err := tx.Query(ctx, `SELECT id, product_id, received_at, quantity, remaining_quantity, unit_cost
+		FROM cost_layers
+		WHERE product_id = $1 AND remaining_quantity > 0
+		ORDER BY received_at, created_at
+		FOR UPDATE`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost layers: %w", err)
+	}
+	defer rows.Close()
+
+	var layers []models.CostLayer
+	for rows.Next() {
+		layer := models.CostLayer{UnitCost: money.Zero(money.BaseCurrency)}
+		err := rows.Scan(&layer.ID, &layer.ProductID, &layer.ReceivedAt,
+			&layer.Quantity, &layer.RemainingQuantity, &layer.UnitCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cost layer: %w", err)
+		}
+		layers = append(layers, layer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cost layers: %w", err)
+	}
+
+	return layers, nil
+}
+
+// insertCostMovement writes a movement to the cost ledger
+func insertCostMovement(ctx context.Context, tx pgx.Tx, m *models.CostMovement) error {
+	m.ID = uuid.NewString()
+	m.CreatedAt = time.Now()
+	m.UnitCost = unitCostOf(m.TotalCost.Abs(), m.Quantity)
+
+	_, err := tx.Exec(ctx, `INSERT INTO cost_movements (
+			id, product_id, movement_type, source_type, source_id, source_item_id, layer_id,
+			quantity, unit_cost, total_cost, average_total, method, occurred_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		m.ID, m.ProductID, m.MovementType, m.SourceType, m.SourceID, m.SourceItemID, m.LayerID,
+		m.Quantity, m.UnitCost, m.TotalCost, m.AverageTotal, m.Method, m.OccurredAt, m.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert cost movement: %w", err)
+	}
+	return nil
+}
+
+// unitCostOf divides a total cost by a quantity, ignoring its sign
+func unitCostOf(total money.Money, quantity int) money.Money {
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	if quantity == 0 {
+		return money.Zero(money.BaseCurrency)
+	}
+	return total.Div(int64(quantity))
+}
@@ -35,7 +35,7 @@ func NewCustomerRepository(db *pgx.Conn) CustomerRepository {
 
 func (r *CustomerRepositoryImpl) GetAll() ([]models.Customer, error) {
 	query := `SELECT id, name, email, phone, address, total_orders, total_spent, 
-	          last_order_at, notes, created_at, updated_at 
+	          last_order_at, notes, payment_terms, price_list_id, created_at, updated_at 
 	          FROM customers 
 	          WHERE deleted_at IS NULL
 	          ORDER BY name ASC`
@@ -54,7 +54,7 @@ func (r *CustomerRepositoryImpl) GetAll() ([]models.Customer, error) {
 		err := rows.Scan(
 			&customer.ID, &customer.Name, &customer.Email, &customer.Phone,
 			&customer.Address, &customer.TotalOrders, &customer.TotalSpent,
-			&lastOrderAt, &customer.Notes, &customer.CreatedAt, &customer.UpdatedAt,
+			&lastOrderAt, &customer.Notes, &customer.PaymentTerms, &customer.PriceListID, &customer.CreatedAt, &customer.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan customer: %w", err)
@@ -76,7 +76,7 @@ func (r *CustomerRepositoryImpl) GetAll() ([]models.Customer, error) {
 
 func (r *CustomerRepositoryImpl) GetByID(id string) (*models.Customer, error) {
 	query := `SELECT id, name, email, phone, address, total_orders, total_spent, 
-	          last_order_at, notes, created_at, updated_at 
+	          last_order_at, notes, payment_terms, price_list_id, created_at, updated_at 
 	          FROM customers 
 	          WHERE id = $1 AND deleted_at IS NULL`
 
@@ -86,7 +86,7 @@ func (r *CustomerRepositoryImpl) GetByID(id string) (*models.Customer, error) {
 	err := r.db.QueryRow(context.Background(), query, id).Scan(
 		&customer.ID, &customer.Name, &customer.Email, &customer.Phone,
 		&customer.Address, &customer.TotalOrders, &customer.TotalSpent,
-		&lastOrderAt, &customer.Notes, &customer.CreatedAt, &customer.UpdatedAt,
+		&lastOrderAt, &customer.Notes, &customer.PaymentTerms, &customer.PriceListID, &customer.CreatedAt, &customer.UpdatedAt,
 	)
 
 	if err != nil {
@@ -105,7 +105,7 @@ func (r *CustomerRepositoryImpl) GetByID(id string) (*models.Customer, error) {
 
 func (r *CustomerRepositoryImpl) GetByEmail(email string) (*models.Customer, error) {
 	query := `SELECT id, name, email, phone, address, total_orders, total_spent, 
-	          last_order_at, notes, created_at, updated_at 
+	          last_order_at, notes, payment_terms, price_list_id, created_at, updated_at 
 	          FROM customers 
 	          WHERE email = $1 AND deleted_at IS NULL`
 
@@ -115,7 +115,7 @@ func (r *CustomerRepositoryImpl) GetByEmail(email string) (*models.Customer, err
 	err := r.db.QueryRow(context.Background(), query, email).Scan(
 		&customer.ID, &customer.Name, &customer.Email, &customer.Phone,
 		&customer.Address, &customer.TotalOrders, &customer.TotalSpent,
-		&lastOrderAt, &customer.Notes, &customer.CreatedAt, &customer.UpdatedAt,
+		&lastOrderAt, &customer.Notes, &customer.PaymentTerms, &customer.PriceListID, &customer.CreatedAt, &customer.UpdatedAt,
 	)
 
 	if err != nil {
@@ -134,13 +134,14 @@ func (r *CustomerRepositoryImpl) GetByEmail(email string) (*models.Customer, err
 
 func (r *CustomerRepositoryImpl) Create(customer *models.Customer) error {
 	query := `INSERT INTO customers (id, name, email, phone, address, total_orders, total_spent, 
-	                   last_order_at, notes, created_at, updated_at) 
-	                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	                   last_order_at, notes, payment_terms, price_list_id, created_at, updated_at) 
+	                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 
 	_, err := r.db.Exec(context.Background(), query,
 		customer.ID, customer.Name, customer.Email, customer.Phone,
 		customer.Address, customer.TotalOrders, customer.TotalSpent,
-		customer.LastOrderAt, customer.Notes, customer.CreatedAt, customer.UpdatedAt,
+		customer.LastOrderAt, customer.Notes, customer.PaymentTerms, customer.PriceListID,
+		customer.CreatedAt, customer.UpdatedAt,
 	)
 
 	if err != nil {
@@ -156,13 +157,13 @@ func (r *CustomerRepositoryImpl) Update(customer *models.Customer) error {
 	query := `UPDATE customers SET
 		name = $1, email = $2, phone = $3, address = $4, 
 		total_orders = $5, total_spent = $6, last_order_at = $7, 
-		notes = $8, updated_at = $9
-		WHERE id = $10`
+		notes = $8, payment_terms = $9, price_list_id = $10, updated_at = $11
+		WHERE id = $12`
 
 	_, err := r.db.Exec(context.Background(), query,
 		customer.Name, customer.Email, customer.Phone, customer.Address,
 		customer.TotalOrders, customer.TotalSpent, customer.LastOrderAt, 
-		customer.Notes, customer.UpdatedAt, customer.ID,
+		customer.Notes, customer.PaymentTerms, customer.PriceListID, customer.UpdatedAt, customer.ID,
 	)
 
 	if err != nil {
@@ -204,7 +205,7 @@ func (r *CustomerRepositoryImpl) Search(query string, offset, limit int) ([]mode
 
 	// Get paginated results
 	searchQuery := `SELECT id, name, email, phone, address, total_orders, total_spent, 
-		last_order_at, notes, created_at, updated_at 
+		last_order_at, notes, payment_terms, price_list_id, created_at, updated_at 
 		FROM customers 
 		WHERE deleted_at IS NULL AND 
 		(name ILIKE $1 OR email ILIKE $2 OR phone ILIKE $3)
@@ -226,7 +227,7 @@ func (r *CustomerRepositoryImpl) Search(query string, offset, limit int) ([]mode
 		err := rows.Scan(
 			&customer.ID, &customer.Name, &customer.Email, &customer.Phone,
 			&customer.Address, &customer.TotalOrders, &customer.TotalSpent,
-			&lastOrderAt, &customer.Notes, &customer.CreatedAt, &customer.UpdatedAt,
+			&lastOrderAt, &customer.Notes, &customer.PaymentTerms, &customer.PriceListID, &customer.CreatedAt, &customer.UpdatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan customer: %w", err)
@@ -248,7 +249,7 @@ func (r *CustomerRepositoryImpl) Search(query string, offset, limit int) ([]mode
 
 func (r *CustomerRepositoryImpl) GetTopCustomers(limit int) ([]models.Customer, error) {
 	query := `SELECT id, name, email, phone, address, total_orders, total_spent, 
-	          last_order_at, notes, created_at, updated_at 
+	          last_order_at, notes, payment_terms, price_list_id, created_at, updated_at 
 	          FROM customers 
 	          WHERE deleted_at IS NULL
 	          ORDER BY total_spent DESC
@@ -268,7 +269,7 @@ func (r *CustomerRepositoryImpl) GetTopCustomers(limit int) ([]models.Customer,
 		err := rows.Scan(
 			&customer.ID, &customer.Name, &customer.Email, &customer.Phone,
 			&customer.Address, &customer.TotalOrders, &customer.TotalSpent,
-			&lastOrderAt, &customer.Notes, &customer.CreatedAt, &customer.UpdatedAt,
+			&lastOrderAt, &customer.Notes, &customer.PaymentTerms, &customer.PriceListID, &customer.CreatedAt, &customer.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan customer: %w", err)
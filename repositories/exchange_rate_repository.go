@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ExchangeRateRepository defines methods for exchange rate operations
+type ExchangeRateRepository interface {
+	GetByID(id string) (*models.ExchangeRate, error)
+	Create(rate *models.ExchangeRate) error
+	Delete(id string) error
+	List(currency string, startDate, endDate *time.Time) ([]models.ExchangeRate, error)
+	GetRateOn(currency string, date time.Time) (*models.ExchangeRate, error)
+	Import(rates []models.ExchangeRate) (int, error)
+}
+
+// ExchangeRateRepositoryImpl implements the ExchangeRateRepository interface
+type ExchangeRateRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewExchangeRateRepository creates a new ExchangeRateRepository
+func NewExchangeRateRepository(db *pgx.Conn) ExchangeRateRepository {
+	return &ExchangeRateRepositoryImpl{db: db}
+}
+
+const exchangeRateColumns = `id, currency, rate, rate_date, source, note, created_at, updated_at`
+
+// upsertExchangeRateQuery replaces the rate for a currency and date if one already exists
+const upsertExchangeRateQuery = `
+	INSERT INTO exchange_rates (id, currency, rate, rate_date, source, note, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (currency, rate_date) DO UPDATE SET
+		rate = EXCLUDED.rate, source = EXCLUDED.source, note = EXCLUDED.note,
+		updated_at = EXCLUDED.updated_at, deleted_at = NULL
+	RETURNING id`
+
+func scanExchangeRate(row pgx.Row) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	var note *string
+	err := row.Scan(
+		&rate.ID, &rate.Currency, &rate.Rate, &rate.RateDate,
+		&rate.Source, &note, &rate.CreatedAt, &rate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if note != nil {
+		rate.Note = *note
+	}
+	return &rate, nil
+}
+
+// GetByID retrieves an exchange rate by its ID
+func (r *ExchangeRateRepositoryImpl) GetByID(id string) (*models.ExchangeRate, error) {
+	query := `SELECT ` + exchangeRateColumns + ` FROM exchange_rates WHERE id = $1 AND deleted_at IS NULL`
+
+	rate, err := scanExchangeRate(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+	return rate, nil
+}
+
+// Create stores an exchange rate, replacing any existing rate for the same currency and date
+func (r *ExchangeRateRepositoryImpl) Create(rate *models.ExchangeRate) error {
+	if rate.ID == "" {
+		rate.ID = uuid.NewString()
+	}
+	now := time.Now()
+	rate.CreatedAt = now
+	rate.UpdatedAt = now
+
+	err := r.db.QueryRow(context.Background(), upsertExchangeRateQuery,
+		rate.ID, rate.Currency, rate.Rate, rate.RateDate, rate.Source, rate.Note, now, now,
+	).Scan(&rate.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save exchange rate: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes an exchange rate
+func (r *ExchangeRateRepositoryImpl) Delete(id string) error {
+	_, err := r.db.Exec(context.Background(),
+		`UPDATE exchange_rates SET deleted_at = $1 WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+	return nil
+}
+
+// List retrieves exchange rates, optionally filtered by currency and date range
+func (r *ExchangeRateRepositoryImpl) List(currency string, startDate, endDate *time.Time) ([]models.ExchangeRate, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+	argIndex := 1
+
+	if currency != "" {
+		conditions = append(conditions, fmt.Sprintf("currency = $%d", argIndex))
+		args = append(args, strings.ToUpper(currency))
+		argIndex++
+	}
+
+	if startDate != nil {
+		conditions = append(conditions, fmt.Sprintf("rate_date >= $%d", argIndex))
+		args = append(args, *startDate)
+		argIndex++
+	}
+
+	if endDate != nil {
+		conditions = append(conditions, fmt.Sprintf("rate_date <= $%d", argIndex))
+		args = append(args, *endDate)
+		argIndex++
+	}
+
+	query := `SELECT ` + exchangeRateColumns + ` FROM exchange_rates
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY rate_date DESC, currency ASC`
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		rate, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rates = append(rates, *rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exchange rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// GetRateOn returns the most recent rate for a currency dated on or before the given date
+func (r *ExchangeRateRepositoryImpl) GetRateOn(currency string, date time.Time) (*models.ExchangeRate, error) {
+	query := `SELECT ` + exchangeRateColumns + ` FROM exchange_rates
+		WHERE currency = $1 AND rate_date <= $2 AND deleted_at IS NULL
+		ORDER BY rate_date DESC
+		LIMIT 1`
+
+	rate, err := scanExchangeRate(r.db.QueryRow(context.Background(), query, strings.ToUpper(currency), date))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+	return rate, nil
+}
+
+// Import stores a batch of rates in a single transaction and returns how many were saved
+func (r *ExchangeRateRepositoryImpl) Import(rates []models.ExchangeRate) (int, error) {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	for i := range rates {
+		rate := &rates[i]
+		if rate.ID == "" {
+			rate.ID = uuid.NewString()
+		}
+		rate.CreatedAt = now
+		rate.UpdatedAt = now
+
+		err = tx.QueryRow(ctx, upsertExchangeRateQuery,
+			rate.ID, rate.Currency, rate.Rate, rate.RateDate, rate.Source, rate.Note, now, now,
+		).Scan(&rate.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import rate for %s on %s: %w",
+				rate.Currency, rate.RateDate.Format("2006-01-02"), err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(rates), nil
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportRepository defines methods for bulk import jobs and the product lookups they need
+type ImportRepository interface {
+	Create(job *models.ImportJob) error
+	// GetByID returns the job with its row errors, or nil if there is no such job
+	GetByID(id string) (*models.ImportJob, error)
+	// List returns jobs newest first, without their row errors
+	List(offset, limit int) ([]models.ImportJob, int64, error)
+	// Save stores the job's status, progress and row errors
+	Save(job *models.ImportJob) error
+	// FailInterrupted marks jobs left queued or running by a stopped server as failed
+	FailInterrupted() (int64, error)
+
+	// LookupSKUs returns the products that have the SKUs, by SKU
+	LookupSKUs(skus []string) (map[string]ImportedProduct, error)
+}
+
+// ImportedProduct is an existing product an import row's SKU or parent SKU refers to
+type ImportedProduct struct {
+	ID        string
+	ParentID  *string
+	IsVariant bool
+}
+
+// ImportRepositoryImpl implements the ImportRepository interface
+type ImportRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewImportRepository creates a new ImportRepository
+func NewImportRepository(db *pgx.Conn) ImportRepository {
+	return &ImportRepositoryImpl{db: db}
+}
+
+const importJobColumns = `id, file_name, format, status, dry_run, upsert, total_rows, processed_rows,
+	created, updated, failed, columns, ignored_columns, stock_in_id, message,
+	created_at, started_at, finished_at, updated_at`
+
+func scanImportJob(row pgx.Row, extra ...any) (*models.ImportJob, error) {
+	var job models.ImportJob
+	dest := []any{&job.ID, &job.FileName, &job.Format, &job.Status, &job.DryRun, &job.Upsert,
+		&job.TotalRows, &job.ProcessedRows, &job.Created, &job.Updated, &job.Failed,
+		&job.Columns, &job.IgnoredColumns, &job.StockInID, &job.Message,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt, &job.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Create stores a new job
+func (r *ImportRepositoryImpl) Create(job *models.ImportJob) error {
+	if job.ID == "" {
+		job.ID = uuid.NewString()
+	}
+	if job.Status == "" {
+		job.Status = models.ImportStatusQueued
+	}
+	if job.IgnoredColumns == nil {
+		job.IgnoredColumns = []string{}
+	}
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO import_jobs (id, file_name, format, status, dry_run, upsert, total_rows, columns,
+			ignored_columns, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		job.ID, job.FileName, job.Format, job.Status, job.DryRun, job.Upsert, job.TotalRows,
+		job.Columns, job.IgnoredColumns, job.CreatedAt, job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a job with its row errors
+func (r *ImportRepositoryImpl) GetByID(id string) (*models.ImportJob, error) {
+	var errorsJSON []byte
+	job, err := scanImportJob(r.db.QueryRow(context.Background(),
+		`SELECT `+importJobColumns+`, errors FROM import_jobs WHERE id = $1`, id), &errorsJSON)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	if err := json.Unmarshal(errorsJSON, &job.Errors); err != nil {
+		return nil, fmt.Errorf("error decoding import errors: %w", err)
+	}
+	return job, nil
+}
+
+// List retrieves a page of jobs, newest first
+func (r *ImportRepositoryImpl) List(offset, limit int) ([]models.ImportJob, int64, error) {
+	ctx := context.Background()
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM import_jobs`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count import jobs: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT `+importJobColumns+` FROM import_jobs
+		ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.ImportJob{}
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating import jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// Save stores the job's status, counts and row errors
+func (r *ImportRepositoryImpl) Save(job *models.ImportJob) error {
+	job.UpdatedAt = time.Now()
+	if job.Errors == nil {
+		job.Errors = []models.ImportRowError{}
+	}
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to marshal import errors: %w", err)
+	}
+
+	_, err = r.db.Exec(context.Background(), `
+		UPDATE import_jobs
+		SET status = $1, total_rows = $2, processed_rows = $3, created = $4, updated = $5, failed = $6,
+			errors = $7, stock_in_id = $8, message = $9, started_at = $10, finished_at = $11, updated_at = $12
+		WHERE id = $13`,
+		job.Status, job.TotalRows, job.ProcessedRows, job.Created, job.Updated, job.Failed,
+		errorsJSON, job.StockInID, job.Message, job.StartedAt, job.FinishedAt, job.UpdatedAt, job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save import job: %w", err)
+	}
+	return nil
+}
+
+// FailInterrupted fails the jobs a previous run of the server did not finish
+func (r *ImportRepositoryImpl) FailInterrupted() (int64, error) {
+	tag, err := r.db.Exec(context.Background(), `
+		UPDATE import_jobs
+		SET status = $1, message = 'The server stopped before the import finished; upload the file again',
+			finished_at = NOW(), updated_at = NOW()
+		WHERE status IN ($2, $3)`,
+		models.ImportStatusFailed, models.ImportStatusQueued, models.ImportStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted import jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// LookupSKUs finds the products with the SKUs in one query
+func (r *ImportRepositoryImpl) LookupSKUs(skus []string) (map[string]ImportedProduct, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT basic->>'sku', id, parent_id, COALESCE((basic->>'is_variant')::boolean, parent_id IS NOT NULL)
+		FROM products
+		WHERE basic->>'sku' = ANY($1) AND deleted_at IS NULL`, skus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]ImportedProduct, len(skus))
+	for rows.Next() {
+		var sku string
+		var p ImportedProduct
+		if err := rows.Scan(&sku, &p.ID, &p.ParentID, &p.IsVariant); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		found[sku] = p
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating products: %w", err)
+	}
+
+	return found, nil
+}
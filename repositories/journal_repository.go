@@ -0,0 +1,470 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// JournalRepository defines methods for the chart of accounts and generated journal entries
+type JournalRepository interface {
+	GetAccounts() ([]models.Account, error)
+	SetAccount(account *models.Account) error
+	List(filter models.JournalFilter, offset, limit int) ([]models.JournalEntry, int64, error)
+	Export(filter models.JournalFilter) ([]models.JournalEntry, error)
+}
+
+// JournalRepositoryImpl implements the JournalRepository interface
+type JournalRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewJournalRepository creates a new JournalRepository
+func NewJournalRepository(db *pgx.Conn) JournalRepository {
+	return &JournalRepositoryImpl{db: db}
+}
+
+// GetAccounts returns the account mapped to every role, using defaults for roles never mapped
+func (r *JournalRepositoryImpl) GetAccounts() ([]models.Account, error) {
+	accounts, err := loadAccounts(context.Background(), r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Account, 0, len(accounts))
+	for _, a := range accounts {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+
+	return result, nil
+}
+
+// SetAccount maps an account role to an account code and name.
+// Entries already posted keep the account they were posted to.
+func (r *JournalRepositoryImpl) SetAccount(account *models.Account) error {
+	now := time.Now()
+	account.UpdatedAt = &now
+
+	query := `INSERT INTO account_mappings (role, code, name, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (role) DO UPDATE SET code = EXCLUDED.code, name = EXCLUDED.name, updated_at = EXCLUDED.updated_at`
+
+	_, err := r.db.Exec(context.Background(), query, account.Role, account.Code, account.Name, account.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save account mapping: %w", err)
+	}
+	return nil
+}
+
+// List returns a page of journal entries with their lines, newest first
+func (r *JournalRepositoryImpl) List(filter models.JournalFilter, offset, limit int) ([]models.JournalEntry, int64, error) {
+	ctx := context.Background()
+	where, args := journalConditions(filter)
+
+	var total int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM journal_entries"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count journal entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM journal_entries%s
+		ORDER BY entry_date DESC, created_at DESC
+		LIMIT $%d OFFSET $%d`, journalEntryColumns, where, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	entries, err := r.queryEntries(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Export returns every journal entry matching the filter with its lines, oldest first
+func (r *JournalRepositoryImpl) Export(filter models.JournalFilter) ([]models.JournalEntry, error) {
+	where, args := journalConditions(filter)
+	query := fmt.Sprintf(`SELECT %s FROM journal_entries%s ORDER BY entry_date, created_at`, journalEntryColumns, where)
+	return r.queryEntries(context.Background(), query, args...)
+}
+
+const journalEntryColumns = `id, entry_no, entry_date, source_type, source_id, reference, description,
+	currency, reversal_of, reversed_at, created_at`
+
+// journalConditions builds the WHERE clause of a journal filter
+func journalConditions(filter models.JournalFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.StartDate != nil {
+		args = append(args, *filter.StartDate)
+		conditions = append(conditions, fmt.Sprintf("entry_date >= $%d", len(args)))
+	}
+	if filter.EndDate != nil {
+		args = append(args, *filter.EndDate)
+		conditions = append(conditions, fmt.Sprintf("entry_date <= $%d", len(args)))
+	}
+	if filter.SourceType != "" {
+		args = append(args, filter.SourceType)
+		conditions = append(conditions, fmt.Sprintf("source_type = $%d", len(args)))
+	}
+	if filter.SourceID != "" {
+		args = append(args, filter.SourceID)
+		conditions = append(conditions, fmt.Sprintf("source_id = $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// queryEntries loads journal entries selected with journalEntryColumns and attaches their lines
+func (r *JournalRepositoryImpl) queryEntries(ctx context.Context, query string, args ...interface{}) ([]models.JournalEntry, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+	entries, err := scanJournalEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := attachJournalLines(ctx, r.db, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// rowsQuerier is implemented by both *pgx.Conn and pgx.Tx
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// loadAccounts returns the chart of accounts by role, with defaults for roles never mapped
+func loadAccounts(ctx context.Context, q rowsQuerier) (map[models.AccountRole]models.Account, error) {
+	accounts := map[models.AccountRole]models.Account{}
+	for _, a := range models.DefaultAccounts() {
+		accounts[a.Role] = a
+	}
+
+	rows, err := q.Query(ctx, `SELECT role, code, name, updated_at FROM account_mappings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account mappings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.Account
+		if err := rows.Scan(&a.Role, &a.Code, &a.Name, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account mapping: %w", err)
+		}
+		if a.Role.IsValid() {
+			accounts[a.Role] = a
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating account mappings: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// scanJournalEntries reads and closes rows selected with journalEntryColumns
+func scanJournalEntries(rows pgx.Rows) ([]models.JournalEntry, error) {
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	for rows.Next() {
+		var e models.JournalEntry
+		err := rows.Scan(&e.ID, &e.EntryNo, &e.EntryDate, &e.SourceType, &e.SourceID, &e.Reference,
+			&e.Description, &e.Currency, &e.ReversalOf, &e.ReversedAt, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		e.Lines = []models.JournalLine{}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating journal entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// attachJournalLines loads the lines of every entry in one query
+func attachJournalLines(ctx context.Context, q rowsQuerier, entries []models.JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(entries))
+	index := make(map[string]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.ID
+		index[e.ID] = i
+	}
+
+	rows, err := q.Query(ctx, `SELECT id, entry_id, line_no, account_role, account_code, account_name,
+			debit, credit, memo
+		FROM journal_lines WHERE entry_id = ANY($1)
+		ORDER BY entry_id, line_no`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get journal lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l := models.JournalLine{
+			Debit:  money.Zero(money.BaseCurrency),
+			Credit: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(&l.ID, &l.EntryID, &l.LineNo, &l.AccountRole, &l.AccountCode, &l.AccountName,
+			&l.Debit, &l.Credit, &l.Memo)
+		if err != nil {
+			return fmt.Errorf("failed to scan journal line: %w", err)
+		}
+		if i, ok := index[l.EntryID]; ok {
+			entries[i].Lines = append(entries[i].Lines, l)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating journal lines: %w", err)
+	}
+
+	return nil
+}
+
+// syncDocumentPostings brings a document's cost movements and journal entries in line
+// with its status. It must run inside the document's transaction.
+func syncDocumentPostings(ctx context.Context, tx pgx.Tx, sourceType, sourceID string, wasCompleted, isCompleted bool) error {
+	if err := syncDocumentCosts(ctx, tx, sourceType, sourceID, wasCompleted, isCompleted); err != nil {
+		return err
+	}
+	return syncDocumentJournal(ctx, tx, sourceType, sourceID, isCompleted)
+}
+
+// journalSourceTypes lists the entry types each document posts
+var journalSourceTypes = map[string][]string{
+	models.CostSourceSale:          {models.JournalSourceSale, models.JournalSourceSalePayment},
+	models.CostSourceStockIn:       {models.JournalSourceStockIn, models.JournalSourceStockInPayment},
+	models.CostSourceReject:        {models.JournalSourceReject},
+	models.JournalSourceLandedCost: {models.JournalSourceLandedCost},
+
+	models.JournalSourceCustomerPayment: {models.JournalSourceCustomerPayment},
+	models.JournalSourceSupplierPayment: {models.JournalSourceSupplierPayment},
+	models.JournalSourceSupplierCredit:  {models.JournalSourceSupplierCredit},
+}
+
+// syncDocumentJournal posts the journal entries a document should have and reverses
+// those it no longer should. Entries that have not changed are left alone, so a
+// document saved again without changes does not add entries to the ledger.
+func syncDocumentJournal(ctx context.Context, tx pgx.Tx, sourceType, sourceID string, isPosted bool) error {
+	sourceTypes, ok := journalSourceTypes[sourceType]
+	if !ok {
+		return fmt.Errorf("unknown journal source %q", sourceType)
+	}
+
+	wanted := map[string]*models.JournalEntry{}
+	if isPosted {
+		entries, err := buildDocumentJournal(ctx, tx, sourceType, sourceID)
+		if err != nil {
+			return err
+		}
+		accounts, err := loadAccounts(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if len(e.Lines) == 0 {
+				continue
+			}
+			e.ApplyAccounts(accounts)
+			if err := e.Validate(); err != nil {
+				return err
+			}
+			wanted[e.SourceType] = e
+		}
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`SELECT %s FROM journal_entries
+		WHERE source_type = ANY($1) AND source_id = $2 AND reversal_of IS NULL AND reversed_at IS NULL
+		ORDER BY created_at`, journalEntryColumns), sourceTypes, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to get journal entries: %w", err)
+	}
+	posted, err := scanJournalEntries(rows)
+	if err != nil {
+		return err
+	}
+	if err := attachJournalLines(ctx, tx, posted); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range posted {
+		entry := &posted[i]
+		if e, ok := wanted[entry.SourceType]; ok && e.SameAs(entry) {
+			delete(wanted, entry.SourceType)
+			continue
+		}
+		if err := insertJournalEntry(ctx, tx, entry.Reverse(now)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `UPDATE journal_entries SET reversed_at = $1 WHERE id = $2`, now, entry.ID)
+		if err != nil {
+			return fmt.Errorf("failed to mark journal entry reversed: %w", err)
+		}
+	}
+
+	for _, t := range sourceTypes {
+		if e, ok := wanted[t]; ok {
+			if err := insertJournalEntry(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildDocumentJournal builds the entries a completed document posts, in the base currency
+func buildDocumentJournal(ctx context.Context, tx pgx.Tx, sourceType, sourceID string) ([]*models.JournalEntry, error) {
+	var reference string
+	var date time.Time
+	var rate money.Rate
+	currency := money.BaseCurrency
+	total := money.Zero(money.BaseCurrency)
+	paid := money.Zero(money.BaseCurrency)
+	tax := money.Zero(money.BaseCurrency)
+	cogs := money.Zero(money.BaseCurrency)
+
+	switch sourceType {
+	case models.CostSourceSale:
+		err := tx.QueryRow(ctx, `SELECT s.reference_no, s.sale_date, s.currency, s.exchange_rate,
+				s.base_total,
+				COALESCE(s.paid, 0) - COALESCE((SELECT SUM(pa.amount) FROM payment_allocations pa WHERE pa.sale_id = s.id), 0),
+				COALESCE(SUM(si.tax), 0), COALESCE(SUM(si.cogs), 0)
+			FROM sales s
+			LEFT JOIN sale_items si ON si.sale_id = s.id AND si.deleted_at IS NULL
+			WHERE s.id = $1
+			GROUP BY s.id`, sourceID).Scan(&reference, &date, &currency, &rate, &total, &paid, &tax, &cogs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sale for journal: %w", err)
+		}
+		return []*models.JournalEntry{
+			models.SaleJournal(sourceID, reference, date, total, toBase(rate, tax, currency), cogs),
+			models.SalePaymentJournal(sourceID, reference, date, toBase(rate, paid, currency)),
+		}, nil
+
+	case models.CostSourceStockIn:
+		var opening bool
+		err := tx.QueryRow(ctx, `SELECT si.reference_no, si.order_date, si.currency, si.exchange_rate,
+				si.base_total, si.opening,
+				COALESCE(si.paid, 0) - COALESCE((SELECT SUM(p.amount) FROM stock_in_payments p WHERE p.stock_in_id = si.id), 0),
+				COALESCE((SELECT SUM(i.tax) FROM stock_in_items i WHERE i.stock_in_id = si.id AND i.deleted_at IS NULL), 0)
+			FROM stock_ins si WHERE si.id = $1`, sourceID).Scan(&reference, &date, &currency, &rate, &total, &opening, &paid, &tax)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock-in for journal: %w", err)
+		}
+		if opening {
+			return []*models.JournalEntry{models.OpeningStockJournal(sourceID, reference, date, total)}, nil
+		}
+		return []*models.JournalEntry{
+			models.StockInJournal(sourceID, reference, date, total, toBase(rate, tax, currency)),
+			models.StockInPaymentJournal(sourceID, reference, date, toBase(rate, paid, currency)),
+		}, nil
+
+	case models.CostSourceReject:
+		err := tx.QueryRow(ctx, `SELECT r.reference_no, r.reject_date, COALESCE(SUM(ri.cogs), 0)
+			FROM rejects r
+			LEFT JOIN reject_items ri ON ri.reject_id = r.id AND ri.deleted_at IS NULL
+			WHERE r.id = $1
+			GROUP BY r.id`, sourceID).Scan(&reference, &date, &cogs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reject for journal: %w", err)
+		}
+		return []*models.JournalEntry{models.RejectJournal(sourceID, reference, date, cogs)}, nil
+
+	case models.JournalSourceLandedCost:
+		var chargeType string
+		err := tx.QueryRow(ctx, `SELECT reference_no, charge_date, charge_type, base_amount
+			FROM landed_costs WHERE id = $1`, sourceID).Scan(&reference, &date, &chargeType, &total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get landed cost for journal: %w", err)
+		}
+		return []*models.JournalEntry{models.LandedCostJournal(sourceID, reference, date, total, chargeType)}, nil
+
+	case models.JournalSourceCustomerPayment:
+		err := tx.QueryRow(ctx, `SELECT payment_no, payment_date, base_amount
+			FROM customer_payments WHERE id = $1`, sourceID).Scan(&reference, &date, &total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get customer payment for journal: %w", err)
+		}
+		return []*models.JournalEntry{models.CustomerPaymentJournal(sourceID, reference, date, total)}, nil
+
+	case models.JournalSourceSupplierPayment:
+		err := tx.QueryRow(ctx, `SELECT payment_no, payment_date, base_amount
+			FROM stock_in_payments WHERE id = $1`, sourceID).Scan(&reference, &date, &total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get supplier payment for journal: %w", err)
+		}
+		return []*models.JournalEntry{models.SupplierPaymentJournal(sourceID, reference, date, total)}, nil
+
+	case models.JournalSourceSupplierCredit:
+		var reason string
+		err := tx.QueryRow(ctx, `SELECT credit_no, credit_date, base_amount, COALESCE(reason, '')
+			FROM supplier_credits WHERE id = $1`, sourceID).Scan(&reference, &date, &total, &reason)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get supplier credit for journal: %w", err)
+		}
+		return []*models.JournalEntry{models.SupplierCreditJournal(sourceID, reference, date, total, reason)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown journal source %q", sourceType)
+}
+
+// toBase converts a document-currency amount scanned from the database to the base currency
+func toBase(rate money.Rate, amount money.Money, currency string) money.Money {
+	currency = money.NormalizeCurrency(currency)
+	return rate.Convert(amount.In(currency), money.BaseCurrency).Round()
+}
+
+// insertJournalEntry stores a balanced journal entry and its lines
+func insertJournalEntry(ctx context.Context, tx pgx.Tx, entry *models.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `INSERT INTO journal_entries (
+			id, entry_no, entry_date, source_type, source_id, reference, description,
+			currency, reversal_of, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		entry.ID, entry.EntryNo, entry.EntryDate, entry.SourceType, entry.SourceID, entry.Reference,
+		entry.Description, entry.Currency, entry.ReversalOf, entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	for _, l := range entry.Lines {
+		_, err = tx.Exec(ctx, `INSERT INTO journal_lines (
+				id, entry_id, line_no, account_role, account_code, account_name, debit, credit, memo
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			l.ID, entry.ID, l.LineNo, l.AccountRole, l.AccountCode, l.AccountName, l.Debit, l.Credit, l.Memo,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert journal line: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,456 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidKit is returned when a kit's components are missing or are kits themselves, or
+// the kit is a component of another kit
+var ErrInvalidKit = errors.New("invalid kit")
+
+// ErrInsufficientStock is returned when there is not enough stock of a component to assemble
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// KitRepository defines methods for kits and the assemblies that build kits and products
+// with a bill of materials
+type KitRepository interface {
+	GetAll() ([]models.Kit, error)
+	// GetByProductID returns the kit definition of a product, or nil when it is not a kit
+	GetByProductID(productID string) (*models.Kit, error)
+	// Save creates or replaces a kit's definition
+	Save(kit *models.Kit) error
+	Delete(productID string) error
+
+	GetAssembly(id string) (*models.Assembly, error)
+	GetAssemblyByReference(referenceNo string) (*models.Assembly, error)
+	ListAssemblies(productID string, startDate, endDate *time.Time) ([]models.Assembly, error)
+	// CreateAssembly consumes the components and adds what it builds to stock at their cost
+	CreateAssembly(assembly *models.Assembly, lock *models.PeriodLock) error
+	// DeleteAssembly returns the components to stock and takes what it built out again
+	DeleteAssembly(id string, lock *models.PeriodLock) error
+}
+
+// KitRepositoryImpl implements the KitRepository interface
+type KitRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewKitRepository creates a new KitRepository
+func NewKitRepository(db *pgx.Conn) KitRepository {
+	return &KitRepositoryImpl{db: db}
+}
+
+// GetAll retrieves every kit with its components and what can be sold
+func (r *KitRepositoryImpl) GetAll() ([]models.Kit, error) {
+	return r.queryKits(context.Background(), ``)
+}
+
+// GetByProductID retrieves a product's kit definition
+func (r *KitRepositoryImpl) GetByProductID(productID string) (*models.Kit, error) {
+	kits, err := r.queryKits(context.Background(), `WHERE pk.product_id = $1`, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(kits) == 0 {
+		return nil, nil
+	}
+	return &kits[0], nil
+}
+
+func (r *KitRepositoryImpl) queryKits(ctx context.Context, where string, args ...any) ([]models.Kit, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT pk.product_id, COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''), p.stock,
+			pk.assembled, pk.created_at, pk.updated_at
+		FROM product_kits pk
+		JOIN products p ON p.id = pk.product_id AND p.deleted_at IS NULL
+		`+where+`
+		ORDER BY p.basic->>'name'`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kits: %w", err)
+	}
+
+	kits := []models.Kit{}
+	index := map[string]int{}
+	for rows.Next() {
+		var kit models.Kit
+		err := rows.Scan(&kit.ProductID, &kit.Name, &kit.SKU, &kit.Stock,
+			&kit.Assembled, &kit.CreatedAt, &kit.UpdatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan kit: %w", err)
+		}
+		index[kit.ProductID] = len(kits)
+		kit.Components = []models.KitComponent{}
+		kits = append(kits, kit)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating kits: %w", err)
+	}
+	if len(kits) == 0 {
+		return kits, nil
+	}
+
+	ids := make([]string, len(kits))
+	for i, kit := range kits {
+		ids[i] = kit.ProductID
+	}
+	rows, err = r.db.Query(ctx, `
+		SELECT kc.id, kc.kit_id, kc.component_id, kc.quantity,
+			COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''), COALESCE(p.stock, 0)
+		FROM kit_components kc
+		LEFT JOIN products p ON p.id = kc.component_id AND p.deleted_at IS NULL
+		WHERE kc.kit_id = ANY($1)
+		ORDER BY kc.sort_order, kc.id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kit components: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.KitComponent
+		if err := rows.Scan(&c.ID, &c.KitID, &c.ComponentID, &c.Quantity, &c.Name, &c.SKU, &c.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan kit component: %w", err)
+		}
+		kit := &kits[index[c.KitID]]
+		kit.Components = append(kit.Components, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating kit components: %w", err)
+	}
+
+	for i := range kits {
+		kits[i].CalculateAvailable()
+	}
+	return kits, nil
+}
+
+// Save stores the kit and replaces its components. Components must be products that are
+// not kits, and the kit cannot be a component of another kit.
+func (r *KitRepositoryImpl) Save(kit *models.Kit) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	componentIDs := make([]string, len(kit.Components))
+	for i, c := range kit.Components {
+		componentIDs[i] = c.ComponentID
+	}
+
+	var found, kitComponents int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(pk.product_id)
+		FROM products p
+		LEFT JOIN product_kits pk ON pk.product_id = p.id
+		WHERE p.id = ANY($1) AND p.deleted_at IS NULL`, componentIDs).Scan(&found, &kitComponents)
+	if err != nil {
+		return fmt.Errorf("failed to check kit components: %w", err)
+	}
+	if found != len(componentIDs) {
+		return fmt.Errorf("%w: component product not found", ErrInvalidKit)
+	}
+	if kitComponents > 0 {
+		return fmt.Errorf("%w: a component cannot be a kit", ErrInvalidKit)
+	}
+
+	var usedIn int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM kit_components WHERE component_id = $1`, kit.ProductID).Scan(&usedIn)
+	if err != nil {
+		return fmt.Errorf("failed to check kit components: %w", err)
+	}
+	if usedIn > 0 {
+		return fmt.Errorf("%w: the product is a component of another kit", ErrInvalidKit)
+	}
+
+	// Stock built by assemblies would be stranded if the kit stopped selling from its own stock
+	if !kit.Assembled {
+		var assembledStock int
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(p.stock), 0)
+			FROM product_kits pk JOIN products p ON p.id = pk.product_id
+			WHERE pk.product_id = $1 AND pk.assembled`, kit.ProductID).Scan(&assembledStock)
+		if err != nil {
+			return fmt.Errorf("failed to check kit stock: %w", err)
+		}
+		if assembledStock > 0 {
+			return fmt.Errorf("%w: the kit still has %d assembled in stock", ErrInvalidKit, assembledStock)
+		}
+	}
+
+	now := time.Now()
+	kit.UpdatedAt = now
+	err = tx.QueryRow(ctx, `
+		INSERT INTO product_kits (product_id, assembled, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (product_id) DO UPDATE SET assembled = EXCLUDED.assembled, updated_at = EXCLUDED.updated_at
+		RETURNING created_at`,
+		kit.ProductID, kit.Assembled, now).Scan(&kit.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save kit: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM kit_components WHERE kit_id = $1`, kit.ProductID); err != nil {
+		return fmt.Errorf("failed to replace kit components: %w", err)
+	}
+	for i, c := range kit.Components {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO kit_components (id, kit_id, component_id, quantity, sort_order)
+			VALUES ($1, $2, $3, $4, $5)`,
+			c.ID, kit.ProductID, c.ComponentID, c.Quantity, i)
+		if err != nil {
+			return fmt.Errorf("failed to insert kit component: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a kit's definition; the product stays. Sales and assemblies already made keep
+// the stock movements they caused.
+func (r *KitRepositoryImpl) Delete(productID string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `DELETE FROM kit_components WHERE kit_id = $1`, productID); err != nil {
+		return fmt.Errorf("failed to delete kit components: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM product_kits WHERE product_id = $1`, productID); err != nil {
+		return fmt.Errorf("failed to delete kit: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+const assemblyColumns = `id, reference_no, product_id, product_name, bom_id, batches, expected_quantity,
+	quantity, assembly_date, COALESCE(note, ''), unit_cost, total_cost, created_at, updated_at`
+
+func scanAssembly(row pgx.Row) (*models.Assembly, error) {
+	var a models.Assembly
+	err := row.Scan(
+		&a.ID, &a.ReferenceNo, &a.ProductID, &a.ProductName, &a.BOMID, &a.Batches, &a.ExpectedQuantity,
+		&a.Quantity, &a.AssemblyDate,
+		&a.Note, &a.UnitCost, &a.TotalCost, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAssembly retrieves an assembly with the components it consumed
+func (r *KitRepositoryImpl) GetAssembly(id string) (*models.Assembly, error) {
+	return r.getAssembly(`id = $1`, id)
+}
+
+// GetAssemblyByReference retrieves an assembly by its reference number
+func (r *KitRepositoryImpl) GetAssemblyByReference(referenceNo string) (*models.Assembly, error) {
+	return r.getAssembly(`reference_no = $1`, referenceNo)
+}
+
+func (r *KitRepositoryImpl) getAssembly(condition string, arg any) (*models.Assembly, error) {
+	ctx := context.Background()
+	query := `SELECT ` + assemblyColumns + ` FROM assemblies WHERE ` + condition + ` AND deleted_at IS NULL`
+
+	assembly, err := scanAssembly(r.db.QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get assembly: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, assembly_id, component_id, component_name, quantity, unit_cost, total_cost
+		FROM assembly_items WHERE assembly_id = $1 ORDER BY component_name`, assembly.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assembly items: %w", err)
+	}
+	defer rows.Close()
+
+	assembly.Items = []models.AssemblyItem{}
+	for rows.Next() {
+		item := models.AssemblyItem{UnitCost: money.Zero(money.BaseCurrency), TotalCost: money.Zero(money.BaseCurrency)}
+		err := rows.Scan(&item.ID, &item.AssemblyID, &item.ComponentID, &item.ComponentName,
+			&item.Quantity, &item.UnitCost, &item.TotalCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan assembly item: %w", err)
+		}
+		assembly.Items = append(assembly.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating assembly items: %w", err)
+	}
+
+	assembly.BindCurrency()
+	return assembly, nil
+}
+
+// ListAssemblies retrieves assemblies newest first, optionally for one product or period
+func (r *KitRepositoryImpl) ListAssemblies(productID string, startDate, endDate *time.Time) ([]models.Assembly, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT `+assemblyColumns+` FROM assemblies
+		WHERE deleted_at IS NULL AND ($1 = '' OR product_id = $1)
+		AND ($2::timestamptz IS NULL OR assembly_date >= $2)
+		AND ($3::timestamptz IS NULL OR assembly_date <= $3)
+		ORDER BY assembly_date DESC, created_at DESC`,
+		productID, startDate, endDate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assemblies: %w", err)
+	}
+	defer rows.Close()
+
+	assemblies := []models.Assembly{}
+	for rows.Next() {
+		assembly, err := scanAssembly(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan assembly: %w", err)
+		}
+		assembly.BindCurrency()
+		assemblies = append(assemblies, *assembly)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating assemblies: %w", err)
+	}
+
+	return assemblies, nil
+}
+
+// CreateAssembly stores the assembly, moves the stock from the components to the kit and
+// costs it, all in one transaction
+func (r *KitRepositoryImpl) CreateAssembly(assembly *models.Assembly, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	assembly.CreatedAt = now
+	assembly.UpdatedAt = now
+	assembly.BindCurrency()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO assemblies (
+			id, reference_no, product_id, product_name, bom_id, batches, expected_quantity,
+			quantity, assembly_date, note, unit_cost, total_cost, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		assembly.ID, assembly.ReferenceNo, assembly.ProductID, assembly.ProductName, assembly.BOMID,
+		assembly.Batches, assembly.ExpectedQuantity, assembly.Quantity, assembly.AssemblyDate, assembly.Note,
+		assembly.UnitCost, assembly.TotalCost, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert assembly: %w", err)
+	}
+
+	for _, item := range assembly.Items {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO assembly_items (id, assembly_id, component_id, component_name, quantity, unit_cost, total_cost)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			item.ID, assembly.ID, item.ComponentID, item.ComponentName, item.Quantity, item.UnitCost, item.TotalCost,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert assembly item: %w", err)
+		}
+
+		var stock int
+		err = tx.QueryRow(ctx, `SELECT stock FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+			item.ComponentID).Scan(&stock)
+		if err != nil {
+			return fmt.Errorf("failed to get component stock: %w", err)
+		}
+		if stock < item.Quantity {
+			return fmt.Errorf("%w of %s: %d needed, %d in stock", ErrInsufficientStock, item.ComponentName, item.Quantity, stock)
+		}
+		if _, err = tx.Exec(ctx, `UPDATE products SET stock = stock - $1 WHERE id = $2`, item.Quantity, item.ComponentID); err != nil {
+			return fmt.Errorf("failed to update component stock: %w", err)
+		}
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE products SET stock = stock + $1 WHERE id = $2`, assembly.Quantity, assembly.ProductID); err != nil {
+		return fmt.Errorf("failed to update assembled product stock: %w", err)
+	}
+
+	// Assemblies move cost between inventory items only, so they post no journal entry
+	if err = syncDocumentCosts(ctx, tx, models.CostSourceAssembly, assembly.ID, false, true); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteAssembly reverses an assembly's stock and cost movements and soft-deletes it. It
+// returns ErrCostLayerConsumed once any of the units it built have been issued.
+func (r *KitRepositoryImpl) DeleteAssembly(id string, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	var productID string
+	var quantity int
+	err = tx.QueryRow(ctx, `SELECT product_id, quantity FROM assemblies WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		id).Scan(&productID, &quantity)
+	if err != nil {
+		return fmt.Errorf("failed to get assembly: %w", err)
+	}
+
+	if err = syncDocumentCosts(ctx, tx, models.CostSourceAssembly, id, true, false); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE products p SET stock = p.stock + ai.quantity
+		FROM assembly_items ai
+		WHERE ai.assembly_id = $1 AND p.id = ai.component_id`, id)
+	if err != nil {
+		return fmt.Errorf("failed to return component stock: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `UPDATE products SET stock = stock - $1 WHERE id = $2`, quantity, productID); err != nil {
+		return fmt.Errorf("failed to update assembled product stock: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE assemblies SET deleted_at = $1 WHERE id = $2`, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to delete assembly: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLabelProductNotFound is returned when labels are asked for a product that does not exist
+var ErrLabelProductNotFound = errors.New("product not found")
+
+// LabelRepository defines methods for label templates and the product data printed on labels
+type LabelRepository interface {
+	GetTemplates() ([]models.LabelTemplate, error)
+	GetTemplateByID(id string) (*models.LabelTemplate, error)
+	GetTemplateByCode(code string) (*models.LabelTemplate, error)
+	CreateTemplate(template *models.LabelTemplate) error
+	UpdateTemplate(template *models.LabelTemplate) error
+	DeleteTemplate(id string) error
+
+	// GetLabels returns the labels for the items, each repeated by its quantity, in the
+	// order given. It returns ErrLabelProductNotFound naming the first product that does not exist.
+	GetLabels(items []models.LabelItem) ([]models.Label, error)
+	// GetStockInItems returns the products a stock-in received and how many of each, in base
+	// units. It returns nil if there is no such stock-in.
+	GetStockInItems(stockInID string) ([]models.LabelItem, error)
+}
+
+// LabelRepositoryImpl implements the LabelRepository interface
+type LabelRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewLabelRepository creates a new LabelRepository
+func NewLabelRepository(db *pgx.Conn) LabelRepository {
+	return &LabelRepositoryImpl{db: db}
+}
+
+const labelTemplateColumns = `id, code, name, page_width, page_height, columns, rows, label_width, label_height,
+	margin_top, margin_left, gap_x, gap_y, created_at, updated_at`
+
+func scanLabelTemplate(row pgx.Row) (*models.LabelTemplate, error) {
+	var t models.LabelTemplate
+	err := row.Scan(&t.ID, &t.Code, &t.Name, &t.PageWidth, &t.PageHeight, &t.Columns, &t.Rows,
+		&t.LabelWidth, &t.LabelHeight, &t.MarginTop, &t.MarginLeft, &t.GapX, &t.GapY,
+		&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTemplates retrieves the label templates ordered by name
+func (r *LabelRepositoryImpl) GetTemplates() ([]models.LabelTemplate, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT `+labelTemplateColumns+` FROM label_templates WHERE deleted_at IS NULL ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query label templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.LabelTemplate{}
+	for rows.Next() {
+		t, err := scanLabelTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan label template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating label templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// GetTemplateByID retrieves a label template by ID
+func (r *LabelRepositoryImpl) GetTemplateByID(id string) (*models.LabelTemplate, error) {
+	return r.getTemplate(`id = $1`, id)
+}
+
+// GetTemplateByCode retrieves a label template by its code
+func (r *LabelRepositoryImpl) GetTemplateByCode(code string) (*models.LabelTemplate, error) {
+	return r.getTemplate(`code = $1`, strings.ToLower(strings.TrimSpace(code)))
+}
+
+func (r *LabelRepositoryImpl) getTemplate(condition string, arg any) (*models.LabelTemplate, error) {
+	query := `SELECT ` + labelTemplateColumns + ` FROM label_templates WHERE ` + condition + ` AND deleted_at IS NULL`
+
+	t, err := scanLabelTemplate(r.db.QueryRow(context.Background(), query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get label template: %w", err)
+	}
+	return t, nil
+}
+
+// CreateTemplate stores a new label template
+func (r *LabelRepositoryImpl) CreateTemplate(t *models.LabelTemplate) error {
+	now := time.Now()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO label_templates (id, code, name, page_width, page_height, columns, rows, label_width, label_height,
+			margin_top, margin_left, gap_x, gap_y, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		t.ID, t.Code, t.Name, t.PageWidth, t.PageHeight, t.Columns, t.Rows, t.LabelWidth, t.LabelHeight,
+		t.MarginTop, t.MarginLeft, t.GapX, t.GapY, t.CreatedAt, t.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create label template: %w", err)
+	}
+	return nil
+}
+
+// UpdateTemplate saves changes to a label template
+func (r *LabelRepositoryImpl) UpdateTemplate(t *models.LabelTemplate) error {
+	t.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(context.Background(), `
+		UPDATE label_templates
+		SET code = $1, name = $2, page_width = $3, page_height = $4, columns = $5, rows = $6,
+			label_width = $7, label_height = $8, margin_top = $9, margin_left = $10, gap_x = $11, gap_y = $12,
+			updated_at = $13
+		WHERE id = $14 AND deleted_at IS NULL`,
+		t.Code, t.Name, t.PageWidth, t.PageHeight, t.Columns, t.Rows, t.LabelWidth, t.LabelHeight,
+		t.MarginTop, t.MarginLeft, t.GapX, t.GapY, t.UpdatedAt, t.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update label template: %w", err)
+	}
+	return nil
+}
+
+// DeleteTemplate soft-deletes a label template
+func (r *LabelRepositoryImpl) DeleteTemplate(id string) error {
+	_, err := r.db.Exec(context.Background(),
+		`UPDATE label_templates SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete label template: %w", err)
+	}
+	return nil
+}
+
+// GetLabels looks up the name, price, SKU and primary barcode of each product once and
+// repeats its label by the quantity asked for
+func (r *LabelRepositoryImpl) GetLabels(items []models.LabelItem) ([]models.Label, error) {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	rows, err := r.db.Query(context.Background(), `
+		SELECT p.id, COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''),
+			COALESCE(p.price->>'price', '0'), COALESCE(p.price->>'currency', ''),
+			COALESCE(b.code, ''), COALESCE(b.symbology, '')
+		FROM products p
+		LEFT JOIN product_barcodes b ON b.product_id = p.id AND b.is_primary
+		WHERE p.id = ANY($1) AND p.deleted_at IS NULL`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]models.Label, len(ids))
+	for rows.Next() {
+		var label models.Label
+		err := rows.Scan(&label.ProductID, &label.Name, &label.SKU, &label.Price, &label.Currency,
+			&label.Barcode, &label.Symbology)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		label.Currency = money.NormalizeCurrency(label.Currency)
+		label.Price = label.Price.In(label.Currency)
+		byID[label.ProductID] = label
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating products: %w", err)
+	}
+
+	labels := []models.Label{}
+	for _, item := range items {
+		label, ok := byID[item.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrLabelProductNotFound, item.ProductID)
+		}
+		for i := 0; i < item.Quantity; i++ {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// GetStockInItems sums the base-unit quantities a stock-in received per product, in the
+// order the products were entered
+func (r *LabelRepositoryImpl) GetStockInItems(stockInID string) ([]models.LabelItem, error) {
+	ctx := context.Background()
+
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM stock_ins WHERE id = $1 AND deleted_at IS NULL)`,
+		stockInID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock in: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT product_id, SUM(quantity)
+		FROM stock_in_items
+		WHERE stock_in_id = $1 AND deleted_at IS NULL AND quantity > 0
+		GROUP BY product_id
+		ORDER BY MIN(created_at) ASC, product_id ASC`, stockInID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock in items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.LabelItem{}
+	for rows.Next() {
+		var item models.LabelItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan stock in item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock in items: %w", err)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,530 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLandedCostStockIn is returned when a charge refers to a missing or cancelled stock-in
+var ErrLandedCostStockIn = errors.New("stock-in not found or cancelled")
+
+// LandedCostRepository defines methods for landed cost operations
+type LandedCostRepository interface {
+	GetByID(id string) (*models.LandedCost, error)
+	Create(landedCost *models.LandedCost, lock *models.PeriodLock) error
+	Update(landedCost *models.LandedCost, lock *models.PeriodLock) error
+	Delete(id string, lock *models.PeriodLock) error
+	List(stockInID string, startDate, endDate *time.Time) ([]models.LandedCost, error)
+	GetStockInAllocations(stockInID string) ([]models.LandedCostAllocation, error)
+}
+
+// LandedCostRepositoryImpl implements the LandedCostRepository interface
+type LandedCostRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewLandedCostRepository creates a new LandedCostRepository
+func NewLandedCostRepository(db *pgx.Conn) LandedCostRepository {
+	return &LandedCostRepositoryImpl{db: db}
+}
+
+const landedCostColumns = `id, reference_no, charge_type, COALESCE(description, ''), charge_date,
+	allocation_method, amount, currency, exchange_rate, base_amount, supplier_id, created_at, updated_at`
+
+func scanLandedCost(row pgx.Row) (*models.LandedCost, error) {
+	var l models.LandedCost
+	err := row.Scan(
+		&l.ID, &l.ReferenceNo, &l.ChargeType, &l.Description, &l.ChargeDate,
+		&l.AllocationMethod, &l.Amount, &l.Currency, &l.ExchangeRate, &l.BaseAmount,
+		&l.SupplierID, &l.CreatedAt, &l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetByID retrieves a landed cost with its stock-ins and allocations
+func (r *LandedCostRepositoryImpl) GetByID(id string) (*models.LandedCost, error) {
+	ctx := context.Background()
+	query := `SELECT ` + landedCostColumns + ` FROM landed_costs WHERE id = $1 AND deleted_at IS NULL`
+
+	landedCost, err := scanLandedCost(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get landed cost: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT stock_in_id FROM landed_cost_stock_ins
+		WHERE landed_cost_id = $1 ORDER BY stock_in_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get landed cost stock-ins: %w", err)
+	}
+	landedCost.StockInIDs, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan landed cost stock-ins: %w", err)
+	}
+
+	landedCost.Allocations, err = r.queryAllocations(ctx, `WHERE a.landed_cost_id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	landedCost.BindCurrency()
+
+	return landedCost, nil
+}
+
+// Create stores a landed cost, allocates it to the items of its stock-ins and
+// re-costs the stock-ins that are already completed
+func (r *LandedCostRepositoryImpl) Create(landedCost *models.LandedCost, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	if landedCost.ID == "" {
+		landedCost.ID = uuid.NewString()
+	}
+	now := time.Now()
+	landedCost.CreatedAt = now
+	landedCost.UpdatedAt = now
+	landedCost.BindCurrency()
+
+	_, err = tx.Exec(ctx, `INSERT INTO landed_costs (
+			id, reference_no, charge_type, description, charge_date, allocation_method,
+			amount, currency, exchange_rate, base_amount, supplier_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		landedCost.ID, landedCost.ReferenceNo, landedCost.ChargeType, landedCost.Description,
+		landedCost.ChargeDate, landedCost.AllocationMethod,
+		landedCost.Amount, landedCost.Currency, landedCost.ExchangeRate, landedCost.BaseAmount,
+		landedCost.SupplierID, landedCost.CreatedAt, landedCost.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert landed cost: %w", err)
+	}
+
+	if err = attachLandedCostStockIns(ctx, tx, landedCost.ID, landedCost.StockInIDs); err != nil {
+		return err
+	}
+
+	affected, err := allocateLandedCost(ctx, tx, landedCost.ID)
+	if err != nil {
+		return err
+	}
+	if err = applyLandedCosts(ctx, tx, affected); err != nil {
+		return err
+	}
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceLandedCost, landedCost.ID, true); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Update changes a landed cost and reallocates it, re-costing the completed
+// stock-ins it was or is now allocated to
+func (r *LandedCostRepositoryImpl) Update(landedCost *models.LandedCost, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	landedCost.UpdatedAt = time.Now()
+	landedCost.BindCurrency()
+
+	_, err = tx.Exec(ctx, `UPDATE landed_costs SET
+			reference_no = $1, charge_type = $2, description = $3, charge_date = $4,
+			allocation_method = $5, amount = $6, currency = $7, exchange_rate = $8,
+			base_amount = $9, supplier_id = $10, updated_at = $11
+		WHERE id = $12`,
+		landedCost.ReferenceNo, landedCost.ChargeType, landedCost.Description, landedCost.ChargeDate,
+		landedCost.AllocationMethod, landedCost.Amount, landedCost.Currency, landedCost.ExchangeRate,
+		landedCost.BaseAmount, landedCost.SupplierID, landedCost.UpdatedAt, landedCost.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update landed cost: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM landed_cost_stock_ins WHERE landed_cost_id = $1`, landedCost.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove landed cost stock-ins: %w", err)
+	}
+	if err = attachLandedCostStockIns(ctx, tx, landedCost.ID, landedCost.StockInIDs); err != nil {
+		return err
+	}
+
+	affected, err := allocateLandedCost(ctx, tx, landedCost.ID)
+	if err != nil {
+		return err
+	}
+	if err = applyLandedCosts(ctx, tx, affected); err != nil {
+		return err
+	}
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceLandedCost, landedCost.ID, true); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Delete soft deletes a landed cost and removes its allocations from the stock-in items
+func (r *LandedCostRepositoryImpl) Delete(id string, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `DELETE FROM landed_cost_allocations
+		WHERE landed_cost_id = $1 RETURNING stock_in_id`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete landed cost allocations: %w", err)
+	}
+	affected, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to scan landed cost allocations: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE landed_costs SET deleted_at = $1 WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete landed cost: %w", err)
+	}
+
+	if err = applyLandedCosts(ctx, tx, affected); err != nil {
+		return err
+	}
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceLandedCost, id, false); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// List returns landed costs, optionally those of one stock-in or charged within a date range
+func (r *LandedCostRepositoryImpl) List(stockInID string, startDate, endDate *time.Time) ([]models.LandedCost, error) {
+	ctx := context.Background()
+
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+	argIndex := 1
+
+	if stockInID != "" {
+		conditions = append(conditions, fmt.Sprintf(
+			"id IN (SELECT landed_cost_id FROM landed_cost_stock_ins WHERE stock_in_id = $%d)", argIndex))
+		args = append(args, stockInID)
+		argIndex++
+	}
+	if startDate != nil {
+		conditions = append(conditions, fmt.Sprintf("charge_date >= $%d", argIndex))
+		args = append(args, *startDate)
+		argIndex++
+	}
+	if endDate != nil {
+		conditions = append(conditions, fmt.Sprintf("charge_date <= $%d", argIndex))
+		args = append(args, *endDate)
+		argIndex++
+	}
+
+	query := `SELECT ` + landedCostColumns + ` FROM landed_costs WHERE ` +
+		strings.Join(conditions, " AND ") + ` ORDER BY charge_date DESC, created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list landed costs: %w", err)
+	}
+	defer rows.Close()
+
+	landedCosts := []models.LandedCost{}
+	for rows.Next() {
+		landedCost, err := scanLandedCost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan landed cost: %w", err)
+		}
+		landedCost.BindCurrency()
+		landedCosts = append(landedCosts, *landedCost)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating landed costs: %w", err)
+	}
+
+	return landedCosts, nil
+}
+
+// GetStockInAllocations returns the landed cost allocations of a stock-in's items
+func (r *LandedCostRepositoryImpl) GetStockInAllocations(stockInID string) ([]models.LandedCostAllocation, error) {
+	return r.queryAllocations(context.Background(), `WHERE a.stock_in_id = $1`, stockInID)
+}
+
+// queryAllocations returns the allocations of live charges matching the condition
+func (r *LandedCostRepositoryImpl) queryAllocations(ctx context.Context, where string, args ...interface{}) ([]models.LandedCostAllocation, error) {
+	query := `SELECT a.id, a.landed_cost_id, a.stock_in_id, a.stock_in_item_id, a.product_id,
+			COALESCE(i.product_name, ''), a.basis, a.amount,
+			l.reference_no, l.charge_type, l.allocation_method
+		FROM landed_cost_allocations a
+		JOIN landed_costs l ON l.id = a.landed_cost_id AND l.deleted_at IS NULL
+		LEFT JOIN stock_in_items i ON i.id = a.stock_in_item_id
+		` + where + `
+		ORDER BY l.charge_date, l.reference_no, i.created_at`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get landed cost allocations: %w", err)
+	}
+	defer rows.Close()
+
+	allocations := []models.LandedCostAllocation{}
+	for rows.Next() {
+		a := models.LandedCostAllocation{Amount: money.Zero(money.BaseCurrency)}
+		err := rows.Scan(
+			&a.ID, &a.LandedCostID, &a.StockInID, &a.StockInItemID, &a.ProductID,
+			&a.ProductName, &a.Basis, &a.Amount,
+			&a.ReferenceNo, &a.ChargeType, &a.AllocationMethod,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan landed cost allocation: %w", err)
+		}
+		allocations = append(allocations, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating landed cost allocations: %w", err)
+	}
+
+	return allocations, nil
+}
+
+// attachLandedCostStockIns links a charge to stock-ins, which must exist and not be cancelled
+func attachLandedCostStockIns(ctx context.Context, tx pgx.Tx, landedCostID string, stockInIDs []string) error {
+	for _, stockInID := range stockInIDs {
+		var status models.StockInStatus
+		err := tx.QueryRow(ctx, `SELECT status FROM stock_ins
+			WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, stockInID).Scan(&status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("%w: %s", ErrLandedCostStockIn, stockInID)
+			}
+			return fmt.Errorf("failed to get stock-in status: %w", err)
+		}
+		if status == models.StockInStatusCancelled {
+			return fmt.Errorf("%w: %s", ErrLandedCostStockIn, stockInID)
+		}
+
+		_, err = tx.Exec(ctx, `INSERT INTO landed_cost_stock_ins (landed_cost_id, stock_in_id)
+			VALUES ($1, $2) ON CONFLICT DO NOTHING`, landedCostID, stockInID)
+		if err != nil {
+			return fmt.Errorf("failed to link landed cost to stock-in: %w", err)
+		}
+	}
+	return nil
+}
+
+// allocateLandedCost replaces a charge's allocations with ones for the current items of
+// its stock-ins and returns every stock-in whose allocation may have changed. A charge
+// whose items have no basis is left unallocated with ErrNoAllocationBasis.
+func allocateLandedCost(ctx context.Context, tx pgx.Tx, landedCostID string) ([]string, error) {
+	landedCost, err := scanLandedCost(tx.QueryRow(ctx,
+		`SELECT `+landedCostColumns+` FROM landed_costs WHERE id = $1 FOR UPDATE`, landedCostID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get landed cost: %w", err)
+	}
+	landedCost.BindCurrency()
+
+	rows, err := tx.Query(ctx, `DELETE FROM landed_cost_allocations
+		WHERE landed_cost_id = $1 RETURNING stock_in_id`, landedCostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete landed cost allocations: %w", err)
+	}
+	affected, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan landed cost allocations: %w", err)
+	}
+
+	// Product weight is stored with a unit of 1 = gram or 2 = kilogram
+	rows, err = tx.Query(ctx, `SELECT i.id, i.stock_in_id, i.product_id, i.product_name,
+			i.quantity, i.base_subtotal,
+			COALESCE((p.weight->>'weight')::numeric *
+				CASE WHEN p.weight->>'unit' = '2' THEN 1000 ELSE 1 END, 0)::float8
+		FROM landed_cost_stock_ins ls
+		JOIN stock_ins s ON s.id = ls.stock_in_id AND s.deleted_at IS NULL
+		JOIN stock_in_items i ON i.stock_in_id = s.id AND i.deleted_at IS NULL
+		LEFT JOIN products p ON p.id = i.product_id
+		WHERE ls.landed_cost_id = $1
+		ORDER BY s.order_date, s.id, i.created_at, i.id`, landedCostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock-in items for landed cost: %w", err)
+	}
+	var items []models.LandedCostItem
+	for rows.Next() {
+		item := models.LandedCostItem{BaseSubtotal: money.Zero(money.BaseCurrency)}
+		err := rows.Scan(&item.ItemID, &item.StockInID, &item.ProductID, &item.ProductName,
+			&item.Quantity, &item.BaseSubtotal, &item.UnitWeight)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan stock-in item for landed cost: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stock-in items: %w", err)
+	}
+
+	allocations, err := landedCost.Allocate(items)
+	if err != nil {
+		// The previous allocations are gone, so their stock-ins still need re-costing
+		return affected, fmt.Errorf("failed to allocate %s: %w", landedCost.ReferenceNo, err)
+	}
+
+	now := time.Now()
+	for _, a := range allocations {
+		_, err = tx.Exec(ctx, `INSERT INTO landed_cost_allocations (
+				id, landed_cost_id, stock_in_id, stock_in_item_id, product_id, basis, amount, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			a.ID, a.LandedCostID, a.StockInID, a.StockInItemID, a.ProductID, a.Basis, a.Amount, now,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert landed cost allocation: %w", err)
+		}
+		affected = append(affected, a.StockInID)
+	}
+
+	return affected, nil
+}
+
+// applyLandedCosts totals the allocations of each stock-in's items and re-costs the
+// stock-ins that are completed so their cost layers carry the landed cost
+func applyLandedCosts(ctx context.Context, tx pgx.Tx, stockInIDs []string) error {
+	seen := map[string]bool{}
+	for _, stockInID := range stockInIDs {
+		if seen[stockInID] {
+			continue
+		}
+		seen[stockInID] = true
+
+		_, err := tx.Exec(ctx, `UPDATE stock_in_items i SET landed_cost = COALESCE((
+				SELECT SUM(a.amount)
+				FROM landed_cost_allocations a
+				JOIN landed_costs l ON l.id = a.landed_cost_id AND l.deleted_at IS NULL
+				WHERE a.stock_in_item_id = i.id
+			), 0)
+			WHERE i.stock_in_id = $1 AND i.deleted_at IS NULL`, stockInID)
+		if err != nil {
+			return fmt.Errorf("failed to update stock-in item landed costs: %w", err)
+		}
+
+		if err = repostStockInCosts(ctx, tx, stockInID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reallocateStockInLandedCosts reallocates every charge attached to a stock-in after its
+// items change and re-costs the stock-in along with any other stock-in sharing a charge
+func reallocateStockInLandedCosts(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	landedCostIDs, err := stockInLandedCostIDs(ctx, tx, stockInID)
+	if err != nil {
+		return err
+	}
+
+	affected := []string{stockInID}
+	for _, id := range landedCostIDs {
+		stockInIDs, err := allocateLandedCost(ctx, tx, id)
+		if err != nil && !errors.Is(err, models.ErrNoAllocationBasis) {
+			return err
+		}
+		affected = append(affected, stockInIDs...)
+	}
+
+	return applyLandedCosts(ctx, tx, affected)
+}
+
+// detachStockInLandedCosts removes a deleted stock-in from its charges and
+// reallocates them over the remaining stock-ins
+func detachStockInLandedCosts(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	landedCostIDs, err := stockInLandedCostIDs(ctx, tx, stockInID)
+	if err != nil {
+		return err
+	}
+	if len(landedCostIDs) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM landed_cost_stock_ins WHERE stock_in_id = $1`, stockInID)
+	if err != nil {
+		return fmt.Errorf("failed to remove stock-in from landed costs: %w", err)
+	}
+
+	var affected []string
+	for _, id := range landedCostIDs {
+		stockInIDs, err := allocateLandedCost(ctx, tx, id)
+		if err != nil && !errors.Is(err, models.ErrNoAllocationBasis) {
+			return err
+		}
+		affected = append(affected, stockInIDs...)
+	}
+
+	var remaining []string
+	for _, id := range affected {
+		if id != stockInID {
+			remaining = append(remaining, id)
+		}
+	}
+	return applyLandedCosts(ctx, tx, remaining)
+}
+
+// stockInLandedCostIDs returns the live charges attached to a stock-in
+func stockInLandedCostIDs(ctx context.Context, tx pgx.Tx, stockInID string) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT ls.landed_cost_id
+		FROM landed_cost_stock_ins ls
+		JOIN landed_costs l ON l.id = ls.landed_cost_id AND l.deleted_at IS NULL
+		WHERE ls.stock_in_id = $1`, stockInID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock-in landed costs: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan stock-in landed costs: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,505 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrStockInHasPayments is returned when a stock-in that supplier payments or credits were
+// recorded against would stop being owed to the supplier
+var ErrStockInHasPayments = errors.New("stock-in has supplier payments or credits recorded against it; delete them first")
+
+// PayableRepository defines methods for accounts payable
+type PayableRepository interface {
+	GetAging(asOf time.Time) (*models.APAgingReport, error)
+	GetStatement(supplier *models.Supplier, startDate, endDate time.Time) (*models.SupplierStatement, error)
+
+	// Supplier payments
+	GetPayment(id string) (*models.StockInPayment, error)
+	GetStockInPayments(stockInID string) ([]models.StockInPayment, error)
+	CreatePayment(payment *models.StockInPayment, lock *models.PeriodLock) error
+	DeletePayment(id string, lock *models.PeriodLock) error
+
+	// Supplier credits
+	GetCredit(id string) (*models.SupplierCredit, error)
+	ListCredits(supplierID, stockInID string) ([]models.SupplierCredit, error)
+	CreateCredit(credit *models.SupplierCredit, lock *models.PeriodLock) error
+	DeleteCredit(id string, lock *models.PeriodLock) error
+}
+
+// PayableRepositoryImpl implements the PayableRepository interface
+type PayableRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewPayableRepository creates a new PayableRepository
+func NewPayableRepository(db *pgx.Conn) PayableRepository {
+	return &PayableRepositoryImpl{db: db}
+}
+
+// GetAging buckets what was owed to each supplier on the given date by days past due. Only
+// stock-ins ordered by then are included, and payments and credits recorded after it are
+// added back to their balance.
+func (r *PayableRepositoryImpl) GetAging(asOf time.Time) (*models.APAgingReport, error) {
+	ctx := context.Background()
+	rows, err := r.db.Query(ctx, `SELECT * FROM (
+			SELECT si.id, si.reference_no, COALESCE(si.supplier_id, '') AS supplier_id,
+				COALESCE(s.name, '') AS supplier_name, si.order_date,
+				COALESCE(si.due_date, si.order_date) AS due_date, si.currency, si.exchange_rate,
+				si.total,
+				si.balance
+					+ COALESCE((SELECT SUM(p.amount) FROM stock_in_payments p
+						WHERE p.stock_in_id = si.id AND p.payment_date > $2), 0)
+					+ COALESCE((SELECT SUM(c.amount) FROM supplier_credits c
+						WHERE c.stock_in_id = si.id AND c.credit_date > $2), 0) AS balance
+			FROM stock_ins si
+			LEFT JOIN suppliers s ON s.id = si.supplier_id
+			WHERE si.deleted_at IS NULL AND si.status = $1 AND si.order_date <= $2 AND NOT si.opening
+		) o
+		WHERE o.balance > 0
+		ORDER BY o.due_date, o.order_date, o.reference_no`, models.StockInStatusCompleted, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open bills: %w", err)
+	}
+	defer rows.Close()
+
+	bills := []models.OpenBill{}
+	for rows.Next() {
+		bill := models.OpenBill{
+			Total:   money.Zero(money.BaseCurrency),
+			Balance: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(&bill.StockInID, &bill.ReferenceNo, &bill.SupplierID, &bill.SupplierName,
+			&bill.OrderDate, &bill.DueDate, &bill.Currency, &bill.ExchangeRate,
+			&bill.Total, &bill.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan open bill: %w", err)
+		}
+		bill.Age(asOf)
+		bills = append(bills, bill)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open bills: %w", err)
+	}
+
+	return models.NewAPAgingReport(asOf, bills), nil
+}
+
+// GetStatement lists a supplier's stock-ins, payments and credits between the dates, in the
+// base currency
+func (r *PayableRepositoryImpl) GetStatement(supplier *models.Supplier, startDate, endDate time.Time) (*models.SupplierStatement, error) {
+	ctx := context.Background()
+	var lines []models.StatementLine
+
+	// Each completed stock-in is a bill; whatever was paid when it was made is a payment on the same day
+	rows, err := r.db.Query(ctx, `SELECT si.id, si.reference_no, si.order_date, si.due_date, si.currency,
+			si.exchange_rate, si.base_total,
+			si.paid - COALESCE((SELECT SUM(p.amount) FROM stock_in_payments p WHERE p.stock_in_id = si.id), 0)
+		FROM stock_ins si
+		WHERE si.supplier_id = $1 AND si.deleted_at IS NULL AND si.status = $2 AND si.order_date <= $3`,
+		supplier.ID, models.StockInStatusCompleted, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier bills: %w", err)
+	}
+	for rows.Next() {
+		var id, reference, currency string
+		var date time.Time
+		var due *time.Time
+		var rate money.Rate
+		total := money.Zero(money.BaseCurrency)
+		paid := money.Zero(money.BaseCurrency)
+		if err := rows.Scan(&id, &reference, &date, &due, &currency, &rate, &total, &paid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan supplier bill: %w", err)
+		}
+		lines = append(lines, models.StatementLine{
+			Date: date, Type: models.StatementLineBill, SourceID: id, Reference: reference, DueDate: due,
+			Debit: money.Zero(money.BaseCurrency), Credit: total.In(money.BaseCurrency),
+		})
+		if paid.IsPositive() {
+			lines = append(lines, models.StatementLine{
+				Date: date, Type: models.StatementLinePayment, SourceID: id, Reference: reference,
+				Debit: toBase(rate, paid, currency), Credit: money.Zero(money.BaseCurrency),
+			})
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating supplier bills: %w", err)
+	}
+
+	payments, err := r.queryPayments(ctx, `WHERE supplier_id = $1 AND payment_date <= $2`, supplier.ID, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		lines = append(lines, models.StatementLine{
+			Date: p.PaymentDate, Type: models.StatementLinePayment, SourceID: p.ID, Reference: p.PaymentNo,
+			Debit: p.BaseAmount, Credit: money.Zero(money.BaseCurrency),
+		})
+	}
+
+	credits, err := r.queryCredits(ctx, `WHERE supplier_id = $1 AND credit_date <= $2`, supplier.ID, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range credits {
+		lines = append(lines, models.StatementLine{
+			Date: c.CreditDate, Type: models.StatementLineCredit, SourceID: c.ID, Reference: c.CreditNo,
+			Debit: c.BaseAmount, Credit: money.Zero(money.BaseCurrency),
+		})
+	}
+
+	return models.NewSupplierStatement(supplier, startDate, endDate, lines), nil
+}
+
+const stockInPaymentColumns = `id, payment_no, stock_in_id, supplier_id, payment_date, currency, amount,
+	COALESCE(payment_method, ''), COALESCE(reference, ''), COALESCE(note, ''), base_amount, created_at`
+
+// queryPayments runs a query over supplier payments with the given WHERE clause, newest first
+func (r *PayableRepositoryImpl) queryPayments(ctx context.Context, where string, args ...interface{}) ([]models.StockInPayment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+stockInPaymentColumns+` FROM stock_in_payments `+where+`
+		ORDER BY payment_date DESC, created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []models.StockInPayment{}
+	for rows.Next() {
+		p := models.StockInPayment{
+			Amount:     money.Zero(money.BaseCurrency),
+			BaseAmount: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(&p.ID, &p.PaymentNo, &p.StockInID, &p.SupplierID, &p.PaymentDate, &p.Currency, &p.Amount,
+			&p.PaymentMethod, &p.Reference, &p.Note, &p.BaseAmount, &p.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplier payment: %w", err)
+		}
+		p.Currency = money.NormalizeCurrency(p.Currency)
+		p.Amount = p.Amount.In(p.Currency)
+		p.BaseAmount = p.BaseAmount.In(money.BaseCurrency)
+		payments = append(payments, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating supplier payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// GetPayment retrieves a supplier payment by ID
+func (r *PayableRepositoryImpl) GetPayment(id string) (*models.StockInPayment, error) {
+	payments, err := r.queryPayments(context.Background(), `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return nil, nil
+	}
+	return &payments[0], nil
+}
+
+// GetStockInPayments returns the payments made against a stock-in, newest first
+func (r *PayableRepositoryImpl) GetStockInPayments(stockInID string) ([]models.StockInPayment, error) {
+	return r.queryPayments(context.Background(), `WHERE stock_in_id = $1`, stockInID)
+}
+
+// CreatePayment records a payment against a completed stock-in, takes it off the stock-in's
+// balance and posts it to the journal
+func (r *PayableRepositoryImpl) CreatePayment(payment *models.StockInPayment, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	payment.GenerateID()
+
+	stockIn, err := lockPayableStockIn(ctx, tx, payment.StockInID)
+	if err != nil {
+		return err
+	}
+	if err = stockIn.ApplyPayment(payment); err != nil {
+		return err
+	}
+
+	payment.CreatedAt = time.Now()
+	_, err = tx.Exec(ctx, `INSERT INTO stock_in_payments (
+			id, payment_no, stock_in_id, supplier_id, payment_date, currency, amount,
+			payment_method, reference, note, base_amount, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		payment.ID, payment.PaymentNo, payment.StockInID, payment.SupplierID, payment.PaymentDate,
+		payment.Currency, payment.Amount, payment.PaymentMethod, payment.Reference, payment.Note,
+		payment.BaseAmount, payment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert supplier payment: %w", err)
+	}
+
+	if err = updateStockInSettlement(ctx, tx, stockIn, payment.CreatedAt); err != nil {
+		return err
+	}
+
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceSupplierPayment, payment.ID, true); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePayment removes a supplier payment, returning it to the stock-in's balance and
+// reversing its journal entry
+func (r *PayableRepositoryImpl) DeletePayment(id string, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE stock_ins si SET paid = si.paid - p.amount, balance = si.balance + p.amount,
+			updated_at = $1
+		FROM stock_in_payments p
+		WHERE p.stock_in_id = si.id AND p.id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore stock-in balance: %w", err)
+	}
+
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceSupplierPayment, id, false); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM stock_in_payments WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete supplier payment: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+const supplierCreditColumns = `id, credit_no, stock_in_id, supplier_id, credit_date, currency, amount,
+	COALESCE(reason, ''), base_amount, created_at`
+
+// queryCredits runs a query over supplier credits with the given WHERE clause, newest first
+func (r *PayableRepositoryImpl) queryCredits(ctx context.Context, where string, args ...interface{}) ([]models.SupplierCredit, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+supplierCreditColumns+` FROM supplier_credits `+where+`
+		ORDER BY credit_date DESC, created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier credits: %w", err)
+	}
+	defer rows.Close()
+
+	credits := []models.SupplierCredit{}
+	for rows.Next() {
+		c := models.SupplierCredit{
+			Amount:     money.Zero(money.BaseCurrency),
+			BaseAmount: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(&c.ID, &c.CreditNo, &c.StockInID, &c.SupplierID, &c.CreditDate, &c.Currency, &c.Amount,
+			&c.Reason, &c.BaseAmount, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplier credit: %w", err)
+		}
+		c.Currency = money.NormalizeCurrency(c.Currency)
+		c.Amount = c.Amount.In(c.Currency)
+		c.BaseAmount = c.BaseAmount.In(money.BaseCurrency)
+		credits = append(credits, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating supplier credits: %w", err)
+	}
+
+	return credits, nil
+}
+
+// GetCredit retrieves a supplier credit by ID
+func (r *PayableRepositoryImpl) GetCredit(id string) (*models.SupplierCredit, error) {
+	credits, err := r.queryCredits(context.Background(), `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(credits) == 0 {
+		return nil, nil
+	}
+	return &credits[0], nil
+}
+
+// ListCredits returns supplier credits, newest first, optionally for one supplier or stock-in
+func (r *PayableRepositoryImpl) ListCredits(supplierID, stockInID string) ([]models.SupplierCredit, error) {
+	var conditions []string
+	var args []interface{}
+	if supplierID != "" {
+		args = append(args, supplierID)
+		conditions = append(conditions, fmt.Sprintf("supplier_id = $%d", len(args)))
+	}
+	if stockInID != "" {
+		args = append(args, stockInID)
+		conditions = append(conditions, fmt.Sprintf("stock_in_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	return r.queryCredits(context.Background(), where, args...)
+}
+
+// CreateCredit records a supplier credit against a completed stock-in, takes it off the
+// stock-in's balance and posts it to the journal
+func (r *PayableRepositoryImpl) CreateCredit(credit *models.SupplierCredit, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	credit.GenerateID()
+
+	stockIn, err := lockPayableStockIn(ctx, tx, credit.StockInID)
+	if err != nil {
+		return err
+	}
+	if err = stockIn.ApplyCredit(credit); err != nil {
+		return err
+	}
+
+	credit.CreatedAt = time.Now()
+	_, err = tx.Exec(ctx, `INSERT INTO supplier_credits (
+			id, credit_no, stock_in_id, supplier_id, credit_date, currency, amount,
+			reason, base_amount, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		credit.ID, credit.CreditNo, credit.StockInID, credit.SupplierID, credit.CreditDate,
+		credit.Currency, credit.Amount, credit.Reason, credit.BaseAmount, credit.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert supplier credit: %w", err)
+	}
+
+	if err = updateStockInSettlement(ctx, tx, stockIn, credit.CreatedAt); err != nil {
+		return err
+	}
+
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceSupplierCredit, credit.ID, true); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCredit removes a supplier credit, returning it to the stock-in's balance and
+// reversing its journal entry
+func (r *PayableRepositoryImpl) DeleteCredit(id string, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE stock_ins si SET credited = si.credited - c.amount, balance = si.balance + c.amount,
+			updated_at = $1
+		FROM supplier_credits c
+		WHERE c.stock_in_id = si.id AND c.id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore stock-in balance: %w", err)
+	}
+
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceSupplierCredit, id, false); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM supplier_credits WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete supplier credit: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// lockPayableStockIn locks a stock-in so concurrent payments cannot settle the same balance
+// and returns what it owes
+func lockPayableStockIn(ctx context.Context, tx pgx.Tx, id string) (*models.StockIn, error) {
+	stockIn := models.StockIn{}
+	err := tx.QueryRow(ctx, `SELECT id, status, total, paid, credited, balance, currency, exchange_rate, supplier_id, opening
+		FROM stock_ins WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`, id).Scan(
+		&stockIn.ID, &stockIn.Status, &stockIn.Total, &stockIn.Paid, &stockIn.Credited, &stockIn.Balance,
+		&stockIn.Currency, &stockIn.ExchangeRate, &stockIn.SupplierID, &stockIn.Opening,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: stock-in not found", models.ErrStockInNotPayable)
+		}
+		return nil, fmt.Errorf("failed to get stock-in: %w", err)
+	}
+	return &stockIn, nil
+}
+
+// updateStockInSettlement stores what a stock-in has been paid and credited
+func updateStockInSettlement(ctx context.Context, tx pgx.Tx, stockIn *models.StockIn, at time.Time) error {
+	_, err := tx.Exec(ctx, `UPDATE stock_ins SET paid = $1, credited = $2, balance = $3, updated_at = $4
+		WHERE id = $5`, stockIn.Paid, stockIn.Credited, stockIn.Balance, at, stockIn.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update stock-in balance: %w", err)
+	}
+	return nil
+}
+
+// checkStockInSettlements returns ErrStockInHasPayments when supplier payments or credits are
+// recorded against the stock-in
+func checkStockInSettlements(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	var count int
+	err := tx.QueryRow(ctx, `SELECT
+			(SELECT COUNT(*) FROM stock_in_payments WHERE stock_in_id = $1) +
+			(SELECT COUNT(*) FROM supplier_credits WHERE stock_in_id = $1)`, stockInID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check stock-in payments: %w", err)
+	}
+	if count > 0 {
+		return ErrStockInHasPayments
+	}
+	return nil
+}
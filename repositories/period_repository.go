@@ -0,0 +1,406 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrPeriodAlreadyClosed is returned when closing a period that is closed
+	ErrPeriodAlreadyClosed = errors.New("accounting period is already closed")
+	// ErrPeriodNotClosed is returned when reopening a period that is open
+	ErrPeriodNotClosed = errors.New("accounting period is not closed")
+)
+
+// PeriodRepository defines methods for accounting periods and the closed-period lock
+type PeriodRepository interface {
+	GetByID(id string) (*models.AccountingPeriod, error)
+	List() ([]models.AccountingPeriod, error)
+	Create(period *models.AccountingPeriod) error
+	Delete(id string) error
+	Close(id, closedBy string) error
+	Reopen(id string, override *models.PeriodOverride) error
+	ListOverrides(periodID string) ([]models.PeriodOverride, error)
+}
+
+// PeriodRepositoryImpl implements the PeriodRepository interface
+type PeriodRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewPeriodRepository creates a new PeriodRepository
+func NewPeriodRepository(db *pgx.Conn) PeriodRepository {
+	return &PeriodRepositoryImpl{db: db}
+}
+
+const periodColumns = `id, name, start_date, end_date, status, COALESCE(note, ''), closed_at,
+	COALESCE(closed_by, ''), snapshot_method, created_at, updated_at`
+
+// scanPeriod scans a row selected with periodColumns
+func scanPeriod(row pgx.Row, p *models.AccountingPeriod, method *models.CostingMethod) error {
+	return row.Scan(&p.ID, &p.Name, &p.StartDate, &p.EndDate, &p.Status, &p.Note, &p.ClosedAt,
+		&p.ClosedBy, method, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// GetByID returns a period with the stock snapshot taken when it was closed
+func (r *PeriodRepositoryImpl) GetByID(id string) (*models.AccountingPeriod, error) {
+	ctx := context.Background()
+
+	var period models.AccountingPeriod
+	var method models.CostingMethod
+	row := r.db.QueryRow(ctx, `SELECT `+periodColumns+` FROM accounting_periods WHERE id = $1`, id)
+	if err := scanPeriod(row, &period, &method); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get accounting period: %w", err)
+	}
+
+	if period.IsClosed() {
+		snapshot, err := r.getSnapshot(ctx, &period, method)
+		if err != nil {
+			return nil, err
+		}
+		period.Snapshot = snapshot
+	}
+
+	return &period, nil
+}
+
+// List returns all periods, latest first. Snapshots are only loaded by GetByID.
+func (r *PeriodRepositoryImpl) List() ([]models.AccountingPeriod, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT `+periodColumns+` FROM accounting_periods ORDER BY start_date DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounting periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.AccountingPeriod{}
+	for rows.Next() {
+		var period models.AccountingPeriod
+		var method models.CostingMethod
+		if err := scanPeriod(rows, &period, &method); err != nil {
+			return nil, fmt.Errorf("failed to scan accounting period: %w", err)
+		}
+		periods = append(periods, period)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounting periods: %w", err)
+	}
+
+	return periods, nil
+}
+
+// Create adds an open period. Periods may not overlap.
+func (r *PeriodRepositoryImpl) Create(period *models.AccountingPeriod) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize period creation so two overlapping periods cannot be added at once
+	if _, err = tx.Exec(ctx, `LOCK TABLE accounting_periods IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock accounting periods: %w", err)
+	}
+
+	var overlaps bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (
+			SELECT 1 FROM accounting_periods WHERE start_date <= $2 AND end_date >= $1
+		)`, period.StartDate.Format("2006-01-02"), period.EndDate.Format("2006-01-02")).Scan(&overlaps)
+	if err != nil {
+		return fmt.Errorf("failed to check accounting periods: %w", err)
+	}
+	if overlaps {
+		return models.ErrPeriodOverlap
+	}
+
+	if period.ID == "" {
+		period.ID = uuid.NewString()
+	}
+	now := time.Now()
+	period.Status = models.PeriodStatusOpen
+	period.CreatedAt = now
+	period.UpdatedAt = now
+
+	_, err = tx.Exec(ctx, `INSERT INTO accounting_periods (
+			id, name, start_date, end_date, status, note, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		period.ID, period.Name, period.StartDate.Format("2006-01-02"), period.EndDate.Format("2006-01-02"),
+		period.Status, period.Note, period.CreatedAt, period.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert accounting period: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes an open period
+func (r *PeriodRepositoryImpl) Delete(id string) error {
+	tag, err := r.db.Exec(context.Background(),
+		`DELETE FROM accounting_periods WHERE id = $1 AND status = $2`, id, models.PeriodStatusOpen)
+	if err != nil {
+		return fmt.Errorf("failed to delete accounting period: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPeriodAlreadyClosed
+	}
+	return nil
+}
+
+// Close locks a period and records the quantity and value of stock on hand at its end
+func (r *PeriodRepositoryImpl) Close(id, closedBy string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var period models.AccountingPeriod
+	var method models.CostingMethod
+	row := tx.QueryRow(ctx, `SELECT `+periodColumns+` FROM accounting_periods WHERE id = $1 FOR UPDATE`, id)
+	if err := scanPeriod(row, &period, &method); err != nil {
+		return fmt.Errorf("failed to get accounting period: %w", err)
+	}
+	if period.IsClosed() {
+		return ErrPeriodAlreadyClosed
+	}
+
+	method, err = costingMethod(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// The snapshot is built from the cost ledger, like the inventory valuation report
+	_, err = tx.Exec(ctx, `DELETE FROM period_snapshots WHERE period_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to clear period snapshot: %w", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO period_snapshots (period_id, product_id, product_name, sku, quantity, value)
+		SELECT $1, m.product_id, COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''),
+			SUM(m.quantity), SUM(m.total_cost)
+		FROM cost_movements m
+		LEFT JOIN products p ON p.id = m.product_id
+		WHERE m.occurred_at <= $2
+		GROUP BY m.product_id, p.basic->>'name', p.basic->>'sku'
+		HAVING SUM(m.quantity) <> 0 OR SUM(m.total_cost) <> 0`,
+		id, period.EndOfPeriod())
+	if err != nil {
+		return fmt.Errorf("failed to record period snapshot: %w", err)
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(ctx, `UPDATE accounting_periods SET
+			status = $1, closed_at = $2, closed_by = $3, snapshot_method = $4, updated_at = $2
+		WHERE id = $5`,
+		models.PeriodStatusClosed, now, closedBy, method, id)
+	if err != nil {
+		return fmt.Errorf("failed to close accounting period: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Reopen unlocks a closed period and records the override that allowed it.
+// The snapshot is kept until the period is closed again.
+func (r *PeriodRepositoryImpl) Reopen(id string, override *models.PeriodOverride) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status models.PeriodStatus
+	var endDate time.Time
+	err = tx.QueryRow(ctx, `SELECT status, end_date FROM accounting_periods WHERE id = $1 FOR UPDATE`, id).
+		Scan(&status, &endDate)
+	if err != nil {
+		return fmt.Errorf("failed to get accounting period: %w", err)
+	}
+	if status != models.PeriodStatusClosed {
+		return ErrPeriodNotClosed
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE accounting_periods SET status = $1, updated_at = $2 WHERE id = $3`,
+		models.PeriodStatusOpen, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to reopen accounting period: %w", err)
+	}
+
+	override.PeriodID = id
+	override.Action = models.PeriodActionReopen
+	override.DocumentDate = endDate
+	if err = insertPeriodOverride(ctx, tx, override); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// lockPeriods checks a change against the accounting periods its dates fall in, inside the
+// transaction that saves it. The periods are locked FOR SHARE so none can be closed before the
+// change is committed. A date in a closed period returns a *models.PeriodClosedError unless the
+// lock carries an override, which is then recorded in the transaction once for each closed
+// period, so the change is rolled back if its audit record cannot be written.
+// A zero date is checked as today, since undated documents are dated when they are saved.
+func lockPeriods(ctx context.Context, tx pgx.Tx, lock *models.PeriodLock) error {
+	recorded := map[string]bool{}
+	for _, date := range lock.Dates {
+		if date.IsZero() {
+			date = time.Now()
+		}
+
+		var period models.AccountingPeriod
+		var method models.CostingMethod
+		row := tx.QueryRow(ctx, `SELECT `+periodColumns+` FROM accounting_periods
+			WHERE start_date <= $1::date AND end_date >= $1::date
+			FOR SHARE`, date.Format("2006-01-02"))
+		err := scanPeriod(row, &period, &method)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check accounting periods: %w", err)
+		}
+		if !period.IsClosed() || recorded[period.ID] {
+			continue
+		}
+		if lock.Override == nil {
+			return &models.PeriodClosedError{Period: period, Date: date}
+		}
+
+		override := *lock.Override
+		override.PeriodID = period.ID
+		override.Action = lock.Action
+		override.SourceType = lock.SourceType
+		override.SourceID = lock.SourceID
+		override.Reference = lock.Reference
+		override.DocumentDate = date
+		if err := insertPeriodOverride(ctx, tx, &override); err != nil {
+			return err
+		}
+		recorded[period.ID] = true
+	}
+	return nil
+}
+
+// ListOverrides returns the override audit log, newest first, optionally for one period
+func (r *PeriodRepositoryImpl) ListOverrides(periodID string) ([]models.PeriodOverride, error) {
+	query := `SELECT o.id, o.period_id, p.name, o.action, o.source_type, o.source_id, o.reference,
+			o.document_date, o.actor, o.reason, o.created_at
+		FROM period_overrides o
+		JOIN accounting_periods p ON p.id = o.period_id`
+	var args []interface{}
+	if periodID != "" {
+		query += ` WHERE o.period_id = $1`
+		args = append(args, periodID)
+	}
+	query += ` ORDER BY o.created_at DESC`
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []models.PeriodOverride{}
+	for rows.Next() {
+		var o models.PeriodOverride
+		err := rows.Scan(&o.ID, &o.PeriodID, &o.PeriodName, &o.Action, &o.SourceType, &o.SourceID,
+			&o.Reference, &o.DocumentDate, &o.Actor, &o.Reason, &o.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan period override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating period overrides: %w", err)
+	}
+
+	return overrides, nil
+}
+
+// getSnapshot loads the stock snapshot recorded when a period was closed
+func (r *PeriodRepositoryImpl) getSnapshot(ctx context.Context, period *models.AccountingPeriod, method models.CostingMethod) (*models.InventoryValuation, error) {
+	rows, err := r.db.Query(ctx, `SELECT product_id, product_name, sku, quantity, value
+		FROM period_snapshots WHERE period_id = $1
+		ORDER BY product_name, product_id`, period.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	snapshot := &models.InventoryValuation{
+		AsOf:       period.EndOfPeriod(),
+		Method:     method,
+		Currency:   money.BaseCurrency,
+		Products:   []models.InventoryValuationLine{},
+		TotalValue: money.Zero(money.BaseCurrency),
+	}
+	for rows.Next() {
+		line := models.InventoryValuationLine{
+			UnitCost: money.Zero(money.BaseCurrency),
+			Value:    money.Zero(money.BaseCurrency),
+		}
+		if err := rows.Scan(&line.ProductID, &line.ProductName, &line.SKU, &line.Quantity, &line.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan period snapshot: %w", err)
+		}
+		if line.Quantity > 0 {
+			line.UnitCost = line.Value.Div(int64(line.Quantity))
+		}
+		snapshot.Products = append(snapshot.Products, line)
+		snapshot.TotalQuantity += line.Quantity
+		snapshot.TotalValue = snapshot.TotalValue.Add(line.Value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating period snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// insertPeriodOverride stores an override audit record
+func insertPeriodOverride(ctx context.Context, tx pgx.Tx, o *models.PeriodOverride) error {
+	if o.ID == "" {
+		o.ID = uuid.NewString()
+	}
+	o.CreatedAt = time.Now()
+
+	_, err := tx.Exec(ctx, `INSERT INTO period_overrides (
+			id, period_id, action, source_type, source_id, reference, document_date, actor, reason, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		o.ID, o.PeriodID, o.Action, o.SourceType, o.SourceID, o.Reference, o.DocumentDate,
+		o.Actor, o.Reason, o.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record period override: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PriceListRepository defines methods for price lists and their quantity breaks
+type PriceListRepository interface {
+	GetAll(activeOnly bool) ([]models.PriceList, error)
+	GetByID(id string) (*models.PriceList, error)
+	GetByCode(code string) (*models.PriceList, error)
+	Create(list *models.PriceList) error
+	Update(list *models.PriceList, replaceItems bool) error
+	Delete(id string) error
+
+	// GetForCustomer returns the active price list a customer buys from: their own, else the
+	// default_price_list setting. It returns nil when there is none.
+	GetForCustomer(customerID *string) (*models.PriceList, error)
+	// GetBreaks returns a price list's quantity breaks for the given products
+	GetBreaks(priceListID string, productIDs []string) ([]models.PriceListItem, error)
+}
+
+// PriceListRepositoryImpl implements the PriceListRepository interface
+type PriceListRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewPriceListRepository creates a new PriceListRepository
+func NewPriceListRepository(db *pgx.Conn) PriceListRepository {
+	return &PriceListRepositoryImpl{db: db}
+}
+
+const priceListColumns = `id, code, name, COALESCE(description, ''), currency, active, created_at, updated_at`
+
+const priceListItemColumns = `id, price_list_id, product_id, min_quantity, price, created_at, updated_at`
+
+func scanPriceList(row pgx.Row) (*models.PriceList, error) {
+	var list models.PriceList
+	err := row.Scan(
+		&list.ID, &list.Code, &list.Name, &list.Description, &list.Currency,
+		&list.Active, &list.CreatedAt, &list.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetAll retrieves price lists ordered by code, without their items
+func (r *PriceListRepositoryImpl) GetAll(activeOnly bool) ([]models.PriceList, error) {
+	query := `SELECT ` + priceListColumns + ` FROM price_lists WHERE deleted_at IS NULL`
+	if activeOnly {
+		query += ` AND active = TRUE`
+	}
+	query += ` ORDER BY code ASC`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price lists: %w", err)
+	}
+	defer rows.Close()
+
+	lists := []models.PriceList{}
+	for rows.Next() {
+		list, err := scanPriceList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price list: %w", err)
+		}
+		lists = append(lists, *list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price lists: %w", err)
+	}
+
+	return lists, nil
+}
+
+// GetByID retrieves a price list and its items
+func (r *PriceListRepositoryImpl) GetByID(id string) (*models.PriceList, error) {
+	return r.getWithItems(`id = $1`, id)
+}
+
+// GetByCode retrieves a price list and its items by code, ignoring case
+func (r *PriceListRepositoryImpl) GetByCode(code string) (*models.PriceList, error) {
+	return r.getWithItems(`code = $1`, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+func (r *PriceListRepositoryImpl) getWithItems(condition string, arg any) (*models.PriceList, error) {
+	ctx := context.Background()
+	list, err := getPriceList(ctx, r.db, condition, arg)
+	if err != nil || list == nil {
+		return list, err
+	}
+
+	list.Items, err = r.queryItems(ctx, `WHERE price_list_id = $1
+		ORDER BY product_id, min_quantity`, list.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		list.Items[i].Price = list.Items[i].Price.In(list.Currency)
+	}
+	return list, nil
+}
+
+// getPriceList loads a single price list matching the condition, without its items
+func getPriceList(ctx context.Context, q queryRower, condition string, arg any) (*models.PriceList, error) {
+	query := `SELECT ` + priceListColumns + ` FROM price_lists WHERE ` + condition + ` AND deleted_at IS NULL`
+
+	list, err := scanPriceList(q.QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get price list: %w", err)
+	}
+	return list, nil
+}
+
+func (r *PriceListRepositoryImpl) queryItems(ctx context.Context, where string, args ...any) ([]models.PriceListItem, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+priceListItemColumns+` FROM price_list_items `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price list items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.PriceListItem{}
+	for rows.Next() {
+		var item models.PriceListItem
+		err := rows.Scan(&item.ID, &item.PriceListID, &item.ProductID, &item.MinQuantity,
+			&item.Price, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price list item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price list items: %w", err)
+	}
+
+	return items, nil
+}
+
+// Create stores a new price list and its items
+func (r *PriceListRepositoryImpl) Create(list *models.PriceList) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	list.GenerateID()
+	now := time.Now()
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO price_lists (id, code, name, description, currency, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		list.ID, list.Code, list.Name, list.Description, list.Currency, list.Active, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create price list: %w", err)
+	}
+
+	if err = insertPriceListItems(ctx, tx, list, now); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Update saves changes to a price list. Its items are replaced when replaceItems is set.
+// Sales already priced keep the prices they were given.
+func (r *PriceListRepositoryImpl) Update(list *models.PriceList, replaceItems bool) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	list.UpdatedAt = time.Now()
+	_, err = tx.Exec(ctx, `
+		UPDATE price_lists SET
+		code = $1, name = $2, description = $3, currency = $4, active = $5, updated_at = $6
+		WHERE id = $7 AND deleted_at IS NULL`,
+		list.Code, list.Name, list.Description, list.Currency, list.Active, list.UpdatedAt, list.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update price list: %w", err)
+	}
+
+	if replaceItems {
+		if _, err = tx.Exec(ctx, `DELETE FROM price_list_items WHERE price_list_id = $1`, list.ID); err != nil {
+			return fmt.Errorf("failed to delete price list items: %w", err)
+		}
+		if err = insertPriceListItems(ctx, tx, list, list.UpdatedAt); err != nil {
+			return err
+		}
+	} else {
+		// The items keep their amounts but take the list's currency
+		for i := range list.Items {
+			list.Items[i].Price = list.Items[i].Price.In(list.Currency)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertPriceListItems stores a price list's items
+func insertPriceListItems(ctx context.Context, tx pgx.Tx, list *models.PriceList, now time.Time) error {
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.ID = uuid.NewString()
+		item.PriceListID = list.ID
+		item.CreatedAt = now
+		item.UpdatedAt = now
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO price_list_items (`+priceListItemColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			item.ID, item.PriceListID, item.ProductID, item.MinQuantity, item.Price, now, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert price list item for product %s: %w", item.ProductID, err)
+		}
+	}
+	return nil
+}
+
+// Delete soft-deletes a price list; its customers fall back to the default price list
+func (r *PriceListRepositoryImpl) Delete(id string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if _, err = tx.Exec(ctx, `UPDATE price_lists SET deleted_at = $1 WHERE id = $2`, now, id); err != nil {
+		return fmt.Errorf("failed to delete price list: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `UPDATE customers SET price_list_id = NULL, updated_at = $1 WHERE price_list_id = $2`, now, id); err != nil {
+		return fmt.Errorf("failed to unassign price list from customers: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetForCustomer returns the customer's active price list, else the default one
+func (r *PriceListRepositoryImpl) GetForCustomer(customerID *string) (*models.PriceList, error) {
+	ctx := context.Background()
+	if customerID != nil && *customerID != "" {
+		list, err := getPriceList(ctx, r.db,
+			`active = TRUE AND id = (SELECT price_list_id FROM customers WHERE id = $1)`, *customerID)
+		if err != nil || list != nil {
+			return list, err
+		}
+	}
+
+	code, err := getSetting(ctx, r.db, models.SettingDefaultPriceList)
+	if err != nil || code == "" {
+		return nil, err
+	}
+	return getPriceList(ctx, r.db, `active = TRUE AND code = $1`, code)
+}
+
+// GetBreaks returns the list's items for the products, ordered by product and quantity
+func (r *PriceListRepositoryImpl) GetBreaks(priceListID string, productIDs []string) ([]models.PriceListItem, error) {
+	if len(productIDs) == 0 {
+		return []models.PriceListItem{}, nil
+	}
+	return r.queryItems(context.Background(), `WHERE price_list_id = $1 AND product_id = ANY($2)
+		ORDER BY product_id, min_quantity`, priceListID, productIDs)
+}
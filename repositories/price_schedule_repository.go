@@ -0,0 +1,418 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PriceScheduleRepository defines methods for scheduled prices and the price history
+type PriceScheduleRepository interface {
+	// GetProductPrice returns a product's current price, or nil when the product does not exist
+	GetProductPrice(productID string) (*models.Price, error)
+	// GetHistory returns a product's price changes, newest first
+	GetHistory(productID string, limit int) ([]models.PriceHistory, error)
+
+	List(productID string, status models.PriceScheduleStatus) ([]models.PriceSchedule, error)
+	GetByID(id string) (*models.PriceSchedule, error)
+	Create(schedule *models.PriceSchedule) error
+	// Cancel stops a pending or active schedule, restoring the previous price of an active one
+	Cancel(schedule *models.PriceSchedule) error
+	// FindOverlap returns a pending or active schedule of the same product whose window
+	// overlaps the schedule's, or nil
+	FindOverlap(schedule *models.PriceSchedule) (*models.PriceSchedule, error)
+
+	// RunDue applies the schedules that have started and reverts those that have ended
+	RunDue(now time.Time) (*models.PriceScheduleRun, error)
+}
+
+// PriceScheduleRepositoryImpl implements the PriceScheduleRepository interface
+type PriceScheduleRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewPriceScheduleRepository creates a new PriceScheduleRepository
+func NewPriceScheduleRepository(db *pgx.Conn) PriceScheduleRepository {
+	return &PriceScheduleRepositoryImpl{db: db}
+}
+
+const priceScheduleColumns = `id, product_id, price, currency, starts_at, ends_at, status, COALESCE(note, ''),
+		previous_price, COALESCE(previous_currency, ''), applied_at, reverted_at, created_at, updated_at`
+
+func scanPriceSchedule(row pgx.Row) (*models.PriceSchedule, error) {
+	var schedule models.PriceSchedule
+	err := row.Scan(
+		&schedule.ID, &schedule.ProductID, &schedule.Price, &schedule.Currency,
+		&schedule.StartsAt, &schedule.EndsAt, &schedule.Status, &schedule.Note,
+		&schedule.PreviousPrice, &schedule.PreviousCurrency, &schedule.AppliedAt, &schedule.RevertedAt,
+		&schedule.CreatedAt, &schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	schedule.BindCurrency()
+	return &schedule, nil
+}
+
+// GetProductPrice reads the price of a product that has not been deleted
+func (r *PriceScheduleRepositoryImpl) GetProductPrice(productID string) (*models.Price, error) {
+	price, err := getProductPrice(context.Background(), r.db, productID, false)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return price, err
+}
+
+// getProductPrice reads a product's price, locking the row when forUpdate is set
+func getProductPrice(ctx context.Context, q queryRower, productID string, forUpdate bool) (*models.Price, error) {
+	query := `SELECT price->>'price', COALESCE(price->>'currency', '') FROM products
+		WHERE id = $1 AND deleted_at IS NULL`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var price models.Price
+	if err := q.QueryRow(ctx, query, productID).Scan(&price.Price, &price.Currency); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get product price: %w", err)
+	}
+	price.BindCurrency()
+	return &price, nil
+}
+
+// setProductPrice changes the price in a product's price JSONB, leaving its other fields
+func setProductPrice(ctx context.Context, tx pgx.Tx, productID string, price models.Price, at time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE products SET
+		price = COALESCE(price, '{}'::jsonb) || jsonb_build_object(
+			'price', $1::numeric, 'currency', $2::text, 'last_update_unix', $3::bigint),
+		updated_at = $4
+		WHERE id = $5`,
+		price.Price, price.Currency, at.Unix(), at, productID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set product price: %w", err)
+	}
+	return nil
+}
+
+// insertPriceHistory records a price change
+func insertPriceHistory(ctx context.Context, tx pgx.Tx, entry *models.PriceHistory) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO price_history (id, product_id, old_price, new_price, currency, source, schedule_id, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		entry.ID, entry.ProductID, entry.OldPrice, entry.NewPrice, entry.Currency,
+		entry.Source, entry.ScheduleID, entry.ChangedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record price history: %w", err)
+	}
+	return nil
+}
+
+// GetHistory returns the product's most recent price changes
+func (r *PriceScheduleRepositoryImpl) GetHistory(productID string, limit int) ([]models.PriceHistory, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT id, product_id, old_price, new_price, currency, source, schedule_id, changed_at
+		FROM price_history WHERE product_id = $1
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2`, productID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.PriceHistory{}
+	for rows.Next() {
+		var entry models.PriceHistory
+		err := rows.Scan(&entry.ID, &entry.ProductID, &entry.OldPrice, &entry.NewPrice, &entry.Currency,
+			&entry.Source, &entry.ScheduleID, &entry.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		entry.NewPrice = entry.NewPrice.In(entry.Currency)
+		if entry.OldPrice != nil {
+			old := entry.OldPrice.In(entry.Currency)
+			entry.OldPrice = &old
+		}
+		history = append(history, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price history: %w", err)
+	}
+
+	return history, nil
+}
+
+func (r *PriceScheduleRepositoryImpl) querySchedules(ctx context.Context, where string, args ...any) ([]models.PriceSchedule, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+priceScheduleColumns+` FROM price_schedules `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.PriceSchedule{}
+	for rows.Next() {
+		schedule, err := scanPriceSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price schedule: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// List retrieves schedules by start time, optionally for one product or status
+func (r *PriceScheduleRepositoryImpl) List(productID string, status models.PriceScheduleStatus) ([]models.PriceSchedule, error) {
+	return r.querySchedules(context.Background(), `
+		WHERE ($1 = '' OR product_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY starts_at ASC, created_at ASC`, productID, string(status))
+}
+
+// GetByID retrieves a schedule by its ID
+func (r *PriceScheduleRepositoryImpl) GetByID(id string) (*models.PriceSchedule, error) {
+	schedule, err := scanPriceSchedule(r.db.QueryRow(context.Background(),
+		`SELECT `+priceScheduleColumns+` FROM price_schedules WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get price schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// Create stores a new pending schedule
+func (r *PriceScheduleRepositoryImpl) Create(schedule *models.PriceSchedule) error {
+	schedule.GenerateID()
+	now := time.Now()
+	schedule.Status = models.PriceSchedulePending
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO price_schedules (id, product_id, price, currency, starts_at, ends_at, status, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		schedule.ID, schedule.ProductID, schedule.Price, schedule.Currency, schedule.StartsAt, schedule.EndsAt,
+		schedule.Status, schedule.Note, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create price schedule: %w", err)
+	}
+	return nil
+}
+
+// FindOverlap looks for a live schedule of the product whose window overlaps
+func (r *PriceScheduleRepositoryImpl) FindOverlap(schedule *models.PriceSchedule) (*models.PriceSchedule, error) {
+	live, err := r.querySchedules(context.Background(), `
+		WHERE product_id = $1 AND status IN ($2, $3) AND id <> $4
+		ORDER BY starts_at ASC`,
+		schedule.ProductID, models.PriceSchedulePending, models.PriceScheduleActive, schedule.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range live {
+		if schedule.Overlaps(&live[i]) {
+			return &live[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Cancel stops the schedule. An active schedule gives the product its previous price back.
+func (r *PriceScheduleRepositoryImpl) Cancel(schedule *models.PriceSchedule) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if schedule.Status == models.PriceScheduleActive {
+		if err = revertSchedule(ctx, tx, schedule, now); err != nil {
+			return err
+		}
+	}
+
+	schedule.Status = models.PriceScheduleCancelled
+	schedule.UpdatedAt = now
+	_, err = tx.Exec(ctx, `UPDATE price_schedules SET status = $1, reverted_at = $2, updated_at = $3 WHERE id = $4`,
+		schedule.Status, schedule.RevertedAt, now, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel price schedule: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RunDue brings every schedule up to date: schedules whose window has passed without them
+// being applied expire, ended schedules are reverted before started ones are applied so
+// back-to-back windows hand over cleanly. Each schedule is saved in its own transaction.
+func (r *PriceScheduleRepositoryImpl) RunDue(now time.Time) (*models.PriceScheduleRun, error) {
+	ctx := context.Background()
+	run := &models.PriceScheduleRun{RanAt: now}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE price_schedules SET status = $1, updated_at = $2
+		WHERE status = $3 AND ends_at IS NOT NULL AND ends_at <= $2`,
+		models.PriceScheduleExpired, now, models.PriceSchedulePending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire price schedules: %w", err)
+	}
+	run.Expired = int(tag.RowsAffected())
+
+	ended, err := r.querySchedules(ctx, `WHERE status = $1 AND ends_at IS NOT NULL AND ends_at <= $2
+		ORDER BY ends_at ASC`, models.PriceScheduleActive, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ended {
+		if err := r.endSchedule(ctx, &ended[i], now); err != nil {
+			return run, err
+		}
+		run.Reverted++
+	}
+
+	started, err := r.querySchedules(ctx, `WHERE status = $1 AND starts_at <= $2
+		ORDER BY starts_at ASC`, models.PriceSchedulePending, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range started {
+		if err := r.startSchedule(ctx, &started[i], now); err != nil {
+			return run, err
+		}
+		run.Applied++
+	}
+
+	return run, nil
+}
+
+// startSchedule gives the product the scheduled price, remembering the one it replaces
+func (r *PriceScheduleRepositoryImpl) startSchedule(ctx context.Context, schedule *models.PriceSchedule, now time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	schedule.Status = models.PriceScheduleActive
+	if schedule.EndsAt == nil {
+		schedule.Status = models.PriceScheduleCompleted
+	}
+	schedule.AppliedAt = &now
+
+	previous, err := getProductPrice(ctx, tx, schedule.ProductID, true)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if previous == nil {
+		// The product was deleted; there is nothing to price
+		schedule.Status = models.PriceScheduleExpired
+	} else {
+		price := models.Price{Price: schedule.Price, Currency: schedule.Currency}
+		schedule.PreviousPrice = &previous.Price
+		schedule.PreviousCurrency = previous.Currency
+		if err = setProductPrice(ctx, tx, schedule.ProductID, price, now); err != nil {
+			return err
+		}
+		entry := models.NewPriceHistory(schedule.ProductID, previous, price, models.PriceChangeScheduleStart, &schedule.ID, now)
+		if err = insertPriceHistory(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE price_schedules SET status = $1, previous_price = $2, previous_currency = $3,
+		applied_at = $4, updated_at = $4
+		WHERE id = $5`,
+		schedule.Status, schedule.PreviousPrice, schedule.PreviousCurrency, now, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update price schedule: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// endSchedule restores the price the product had before the schedule and completes it
+func (r *PriceScheduleRepositoryImpl) endSchedule(ctx context.Context, schedule *models.PriceSchedule, now time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = revertSchedule(ctx, tx, schedule, now); err != nil {
+		return err
+	}
+
+	schedule.Status = models.PriceScheduleCompleted
+	_, err = tx.Exec(ctx, `UPDATE price_schedules SET status = $1, reverted_at = $2, updated_at = $3 WHERE id = $4`,
+		schedule.Status, schedule.RevertedAt, now, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update price schedule: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// revertSchedule gives the product back the price it had before an active schedule. A price
+// changed by hand while the schedule ran is kept.
+func revertSchedule(ctx context.Context, tx pgx.Tx, schedule *models.PriceSchedule, now time.Time) error {
+	schedule.RevertedAt = &now
+	if schedule.PreviousPrice == nil {
+		return nil
+	}
+
+	current, err := getProductPrice(ctx, tx, schedule.ProductID, true)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	scheduled := models.Price{Price: schedule.Price, Currency: schedule.Currency}
+	if models.PriceChanged(*current, scheduled) {
+		return nil
+	}
+
+	previous := models.Price{Price: *schedule.PreviousPrice, Currency: schedule.PreviousCurrency}
+	if err = setProductPrice(ctx, tx, schedule.ProductID, previous, now); err != nil {
+		return err
+	}
+	entry := models.NewPriceHistory(schedule.ProductID, current, previous, models.PriceChangeScheduleEnd, &schedule.ID, now)
+	return insertPriceHistory(ctx, tx, entry)
+}
+
+// recordProductPrice records a product's price when it is created or changed by hand
+func recordProductPrice(ctx context.Context, tx pgx.Tx, productID string, old *models.Price, price models.Price, at time.Time) error {
+	source := models.PriceChangeManual
+	if old == nil {
+		source = models.PriceChangeCreated
+	} else if !models.PriceChanged(*old, price) {
+		return nil
+	}
+	return insertPriceHistory(ctx, tx, models.NewPriceHistory(productID, old, price, source, nil, at))
+}
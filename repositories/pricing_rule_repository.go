@@ -0,0 +1,476 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSuggestionReviewed is returned when a price suggestion is no longer pending
+var ErrSuggestionReviewed = errors.New("price suggestion has already been reviewed")
+
+// PricingRuleRepository defines methods for cost-plus pricing rules and the price suggestions
+// they make
+type PricingRuleRepository interface {
+	GetAll(activeOnly bool) ([]models.PricingRule, error)
+	GetByID(id string) (*models.PricingRule, error)
+	// GetByTarget returns the rule for the same product or category as the given rule, or nil
+	GetByTarget(rule *models.PricingRule) (*models.PricingRule, error)
+	Create(rule *models.PricingRule) error
+	Update(rule *models.PricingRule) error
+	Delete(id string) error
+
+	ListSuggestions(status models.PriceSuggestionStatus, productID string) ([]models.PriceSuggestion, error)
+	GetSuggestion(id string) (*models.PriceSuggestion, error)
+	// ApplySuggestion gives the product the price and marks the suggestion applied
+	ApplySuggestion(suggestion *models.PriceSuggestion, price money.Money) error
+	RejectSuggestion(suggestion *models.PriceSuggestion) error
+}
+
+// PricingRuleRepositoryImpl implements the PricingRuleRepository interface
+type PricingRuleRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewPricingRuleRepository creates a new PricingRuleRepository
+func NewPricingRuleRepository(db *pgx.Conn) PricingRuleRepository {
+	return &PricingRuleRepositoryImpl{db: db}
+}
+
+const pricingRuleColumns = `id, name, scope, product_id, category_id, markup_type, markup_percent, markup_amount,
+		round_to, rounding, threshold_percent, auto_apply, active, created_at, updated_at`
+
+func scanPricingRule(row pgx.Row) (*models.PricingRule, error) {
+	var rule models.PricingRule
+	err := row.Scan(
+		&rule.ID, &rule.Name, &rule.Scope, &rule.ProductID, &rule.CategoryID,
+		&rule.MarkupType, &rule.MarkupPercent, &rule.MarkupAmount,
+		&rule.RoundTo, &rule.Rounding, &rule.ThresholdPercent, &rule.AutoApply, &rule.Active,
+		&rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rule.BindCurrency()
+	return &rule, nil
+}
+
+// queryPricingRules loads the rules matching a WHERE clause
+func queryPricingRules(ctx context.Context, q rowsQuerier, where string, args ...any) ([]models.PricingRule, error) {
+	rows, err := q.Query(ctx, `SELECT `+pricingRuleColumns+` FROM pricing_rules `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pricing rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.PricingRule{}
+	for rows.Next() {
+		rule, err := scanPricingRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pricing rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pricing rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// GetAll retrieves the pricing rules, product rules first
+func (r *PricingRuleRepositoryImpl) GetAll(activeOnly bool) ([]models.PricingRule, error) {
+	where := `WHERE deleted_at IS NULL`
+	if activeOnly {
+		where += ` AND active = TRUE`
+	}
+	return queryPricingRules(context.Background(), r.db, where+` ORDER BY scope DESC, name ASC`)
+}
+
+// GetByID retrieves a pricing rule by its ID
+func (r *PricingRuleRepositoryImpl) GetByID(id string) (*models.PricingRule, error) {
+	rule, err := scanPricingRule(r.db.QueryRow(context.Background(),
+		`SELECT `+pricingRuleColumns+` FROM pricing_rules WHERE id = $1 AND deleted_at IS NULL`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get pricing rule: %w", err)
+	}
+	return rule, nil
+}
+
+// GetByTarget looks up the rule covering the same product or category
+func (r *PricingRuleRepositoryImpl) GetByTarget(rule *models.PricingRule) (*models.PricingRule, error) {
+	rules, err := queryPricingRules(context.Background(), r.db, `
+		WHERE deleted_at IS NULL AND scope = $1
+		AND product_id IS NOT DISTINCT FROM $2 AND category_id IS NOT DISTINCT FROM $3
+		LIMIT 1`,
+		rule.Scope, rule.ProductID, rule.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &rules[0], nil
+}
+
+// Create stores a new pricing rule
+func (r *PricingRuleRepositoryImpl) Create(rule *models.PricingRule) error {
+	rule.GenerateID()
+	now := time.Now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO pricing_rules (
+			id, name, scope, product_id, category_id, markup_type, markup_percent, markup_amount,
+			round_to, rounding, threshold_percent, auto_apply, active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		rule.ID, rule.Name, rule.Scope, rule.ProductID, rule.CategoryID,
+		rule.MarkupType, rule.MarkupPercent, rule.MarkupAmount,
+		rule.RoundTo, rule.Rounding, rule.ThresholdPercent, rule.AutoApply, rule.Active, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pricing rule: %w", err)
+	}
+	return nil
+}
+
+// Update saves changes to a pricing rule. Suggestions it already made are kept.
+func (r *PricingRuleRepositoryImpl) Update(rule *models.PricingRule) error {
+	rule.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(context.Background(), `
+		UPDATE pricing_rules SET
+		name = $1, scope = $2, product_id = $3, category_id = $4, markup_type = $5,
+		markup_percent = $6, markup_amount = $7, round_to = $8, rounding = $9,
+		threshold_percent = $10, auto_apply = $11, active = $12, updated_at = $13
+		WHERE id = $14 AND deleted_at IS NULL`,
+		rule.Name, rule.Scope, rule.ProductID, rule.CategoryID, rule.MarkupType,
+		rule.MarkupPercent, rule.MarkupAmount, rule.RoundTo, rule.Rounding,
+		rule.ThresholdPercent, rule.AutoApply, rule.Active, rule.UpdatedAt, rule.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update pricing rule: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes a pricing rule. Its suggestions stay in the review queue.
+func (r *PricingRuleRepositoryImpl) Delete(id string) error {
+	_, err := r.db.Exec(context.Background(), `UPDATE pricing_rules SET deleted_at = $1 WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete pricing rule: %w", err)
+	}
+	return nil
+}
+
+const priceSuggestionColumns = `ps.id, ps.product_id, COALESCE(p.basic->>'name', ''), COALESCE(p.basic->>'sku', ''),
+		ps.rule_id, ps.stock_in_id, ps.previous_cost, ps.unit_cost, ps.current_price, ps.suggested_price,
+		ps.applied_price, ps.currency, ps.status, ps.auto_applied, ps.reviewed_at, ps.created_at, ps.updated_at`
+
+func scanPriceSuggestion(row pgx.Row) (*models.PriceSuggestion, error) {
+	var s models.PriceSuggestion
+	err := row.Scan(
+		&s.ID, &s.ProductID, &s.ProductName, &s.SKU, &s.RuleID, &s.StockInID,
+		&s.PreviousCost, &s.UnitCost, &s.CurrentPrice, &s.SuggestedPrice, &s.AppliedPrice,
+		&s.Currency, &s.Status, &s.AutoApplied, &s.ReviewedAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.BindCurrency()
+	return &s, nil
+}
+
+// ListSuggestions retrieves suggestions newest first, optionally by status or product
+func (r *PricingRuleRepositoryImpl) ListSuggestions(status models.PriceSuggestionStatus, productID string) ([]models.PriceSuggestion, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT `+priceSuggestionColumns+`
+		FROM price_suggestions ps
+		LEFT JOIN products p ON p.id = ps.product_id
+		WHERE ($1 = '' OR ps.status = $1) AND ($2 = '' OR ps.product_id = $2)
+		ORDER BY ps.created_at DESC`,
+		string(status), productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.PriceSuggestion{}
+	for rows.Next() {
+		suggestion, err := scanPriceSuggestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price suggestion: %w", err)
+		}
+		suggestions = append(suggestions, *suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// GetSuggestion retrieves a price suggestion by its ID
+func (r *PricingRuleRepositoryImpl) GetSuggestion(id string) (*models.PriceSuggestion, error) {
+	suggestion, err := scanPriceSuggestion(r.db.QueryRow(context.Background(), `
+		SELECT `+priceSuggestionColumns+`
+		FROM price_suggestions ps
+		LEFT JOIN products p ON p.id = ps.product_id
+		WHERE ps.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get price suggestion: %w", err)
+	}
+	return suggestion, nil
+}
+
+// ApplySuggestion changes the product's price and records it in the price history
+func (r *PricingRuleRepositoryImpl) ApplySuggestion(suggestion *models.PriceSuggestion, price money.Money) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if err = reviewSuggestion(ctx, tx, suggestion, models.PriceSuggestionApplied, now); err != nil {
+		return err
+	}
+
+	current, err := getProductPrice(ctx, tx, suggestion.ProductID, true)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("product not found")
+	}
+	if err != nil {
+		return err
+	}
+	if err = applyCostPlusPrice(ctx, tx, suggestion, current, price, now); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE price_suggestions SET applied_price = $1 WHERE id = $2`,
+		suggestion.AppliedPrice, suggestion.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update price suggestion: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RejectSuggestion marks a pending suggestion rejected, leaving the product's price alone
+func (r *PricingRuleRepositoryImpl) RejectSuggestion(suggestion *models.PriceSuggestion) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = reviewSuggestion(ctx, tx, suggestion, models.PriceSuggestionRejected, time.Now()); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// reviewSuggestion moves a pending suggestion to its reviewed status, returning
+// ErrSuggestionReviewed when it is no longer pending
+func reviewSuggestion(ctx context.Context, tx pgx.Tx, suggestion *models.PriceSuggestion, status models.PriceSuggestionStatus, at time.Time) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE price_suggestions SET status = $1, reviewed_at = $2, updated_at = $2
+		WHERE id = $3 AND status = $4`,
+		status, at, suggestion.ID, models.PriceSuggestionPending)
+	if err != nil {
+		return fmt.Errorf("failed to update price suggestion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSuggestionReviewed
+	}
+	suggestion.Status = status
+	suggestion.ReviewedAt = &at
+	suggestion.UpdatedAt = at
+	return nil
+}
+
+// applyCostPlusPrice sets the product's price for a suggestion and records the change
+func applyCostPlusPrice(ctx context.Context, tx pgx.Tx, suggestion *models.PriceSuggestion, current *models.Price, amount money.Money, at time.Time) error {
+	price := models.Price{Price: amount, Currency: suggestion.Currency}
+	price.BindCurrency()
+	if err := setProductPrice(ctx, tx, suggestion.ProductID, price, at); err != nil {
+		return err
+	}
+	entry := models.NewPriceHistory(suggestion.ProductID, current, price, models.PriceChangeCostPlus, nil, at)
+	if err := insertPriceHistory(ctx, tx, entry); err != nil {
+		return err
+	}
+	suggestion.AppliedPrice = &price.Price
+	return nil
+}
+
+// withdrawPriceSuggestions supersedes the pending suggestions of a stock-in that is no
+// longer completed
+func withdrawPriceSuggestions(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE price_suggestions SET status = $1, updated_at = $2
+		WHERE stock_in_id = $3 AND status = $4`,
+		models.PriceSuggestionSuperseded, time.Now(), stockInID, models.PriceSuggestionPending)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw price suggestions: %w", err)
+	}
+	return nil
+}
+
+// receiptCost is a product's unit cost on a stock-in and on the purchase before it
+type receiptCost struct {
+	ProductID    string
+	UnitCost     money.Money
+	PreviousCost *money.Money
+}
+
+// suggestCostPlusPrices runs the pricing rules over the products a completed stock-in
+// received. Where a product's landed unit cost moved by at least its rule's threshold since
+// the previous purchase, the rule's price is applied straight away or queued for review,
+// replacing any suggestion still pending for the product. A stock-in dated before a later
+// purchase of the product does not reprice it. It must run inside the stock-in's transaction,
+// after its cost movements are posted.
+func suggestCostPlusPrices(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	rules, err := queryPricingRules(ctx, tx, `WHERE deleted_at IS NULL AND active = TRUE`)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT m.product_id, ROUND(SUM(m.total_cost) / SUM(m.quantity), 4),
+			(SELECT prev.unit_cost FROM cost_movements prev
+				WHERE prev.product_id = m.product_id AND prev.movement_type = $2 AND prev.source_type = $3
+				AND prev.source_id <> $1 AND prev.reversed_at IS NULL AND prev.occurred_at <= MAX(m.occurred_at)
+				ORDER BY prev.occurred_at DESC, prev.created_at DESC LIMIT 1)
+		FROM cost_movements m
+		WHERE m.source_type = $3 AND m.source_id = $1 AND m.movement_type = $2 AND m.reversed_at IS NULL
+		GROUP BY m.product_id
+		HAVING SUM(m.quantity) > 0 AND NOT EXISTS (
+			SELECT 1 FROM cost_movements later
+			WHERE later.product_id = m.product_id AND later.movement_type = $2 AND later.source_type = $3
+			AND later.source_id <> $1 AND later.reversed_at IS NULL AND later.occurred_at > MAX(m.occurred_at))`,
+		stockInID, models.CostMovementReceipt, models.CostSourceStockIn,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get stock-in unit costs: %w", err)
+	}
+
+	var costs []receiptCost
+	for rows.Next() {
+		cost := receiptCost{UnitCost: money.Zero(money.BaseCurrency)}
+		if err := rows.Scan(&cost.ProductID, &cost.UnitCost, &cost.PreviousCost); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan stock-in unit cost: %w", err)
+		}
+		costs = append(costs, cost)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating stock-in unit costs: %w", err)
+	}
+
+	productIDs := make([]string, len(costs))
+	for i, cost := range costs {
+		productIDs[i] = cost.ProductID
+	}
+	scopes, err := queryProductScopes(ctx, tx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, cost := range costs {
+		rule := models.SelectPricingRule(cost.ProductID, scopes[cost.ProductID], rules)
+		if rule == nil || !models.CostChanged(cost.PreviousCost, cost.UnitCost, rule.ThresholdPercent) {
+			continue
+		}
+
+		current, err := getProductPrice(ctx, tx, cost.ProductID, true)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Rules price in the base currency
+		if current.Currency != money.BaseCurrency {
+			continue
+		}
+		suggested := rule.PriceFor(cost.UnitCost)
+		if suggested.Cmp(current.Price) == 0 {
+			continue
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE price_suggestions SET status = $1, updated_at = $2
+			WHERE product_id = $3 AND status = $4`,
+			models.PriceSuggestionSuperseded, now, cost.ProductID, models.PriceSuggestionPending)
+		if err != nil {
+			return fmt.Errorf("failed to supersede price suggestions: %w", err)
+		}
+
+		suggestion := &models.PriceSuggestion{
+			ID:             uuid.NewString(),
+			ProductID:      cost.ProductID,
+			RuleID:         &rule.ID,
+			StockInID:      stockInID,
+			PreviousCost:   cost.PreviousCost,
+			UnitCost:       cost.UnitCost,
+			CurrentPrice:   current.Price,
+			SuggestedPrice: suggested,
+			Currency:       money.BaseCurrency,
+			Status:         models.PriceSuggestionPending,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		suggestion.BindCurrency()
+		if rule.AutoApply {
+			if err = applyCostPlusPrice(ctx, tx, suggestion, current, suggested, now); err != nil {
+				return err
+			}
+			suggestion.Status = models.PriceSuggestionApplied
+			suggestion.AutoApplied = true
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO price_suggestions (
+				id, product_id, rule_id, stock_in_id, previous_cost, unit_cost, current_price,
+				suggested_price, applied_price, currency, status, auto_applied, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			suggestion.ID, suggestion.ProductID, suggestion.RuleID, suggestion.StockInID,
+			suggestion.PreviousCost, suggestion.UnitCost, suggestion.CurrentPrice,
+			suggestion.SuggestedPrice, suggestion.AppliedPrice, suggestion.Currency,
+			suggestion.Status, suggestion.AutoApplied, suggestion.CreatedAt, suggestion.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert price suggestion: %w", err)
+		}
+	}
+
+	return nil
+}
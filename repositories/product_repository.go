@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"inventory-go/models"
+	"inventory-go/money"
+	"sort"
 	"strconv"
 	"time"
 
@@ -16,8 +18,8 @@ import (
 
 // Define Attribute type for product attributes
 type Attribute struct {
-	Key   string
-	Value string
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 type ProductRepository interface {
@@ -33,9 +35,14 @@ type ProductRepository interface {
 	AddVariant(parentID string, variant *models.Product) error
 
 	// Image operations
+	GetImages(productID string) ([]*models.Images, error)
+	GetImage(productID, imageID string) (*models.Images, error)
 	AddImage(productID string, image *models.Images) error
 	RemoveImage(productID, imageID string) error
 	SetPrimaryImage(productID, imageID string) error
+	ReorderImages(productID string, imageIDs []string) error
+	// DeleteImages removes all images of a product and returns them, so their files can be removed
+	DeleteImages(productID string) ([]*models.Images, error)
 
 	// Stock operations
 	UpdateStock(id string, quantity int) error
@@ -43,13 +50,22 @@ type ProductRepository interface {
 
 	// Attribute operations
 	AddAttribute(productID string, attribute Attribute) error
+	RemoveAttribute(productID, key string) error
 
-	// Pagination, search and filter
-	List(offset, limit int, status string) ([]*models.Product, int64, error)
-	Search(query string, categoryID *string) ([]*models.Product, error)
+	// Pagination and filter
+	List(offset, limit int, filter models.ProductFilter) ([]*models.Product, int64, error)
 	GetBySKU(sku string) (*models.Product, error)
 }
 
+var (
+	// ErrImageNotFound is returned when an image does not exist or belongs to another product
+	ErrImageNotFound = errors.New("image not found")
+	// ErrInvalidImageOrder is returned when a reorder lists an image of another product
+	ErrInvalidImageOrder = errors.New("invalid image order")
+	// ErrTooManyImages is returned when a product already has the most images allowed
+	ErrTooManyImages = fmt.Errorf("a product can have at most %d images", models.MaxImagesPerProduct)
+)
+
 type ProductRepositoryImpl struct {
 	*BaseRepository
 }
@@ -86,7 +102,6 @@ func (r *ProductRepositoryImpl) GetAll() ([]*models.Product, error) {
 	var products []*models.Product
 	for rows.Next() {
 		product := &models.Product{}
-		var priceStr string
 		var deletedAt pgtype.Timestamp
 		
 		err := rows.Scan(
@@ -94,20 +109,14 @@ func (r *ProductRepositoryImpl) GetAll() ([]*models.Product, error) {
 			&product.CreatedAt, &product.UpdatedAt, &deletedAt,
 			&product.Basic.Name, &product.Basic.Description, &product.Basic.Status,
 			&product.Basic.Condition, &product.Basic.SKU, &product.Basic.IsVariant,
-			&priceStr, &product.Price.Currency,
+			&product.Price.Price, &product.Price.Currency,
 		)
 		
 		if err != nil {
 			return nil, fmt.Errorf("error scanning product: %w", err)
 		}
 		
-		// Convert string values to proper types
-		if priceStr != "" {
-			price, err := strconv.ParseFloat(priceStr, 64)
-			if err == nil {
-				product.Price.Price = price
-			}
-		}
+		product.Price.BindCurrency()
 		
 		if deletedAt.Valid {
 			product.DeletedAt = &deletedAt.Time
@@ -162,18 +171,49 @@ func (r *ProductRepositoryImpl) Create(product *models.Product) error {
 			basic, price, weight, images, inventory_activity
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
 
-	_, err = r.db.Exec(context.Background(), query,
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query,
 		product.ID, product.ParentID, product.Stock, product.ChildCategoryID,
 		product.CreatedAt, product.UpdatedAt,
 		basicJSON, priceJSON, weightJSON, imagesJSON, inventoryJSON)
-	
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Start the product's price history
+	if err = recordProductPrice(ctx, tx, product.ID, nil, product.Price, now); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 // Update implements ProductRepository.
 func (r *ProductRepositoryImpl) Update(product *models.Product) error {
 	product.UpdatedAt = time.Now()
 
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Stamp the price with the time it changed
+	oldPrice, err := getProductPrice(ctx, tx, product.ID, true)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if oldPrice != nil && models.PriceChanged(*oldPrice, product.Price) {
+		product.Price.LastUpdateUnix = product.UpdatedAt.Unix()
+	}
+
 	// Marshal structs to JSONB
 	basicJSON, err := json.Marshal(product.Basic)
 	if err != nil {
@@ -213,7 +253,7 @@ func (r *ProductRepositoryImpl) Update(product *models.Product) error {
 			inventory_activity = $9
 		WHERE id = $10`
 
-	result, err := r.db.Exec(context.Background(), query,
+	result, err := tx.Exec(ctx, query,
 		product.UpdatedAt, 
 		product.ParentID, 
 		product.Stock, 
@@ -234,7 +274,12 @@ func (r *ProductRepositoryImpl) Update(product *models.Product) error {
 		return fmt.Errorf("product not found with id: %s", product.ID)
 	}
 
-	return nil
+	// Record the change in the price history
+	if err = recordProductPrice(ctx, tx, product.ID, oldPrice, product.Price, product.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 // Helper function to check if product exists
@@ -286,6 +331,7 @@ func (r *ProductRepositoryImpl) GetByID(id string) (*models.Product, error) {
 		    p.basic->>'sku', (p.basic->>'is_variant')::boolean,
 		    p.price->>'price', p.price->>'currency',
 		    (p.price->>'last_update_unix')::bigint as last_update_unix,
+		    p.price->>'tax_code_id' as tax_code_id,
 		    p.weight->>'weight' as weight,
 		    (p.weight->>'unit')::int as unit,
 		    p.inventory_activity->>'sales_count' as sales_count,
@@ -301,6 +347,7 @@ func (r *ProductRepositoryImpl) GetByID(id string) (*models.Product, error) {
 		&product.Basic.Name, &product.Basic.Description, &product.Basic.Status,
 		&product.Basic.Condition, &product.Basic.SKU, &product.Basic.IsVariant,
 		&product.Price.Price, &product.Price.Currency, &product.Price.LastUpdateUnix,
+		&product.Price.TaxCodeID,
 		&product.Weight.Weight, &product.Weight.Unit,
 		&product.InventoryActivity.SalesCount, &product.InventoryActivity.StockIn,
 		&product.InventoryActivity.Reject, &attributesJSON,
@@ -313,9 +360,15 @@ func (r *ProductRepositoryImpl) GetByID(id string) (*models.Product, error) {
 		return nil, fmt.Errorf("error getting product: %w", err)
 	}
 
-	// Skip attributes unmarshaling as it's not part of the Product struct
-	// We would handle them separately if needed through the AddAttribute method
-	_ = attributesJSON
+	product.Price.BindCurrency()
+
+	if err := json.Unmarshal(attributesJSON, &product.Attributes); err != nil {
+		return nil, fmt.Errorf("error decoding attributes: %w", err)
+	}
+
+	if product.Images, err = r.GetImages(id); err != nil {
+		return nil, err
+	}
 
 	// Load variants if this is a parent product
 	if !product.Basic.IsVariant {
@@ -338,7 +391,7 @@ func (r *ProductRepositoryImpl) GetByID(id string) (*models.Product, error) {
 }
 
 // List implements ProductRepository.
-func (r *ProductRepositoryImpl) List(offset int, limit int, status string) ([]*models.Product, int64, error) {
+func (r *ProductRepositoryImpl) List(offset int, limit int, filter models.ProductFilter) ([]*models.Product, int64, error) {
 	var products []*models.Product
 	var total int64
 
@@ -350,20 +403,18 @@ func (r *ProductRepositoryImpl) List(offset int, limit int, status string) ([]*m
 			p.basic->>'name', p.basic->>'description', 
 			(p.basic->>'status')::int, (p.basic->>'condition')::int,
 			p.basic->>'sku', (p.basic->>'is_variant')::boolean,
-			p.price->>'price', p.price->>'currency',
-			(SELECT COUNT(*) FROM products WHERE deleted_at IS NULL) as total
+			p.price->>'price', p.price->>'currency', p.attributes
 		FROM products p 
 		WHERE p.deleted_at IS NULL`
 	
-	// Add status filter if provided
-	args := []interface{}{}
-	if status != "" {
-		query += " AND p.basic->>'status' = $1"
-		args = append(args, status)
-	}
+	// Add status and attribute filters if provided
+	where, args := productFilterConditions(filter)
+	query += where
+	countQuery := "SELECT COUNT(*) FROM products p WHERE p.deleted_at IS NULL" + where
+	countArgs := append([]interface{}{}, args...)
 	
-	// Add pagination
-	query += " ORDER BY p.created_at DESC LIMIT $" + strconv.Itoa(len(args)+1) + 
+	// Add sorting and pagination
+	query += " ORDER BY " + productOrderBy(filter.Sort, "") + " LIMIT $" + strconv.Itoa(len(args)+1) + 
 		" OFFSET $" + strconv.Itoa(len(args)+2)
 	args = append(args, limit, offset)
 
@@ -375,30 +426,27 @@ func (r *ProductRepositoryImpl) List(offset int, limit int, status string) ([]*m
 	
 	for rows.Next() {
 		product := &models.Product{}
-		var priceStr string
 		var deletedAt pgtype.Timestamp
+		var attributesJSON []byte
 		
 		err := rows.Scan(
 			&product.ID, &product.ParentID, &product.Stock, &product.ChildCategoryID,
 			&product.CreatedAt, &product.UpdatedAt, &deletedAt,
 			&product.Basic.Name, &product.Basic.Description, &product.Basic.Status,
 			&product.Basic.Condition, &product.Basic.SKU, &product.Basic.IsVariant,
-			&priceStr, &product.Price.Currency,
+			&product.Price.Price, &product.Price.Currency, &attributesJSON,
 		)
 		if err != nil {
 			return nil, 0, err
 		}
+		if err := json.Unmarshal(attributesJSON, &product.Attributes); err != nil {
+			return nil, 0, fmt.Errorf("error decoding attributes: %w", err)
+		}
 		if deletedAt.Valid {
 			product.DeletedAt = &deletedAt.Time
 		}
 		
-		// Convert string values to proper types
-		if priceStr != "" {
-			price, err := strconv.ParseFloat(priceStr, 64)
-			if err == nil {
-				product.Price.Price = price
-			}
-		}
+		product.Price.BindCurrency()
 		
 		products = append(products, product)
 	}
@@ -407,7 +455,7 @@ func (r *ProductRepositoryImpl) List(offset int, limit int, status string) ([]*m
 		return nil, 0, err
 	}
 
-	err = r.db.QueryRow(context.Background(), "SELECT COUNT(*) FROM products WHERE deleted_at IS NULL").Scan(&total)
+	err = r.db.QueryRow(context.Background(), countQuery, countArgs...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -415,143 +463,66 @@ func (r *ProductRepositoryImpl) List(offset int, limit int, status string) ([]*m
 	return products, total, nil
 }
 
-// Search implements ProductRepository.
-func (r *ProductRepositoryImpl) Search(searchQuery string, categoryID *string) ([]*models.Product, error) {
-	query := `
-		SELECT 
-			p.id, p.parent_id, p.stock, p.child_category_id,
-			p.created_at, p.updated_at, p.deleted_at, 
-			p.basic->>'name', p.basic->>'description', 
-			(p.basic->>'status')::int, (p.basic->>'condition')::int,
-			p.basic->>'sku', (p.basic->>'is_variant')::boolean,
-			p.price->>'price', p.price->>'currency'
-		FROM products p 
-		WHERE p.deleted_at IS NULL`
-
-	// Add search conditions
-	args := []interface{}{}
-	if searchQuery != "" {
-		query += " AND (p.basic->>'name' ILIKE $1 OR p.basic->>'description' ILIKE $1 OR p.basic->>'sku' ILIKE $1)"
-		args = append(args, "%"+searchQuery+"%")
+// RemoveImage deletes an image, making the next image primary when it was the primary one
+func (r *ProductRepositoryImpl) RemoveImage(productID string, imageID string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	if categoryID != nil && *categoryID != "" {
-		query += fmt.Sprintf(" AND p.child_category_id = $%d", len(args)+1)
-		args = append(args, *categoryID)
+	var isPrimary bool
+	err = tx.QueryRow(ctx, `DELETE FROM images WHERE id = $1 AND product_id = $2 RETURNING is_primary`,
+		imageID, productID).Scan(&isPrimary)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrImageNotFound
 	}
-
-	query += " ORDER BY p.created_at DESC LIMIT 100"
-
-	rows, err := r.db.Query(context.Background(), query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("error executing search query: %w", err)
+		return fmt.Errorf("failed to delete image: %w", err)
 	}
-	defer rows.Close()
-	
-	var products []*models.Product
-	for rows.Next() {
-		product := &models.Product{}
-		var priceStr string
-		var deletedAt pgtype.Timestamp
-		
-		err := rows.Scan(
-			&product.ID, &product.ParentID, &product.Stock, &product.ChildCategoryID,
-			&product.CreatedAt, &product.UpdatedAt, &deletedAt,
-			&product.Basic.Name, &product.Basic.Description, &product.Basic.Status,
-			&product.Basic.Condition, &product.Basic.SKU, &product.Basic.IsVariant,
-			&priceStr, &product.Price.Currency,
-		)
+
+	if isPrimary {
+		_, err = tx.Exec(ctx, `
+			UPDATE images SET is_primary = TRUE
+			WHERE id = (
+				SELECT id FROM images WHERE product_id = $1
+				ORDER BY sort_order ASC, created_at ASC
+				LIMIT 1
+			)`, productID)
 		if err != nil {
-			return nil, fmt.Errorf("error scanning product: %w", err)
-		}
-		if deletedAt.Valid {
-			product.DeletedAt = &deletedAt.Time
+			return fmt.Errorf("failed to set primary image: %w", err)
 		}
-		
-		// Convert string values to proper types
-		if priceStr != "" {
-			price, err := strconv.ParseFloat(priceStr, 64)
-			if err == nil {
-				product.Price.Price = price
-			}
-		}
-		
-		products = append(products, product)
 	}
-	
-	return products, nil
-}
 
-// RemoveImage implements ProductRepository interface
-func (r *ProductRepositoryImpl) RemoveImage(productID string, imageID string) error {
-	// First, check if this is the primary image
-	var image models.Images
-	query := `
-		SELECT id, product_id, is_primary
-		FROM images 
-		WHERE id = $1`
+	return tx.Commit(ctx)
+}
 
-	err := r.db.QueryRow(context.Background(), query, imageID).Scan(&image.ID, &image.ProductID, &image.IsPrimary)
+// SetPrimaryImage makes an image the one shown first for its product
+func (r *ProductRepositoryImpl) SetPrimaryImage(productID string, imageID string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return err
-	}
-
-	// If it's the primary image, we need to set another image as primary
-	if image.IsPrimary {
-		// Find another image for the same product to set as primary
-		var otherImage models.Images
-		query = `
-			SELECT id, product_id, is_primary
-			FROM images 
-			WHERE product_id = $1 AND id != $2
-			ORDER BY sort_order ASC
-			LIMIT 1`
-
-		err := r.db.QueryRow(context.Background(), query, image.ProductID, imageID).Scan(&otherImage.ID, &otherImage.ProductID, &otherImage.IsPrimary)
-		if err == nil {
-			// Found another image, set it as primary
-			query = `
-				UPDATE images 
-				SET is_primary = TRUE 
-				WHERE id = $1`
-
-			_, err = r.db.Exec(context.Background(), query, otherImage.ID)
-			if err != nil {
-				return err
-			}
-		}
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	// Delete the image
-	query = `
-		DELETE FROM images 
-		WHERE id = $1`
-
-	_, err = r.db.Exec(context.Background(), query, imageID)
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM images WHERE id = $1 AND product_id = $2)`,
+		imageID, productID).Scan(&exists)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get image: %w", err)
 	}
-
-	return nil
-}
-
-// SetPrimaryImage implements ProductRepository.
-func (r *ProductRepositoryImpl) SetPrimaryImage(productID string, imageID string) error {
-	// First, unset any existing primary image for this product
-	query := `UPDATE images SET is_primary = false WHERE product_id = $1`
-	_, err := r.db.Exec(context.Background(), query, productID)
-	if err != nil {
-		return err
+	if !exists {
+		return ErrImageNotFound
 	}
 
-	// Then set this image as primary
-	query = `UPDATE images SET is_primary = true WHERE id = $1 AND product_id = $2`
-	_, err = r.db.Exec(context.Background(), query, imageID, productID)
+	_, err = tx.Exec(ctx, `UPDATE images SET is_primary = (id = $1) WHERE product_id = $2`, imageID, productID)
 	if err != nil {
 		return fmt.Errorf("failed to update image: %w", err)
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
 
@@ -578,7 +549,7 @@ func (r *ProductRepositoryImpl) AddAttribute(productID string, attribute Attribu
 	}
 
 	// Update product with new attribute
-	query := `UPDATE products SET attributes = attributes || $1 WHERE id = $2`
+	query := `UPDATE products SET attributes = attributes || $1::jsonb WHERE id = $2`
 	_, err = r.db.Exec(context.Background(), query, attrsJSON, productID)
 	if err != nil {
 		return fmt.Errorf("failed to update attributes: %w", err)
@@ -587,6 +558,121 @@ func (r *ProductRepositoryImpl) AddAttribute(productID string, attribute Attribu
 	return nil
 }
 
+// RemoveAttribute removes an attribute from a product
+func (r *ProductRepositoryImpl) RemoveAttribute(productID string, key string) error {
+	query := `UPDATE products SET attributes = attributes - $1::text WHERE id = $2 AND deleted_at IS NULL`
+	_, err := r.db.Exec(context.Background(), query, key, productID)
+	if err != nil {
+		return fmt.Errorf("failed to remove attribute: %w", err)
+	}
+
+	return nil
+}
+
+// productSoldQuantity is the quantity of a product, and of its variants, sold by completed sales
+const productSoldQuantity = `(SELECT COALESCE(SUM(si.quantity), 0) FROM sale_items si
+	JOIN sales s ON s.id = si.sale_id AND s.status = 'completed' AND s.deleted_at IS NULL
+	WHERE si.deleted_at IS NULL AND si.product_id IN (SELECT p.id UNION ALL SELECT v.id FROM products v WHERE v.parent_id = p.id))`
+
+// productBasePrice is a product's price in the base currency at the latest exchange rate, so
+// prices in different currencies compare. It is NULL while the currency has no rate.
+const productBasePrice = `(SELECT (p.price->>'price')::numeric * CASE
+		WHEN c.currency = '` + money.BaseCurrency + `' THEN 1
+		ELSE (SELECT er.rate FROM exchange_rates er
+			WHERE er.currency = c.currency AND er.rate_date <= CURRENT_DATE AND er.deleted_at IS NULL
+			ORDER BY er.rate_date DESC LIMIT 1)
+	END
+	FROM (SELECT UPPER(COALESCE(NULLIF(p.price->>'currency', ''), '` + money.DefaultCurrency + `')) AS currency) c)`
+
+// productOrderBy returns the ORDER BY clause for a product sort. The rank expression orders
+// by relevance and is empty outside a search; newest products come first on ties.
+func productOrderBy(sort string, rank string) string {
+	switch sort {
+	case models.ProductSortPriceAsc:
+		return productBasePrice + " ASC NULLS LAST, p.created_at DESC"
+	case models.ProductSortPriceDesc:
+		return productBasePrice + " DESC NULLS LAST, p.created_at DESC"
+	case models.ProductSortStockAsc:
+		return "p.stock ASC, p.created_at DESC"
+	case models.ProductSortStockDesc:
+		return "p.stock DESC, p.created_at DESC"
+	case models.ProductSortNameAsc:
+		return "LOWER(p.basic->>'name') ASC, p.created_at DESC"
+	case models.ProductSortNameDesc:
+		return "LOWER(p.basic->>'name') DESC, p.created_at DESC"
+	case models.ProductSortBestSelling:
+		return productSoldQuantity + " DESC, p.created_at DESC"
+	case models.ProductSortNewest:
+		return "p.created_at DESC"
+	}
+	if rank != "" {
+		return rank + " DESC, p.created_at DESC"
+	}
+	return "p.created_at DESC"
+}
+
+// productFilterConditions builds the WHERE conditions for a product listing. A product
+// matches the attributes when it or one of its variants has all of them.
+func productFilterConditions(filter models.ProductFilter) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+	if filter.CategoryID != "" {
+		args = append(args, filter.CategoryID)
+		where += fmt.Sprintf(` AND p.child_category_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = $%d AND deleted_at IS NULL
+				UNION
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+			)
+			SELECT id FROM subtree)`, len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND p.basic->>'status' = $%d", len(args))
+	}
+	if filter.Condition != "" {
+		args = append(args, filter.Condition)
+		where += fmt.Sprintf(" AND p.basic->>'condition' = $%d", len(args))
+	}
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		where += fmt.Sprintf(" AND "+productBasePrice+" >= $%d::numeric", len(args))
+	}
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		where += fmt.Sprintf(" AND "+productBasePrice+" <= $%d::numeric", len(args))
+	}
+	if filter.InStock {
+		where += " AND p.stock > 0"
+	}
+
+	if len(filter.Attributes) > 0 {
+		keys := make([]string, 0, len(filter.Attributes))
+		for key := range filter.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		match := func(alias string) string {
+			conditions := ""
+			for i := range keys {
+				if i > 0 {
+					conditions += " AND "
+				}
+				conditions += fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_each_text(%s.attributes) a WHERE LOWER(a.key) = LOWER($%d) AND LOWER(a.value) = LOWER($%d))",
+					alias, len(args)+2*i+1, len(args)+2*i+2)
+			}
+			return conditions
+		}
+		where += " AND ((" + match("p") + ") OR EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id AND v.deleted_at IS NULL AND " + match("v") + "))"
+		for _, key := range keys {
+			args = append(args, key, filter.Attributes[key])
+		}
+	}
+
+	return where, args
+}
+
 // UpdateStock implements ProductRepository.
 func (r *ProductRepositoryImpl) UpdateStock(id string, quantity int) error {
 	// Get the existing product
@@ -637,24 +723,18 @@ func (r *ProductRepositoryImpl) GetVariants(parentID string) ([]*models.Product,
 
 	for rows.Next() {
 		product := &models.Product{}
-		var priceStr string
 		
 		err := rows.Scan(
 			&product.ID, &product.ParentID, &product.Stock, &product.ChildCategoryID,
 			&product.Basic.Name, &product.Basic.Description, &product.Basic.Status,
 			&product.Basic.Condition, &product.Basic.SKU, &product.Basic.IsVariant,
-			&priceStr,
+			&product.Price.Price,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan variant: %w", err)
 		}
 		
-		if priceStr != "" {
-			price, err := strconv.ParseFloat(priceStr, 64)
-			if err == nil {
-				product.Price.Price = price
-			}
-		}
+		product.Price.BindCurrency()
 		
 		variants = append(variants, product)
 	}
@@ -725,7 +805,7 @@ func (r *ProductRepositoryImpl) GetBySKU(sku string) (*models.Product, error) {
 		WHERE p.basic->>'sku' = $1 AND p.deleted_at IS NULL`
 
 	var product models.Product
-	var priceStr, weightStr string
+	var weightStr string
 	var lastUpdateUnix int64
 	
 	err := r.db.QueryRow(context.Background(), query, sku).Scan(
@@ -733,7 +813,7 @@ func (r *ProductRepositoryImpl) GetBySKU(sku string) (*models.Product, error) {
 		&product.CreatedAt, &product.UpdatedAt, &product.DeletedAt,
 		&product.Basic.Name, &product.Basic.Description, &product.Basic.Status,
 		&product.Basic.Condition, &product.Basic.SKU, &product.Basic.IsVariant,
-		&priceStr, &product.Price.Currency, &lastUpdateUnix,
+		&product.Price.Price, &product.Price.Currency, &lastUpdateUnix,
 		&weightStr, &product.Weight.Unit,
 	)
 
@@ -744,13 +824,7 @@ func (r *ProductRepositoryImpl) GetBySKU(sku string) (*models.Product, error) {
 		return nil, fmt.Errorf("error getting product by SKU: %w", err)
 	}
 
-	// Convert string values to their proper types
-	if priceStr != "" {
-		price, err := strconv.ParseFloat(priceStr, 64)
-		if err == nil {
-			product.Price.Price = price
-		}
-	}
+	product.Price.BindCurrency()
 
 	if weightStr != "" {
 		weight, err := strconv.ParseFloat(weightStr, 64)
@@ -812,25 +886,211 @@ func (r *ProductRepositoryImpl) AddVariant(parentID string, variant *models.Prod
 	return nil
 }
 
+const imageColumns = `id, product_id, url, COALESCE(thumbnail_url, ''), COALESCE(medium_url, ''),
+	COALESCE(content_type, ''), COALESCE(size, 0), COALESCE(width, 0), COALESCE(height, 0), storage_keys,
+	is_primary, sort_order, created_at`
+
+func scanImage(row pgx.Row) (*models.Images, error) {
+	var image models.Images
+	err := row.Scan(&image.ID, &image.ProductID, &image.URL, &image.ThumbnailURL, &image.MediumURL,
+		&image.ContentType, &image.Size, &image.Width, &image.Height, &image.StorageKeys,
+		&image.IsPrimary, &image.SortOrder, &image.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// GetImages retrieves the images of a product in display order
+func (r *ProductRepositoryImpl) GetImages(productID string) ([]*models.Images, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT `+imageColumns+` FROM images
+		WHERE product_id = $1
+		ORDER BY sort_order ASC, created_at ASC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	defer rows.Close()
+
+	images := []*models.Images{}
+	for rows.Next() {
+		image, err := scanImage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image: %w", err)
+		}
+		images = append(images, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating images: %w", err)
+	}
+
+	return images, nil
+}
+
+// GetImage retrieves an image of a product, or nil if the product has no such image
+func (r *ProductRepositoryImpl) GetImage(productID string, imageID string) (*models.Images, error) {
+	image, err := scanImage(r.db.QueryRow(context.Background(),
+		`SELECT `+imageColumns+` FROM images WHERE id = $1 AND product_id = $2`, imageID, productID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	return image, nil
+}
+
+// AddImage adds an image after the product's other images. The first image of a product is
+// always primary; a later one replaces the primary image when it is marked primary.
 func (r *ProductRepositoryImpl) AddImage(productID string, image *models.Images) error {
 	image.ProductID = productID
 	if image.ID == "" {
 		image.ID = uuid.NewString()
 	}
-	
-	query := `INSERT INTO images (
-		id, product_id, url, is_primary, sort_order, created_at
-	) VALUES ($1, $2, $3, $4, $5, $6)`
-	
-	_, err := r.db.Exec(context.Background(), query,
-		image.ID, image.ProductID, image.URL, image.IsPrimary, image.SortOrder, time.Now(),
+	if image.StorageKeys == nil {
+		image.StorageKeys = []string{}
+	}
+	image.CreatedAt = time.Now()
+
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the product so concurrent uploads get distinct positions
+	var locked string
+	err = tx.QueryRow(ctx, `SELECT id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, productID).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("product not found: %s", productID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	var count, next int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*), COALESCE(MAX(sort_order) + 1, 0) FROM images WHERE product_id = $1`,
+		productID).Scan(&count, &next)
+	if err != nil {
+		return fmt.Errorf("failed to count images: %w", err)
+	}
+	if count >= models.MaxImagesPerProduct {
+		return ErrTooManyImages
+	}
+	image.SortOrder = next
+	if count == 0 {
+		image.IsPrimary = true
+	} else if image.IsPrimary {
+		if _, err := tx.Exec(ctx, `UPDATE images SET is_primary = FALSE WHERE product_id = $1`, productID); err != nil {
+			return fmt.Errorf("failed to update images: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO images (
+			id, product_id, url, thumbnail_url, medium_url, content_type, size, width, height, storage_keys,
+			is_primary, sort_order, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		image.ID, image.ProductID, image.URL, image.ThumbnailURL, image.MediumURL, image.ContentType,
+		image.Size, image.Width, image.Height, image.StorageKeys, image.IsPrimary, image.SortOrder, image.CreatedAt,
 	)
-	
 	if err != nil {
 		return fmt.Errorf("failed to add image: %w", err)
 	}
-	
-	return nil
+
+	return tx.Commit(ctx)
+}
+
+// ReorderImages numbers the images of a product in the order given. Images not listed keep
+// their order after the listed ones.
+func (r *ProductRepositoryImpl) ReorderImages(productID string, imageIDs []string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM images
+		WHERE product_id = $1
+		ORDER BY sort_order ASC, created_at ASC
+		FOR UPDATE`, productID)
+	if err != nil {
+		return fmt.Errorf("failed to query images: %w", err)
+	}
+	var current []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan image: %w", err)
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating images: %w", err)
+	}
+
+	isImage := make(map[string]bool, len(current))
+	for _, id := range current {
+		isImage[id] = true
+	}
+	order := make([]string, 0, len(current))
+	listed := make(map[string]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		if !isImage[id] {
+			return fmt.Errorf("%w: %s is not an image of the product", ErrInvalidImageOrder, id)
+		}
+		if listed[id] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidImageOrder, id)
+		}
+		listed[id] = true
+		order = append(order, id)
+	}
+	for _, id := range current {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE images i SET sort_order = o.position - 1
+		FROM unnest($1::text[]) WITH ORDINALITY AS o(id, position)
+		WHERE i.id = o.id AND i.sort_order <> o.position - 1`, order)
+	if err != nil {
+		return fmt.Errorf("failed to reorder images: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteImages removes the image rows of a product and returns them
+func (r *ProductRepositoryImpl) DeleteImages(productID string) ([]*models.Images, error) {
+	rows, err := r.db.Query(context.Background(),
+		`DELETE FROM images WHERE product_id = $1 RETURNING `+imageColumns, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete images: %w", err)
+	}
+	defer rows.Close()
+
+	images := []*models.Images{}
+	for rows.Next() {
+		image, err := scanImage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image: %w", err)
+		}
+		images = append(images, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating images: %w", err)
+	}
+
+	return images, nil
 }
 
 // ... implement other methods following the same pattern
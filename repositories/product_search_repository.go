@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"inventory-go/models"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProductSearchRepository defines full-text product search
+type ProductSearchRepository interface {
+	// Search finds products matching the query and filters, best matches first, with facet
+	// counts over everything found
+	Search(search models.ProductSearch) (*models.ProductSearchResult, error)
+}
+
+// ProductSearchRepositoryImpl implements the ProductSearchRepository interface
+type ProductSearchRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewProductSearchRepository creates a new ProductSearchRepository
+func NewProductSearchRepository(db *pgx.Conn) ProductSearchRepository {
+	return &ProductSearchRepositoryImpl{db: db}
+}
+
+// productSearchQuery matches the words in Indonesian, English and as typed, so stems,
+// SKUs and codes are all found
+const productSearchQuery = `(websearch_to_tsquery('indonesian', %[1]s) || websearch_to_tsquery('english', %[1]s) || websearch_to_tsquery('simple', %[1]s))`
+
+// searchConditions adds the text match to the filter conditions. Besides the search vector, a
+// name close to the query (a typo) or a SKU starting with it matches. The rank puts an exact
+// SKU first, then the text rank plus the name's closeness.
+func searchConditions(search models.ProductSearch) (where string, rank string, args []interface{}) {
+	where, args = productFilterConditions(search.ProductFilter)
+	query := strings.TrimSpace(search.Query)
+	if query == "" {
+		return where, "0::float8", args
+	}
+
+	args = append(args, query)
+	term := "$" + strconv.Itoa(len(args))
+	tsquery := fmt.Sprintf(productSearchQuery, term)
+	where += fmt.Sprintf(` AND (p.search_vector @@ %[1]s OR %[2]s <%% (p.basic->>'name') OR p.basic->>'sku' ILIKE %[2]s || '%%')`, tsquery, term)
+	rank = fmt.Sprintf(`(CASE WHEN LOWER(p.basic->>'sku') = LOWER(%[2]s) THEN 10 ELSE 0 END
+		+ ts_rank_cd(p.search_vector, %[1]s) + word_similarity(%[2]s, p.basic->>'name'))::float8`, tsquery, term)
+	return where, rank, args
+}
+
+// Search finds a page of products and counts the facets of everything found
+func (r *ProductSearchRepositoryImpl) Search(search models.ProductSearch) (*models.ProductSearchResult, error) {
+	ctx := context.Background()
+	where, rank, args := searchConditions(search)
+
+	query := `
+		SELECT p.id, p.parent_id, p.stock, p.child_category_id, p.created_at, p.updated_at,
+			p.basic->>'name', COALESCE(p.basic->>'description', ''),
+			COALESCE((p.basic->>'status')::int, 0), COALESCE((p.basic->>'condition')::int, 0),
+			COALESCE(p.basic->>'sku', ''), COALESCE((p.basic->>'is_variant')::boolean, FALSE),
+			p.price->>'price', COALESCE(p.price->>'currency', ''), p.attributes,
+			` + rank + ` AS search_rank
+		FROM products p
+		WHERE p.deleted_at IS NULL` + where + `
+		ORDER BY ` + productOrderBy(search.Sort, "search_rank") + `
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, append(args, search.Limit, search.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	defer rows.Close()
+
+	result := &models.ProductSearchResult{Products: []models.ProductSearchHit{}}
+	for rows.Next() {
+		var hit models.ProductSearchHit
+		var attributes []byte
+		err := rows.Scan(
+			&hit.ID, &hit.ParentID, &hit.Stock, &hit.ChildCategoryID, &hit.CreatedAt, &hit.UpdatedAt,
+			&hit.Basic.Name, &hit.Basic.Description, &hit.Basic.Status, &hit.Basic.Condition,
+			&hit.Basic.SKU, &hit.Basic.IsVariant, &hit.Price.Price, &hit.Price.Currency, &attributes,
+			&hit.Rank,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		hit.Price.BindCurrency()
+		if err := json.Unmarshal(attributes, &hit.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to decode product attributes: %w", err)
+		}
+		result.Products = append(result.Products, hit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating products: %w", err)
+	}
+	rows.Close()
+
+	if err := r.countFacets(ctx, where, args, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// countFacets counts every product found by category, status, condition, price range and
+// availability. The total is the number found.
+func (r *ProductSearchRepositoryImpl) countFacets(ctx context.Context, where string, args []interface{}, result *models.ProductSearchResult) error {
+	args = append(args, models.PriceFacetBounds)
+
+	query := `
+		WITH matched AS (
+			SELECT p.child_category_id, p.basic->>'status' AS status, p.basic->>'condition' AS condition,
+				width_bucket(COALESCE((p.price->>'price')::numeric, 0), $` + strconv.Itoa(len(args)) + `::numeric[]) AS price_bucket,
+				p.stock > 0 AS in_stock
+			FROM products p
+			WHERE p.deleted_at IS NULL` + where + `
+		)
+		SELECT 'category', COALESCE(m.child_category_id, ''), COALESCE(c.name, ''), COUNT(*)
+		FROM matched m LEFT JOIN categories c ON c.id = m.child_category_id
+		GROUP BY 2, 3
+		UNION ALL
+		SELECT 'status', COALESCE(status, ''), '', COUNT(*) FROM matched GROUP BY 2
+		UNION ALL
+		SELECT 'condition', COALESCE(condition, ''), '', COUNT(*) FROM matched GROUP BY 2
+		UNION ALL
+		SELECT 'price', price_bucket::text, '', COUNT(*) FROM matched GROUP BY 2
+		UNION ALL
+		SELECT 'availability', CASE WHEN in_stock THEN '` + models.AvailabilityInStock + `' ELSE '` + models.AvailabilityOutOfStock + `' END, '', COUNT(*)
+		FROM matched GROUP BY 2
+		ORDER BY 1, 4 DESC, 2`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to count facets: %w", err)
+	}
+	defer rows.Close()
+
+	facets := models.ProductFacets{
+		Categories:   []models.FacetCount{},
+		Status:       []models.FacetCount{},
+		Condition:    []models.FacetCount{},
+		PriceRanges:  models.PriceRanges(),
+		Availability: []models.FacetCount{},
+	}
+	for rows.Next() {
+		var facet string
+		var count models.FacetCount
+		if err := rows.Scan(&facet, &count.Value, &count.Label, &count.Count); err != nil {
+			return fmt.Errorf("failed to scan facet: %w", err)
+		}
+		switch facet {
+		case "category":
+			facets.Categories = append(facets.Categories, count)
+		case "status":
+			facets.Status = append(facets.Status, count)
+		case "condition":
+			facets.Condition = append(facets.Condition, count)
+		case "price":
+			// width_bucket numbers the ranges from 1; prices below the first bound are 0
+			if bucket, err := strconv.Atoi(count.Value); err == nil && bucket > 0 && bucket <= len(facets.PriceRanges) {
+				facets.PriceRanges[bucket-1].Count += count.Count
+			}
+		case "availability":
+			facets.Availability = append(facets.Availability, count)
+			result.Total += count.Count
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating facets: %w", err)
+	}
+
+	result.Facets = facets
+	return nil
+}
@@ -0,0 +1,394 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PromotionRepository defines methods for promotions and their use on sales
+type PromotionRepository interface {
+	GetAll(activeOnly bool) ([]models.Promotion, error)
+	GetByID(id string) (*models.Promotion, error)
+	GetByCode(code string) (*models.Promotion, error)
+	Create(promotion *models.Promotion) error
+	Update(promotion *models.Promotion) error
+	Delete(id string) error
+
+	// GetRunning returns the active promotions whose date window includes the given time
+	GetRunning(at time.Time) ([]models.Promotion, error)
+	// GetUsage counts the sales each promotion has been applied to, in total and for the
+	// customer, leaving out the given sale so it can be re-priced
+	GetUsage(promotionIDs []string, customerID *string, excludeSaleID string) (map[string]models.PromotionUsage, error)
+	// GetProductScopes returns the parent product and category chain of each product
+	GetProductScopes(productIDs []string) (map[string]models.ProductScope, error)
+	GetReport(startDate, endDate time.Time) (*models.PromotionReport, error)
+}
+
+// PromotionRepositoryImpl implements the PromotionRepository interface
+type PromotionRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewPromotionRepository creates a new PromotionRepository
+func NewPromotionRepository(db *pgx.Conn) PromotionRepository {
+	return &PromotionRepositoryImpl{db: db}
+}
+
+// promotionUsageCount counts the live sales a promotion has been applied to
+const promotionUsageCount = `(SELECT COUNT(*) FROM sale_promotions sp
+		JOIN sales s ON s.id = sp.sale_id AND s.deleted_at IS NULL AND s.status <> 'cancelled'
+		WHERE sp.promotion_id = promotions.id)`
+
+const promotionColumns = `id, code, name, COALESCE(description, ''), type, scope, product_ids, category_ids,
+		currency, percent, amount, buy_quantity, get_quantity, bundle_quantity, bundle_price, min_spend,
+		starts_at, ends_at, platforms, usage_limit, usage_limit_per_customer, ` + promotionUsageCount + `,
+		priority, exclusive, active, created_at, updated_at`
+
+func scanPromotion(row pgx.Row) (*models.Promotion, error) {
+	var promotion models.Promotion
+	var platforms []string
+	err := row.Scan(
+		&promotion.ID, &promotion.Code, &promotion.Name, &promotion.Description,
+		&promotion.Type, &promotion.Scope, &promotion.ProductIDs, &promotion.CategoryIDs,
+		&promotion.Currency, &promotion.Percent, &promotion.Amount,
+		&promotion.BuyQuantity, &promotion.GetQuantity, &promotion.BundleQuantity,
+		&promotion.BundlePrice, &promotion.MinSpend, &promotion.StartsAt, &promotion.EndsAt,
+		&platforms, &promotion.UsageLimit, &promotion.UsageLimitPerCustomer, &promotion.UsageCount,
+		&promotion.Priority, &promotion.Exclusive, &promotion.Active,
+		&promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, platform := range platforms {
+		promotion.Platforms = append(promotion.Platforms, models.PlatformType(platform))
+	}
+	promotion.BindCurrency()
+	return &promotion, nil
+}
+
+// platformNames converts platforms to the text array they are stored as
+func platformNames(platforms []models.PlatformType) []string {
+	if len(platforms) == 0 {
+		return nil
+	}
+	names := make([]string, len(platforms))
+	for i, platform := range platforms {
+		names[i] = string(platform)
+	}
+	return names
+}
+
+func (r *PromotionRepositoryImpl) queryPromotions(ctx context.Context, where string, args ...any) ([]models.Promotion, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+promotionColumns+` FROM promotions `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := []models.Promotion{}
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotions = append(promotions, *promotion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promotions: %w", err)
+	}
+
+	return promotions, nil
+}
+
+// GetAll retrieves promotions, highest priority first
+func (r *PromotionRepositoryImpl) GetAll(activeOnly bool) ([]models.Promotion, error) {
+	where := `WHERE deleted_at IS NULL`
+	if activeOnly {
+		where += ` AND active = TRUE`
+	}
+	return r.queryPromotions(context.Background(), where+` ORDER BY priority DESC, code ASC`)
+}
+
+// GetByID retrieves a promotion by its ID
+func (r *PromotionRepositoryImpl) GetByID(id string) (*models.Promotion, error) {
+	return r.getPromotion(`id = $1`, id)
+}
+
+// GetByCode retrieves a promotion by code, ignoring case
+func (r *PromotionRepositoryImpl) GetByCode(code string) (*models.Promotion, error) {
+	return r.getPromotion(`code = $1`, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+func (r *PromotionRepositoryImpl) getPromotion(condition string, arg any) (*models.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE ` + condition + ` AND deleted_at IS NULL`
+
+	promotion, err := scanPromotion(r.db.QueryRow(context.Background(), query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+// Create stores a new promotion
+func (r *PromotionRepositoryImpl) Create(promotion *models.Promotion) error {
+	promotion.GenerateID()
+	now := time.Now()
+	promotion.CreatedAt = now
+	promotion.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO promotions (
+			id, code, name, description, type, scope, product_ids, category_ids,
+			currency, percent, amount, buy_quantity, get_quantity, bundle_quantity, bundle_price, min_spend,
+			starts_at, ends_at, platforms, usage_limit, usage_limit_per_customer,
+			priority, exclusive, active, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+			$20, $21, $22, $23, $24, $25, $26)`,
+		promotion.ID, promotion.Code, promotion.Name, promotion.Description,
+		promotion.Type, promotion.Scope, promotion.ProductIDs, promotion.CategoryIDs,
+		promotion.Currency, promotion.Percent, promotion.Amount,
+		promotion.BuyQuantity, promotion.GetQuantity, promotion.BundleQuantity,
+		promotion.BundlePrice, promotion.MinSpend, promotion.StartsAt, promotion.EndsAt,
+		platformNames(promotion.Platforms), promotion.UsageLimit, promotion.UsageLimitPerCustomer,
+		promotion.Priority, promotion.Exclusive, promotion.Active, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create promotion: %w", err)
+	}
+	return nil
+}
+
+// Update saves changes to a promotion. Sales already priced keep the discounts they were given.
+func (r *PromotionRepositoryImpl) Update(promotion *models.Promotion) error {
+	promotion.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(context.Background(), `
+		UPDATE promotions SET
+		code = $1, name = $2, description = $3, type = $4, scope = $5, product_ids = $6, category_ids = $7,
+		currency = $8, percent = $9, amount = $10, buy_quantity = $11, get_quantity = $12,
+		bundle_quantity = $13, bundle_price = $14, min_spend = $15, starts_at = $16, ends_at = $17,
+		platforms = $18, usage_limit = $19, usage_limit_per_customer = $20,
+		priority = $21, exclusive = $22, active = $23, updated_at = $24
+		WHERE id = $25 AND deleted_at IS NULL`,
+		promotion.Code, promotion.Name, promotion.Description, promotion.Type, promotion.Scope,
+		promotion.ProductIDs, promotion.CategoryIDs,
+		promotion.Currency, promotion.Percent, promotion.Amount, promotion.BuyQuantity, promotion.GetQuantity,
+		promotion.BundleQuantity, promotion.BundlePrice, promotion.MinSpend, promotion.StartsAt, promotion.EndsAt,
+		platformNames(promotion.Platforms), promotion.UsageLimit, promotion.UsageLimitPerCustomer,
+		promotion.Priority, promotion.Exclusive, promotion.Active, promotion.UpdatedAt, promotion.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes a promotion. Sales it was applied to keep their record of it.
+func (r *PromotionRepositoryImpl) Delete(id string) error {
+	_, err := r.db.Exec(context.Background(), `UPDATE promotions SET deleted_at = $1 WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+	return nil
+}
+
+// GetRunning retrieves the active promotions running at the given time
+func (r *PromotionRepositoryImpl) GetRunning(at time.Time) ([]models.Promotion, error) {
+	return r.queryPromotions(context.Background(), `WHERE deleted_at IS NULL AND active = TRUE
+		AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at >= $1)
+		ORDER BY priority DESC, code ASC`, at)
+}
+
+// GetUsage counts the live sales each promotion has been applied to
+func (r *PromotionRepositoryImpl) GetUsage(promotionIDs []string, customerID *string, excludeSaleID string) (map[string]models.PromotionUsage, error) {
+	usage := map[string]models.PromotionUsage{}
+	if len(promotionIDs) == 0 {
+		return usage, nil
+	}
+
+	rows, err := r.db.Query(context.Background(), `
+		SELECT sp.promotion_id, COUNT(*),
+			COUNT(*) FILTER (WHERE s.customer_id = $2::text)
+		FROM sale_promotions sp
+		JOIN sales s ON s.id = sp.sale_id AND s.deleted_at IS NULL AND s.status <> 'cancelled'
+		WHERE sp.promotion_id = ANY($1) AND sp.sale_id <> $3
+		GROUP BY sp.promotion_id`,
+		promotionIDs, customerID, excludeSaleID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotion usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var count models.PromotionUsage
+		if err := rows.Scan(&id, &count.Total, &count.Customer); err != nil {
+			return nil, fmt.Errorf("failed to scan promotion usage: %w", err)
+		}
+		usage[id] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promotion usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetProductScopes finds each product's parent and the categories it sits in
+func (r *PromotionRepositoryImpl) GetProductScopes(productIDs []string) (map[string]models.ProductScope, error) {
+	return queryProductScopes(context.Background(), r.db, productIDs)
+}
+
+// queryProductScopes finds each product's parent and the categories it sits in, from its own
+// category up to the top of the tree
+func queryProductScopes(ctx context.Context, q rowsQuerier, productIDs []string) (map[string]models.ProductScope, error) {
+	scopes := map[string]models.ProductScope{}
+	if len(productIDs) == 0 {
+		return scopes, nil
+	}
+
+	rows, err := q.Query(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT p.id AS product_id, c.id AS category_id, c.parent_id, 0 AS depth
+			FROM products p
+			JOIN categories c ON c.id = p.child_category_id AND c.deleted_at IS NULL
+			WHERE p.id = ANY($1)
+			UNION ALL
+			SELECT chain.product_id, c.id, c.parent_id, chain.depth + 1
+			FROM chain
+			JOIN categories c ON c.id = chain.parent_id AND c.deleted_at IS NULL
+			WHERE chain.depth < 32
+		)
+		SELECT p.id, p.parent_id, COALESCE(
+			(SELECT array_agg(chain.category_id ORDER BY chain.depth) FROM chain WHERE chain.product_id = p.id),
+			'{}')
+		FROM products p
+		WHERE p.id = ANY($1)`,
+		productIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product categories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var scope models.ProductScope
+		if err := rows.Scan(&id, &scope.ParentID, &scope.CategoryIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan product categories: %w", err)
+		}
+		scopes[id] = scope
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product categories: %w", err)
+	}
+
+	return scopes, nil
+}
+
+// GetReport totals the discounts each promotion gave on completed sales dated in the period
+func (r *PromotionRepositoryImpl) GetReport(startDate, endDate time.Time) (*models.PromotionReport, error) {
+	rows, err := r.db.Query(context.Background(), `
+		SELECT sp.promotion_id, sp.code, sp.name, sp.type, COUNT(DISTINCT sp.sale_id),
+			COALESCE(SUM(sp.base_discount), 0), COALESCE(SUM(s.base_total), 0)
+		FROM sale_promotions sp
+		JOIN sales s ON s.id = sp.sale_id
+		WHERE s.deleted_at IS NULL AND s.status = $1 AND s.sale_date BETWEEN $2 AND $3
+		GROUP BY sp.promotion_id, sp.code, sp.name, sp.type
+		ORDER BY 6 DESC, sp.code ASC`,
+		models.SaleStatusCompleted, startDate, endDate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotion report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.PromotionReport{
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Lines:         []models.PromotionReportLine{},
+		TotalDiscount: money.Zero(money.BaseCurrency),
+	}
+	for rows.Next() {
+		var line models.PromotionReportLine
+		err := rows.Scan(&line.PromotionID, &line.Code, &line.Name, &line.Type, &line.SaleCount,
+			&line.Discount, &line.SalesTotal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion report line: %w", err)
+		}
+		line.Discount = line.Discount.In(money.BaseCurrency)
+		line.SalesTotal = line.SalesTotal.In(money.BaseCurrency)
+		report.TotalDiscount = report.TotalDiscount.Add(line.Discount)
+		report.Lines = append(report.Lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promotion report: %w", err)
+	}
+
+	return report, nil
+}
+
+// insertSalePromotions records the promotions applied to a sale
+func insertSalePromotions(ctx context.Context, tx pgx.Tx, sale *models.Sale) error {
+	now := time.Now()
+	for i := range sale.Promotions {
+		applied := &sale.Promotions[i]
+		applied.ID = uuid.NewString()
+		applied.SaleID = sale.ID
+		applied.CreatedAt = now
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO sale_promotions (id, sale_id, promotion_id, code, name, type, discount, base_discount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			applied.ID, applied.SaleID, applied.PromotionID, applied.Code, applied.Name, applied.Type,
+			applied.Discount, applied.BaseDiscount, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record promotion %s on sale: %w", applied.Code, err)
+		}
+	}
+	return nil
+}
+
+// getSalePromotions loads the promotions applied to a sale
+func getSalePromotions(ctx context.Context, q *pgx.Conn, saleID string) ([]models.AppliedPromotion, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, sale_id, promotion_id, code, name, type, discount, base_discount, created_at
+		FROM sale_promotions WHERE sale_id = $1 ORDER BY created_at, code`, saleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sale promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []models.AppliedPromotion
+	for rows.Next() {
+		var applied models.AppliedPromotion
+		err := rows.Scan(&applied.ID, &applied.SaleID, &applied.PromotionID, &applied.Code, &applied.Name,
+			&applied.Type, &applied.Discount, &applied.BaseDiscount, &applied.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sale promotion: %w", err)
+		}
+		promotions = append(promotions, applied)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sale promotions: %w", err)
+	}
+	return promotions, nil
+}
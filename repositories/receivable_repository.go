@@ -0,0 +1,386 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSaleHasPayments is returned when a sale that customer payments were allocated to
+// would stop being an open invoice
+var ErrSaleHasPayments = errors.New("sale has customer payments allocated to it; delete the payments first")
+
+// ReceivableRepository defines methods for accounts receivable
+type ReceivableRepository interface {
+	GetOpenInvoices(customerID string) ([]models.OpenInvoice, error)
+	GetAging(asOf time.Time) (*models.ARAgingReport, error)
+	GetStatement(customer *models.Customer, startDate, endDate time.Time) (*models.CustomerStatement, error)
+
+	// Customer payments
+	GetPayment(id string) (*models.CustomerPayment, error)
+	ListPayments(customerID string) ([]models.CustomerPayment, error)
+	CreatePayment(payment *models.CustomerPayment, lock *models.PeriodLock) error
+	DeletePayment(id string, lock *models.PeriodLock) error
+}
+
+// ReceivableRepositoryImpl implements the ReceivableRepository interface
+type ReceivableRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewReceivableRepository creates a new ReceivableRepository
+func NewReceivableRepository(db *pgx.Conn) ReceivableRepository {
+	return &ReceivableRepositoryImpl{db: db}
+}
+
+// GetOpenInvoices returns a customer's unpaid completed sales, oldest due first
+func (r *ReceivableRepositoryImpl) GetOpenInvoices(customerID string) ([]models.OpenInvoice, error) {
+	return queryOpenInvoices(context.Background(), r.db, customerID, time.Now(), false)
+}
+
+// GetAging buckets every customer's balance outstanding on the given date by days past due
+func (r *ReceivableRepositoryImpl) GetAging(asOf time.Time) (*models.ARAgingReport, error) {
+	invoices, err := queryOpenInvoices(context.Background(), r.db, "", asOf, true)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewARAgingReport(asOf, invoices), nil
+}
+
+// queryOpenInvoices returns completed sales with a balance, oldest due first. With historical
+// set, only sales made by asOf are included and payments received after it are added back to
+// their balance, giving what was outstanding on that date.
+func queryOpenInvoices(ctx context.Context, q rowsQuerier, customerID string, asOf time.Time, historical bool) ([]models.OpenInvoice, error) {
+	conditions := []string{"s.deleted_at IS NULL", "s.status = $1"}
+	args := []interface{}{models.SaleStatusCompleted}
+
+	balance := "s.balance"
+	if historical {
+		args = append(args, asOf)
+		conditions = append(conditions, fmt.Sprintf("s.sale_date <= $%d", len(args)))
+		balance = fmt.Sprintf(`s.balance + COALESCE((SELECT SUM(pa.amount)
+			FROM payment_allocations pa
+			JOIN customer_payments cp ON cp.id = pa.payment_id
+			WHERE pa.sale_id = s.id AND cp.payment_date > $%d), 0)`, len(args))
+	}
+	if customerID != "" {
+		args = append(args, customerID)
+		conditions = append(conditions, fmt.Sprintf("s.customer_id = $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM (
+			SELECT s.id, s.reference_no, COALESCE(s.customer_id, '') AS customer_id,
+				COALESCE(c.name, '') AS customer_name, s.sale_date,
+				COALESCE(s.due_date, s.sale_date) AS due_date, s.currency, s.exchange_rate,
+				s.total, s.paid, %s AS balance
+			FROM sales s
+			LEFT JOIN customers c ON c.id = s.customer_id
+			WHERE %s
+		) o
+		WHERE o.balance > 0
+		ORDER BY o.due_date, o.sale_date, o.reference_no`, balance, strings.Join(conditions, " AND "))
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.OpenInvoice{}
+	for rows.Next() {
+		inv := models.OpenInvoice{
+			Total:   money.Zero(money.BaseCurrency),
+			Paid:    money.Zero(money.BaseCurrency),
+			Balance: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(&inv.SaleID, &inv.ReferenceNo, &inv.CustomerID, &inv.CustomerName,
+			&inv.SaleDate, &inv.DueDate, &inv.Currency, &inv.ExchangeRate,
+			&inv.Total, &inv.Paid, &inv.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan open invoice: %w", err)
+		}
+		inv.Age(asOf)
+		invoices = append(invoices, inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open invoices: %w", err)
+	}
+
+	return invoices, nil
+}
+
+// GetStatement lists a customer's invoices and payments between the dates, in the base currency
+func (r *ReceivableRepositoryImpl) GetStatement(customer *models.Customer, startDate, endDate time.Time) (*models.CustomerStatement, error) {
+	ctx := context.Background()
+	var lines []models.StatementLine
+
+	// Each completed sale is an invoice; whatever was paid when it was made is a payment on the same day
+	rows, err := r.db.Query(ctx, `SELECT s.id, s.reference_no, s.sale_date, s.due_date, s.currency,
+			s.exchange_rate, s.base_total,
+			s.paid - COALESCE((SELECT SUM(pa.amount) FROM payment_allocations pa WHERE pa.sale_id = s.id), 0)
+		FROM sales s
+		WHERE s.customer_id = $1 AND s.deleted_at IS NULL AND s.status = $2 AND s.sale_date <= $3`,
+		customer.ID, models.SaleStatusCompleted, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer invoices: %w", err)
+	}
+	for rows.Next() {
+		var id, reference, currency string
+		var date time.Time
+		var due *time.Time
+		var rate money.Rate
+		total := money.Zero(money.BaseCurrency)
+		paid := money.Zero(money.BaseCurrency)
+		if err := rows.Scan(&id, &reference, &date, &due, &currency, &rate, &total, &paid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan customer invoice: %w", err)
+		}
+		lines = append(lines, models.StatementLine{
+			Date: date, Type: models.StatementLineInvoice, SourceID: id, Reference: reference, DueDate: due,
+			Debit: total.In(money.BaseCurrency), Credit: money.Zero(money.BaseCurrency),
+		})
+		if paid.IsPositive() {
+			lines = append(lines, models.StatementLine{
+				Date: date, Type: models.StatementLinePayment, SourceID: id, Reference: reference,
+				Debit: money.Zero(money.BaseCurrency), Credit: toBase(rate, paid, currency),
+			})
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer invoices: %w", err)
+	}
+
+	payments, err := r.queryPayments(ctx, `WHERE customer_id = $1 AND payment_date <= $2`, customer.ID, endDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		lines = append(lines, models.StatementLine{
+			Date: p.PaymentDate, Type: models.StatementLinePayment, SourceID: p.ID, Reference: p.PaymentNo,
+			Debit: money.Zero(money.BaseCurrency), Credit: p.BaseAmount,
+		})
+	}
+
+	return models.NewCustomerStatement(customer, startDate, endDate, lines), nil
+}
+
+const customerPaymentColumns = `id, payment_no, customer_id, payment_date, currency, amount,
+	COALESCE(payment_method, ''), COALESCE(reference, ''), COALESCE(note, ''), base_amount, created_at`
+
+// queryPayments runs a query over customer payments with the given WHERE clause, newest first
+func (r *ReceivableRepositoryImpl) queryPayments(ctx context.Context, where string, args ...interface{}) ([]models.CustomerPayment, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+customerPaymentColumns+` FROM customer_payments `+where+`
+		ORDER BY payment_date DESC, created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []models.CustomerPayment{}
+	for rows.Next() {
+		p := models.CustomerPayment{
+			Amount:     money.Zero(money.BaseCurrency),
+			BaseAmount: money.Zero(money.BaseCurrency),
+		}
+		err := rows.Scan(&p.ID, &p.PaymentNo, &p.CustomerID, &p.PaymentDate, &p.Currency, &p.Amount,
+			&p.PaymentMethod, &p.Reference, &p.Note, &p.BaseAmount, &p.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer payment: %w", err)
+		}
+		p.Currency = money.NormalizeCurrency(p.Currency)
+		p.Amount = p.Amount.In(p.Currency)
+		p.BaseAmount = p.BaseAmount.In(money.BaseCurrency)
+		payments = append(payments, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// GetPayment retrieves a customer payment with its allocations
+func (r *ReceivableRepositoryImpl) GetPayment(id string) (*models.CustomerPayment, error) {
+	ctx := context.Background()
+	payments, err := r.queryPayments(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return nil, nil
+	}
+	payment := &payments[0]
+
+	rows, err := r.db.Query(ctx, `SELECT pa.id, pa.sale_id, s.reference_no, pa.amount, pa.base_amount, pa.created_at
+		FROM payment_allocations pa
+		JOIN sales s ON s.id = pa.sale_id
+		WHERE pa.payment_id = $1
+		ORDER BY pa.created_at, s.reference_no`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment allocations: %w", err)
+	}
+	defer rows.Close()
+
+	payment.Allocations = []models.PaymentAllocation{}
+	for rows.Next() {
+		a := models.PaymentAllocation{
+			PaymentID:  id,
+			Amount:     money.Zero(payment.Currency),
+			BaseAmount: money.Zero(money.BaseCurrency),
+		}
+		if err := rows.Scan(&a.ID, &a.SaleID, &a.SaleReference, &a.Amount, &a.BaseAmount, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payment allocation: %w", err)
+		}
+		a.Amount = a.Amount.In(payment.Currency)
+		a.BaseAmount = a.BaseAmount.In(money.BaseCurrency)
+		payment.Allocations = append(payment.Allocations, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payment allocations: %w", err)
+	}
+
+	return payment, nil
+}
+
+// ListPayments returns customer payments, newest first, optionally for one customer
+func (r *ReceivableRepositoryImpl) ListPayments(customerID string) ([]models.CustomerPayment, error) {
+	if customerID == "" {
+		return r.queryPayments(context.Background(), "")
+	}
+	return r.queryPayments(context.Background(), `WHERE customer_id = $1`, customerID)
+}
+
+// CreatePayment allocates a payment to the customer's open sales, records what each sale
+// has been paid and posts the payment to the journal
+func (r *ReceivableRepositoryImpl) CreatePayment(payment *models.CustomerPayment, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	payment.GenerateID()
+
+	// Lock the customer's sales so concurrent payments cannot settle the same balance
+	_, err = tx.Exec(ctx, `SELECT id FROM sales
+		WHERE customer_id = $1 AND status = $2 AND deleted_at IS NULL
+		FOR UPDATE`, payment.CustomerID, models.SaleStatusCompleted)
+	if err != nil {
+		return fmt.Errorf("failed to lock customer sales: %w", err)
+	}
+
+	invoices, err := queryOpenInvoices(ctx, tx, payment.CustomerID, time.Now(), false)
+	if err != nil {
+		return err
+	}
+	if err = payment.Allocate(invoices); err != nil {
+		return err
+	}
+
+	payment.CreatedAt = time.Now()
+	_, err = tx.Exec(ctx, `INSERT INTO customer_payments (
+			id, payment_no, customer_id, payment_date, currency, amount,
+			payment_method, reference, note, base_amount, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		payment.ID, payment.PaymentNo, payment.CustomerID, payment.PaymentDate, payment.Currency, payment.Amount,
+		payment.PaymentMethod, payment.Reference, payment.Note, payment.BaseAmount, payment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert customer payment: %w", err)
+	}
+
+	for i := range payment.Allocations {
+		a := &payment.Allocations[i]
+		a.CreatedAt = payment.CreatedAt
+		_, err = tx.Exec(ctx, `INSERT INTO payment_allocations (id, payment_id, sale_id, amount, base_amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			a.ID, payment.ID, a.SaleID, a.Amount, a.BaseAmount, a.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert payment allocation: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE sales SET paid = paid + $1, balance = balance - $1, updated_at = $2
+			WHERE id = $3`, a.Amount, payment.CreatedAt, a.SaleID)
+		if err != nil {
+			return fmt.Errorf("failed to update sale balance: %w", err)
+		}
+	}
+
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceCustomerPayment, payment.ID, true); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePayment removes a payment, returning its allocations to the balances of the sales
+// it settled and reversing its journal entry
+func (r *ReceivableRepositoryImpl) DeletePayment(id string, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE sales s SET paid = s.paid - pa.amount, balance = s.balance + pa.amount,
+			updated_at = $1
+		FROM payment_allocations pa
+		WHERE pa.sale_id = s.id AND pa.payment_id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore sale balances: %w", err)
+	}
+
+	if err = syncDocumentJournal(ctx, tx, models.JournalSourceCustomerPayment, id, false); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM payment_allocations WHERE payment_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete payment allocations: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM customer_payments WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete customer payment: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// checkSaleAllocations returns ErrSaleHasPayments when customer payments are allocated to the sale
+func checkSaleAllocations(ctx context.Context, tx pgx.Tx, saleID string) error {
+	var count int
+	err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM payment_allocations WHERE sale_id = $1`, saleID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check sale payments: %w", err)
+	}
+	if count > 0 {
+		return ErrSaleHasPayments
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"inventory-go/models"
+	"inventory-go/money"
 	"strings"
 	"time"
 
@@ -14,13 +16,13 @@ import (
 type RejectRepository interface {
 	GetByID(id string) (*models.Reject, error)
 	GetByReference(reference string) (*models.Reject, error)
-	Create(reject *models.Reject) error
-	Update(reject *models.Reject) error
-	Delete(id string) error
+	Create(reject *models.Reject, lock *models.PeriodLock) error
+	Update(reject *models.Reject, lock *models.PeriodLock) error
+	Delete(id string, lock *models.PeriodLock) error
 	List(offset, limit int, status string, startDate, endDate *time.Time) ([]models.Reject, int64, error)
-	AddRejectItem(item *models.RejectItem) error
-	UpdateRejectItem(item *models.RejectItem) error
-	DeleteRejectItem(id string) error
+	AddRejectItem(item *models.RejectItem, lock *models.PeriodLock) error
+	UpdateRejectItem(item *models.RejectItem, lock *models.PeriodLock) error
+	DeleteRejectItem(id string, lock *models.PeriodLock) error
 	GetRejectSummary(startDate, endDate time.Time) (*models.RejectSummary, error)
 	GetDailyReject(startDate, endDate time.Time) ([]models.DailyReject, error)
 }
@@ -44,7 +46,7 @@ func (r *RejectRepositoryImpl) GetByID(id string) (*models.Reject, error) {
 		WHERE id = $1 AND deleted_at IS NULL
 	`
 	var reject models.Reject
-	err := r.db.QueryRow(nil, query, id).Scan(
+	err := r.db.QueryRow(context.Background(), query, id).Scan(
 		&reject.ID, &reject.ReferenceNo, &reject.Status, &reject.RejectDate,
 		&reject.Reason, &reject.Total, &reject.CreatedAt, &reject.UpdatedAt,
 	)
@@ -57,11 +59,12 @@ func (r *RejectRepositoryImpl) GetByID(id string) (*models.Reject, error) {
 
 	// Get the reject items
 	itemsQuery := `
-		SELECT id, reject_id, product_id, product_name, quantity, unit_cost, subtotal, created_at, updated_at
+		SELECT id, reject_id, product_id, product_name, quantity, unit_cost, subtotal, cogs,
+			COALESCE(unit, ''), COALESCE(unit_quantity, 0), unit_factor, COALESCE(base_unit, ''), created_at, updated_at
 		FROM reject_items
 		WHERE reject_id = $1 AND deleted_at IS NULL
 	`
-	rows, err := r.db.Query(nil, itemsQuery, id)
+	rows, err := r.db.Query(context.Background(), itemsQuery, id)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +74,8 @@ func (r *RejectRepositoryImpl) GetByID(id string) (*models.Reject, error) {
 		var item models.RejectItem
 		err := rows.Scan(
 			&item.ID, &item.RejectID, &item.ProductID, &item.ProductName,
-			&item.Quantity, &item.UnitCost, &item.Subtotal, &item.CreatedAt, &item.UpdatedAt,
+			&item.Quantity, &item.UnitCost, &item.Subtotal, &item.COGS,
+			&item.Unit, &item.UnitQuantity, &item.UnitFactor, &item.BaseUnit, &item.CreatedAt, &item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -90,7 +94,7 @@ func (r *RejectRepositoryImpl) GetByReference(reference string) (*models.Reject,
 		WHERE reference_no = $1 AND deleted_at IS NULL
 	`
 	var id string
-	err := r.db.QueryRow(nil, query, reference).Scan(&id)
+	err := r.db.QueryRow(context.Background(), query, reference).Scan(&id)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -102,13 +106,18 @@ func (r *RejectRepositoryImpl) GetByReference(reference string) (*models.Reject,
 }
 
 // Create creates a new reject
-func (r *RejectRepositoryImpl) Create(reject *models.Reject) error {
+func (r *RejectRepositoryImpl) Create(reject *models.Reject, lock *models.PeriodLock) error {
 	// Start a transaction
-	tx, err := r.db.Begin(nil)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(nil)
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
 
 	// Generate ID if not provided
 	if reject.ID == "" {
@@ -122,7 +131,7 @@ func (r *RejectRepositoryImpl) Create(reject *models.Reject) error {
 		RETURNING id
 	`
 	var id string
-	err = tx.QueryRow(nil, query,
+	err = tx.QueryRow(ctx, query,
 		reject.ID, reject.ReferenceNo, reject.Status, reject.RejectDate,
 		reject.Reason, reject.Total, time.Now(), time.Now(),
 	).Scan(&id)
@@ -140,12 +149,14 @@ func (r *RejectRepositoryImpl) Create(reject *models.Reject) error {
 		}
 
 		itemQuery := `
-			INSERT INTO reject_items (id, reject_id, product_id, product_name, quantity, unit_cost, subtotal, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			INSERT INTO reject_items (id, reject_id, product_id, product_name, quantity, unit_cost, subtotal,
+				unit, unit_quantity, unit_factor, base_unit, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		`
-		_, err = tx.Exec(nil, itemQuery,
+		_, err = tx.Exec(ctx, itemQuery,
 			item.ID, item.RejectID, item.ProductID, item.ProductName,
-			item.Quantity, item.UnitCost, item.Subtotal, time.Now(), time.Now(),
+			item.Quantity, item.UnitCost, item.Subtotal,
+			item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit, time.Now(), time.Now(),
 		)
 		if err != nil {
 			return err
@@ -153,63 +164,117 @@ func (r *RejectRepositoryImpl) Create(reject *models.Reject) error {
 	}
 
 	// Update the total if not provided
-	if reject.Total == 0 && len(reject.Items) > 0 {
-		var total float64
+	if reject.Total.IsZero() && len(reject.Items) > 0 {
+		total := money.Zero(money.DefaultCurrency)
 		for _, item := range reject.Items {
-			total += item.Subtotal
+			total = total.Add(item.Subtotal)
 		}
 
 		updateQuery := `UPDATE rejects SET total = $1 WHERE id = $2`
-		_, err = tx.Exec(nil, updateQuery, total, id)
+		_, err = tx.Exec(ctx, updateQuery, total, id)
 		if err != nil {
 			return err
 		}
 		reject.Total = total
 	}
 
+	// Write off the inventory cost of a completed reject
+	if reject.Status == models.RejectStatusCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceReject, id, false, true); err != nil {
+			return err
+		}
+	}
+
 	// Commit the transaction
-	return tx.Commit(nil)
+	return tx.Commit(ctx)
 }
 
 // Update updates an existing reject
-func (r *RejectRepositoryImpl) Update(reject *models.Reject) error {
+func (r *RejectRepositoryImpl) Update(reject *models.Reject, lock *models.PeriodLock) error {
+	// Start a transaction
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	previousStatus, err := lockRejectStatus(ctx, tx, reject.ID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE rejects
 		SET reference_no = $1, status = $2, reject_date = $3, reason = $4, total = $5, updated_at = $6
 		WHERE id = $7 AND deleted_at IS NULL
 	`
-	_, err := r.db.Exec(nil, query,
+	_, err = tx.Exec(ctx, query,
 		reject.ReferenceNo, reject.Status, reject.RejectDate,
 		reject.Reason, reject.Total, time.Now(), reject.ID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Write off or restore inventory cost when the status changes to or from completed
+	wasCompleted := previousStatus == models.RejectStatusCompleted
+	isCompleted := reject.Status == models.RejectStatusCompleted
+	if wasCompleted != isCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceReject, reject.ID, wasCompleted, isCompleted); err != nil {
+			return err
+		}
+	}
+
+	// Commit the transaction
+	return tx.Commit(ctx)
 }
 
 // Delete soft-deletes a reject
-func (r *RejectRepositoryImpl) Delete(id string) error {
+func (r *RejectRepositoryImpl) Delete(id string, lock *models.PeriodLock) error {
 	// Start a transaction
-	tx, err := r.db.Begin(nil)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(nil)
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	// Restore the inventory cost of a completed reject
+	status, err := lockRejectStatus(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if status == models.RejectStatusCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceReject, id, true, false); err != nil {
+			return err
+		}
+	}
 
 	// Delete the reject items
 	itemsQuery := `UPDATE reject_items SET deleted_at = $1 WHERE reject_id = $2`
-	_, err = tx.Exec(nil, itemsQuery, time.Now(), id)
+	_, err = tx.Exec(ctx, itemsQuery, time.Now(), id)
 	if err != nil {
 		return err
 	}
 
 	// Delete the reject
 	query := `UPDATE rejects SET deleted_at = $1 WHERE id = $2`
-	_, err = tx.Exec(nil, query, time.Now(), id)
+	_, err = tx.Exec(ctx, query, time.Now(), id)
 	if err != nil {
 		return err
 	}
 
 	// Commit the transaction
-	return tx.Commit(nil)
+	return tx.Commit(ctx)
 }
 
 // List retrieves a list of rejects with pagination and filtering
@@ -241,7 +306,7 @@ func (r *RejectRepositoryImpl) List(offset, limit int, status string, startDate,
 	whereClause := "WHERE " + strings.Join(conditions, " AND ")
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM rejects %s", whereClause)
 	var total int64
-	err := r.db.QueryRow(nil, countQuery, args...).Scan(&total)
+	err := r.db.QueryRow(context.Background(), countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -256,7 +321,7 @@ func (r *RejectRepositoryImpl) List(offset, limit int, status string, startDate,
 	`, whereClause, argIndex, argIndex+1)
 	args = append(args, limit, offset)
 
-	rows, err := r.db.Query(nil, query, args...)
+	rows, err := r.db.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -279,27 +344,34 @@ func (r *RejectRepositoryImpl) List(offset, limit int, status string, startDate,
 }
 
 // AddRejectItem adds a new item to a reject
-func (r *RejectRepositoryImpl) AddRejectItem(item *models.RejectItem) error {
+func (r *RejectRepositoryImpl) AddRejectItem(item *models.RejectItem, lock *models.PeriodLock) error {
 	// Generate ID if not provided
 	if item.ID == "" {
 		item.ID = uuid.NewString()
 	}
 
 	// Start a transaction
-	tx, err := r.db.Begin(nil)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(nil)
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
 
 	// Insert the item
 	query := `
-		INSERT INTO reject_items (id, reject_id, product_id, product_name, quantity, unit_cost, subtotal, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO reject_items (id, reject_id, product_id, product_name, quantity, unit_cost, subtotal,
+			unit, unit_quantity, unit_factor, base_unit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
-	_, err = tx.Exec(nil, query,
+	_, err = tx.Exec(ctx, query,
 		item.ID, item.RejectID, item.ProductID, item.ProductName,
-		item.Quantity, item.UnitCost, item.Subtotal, time.Now(), time.Now(),
+		item.Quantity, item.UnitCost, item.Subtotal,
+		item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit, time.Now(), time.Now(),
 	)
 	if err != nil {
 		return err
@@ -312,33 +384,45 @@ func (r *RejectRepositoryImpl) AddRejectItem(item *models.RejectItem) error {
 		    updated_at = $2
 		WHERE id = $1
 	`
-	_, err = tx.Exec(nil, updateQuery, item.RejectID, time.Now())
+	_, err = tx.Exec(ctx, updateQuery, item.RejectID, time.Now())
 	if err != nil {
 		return err
 	}
 
+	// Re-cost a completed reject after its items change
+	if err = repostRejectCosts(ctx, tx, item.RejectID); err != nil {
+		return err
+	}
+
 	// Commit the transaction
-	return tx.Commit(nil)
+	return tx.Commit(ctx)
 }
 
 // UpdateRejectItem updates an existing reject item
-func (r *RejectRepositoryImpl) UpdateRejectItem(item *models.RejectItem) error {
+func (r *RejectRepositoryImpl) UpdateRejectItem(item *models.RejectItem, lock *models.PeriodLock) error {
 	// Start a transaction
-	tx, err := r.db.Begin(nil)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(nil)
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
 
 	// Update the item
 	query := `
 		UPDATE reject_items
-		SET product_id = $1, product_name = $2, quantity = $3, unit_cost = $4, subtotal = $5, updated_at = $6
-		WHERE id = $7 AND deleted_at IS NULL
+		SET product_id = $1, product_name = $2, quantity = $3, unit_cost = $4, subtotal = $5,
+			unit = $6, unit_quantity = $7, unit_factor = $8, base_unit = $9, updated_at = $10
+		WHERE id = $11 AND deleted_at IS NULL
 	`
-	_, err = tx.Exec(nil, query,
+	_, err = tx.Exec(ctx, query,
 		item.ProductID, item.ProductName, item.Quantity,
-		item.UnitCost, item.Subtotal, time.Now(), item.ID,
+		item.UnitCost, item.Subtotal, item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit,
+		time.Now(), item.ID,
 	)
 	if err != nil {
 		return err
@@ -351,35 +435,45 @@ func (r *RejectRepositoryImpl) UpdateRejectItem(item *models.RejectItem) error {
 		    updated_at = $2
 		WHERE id = $1
 	`
-	_, err = tx.Exec(nil, updateQuery, item.RejectID, time.Now())
+	_, err = tx.Exec(ctx, updateQuery, item.RejectID, time.Now())
 	if err != nil {
 		return err
 	}
 
+	// Re-cost a completed reject after its items change
+	if err = repostRejectCosts(ctx, tx, item.RejectID); err != nil {
+		return err
+	}
+
 	// Commit the transaction
-	return tx.Commit(nil)
+	return tx.Commit(ctx)
 }
 
 // DeleteRejectItem soft-deletes a reject item
-func (r *RejectRepositoryImpl) DeleteRejectItem(id string) error {
+func (r *RejectRepositoryImpl) DeleteRejectItem(id string, lock *models.PeriodLock) error {
 	// Start a transaction
-	tx, err := r.db.Begin(nil)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(nil)
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
 
 	// Get the reject ID for the item
 	var rejectID string
 	idQuery := `SELECT reject_id FROM reject_items WHERE id = $1 AND deleted_at IS NULL`
-	err = tx.QueryRow(nil, idQuery, id).Scan(&rejectID)
+	err = tx.QueryRow(ctx, idQuery, id).Scan(&rejectID)
 	if err != nil {
 		return err
 	}
 
 	// Delete the item
 	query := `UPDATE reject_items SET deleted_at = $1 WHERE id = $2`
-	_, err = tx.Exec(nil, query, time.Now(), id)
+	_, err = tx.Exec(ctx, query, time.Now(), id)
 	if err != nil {
 		return err
 	}
@@ -391,13 +485,18 @@ func (r *RejectRepositoryImpl) DeleteRejectItem(id string) error {
 		    updated_at = $2
 		WHERE id = $1
 	`
-	_, err = tx.Exec(nil, updateQuery, rejectID, time.Now())
+	_, err = tx.Exec(ctx, updateQuery, rejectID, time.Now())
 	if err != nil {
 		return err
 	}
 
+	// Re-cost a completed reject after its items change
+	if err = repostRejectCosts(ctx, tx, rejectID); err != nil {
+		return err
+	}
+
 	// Commit the transaction
-	return tx.Commit(nil)
+	return tx.Commit(ctx)
 }
 
 // GetRejectSummary retrieves summary statistics for rejects within a date range
@@ -416,7 +515,7 @@ func (r *RejectRepositoryImpl) GetRejectSummary(startDate, endDate time.Time) (*
 	`
 
 	var summary models.RejectSummary
-	err := r.db.QueryRow(nil, query, startDate, endDate).Scan(
+	err := r.db.QueryRow(context.Background(), query, startDate, endDate).Scan(
 		&summary.TotalRejects,
 		&summary.TotalCompletedRejects,
 		&summary.TotalPendingRejects,
@@ -449,7 +548,7 @@ func (r *RejectRepositoryImpl) GetDailyReject(startDate, endDate time.Time) ([]m
 		ORDER BY DATE(reject_date)
 	`
 
-	rows, err := r.db.Query(nil, query, startDate, endDate)
+	rows, err := r.db.Query(context.Background(), query, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -472,3 +571,25 @@ func (r *RejectRepositoryImpl) GetDailyReject(startDate, endDate time.Time) ([]m
 
 	return results, nil
 }
+
+// lockRejectStatus locks a reject row and returns its current status
+func lockRejectStatus(ctx context.Context, tx pgx.Tx, id string) (models.RejectStatus, error) {
+	var status models.RejectStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM rejects WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("failed to get reject status: %w", err)
+	}
+	return status, nil
+}
+
+// repostRejectCosts re-costs a completed reject from its current items
+func repostRejectCosts(ctx context.Context, tx pgx.Tx, rejectID string) error {
+	status, err := lockRejectStatus(ctx, tx, rejectID)
+	if err != nil {
+		return err
+	}
+	if status != models.RejectStatusCompleted {
+		return nil
+	}
+	return syncDocumentPostings(ctx, tx, models.CostSourceReject, rejectID, true, true)
+}
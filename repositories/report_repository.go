@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReportRepository defines methods for sales profitability reports
+type ReportRepository interface {
+	GetProfitReport(filter models.ProfitReportFilter) (*models.ProfitReport, error)
+}
+
+// ReportRepositoryImpl implements the ReportRepository interface
+type ReportRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewReportRepository creates a new ReportRepository
+func NewReportRepository(db *pgx.Conn) ReportRepository {
+	return &ReportRepositoryImpl{db: db}
+}
+
+// Report segments of a sale relative to the requested period
+const (
+	profitSegmentCurrent  = "current"
+	profitSegmentPrevious = "previous"
+	profitSegmentBefore   = "before"
+)
+
+// profitGroupKeys maps a grouping to the SQL expressions of its key and label
+var profitGroupKeys = map[models.ProfitGroupBy][2]string{
+	models.ProfitGroupByProduct: {
+		"COALESCE(p.parent_id, si.product_id)",
+		"COALESCE(pp.basic->>'name', p.basic->>'name', si.product_name)",
+	},
+	models.ProfitGroupByVariant: {
+		"si.product_id",
+		"COALESCE(p.basic->>'name', si.product_name)",
+	},
+	models.ProfitGroupByCategory: {
+		"COALESCE(rc.id, '')",
+		"COALESCE(rc.name, 'Uncategorized')",
+	},
+	models.ProfitGroupByCustomer: {
+		"COALESCE(s.customer_id, '')",
+		"COALESCE(cu.name, 'Walk-in customer')",
+	},
+	models.ProfitGroupByPlatform: {
+		"COALESCE(s.platform, '')",
+		"COALESCE(s.platform, '')",
+	},
+}
+
+// GetProfitReport returns revenue, discounts, COGS and gross margin of completed sales,
+// grouped by the filter's dimension and compared with the previous period.
+//
+// COGS is the cost recorded when the sale was completed. Sales completed before costing
+// was introduced fall back to the weighted average landed unit cost of the product's
+// completed stock-ins.
+func (r *ReportRepositoryImpl) GetProfitReport(filter models.ProfitReportFilter) (*models.ProfitReport, error) {
+	ctx := context.Background()
+
+	if filter.CategoryLevel < 1 {
+		filter.CategoryLevel = 1
+	}
+	previousStart, previousEnd := filter.PreviousPeriod()
+
+	// Period lines are compared with the whole bucket before them, which may start before the previous period
+	windowStart := previousStart
+	keyExpr, labelExpr := "", ""
+	if filter.GroupBy == models.ProfitGroupByPeriod {
+		bucketBefore := filter.Interval.Previous(filter.Interval.Truncate(filter.StartDate))
+		if bucketBefore.Before(windowStart) {
+			windowStart = bucketBefore
+		}
+		keyExpr = fmt.Sprintf("TO_CHAR(DATE_TRUNC('%s', s.sale_date), 'YYYY-MM-DD')", filter.Interval)
+		labelExpr = keyExpr
+	} else {
+		exprs, ok := profitGroupKeys[filter.GroupBy]
+		if !ok {
+			return nil, fmt.Errorf("unsupported profit report grouping %q", filter.GroupBy)
+		}
+		keyExpr, labelExpr = exprs[0], exprs[1]
+	}
+
+	conditions := []string{
+		"s.status = 'completed'",
+		"s.deleted_at IS NULL",
+		"si.deleted_at IS NULL",
+		"s.sale_date >= $1",
+		"s.sale_date <= $2",
+	}
+	args := []interface{}{windowStart, filter.EndDate, filter.StartDate, previousStart, filter.CategoryLevel}
+	argIndex := len(args) + 1
+
+	if filter.Platform != "" {
+		conditions = append(conditions, fmt.Sprintf("s.platform = $%d", argIndex))
+		args = append(args, filter.Platform)
+		argIndex++
+	}
+	if filter.CustomerID != "" {
+		conditions = append(conditions, fmt.Sprintf("s.customer_id = $%d", argIndex))
+		args = append(args, filter.CustomerID)
+		argIndex++
+	}
+	if filter.CategoryID != "" {
+		conditions = append(conditions, fmt.Sprintf("$%d = ANY(cp.path)", argIndex))
+		args = append(args, filter.CategoryID)
+		argIndex++
+	}
+
+	query := fmt.Sprintf(`WITH RECURSIVE category_paths AS (
+			SELECT id, ARRAY[id]::VARCHAR[] AS path
+			FROM categories
+			WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id, cp.path || c.id
+			FROM categories c
+			JOIN category_paths cp ON cp.id = c.parent_id
+		),
+		stock_costs AS (
+			SELECT sii.product_id,
+				SUM(sii.base_unit_cost * sii.quantity + sii.landed_cost) / NULLIF(SUM(sii.quantity), 0) AS unit_cost
+			FROM stock_in_items sii
+			JOIN stock_ins st ON st.id = sii.stock_in_id
+			WHERE st.status = 'completed' AND st.deleted_at IS NULL AND sii.deleted_at IS NULL
+			GROUP BY sii.product_id
+		),
+		lines AS (
+			SELECT %s AS group_key, %s AS group_label,
+				CASE WHEN s.sale_date >= $3 THEN '%s'
+					WHEN s.sale_date >= $4 THEN '%s'
+					ELSE '%s' END AS segment,
+				s.id AS sale_id, si.quantity,
+				si.base_subtotal - si.base_tax + si.base_discount AS revenue,
+				si.base_discount AS discount,
+				CASE WHEN si.cogs <> 0 THEN si.cogs
+					ELSE si.quantity * COALESCE(sc.unit_cost, 0) END AS cogs
+			FROM sale_items si
+			JOIN sales s ON s.id = si.sale_id
+			LEFT JOIN products p ON p.id = si.product_id
+			LEFT JOIN products pp ON pp.id = p.parent_id
+			LEFT JOIN customers cu ON cu.id = s.customer_id
+			LEFT JOIN stock_costs sc ON sc.product_id = si.product_id
+			LEFT JOIN category_paths cp ON cp.id = COALESCE(p.child_category_id, pp.child_category_id)
+			LEFT JOIN categories rc ON rc.id = cp.path[LEAST($5, array_length(cp.path, 1))]
+			WHERE %s
+		)
+		SELECT group_key, MAX(group_label), segment,
+			COUNT(DISTINCT sale_id), SUM(quantity), SUM(revenue), SUM(discount), SUM(cogs)
+		FROM lines
+		GROUP BY GROUPING SETS ((group_key, segment), (segment))`,
+		keyExpr, labelExpr,
+		profitSegmentCurrent, profitSegmentPrevious, profitSegmentBefore,
+		strings.Join(conditions, " AND "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profit report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.ProfitReport{
+		GroupBy:           filter.GroupBy,
+		Currency:          money.BaseCurrency,
+		StartDate:         filter.StartDate,
+		EndDate:           filter.EndDate,
+		PreviousStartDate: previousStart,
+		PreviousEndDate:   previousEnd,
+		Lines:             []models.ProfitReportLine{},
+	}
+	switch filter.GroupBy {
+	case models.ProfitGroupByPeriod:
+		report.Interval = filter.Interval
+	case models.ProfitGroupByCategory:
+		report.CategoryLevel = filter.CategoryLevel
+	}
+
+	total := models.NewProfitReportLine("", "")
+	lines := map[string]*models.ProfitReportLine{}
+	var order []string
+	// Whole-bucket figures used as the previous figures of period lines
+	buckets := map[string]models.ProfitMetrics{}
+
+	for rows.Next() {
+		var key, label *string
+		var segment string
+		m := models.NewProfitMetrics()
+		if err := rows.Scan(&key, &label, &segment, &m.Orders, &m.Quantity, &m.Revenue, &m.Discounts, &m.COGS); err != nil {
+			return nil, fmt.Errorf("failed to scan profit report: %w", err)
+		}
+
+		if key == nil {
+			switch segment {
+			case profitSegmentCurrent:
+				total.Current.Add(m)
+			case profitSegmentPrevious:
+				total.Previous.Add(m)
+			}
+			continue
+		}
+
+		if filter.GroupBy == models.ProfitGroupByPeriod {
+			bucket, ok := buckets[*key]
+			if !ok {
+				bucket = models.NewProfitMetrics()
+			}
+			bucket.Add(m)
+			buckets[*key] = bucket
+			if segment != profitSegmentCurrent {
+				continue
+			}
+		} else if segment == profitSegmentBefore {
+			continue
+		}
+
+		line, ok := lines[*key]
+		if !ok {
+			name := ""
+			if label != nil {
+				name = *label
+			}
+			line = models.NewProfitReportLine(*key, name)
+			lines[*key] = line
+			order = append(order, *key)
+		}
+		if segment == profitSegmentCurrent {
+			line.Current.Add(m)
+		} else {
+			line.Previous.Add(m)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating profit report: %w", err)
+	}
+
+	for _, key := range order {
+		line := lines[key]
+		if filter.GroupBy == models.ProfitGroupByPeriod {
+			if start, err := time.Parse("2006-01-02", key); err == nil {
+				if previous, ok := buckets[filter.Interval.Previous(start).Format("2006-01-02")]; ok {
+					line.Previous = previous
+				}
+			}
+		}
+		line.Compare()
+		report.Lines = append(report.Lines, *line)
+	}
+	total.Compare()
+	report.Total = *total
+	report.SortLines()
+
+	return report, nil
+}
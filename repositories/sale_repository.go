@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"inventory-go/models"
+	"inventory-go/money"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,9 +17,9 @@ type SaleRepository interface {
 	// Basic CRUD
 	GetByID(id string) (*models.Sale, error)
 	GetByReference(referenceNo string) (*models.Sale, error)
-	Create(sale *models.Sale) error
-	Update(sale *models.Sale) error
-	Delete(id string) error
+	Create(sale *models.Sale, lock *models.PeriodLock) error
+	Update(sale *models.Sale, lock *models.PeriodLock) error
+	Delete(id string, lock *models.PeriodLock) error
 
 	// Queries
 	List(offset, limit int, status string, customerID *string, startDate, endDate *time.Time) ([]models.Sale, int64, error)
@@ -34,21 +36,27 @@ func NewSaleRepository(db *pgx.Conn) SaleRepository {
 	return &SaleRepositoryImpl{db: db}
 }
 
+const saleColumns = `id, reference_no, status, sale_date, COALESCE(notes, ''), total, paid, balance, due_date,
+		tax_mode, currency, exchange_rate, base_total, customer_id, platform, created_at, updated_at`
+
+func scanSale(row pgx.Row, sale *models.Sale) error {
+	return row.Scan(
+		&sale.ID, &sale.ReferenceNo, &sale.Status, &sale.SaleDate, &sale.Note,
+		&sale.Total, &sale.Paid, &sale.Balance, &sale.DueDate,
+		&sale.TaxMode, &sale.Currency, &sale.ExchangeRate, &sale.BaseTotal,
+		&sale.CustomerID, &sale.Platform, &sale.CreatedAt, &sale.UpdatedAt,
+	)
+}
+
 func (r *SaleRepositoryImpl) GetByID(id string) (*models.Sale, error) {
 	ctx := context.Background()
 	var sale models.Sale
 
 	// Get sale details
-	saleQuery := `SELECT id, reference_no, status, sale_date, note, total, paid, balance, 
-		customer_id, platform, created_at, updated_at 
+	saleQuery := `SELECT ` + saleColumns + `
 		FROM sales WHERE id = $1 AND deleted_at IS NULL`
 
-	err := r.db.QueryRow(ctx, saleQuery, id).Scan(
-		&sale.ID, &sale.ReferenceNo, &sale.Status, &sale.SaleDate, &sale.Note,
-		&sale.Total, &sale.Paid, &sale.Balance, &sale.CustomerID, &sale.Platform,
-		&sale.CreatedAt, &sale.UpdatedAt,
-	)
-
+	err := scanSale(r.db.QueryRow(ctx, saleQuery, id), &sale)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -57,9 +65,12 @@ func (r *SaleRepositoryImpl) GetByID(id string) (*models.Sale, error) {
 	}
 
 	// Get sale items
-	itemsQuery := `SELECT id, product_id, product_name, quantity, unit_price, tax, discount, subtotal
+	itemsQuery := `SELECT id, product_id, product_name, quantity, unit_price, tax, discount, promotion_discount,
+		subtotal, base_subtotal, base_tax, base_discount, unit_cost, cogs, tax_code_id, COALESCE(tax_code, ''), tax_rate,
+		COALESCE(price_source, ''), price_list_id, COALESCE(price_list_code, ''), price_min_quantity,
+		COALESCE(unit, ''), COALESCE(unit_quantity, 0), unit_factor, COALESCE(base_unit, '')
 		FROM sale_items WHERE sale_id = $1 AND deleted_at IS NULL`
-		
+
 	rows, err := r.db.Query(ctx, itemsQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sale items: %w", err)
@@ -70,18 +81,30 @@ func (r *SaleRepositoryImpl) GetByID(id string) (*models.Sale, error) {
 	for rows.Next() {
 		var item models.SaleItem
 		item.SaleID = id
-		
+
 		err := rows.Scan(
 			&item.ID, &item.ProductID, &item.ProductName, &item.Quantity,
-			&item.UnitPrice, &item.Tax, &item.Discount, &item.Subtotal,
+			&item.UnitPrice, &item.Tax, &item.Discount, &item.PromotionDiscount, &item.Subtotal, &item.BaseSubtotal,
+			&item.BaseTax, &item.BaseDiscount, &item.UnitCost, &item.COGS, &item.TaxCodeID, &item.TaxCode, &item.TaxRate,
+			&item.PriceSource, &item.PriceListID, &item.PriceListCode, &item.PriceMinQuantity,
+			&item.Unit, &item.UnitQuantity, &item.UnitFactor, &item.BaseUnit,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan sale item: %w", err)
 		}
 		items = append(items, item)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sale items: %w", err)
+	}
 	sale.Items = items
 
+	if sale.Promotions, err = getSalePromotions(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+	sale.BindCurrency()
+	sale.SummarizeTax()
+
 	return &sale, nil
 }
 
@@ -89,7 +112,7 @@ func (r *SaleRepositoryImpl) GetByReference(referenceNo string) (*models.Sale, e
 	// First get the ID of the sale by reference
 	var id string
 	query := `SELECT id FROM sales WHERE reference_no = $1 AND deleted_at IS NULL`
-	
+
 	err := r.db.QueryRow(context.Background(), query, referenceNo).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -97,12 +120,12 @@ func (r *SaleRepositoryImpl) GetByReference(referenceNo string) (*models.Sale, e
 		}
 		return nil, fmt.Errorf("failed to get sale by reference: %w", err)
 	}
-	
+
 	// Then use GetByID to get the full sale
 	return r.GetByID(id)
 }
 
-func (r *SaleRepositoryImpl) Create(sale *models.Sale) error {
+func (r *SaleRepositoryImpl) Create(sale *models.Sale, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -111,26 +134,48 @@ func (r *SaleRepositoryImpl) Create(sale *models.Sale) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	// Generate ID if not set
 	if sale.ID == "" {
 		sale.ID = uuid.NewString()
 	}
-	
+	sale.BindCurrency()
+
 	// Insert sale
 	saleQuery := `INSERT INTO sales (
-		id, reference_no, status, sale_date, note, total, paid, balance,
+		id, reference_no, status, sale_date, notes, total, paid, balance, due_date,
+		tax_mode, currency, exchange_rate, base_total,
 		customer_id, platform, created_at, updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-	
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
+
 	_, err = tx.Exec(ctx, saleQuery,
 		sale.ID, sale.ReferenceNo, sale.Status, sale.SaleDate, sale.Note,
-		sale.Total, sale.Paid, sale.Balance, sale.CustomerID, sale.Platform,
-		time.Now(), time.Now(),
+		sale.Total, sale.Paid, sale.Balance, sale.DueDate,
+		sale.TaxMode, sale.Currency, sale.ExchangeRate, sale.BaseTotal,
+		sale.CustomerID, sale.Platform, time.Now(), time.Now(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert sale: %w", err)
 	}
 
+	// Insert sale items and the promotions applied to them
+	if err = insertSaleItems(ctx, tx, sale); err != nil {
+		return err
+	}
+	if err = insertSalePromotions(ctx, tx, sale); err != nil {
+		return err
+	}
+
+	// Assign cost of goods sold to completed sales
+	if sale.Status == models.SaleStatusCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceSale, sale.ID, false, true); err != nil {
+			return err
+		}
+	}
+
 	// Update customer stats if customer exists
 	if sale.CustomerID != nil {
 		updateCustomerQuery := `UPDATE customers SET 
@@ -139,24 +184,24 @@ func (r *SaleRepositoryImpl) Create(sale *models.Sale) error {
 			last_order_at = $2,
 			updated_at = $3
 			WHERE id = $4`
-		
+
 		_, err = tx.Exec(ctx, updateCustomerQuery,
-			sale.Total, time.Now(), time.Now(), *sale.CustomerID,
+			sale.BaseTotal, time.Now(), time.Now(), *sale.CustomerID,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to update customer stats: %w", err)
 		}
 	}
-	
+
 	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	
+
 	return nil
 }
 
-func (r *SaleRepositoryImpl) Update(sale *models.Sale) error {
+func (r *SaleRepositoryImpl) Update(sale *models.Sale, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -165,64 +210,367 @@ func (r *SaleRepositoryImpl) Update(sale *models.Sale) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	sale.UpdatedAt = time.Now()
-	
+	sale.BindCurrency()
+
+	var previousStatus models.SaleStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM sales WHERE id = $1 FOR UPDATE`, sale.ID).Scan(&previousStatus)
+	if err != nil {
+		return fmt.Errorf("failed to get sale status: %w", err)
+	}
+
 	// Update the basic sale information
 	updateQuery := `UPDATE sales SET 
-		reference_no = $1, status = $2, sale_date = $3, note = $4, 
+		reference_no = $1, status = $2, sale_date = $3, notes = $4,
 		total = $5, paid = $6, balance = $7, customer_id = $8, 
-		platform = $9, updated_at = $10
-		WHERE id = $11`
-	
+		platform = $9, currency = $10, exchange_rate = $11, base_total = $12,
+		due_date = $13, tax_mode = $14, updated_at = $15
+		WHERE id = $16`
+
 	_, err = tx.Exec(ctx, updateQuery,
 		sale.ReferenceNo, sale.Status, sale.SaleDate, sale.Note,
 		sale.Total, sale.Paid, sale.Balance, sale.CustomerID,
-		sale.Platform, sale.UpdatedAt, sale.ID,
+		sale.Platform, sale.Currency, sale.ExchangeRate, sale.BaseTotal,
+		sale.DueDate, sale.TaxMode, sale.UpdatedAt, sale.ID,
 	)
-	
+
 	if err != nil {
 		return fmt.Errorf("failed to update sale: %w", err)
 	}
-	
+
+	// Update the items that were kept, add the new ones and remove the rest
+	if err = saveSaleItems(ctx, tx, sale); err != nil {
+		return err
+	}
+
+	// Replace the promotions applied, which were worked out again with the items
+	if _, err = tx.Exec(ctx, `DELETE FROM sale_promotions WHERE sale_id = $1`, sale.ID); err != nil {
+		return fmt.Errorf("failed to remove old sale promotions: %w", err)
+	}
+	if err = insertSalePromotions(ctx, tx, sale); err != nil {
+		return err
+	}
+
+	// Items may have changed, so re-cost the sale if it is or was completed
+	wasCompleted := previousStatus == models.SaleStatusCompleted
+	isCompleted := sale.Status == models.SaleStatusCompleted
+	if wasCompleted && !isCompleted {
+		if err = checkSaleAllocations(ctx, tx, sale.ID); err != nil {
+			return err
+		}
+	}
+	if wasCompleted || isCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceSale, sale.ID, wasCompleted, isCompleted); err != nil {
+			return err
+		}
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	
+
+	return nil
+}
+
+// saleItemColumns are the sale_items columns written from a models.SaleItem, in the order
+// of saleItemValues
+const saleItemColumns = `product_id, product_name, quantity, unit_price,
+	tax, discount, promotion_discount, subtotal, base_subtotal, base_tax, base_discount,
+	tax_code_id, tax_code, tax_rate, price_source, price_list_id, price_list_code, price_min_quantity,
+	unit, unit_quantity, unit_factor, base_unit`
+
+func saleItemValues(item *models.SaleItem) []interface{} {
+	return []interface{}{
+		item.ProductID, item.ProductName, item.Quantity, item.UnitPrice,
+		item.Tax, item.Discount, item.PromotionDiscount, item.Subtotal, item.BaseSubtotal,
+		item.BaseTax, item.BaseDiscount, item.TaxCodeID, item.TaxCode, item.TaxRate,
+		item.PriceSource, item.PriceListID, item.PriceListCode, item.PriceMinQuantity,
+		item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit,
+	}
+}
+
+// insertSaleItems inserts every item of the sale inside the given transaction
+func insertSaleItems(ctx context.Context, tx pgx.Tx, sale *models.Sale) error {
+	now := time.Now()
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		item.SaleID = sale.ID
+		if err := insertSaleItem(ctx, tx, item, now); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (r *SaleRepositoryImpl) Delete(id string) error {
+// insertSaleItem inserts an item under a new ID
+func insertSaleItem(ctx context.Context, tx pgx.Tx, item *models.SaleItem, now time.Time) error {
+	item.ID = uuid.NewString()
+	item.CreatedAt = now
+	item.UpdatedAt = now
+
+	args := append([]interface{}{item.ID, item.SaleID}, saleItemValues(item)...)
+	_, err := tx.Exec(ctx, `INSERT INTO sale_items (id, sale_id, `+saleItemColumns+`, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+		$21, $22, $23, $24, $25, $26)`, append(args, now, now)...)
+	if err != nil {
+		return fmt.Errorf("failed to insert sale item: %w", err)
+	}
+	return nil
+}
+
+// saveSaleItems brings the stored items of a sale in line with sale.Items inside the given
+// transaction. Items sent with the ID of one of the sale's items are updated in place, so the
+// cost movements recorded against the line keep their item; items without one are inserted
+// and the stored items that were not sent are soft deleted.
+func saveSaleItems(ctx context.Context, tx pgx.Tx, sale *models.Sale) error {
+	rows, err := tx.Query(ctx,
+		`SELECT id FROM sale_items WHERE sale_id = $1 AND deleted_at IS NULL FOR UPDATE`, sale.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get sale items: %w", err)
+	}
+	stored := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan sale item: %w", err)
+		}
+		stored[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating sale items: %w", err)
+	}
+
+	now := time.Now()
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		item.SaleID = sale.ID
+		if !stored[item.ID] {
+			if err := insertSaleItem(ctx, tx, item, now); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Forget the ID, so a second item sent with it is added as a new line
+		delete(stored, item.ID)
+		item.UpdatedAt = now
+		args := append(saleItemValues(item), now, item.ID)
+		_, err := tx.Exec(ctx, `UPDATE sale_items SET (`+saleItemColumns+`, updated_at)
+			= ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+			$21, $22, $23)
+			WHERE id = $24`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update sale item: %w", err)
+		}
+	}
+
+	if len(stored) == 0 {
+		return nil
+	}
+	removed := make([]string, 0, len(stored))
+	for id := range stored {
+		removed = append(removed, id)
+	}
+	_, err = tx.Exec(ctx, `UPDATE sale_items SET deleted_at = $1 WHERE id = ANY($2)`, now, removed)
+	if err != nil {
+		return fmt.Errorf("failed to remove sale items: %w", err)
+	}
+	return nil
+}
+
+func (r *SaleRepositoryImpl) Delete(id string, lock *models.PeriodLock) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	var status models.SaleStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM sales WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get sale status: %w", err)
+	}
+
+	// Return the stock of a completed sale at the cost it was issued
+	if status == models.SaleStatusCompleted {
+		if err = checkSaleAllocations(ctx, tx, id); err != nil {
+			return err
+		}
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceSale, id, true, false); err != nil {
+			return err
+		}
+	}
+
 	// Soft delete the sale
 	query := `UPDATE sales SET deleted_at = $1 WHERE id = $2`
-	_, err := r.db.Exec(context.Background(), query, time.Now(), id)
+	_, err = tx.Exec(ctx, query, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete sale: %w", err)
 	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
 func (r *SaleRepositoryImpl) List(offset, limit int, status string, customerID *string, startDate, endDate *time.Time) ([]models.Sale, int64, error) {
-	var sales []models.Sale
-	var total int64 = 0
-	// Basic implementation to allow compilation
+	ctx := context.Background()
+
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+	argIndex := 1
+
+	if status != "" {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argIndex))
+		args = append(args, status)
+		argIndex++
+	}
+
+	if customerID != nil && *customerID != "" {
+		conditions = append(conditions, fmt.Sprintf("customer_id = $%d", argIndex))
+		args = append(args, *customerID)
+		argIndex++
+	}
+
+	if startDate != nil {
+		conditions = append(conditions, fmt.Sprintf("sale_date >= $%d", argIndex))
+		args = append(args, *startDate)
+		argIndex++
+	}
+
+	if endDate != nil {
+		conditions = append(conditions, fmt.Sprintf("sale_date <= $%d", argIndex))
+		args = append(args, *endDate)
+		argIndex++
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM sales WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count sales: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM sales WHERE %s
+		ORDER BY sale_date DESC LIMIT $%d OFFSET $%d`, saleColumns, where, argIndex, argIndex+1)
+	args = append(args, limit, offset)
+
+	sales, err := r.querySales(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	return sales, total, nil
 }
 
 func (r *SaleRepositoryImpl) GetSalesByCustomer(customerID string) ([]models.Sale, error) {
-	var sales []models.Sale
-	// Basic implementation to allow compilation
+	query := `SELECT ` + saleColumns + ` FROM sales
+		WHERE customer_id = $1 AND deleted_at IS NULL
+		ORDER BY sale_date DESC`
+
+	return r.querySales(context.Background(), query, customerID)
+}
+
+// querySales runs a query selecting saleColumns and returns the sales without their items
+func (r *SaleRepositoryImpl) querySales(ctx context.Context, query string, args ...interface{}) ([]models.Sale, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sales: %w", err)
+	}
+	defer rows.Close()
+
+	sales := []models.Sale{}
+	for rows.Next() {
+		var sale models.Sale
+		if err := scanSale(rows, &sale); err != nil {
+			return nil, fmt.Errorf("failed to scan sale: %w", err)
+		}
+		sale.BindCurrency()
+		sales = append(sales, sale)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sales: %w", err)
+	}
+
 	return sales, nil
 }
 
+// GetSalesSummary aggregates sales in the base currency
 func (r *SaleRepositoryImpl) GetSalesSummary(startDate, endDate time.Time) (*models.SalesSummary, error) {
-	var summary models.SalesSummary
-	// Basic implementation to allow compilation
+	query := `SELECT 
+		COUNT(*) as total_orders,
+		COALESCE(SUM(base_total), 0) as total_sales,
+		COUNT(*) FILTER (WHERE status = 'completed') as completed_orders,
+		COALESCE(SUM(base_total) FILTER (WHERE status = 'completed'), 0) as completed_sales
+		FROM sales
+		WHERE sale_date BETWEEN $1 AND $2 AND deleted_at IS NULL`
+
+	summary := models.SalesSummary{
+		TotalSales:     money.Zero(money.BaseCurrency),
+		CompletedSales: money.Zero(money.BaseCurrency),
+	}
+	err := r.db.QueryRow(context.Background(), query, startDate, endDate).Scan(
+		&summary.TotalOrders, &summary.TotalSales,
+		&summary.CompletedOrders, &summary.CompletedSales,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales summary: %w", err)
+	}
+
+	summary.AverageOrderValue = money.Zero(money.BaseCurrency)
+	if summary.TotalOrders > 0 {
+		summary.AverageOrderValue = summary.TotalSales.Div(int64(summary.TotalOrders)).Round()
+	}
+
 	return &summary, nil
 }
 
+// GetDailySales aggregates sales per day in the base currency
 func (r *SaleRepositoryImpl) GetDailySales(startDate, endDate time.Time) ([]models.DailySales, error) {
-	var results []models.DailySales
-	// Basic implementation to allow compilation
+	query := `SELECT 
+		TO_CHAR(DATE(sale_date), 'YYYY-MM-DD') as date,
+		COUNT(*) as order_count,
+		COALESCE(SUM(base_total), 0) as total_sales
+		FROM sales
+		WHERE sale_date BETWEEN $1 AND $2 AND deleted_at IS NULL
+		GROUP BY DATE(sale_date)
+		ORDER BY DATE(sale_date)`
+
+	rows, err := r.db.Query(context.Background(), query, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily sales: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.DailySales{}
+	for rows.Next() {
+		daily := models.DailySales{TotalSales: money.Zero(money.BaseCurrency)}
+		if err := rows.Scan(&daily.Date, &daily.OrderCount, &daily.TotalSales); err != nil {
+			return nil, fmt.Errorf("failed to scan daily sales: %w", err)
+		}
+		results = append(results, daily)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily sales: %w", err)
+	}
+
 	return results, nil
 }
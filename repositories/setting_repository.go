@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SettingRepository defines methods for company settings
+type SettingRepository interface {
+	GetAll() ([]models.Setting, error)
+	Get(key string) (string, error)
+	Set(setting *models.Setting) error
+}
+
+// SettingRepositoryImpl implements the SettingRepository interface
+type SettingRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewSettingRepository creates a new SettingRepository
+func NewSettingRepository(db *pgx.Conn) SettingRepository {
+	return &SettingRepositoryImpl{db: db}
+}
+
+// GetAll returns every known setting, using defaults for those never saved
+func (r *SettingRepositoryImpl) GetAll() ([]models.Setting, error) {
+	settings := map[string]models.Setting{}
+	for _, s := range models.DefaultSettings() {
+		settings[s.Key] = s
+	}
+
+	rows, err := r.db.Query(context.Background(), `SELECT key, value, updated_at FROM settings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Setting
+		if err := rows.Scan(&s.Key, &s.Value, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		settings[s.Key] = s
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating settings: %w", err)
+	}
+
+	result := make([]models.Setting, 0, len(settings))
+	for _, s := range settings {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	return result, nil
+}
+
+// Get returns the value of a setting, or its default when it has never been saved
+func (r *SettingRepositoryImpl) Get(key string) (string, error) {
+	return getSetting(context.Background(), r.db, key)
+}
+
+// Set saves a setting value
+func (r *SettingRepositoryImpl) Set(setting *models.Setting) error {
+	setting.UpdatedAt = time.Now()
+
+	query := `INSERT INTO settings (key, value, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`
+
+	_, err := r.db.Exec(context.Background(), query, setting.Key, setting.Value, setting.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save setting: %w", err)
+	}
+	return nil
+}
+
+// queryRower is implemented by both *pgx.Conn and pgx.Tx
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// getSetting reads a setting through a connection or transaction
+func getSetting(ctx context.Context, q queryRower, key string) (string, error) {
+	var value string
+	err := q.QueryRow(ctx, `SELECT value FROM settings WHERE key = $1`, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DefaultSettingValue(key), nil
+		}
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, nil
+}
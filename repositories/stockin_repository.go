@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"inventory-go/models"
+	"inventory-go/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,14 +16,14 @@ type StockInRepository interface {
 	// Basic CRUD
 	GetByID(id string) (*models.StockIn, error)
 	GetByReference(referenceNo string) (*models.StockIn, error)
-	Create(stockIn *models.StockIn) error
-	Update(stockIn *models.StockIn) error
-	Delete(id string) error
+	Create(stockIn *models.StockIn, lock *models.PeriodLock) error
+	Update(stockIn *models.StockIn, lock *models.PeriodLock) error
+	Delete(id string, lock *models.PeriodLock) error
 
 	// Items
-	AddStockInItem(item *models.StockInItem) error
-	UpdateStockInItem(item *models.StockInItem) error
-	DeleteStockInItem(id string) error
+	AddStockInItem(item *models.StockInItem, lock *models.PeriodLock) error
+	UpdateStockInItem(item *models.StockInItem, lock *models.PeriodLock) error
+	DeleteStockInItem(id string, lock *models.PeriodLock) error
 	GetStockInItems(stockInID string) ([]models.StockInItem, error)
 
 	// Queries
@@ -45,14 +46,15 @@ func (r *StockInRepositoryImpl) GetByID(id string) (*models.StockIn, error) {
 	var stockIn models.StockIn
 
 	// Get stockIn details
-	stockInQuery := `SELECT id, reference_no, status, order_date, note, total, paid, balance, 
-		supplier_id, created_at, updated_at 
+	stockInQuery := `SELECT id, reference_no, status, order_date, note, total, paid, balance, credited, due_date,
+		tax_mode, currency, exchange_rate, base_total, supplier_id, opening, created_at, updated_at 
 		FROM stock_ins WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.QueryRow(ctx, stockInQuery, id).Scan(
 		&stockIn.ID, &stockIn.ReferenceNo, &stockIn.Status, &stockIn.OrderDate, &stockIn.Note,
-		&stockIn.Total, &stockIn.Paid, &stockIn.Balance, &stockIn.SupplierID,
-		&stockIn.CreatedAt, &stockIn.UpdatedAt,
+		&stockIn.Total, &stockIn.Paid, &stockIn.Balance, &stockIn.Credited, &stockIn.DueDate,
+		&stockIn.TaxMode, &stockIn.Currency, &stockIn.ExchangeRate, &stockIn.BaseTotal, &stockIn.SupplierID,
+		&stockIn.Opening, &stockIn.CreatedAt, &stockIn.UpdatedAt,
 	)
 
 	if err != nil {
@@ -69,6 +71,28 @@ func (r *StockInRepositoryImpl) GetByID(id string) (*models.StockIn, error) {
 	}
 	stockIn.Items = items
 
+	// Get the landed cost breakdown
+	landedCosts, err := NewLandedCostRepository(r.db).GetStockInAllocations(id)
+	if err != nil {
+		return nil, err
+	}
+	stockIn.LandedCosts = landedCosts
+	stockIn.LandedCostTotal = money.Zero(money.BaseCurrency)
+	for _, a := range landedCosts {
+		stockIn.LandedCostTotal = stockIn.LandedCostTotal.Add(a.Amount)
+	}
+
+	// Get the payments and supplier credits recorded against it
+	payableRepo := NewPayableRepository(r.db)
+	if stockIn.Payments, err = payableRepo.GetStockInPayments(id); err != nil {
+		return nil, err
+	}
+	if stockIn.Credits, err = payableRepo.ListCredits("", id); err != nil {
+		return nil, err
+	}
+	stockIn.BindCurrency()
+	stockIn.SummarizeTax()
+
 	// Get supplier if exists
 	if stockIn.SupplierID != nil {
 		supplierRepo := NewSupplierRepository(r.db)
@@ -99,7 +123,7 @@ func (r *StockInRepositoryImpl) GetByReference(referenceNo string) (*models.Stoc
 	return r.GetByID(id)
 }
 
-func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn) error {
+func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -108,21 +132,27 @@ func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	// Generate ID if not set
 	if stockIn.ID == "" {
 		stockIn.ID = uuid.NewString()
 	}
+	stockIn.BindCurrency()
 
 	// Insert stockIn
 	stockInQuery := `INSERT INTO stock_ins (
-		id, reference_no, status, order_date, note, total, paid, balance,
-		supplier_id, created_at, updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		id, reference_no, status, order_date, note, total, paid, balance, due_date,
+		tax_mode, currency, exchange_rate, base_total, supplier_id, opening, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
 
 	_, err = tx.Exec(ctx, stockInQuery,
 		stockIn.ID, stockIn.ReferenceNo, stockIn.Status, stockIn.OrderDate, stockIn.Note,
-		stockIn.Total, stockIn.Paid, stockIn.Balance, stockIn.SupplierID,
-		time.Now(), time.Now(),
+		stockIn.Total, stockIn.Paid, stockIn.Balance, stockIn.DueDate, stockIn.TaxMode,
+		stockIn.Currency, stockIn.ExchangeRate, stockIn.BaseTotal, stockIn.SupplierID,
+		stockIn.Opening, time.Now(), time.Now(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert stock-in: %w", err)
@@ -139,12 +169,16 @@ func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn) error {
 
 		itemQuery := `INSERT INTO stock_in_items (
 			id, stock_in_id, product_id, product_name, quantity, 
-			unit_cost, tax, discount, subtotal, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+			unit_cost, tax, discount, subtotal, base_unit_cost, base_subtotal,
+			tax_code_id, tax_code, tax_rate, unit, unit_quantity, unit_factor, base_unit, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
 
 		_, err = tx.Exec(ctx, itemQuery,
 			item.ID, item.StockInID, item.ProductID, item.ProductName, item.Quantity,
-			item.UnitCost, item.Tax, item.Discount, item.Subtotal, time.Now(), time.Now(),
+			item.UnitCost, item.Tax, item.Discount, item.Subtotal,
+			item.BaseUnitCost, item.BaseSubtotal, item.TaxCodeID, item.TaxCode, item.TaxRate,
+			item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit,
+			time.Now(), time.Now(),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert stock-in item: %w", err)
@@ -158,6 +192,16 @@ func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn) error {
 		}
 	}
 
+	// Create cost layers for stock received as completed and reprice by the new unit costs
+	if stockIn.Status == models.StockInStatusCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceStockIn, stockIn.ID, false, true); err != nil {
+			return err
+		}
+		if err = suggestCostPlusPrices(ctx, tx, stockIn.ID); err != nil {
+			return err
+		}
+	}
+
 	// Update supplier stats if supplier exists
 	if stockIn.SupplierID != nil {
 		updateSupplierQuery := `UPDATE suppliers SET 
@@ -168,7 +212,7 @@ func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn) error {
 			WHERE id = $4`
 
 		_, err = tx.Exec(ctx, updateSupplierQuery,
-			stockIn.Total, time.Now(), time.Now(), *stockIn.SupplierID,
+			stockIn.BaseTotal, time.Now(), time.Now(), *stockIn.SupplierID,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to update supplier stats: %w", err)
@@ -183,7 +227,7 @@ func (r *StockInRepositoryImpl) Create(stockIn *models.StockIn) error {
 	return nil
 }
 
-func (r *StockInRepositoryImpl) Update(stockIn *models.StockIn) error {
+func (r *StockInRepositoryImpl) Update(stockIn *models.StockIn, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -192,25 +236,64 @@ func (r *StockInRepositoryImpl) Update(stockIn *models.StockIn) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	stockIn.UpdatedAt = time.Now()
+	stockIn.BindCurrency()
+
+	previousStatus, err := lockStockInStatus(ctx, tx, stockIn.ID)
+	if err != nil {
+		return err
+	}
 
 	// Update the basic stockIn information
 	updateQuery := `UPDATE stock_ins SET 
 		reference_no = $1, status = $2, order_date = $3, note = $4, 
 		total = $5, paid = $6, balance = $7, supplier_id = $8, 
-		updated_at = $9
-		WHERE id = $10`
+		currency = $9, exchange_rate = $10, base_total = $11,
+		due_date = $12, tax_mode = $13, updated_at = $14
+		WHERE id = $15`
 
 	_, err = tx.Exec(ctx, updateQuery,
 		stockIn.ReferenceNo, stockIn.Status, stockIn.OrderDate, stockIn.Note,
 		stockIn.Total, stockIn.Paid, stockIn.Balance, stockIn.SupplierID,
-		stockIn.UpdatedAt, stockIn.ID,
+		stockIn.Currency, stockIn.ExchangeRate, stockIn.BaseTotal,
+		stockIn.DueDate, stockIn.TaxMode, stockIn.UpdatedAt, stockIn.ID,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to update stock-in: %w", err)
 	}
 
+	// Receive or reverse cost layers when the stock-in is completed or un-completed
+	wasCompleted := previousStatus == models.StockInStatusCompleted
+	isCompleted := stockIn.Status == models.StockInStatusCompleted
+	if wasCompleted && !isCompleted {
+		if err = checkStockInSettlements(ctx, tx, stockIn.ID); err != nil {
+			return err
+		}
+	}
+	if wasCompleted != isCompleted {
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceStockIn, stockIn.ID, wasCompleted, isCompleted); err != nil {
+			return err
+		}
+		if isCompleted {
+			err = suggestCostPlusPrices(ctx, tx, stockIn.ID)
+		} else {
+			err = withdrawPriceSuggestions(ctx, tx, stockIn.ID)
+		}
+		if err != nil {
+			return err
+		}
+	} else if isCompleted {
+		// Totals or payments may have changed on a stock-in that stays completed
+		if err = syncDocumentJournal(ctx, tx, models.CostSourceStockIn, stockIn.ID, true); err != nil {
+			return err
+		}
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -219,7 +302,7 @@ func (r *StockInRepositoryImpl) Update(stockIn *models.StockIn) error {
 	return nil
 }
 
-func (r *StockInRepositoryImpl) Delete(id string) error {
+func (r *StockInRepositoryImpl) Delete(id string, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -228,6 +311,27 @@ func (r *StockInRepositoryImpl) Delete(id string) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
+	// Remove the cost layers of a completed stock-in
+	status, err := lockStockInStatus(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if status == models.StockInStatusCompleted {
+		if err = checkStockInSettlements(ctx, tx, id); err != nil {
+			return err
+		}
+		if err = syncDocumentPostings(ctx, tx, models.CostSourceStockIn, id, true, false); err != nil {
+			return err
+		}
+		if err = withdrawPriceSuggestions(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
 	// Get all items to revert stock
 	rows, err := tx.Query(ctx,
 		`SELECT product_id, quantity FROM stock_in_items WHERE stock_in_id = $1`, id)
@@ -267,6 +371,11 @@ func (r *StockInRepositoryImpl) Delete(id string) error {
 		return fmt.Errorf("failed to delete stock-in: %w", err)
 	}
 
+	// Spread its landed costs over the other stock-ins sharing them
+	if err = detachStockInLandedCosts(ctx, tx, id); err != nil {
+		return err
+	}
+
 	// Commit transaction
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -275,7 +384,7 @@ func (r *StockInRepositoryImpl) Delete(id string) error {
 	return nil
 }
 
-func (r *StockInRepositoryImpl) AddStockInItem(item *models.StockInItem) error {
+func (r *StockInRepositoryImpl) AddStockInItem(item *models.StockInItem, lock *models.PeriodLock) error {
 	if item.ID == "" {
 		item.ID = uuid.NewString()
 	}
@@ -292,15 +401,23 @@ func (r *StockInRepositoryImpl) AddStockInItem(item *models.StockInItem) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	// Insert the item
 	query := `INSERT INTO stock_in_items (
 		id, stock_in_id, product_id, product_name, quantity, 
-		unit_cost, tax, discount, subtotal, created_at, updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		unit_cost, tax, discount, subtotal, base_unit_cost, base_subtotal,
+		tax_code_id, tax_code, tax_rate, unit, unit_quantity, unit_factor, base_unit, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
 
 	_, err = tx.Exec(ctx, query,
 		item.ID, item.StockInID, item.ProductID, item.ProductName, item.Quantity,
-		item.UnitCost, item.Tax, item.Discount, item.Subtotal, item.CreatedAt, item.UpdatedAt,
+		item.UnitCost, item.Tax, item.Discount, item.Subtotal,
+		item.BaseUnitCost, item.BaseSubtotal, item.TaxCodeID, item.TaxCode, item.TaxRate,
+		item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit,
+		item.CreatedAt, item.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert stock-in item: %w", err)
@@ -314,15 +431,13 @@ func (r *StockInRepositoryImpl) AddStockInItem(item *models.StockInItem) error {
 	}
 
 	// Update stock-in total
-	_, err = tx.Exec(ctx, `
-		UPDATE stock_ins SET 
-		total = (SELECT SUM(subtotal) FROM stock_in_items WHERE stock_in_id = $1 AND deleted_at IS NULL),
-		updated_at = $2
-		WHERE id = $1`,
-		item.StockInID, now,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update stock-in total: %w", err)
+	if err = refreshStockInTotals(ctx, tx, item.StockInID, now); err != nil {
+		return err
+	}
+
+	// Reallocate landed costs and re-cost a completed stock-in after its items change
+	if err = reallocateStockInLandedCosts(ctx, tx, item.StockInID); err != nil {
+		return err
 	}
 
 	// Commit transaction
@@ -333,7 +448,7 @@ func (r *StockInRepositoryImpl) AddStockInItem(item *models.StockInItem) error {
 	return nil
 }
 
-func (r *StockInRepositoryImpl) UpdateStockInItem(item *models.StockInItem) error {
+func (r *StockInRepositoryImpl) UpdateStockInItem(item *models.StockInItem, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -342,6 +457,10 @@ func (r *StockInRepositoryImpl) UpdateStockInItem(item *models.StockInItem) erro
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	// Get original quantity
 	var originalQuantity int
 	err = tx.QueryRow(ctx,
@@ -357,12 +476,16 @@ func (r *StockInRepositoryImpl) UpdateStockInItem(item *models.StockInItem) erro
 	item.UpdatedAt = time.Now()
 	query := `UPDATE stock_in_items SET 
 		product_id = $1, product_name = $2, quantity = $3, 
-		unit_cost = $4, tax = $5, discount = $6, subtotal = $7, updated_at = $8
-		WHERE id = $9`
+		unit_cost = $4, tax = $5, discount = $6, subtotal = $7,
+		base_unit_cost = $8, base_subtotal = $9, tax_code_id = $10, tax_code = $11,
+		tax_rate = $12, unit = $13, unit_quantity = $14, unit_factor = $15, base_unit = $16, updated_at = $17
+		WHERE id = $18`
 
 	_, err = tx.Exec(ctx, query,
 		item.ProductID, item.ProductName, item.Quantity,
-		item.UnitCost, item.Tax, item.Discount, item.Subtotal, item.UpdatedAt, item.ID,
+		item.UnitCost, item.Tax, item.Discount, item.Subtotal,
+		item.BaseUnitCost, item.BaseSubtotal, item.TaxCodeID, item.TaxCode,
+		item.TaxRate, item.Unit, item.UnitQuantity, item.UnitFactor, item.BaseUnit, item.UpdatedAt, item.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update stock-in item: %w", err)
@@ -378,15 +501,13 @@ func (r *StockInRepositoryImpl) UpdateStockInItem(item *models.StockInItem) erro
 	}
 
 	// Update stock-in total
-	_, err = tx.Exec(ctx, `
-		UPDATE stock_ins SET 
-		total = (SELECT SUM(subtotal) FROM stock_in_items WHERE stock_in_id = $1 AND deleted_at IS NULL),
-		updated_at = $2
-		WHERE id = $1`,
-		item.StockInID, item.UpdatedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update stock-in total: %w", err)
+	if err = refreshStockInTotals(ctx, tx, item.StockInID, item.UpdatedAt); err != nil {
+		return err
+	}
+
+	// Reallocate landed costs and re-cost a completed stock-in after its items change
+	if err = reallocateStockInLandedCosts(ctx, tx, item.StockInID); err != nil {
+		return err
 	}
 
 	// Commit transaction
@@ -397,7 +518,7 @@ func (r *StockInRepositoryImpl) UpdateStockInItem(item *models.StockInItem) erro
 	return nil
 }
 
-func (r *StockInRepositoryImpl) DeleteStockInItem(id string) error {
+func (r *StockInRepositoryImpl) DeleteStockInItem(id string, lock *models.PeriodLock) error {
 	// Begin transaction
 	ctx := context.Background()
 	tx, err := r.db.Begin(ctx)
@@ -406,6 +527,10 @@ func (r *StockInRepositoryImpl) DeleteStockInItem(id string) error {
 	}
 	defer tx.Rollback(ctx)
 
+	if err = lockPeriods(ctx, tx, lock); err != nil {
+		return err
+	}
+
 	// Get item details before deleting
 	var item models.StockInItem
 	var stockInID string
@@ -432,15 +557,13 @@ func (r *StockInRepositoryImpl) DeleteStockInItem(id string) error {
 	}
 
 	// Update stock-in total
-	_, err = tx.Exec(ctx, `
-		UPDATE stock_ins SET 
-		total = (SELECT COALESCE(SUM(subtotal), 0) FROM stock_in_items WHERE stock_in_id = $1 AND deleted_at IS NULL),
-		updated_at = $2
-		WHERE id = $1`,
-		stockInID, now,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update stock-in total: %w", err)
+	if err = refreshStockInTotals(ctx, tx, stockInID, now); err != nil {
+		return err
+	}
+
+	// Reallocate landed costs and re-cost a completed stock-in after its items change
+	if err = reallocateStockInLandedCosts(ctx, tx, stockInID); err != nil {
+		return err
 	}
 
 	// Commit transaction
@@ -453,7 +576,9 @@ func (r *StockInRepositoryImpl) DeleteStockInItem(id string) error {
 
 func (r *StockInRepositoryImpl) GetStockInItems(stockInID string) ([]models.StockInItem, error) {
 	query := `SELECT id, stock_in_id, product_id, product_name, quantity, 
-		unit_cost, tax, discount, subtotal, created_at, updated_at
+		unit_cost, tax, discount, subtotal, base_unit_cost, base_subtotal, landed_cost,
+		tax_code_id, COALESCE(tax_code, ''), tax_rate,
+		COALESCE(unit, ''), COALESCE(unit_quantity, 0), unit_factor, COALESCE(base_unit, ''), created_at, updated_at
 		FROM stock_in_items 
 		WHERE stock_in_id = $1 AND deleted_at IS NULL`
 
@@ -470,7 +595,9 @@ func (r *StockInRepositoryImpl) GetStockInItems(stockInID string) ([]models.Stoc
 		err := rows.Scan(
 			&item.ID, &item.StockInID, &item.ProductID, &item.ProductName, &item.Quantity,
 			&item.UnitCost, &item.Tax, &item.Discount, &item.Subtotal,
-			&item.CreatedAt, &item.UpdatedAt,
+			&item.BaseUnitCost, &item.BaseSubtotal, &item.LandedCost,
+			&item.TaxCodeID, &item.TaxCode, &item.TaxRate,
+			&item.Unit, &item.UnitQuantity, &item.UnitFactor, &item.BaseUnit, &item.CreatedAt, &item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stock-in item: %w", err)
@@ -532,8 +659,8 @@ func (r *StockInRepositoryImpl) List(offset, limit int, status string, supplierI
 
 	// Get paginated results
 	query := fmt.Sprintf(`
-		SELECT id, reference_no, status, order_date, note, total, paid, balance, 
-		supplier_id, created_at, updated_at 
+		SELECT id, reference_no, status, order_date, note, total, paid, balance, credited, due_date,
+		tax_mode, currency, exchange_rate, base_total, supplier_id, opening, created_at, updated_at 
 		FROM stock_ins 
 		%s
 		ORDER BY order_date DESC
@@ -553,13 +680,15 @@ func (r *StockInRepositoryImpl) List(offset, limit int, status string, supplierI
 
 		err := rows.Scan(
 			&stockIn.ID, &stockIn.ReferenceNo, &stockIn.Status, &stockIn.OrderDate, &stockIn.Note,
-			&stockIn.Total, &stockIn.Paid, &stockIn.Balance, &stockIn.SupplierID,
-			&stockIn.CreatedAt, &stockIn.UpdatedAt,
+			&stockIn.Total, &stockIn.Paid, &stockIn.Balance, &stockIn.Credited, &stockIn.DueDate,
+			&stockIn.TaxMode, &stockIn.Currency, &stockIn.ExchangeRate, &stockIn.BaseTotal, &stockIn.SupplierID,
+			&stockIn.Opening, &stockIn.CreatedAt, &stockIn.UpdatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan stock-in: %w", err)
 		}
 
+		stockIn.BindCurrency()
 		stockIns = append(stockIns, stockIn)
 	}
 
@@ -572,8 +701,8 @@ func (r *StockInRepositoryImpl) List(offset, limit int, status string, supplierI
 
 func (r *StockInRepositoryImpl) GetStockInsBySupplier(supplierID string) ([]models.StockIn, error) {
 	query := `
-		SELECT id, reference_no, status, order_date, note, total, paid, balance, 
-		supplier_id, created_at, updated_at 
+		SELECT id, reference_no, status, order_date, note, total, paid, balance, credited, due_date,
+		tax_mode, currency, exchange_rate, base_total, supplier_id, opening, created_at, updated_at 
 		FROM stock_ins 
 		WHERE supplier_id = $1 AND deleted_at IS NULL
 		ORDER BY order_date DESC`
@@ -590,13 +719,15 @@ func (r *StockInRepositoryImpl) GetStockInsBySupplier(supplierID string) ([]mode
 
 		err := rows.Scan(
 			&stockIn.ID, &stockIn.ReferenceNo, &stockIn.Status, &stockIn.OrderDate, &stockIn.Note,
-			&stockIn.Total, &stockIn.Paid, &stockIn.Balance, &stockIn.SupplierID,
-			&stockIn.CreatedAt, &stockIn.UpdatedAt,
+			&stockIn.Total, &stockIn.Paid, &stockIn.Balance, &stockIn.Credited, &stockIn.DueDate,
+			&stockIn.TaxMode, &stockIn.Currency, &stockIn.ExchangeRate, &stockIn.BaseTotal, &stockIn.SupplierID,
+			&stockIn.Opening, &stockIn.CreatedAt, &stockIn.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan stock-in: %w", err)
 		}
 
+		stockIn.BindCurrency()
 		stockIns = append(stockIns, stockIn)
 	}
 
@@ -611,14 +742,19 @@ func (r *StockInRepositoryImpl) GetStockInSummary(startDate, endDate time.Time)
 	query := `
 		SELECT 
 			COUNT(*) as total_orders,
-			COALESCE(SUM(total), 0) as total_cost,
+			COALESCE(SUM(base_total), 0) as total_cost,
 			COUNT(CASE WHEN status = 'completed' THEN 1 END) as completed_orders,
-			COALESCE(SUM(CASE WHEN status = 'completed' THEN total ELSE 0 END), 0) as completed_cost
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN base_total ELSE 0 END), 0) as completed_cost
 		FROM stock_ins
 		WHERE order_date BETWEEN $1 AND $2
 		AND deleted_at IS NULL`
 
-	var summary models.StockInSummary
+	// Totals are reported in the base currency
+	summary := models.StockInSummary{
+		TotalCost:         money.Zero(money.BaseCurrency),
+		CompletedCost:     money.Zero(money.BaseCurrency),
+		AverageOrderValue: money.Zero(money.BaseCurrency),
+	}
 	err := r.db.QueryRow(context.Background(), query, startDate, endDate).Scan(
 		&summary.TotalOrders, &summary.TotalCost,
 		&summary.CompletedOrders, &summary.CompletedCost,
@@ -630,7 +766,7 @@ func (r *StockInRepositoryImpl) GetStockInSummary(startDate, endDate time.Time)
 
 	// Calculate average order value
 	if summary.TotalOrders > 0 {
-		summary.AverageOrderValue = summary.TotalCost / float64(summary.TotalOrders)
+		summary.AverageOrderValue = summary.TotalCost.Div(int64(summary.TotalOrders)).Round()
 	}
 
 	return &summary, nil
@@ -641,7 +777,7 @@ func (r *StockInRepositoryImpl) GetDailyStockIn(startDate, endDate time.Time) ([
 		SELECT 
 			TO_CHAR(order_date, 'YYYY-MM-DD') as date,
 			COUNT(*) as order_count,
-			COALESCE(SUM(total), 0) as total_cost
+			COALESCE(SUM(base_total), 0) as total_cost
 		FROM stock_ins
 		WHERE order_date BETWEEN $1 AND $2
 		AND deleted_at IS NULL
@@ -656,7 +792,7 @@ func (r *StockInRepositoryImpl) GetDailyStockIn(startDate, endDate time.Time) ([
 
 	var results []models.DailyStockIn
 	for rows.Next() {
-		var day models.DailyStockIn
+		day := models.DailyStockIn{TotalCost: money.Zero(money.BaseCurrency)}
 
 		err := rows.Scan(&day.Date, &day.OrderCount, &day.TotalCost)
 		if err != nil {
@@ -672,3 +808,49 @@ func (r *StockInRepositoryImpl) GetDailyStockIn(startDate, endDate time.Time) ([
 
 	return results, nil
 }
+
+// refreshStockInTotals sets a stock-in's totals and balance from its items after they change.
+// The total includes the items' tax, which is converted at the stock-in's exchange rate.
+// Opening stock keeps a zero balance.
+func refreshStockInTotals(ctx context.Context, tx pgx.Tx, stockInID string, at time.Time) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE stock_ins si SET
+		total = t.subtotal + t.tax,
+		base_total = t.base_subtotal + ROUND(t.tax * si.exchange_rate, %d),
+		balance = CASE WHEN si.opening THEN 0 ELSE t.subtotal + t.tax - si.paid - si.credited END,
+		updated_at = $2
+		FROM (
+			SELECT COALESCE(SUM(subtotal), 0) AS subtotal, COALESCE(SUM(tax), 0) AS tax,
+				COALESCE(SUM(base_subtotal), 0) AS base_subtotal
+			FROM stock_in_items WHERE stock_in_id = $1 AND deleted_at IS NULL
+		) t
+		WHERE si.id = $1`, money.Decimals(money.BaseCurrency)),
+		stockInID, at,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update stock-in total: %w", err)
+	}
+	return nil
+}
+
+// lockStockInStatus locks a stock-in row and returns its current status
+func lockStockInStatus(ctx context.Context, tx pgx.Tx, id string) (models.StockInStatus, error) {
+	var status models.StockInStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM stock_ins WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("failed to get stock-in status: %w", err)
+	}
+	return status, nil
+}
+
+// repostStockInCosts replaces the cost layers of a completed stock-in with ones for its current items
+func repostStockInCosts(ctx context.Context, tx pgx.Tx, stockInID string) error {
+	status, err := lockStockInStatus(ctx, tx, stockInID)
+	if err != nil {
+		return err
+	}
+	if status != models.StockInStatusCompleted {
+		return nil
+	}
+	return syncDocumentPostings(ctx, tx, models.CostSourceStockIn, stockInID, true, true)
+}
@@ -36,7 +36,7 @@ func NewSupplierRepository(db *pgx.Conn) SupplierRepository {
 
 func (r *SupplierRepositoryImpl) GetAll() ([]models.Supplier, error) {
 	query := `SELECT id, name, email, phone, address, contact_person, total_purchases, 
-	          total_spent, last_order_at, notes, created_at, updated_at 
+	          total_spent, last_order_at, notes, payment_terms, created_at, updated_at 
 	          FROM suppliers 
 	          WHERE deleted_at IS NULL
 	          ORDER BY name ASC`
@@ -55,7 +55,7 @@ func (r *SupplierRepositoryImpl) GetAll() ([]models.Supplier, error) {
 		err := rows.Scan(
 			&supplier.ID, &supplier.Name, &supplier.Email, &supplier.Phone,
 			&supplier.Address, &supplier.ContactPerson, &supplier.TotalPurchases, &supplier.TotalSpent,
-			&lastOrderAt, &supplier.Notes, &supplier.CreatedAt, &supplier.UpdatedAt,
+			&lastOrderAt, &supplier.Notes, &supplier.PaymentTerms, &supplier.CreatedAt, &supplier.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan supplier: %w", err)
@@ -77,7 +77,7 @@ func (r *SupplierRepositoryImpl) GetAll() ([]models.Supplier, error) {
 
 func (r *SupplierRepositoryImpl) GetByID(id string) (*models.Supplier, error) {
 	query := `SELECT id, name, email, phone, address, contact_person, total_purchases, 
-	          total_spent, last_order_at, notes, created_at, updated_at 
+	          total_spent, last_order_at, notes, payment_terms, created_at, updated_at 
 	          FROM suppliers 
 	          WHERE id = $1 AND deleted_at IS NULL`
 
@@ -87,7 +87,7 @@ func (r *SupplierRepositoryImpl) GetByID(id string) (*models.Supplier, error) {
 	err := r.db.QueryRow(context.Background(), query, id).Scan(
 		&supplier.ID, &supplier.Name, &supplier.Email, &supplier.Phone,
 		&supplier.Address, &supplier.ContactPerson, &supplier.TotalPurchases, &supplier.TotalSpent,
-		&lastOrderAt, &supplier.Notes, &supplier.CreatedAt, &supplier.UpdatedAt,
+		&lastOrderAt, &supplier.Notes, &supplier.PaymentTerms, &supplier.CreatedAt, &supplier.UpdatedAt,
 	)
 
 	if err != nil {
@@ -106,7 +106,7 @@ func (r *SupplierRepositoryImpl) GetByID(id string) (*models.Supplier, error) {
 
 func (r *SupplierRepositoryImpl) GetByEmail(email string) (*models.Supplier, error) {
 	query := `SELECT id, name, email, phone, address, contact_person, total_purchases, 
-	          total_spent, last_order_at, notes, created_at, updated_at 
+	          total_spent, last_order_at, notes, payment_terms, created_at, updated_at 
 	          FROM suppliers 
 	          WHERE email = $1 AND deleted_at IS NULL`
 
@@ -116,7 +116,7 @@ func (r *SupplierRepositoryImpl) GetByEmail(email string) (*models.Supplier, err
 	err := r.db.QueryRow(context.Background(), query, email).Scan(
 		&supplier.ID, &supplier.Name, &supplier.Email, &supplier.Phone,
 		&supplier.Address, &supplier.ContactPerson, &supplier.TotalPurchases, &supplier.TotalSpent,
-		&lastOrderAt, &supplier.Notes, &supplier.CreatedAt, &supplier.UpdatedAt,
+		&lastOrderAt, &supplier.Notes, &supplier.PaymentTerms, &supplier.CreatedAt, &supplier.UpdatedAt,
 	)
 
 	if err != nil {
@@ -145,14 +145,14 @@ func (r *SupplierRepositoryImpl) Create(supplier *models.Supplier) error {
 	query := `INSERT INTO suppliers (
 		id, name, email, phone, address, contact_person, 
 		total_purchases, total_spent, last_order_at, notes, 
-		created_at, updated_at
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+		payment_terms, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 
 	_, err := r.db.Exec(context.Background(), query,
 		supplier.ID, supplier.Name, supplier.Email, supplier.Phone,
 		supplier.Address, supplier.ContactPerson, supplier.TotalPurchases,
 		supplier.TotalSpent, supplier.LastOrderAt, supplier.Notes,
-		supplier.CreatedAt, supplier.UpdatedAt,
+		supplier.PaymentTerms, supplier.CreatedAt, supplier.UpdatedAt,
 	)
 
 	if err != nil {
@@ -168,13 +168,13 @@ func (r *SupplierRepositoryImpl) Update(supplier *models.Supplier) error {
 	query := `UPDATE suppliers SET
 		name = $1, email = $2, phone = $3, address = $4, 
 		contact_person = $5, total_purchases = $6, total_spent = $7, 
-		last_order_at = $8, notes = $9, updated_at = $10
-		WHERE id = $11`
+		last_order_at = $8, notes = $9, payment_terms = $10, updated_at = $11
+		WHERE id = $12`
 
 	_, err := r.db.Exec(context.Background(), query,
 		supplier.Name, supplier.Email, supplier.Phone, supplier.Address,
 		supplier.ContactPerson, supplier.TotalPurchases, supplier.TotalSpent, 
-		supplier.LastOrderAt, supplier.Notes, supplier.UpdatedAt, supplier.ID,
+		supplier.LastOrderAt, supplier.Notes, supplier.PaymentTerms, supplier.UpdatedAt, supplier.ID,
 	)
 
 	if err != nil {
@@ -216,7 +216,7 @@ func (r *SupplierRepositoryImpl) Search(query string, offset, limit int) ([]mode
 
 	// Get paginated results
 	searchQuery := `SELECT id, name, email, phone, address, contact_person, total_purchases, 
-		total_spent, last_order_at, notes, created_at, updated_at 
+		total_spent, last_order_at, notes, payment_terms, created_at, updated_at 
 		FROM suppliers 
 		WHERE deleted_at IS NULL AND 
 		(name ILIKE $1 OR email ILIKE $2 OR phone ILIKE $3 OR contact_person ILIKE $4)
@@ -238,7 +238,7 @@ func (r *SupplierRepositoryImpl) Search(query string, offset, limit int) ([]mode
 		err := rows.Scan(
 			&supplier.ID, &supplier.Name, &supplier.Email, &supplier.Phone,
 			&supplier.Address, &supplier.ContactPerson, &supplier.TotalPurchases, &supplier.TotalSpent,
-			&lastOrderAt, &supplier.Notes, &supplier.CreatedAt, &supplier.UpdatedAt,
+			&lastOrderAt, &supplier.Notes, &supplier.PaymentTerms, &supplier.CreatedAt, &supplier.UpdatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan supplier: %w", err)
@@ -260,7 +260,7 @@ func (r *SupplierRepositoryImpl) Search(query string, offset, limit int) ([]mode
 
 func (r *SupplierRepositoryImpl) GetTopSuppliers(limit int) ([]models.Supplier, error) {
 	query := `SELECT id, name, email, phone, address, contact_person, total_purchases, 
-	          total_spent, last_order_at, notes, created_at, updated_at 
+	          total_spent, last_order_at, notes, payment_terms, created_at, updated_at 
 	          FROM suppliers 
 	          WHERE deleted_at IS NULL
 	          ORDER BY total_spent DESC
@@ -280,7 +280,7 @@ func (r *SupplierRepositoryImpl) GetTopSuppliers(limit int) ([]models.Supplier,
 		err := rows.Scan(
 			&supplier.ID, &supplier.Name, &supplier.Email, &supplier.Phone,
 			&supplier.Address, &supplier.ContactPerson, &supplier.TotalPurchases, &supplier.TotalSpent,
-			&lastOrderAt, &supplier.Notes, &supplier.CreatedAt, &supplier.UpdatedAt,
+			&lastOrderAt, &supplier.Notes, &supplier.PaymentTerms, &supplier.CreatedAt, &supplier.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan supplier: %w", err)
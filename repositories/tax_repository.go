@@ -0,0 +1,374 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"inventory-go/money"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TaxRepository defines methods for tax codes and VAT reporting
+type TaxRepository interface {
+	GetAll(activeOnly bool) ([]models.TaxCode, error)
+	GetByID(id string) (*models.TaxCode, error)
+	GetByCode(code string) (*models.TaxCode, error)
+	Create(code *models.TaxCode) error
+	Update(code *models.TaxCode) error
+	Delete(id string) error
+
+	// ResolveProductTaxCodes returns the tax code that applies to each product. Products
+	// without one are left out of the map.
+	ResolveProductTaxCodes(productIDs []string) (map[string]*models.TaxCode, error)
+	GetVATReport(year, month int) (*models.VATReport, error)
+}
+
+// TaxRepositoryImpl implements the TaxRepository interface
+type TaxRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewTaxRepository creates a new TaxRepository
+func NewTaxRepository(db *pgx.Conn) TaxRepository {
+	return &TaxRepositoryImpl{db: db}
+}
+
+const taxCodeColumns = `id, code, name, COALESCE(description, ''), type, rate, active, created_at, updated_at`
+
+func scanTaxCode(row pgx.Row) (*models.TaxCode, error) {
+	var code models.TaxCode
+	err := row.Scan(
+		&code.ID, &code.Code, &code.Name, &code.Description, &code.Type,
+		&code.Rate, &code.Active, &code.CreatedAt, &code.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// GetAll retrieves tax codes ordered by code, optionally only the active ones
+func (r *TaxRepositoryImpl) GetAll(activeOnly bool) ([]models.TaxCode, error) {
+	query := `SELECT ` + taxCodeColumns + ` FROM tax_codes WHERE deleted_at IS NULL`
+	if activeOnly {
+		query += ` AND active = TRUE`
+	}
+	query += ` ORDER BY code ASC`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []models.TaxCode{}
+	for rows.Next() {
+		code, err := scanTaxCode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tax code: %w", err)
+		}
+		codes = append(codes, *code)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tax codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// GetByID retrieves a tax code by its ID
+func (r *TaxRepositoryImpl) GetByID(id string) (*models.TaxCode, error) {
+	return getTaxCode(context.Background(), r.db, `id = $1`, id)
+}
+
+// GetByCode retrieves a tax code by its code, ignoring case
+func (r *TaxRepositoryImpl) GetByCode(code string) (*models.TaxCode, error) {
+	return getTaxCode(context.Background(), r.db, `code = $1`, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// getTaxCode loads a single tax code matching the condition through a connection or transaction
+func getTaxCode(ctx context.Context, q queryRower, condition string, arg any) (*models.TaxCode, error) {
+	query := `SELECT ` + taxCodeColumns + ` FROM tax_codes WHERE ` + condition + ` AND deleted_at IS NULL`
+
+	code, err := scanTaxCode(q.QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tax code: %w", err)
+	}
+	return code, nil
+}
+
+// Create stores a new tax code
+func (r *TaxRepositoryImpl) Create(code *models.TaxCode) error {
+	code.GenerateID()
+	now := time.Now()
+	code.CreatedAt = now
+	code.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO tax_codes (id, code, name, description, type, rate, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		code.ID, code.Code, code.Name, code.Description, code.Type, code.Rate, code.Active,
+		code.CreatedAt, code.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create tax code: %w", err)
+	}
+	return nil
+}
+
+// Update saves changes to a tax code. Documents already priced keep the rate they were given.
+func (r *TaxRepositoryImpl) Update(code *models.TaxCode) error {
+	code.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(context.Background(), `
+		UPDATE tax_codes SET
+		code = $1, name = $2, description = $3, type = $4, rate = $5, active = $6, updated_at = $7
+		WHERE id = $8 AND deleted_at IS NULL`,
+		code.Code, code.Name, code.Description, code.Type, code.Rate, code.Active,
+		code.UpdatedAt, code.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update tax code: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes a tax code and unassigns it from categories
+func (r *TaxRepositoryImpl) Delete(id string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if _, err = tx.Exec(ctx, `UPDATE tax_codes SET deleted_at = $1 WHERE id = $2`, now, id); err != nil {
+		return fmt.Errorf("failed to delete tax code: %w", err)
+	}
+	if _, err = tx.Exec(ctx, `UPDATE categories SET tax_code_id = NULL, updated_at = $1 WHERE tax_code_id = $2`, now, id); err != nil {
+		return fmt.Errorf("failed to unassign tax code from categories: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ResolveProductTaxCodes finds each product's tax code: the product's own, else the nearest
+// category up its tree that has one, else the default_tax_code setting. Inactive codes are
+// skipped in favour of the default.
+func (r *TaxRepositoryImpl) ResolveProductTaxCodes(productIDs []string) (map[string]*models.TaxCode, error) {
+	ctx := context.Background()
+	resolved := map[string]*models.TaxCode{}
+	if len(productIDs) == 0 {
+		return resolved, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT p.id AS product_id, c.parent_id, c.tax_code_id, 0 AS depth
+			FROM products p
+			JOIN categories c ON c.id = p.child_category_id AND c.deleted_at IS NULL
+			WHERE p.id = ANY($1)
+			UNION ALL
+			SELECT chain.product_id, c.parent_id, c.tax_code_id, chain.depth + 1
+			FROM chain
+			JOIN categories c ON c.id = chain.parent_id AND c.deleted_at IS NULL
+			WHERE chain.tax_code_id IS NULL AND chain.depth < 32
+		)
+		SELECT p.id, COALESCE(NULLIF(p.price->>'tax_code_id', ''), (
+			SELECT chain.tax_code_id FROM chain
+			WHERE chain.product_id = p.id AND chain.tax_code_id IS NOT NULL
+			ORDER BY chain.depth LIMIT 1
+		), '')
+		FROM products p
+		WHERE p.id = ANY($1)`, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve product tax codes: %w", err)
+	}
+
+	assigned := map[string]string{}
+	codeIDs := []string{}
+	for rows.Next() {
+		var productID, codeID string
+		if err := rows.Scan(&productID, &codeID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan product tax code: %w", err)
+		}
+		if codeID != "" {
+			assigned[productID] = codeID
+			codeIDs = append(codeIDs, codeID)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product tax codes: %w", err)
+	}
+
+	codes := map[string]*models.TaxCode{}
+	if len(codeIDs) > 0 {
+		rows, err = r.db.Query(ctx, `SELECT `+taxCodeColumns+` FROM tax_codes
+			WHERE id = ANY($1) AND active = TRUE AND deleted_at IS NULL`, codeIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tax codes: %w", err)
+		}
+		for rows.Next() {
+			code, err := scanTaxCode(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan tax code: %w", err)
+			}
+			codes[code.ID] = code
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating tax codes: %w", err)
+		}
+	}
+
+	defaultCode, err := getSetting(ctx, r.db, models.SettingDefaultTaxCode)
+	if err != nil {
+		return nil, err
+	}
+	var fallback *models.TaxCode
+	if defaultCode != "" {
+		if fallback, err = getTaxCode(ctx, r.db, `code = $1 AND active = TRUE`, defaultCode); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range productIDs {
+		if code, ok := codes[assigned[id]]; ok {
+			resolved[id] = code
+		} else if fallback != nil {
+			resolved[id] = fallback
+		}
+	}
+	return resolved, nil
+}
+
+// GetVATReport totals output VAT on the lines of completed sales and input VAT on the lines
+// of completed stock-ins dated in the month, per tax code and rate, in the base currency
+func (r *TaxRepositoryImpl) GetVATReport(year, month int) (*models.VATReport, error) {
+	ctx := context.Background()
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	output, err := r.vatLines(ctx, `
+		SELECT i.tax_code_id, COALESCE(i.tax_code, ''), i.tax_rate, d.currency, d.exchange_rate,
+			COUNT(DISTINCT d.id), COALESCE(SUM(i.subtotal - i.tax), 0), COALESCE(SUM(i.tax), 0)
+		FROM sale_items i
+		JOIN sales d ON d.id = i.sale_id
+		WHERE d.deleted_at IS NULL AND i.deleted_at IS NULL AND d.status = $1
+			AND d.sale_date >= $2 AND d.sale_date < $3
+		GROUP BY i.tax_code_id, i.tax_code, i.tax_rate, d.currency, d.exchange_rate`,
+		models.SaleStatusCompleted, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output VAT: %w", err)
+	}
+
+	input, err := r.vatLines(ctx, `
+		SELECT i.tax_code_id, COALESCE(i.tax_code, ''), i.tax_rate, d.currency, d.exchange_rate,
+			COUNT(DISTINCT d.id), COALESCE(SUM(i.subtotal), 0), COALESCE(SUM(i.tax), 0)
+		FROM stock_in_items i
+		JOIN stock_ins d ON d.id = i.stock_in_id
+		WHERE d.deleted_at IS NULL AND i.deleted_at IS NULL AND d.status = $1
+			AND d.order_date >= $2 AND d.order_date < $3
+		GROUP BY i.tax_code_id, i.tax_code, i.tax_rate, d.currency, d.exchange_rate`,
+		models.StockInStatusCompleted, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get input VAT: %w", err)
+	}
+
+	return models.NewVATReport(year, month, start, end.Add(-time.Second), output, input), nil
+}
+
+// vatLines runs a VAT query grouped by tax code, rate and document currency, converts each
+// group to the base currency and merges the groups of the same code and rate
+func (r *TaxRepositoryImpl) vatLines(ctx context.Context, query string, args ...any) ([]models.VATReportLine, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []models.VATReportLine{}
+	index := map[string]int{}
+	for rows.Next() {
+		var codeID *string
+		var code, currency string
+		var taxRate, exchangeRate money.Rate
+		var count int
+		taxable := money.Zero(money.BaseCurrency)
+		tax := money.Zero(money.BaseCurrency)
+		if err := rows.Scan(&codeID, &code, &taxRate, &currency, &exchangeRate, &count, &taxable, &tax); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		key := code + "|" + taxRate.String()
+		i, ok := index[key]
+		if !ok {
+			line := models.VATReportLine{
+				TaxCode:       code,
+				TaxRate:       taxRate,
+				TaxableAmount: money.Zero(money.BaseCurrency),
+				Tax:           money.Zero(money.BaseCurrency),
+			}
+			if codeID != nil {
+				line.TaxCodeID = *codeID
+			}
+			lines = append(lines, line)
+			i = len(lines) - 1
+			index[key] = i
+		}
+		lines[i].DocumentCount += count
+		lines[i].TaxableAmount = lines[i].TaxableAmount.Add(toBase(exchangeRate, taxable, currency))
+		lines[i].Tax = lines[i].Tax.Add(toBase(exchangeRate, tax, currency))
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Tax types come from the codes as they are now; lines without a code have none
+	types := map[string]models.TaxType{}
+	typeRows, err := r.db.Query(ctx, `SELECT id, type FROM tax_codes`)
+	if err != nil {
+		return nil, err
+	}
+	for typeRows.Next() {
+		var id string
+		var taxType models.TaxType
+		if err := typeRows.Scan(&id, &taxType); err != nil {
+			typeRows.Close()
+			return nil, err
+		}
+		types[id] = taxType
+	}
+	typeRows.Close()
+	if err = typeRows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		lines[i].TaxType = types[lines[i].TaxCodeID]
+	}
+
+	sort.SliceStable(lines, func(a, b int) bool {
+		if lines[a].TaxCode != lines[b].TaxCode {
+			return lines[a].TaxCode < lines[b].TaxCode
+		}
+		return lines[a].TaxRate.Rat().Cmp(lines[b].TaxRate.Rat()) < 0
+	})
+	return lines, nil
+}
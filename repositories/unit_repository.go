@@ -0,0 +1,303 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnitInUse is returned when a unit still used by product conversions would be deleted or renamed
+var ErrUnitInUse = errors.New("unit is in use")
+
+// ErrUnknownUnit is returned when product conversions name a unit that is not set up
+var ErrUnknownUnit = errors.New("unknown unit")
+
+// ErrBaseUnitHasStock is returned when a product's base unit would change while it has stock
+var ErrBaseUnitHasStock = errors.New("base unit cannot change while the product has stock")
+
+// UnitRepository defines methods for units of measure and the units each product is kept in
+type UnitRepository interface {
+	GetAll() ([]models.Unit, error)
+	GetByID(id string) (*models.Unit, error)
+	GetByCode(code string) (*models.Unit, error)
+	Create(unit *models.Unit) error
+	// Update saves the unit; its code can only change while no product uses it
+	Update(unit *models.Unit) error
+	// Delete soft-deletes the unit, returning ErrUnitInUse while a product uses it
+	Delete(id string) error
+
+	// GetProductUnits returns a product's base unit and conversions. Products without any are
+	// counted in the default base unit.
+	GetProductUnits(productID string) (*models.ProductUnits, error)
+	// ResolveProductUnits returns the units of each product
+	ResolveProductUnits(productIDs []string) (map[string]*models.ProductUnits, error)
+	// SaveProductUnits replaces a product's base unit and conversions
+	SaveProductUnits(units *models.ProductUnits) error
+}
+
+// UnitRepositoryImpl implements the UnitRepository interface
+type UnitRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewUnitRepository creates a new UnitRepository
+func NewUnitRepository(db *pgx.Conn) UnitRepository {
+	return &UnitRepositoryImpl{db: db}
+}
+
+const unitColumns = `id, code, name, created_at, updated_at`
+
+func scanUnit(row pgx.Row) (*models.Unit, error) {
+	var unit models.Unit
+	if err := row.Scan(&unit.ID, &unit.Code, &unit.Name, &unit.CreatedAt, &unit.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// GetAll retrieves the units ordered by code
+func (r *UnitRepositoryImpl) GetAll() ([]models.Unit, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT `+unitColumns+` FROM units WHERE deleted_at IS NULL ORDER BY code ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query units: %w", err)
+	}
+	defer rows.Close()
+
+	units := []models.Unit{}
+	for rows.Next() {
+		unit, err := scanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan unit: %w", err)
+		}
+		units = append(units, *unit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating units: %w", err)
+	}
+
+	return units, nil
+}
+
+// GetByID retrieves a unit by ID
+func (r *UnitRepositoryImpl) GetByID(id string) (*models.Unit, error) {
+	return r.getUnit(`id = $1`, id)
+}
+
+// GetByCode retrieves a unit by its code, ignoring case
+func (r *UnitRepositoryImpl) GetByCode(code string) (*models.Unit, error) {
+	return r.getUnit(`code = $1`, models.NormalizeUnit(code))
+}
+
+func (r *UnitRepositoryImpl) getUnit(condition string, arg any) (*models.Unit, error) {
+	query := `SELECT ` + unitColumns + ` FROM units WHERE ` + condition + ` AND deleted_at IS NULL`
+
+	unit, err := scanUnit(r.db.QueryRow(context.Background(), query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get unit: %w", err)
+	}
+	return unit, nil
+}
+
+// Create stores a new unit
+func (r *UnitRepositoryImpl) Create(unit *models.Unit) error {
+	now := time.Now()
+	unit.CreatedAt = now
+	unit.UpdatedAt = now
+
+	_, err := r.db.Exec(context.Background(), `
+		INSERT INTO units (id, code, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		unit.ID, unit.Code, unit.Name, unit.CreatedAt, unit.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create unit: %w", err)
+	}
+	return nil
+}
+
+// Update saves changes to a unit. Documents keep the unit code they were entered in.
+func (r *UnitRepositoryImpl) Update(unit *models.Unit) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var code string
+	err = tx.QueryRow(ctx, `SELECT code FROM units WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, unit.ID).Scan(&code)
+	if err != nil {
+		return fmt.Errorf("failed to get unit: %w", err)
+	}
+	if code != unit.Code {
+		if err := checkUnitUnused(ctx, tx, code); err != nil {
+			return err
+		}
+	}
+
+	unit.UpdatedAt = time.Now()
+	_, err = tx.Exec(ctx, `UPDATE units SET code = $1, name = $2, updated_at = $3 WHERE id = $4`,
+		unit.Code, unit.Name, unit.UpdatedAt, unit.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update unit: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes a unit no product uses
+func (r *UnitRepositoryImpl) Delete(id string) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var code string
+	err = tx.QueryRow(ctx, `SELECT code FROM units WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&code)
+	if err != nil {
+		return fmt.Errorf("failed to get unit: %w", err)
+	}
+	if err := checkUnitUnused(ctx, tx, code); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, `UPDATE units SET deleted_at = $1 WHERE id = $2`, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to delete unit: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// checkUnitUnused returns ErrUnitInUse when a product is kept in or converted to the unit
+func checkUnitUnused(ctx context.Context, tx pgx.Tx, code string) error {
+	var used int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM product_units WHERE unit = $1`, code).Scan(&used); err != nil {
+		return fmt.Errorf("failed to check unit use: %w", err)
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: %s is used by %d products", ErrUnitInUse, code, used)
+	}
+	return nil
+}
+
+// GetProductUnits retrieves a product's units
+func (r *UnitRepositoryImpl) GetProductUnits(productID string) (*models.ProductUnits, error) {
+	units, err := queryProductUnits(context.Background(), r.db, []string{productID})
+	if err != nil {
+		return nil, err
+	}
+	return units[productID], nil
+}
+
+// ResolveProductUnits retrieves the units of several products
+func (r *UnitRepositoryImpl) ResolveProductUnits(productIDs []string) (map[string]*models.ProductUnits, error) {
+	return queryProductUnits(context.Background(), r.db, productIDs)
+}
+
+// queryProductUnits loads the units of the products, defaulting those without any to the
+// default base unit
+func queryProductUnits(ctx context.Context, q rowsQuerier, productIDs []string) (map[string]*models.ProductUnits, error) {
+	units := make(map[string]*models.ProductUnits, len(productIDs))
+	for _, id := range productIDs {
+		units[id] = &models.ProductUnits{ProductID: id, BaseUnit: models.DefaultBaseUnit, Conversions: []models.UnitConversion{}}
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT pu.product_id, pu.unit, COALESCE(u.name, pu.unit), pu.factor
+		FROM product_units pu
+		LEFT JOIN units u ON u.code = pu.unit AND u.deleted_at IS NULL
+		WHERE pu.product_id = ANY($1)
+		ORDER BY pu.product_id, pu.factor`, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product units: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID string
+		var c models.UnitConversion
+		if err := rows.Scan(&productID, &c.Unit, &c.Name, &c.Factor); err != nil {
+			return nil, fmt.Errorf("failed to scan product unit: %w", err)
+		}
+		if c.Factor == 1 {
+			units[productID].BaseUnit = c.Unit
+			continue
+		}
+		units[productID].Conversions = append(units[productID].Conversions, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product units: %w", err)
+	}
+
+	return units, nil
+}
+
+// SaveProductUnits replaces the product's units. Every unit must be set up, and the base unit
+// can only change while the product has no stock, as stock is counted in it.
+func (r *UnitRepositoryImpl) SaveProductUnits(units *models.ProductUnits) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	codes := units.Codes()
+	var known int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM units WHERE code = ANY($1) AND deleted_at IS NULL`, codes).Scan(&known)
+	if err != nil {
+		return fmt.Errorf("failed to check units: %w", err)
+	}
+	if known != len(codes) {
+		return fmt.Errorf("%w: every unit must be set up before it is used", ErrUnknownUnit)
+	}
+
+	var stock int
+	err = tx.QueryRow(ctx, `SELECT stock FROM products WHERE id = $1 FOR UPDATE`, units.ProductID).Scan(&stock)
+	if err != nil {
+		return fmt.Errorf("failed to get product stock: %w", err)
+	}
+	current, err := queryProductUnits(ctx, tx, []string{units.ProductID})
+	if err != nil {
+		return err
+	}
+	if current[units.ProductID].BaseUnit != units.BaseUnit && stock != 0 {
+		return ErrBaseUnitHasStock
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM product_units WHERE product_id = $1`, units.ProductID); err != nil {
+		return fmt.Errorf("failed to replace product units: %w", err)
+	}
+	insert := `INSERT INTO product_units (product_id, unit, factor) VALUES ($1, $2, $3)`
+	if _, err = tx.Exec(ctx, insert, units.ProductID, units.BaseUnit, 1); err != nil {
+		return fmt.Errorf("failed to insert product unit: %w", err)
+	}
+	for _, c := range units.Conversions {
+		if _, err = tx.Exec(ctx, insert, units.ProductID, c.Unit, c.Factor); err != nil {
+			return fmt.Errorf("failed to insert product unit: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,505 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-go/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrOptionInUse is returned when an option or value a variant is bound to would be removed
+var ErrOptionInUse = errors.New("option is used by a variant")
+
+// ErrDuplicateVariant is returned when a parent already has a variant with the same option values
+var ErrDuplicateVariant = errors.New("a variant with these option values already exists")
+
+// ErrInvalidVariant is returned when variants cannot be made from the options or patterns given
+var ErrInvalidVariant = errors.New("invalid variant")
+
+// ErrDuplicateSKU is returned when a variant's SKU is already used by another product
+var ErrDuplicateSKU = errors.New("SKU is already used by another product")
+
+// VariantRepository defines methods for a parent product's options and the variants bound to
+// combinations of their values
+type VariantRepository interface {
+	GetOptions(productID string) ([]models.ProductOption, error)
+	// SaveOptions replaces the product's options, keeping the ones variants are bound to
+	SaveOptions(productID string, options []models.ProductOption) error
+
+	// GetVariants returns the parent's variants with their option values
+	GetVariants(parentID string) ([]models.Variant, error)
+	// CreateVariant adds a variant of the parent bound to the option values
+	CreateVariant(parent *models.Product, variant *models.Variant, options []models.ProductOption, values []models.ProductOptionValue) error
+	// GenerateVariants adds a variant for every combination of the parent's option values
+	// that has none
+	GenerateVariants(parent *models.Product, generation models.VariantGeneration) (*models.VariantGenerationResult, error)
+}
+
+// VariantRepositoryImpl implements the VariantRepository interface
+type VariantRepositoryImpl struct {
+	db *pgx.Conn
+}
+
+// NewVariantRepository creates a new VariantRepository
+func NewVariantRepository(db *pgx.Conn) VariantRepository {
+	return &VariantRepositoryImpl{db: db}
+}
+
+// GetOptions retrieves the product's options and their values in order
+func (r *VariantRepositoryImpl) GetOptions(productID string) ([]models.ProductOption, error) {
+	return queryProductOptions(context.Background(), r.db, productID)
+}
+
+func queryProductOptions(ctx context.Context, q rowsQuerier, productID string) ([]models.ProductOption, error) {
+	rows, err := q.Query(ctx, `
+		SELECT o.id, o.product_id, o.name, o.sort_order, v.id, v.value, v.code, v.sort_order
+		FROM product_options o
+		JOIN product_option_values v ON v.option_id = o.id
+		WHERE o.product_id = $1
+		ORDER BY o.sort_order, v.sort_order`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product options: %w", err)
+	}
+	defer rows.Close()
+
+	options := []models.ProductOption{}
+	for rows.Next() {
+		var o models.ProductOption
+		var v models.ProductOptionValue
+		if err := rows.Scan(&o.ID, &o.ProductID, &o.Name, &o.SortOrder, &v.ID, &v.Value, &v.Code, &v.SortOrder); err != nil {
+			return nil, fmt.Errorf("failed to scan product option: %w", err)
+		}
+		v.OptionID = o.ID
+		if n := len(options); n == 0 || options[n-1].ID != o.ID {
+			options = append(options, o)
+		}
+		last := &options[len(options)-1]
+		last.Values = append(last.Values, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product options: %w", err)
+	}
+
+	return options, nil
+}
+
+// SaveOptions replaces the product's options. Options and values are matched to the current
+// ones by name, so renaming keeps variants bound; removing one a variant uses is refused.
+func (r *VariantRepositoryImpl) SaveOptions(productID string, options []models.ProductOption) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the product so concurrent saves and generations take turns
+	if _, err = tx.Exec(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, productID); err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	current, err := queryProductOptions(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	models.MatchProductOptions(current, options)
+
+	known := map[string]bool{}
+	for _, o := range current {
+		known[o.ID] = true
+	}
+	added := false
+	keepOptions, keepValues := []string{}, []string{}
+	for _, o := range options {
+		added = added || !known[o.ID]
+		keepOptions = append(keepOptions, o.ID)
+		for _, v := range o.Values {
+			keepValues = append(keepValues, v.ID)
+		}
+	}
+
+	var bound, used int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT pv.variant_id), COUNT(*) FILTER (WHERE NOT (pvv.value_id = ANY($2)))
+		FROM product_variants pv
+		JOIN product_variant_values pvv ON pvv.variant_id = pv.variant_id
+		JOIN products p ON p.id = pv.variant_id AND p.deleted_at IS NULL
+		WHERE pv.parent_id = $1`,
+		productID, keepValues).Scan(&bound, &used)
+	if err != nil {
+		return fmt.Errorf("failed to check option use: %w", err)
+	}
+	if used > 0 {
+		return fmt.Errorf("%w: delete the variants before removing their option values", ErrOptionInUse)
+	}
+	// Variants must have a value for every option, so options are set before variants are made
+	if added && bound > 0 {
+		return fmt.Errorf("%w: options cannot be added while the product has variants", ErrOptionInUse)
+	}
+
+	// Bindings of deleted variants go with the options they name
+	_, err = tx.Exec(ctx, `
+		DELETE FROM product_variants WHERE parent_id = $1 AND variant_id IN (
+			SELECT pvv.variant_id FROM product_variant_values pvv WHERE NOT (pvv.value_id = ANY($2)))`,
+		productID, keepValues)
+	if err != nil {
+		return fmt.Errorf("failed to remove variant bindings: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM product_option_values
+		WHERE option_id IN (SELECT id FROM product_options WHERE product_id = $1) AND NOT (id = ANY($2))`,
+		productID, keepValues)
+	if err != nil {
+		return fmt.Errorf("failed to remove option values: %w", err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM product_options WHERE product_id = $1 AND NOT (id = ANY($2))`, productID, keepOptions)
+	if err != nil {
+		return fmt.Errorf("failed to remove options: %w", err)
+	}
+
+	now := time.Now()
+	for _, o := range options {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO product_options (id, product_id, name, sort_order, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $5)
+			ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, sort_order = EXCLUDED.sort_order, updated_at = EXCLUDED.updated_at`,
+			o.ID, productID, o.Name, o.SortOrder, now)
+		if err != nil {
+			return fmt.Errorf("failed to save option: %w", err)
+		}
+		for _, v := range o.Values {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO product_option_values (id, option_id, value, code, sort_order)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (id) DO UPDATE SET value = EXCLUDED.value, code = EXCLUDED.code, sort_order = EXCLUDED.sort_order`,
+				v.ID, o.ID, v.Value, v.Code, v.SortOrder)
+			if err != nil {
+				return fmt.Errorf("failed to save option value: %w", err)
+			}
+		}
+	}
+
+	// Keep the variants' attributes in step with renamed options and values
+	oldNames := []string{}
+	for _, o := range current {
+		oldNames = append(oldNames, o.Name)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE products p SET attributes = (p.attributes - $2::text[]) || a.attributes
+		FROM (
+			SELECT pvv.variant_id, jsonb_object_agg(o.name, v.value) AS attributes
+			FROM product_variant_values pvv
+			JOIN product_options o ON o.id = pvv.option_id
+			JOIN product_option_values v ON v.id = pvv.value_id
+			JOIN product_variants pv ON pv.variant_id = pvv.variant_id
+			WHERE pv.parent_id = $1
+			GROUP BY pvv.variant_id
+		) a
+		WHERE p.id = a.variant_id`, productID, oldNames)
+	if err != nil {
+		return fmt.Errorf("failed to update variant attributes: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetVariants retrieves the parent's variants and the option values they are bound to.
+// Variants added without options are listed with none.
+func (r *VariantRepositoryImpl) GetVariants(parentID string) ([]models.Variant, error) {
+	ctx := context.Background()
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, p.parent_id, p.stock, p.child_category_id, p.created_at, p.updated_at,
+			p.basic->>'name', COALESCE(p.basic->>'description', ''),
+			COALESCE((p.basic->>'status')::int, 0), COALESCE((p.basic->>'condition')::int, 0),
+			COALESCE(p.basic->>'sku', ''), COALESCE((p.basic->>'is_variant')::boolean, TRUE),
+			p.price->>'price', COALESCE(p.price->>'currency', ''), p.attributes
+		FROM products p
+		WHERE p.parent_id = $1 AND p.deleted_at IS NULL
+		ORDER BY p.created_at, p.basic->>'sku'`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+	defer rows.Close()
+
+	variants := []models.Variant{}
+	index := map[string]int{}
+	for rows.Next() {
+		var v models.Variant
+		var attributes []byte
+		err := rows.Scan(
+			&v.ID, &v.ParentID, &v.Stock, &v.ChildCategoryID, &v.CreatedAt, &v.UpdatedAt,
+			&v.Basic.Name, &v.Basic.Description, &v.Basic.Status, &v.Basic.Condition,
+			&v.Basic.SKU, &v.Basic.IsVariant, &v.Price.Price, &v.Price.Currency, &attributes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan variant: %w", err)
+		}
+		v.Price.BindCurrency()
+		if err := json.Unmarshal(attributes, &v.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to decode variant attributes: %w", err)
+		}
+		v.Options = []models.VariantOption{}
+		index[v.ID] = len(variants)
+		variants = append(variants, v)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating variants: %w", err)
+	}
+	rows.Close()
+
+	optionRows, err := r.db.Query(ctx, `
+		SELECT pvv.variant_id, o.id, o.name, v.id, v.value
+		FROM product_variant_values pvv
+		JOIN product_variants pv ON pv.variant_id = pvv.variant_id
+		JOIN product_options o ON o.id = pvv.option_id
+		JOIN product_option_values v ON v.id = pvv.value_id
+		WHERE pv.parent_id = $1
+		ORDER BY o.sort_order`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variant options: %w", err)
+	}
+	defer optionRows.Close()
+
+	for optionRows.Next() {
+		var variantID string
+		var o models.VariantOption
+		if err := optionRows.Scan(&variantID, &o.OptionID, &o.Option, &o.ValueID, &o.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan variant option: %w", err)
+		}
+		if i, ok := index[variantID]; ok {
+			variants[i].Options = append(variants[i].Options, o)
+		}
+	}
+
+	if err = optionRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating variant options: %w", err)
+	}
+
+	return variants, nil
+}
+
+// CreateVariant stores a variant of the parent bound to the option values. The variant is
+// given the parent's category, and its price and weight unless it has its own.
+func (r *VariantRepositoryImpl) CreateVariant(parent *models.Product, variant *models.Variant, options []models.ProductOption, values []models.ProductOptionValue) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, parent.ID); err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+	if err = clearDeletedVariants(ctx, tx, parent.ID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM product_variants WHERE parent_id = $1 AND option_key = $2)`,
+		parent.ID, models.VariantKey(values)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check variant: %w", err)
+	}
+	if exists {
+		return ErrDuplicateVariant
+	}
+
+	if err = insertVariant(ctx, tx, parent, variant, options, values); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GenerateVariants creates the variants the parent's option matrix is missing, named and
+// given SKUs by the patterns. Nothing is created when any SKU is already taken.
+func (r *VariantRepositoryImpl) GenerateVariants(parent *models.Product, generation models.VariantGeneration) (*models.VariantGenerationResult, error) {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, parent.ID); err != nil {
+		return nil, fmt.Errorf("failed to lock product: %w", err)
+	}
+	if err = clearDeletedVariants(ctx, tx, parent.ID); err != nil {
+		return nil, err
+	}
+
+	options, err := queryProductOptions(ctx, tx, parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("%w: the product has no options to generate variants from", ErrInvalidVariant)
+	}
+	if generation.SKUPattern == "" {
+		generation.SKUPattern = models.DefaultSKUPattern(options)
+	}
+	if generation.NamePattern == "" {
+		generation.NamePattern = models.DefaultNamePattern(options)
+	}
+
+	existing := map[string]bool{}
+	rows, err := tx.Query(ctx, `SELECT option_key FROM product_variants WHERE parent_id = $1`, parent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query variants: %w", err)
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan variant: %w", err)
+		}
+		existing[key] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating variants: %w", err)
+	}
+
+	result := &models.VariantGenerationResult{Created: []models.Variant{}}
+	for _, values := range models.OptionMatrix(options) {
+		if existing[models.VariantKey(values)] {
+			result.Skipped++
+			continue
+		}
+
+		sku, err := models.ExpandVariantPattern(generation.SKUPattern, parent.Basic, options, values, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidVariant, err)
+		}
+		name, err := models.ExpandVariantPattern(generation.NamePattern, parent.Basic, options, values, false)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidVariant, err)
+		}
+
+		variant := models.Variant{Product: models.Product{
+			Basic: models.BasicInfo{
+				Name:        name,
+				Description: parent.Basic.Description,
+				Status:      parent.Basic.Status,
+				Condition:   parent.Basic.Condition,
+				SKU:         sku,
+			},
+		}}
+		if err := insertVariant(ctx, tx, parent, &variant, options, values); err != nil {
+			return nil, err
+		}
+		result.Created = append(result.Created, variant)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// clearDeletedVariants drops the option bindings of deleted variants so their combinations
+// can be made again
+func clearDeletedVariants(ctx context.Context, tx pgx.Tx, parentID string) error {
+	_, err := tx.Exec(ctx, `
+		DELETE FROM product_variants
+		WHERE parent_id = $1 AND variant_id IN (SELECT id FROM products WHERE deleted_at IS NOT NULL)`, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to clear deleted variants: %w", err)
+	}
+	return nil
+}
+
+// insertVariant stores the variant product and binds it to the option values. Its attributes
+// are its option values.
+func insertVariant(ctx context.Context, tx pgx.Tx, parent *models.Product, variant *models.Variant, options []models.ProductOption, values []models.ProductOptionValue) error {
+	if variant.Basic.SKU != "" {
+		var taken bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM products WHERE basic->>'sku' = $1 AND deleted_at IS NULL)`,
+			variant.Basic.SKU).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check SKU: %w", err)
+		}
+		if taken {
+			return fmt.Errorf("%w: %s", ErrDuplicateSKU, variant.Basic.SKU)
+		}
+	}
+
+	if variant.ID == "" {
+		variant.ID = uuid.NewString()
+	}
+	now := time.Now()
+	variant.ParentID = &parent.ID
+	variant.ChildCategoryID = parent.ChildCategoryID
+	variant.Basic.IsVariant = true
+	if variant.Price.Price.IsZero() {
+		variant.Price = parent.Price
+	}
+	variant.Price.LastUpdateUnix = now.Unix()
+	if variant.Weight.Weight == 0 {
+		variant.Weight = parent.Weight
+	}
+	variant.Attributes = models.VariantAttributes(options, values)
+	variant.CreatedAt = now
+	variant.UpdatedAt = now
+
+	basicJSON, err := json.Marshal(variant.Basic)
+	if err != nil {
+		return fmt.Errorf("failed to marshal basic info: %w", err)
+	}
+	priceJSON, err := json.Marshal(variant.Price)
+	if err != nil {
+		return fmt.Errorf("failed to marshal price: %w", err)
+	}
+	weightJSON, err := json.Marshal(variant.Weight)
+	if err != nil {
+		return fmt.Errorf("failed to marshal weight: %w", err)
+	}
+	attributesJSON, err := json.Marshal(variant.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO products (
+			id, parent_id, stock, child_category_id, created_at, updated_at,
+			basic, price, weight, attributes
+		) VALUES ($1, $2, 0, $3, $4, $5, $6, $7, $8, $9)`,
+		variant.ID, parent.ID, variant.ChildCategoryID, variant.CreatedAt, variant.UpdatedAt,
+		basicJSON, priceJSON, weightJSON, attributesJSON,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create variant: %w", err)
+	}
+	if err = recordProductPrice(ctx, tx, variant.ID, nil, variant.Price, now); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO product_variants (variant_id, parent_id, option_key, created_at) VALUES ($1, $2, $3, $4)`,
+		variant.ID, parent.ID, models.VariantKey(values), now)
+	if err != nil {
+		return fmt.Errorf("failed to bind variant: %w", err)
+	}
+	variant.Options = make([]models.VariantOption, len(values))
+	for i, v := range values {
+		_, err = tx.Exec(ctx, `INSERT INTO product_variant_values (variant_id, option_id, value_id) VALUES ($1, $2, $3)`,
+			variant.ID, options[i].ID, v.ID)
+		if err != nil {
+			return fmt.Errorf("failed to bind variant option: %w", err)
+		}
+		variant.Options[i] = models.VariantOption{OptionID: options[i].ID, Option: options[i].Name, ValueID: v.ID, Value: v.Value}
+	}
+	return nil
+}
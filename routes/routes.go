@@ -2,36 +2,114 @@ package routes
 
 import (
 	"inventory-go/handlers"
+	"inventory-go/storage"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5"
 )
 
-func SetupRoutes(r *mux.Router, db *pgx.Conn) {
+func SetupRoutes(r *mux.Router, db *pgx.Conn, store storage.BlobStore) {
 	// Initialize handlers with database connection
-	productHandler := handlers.NewProductHandler(db)
+	productHandler := handlers.NewProductHandler(db, store)
 	categoryHandler := handlers.NewCategoryHandler(db)
 	customerHandler := handlers.NewCustomerHandler(db)
 	saleHandler := handlers.NewSaleHandler(db)
 	supplierHandler := handlers.NewSupplierHandler(db)
 	stockInHandler := handlers.NewStockInHandler(db)
 	rejectHandler := handlers.NewRejectHandler(db)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(db)
+	settingHandler := handlers.NewSettingHandler(db)
+	accountingHandler := handlers.NewAccountingHandler(db)
+	periodHandler := handlers.NewPeriodHandler(db)
+	receivableHandler := handlers.NewReceivableHandler(db)
+	payableHandler := handlers.NewPayableHandler(db)
+	reportHandler := handlers.NewReportHandler(db)
+	taxHandler := handlers.NewTaxHandler(db)
+	priceListHandler := handlers.NewPriceListHandler(db)
+	promotionHandler := handlers.NewPromotionHandler(db)
+	priceScheduleHandler := handlers.NewPriceScheduleHandler(db)
+	pricingRuleHandler := handlers.NewPricingRuleHandler(db)
+	kitHandler := handlers.NewKitHandler(db)
+	bomHandler := handlers.NewBOMHandler(db)
+	unitHandler := handlers.NewUnitHandler(db)
+	variantHandler := handlers.NewVariantHandler(db)
+	barcodeHandler := handlers.NewBarcodeHandler(db)
+	labelHandler := handlers.NewLabelHandler(db)
+	imageHandler := handlers.NewImageHandler(db, store)
+	importHandler := handlers.NewImportHandler(db)
 
 	// Product routes
 	r.HandleFunc("/api/products", productHandler.CreateProduct).Methods("POST")
 	r.HandleFunc("/api/products", productHandler.GetAllProducts).Methods("GET")
+	r.HandleFunc("/api/products/search", productHandler.SearchProducts).Methods("GET")
+	r.HandleFunc("/api/products/barcode/{code}", barcodeHandler.GetProductByBarcode).Methods("GET")
 	r.HandleFunc("/api/products/{id}", productHandler.GetProduct).Methods("GET")
 	r.HandleFunc("/api/products/{id}", productHandler.UpdateProduct).Methods("PUT")
 	r.HandleFunc("/api/products/{id}", productHandler.DeleteProduct).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/cost-movements", reportHandler.GetProductCostMovements).Methods("GET")
+	r.HandleFunc("/api/products/{id}/price-history", priceScheduleHandler.GetPriceHistory).Methods("GET")
+	r.HandleFunc("/api/products/{id}/price-schedules", priceScheduleHandler.GetProductPriceSchedules).Methods("GET")
+	r.HandleFunc("/api/products/{id}/price-schedules", priceScheduleHandler.CreatePriceSchedule).Methods("POST")
+	r.HandleFunc("/api/products/{id}/kit", kitHandler.GetProductKit).Methods("GET")
+	r.HandleFunc("/api/products/{id}/kit", kitHandler.SaveProductKit).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/kit", kitHandler.DeleteProductKit).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/bom", bomHandler.GetProductBOM).Methods("GET")
+	r.HandleFunc("/api/products/{id}/bom", bomHandler.SaveProductBOM).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/bom", bomHandler.DeleteProductBOM).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/units", unitHandler.GetProductUnits).Methods("GET")
+	r.HandleFunc("/api/products/{id}/units", unitHandler.SaveProductUnits).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/attributes", productHandler.AddProductAttribute).Methods("POST")
+	r.HandleFunc("/api/products/{id}/attributes/{key}", productHandler.DeleteProductAttribute).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/options", variantHandler.GetProductOptions).Methods("GET")
+	r.HandleFunc("/api/products/{id}/options", variantHandler.SaveProductOptions).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/variants", variantHandler.GetProductVariants).Methods("GET")
+	r.HandleFunc("/api/products/{id}/variants", variantHandler.CreateProductVariant).Methods("POST")
+	r.HandleFunc("/api/products/{id}/variants/generate", variantHandler.GenerateProductVariants).Methods("POST")
+	r.HandleFunc("/api/products/{id}/barcodes", barcodeHandler.GetProductBarcodes).Methods("GET")
+	r.HandleFunc("/api/products/{id}/barcodes", barcodeHandler.AddProductBarcode).Methods("POST")
+	r.HandleFunc("/api/products/{id}/barcodes/{barcodeId}", barcodeHandler.DeleteProductBarcode).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/images", imageHandler.GetProductImages).Methods("GET")
+	r.HandleFunc("/api/products/{id}/images", imageHandler.UploadProductImage).Methods("POST")
+	r.HandleFunc("/api/products/{id}/images/reorder", imageHandler.ReorderProductImages).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/images/{imageId}/primary", imageHandler.SetPrimaryProductImage).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/images/{imageId}", imageHandler.DeleteProductImage).Methods("DELETE")
+
+	// Barcode routes
+	r.HandleFunc("/api/barcodes/generate", barcodeHandler.GenerateMissingBarcodes).Methods("POST")
+	r.HandleFunc("/api/barcodes/{code}/image", barcodeHandler.RenderBarcode).Methods("GET")
+
+	// Label routes
+	r.HandleFunc("/api/labels", labelHandler.PrintLabels).Methods("POST")
+	r.HandleFunc("/api/label-templates", labelHandler.GetLabelTemplates).Methods("GET")
+	r.HandleFunc("/api/label-templates", labelHandler.CreateLabelTemplate).Methods("POST")
+	r.HandleFunc("/api/label-templates/{id}", labelHandler.GetLabelTemplate).Methods("GET")
+	r.HandleFunc("/api/label-templates/{id}", labelHandler.UpdateLabelTemplate).Methods("PUT")
+	r.HandleFunc("/api/label-templates/{id}", labelHandler.DeleteLabelTemplate).Methods("DELETE")
+
+	// Import routes
+	r.HandleFunc("/api/imports", importHandler.ListImports).Methods("GET")
+	r.HandleFunc("/api/imports", importHandler.CreateImport).Methods("POST")
+	r.HandleFunc("/api/imports/{id}", importHandler.GetImport).Methods("GET")
+	r.HandleFunc("/api/imports/{id}/errors", importHandler.GetImportErrors).Methods("GET")
+
+	// Unit of measure routes
+	r.HandleFunc("/api/units", unitHandler.GetUnits).Methods("GET")
+	r.HandleFunc("/api/units", unitHandler.CreateUnit).Methods("POST")
+	r.HandleFunc("/api/units/{id}", unitHandler.GetUnit).Methods("GET")
+	r.HandleFunc("/api/units/{id}", unitHandler.UpdateUnit).Methods("PUT")
+	r.HandleFunc("/api/units/{id}", unitHandler.DeleteUnit).Methods("DELETE")
 
 	// Category routes
 	r.HandleFunc("/api/categories", categoryHandler.CreateCategory).Methods("POST")
 	r.HandleFunc("/api/categories", categoryHandler.GetAllCategories).Methods("GET")
+	r.HandleFunc("/api/categories/tree", categoryHandler.GetCategoryTree).Methods("GET")
+	r.HandleFunc("/api/categories/reorder", categoryHandler.ReorderCategories).Methods("PUT")
 	r.HandleFunc("/api/categories/{idOrSlug}", categoryHandler.GetCategoryByIDOrSlug).Methods("GET")
 	r.HandleFunc("/api/categories/{id}/children", categoryHandler.GetWithChildren).Methods("GET")
 	r.HandleFunc("/api/categories/parent/{parentID}", categoryHandler.GetCategoriesByParentID).Methods("GET")
 	r.HandleFunc("/api/categories/{id}/breadcrumbs", categoryHandler.GetBreadcrumbs).Methods("GET")
 	r.HandleFunc("/api/categories/{id}", categoryHandler.UpdateCategory).Methods("PUT")
+	r.HandleFunc("/api/categories/{id}/move", categoryHandler.MoveCategory).Methods("PUT")
 	r.HandleFunc("/api/categories/{id}", categoryHandler.DeleteCategory).Methods("DELETE")
 
 	// Customer routes
@@ -45,14 +123,22 @@ func SetupRoutes(r *mux.Router, db *pgx.Conn) {
 
 	// Sale routes
 	r.HandleFunc("/api/sales", saleHandler.GetSales).Methods("GET")
+	r.HandleFunc("/api/sales/summary", saleHandler.GetSalesSummary).Methods("GET")
+	r.HandleFunc("/api/sales/daily", saleHandler.GetDailySales).Methods("GET")
 	r.HandleFunc("/api/sales/{id}", saleHandler.GetSale).Methods("GET")
 	r.HandleFunc("/api/sales", saleHandler.CreateSale).Methods("POST")
 	r.HandleFunc("/api/sales/{id}", saleHandler.UpdateSale).Methods("PUT")
 	r.HandleFunc("/api/sales/{id}", saleHandler.DeleteSale).Methods("DELETE")
 	r.HandleFunc("/api/sales/reference/{reference}", saleHandler.GetSaleByReference).Methods("GET")
 	r.HandleFunc("/api/customers/{id}/sales", saleHandler.GetCustomerSales).Methods("GET")
-	r.HandleFunc("/api/sales/summary", saleHandler.GetSalesSummary).Methods("GET")
-	r.HandleFunc("/api/sales/daily", saleHandler.GetDailySales).Methods("GET")
+
+	// Accounts receivable routes
+	r.HandleFunc("/api/customers/{id}/open-invoices", receivableHandler.GetOpenInvoices).Methods("GET")
+	r.HandleFunc("/api/customers/{id}/statement", receivableHandler.GetCustomerStatement).Methods("GET")
+	r.HandleFunc("/api/customer-payments", receivableHandler.GetCustomerPayments).Methods("GET")
+	r.HandleFunc("/api/customer-payments", receivableHandler.CreateCustomerPayment).Methods("POST")
+	r.HandleFunc("/api/customer-payments/{id}", receivableHandler.GetCustomerPayment).Methods("GET")
+	r.HandleFunc("/api/customer-payments/{id}", receivableHandler.DeleteCustomerPayment).Methods("DELETE")
 
 	// Supplier routes
 	r.HandleFunc("/api/suppliers", supplierHandler.GetAllSuppliers).Methods("GET")
@@ -65,6 +151,8 @@ func SetupRoutes(r *mux.Router, db *pgx.Conn) {
 
 	// Stock-in routes
 	r.HandleFunc("/api/stockins", stockInHandler.GetStockIns).Methods("GET")
+	r.HandleFunc("/api/stockins/summary", stockInHandler.GetStockInSummary).Methods("GET")
+	r.HandleFunc("/api/stockins/daily", stockInHandler.GetDailyStockIn).Methods("GET")
 	r.HandleFunc("/api/stockins/{id}", stockInHandler.GetStockIn).Methods("GET")
 	r.HandleFunc("/api/stockins", stockInHandler.CreateStockIn).Methods("POST")
 	r.HandleFunc("/api/stockins/{id}", stockInHandler.UpdateStockIn).Methods("PUT")
@@ -74,11 +162,41 @@ func SetupRoutes(r *mux.Router, db *pgx.Conn) {
 	r.HandleFunc("/api/stockins/{stockInId}/items/{itemId}", stockInHandler.UpdateStockInItem).Methods("PUT")
 	r.HandleFunc("/api/stockins/{stockInId}/items/{itemId}", stockInHandler.DeleteStockInItem).Methods("DELETE")
 	r.HandleFunc("/api/suppliers/{id}/stockins", stockInHandler.GetStockInsBySupplier).Methods("GET")
-	r.HandleFunc("/api/stockins/summary", stockInHandler.GetStockInSummary).Methods("GET")
-	r.HandleFunc("/api/stockins/daily", stockInHandler.GetDailyStockIn).Methods("GET")
+
+	// Accounts payable routes
+	r.HandleFunc("/api/stockins/{id}/payments", payableHandler.GetStockInPayments).Methods("GET")
+	r.HandleFunc("/api/stockins/{id}/payments", payableHandler.CreateStockInPayment).Methods("POST")
+	r.HandleFunc("/api/stockins/{id}/payments/{paymentId}", payableHandler.DeleteStockInPayment).Methods("DELETE")
+	r.HandleFunc("/api/supplier-credits", payableHandler.GetSupplierCredits).Methods("GET")
+	r.HandleFunc("/api/supplier-credits", payableHandler.CreateSupplierCredit).Methods("POST")
+	r.HandleFunc("/api/supplier-credits/{id}", payableHandler.GetSupplierCredit).Methods("GET")
+	r.HandleFunc("/api/supplier-credits/{id}", payableHandler.DeleteSupplierCredit).Methods("DELETE")
+	r.HandleFunc("/api/suppliers/{id}/statement", payableHandler.GetSupplierStatement).Methods("GET")
+
+	// Landed cost routes
+	landedCostHandler := handlers.NewLandedCostHandler(db)
+	r.HandleFunc("/api/landed-costs", landedCostHandler.GetLandedCosts).Methods("GET")
+	r.HandleFunc("/api/landed-costs", landedCostHandler.CreateLandedCost).Methods("POST")
+	r.HandleFunc("/api/landed-costs/{id}", landedCostHandler.GetLandedCost).Methods("GET")
+	r.HandleFunc("/api/landed-costs/{id}", landedCostHandler.UpdateLandedCost).Methods("PUT")
+	r.HandleFunc("/api/landed-costs/{id}", landedCostHandler.DeleteLandedCost).Methods("DELETE")
+
+	// Kit routes
+	r.HandleFunc("/api/kits", kitHandler.GetKits).Methods("GET")
+
+	// Bill of materials routes
+	r.HandleFunc("/api/boms", bomHandler.GetBOMs).Methods("GET")
+
+	// Assembly routes (building assembled kits and bill of materials products from their components)
+	r.HandleFunc("/api/assemblies", kitHandler.GetAssemblies).Methods("GET")
+	r.HandleFunc("/api/assemblies", kitHandler.CreateAssembly).Methods("POST")
+	r.HandleFunc("/api/assemblies/{id}", kitHandler.GetAssembly).Methods("GET")
+	r.HandleFunc("/api/assemblies/{id}", kitHandler.DeleteAssembly).Methods("DELETE")
 
 	// Reject routes (for inventory decreases/write-offs)
 	r.HandleFunc("/api/rejects", rejectHandler.GetRejects).Methods("GET")
+	r.HandleFunc("/api/rejects/summary", rejectHandler.GetRejectSummary).Methods("GET")
+	r.HandleFunc("/api/rejects/daily", rejectHandler.GetDailyReject).Methods("GET")
 	r.HandleFunc("/api/rejects/{id}", rejectHandler.GetReject).Methods("GET")
 	r.HandleFunc("/api/rejects", rejectHandler.CreateReject).Methods("POST")
 	r.HandleFunc("/api/rejects/{id}", rejectHandler.UpdateReject).Methods("PUT")
@@ -87,6 +205,81 @@ func SetupRoutes(r *mux.Router, db *pgx.Conn) {
 	r.HandleFunc("/api/rejects/{id}/items", rejectHandler.AddRejectItem).Methods("POST")
 	r.HandleFunc("/api/rejects/{rejectId}/items/{itemId}", rejectHandler.UpdateRejectItem).Methods("PUT")
 	r.HandleFunc("/api/rejects/{rejectId}/items/{itemId}", rejectHandler.DeleteRejectItem).Methods("DELETE")
-	r.HandleFunc("/api/rejects/summary", rejectHandler.GetRejectSummary).Methods("GET")
-	r.HandleFunc("/api/rejects/daily", rejectHandler.GetDailyReject).Methods("GET")
+
+	// Exchange rate routes
+	r.HandleFunc("/api/exchange-rates", exchangeRateHandler.GetExchangeRates).Methods("GET")
+	r.HandleFunc("/api/exchange-rates", exchangeRateHandler.CreateExchangeRate).Methods("POST")
+	r.HandleFunc("/api/exchange-rates/latest", exchangeRateHandler.GetLatestExchangeRate).Methods("GET")
+	r.HandleFunc("/api/exchange-rates/import", exchangeRateHandler.ImportExchangeRates).Methods("POST")
+	r.HandleFunc("/api/exchange-rates/{id}", exchangeRateHandler.GetExchangeRate).Methods("GET")
+	r.HandleFunc("/api/exchange-rates/{id}", exchangeRateHandler.DeleteExchangeRate).Methods("DELETE")
+
+	// Tax code routes
+	r.HandleFunc("/api/tax-codes", taxHandler.GetTaxCodes).Methods("GET")
+	r.HandleFunc("/api/tax-codes", taxHandler.CreateTaxCode).Methods("POST")
+	r.HandleFunc("/api/tax-codes/{id}", taxHandler.GetTaxCode).Methods("GET")
+	r.HandleFunc("/api/tax-codes/{id}", taxHandler.UpdateTaxCode).Methods("PUT")
+	r.HandleFunc("/api/tax-codes/{id}", taxHandler.DeleteTaxCode).Methods("DELETE")
+
+	// Price list routes
+	r.HandleFunc("/api/price-lists", priceListHandler.GetPriceLists).Methods("GET")
+	r.HandleFunc("/api/price-lists", priceListHandler.CreatePriceList).Methods("POST")
+	r.HandleFunc("/api/price-lists/quote", priceListHandler.GetPriceQuote).Methods("GET")
+	r.HandleFunc("/api/price-lists/{id}", priceListHandler.GetPriceList).Methods("GET")
+	r.HandleFunc("/api/price-lists/{id}", priceListHandler.UpdatePriceList).Methods("PUT")
+	r.HandleFunc("/api/price-lists/{id}", priceListHandler.DeletePriceList).Methods("DELETE")
+
+	// Promotion routes
+	r.HandleFunc("/api/promotions", promotionHandler.GetPromotions).Methods("GET")
+	r.HandleFunc("/api/promotions", promotionHandler.CreatePromotion).Methods("POST")
+	r.HandleFunc("/api/promotions/{id}", promotionHandler.GetPromotion).Methods("GET")
+	r.HandleFunc("/api/promotions/{id}", promotionHandler.UpdatePromotion).Methods("PUT")
+	r.HandleFunc("/api/promotions/{id}", promotionHandler.DeletePromotion).Methods("DELETE")
+
+	// Price schedule routes
+	r.HandleFunc("/api/price-schedules", priceScheduleHandler.GetPriceSchedules).Methods("GET")
+	r.HandleFunc("/api/price-schedules/run", priceScheduleHandler.RunPriceSchedules).Methods("POST")
+	r.HandleFunc("/api/price-schedules/{id}", priceScheduleHandler.GetPriceSchedule).Methods("GET")
+	r.HandleFunc("/api/price-schedules/{id}", priceScheduleHandler.CancelPriceSchedule).Methods("DELETE")
+
+	// Pricing rule routes
+	r.HandleFunc("/api/pricing-rules", pricingRuleHandler.GetPricingRules).Methods("GET")
+	r.HandleFunc("/api/pricing-rules", pricingRuleHandler.CreatePricingRule).Methods("POST")
+	r.HandleFunc("/api/pricing-rules/{id}", pricingRuleHandler.GetPricingRule).Methods("GET")
+	r.HandleFunc("/api/pricing-rules/{id}", pricingRuleHandler.UpdatePricingRule).Methods("PUT")
+	r.HandleFunc("/api/pricing-rules/{id}", pricingRuleHandler.DeletePricingRule).Methods("DELETE")
+	r.HandleFunc("/api/price-suggestions", pricingRuleHandler.GetPriceSuggestions).Methods("GET")
+	r.HandleFunc("/api/price-suggestions/{id}", pricingRuleHandler.GetPriceSuggestion).Methods("GET")
+	r.HandleFunc("/api/price-suggestions/{id}/apply", pricingRuleHandler.ApplyPriceSuggestion).Methods("POST")
+	r.HandleFunc("/api/price-suggestions/{id}/reject", pricingRuleHandler.RejectPriceSuggestion).Methods("POST")
+
+	// Setting routes
+	r.HandleFunc("/api/settings", settingHandler.GetSettings).Methods("GET")
+	r.HandleFunc("/api/settings/{key}", settingHandler.UpdateSetting).Methods("PUT")
+
+	// Accounting routes
+	r.HandleFunc("/api/accounting/accounts", accountingHandler.GetAccounts).Methods("GET")
+	r.HandleFunc("/api/accounting/accounts/{role}", accountingHandler.UpdateAccount).Methods("PUT")
+	r.HandleFunc("/api/accounting/journals", accountingHandler.GetJournals).Methods("GET")
+	r.HandleFunc("/api/accounting/journals/export", accountingHandler.ExportJournals).Methods("GET")
+	r.HandleFunc("/api/accounting/periods", periodHandler.GetPeriods).Methods("GET")
+	r.HandleFunc("/api/accounting/periods", periodHandler.CreatePeriod).Methods("POST")
+	r.HandleFunc("/api/accounting/periods/{id}", periodHandler.GetPeriod).Methods("GET")
+	r.HandleFunc("/api/accounting/periods/{id}", periodHandler.DeletePeriod).Methods("DELETE")
+	r.HandleFunc("/api/accounting/periods/{id}/close", periodHandler.ClosePeriod).Methods("POST")
+	r.HandleFunc("/api/accounting/periods/{id}/reopen", periodHandler.ReopenPeriod).Methods("POST")
+	r.HandleFunc("/api/accounting/period-overrides", periodHandler.GetPeriodOverrides).Methods("GET")
+
+	// Report routes
+	r.HandleFunc("/api/reports/inventory-valuation", reportHandler.GetInventoryValuation).Methods("GET")
+	r.HandleFunc("/api/reports/profit", reportHandler.GetProfitReport).Methods("GET")
+	r.HandleFunc("/api/reports/ar-aging", reportHandler.GetARAging).Methods("GET")
+	r.HandleFunc("/api/reports/ap-aging", reportHandler.GetAPAging).Methods("GET")
+	r.HandleFunc("/api/reports/vat", taxHandler.GetVATReport).Methods("GET")
+	r.HandleFunc("/api/reports/promotions", promotionHandler.GetPromotionReport).Methods("GET")
+
+	// Uploaded files, when they are kept on this server
+	if local, ok := store.(*storage.LocalStore); ok {
+		r.PathPrefix(local.PathPrefix()).Handler(local.Handler()).Methods("GET", "HEAD")
+	}
 }
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"inventory-go/db"
+	"inventory-go/repositories"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultPriceInterval is how often scheduled prices are checked when no interval is configured
+const DefaultPriceInterval = time.Minute
+
+// PriceScheduler applies scheduled prices when they start and reverts them when they end.
+// Each run gets its own connection from a db.Connector.
+type PriceScheduler struct {
+	connector *db.Connector
+	interval  time.Duration
+}
+
+// NewPriceScheduler creates a PriceScheduler that connects with the same settings as conn and
+// checks for due prices every interval
+func NewPriceScheduler(conn *pgx.Conn, interval time.Duration) *PriceScheduler {
+	if interval <= 0 {
+		interval = DefaultPriceInterval
+	}
+	return &PriceScheduler{
+		connector: db.NewConnector(conn),
+		interval:  interval,
+	}
+}
+
+// Start runs the scheduler in the background until the context is cancelled. It runs once
+// straight away so prices that fell due while the server was down are brought up to date.
+func (s *PriceScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.runOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runOnce applies and reverts the schedules that are due, logging what changed
+func (s *PriceScheduler) runOnce(ctx context.Context) {
+	conn, err := s.connector.Connect(ctx)
+	if err != nil {
+		log.Printf("Price scheduler: failed to connect: %v", err)
+		return
+	}
+	defer conn.Close(context.Background())
+
+	run, err := repositories.NewPriceScheduleRepository(conn).RunDue(time.Now())
+	if err != nil {
+		log.Printf("Price scheduler: %v", err)
+	}
+	if run != nil && run.Applied+run.Reverted+run.Expired > 0 {
+		log.Printf("Price scheduler: %d applied, %d reverted, %d expired", run.Applied, run.Reverted, run.Expired)
+	}
+}
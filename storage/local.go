@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps files in a directory on the server and serves them itself
+type LocalStore struct {
+	dir       string
+	publicURL string
+}
+
+// NewLocalStore creates a store that keeps files under dir, creating it if needed. publicURL
+// is where Handler is mounted, either a path such as /uploads or a full URL.
+func NewLocalStore(dir, publicURL string) (*LocalStore, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalStore{dir: dir, publicURL: strings.TrimSuffix(publicURL, "/")}, nil
+}
+
+// Put writes the file to a temporary name first, so readers never see a partly written file
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return joinURL(s.publicURL, key), nil
+}
+
+// Delete removes the file and any directories it leaves empty
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	// Removing a directory fails while it still has files, which ends the walk up
+	for dir := filepath.Dir(target); dir != s.dir && strings.HasPrefix(dir, s.dir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// PathPrefix is the URL path Handler serves files under
+func (s *LocalStore) PathPrefix() string {
+	prefix := s.publicURL
+	if u, err := url.Parse(s.publicURL); err == nil {
+		prefix = u.Path
+	}
+	return strings.TrimSuffix(prefix, "/") + "/"
+}
+
+// Handler serves the stored files under PathPrefix, without listing directories
+func (s *LocalStore) Handler() http.Handler {
+	files := http.StripPrefix(strings.TrimSuffix(s.PathPrefix(), "/"), http.FileServer(http.Dir(s.dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config locates the bucket of an S3Store. Any S3-compatible service works, such as AWS
+// S3, Cloudflare R2, DigitalOcean Spaces or MinIO.
+type S3Config struct {
+	// Endpoint is the host, and port if needed, of the service; AWS S3 when empty
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Insecure connects over plain HTTP, for a local MinIO
+	Insecure bool
+	// PublicURL is where the bucket's files are served from, such as a CDN. When empty,
+	// files are linked in the bucket itself, which then has to allow public reads.
+	PublicURL string
+}
+
+// S3Store keeps files in an S3-compatible bucket
+type S3Store struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3Store creates a store for the configured bucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3 bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3 access key ID and secret access key are required")
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: !cfg.Insecure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = joinURL(client.EndpointURL().String(), cfg.Bucket)
+	}
+	return &S3Store{client: client, bucket: cfg.Bucket, publicURL: publicURL}, nil
+}
+
+// Put uploads the file; stored files never change under their key, so they may be cached for long
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		CacheControl: "public, max-age=31536000, immutable",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return joinURL(s.publicURL, key), nil
+}
+
+// Delete removes the object from the bucket
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package storage keeps uploaded files, such as product images, on the local disk or in an
+// S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Storage drivers, chosen with STORAGE_DRIVER
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// BlobStore stores files under slash-separated keys such as products/{id}/{imageId}/original.jpg
+type BlobStore interface {
+	// Put stores size bytes from r under key, replacing any file already there, and returns
+	// the URL the file is served from
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error)
+	// Delete removes the file under key. Deleting a file that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromEnv creates the store named by STORAGE_DRIVER, local by default:
+//
+//   - local keeps files under STORAGE_LOCAL_DIR (default ./uploads), served from
+//     STORAGE_PUBLIC_URL (default /uploads)
+//   - s3 keeps files in S3_BUCKET at S3_ENDPOINT (default s3.amazonaws.com), signing in with
+//     S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY; files are served from STORAGE_PUBLIC_URL,
+//     or from the bucket itself when it is empty
+func NewFromEnv() (BlobStore, error) {
+	publicURL := os.Getenv("STORAGE_PUBLIC_URL")
+
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", DriverLocal:
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		if publicURL == "" {
+			publicURL = "/uploads"
+		}
+		return NewLocalStore(dir, publicURL)
+	case DriverS3:
+		return NewS3Store(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			Insecure:        os.Getenv("S3_INSECURE") == "true",
+			PublicURL:       publicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q; use %s or %s", driver, DriverLocal, DriverS3)
+	}
+}
+
+// cleanKey checks that a key is a relative path that stays inside the store
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(key)
+	if key == "" || cleaned != key || strings.HasPrefix(cleaned, "/") || cleaned == "." ||
+		cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.New("invalid storage key: " + key)
+	}
+	return cleaned, nil
+}
+
+// joinURL appends a key to a base URL
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}